@@ -7,46 +7,55 @@ package api
 import (
 	// stdlib
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
 	"time"
 
 	// third-party
-
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 
 	// internal
+	ocsv1 "ocs/api/proto/ocs/v1"
 	"ocs/managers"
 	"ocs/src/tools"
+	"ocs/src/types"
 )
 
-// Assuming the following proto definition exists in ocs.proto:
-// service OCS {
-//   rpc ListModels(ListModelsRequest) returns (ListModelsResponse);
-//   rpc CreateSession(CreateSessionRequest) returns (CreateSessionResponse);
-//   rpc GetSession(GetSessionRequest) returns (GetSessionResponse);
-//   rpc AddMessage(AddMessageRequest) returns (AddMessageResponse);
-//   rpc ProcessInference(ProcessInferenceRequest) returns (ProcessInferenceResponse);
-//   rpc ExecuteTool(ExecuteToolRequest) returns (ExecuteToolResponse);
-// }
-
 // OCSGrpcServer implements the gRPC server for OCS
 type OCSGrpcServer struct {
-	configManager          *managers.ConfigManager
-	modelManager           *managers.ModelManager
-	sessionManager         *managers.SessionManager
-	inferenceManager       *managers.InferenceManager
-	tokenManager           *managers.TokenManager
-	diskManager            *managers.DiskManager
-	conversationMgr        *managers.ConversationManager
-	codeTool               *tools.CodeTool
-	fileTool               *tools.FileTool
-	searchTool             *tools.SearchTool
-	UnimplementedOCSServer // Embed for forward compatibility
+	configManager                *managers.ConfigManager
+	modelManager                 *managers.ModelManager
+	sessionManager               *managers.SessionManager
+	inferenceManager             *managers.InferenceManager
+	tokenManager                 *managers.TokenManager
+	diskManager                  *managers.DiskManager
+	conversationMgr              *managers.ConversationManager
+	toolRegistry                 *tools.ToolRegistry
+	authenticator                Authenticator
+	ocsv1.UnimplementedOCSServer // Embed for forward compatibility
+}
+
+// Authenticator resolves a bearer token to the caller it was issued to
+type Authenticator func(token string) (*managers.Caller, error)
+
+// authenticatedStream carries the authenticated caller on a server stream's context
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
 }
 
 // NewOCSGrpcServer creates a new gRPC server
@@ -58,9 +67,7 @@ func NewOCSGrpcServer(
 	tokenManager *managers.TokenManager,
 	diskManager *managers.DiskManager,
 	conversationMgr *managers.ConversationManager,
-	codeTool *tools.CodeTool,
-	fileTool *tools.FileTool,
-	searchTool *tools.SearchTool,
+	toolRegistry *tools.ToolRegistry,
 ) *OCSGrpcServer {
 	return &OCSGrpcServer{
 		configManager:    configManager,
@@ -70,16 +77,19 @@ func NewOCSGrpcServer(
 		tokenManager:     tokenManager,
 		diskManager:      diskManager,
 		conversationMgr:  conversationMgr,
-		codeTool:         codeTool,
-		fileTool:         fileTool,
-		searchTool:       searchTool,
+		toolRegistry:     toolRegistry,
 	}
 }
 
+// SetAuthenticator sets how OCS RPCs resolve the bearer token in their "authorization"
+// metadata; without one every OCS RPC is refused
+func (s *OCSGrpcServer) SetAuthenticator(authenticator Authenticator) {
+	s.authenticator = authenticator
+}
+
 // Start starts the gRPC server
 func (s *OCSGrpcServer) Start(ctx context.Context, addr string) error {
-	grpcServer := grpc.NewServer()
-	RegisterOCSServer(grpcServer, s)
+	grpcServer := s.newServer()
 
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -97,17 +107,81 @@ func (s *OCSGrpcServer) Start(ctx context.Context, addr string) error {
 	return nil
 }
 
+// newServer creates a grpc.Server with the OCS, health and reflection services registered
+func (s *OCSGrpcServer) newServer() *grpc.Server {
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(s.authenticateUnary),
+		grpc.StreamInterceptor(s.authenticateStream),
+	)
+	ocsv1.RegisterOCSServer(grpcServer, s)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(ocsv1.OCS_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	reflection.Register(grpcServer)
+	return grpcServer
+}
+
+// authenticate attaches the caller named by the request's bearer token to ctx; health
+// and reflection stay open
+func (s *OCSGrpcServer) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if !strings.HasPrefix(method, "/"+ocsv1.OCS_ServiceDesc.ServiceName+"/") {
+		return ctx, nil
+	}
+	if s.authenticator == nil {
+		return nil, status.Error(codes.Unauthenticated, "authentication is not configured")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || !strings.HasPrefix(values[0], "Bearer ") {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+	caller, err := s.authenticator(strings.TrimPrefix(values[0], "Bearer "))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return managers.WithCaller(ctx, caller), nil
+}
+
+// authenticateUnary runs unary RPCs as the authenticated caller
+func (s *OCSGrpcServer) authenticateUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authenticateStream runs streaming RPCs as the authenticated caller
+func (s *OCSGrpcServer) authenticateStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
 // ListModels returns available and loaded models
-func (s *OCSGrpcServer) ListModels(ctx context.Context, req *ListModelsRequest) (*ListModelsResponse, error) {
-	availableModels, err := s.modelManager.listAvailableModels(ctx)
+func (s *OCSGrpcServer) ListModels(ctx context.Context, req *ocsv1.ListModelsRequest) (*ocsv1.ListModelsResponse, error) {
+	availableModels, err := s.modelManager.ListAvailableModels(ctx)
 	if err != nil {
 		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to list models: %v", err))
 	}
 
+	available := make([]*ocsv1.AvailableModel, 0, len(availableModels.Models))
+	for _, model := range availableModels.Models {
+		available = append(available, &ocsv1.AvailableModel{
+			Name:       model.Name,
+			Size:       model.Size,
+			ModifiedAt: model.ModifiedAt.Format(time.RFC3339),
+		})
+	}
+
 	loadedModels := s.modelManager.GetLoadedModels()
-	loaded := make([]*ModelInfo, 0, len(loadedModels))
+	loaded := make([]*ocsv1.ModelInfo, 0, len(loadedModels))
 	for _, model := range loadedModels {
-		loaded = append(loaded, &ModelInfo{
+		loaded = append(loaded, &ocsv1.ModelInfo{
 			Name:           model.Name,
 			Size:           model.Size,
 			LoadedAt:       model.LoadedAt.Format(time.RFC3339),
@@ -119,75 +193,56 @@ func (s *OCSGrpcServer) ListModels(ctx context.Context, req *ListModelsRequest)
 		})
 	}
 
-	return &ListModelsResponse{
-		Available: availableModels.Models,
+	return &ocsv1.ListModelsResponse{
+		Available: available,
 		Loaded:    loaded,
 	}, nil
 }
 
 // CreateSession creates a new session
-func (s *OCSGrpcServer) CreateSession(ctx context.Context, req *CreateSessionRequest) (*CreateSessionResponse, error) {
-	session, err := s.sessionManager.CreateSession(ctx, req.UserId, req.ModelName, &managers.SessionSettings{
-		MaxTokens:         int(req.Settings.MaxTokens),
-		Temperature:       req.Settings.Temperature,
-		TopP:              req.Settings.TopP,
-		RepetitionPenalty: req.Settings.RepetitionPenalty,
-		ContextWindow:     int(req.Settings.ContextWindow),
-		AutoSave:          req.Settings.AutoSave,
-		PersistMemory:     req.Settings.PersistMemory,
-		EnableTools:       req.Settings.EnableTools,
-		EnableCodeExec:    req.Settings.EnableCodeExec,
-	})
+func (s *OCSGrpcServer) CreateSession(ctx context.Context, req *ocsv1.CreateSessionRequest) (*ocsv1.CreateSessionResponse, error) {
+	var settings *managers.SessionSettings
+	if req.Settings != nil {
+		settings = &managers.SessionSettings{
+			MaxTokens:         int(req.Settings.MaxTokens),
+			Temperature:       req.Settings.Temperature,
+			TopP:              req.Settings.TopP,
+			RepetitionPenalty: req.Settings.RepetitionPenalty,
+			ContextWindow:     int(req.Settings.ContextWindow),
+			AutoSave:          req.Settings.AutoSave,
+			PersistMemory:     req.Settings.PersistMemory,
+			EnableTools:       req.Settings.EnableTools,
+			EnableCodeExec:    req.Settings.EnableCodeExec,
+		}
+	}
+
+	session, err := s.sessionManager.CreateSession(ctx, req.UserId, req.ModelName, settings)
 	if err != nil {
 		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to create session: %v", err))
 	}
 
-	return &CreateSessionResponse{
-		Session: &Session{
-			Id:           session.ID,
-			UserId:       session.UserID,
-			Title:        session.Title,
-			CreatedAt:    session.CreatedAt.Format(time.RFC3339),
-			LastActivity: session.LastActivity.Format(time.RFC3339),
-			MessageCount: int32(session.MessageCount),
-			TokensUsed:   session.TokensUsed,
-			ModelName:    session.ModelName,
-			IsActive:     session.IsActive,
-		},
-	}, nil
+	return &ocsv1.CreateSessionResponse{Session: toProtoSession(session)}, nil
 }
 
 // GetSession retrieves a session
-func (s *OCSGrpcServer) GetSession(ctx context.Context, req *GetSessionRequest) (*GetSessionResponse, error) {
+func (s *OCSGrpcServer) GetSession(ctx context.Context, req *ocsv1.GetSessionRequest) (*ocsv1.GetSessionResponse, error) {
 	session, exists := s.sessionManager.GetSession(req.SessionId)
 	if !exists {
 		return nil, status.Error(codes.NotFound, "session not found")
 	}
 
-	return &GetSessionResponse{
-		Session: &Session{
-			Id:           session.ID,
-			UserId:       session.UserID,
-			Title:        session.Title,
-			CreatedAt:    session.CreatedAt.Format(time.RFC3339),
-			LastActivity: session.LastActivity.Format(time.RFC3339),
-			MessageCount: int32(session.MessageCount),
-			TokensUsed:   session.TokensUsed,
-			ModelName:    session.ModelName,
-			IsActive:     session.IsActive,
-		},
-	}, nil
+	return &ocsv1.GetSessionResponse{Session: toProtoSession(session)}, nil
 }
 
 // AddMessage adds a message to a session
-func (s *OCSGrpcServer) AddMessage(ctx context.Context, req *AddMessageRequest) (*AddMessageResponse, error) {
-	message, err := s.sessionManager.AddMessage(req.SessionId, req.Role, req.Content, req.Metadata)
+func (s *OCSGrpcServer) AddMessage(ctx context.Context, req *ocsv1.AddMessageRequest) (*ocsv1.AddMessageResponse, error) {
+	message, err := s.sessionManager.AddMessage(req.SessionId, req.Role, req.Content, req.Metadata.AsMap())
 	if err != nil {
 		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to add message: %v", err))
 	}
 
-	return &AddMessageResponse{
-		Message: &Message{
+	return &ocsv1.AddMessageResponse{
+		Message: &ocsv1.Message{
 			Id:        message.ID,
 			Role:      message.Role,
 			Content:   message.Content,
@@ -199,14 +254,116 @@ func (s *OCSGrpcServer) AddMessage(ctx context.Context, req *AddMessageRequest)
 }
 
 // ProcessInference processes an inference request
-func (s *OCSGrpcServer) ProcessInference(ctx context.Context, req *ProcessInferenceRequest) (*ProcessInferenceResponse, error) {
+func (s *OCSGrpcServer) ProcessInference(ctx context.Context, req *ocsv1.ProcessInferenceRequest) (*ocsv1.ProcessInferenceResponse, error) {
+	inferenceReq, err := buildInferenceRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.inferenceManager.ProcessInference(ctx, inferenceReq)
+	if err != nil {
+		return nil, inferenceStatus(err)
+	}
+
+	return &ocsv1.ProcessInferenceResponse{
+		RequestId:        inferenceReq.ID,
+		Content:          result.Content,
+		ModelUsed:        result.ModelUsed,
+		FinishReason:     result.FinishReason,
+		Usage:            toProtoUsage(result.Usage),
+		PerformanceStats: toProtoPerformanceStats(result.PerformanceStats),
+	}, nil
+}
+
+// StreamInference streams inference chunks as they are generated
+func (s *OCSGrpcServer) StreamInference(req *ocsv1.ProcessInferenceRequest, stream ocsv1.OCS_StreamInferenceServer) error {
+	inferenceReq, err := buildInferenceRequest(req)
+	if err != nil {
+		return err
+	}
+
+	streamChan, err := s.inferenceManager.ProcessStreamingInference(stream.Context(), inferenceReq)
+	if err != nil {
+		return inferenceStatus(err)
+	}
+
+	for chunk := range streamChan {
+		if err := stream.Send(&ocsv1.StreamInferenceResponse{
+			RequestId:        inferenceReq.ID,
+			Content:          chunk.Content,
+			Done:             chunk.Done,
+			TokenCount:       int32(chunk.TokenCount),
+			Error:            chunk.Error,
+			Usage:            toProtoUsage(chunk.Usage),
+			PerformanceStats: toProtoPerformanceStats(chunk.PerformanceStats),
+		}); err != nil {
+			s.inferenceManager.CancelInference(inferenceReq.ID)
+			for range streamChan {
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExecuteTool processes a tool call
+func (s *OCSGrpcServer) ExecuteTool(ctx context.Context, req *ocsv1.ExecuteToolRequest) (*ocsv1.ExecuteToolResponse, error) {
+	if _, ok := s.toolRegistry.Get(req.Name); !ok {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("unknown tool: %s", req.Name))
+	}
+
+	// The call runs in the authenticated caller's session; req.SessionId is ignored
+	toolCall := &types.ToolCall{
+		Name:      req.Name,
+		Arguments: req.Arguments.AsMap(),
+	}
+
+	result, err := s.toolRegistry.Execute(ctx, toolCall)
+	if errors.Is(err, tools.ErrToolPermission) {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, fmt.Sprintf("tool execution failed: %v", err))
+	}
+
+	return &ocsv1.ExecuteToolResponse{
+		Success: result.Success,
+		Content: result.Content,
+		Error:   result.Error,
+	}, nil
+}
+
+// inferenceStatus maps an inference error to a gRPC status; a full queue is retryable
+func inferenceStatus(err error) error {
+	var queueErr *managers.QueueFullError
+	if errors.As(err, &queueErr) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return status.Error(codes.Internal, fmt.Sprintf("inference failed: %v", err))
+}
+
+// buildInferenceRequest converts a proto inference request to the manager format
+func buildInferenceRequest(req *ocsv1.ProcessInferenceRequest) (*managers.InferenceRequest, error) {
+	// Struct fields follow InferenceParameters' JSON names
+	parameters := &managers.InferenceParameters{}
+	if req.Parameters != nil {
+		data, err := json.Marshal(req.Parameters.AsMap())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid parameters: %v", err))
+		}
+		if err := json.Unmarshal(data, parameters); err != nil {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid parameters: %v", err))
+		}
+	}
+
 	inferenceReq := &managers.InferenceRequest{
-		ID:         fmt.Sprintf("%s_%s", req.InferenceType, req.UserId),
+		ID:         fmt.Sprintf("%s_%s_%d", req.InferenceType, req.UserId, time.Now().UnixNano()),
 		UserID:     req.UserId,
 		SessionID:  req.SessionId,
 		ModelName:  req.ModelName,
 		Messages:   []managers.Message{{Role: "user", Content: req.Prompt}},
-		Parameters: &managers.InferenceParameters{Parameters: req.Parameters},
+		Parameters: parameters,
 	}
 
 	switch req.InferenceType {
@@ -220,48 +377,45 @@ func (s *OCSGrpcServer) ProcessInference(ctx context.Context, req *ProcessInfere
 		return nil, status.Error(codes.InvalidArgument, "invalid inference type")
 	}
 
-	result, err := s.inferenceManager.ProcessInference(ctx, inferenceReq)
-	if err != nil {
-		return nil, status.Error(codes.Internal, fmt.Sprintf("inference failed: %v", err))
-	}
-
-	return &ProcessInferenceResponse{
-		Content: result.Content,
-		Usage: &TokenUsage{
-			PromptTokens:     result.Usage.PromptTokens,
-			CompletionTokens: result.Usage.CompletionTokens,
-			TotalTokens:      result.Usage.TotalTokens,
-		},
-	}, nil
+	return inferenceReq, nil
 }
 
-// ExecuteTool processes a tool call
-func (s *OCSGrpcServer) ExecuteTool(ctx context.Context, req *ExecuteToolRequest) (*ExecuteToolResponse, error) {
-	toolCall := &managers.ToolCall{
-		Name:      req.Name,
-		Arguments: req.Arguments,
-	}
-
-	var result *managers.ToolResult
-	var err error
-	switch {
-	case strings.HasPrefix(req.Name, "execute_code") || strings.HasPrefix(req.Name, "format_code") || strings.HasPrefix(req.Name, "validate_code"):
-		result, err = s.codeTool.Execute(ctx, toolCall)
-	case strings.HasPrefix(req.Name, "read_file") || strings.HasPrefix(req.Name, "write_file") || strings.HasPrefix(req.Name, "delete_file") || strings.HasPrefix(req.Name, "list_files"):
-		result, err = s.fileTool.Execute(ctx, toolCall)
-	case strings.HasPrefix(req.Name, "search_memory") || strings.HasPrefix(req.Name, "search_conversation"):
-		result, err = s.searchTool.Execute(ctx, toolCall)
-	default:
-		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("unknown tool: %s", req.Name))
+// toProtoSession converts a session to its proto form
+func toProtoSession(session *managers.Session) *ocsv1.Session {
+	return &ocsv1.Session{
+		Id:           session.ID,
+		UserId:       session.UserID,
+		Title:        session.Title,
+		CreatedAt:    session.CreatedAt.Format(time.RFC3339),
+		LastActivity: session.LastActivity.Format(time.RFC3339),
+		MessageCount: int32(session.MessageCount),
+		TokensUsed:   session.TokensUsed,
+		ModelName:    session.ModelName,
+		IsActive:     session.IsActive,
 	}
+}
 
-	if err != nil {
-		return nil, status.Error(codes.Internal, fmt.Sprintf("tool execution failed: %v", err))
+// toProtoUsage converts token usage to its proto form
+func toProtoUsage(usage *managers.TokenUsage) *ocsv1.TokenUsage {
+	if usage == nil {
+		return nil
+	}
+	return &ocsv1.TokenUsage{
+		InputTokens:  int32(usage.InputTokens),
+		OutputTokens: int32(usage.OutputTokens),
+		TotalTokens:  int32(usage.TotalTokens),
+		CachedTokens: int32(usage.CachedTokens),
 	}
+}
 
-	return &ExecuteToolResponse{
-		Success: result.Success,
-		Content: result.Content,
-		Error:   result.Error,
-	}, nil
+// toProtoPerformanceStats converts performance stats to their proto form
+func toProtoPerformanceStats(stats *managers.PerformanceStats) *ocsv1.PerformanceStats {
+	if stats == nil {
+		return nil
+	}
+	return &ocsv1.PerformanceStats{
+		ProcessingTimeMs: stats.ProcessingTime.Milliseconds(),
+		FirstTokenTimeMs: stats.FirstTokenTime.Milliseconds(),
+		TokensPerSecond:  stats.TokensPerSecond,
+	}
 }
@@ -0,0 +1,457 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = api/grpc-api_test.go
+
+package api
+
+import (
+	// stdlib
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	// third-party
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	// internal
+	ocsv1 "ocs/api/proto/ocs/v1"
+	"ocs/managers"
+	"ocs/src/tools"
+	"ocs/src/types"
+)
+
+const testModel = "llama3.2"
+
+// testModelsYAML is the models.yaml the suite runs with
+const testModelsYAML = `- name: "llama3.2"
+  specialization: "chat"
+  context_window: 8192
+  priority: 1
+`
+
+// testLimitsYAML gives every user room for the suite's requests
+const testLimitsYAML = `max_requests_per_minute: 600
+max_tokens_per_request: 4096
+token_budget_per_user: 100000
+reset_interval_hours: 24
+max_concurrent_per_model: 2
+max_queue_size: 16
+`
+
+// newFakeOllama serves the Ollama endpoints OCS uses; chat replies echo the last user
+// message, or call the first offered tool with {"city":"Paris"} when it asks about the
+// weather, and embeddings are [prompt length, 1]
+func newFakeOllama(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"models":[{"name":%q,"size":2019393189,"modified_at":"2026-01-02T15:04:05Z"}]}`, testModel)
+	})
+	mux.HandleFunc("GET /api/ps", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"models":[]}`)
+	})
+	mux.HandleFunc("POST /api/generate", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"done":true}`)
+	})
+	mux.HandleFunc("POST /api/chat", func(w http.ResponseWriter, r *http.Request) {
+		var req managers.OllamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		prompt := ""
+		for _, msg := range req.Messages {
+			if msg.Role == "user" {
+				prompt = msg.Content
+			}
+		}
+
+		encoder := json.NewEncoder(w)
+		if len(req.Tools) > 0 && strings.Contains(prompt, "weather") {
+			call := managers.OllamaToolCall{Function: &managers.FunctionCall{Name: req.Tools[0].Function.Name, Arguments: map[string]interface{}{"city": "Paris"}}}
+			encoder.Encode(&managers.OllamaResponse{
+				Model:           req.Model,
+				Message:         &managers.OllamaMessage{Role: "assistant", ToolCalls: []managers.OllamaToolCall{call}},
+				Done:            true,
+				PromptEvalCount: 11,
+				EvalCount:       4,
+			})
+			return
+		}
+		if !req.Stream {
+			encoder.Encode(&managers.OllamaResponse{
+				Model:           req.Model,
+				Message:         &managers.OllamaMessage{Role: "assistant", Content: "echo: " + prompt},
+				Done:            true,
+				PromptEvalCount: 7,
+				EvalCount:       3,
+				EvalDuration:    int64(30 * time.Millisecond),
+			})
+			return
+		}
+		for _, word := range append([]string{"echo:"}, strings.Fields(prompt)...) {
+			encoder.Encode(&managers.OllamaResponse{
+				Model:   req.Model,
+				Message: &managers.OllamaMessage{Role: "assistant", Content: word + " "},
+			})
+			w.(http.Flusher).Flush()
+		}
+		encoder.Encode(&managers.OllamaResponse{
+			Model:           req.Model,
+			Message:         &managers.OllamaMessage{Role: "assistant"},
+			Done:            true,
+			PromptEvalCount: 7,
+			EvalCount:       3,
+		})
+	})
+	mux.HandleFunc("POST /api/embeddings", func(w http.ResponseWriter, r *http.Request) {
+		var req managers.OllamaEmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(&managers.OllamaEmbeddingResponse{Embedding: []float64{float64(len(req.Prompt)), 1}})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// loadTestConfig points the shared config manager at configs written for the test
+func loadTestConfig(t *testing.T) *managers.ConfigManager {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "configs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	configManager := managers.GetConfigManager()
+	for _, config := range []struct {
+		path   string
+		body   string
+		target interface{}
+	}{
+		{"configs/models.yaml", testModelsYAML, &[]managers.ModelConfig{}},
+		{"configs/limits.yaml", testLimitsYAML, &managers.LimitsConfig{}},
+	} {
+		if err := os.WriteFile(config.path, []byte(config.body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := configManager.LoadConfig(config.path, config.target); err != nil {
+			t.Fatalf("LoadConfig(%s): %v", config.path, err)
+		}
+	}
+	return configManager
+}
+
+// grpcSuite is an OCS gRPC server on an in-memory listener; ctx authenticates as alice
+type grpcSuite struct {
+	ctx            context.Context
+	conn           *grpc.ClientConn
+	client         ocsv1.OCSClient
+	sessionManager *managers.SessionManager
+}
+
+// testAuthenticator accepts "<user>:<permission>,..." tokens
+func testAuthenticator(token string) (*managers.Caller, error) {
+	userID, permissions, _ := strings.Cut(token, ":")
+	if userID == "" {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return &managers.Caller{UserID: userID, SessionID: userID + "-session", Permissions: strings.Split(permissions, ",")}, nil
+}
+
+// withToken returns a context sending token as the bearer credential
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func newGRPCSuite(t *testing.T) *grpcSuite {
+	t.Helper()
+	ollama := newFakeOllama(t)
+	configManager := loadTestConfig(t)
+
+	modelManager := managers.NewModelManager(ollama.URL, configManager)
+	memoryManager := managers.NewMemoryManager(configManager)
+	sessionManager := managers.NewSessionManager(configManager, nil, memoryManager)
+	tokenManager := managers.NewTokenManager(configManager)
+	inferenceManager := managers.NewInferenceManager(configManager, modelManager, tokenManager, memoryManager, sessionManager, ollama.URL)
+	t.Cleanup(func() {
+		memoryManager.Shutdown(context.Background())
+		sessionManager.Shutdown(context.Background())
+	})
+
+	suite := &grpcSuite{ctx: withToken("alice:chat,tools"), sessionManager: sessionManager}
+	registry := tools.NewToolRegistry(sessionManager)
+	if err := registry.Register(echoTool{}); err != nil {
+		t.Fatal(err)
+	}
+	server := NewOCSGrpcServer(configManager, modelManager, sessionManager, inferenceManager, tokenManager, nil, nil, registry)
+	server.SetAuthenticator(testAuthenticator)
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := server.newServer()
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	suite.conn = conn
+	suite.client = ocsv1.NewOCSClient(conn)
+	return suite
+}
+
+func wantCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != code {
+		t.Fatalf("status = %v (%v), want %v", got, err, code)
+	}
+}
+
+func TestGRPCListModels(t *testing.T) {
+	suite := newGRPCSuite(t)
+
+	resp, err := suite.client.ListModels(suite.ctx, &ocsv1.ListModelsRequest{})
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if len(resp.Available) != 1 || resp.Available[0].Name != testModel {
+		t.Fatalf("available = %v", resp.Available)
+	}
+	if resp.Available[0].ModifiedAt != "2026-01-02T15:04:05Z" {
+		t.Errorf("modified_at = %q", resp.Available[0].ModifiedAt)
+	}
+}
+
+func TestGRPCSessions(t *testing.T) {
+	suite := newGRPCSuite(t)
+	ctx := suite.ctx
+
+	created, err := suite.client.CreateSession(ctx, &ocsv1.CreateSessionRequest{
+		UserId:    "alice",
+		ModelName: testModel,
+		Settings:  &ocsv1.SessionSettings{MaxTokens: 512, Temperature: 0.2},
+	})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if created.Session.UserId != "alice" || created.Session.ModelName != testModel {
+		t.Fatalf("session = %v", created.Session)
+	}
+
+	extra, _ := structpb.NewStruct(map[string]interface{}{"source": "test"})
+	added, err := suite.client.AddMessage(ctx, &ocsv1.AddMessageRequest{
+		SessionId: created.Session.Id,
+		Role:      "user",
+		Content:   "hello there",
+		Metadata:  extra,
+	})
+	if err != nil {
+		t.Fatalf("AddMessage: %v", err)
+	}
+	if added.Message.Content != "hello there" || added.Message.Role != "user" {
+		t.Errorf("message = %v", added.Message)
+	}
+
+	got, err := suite.client.GetSession(ctx, &ocsv1.GetSessionRequest{SessionId: created.Session.Id})
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if got.Session.Id != created.Session.Id || got.Session.MessageCount == 0 {
+		t.Errorf("session after AddMessage = %v", got.Session)
+	}
+
+	_, err = suite.client.GetSession(ctx, &ocsv1.GetSessionRequest{SessionId: "missing"})
+	wantCode(t, err, codes.NotFound)
+}
+
+func TestGRPCProcessInference(t *testing.T) {
+	suite := newGRPCSuite(t)
+
+	resp, err := suite.client.ProcessInference(suite.ctx, &ocsv1.ProcessInferenceRequest{
+		UserId:        "alice",
+		ModelName:     testModel,
+		Prompt:        "ping",
+		InferenceType: "chat",
+	})
+	if err != nil {
+		t.Fatalf("ProcessInference: %v", err)
+	}
+	if resp.Content != "echo: ping" || resp.ModelUsed != testModel || resp.FinishReason != "stop" {
+		t.Errorf("response = %v", resp)
+	}
+	if resp.Usage.GetInputTokens() != 7 || resp.Usage.GetOutputTokens() != 3 || resp.Usage.GetTotalTokens() != 10 {
+		t.Errorf("usage = %v", resp.Usage)
+	}
+	if resp.RequestId == "" {
+		t.Error("missing request id")
+	}
+
+	_, err = suite.client.ProcessInference(suite.ctx, &ocsv1.ProcessInferenceRequest{
+		UserId:        "alice",
+		ModelName:     testModel,
+		Prompt:        "ping",
+		InferenceType: "poetry",
+	})
+	wantCode(t, err, codes.InvalidArgument)
+}
+
+func TestGRPCStreamInference(t *testing.T) {
+	suite := newGRPCSuite(t)
+
+	parameters, _ := structpb.NewStruct(map[string]interface{}{"temperature": 0.3})
+	stream, err := suite.client.StreamInference(suite.ctx, &ocsv1.ProcessInferenceRequest{
+		UserId:        "alice",
+		ModelName:     testModel,
+		Prompt:        "stream these words",
+		InferenceType: "chat",
+		Parameters:    parameters,
+	})
+	if err != nil {
+		t.Fatalf("StreamInference: %v", err)
+	}
+
+	var content strings.Builder
+	var last *ocsv1.StreamInferenceResponse
+	chunks := 0
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		if chunk.Error != "" {
+			t.Fatalf("stream error: %s", chunk.Error)
+		}
+		content.WriteString(chunk.Content)
+		last = chunk
+		chunks++
+	}
+
+	if got := strings.TrimSpace(content.String()); got != "echo: stream these words" {
+		t.Errorf("content = %q", got)
+	}
+	if chunks < 4 {
+		t.Errorf("got %d chunks, want one per word", chunks)
+	}
+	if last == nil || !last.Done {
+		t.Fatalf("last chunk = %v, want done", last)
+	}
+	if last.Usage.GetTotalTokens() != 10 {
+		t.Errorf("final usage = %v", last.Usage)
+	}
+}
+
+func TestGRPCHealthAndReflection(t *testing.T) {
+	suite := newGRPCSuite(t)
+	ctx := context.Background()
+
+	health := healthpb.NewHealthClient(suite.conn)
+	resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: "ocs.v1.OCS"})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("status = %v", resp.Status)
+	}
+	_, err = health.Check(ctx, &healthpb.HealthCheckRequest{Service: "ocs.v0.Missing"})
+	wantCode(t, err, codes.NotFound)
+
+	stream, err := reflectionpb.NewServerReflectionClient(suite.conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatalf("ServerReflectionInfo: %v", err)
+	}
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	reply, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	var services []string
+	for _, service := range reply.GetListServicesResponse().GetService() {
+		services = append(services, service.Name)
+	}
+	if listed := strings.Join(services, ","); !strings.Contains(listed, "ocs.v1.OCS") || !strings.Contains(listed, "grpc.health.v1.Health") {
+		t.Errorf("services = %v", services)
+	}
+	stream.CloseSend()
+}
+
+// echoTool is a tool registered under every scope that echoes the arguments it receives
+type echoTool struct{}
+
+func (echoTool) ToolSpecs() []*tools.ToolSpec {
+	schema := map[string]interface{}{"type": "object", "properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}}}
+	return []*tools.ToolSpec{
+		{Name: "echo", Scope: tools.ScopeTools, Parameters: schema},
+		{Name: "run", Scope: tools.ScopeExecute, Parameters: schema},
+	}
+}
+
+func (echoTool) Execute(ctx context.Context, toolCall *types.ToolCall) (*types.ToolResult, error) {
+	data, err := json.Marshal(toolCall.Arguments)
+	return &types.ToolResult{Success: true, Content: string(data)}, err
+}
+
+func TestGRPCAuthentication(t *testing.T) {
+	suite := newGRPCSuite(t)
+	arguments, _ := structpb.NewStruct(map[string]interface{}{"text": "hi"})
+
+	// OCS RPCs need a token; health stays open
+	_, err := suite.client.ListModels(context.Background(), &ocsv1.ListModelsRequest{})
+	wantCode(t, err, codes.Unauthenticated)
+	_, err = suite.client.ListModels(withToken(":chat"), &ocsv1.ListModelsRequest{})
+	wantCode(t, err, codes.Unauthenticated)
+	if _, err := healthpb.NewHealthClient(suite.conn).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("health without token: %v", err)
+	}
+	stream, err := suite.client.StreamInference(context.Background(), &ocsv1.ProcessInferenceRequest{InferenceType: "chat", Prompt: "hi"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	wantCode(t, err, codes.Unauthenticated)
+
+	// Tool calls need the tool's scope and run in the caller's session
+	resp, err := suite.client.ExecuteTool(suite.ctx, &ocsv1.ExecuteToolRequest{Name: "echo", Arguments: arguments, SessionId: "bob-session"})
+	if err != nil || !resp.Success || !strings.Contains(resp.Content, `"session_id":"alice-session","text":"hi","user_id":"alice"`) {
+		t.Errorf("echo = %v, %v", resp, err)
+	}
+	_, err = suite.client.ExecuteTool(suite.ctx, &ocsv1.ExecuteToolRequest{Name: "run", Arguments: arguments})
+	wantCode(t, err, codes.PermissionDenied)
+	if _, err := suite.client.ExecuteTool(withToken("alice:execute"), &ocsv1.ExecuteToolRequest{Name: "run", Arguments: arguments}); err != nil {
+		t.Errorf("run with execute permission: %v", err)
+	}
+}
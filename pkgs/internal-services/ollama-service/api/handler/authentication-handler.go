@@ -70,9 +70,10 @@ func (ah *AuthenticationHandler) Authenticate(w http.ResponseWriter, r *http.Req
 
 	// Generate JWT
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id":    req.UserID,
-		"session_id": session.ID,
-		"exp":        time.Now().Add(24 * time.Hour).Unix(),
+		"user_id":     req.UserID,
+		"session_id":  session.ID,
+		"permissions": ah.tokenPermissions(),
+		"exp":         time.Now().Add(24 * time.Hour).Unix(),
 	})
 	tokenString, err := token.SignedString(ah.secretKey)
 	if err != nil {
@@ -107,42 +108,82 @@ func (ah *AuthenticationHandler) Middleware(next http.Handler) http.Handler {
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return ah.secretKey, nil
-		})
-
-		if err != nil || !token.Valid {
-			http.Error(w, "invalid token", http.StatusUnauthorized)
+		caller, err := ah.ValidateCaller(tokenString)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			http.Error(w, "invalid token claims", http.StatusUnauthorized)
-			return
-		}
+		// Add the caller, and user_id and session_id for older readers, to context
+		ctx := managers.WithCaller(r.Context(), caller)
+		ctx = context.WithValue(ctx, "user_id", caller.UserID)
+		ctx = context.WithValue(ctx, "session_id", caller.SessionID)
+		next.ServeHTTP(w, r.WithContext(ctx))
 
-		userID, ok := claims["user_id"].(string)
-		sessionID, ok2 := claims["session_id"].(string)
-		if !ok || !ok2 || userID == "" || sessionID == "" {
-			http.Error(w, "invalid token claims", http.StatusUnauthorized)
-			return
+		log.Info().Str("user_id", caller.UserID).Str("session_id", caller.SessionID).Msg("Authenticated request")
+	})
+}
+
+// tokenPermissions are the permissions granted to newly issued tokens
+func (ah *AuthenticationHandler) tokenPermissions() []string {
+	if serverConfig, err := ah.configManager.GetServerConfig(); err == nil && len(serverConfig.TokenPermissions) > 0 {
+		return serverConfig.TokenPermissions
+	}
+	return managers.DefaultPermissions
+}
+
+// ValidateToken checks a JWT issued by Authenticate and returns the user and session it names
+func (ah *AuthenticationHandler) ValidateToken(tokenString string) (userID, sessionID string, err error) {
+	caller, err := ah.ValidateCaller(tokenString)
+	if err != nil {
+		return "", "", err
+	}
+	return caller.UserID, caller.SessionID, nil
+}
+
+// ValidateCaller checks a JWT issued by Authenticate and returns the caller it names
+func (ah *AuthenticationHandler) ValidateCaller(tokenString string) (*managers.Caller, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
+		return ah.secretKey, nil
+	})
 
-		// Verify session
-		if _, exists := ah.sessionManager.GetSession(sessionID); !exists {
-			http.Error(w, "session not found", http.StatusUnauthorized)
-			return
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+
+	userID, ok := claims["user_id"].(string)
+	sessionID, ok2 := claims["session_id"].(string)
+	if !ok || !ok2 || userID == "" || sessionID == "" {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+
+	// Tokens issued before permissions were added hold the defaults
+	permissions := managers.DefaultPermissions
+	if granted, ok := claims["permissions"].([]interface{}); ok {
+		permissions = make([]string, 0, len(granted))
+		for _, permission := range granted {
+			if name, ok := permission.(string); ok {
+				permissions = append(permissions, name)
+			}
 		}
+	}
 
-		// Add user_id and session_id to context
-		ctx := context.WithValue(r.Context(), "user_id", userID)
-		ctx = context.WithValue(ctx, "session_id", sessionID)
-		next.ServeHTTP(w, r.WithContext(ctx))
+	// Verify session belongs to the token's user
+	session, exists := ah.sessionManager.GetSession(sessionID)
+	if !exists {
+		return nil, fmt.Errorf("session not found")
+	}
+	if session.UserID != userID {
+		return nil, fmt.Errorf("session does not belong to user")
+	}
 
-		log.Info().Str("user_id", userID).Str("session_id", sessionID).Msg("Authenticated request")
-	})
+	return &managers.Caller{UserID: userID, SessionID: sessionID, Permissions: permissions}, nil
 }
@@ -10,7 +10,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 
 	// third-party
 	"github.com/gorilla/websocket"
@@ -19,15 +18,14 @@ import (
 	// internal
 	"ocs/managers"
 	"ocs/src/tools"
+	"ocs/src/types"
 )
 
 // WebSocketHandler handles WebSocket connections
 type WebSocketHandler struct {
 	wsManager        *managers.WebSocketManager
 	inferenceManager *managers.InferenceManager
-	codeTool         *tools.CodeTool
-	fileTool         *tools.FileTool
-	searchTool       *tools.SearchTool
+	toolRegistry     *tools.ToolRegistry
 	upgrader         websocket.Upgrader
 }
 
@@ -35,16 +33,12 @@ type WebSocketHandler struct {
 func NewWebSocketHandler(
 	wsManager *managers.WebSocketManager,
 	inferenceManager *managers.InferenceManager,
-	codeTool *tools.CodeTool,
-	fileTool *tools.FileTool,
-	searchTool *tools.SearchTool,
+	toolRegistry *tools.ToolRegistry,
 ) *WebSocketHandler {
 	return &WebSocketHandler{
 		wsManager:        wsManager,
 		inferenceManager: inferenceManager,
-		codeTool:         codeTool,
-		fileTool:         fileTool,
-		searchTool:       searchTool,
+		toolRegistry:     toolRegistry,
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
@@ -144,26 +138,13 @@ func (wh *WebSocketHandler) handleInferenceMessage(ctx context.Context, client *
 
 // handleToolCallMessage processes tool call requests
 func (wh *WebSocketHandler) handleToolCallMessage(ctx context.Context, client *managers.ClientConnection, msg *managers.WebSocketMessage) {
-	var toolCall managers.ToolCall
+	var toolCall types.ToolCall
 	if err := json.Unmarshal(msg.Payload, &toolCall); err != nil {
 		wh.sendError(client, "invalid payload")
 		return
 	}
-
-	var result *managers.ToolResult
-	var err error
-	switch {
-	case strings.HasPrefix(toolCall.Name, "execute_code") || strings.HasPrefix(toolCall.Name, "format_code") || strings.HasPrefix(toolCall.Name, "validate_code"):
-		result, err = wh.codeTool.Execute(ctx, &toolCall)
-	case strings.HasPrefix(toolCall.Name, "read_file") || strings.HasPrefix(toolCall.Name, "write_file") || strings.HasPrefix(toolCall.Name, "delete_file") || strings.HasPrefix(toolCall.Name, "list_files"):
-		result, err = wh.fileTool.Execute(ctx, &toolCall)
-	case strings.HasPrefix(toolCall.Name, "search_memory") || strings.HasPrefix(toolCall.Name, "search_conversation"):
-		result, err = wh.searchTool.Execute(ctx, &toolCall)
-	default:
-		wh.sendError(client, fmt.Sprintf("unknown tool: %s", toolCall.Name))
-		return
-	}
-
+	// Execute checks the caller the middleware put on ctx and binds the call to its session
+	result, err := wh.toolRegistry.Execute(ctx, &toolCall)
 	if err != nil {
 		wh.sendError(client, fmt.Sprintf("tool execution failed: %v", err))
 		return
@@ -185,6 +166,15 @@ func (wh *WebSocketHandler) handleFileOperationMessage(ctx context.Context, clie
 		wh.sendError(client, "invalid payload")
 		return
 	}
+	// Scope the operation to the connection, never to what the payload claims
+	fileOp.SessionID = client.SessionID
+	fileOp.UserID = client.UserID
+	if fileOp.Operation == "write" || fileOp.Operation == "delete" {
+		if caller, ok := managers.CallerFromContext(ctx); !ok || !caller.Can(managers.ToolScopeFiles) {
+			wh.sendError(client, fmt.Sprintf("file %s requires %q permission", fileOp.Operation, managers.ToolScopeFiles))
+			return
+		}
+	}
 
 	result, err := wh.wsManager.diskManager.HandleFileOperation(ctx, &fileOp)
 	if err != nil {
@@ -0,0 +1,579 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = api/openai-api.go
+
+package api
+
+import (
+	// stdlib
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	// thrid-party
+	"github.com/rs/zerolog/log"
+
+	// internal
+	"ocs/managers"
+)
+
+// openAIChatRequest mirrors the OpenAI chat completions request body
+type openAIChatRequest struct {
+	Model               string                    `json:"model"`
+	Messages            []openAIMessage           `json:"messages"`
+	Stream              bool                      `json:"stream"`
+	StreamOptions       *openAIStreamOptions      `json:"stream_options,omitempty"`
+	Temperature         float64                   `json:"temperature,omitempty"`
+	TopP                float64                   `json:"top_p,omitempty"`
+	MaxTokens           int                       `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int                       `json:"max_completion_tokens,omitempty"`
+	Stop                interface{}               `json:"stop,omitempty"`
+	Seed                int                       `json:"seed,omitempty"`
+	Tools               []managers.ToolDefinition `json:"tools,omitempty"`
+	ResponseFormat      *openAIResponseFormat     `json:"response_format,omitempty"`
+	User                string                    `json:"user,omitempty"`
+}
+
+// openAIResponseFormat requests JSON mode or output matching a JSON Schema
+type openAIResponseFormat struct {
+	Type       string `json:"type"` // text, json_object or json_schema
+	JSONSchema *struct {
+		Name   string                 `json:"name"`
+		Schema map[string]interface{} `json:"schema"`
+	} `json:"json_schema,omitempty"`
+}
+
+// openAIStreamOptions controls extra chunks in streaming responses
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIMessage is a chat message in OpenAI format
+type openAIMessage struct {
+	Role       string           `json:"role,omitempty"`
+	Content    interface{}      `json:"content"`
+	Name       string           `json:"name,omitempty"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// openAIToolCall is a tool call in OpenAI format; arguments are a JSON string
+type openAIToolCall struct {
+	Index    *int               `json:"index,omitempty"`
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+// openAIFunctionCall holds the called function name and encoded arguments
+type openAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// openAIChatResponse is a chat.completion or chat.completion.chunk object
+type openAIChatResponse struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []openAIChoice `json:"choices"`
+	Usage   *openAIUsage   `json:"usage,omitempty"`
+}
+
+// openAIChoice holds a full message or a streaming delta
+type openAIChoice struct {
+	Index        int            `json:"index"`
+	Message      *openAIMessage `json:"message,omitempty"`
+	Delta        *openAIMessage `json:"delta,omitempty"`
+	FinishReason *string        `json:"finish_reason"`
+}
+
+// openAIUsage is the OpenAI usage block
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// openAIEmbeddingRequest mirrors the OpenAI embeddings request body
+type openAIEmbeddingRequest struct {
+	Model string      `json:"model"`
+	Input interface{} `json:"input"`
+	User  string      `json:"user,omitempty"`
+}
+
+// openAIEmbedding is a single embedding in a list response
+type openAIEmbedding struct {
+	Object    string    `json:"object"`
+	Index     int       `json:"index"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// openAIModel is a model entry in the models list
+type openAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// handleOpenAIChatCompletions serves /v1/chat/completions on top of the inference manager
+func (api *RESTAPI) handleOpenAIChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req openAIChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "invalid request body")
+		return
+	}
+	if len(req.Messages) == 0 {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "messages is required")
+		return
+	}
+
+	caller, ok := api.openAICaller(w, r)
+	if !ok {
+		return
+	}
+	inferenceReq, err := buildOpenAIInferenceRequest(r, &req, caller)
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	if req.Stream {
+		api.streamOpenAIChatCompletion(w, r, &req, inferenceReq)
+		return
+	}
+
+	result, err := api.inferenceManager.ProcessInference(r.Context(), inferenceReq)
+	if err != nil {
+		setRetryAfter(w, err)
+		writeOpenAIError(w, openAIErrorStatus(err), "server_error", fmt.Sprintf("inference failed: %v", err))
+		return
+	}
+
+	finishReason := result.FinishReason
+	response := &openAIChatResponse{
+		ID:      inferenceReq.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   result.ModelUsed,
+		Choices: []openAIChoice{{
+			Message: &openAIMessage{
+				Role:      "assistant",
+				Content:   result.Content,
+				ToolCalls: toOpenAIToolCalls(result.ToolCalls),
+			},
+			FinishReason: &finishReason,
+		}},
+		Usage: toOpenAIUsage(result.Usage),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode chat completion response")
+	}
+}
+
+// streamOpenAIChatCompletion relays stream chunks as chat.completion.chunk SSE events
+func (api *RESTAPI) streamOpenAIChatCompletion(w http.ResponseWriter, r *http.Request, req *openAIChatRequest, inferenceReq *managers.InferenceRequest) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeOpenAIError(w, http.StatusInternalServerError, "server_error", "streaming not supported")
+		return
+	}
+
+	streamChan, err := api.inferenceManager.ProcessStreamingInference(r.Context(), inferenceReq)
+	if err != nil {
+		setRetryAfter(w, err)
+		writeOpenAIError(w, openAIErrorStatus(err), "server_error", fmt.Sprintf("inference failed: %v", err))
+		return
+	}
+
+	// Streams outlive the server write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	created := time.Now().Unix()
+	send := func(choices []openAIChoice, usage *openAIUsage) bool {
+		data, err := json.Marshal(&openAIChatResponse{
+			ID:      inferenceReq.ID,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   inferenceReq.ModelName,
+			Choices: choices,
+			Usage:   usage,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal chat completion chunk")
+			return false
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	if !send([]openAIChoice{{Delta: &openAIMessage{Role: "assistant", Content: ""}}}, nil) {
+		return
+	}
+
+	var usage *managers.TokenUsage
+	finishReason := "stop"
+	toolCallCount := 0
+	for chunk := range streamChan {
+		if chunk.Error != "" {
+			data, _ := json.Marshal(map[string]interface{}{
+				"error": map[string]string{"message": chunk.Error, "type": "server_error"},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+			return
+		}
+
+		delta := &openAIMessage{}
+		if chunk.Content != "" {
+			delta.Content = chunk.Content
+		}
+		if len(chunk.ToolCalls) > 0 {
+			delta.ToolCalls = toOpenAIToolCalls(chunk.ToolCalls)
+			for i := range delta.ToolCalls {
+				index := toolCallCount + i
+				delta.ToolCalls[i].Index = &index
+			}
+			toolCallCount += len(delta.ToolCalls)
+			finishReason = "tool_calls"
+		}
+		if delta.Content != nil || len(delta.ToolCalls) > 0 {
+			if !send([]openAIChoice{{Delta: delta}}, nil) {
+				return
+			}
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	}
+
+	if finishReason == "stop" && usage != nil && inferenceReq.Parameters.MaxTokens > 0 && usage.OutputTokens >= inferenceReq.Parameters.MaxTokens {
+		finishReason = "length"
+	}
+	if !send([]openAIChoice{{Delta: &openAIMessage{}, FinishReason: &finishReason}}, nil) {
+		return
+	}
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage && usage != nil {
+		if !send([]openAIChoice{}, toOpenAIUsage(usage)) {
+			return
+		}
+	}
+
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+// handleOpenAIEmbeddings serves /v1/embeddings
+func (api *RESTAPI) handleOpenAIEmbeddings(w http.ResponseWriter, r *http.Request) {
+	if _, ok := api.openAICaller(w, r); !ok {
+		return
+	}
+
+	var req openAIEmbeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "invalid request body")
+		return
+	}
+
+	var inputs []string
+	switch input := req.Input.(type) {
+	case string:
+		inputs = []string{input}
+	case []interface{}:
+		for _, item := range input {
+			text, ok := item.(string)
+			if !ok {
+				writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "input must be a string or array of strings")
+				return
+			}
+			inputs = append(inputs, text)
+		}
+	}
+	if len(inputs) == 0 {
+		writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "input is required")
+		return
+	}
+
+	data := make([]openAIEmbedding, 0, len(inputs))
+	modelName := req.Model
+	promptTokens := 0
+	for i, text := range inputs {
+		embedding, modelUsed, err := api.inferenceManager.CreateEmbedding(r.Context(), req.Model, text)
+		if err != nil {
+			writeOpenAIError(w, http.StatusBadGateway, "server_error", fmt.Sprintf("embedding failed: %v", err))
+			return
+		}
+		modelName = modelUsed
+		promptTokens += api.tokenManager.EstimateTokens(text, modelUsed)
+		data = append(data, openAIEmbedding{Object: "embedding", Index: i, Embedding: embedding})
+	}
+
+	response := map[string]interface{}{
+		"object": "list",
+		"data":   data,
+		"model":  modelName,
+		"usage":  &openAIUsage{PromptTokens: promptTokens, TotalTokens: promptTokens},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode embeddings response")
+	}
+}
+
+// handleOpenAIListModels serves /v1/models
+func (api *RESTAPI) handleOpenAIListModels(w http.ResponseWriter, r *http.Request) {
+	if _, ok := api.openAICaller(w, r); !ok {
+		return
+	}
+
+	availableModels, err := api.modelManager.ListAvailableModels(r.Context())
+	if err != nil {
+		writeOpenAIError(w, http.StatusBadGateway, "server_error", fmt.Sprintf("failed to list models: %v", err))
+		return
+	}
+
+	data := make([]openAIModel, 0, len(availableModels.Models))
+	for _, model := range availableModels.Models {
+		data = append(data, openAIModel{
+			ID:      model.Name,
+			Object:  "model",
+			Created: model.ModifiedAt.Unix(),
+			OwnedBy: "ocs",
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"object": "list", "data": data}); err != nil {
+		log.Error().Err(err).Msg("Failed to encode models response")
+	}
+}
+
+// openAICaller returns the authenticated caller, writing an OpenAI error when there is
+// none or when X-OCS-Session-ID names a session the caller does not own
+func (api *RESTAPI) openAICaller(w http.ResponseWriter, r *http.Request) (*managers.Caller, bool) {
+	caller, ok := managers.CallerFromContext(r.Context())
+	if !ok {
+		writeOpenAIError(w, http.StatusUnauthorized, "authentication_error", "unauthenticated")
+		return nil, false
+	}
+	if sessionID := r.Header.Get("X-OCS-Session-ID"); sessionID != "" {
+		session, exists := api.sessionManager.GetSession(sessionID)
+		if !exists || session.UserID != caller.UserID {
+			writeOpenAIError(w, http.StatusForbidden, "permission_error", "session not found: "+sessionID)
+			return nil, false
+		}
+	}
+	return caller, true
+}
+
+// buildOpenAIInferenceRequest converts an OpenAI chat request into an OCS inference
+// request made by caller; the request's user field is only an end-user label
+func buildOpenAIInferenceRequest(r *http.Request, req *openAIChatRequest, caller *managers.Caller) (*managers.InferenceRequest, error) {
+	sessionID := r.Header.Get("X-OCS-Session-ID")
+
+	messages := make([]managers.Message, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		message := managers.Message{
+			Role:    msg.Role,
+			Content: openAIContentText(msg.Content),
+		}
+		if len(msg.ToolCalls) > 0 || msg.ToolCallID != "" {
+			message.Metadata = make(map[string]interface{})
+		}
+		if len(msg.ToolCalls) > 0 {
+			calls, err := fromOpenAIToolCalls(msg.ToolCalls)
+			if err != nil {
+				return nil, err
+			}
+			message.Metadata["tool_calls"] = calls
+		}
+		if msg.ToolCallID != "" {
+			message.Metadata["tool_call_id"] = msg.ToolCallID
+		}
+		messages = append(messages, message)
+	}
+
+	maxTokens := req.MaxTokens
+	if req.MaxCompletionTokens > 0 {
+		maxTokens = req.MaxCompletionTokens
+	}
+
+	var stop []string
+	switch value := req.Stop.(type) {
+	case string:
+		stop = []string{value}
+	case []interface{}:
+		for _, item := range value {
+			if text, ok := item.(string); ok {
+				stop = append(stop, text)
+			}
+		}
+	}
+
+	// JSON mode is a schema that accepts any object
+	var schema map[string]interface{}
+	if format := req.ResponseFormat; format != nil {
+		switch format.Type {
+		case "json_object":
+			schema = map[string]interface{}{"type": "object"}
+		case "json_schema":
+			if format.JSONSchema == nil || format.JSONSchema.Schema == nil {
+				return nil, fmt.Errorf("response_format json_schema requires a schema")
+			}
+			schema = format.JSONSchema.Schema
+		}
+	}
+
+	metadata := map[string]interface{}{"source": "openai"}
+	if req.User != "" {
+		metadata["openai_user"] = req.User
+	}
+
+	return &managers.InferenceRequest{
+		ID:          fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano()),
+		UserID:      caller.UserID,
+		SessionID:   sessionID,
+		ModelName:   req.Model,
+		RequestType: managers.InferenceTypeChat,
+		Messages:    messages,
+		Parameters: &managers.InferenceParameters{
+			Temperature:     req.Temperature,
+			TopP:            req.TopP,
+			Seed:            req.Seed,
+			MaxTokens:       maxTokens,
+			Stop:            stop,
+			Stream:          req.Stream,
+			UseMemory:       sessionID != "",
+			UseCache:        strings.EqualFold(r.Header.Get("X-OCS-Cache"), "true"),
+			MemoryDepth:     5,
+			ToolDefinitions: req.Tools,
+			ResponseSchema:  schema,
+		},
+		Metadata: metadata,
+	}, nil
+}
+
+// openAIContentText flattens string or content-part message content to text
+func openAIContentText(content interface{}) string {
+	switch value := content.(type) {
+	case string:
+		return value
+	case []interface{}:
+		var parts []string
+		for _, item := range value {
+			part, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if text, ok := part["text"].(string); ok {
+				parts = append(parts, text)
+			}
+		}
+		return strings.Join(parts, "\n")
+	}
+	return ""
+}
+
+// toOpenAIToolCalls encodes tool call arguments as JSON strings
+func toOpenAIToolCalls(calls []managers.ToolCall) []openAIToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	result := make([]openAIToolCall, 0, len(calls))
+	for _, call := range calls {
+		if call.Function == nil {
+			continue
+		}
+		arguments, err := json.Marshal(call.Function.Arguments)
+		if err != nil || call.Function.Arguments == nil {
+			arguments = []byte("{}")
+		}
+		toolCall := openAIToolCall{
+			ID:   call.ID,
+			Type: "function",
+			Function: openAIFunctionCall{
+				Name:      call.Function.Name,
+				Arguments: string(arguments),
+			},
+		}
+		result = append(result, toolCall)
+	}
+	return result
+}
+
+// fromOpenAIToolCalls decodes JSON string arguments into OCS tool calls
+func fromOpenAIToolCalls(calls []openAIToolCall) ([]managers.ToolCall, error) {
+	result := make([]managers.ToolCall, 0, len(calls))
+	for _, call := range calls {
+		arguments := make(map[string]interface{})
+		if call.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(call.Function.Arguments), &arguments); err != nil {
+				return nil, fmt.Errorf("invalid arguments for tool call %s: %w", call.ID, err)
+			}
+		}
+		result = append(result, managers.ToolCall{
+			ID:   call.ID,
+			Type: "function",
+			Function: &managers.FunctionCall{
+				Name:      call.Function.Name,
+				Arguments: arguments,
+			},
+		})
+	}
+	return result, nil
+}
+
+// toOpenAIUsage converts OCS token usage to the OpenAI usage block
+func toOpenAIUsage(usage *managers.TokenUsage) *openAIUsage {
+	if usage == nil {
+		return nil
+	}
+	return &openAIUsage{
+		PromptTokens:     usage.InputTokens,
+		CompletionTokens: usage.OutputTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
+// openAIErrorStatus maps inference errors to HTTP status codes
+func openAIErrorStatus(err error) int {
+	var queueErr *managers.QueueFullError
+	switch {
+	case errors.As(err, &queueErr):
+		return http.StatusTooManyRequests
+	case strings.Contains(err.Error(), "token budget exceeded"):
+		return http.StatusTooManyRequests
+	case strings.Contains(err.Error(), "invalid request"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeOpenAIError writes an error in OpenAI's error envelope
+func writeOpenAIError(w http.ResponseWriter, status int, errType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    errType,
+		},
+	})
+}
@@ -0,0 +1,215 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = api/openai-api_test.go
+
+package api
+
+import (
+	// stdlib
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// openAIPost sends an OpenAI request as token, with extra headers as name, value pairs
+func (s *restSuite) openAIPost(t *testing.T, path, token, body string, headers ...string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, s.server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// decodeJSON decodes a response body, failing unless the status is want
+func decodeJSON(t *testing.T, resp *http.Response, want int, v interface{}) {
+	t.Helper()
+	if resp.StatusCode != want {
+		var body strings.Builder
+		bufio.NewReader(resp.Body).WriteTo(&body)
+		t.Fatalf("status = %d, want %d: %s", resp.StatusCode, want, body.String())
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readOpenAIStream collects the chunks of an OpenAI SSE stream up to [DONE]
+func readOpenAIStream(t *testing.T, resp *http.Response) []openAIChatResponse {
+	t.Helper()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status = %d, content type = %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	var chunks []openAIChatResponse
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			return chunks
+		}
+		var chunk openAIChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("chunk %q: %v", data, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	t.Fatalf("stream ended without [DONE] after %d chunks", len(chunks))
+	return nil
+}
+
+const weatherTool = `"tools":[{"type":"function","function":{"name":"get_weather","parameters":{"type":"object","properties":{"city":{"type":"string"}}}}}]`
+
+func TestOpenAIChatCompletions(t *testing.T) {
+	suite := newRESTSuite(t, newFakeOllama(t).URL)
+
+	var completion openAIChatResponse
+	decodeJSON(t, suite.openAIPost(t, "/v1/chat/completions", "alice:chat",
+		`{"model":"llama3.2","user":"end-user-42","messages":[{"role":"system","content":"be brief"},{"role":"user","content":[{"type":"text","text":"hello there"}]}]}`),
+		http.StatusOK, &completion)
+	choice := completion.Choices[0]
+	if completion.Object != "chat.completion" || completion.Model != testModel || choice.Message.Content != "echo: hello there" || *choice.FinishReason != "stop" {
+		t.Errorf("completion = %+v, message = %+v", completion, choice.Message)
+	}
+	if usage := completion.Usage; usage == nil || usage.PromptTokens != 7 || usage.CompletionTokens != 3 || usage.TotalTokens != 10 {
+		t.Errorf("usage = %+v", completion.Usage)
+	}
+
+	// Usage is charged to the token's user, not the request's user label
+	if _, _, err := suite.tokenManager.GetUserUsage("alice"); err != nil {
+		t.Errorf("alice was not charged: %v", err)
+	}
+	for _, userID := range []string{"end-user-42", "openai"} {
+		if _, _, err := suite.tokenManager.GetUserUsage(userID); err == nil {
+			t.Errorf("%s was charged", userID)
+		}
+	}
+
+	// Tools the client defined come back as tool_calls for it to run
+	decodeJSON(t, suite.openAIPost(t, "/v1/chat/completions", "alice:chat",
+		`{"model":"llama3.2","messages":[{"role":"user","content":"what is the weather?"}],`+weatherTool+`}`),
+		http.StatusOK, &completion)
+	choice = completion.Choices[0]
+	if *choice.FinishReason != "tool_calls" || len(choice.Message.ToolCalls) != 1 {
+		t.Fatalf("tool completion = %+v", choice.Message)
+	}
+	if call := choice.Message.ToolCalls[0]; call.Type != "function" || call.Function.Name != "get_weather" || call.Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("tool call = %+v", call)
+	}
+	if usage := completion.Usage; usage == nil || usage.TotalTokens != 15 {
+		t.Errorf("tool usage = %+v", completion.Usage)
+	}
+}
+
+func TestOpenAIChatCompletionsStream(t *testing.T) {
+	suite := newRESTSuite(t, newFakeOllama(t).URL)
+
+	chunks := readOpenAIStream(t, suite.openAIPost(t, "/v1/chat/completions", "alice:chat",
+		`{"model":"llama3.2","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"stream these words"}]}`))
+	if len(chunks) < 4 || chunks[0].Choices[0].Delta.Role != "assistant" {
+		t.Fatalf("chunks = %+v", chunks)
+	}
+	var content strings.Builder
+	for _, chunk := range chunks[1 : len(chunks)-2] {
+		if chunk.Object != "chat.completion.chunk" || chunk.ID != chunks[0].ID {
+			t.Errorf("chunk = %+v", chunk)
+		}
+		text, _ := chunk.Choices[0].Delta.Content.(string)
+		content.WriteString(text)
+	}
+	if got := strings.TrimSpace(content.String()); got != "echo: stream these words" {
+		t.Errorf("content = %q", got)
+	}
+	if finish := chunks[len(chunks)-2].Choices[0].FinishReason; finish == nil || *finish != "stop" {
+		t.Errorf("finish chunk = %+v", chunks[len(chunks)-2])
+	}
+	if last := chunks[len(chunks)-1]; len(last.Choices) != 0 || last.Usage == nil || last.Usage.TotalTokens != 10 {
+		t.Errorf("usage chunk = %+v", last)
+	}
+
+	// Streamed tool calls carry their index and end with finish_reason tool_calls
+	chunks = readOpenAIStream(t, suite.openAIPost(t, "/v1/chat/completions", "alice:chat",
+		`{"model":"llama3.2","stream":true,"messages":[{"role":"user","content":"what is the weather?"}],`+weatherTool+`}`))
+	var calls []openAIToolCall
+	for _, chunk := range chunks {
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta != nil {
+			calls = append(calls, chunk.Choices[0].Delta.ToolCalls...)
+		}
+		if chunk.Usage != nil {
+			t.Errorf("usage sent without include_usage: %+v", chunk)
+		}
+	}
+	if len(calls) != 1 || calls[0].Index == nil || *calls[0].Index != 0 || calls[0].Function.Name != "get_weather" || calls[0].Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("tool calls = %+v", calls)
+	}
+	if finish := chunks[len(chunks)-1].Choices[0].FinishReason; finish == nil || *finish != "tool_calls" {
+		t.Errorf("finish chunk = %+v", chunks[len(chunks)-1])
+	}
+}
+
+func TestOpenAIEmbeddingsAndModels(t *testing.T) {
+	suite := newRESTSuite(t, newFakeOllama(t).URL)
+
+	var embeddings struct {
+		Object string            `json:"object"`
+		Data   []openAIEmbedding `json:"data"`
+		Model  string            `json:"model"`
+		Usage  openAIUsage       `json:"usage"`
+	}
+	decodeJSON(t, suite.openAIPost(t, "/v1/embeddings", "alice:chat", `{"model":"llama3.2","input":["ab","abcd"]}`), http.StatusOK, &embeddings)
+	if embeddings.Object != "list" || embeddings.Model != testModel || len(embeddings.Data) != 2 || embeddings.Usage.PromptTokens == 0 {
+		t.Fatalf("embeddings = %+v", embeddings)
+	}
+	for i, want := range []float64{2, 4} {
+		if data := embeddings.Data[i]; data.Index != i || data.Object != "embedding" || len(data.Embedding) != 2 || data.Embedding[0] != want {
+			t.Errorf("embedding %d = %+v", i, data)
+		}
+	}
+	if resp := suite.openAIPost(t, "/v1/embeddings", "alice:chat", `{"model":"llama3.2","input":[1,2]}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("numeric input = %d", resp.StatusCode)
+	}
+
+	var models struct {
+		Object string        `json:"object"`
+		Data   []openAIModel `json:"data"`
+	}
+	decodeJSON(t, suite.do(t, context.Background(), http.MethodGet, "/v1/models", "alice:chat", ""), http.StatusOK, &models)
+	if models.Object != "list" || len(models.Data) != 1 || models.Data[0].ID != testModel || models.Data[0].Object != "model" || models.Data[0].OwnedBy != "ocs" {
+		t.Errorf("models = %+v", models)
+	}
+}
+
+func TestOpenAISessionBelongsToCaller(t *testing.T) {
+	suite := newRESTSuite(t, newFakeOllama(t).URL)
+	bobs, err := suite.sessionManager.CreateSession(context.Background(), "bob", testModel, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"model":"llama3.2","messages":[{"role":"user","content":"hi"}]}`
+
+	var failure struct {
+		Error struct {
+			Type string `json:"type"`
+		} `json:"error"`
+	}
+	decodeJSON(t, suite.openAIPost(t, "/v1/chat/completions", "alice:chat", body, "X-OCS-Session-ID", bobs.ID), http.StatusForbidden, &failure)
+	if failure.Error.Type != "permission_error" {
+		t.Errorf("error = %+v", failure)
+	}
+	var completion openAIChatResponse
+	decodeJSON(t, suite.openAIPost(t, "/v1/chat/completions", "bob:chat", body, "X-OCS-Session-ID", bobs.ID), http.StatusOK, &completion)
+}
@@ -0,0 +1,1616 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        v5.29.3
+// source: ocs/v1/ocs.proto
+
+package ocsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ListModelsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListModelsRequest) Reset() {
+	*x = ListModelsRequest{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListModelsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListModelsRequest) ProtoMessage() {}
+
+func (x *ListModelsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListModelsRequest.ProtoReflect.Descriptor instead.
+func (*ListModelsRequest) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{0}
+}
+
+type AvailableModel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	ModifiedAt    string                 `protobuf:"bytes,3,opt,name=modified_at,json=modifiedAt,proto3" json:"modified_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AvailableModel) Reset() {
+	*x = AvailableModel{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AvailableModel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AvailableModel) ProtoMessage() {}
+
+func (x *AvailableModel) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AvailableModel.ProtoReflect.Descriptor instead.
+func (*AvailableModel) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AvailableModel) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *AvailableModel) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *AvailableModel) GetModifiedAt() string {
+	if x != nil {
+		return x.ModifiedAt
+	}
+	return ""
+}
+
+type ModelInfo struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Name           string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Size           int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	LoadedAt       string                 `protobuf:"bytes,3,opt,name=loaded_at,json=loadedAt,proto3" json:"loaded_at,omitempty"`
+	LastUsed       string                 `protobuf:"bytes,4,opt,name=last_used,json=lastUsed,proto3" json:"last_used,omitempty"`
+	Specialization string                 `protobuf:"bytes,5,opt,name=specialization,proto3" json:"specialization,omitempty"`
+	Priority       int32                  `protobuf:"varint,6,opt,name=priority,proto3" json:"priority,omitempty"`
+	Status         string                 `protobuf:"bytes,7,opt,name=status,proto3" json:"status,omitempty"`
+	ErrorMsg       string                 `protobuf:"bytes,8,opt,name=error_msg,json=errorMsg,proto3" json:"error_msg,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ModelInfo) Reset() {
+	*x = ModelInfo{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ModelInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModelInfo) ProtoMessage() {}
+
+func (x *ModelInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModelInfo.ProtoReflect.Descriptor instead.
+func (*ModelInfo) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ModelInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ModelInfo) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *ModelInfo) GetLoadedAt() string {
+	if x != nil {
+		return x.LoadedAt
+	}
+	return ""
+}
+
+func (x *ModelInfo) GetLastUsed() string {
+	if x != nil {
+		return x.LastUsed
+	}
+	return ""
+}
+
+func (x *ModelInfo) GetSpecialization() string {
+	if x != nil {
+		return x.Specialization
+	}
+	return ""
+}
+
+func (x *ModelInfo) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *ModelInfo) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ModelInfo) GetErrorMsg() string {
+	if x != nil {
+		return x.ErrorMsg
+	}
+	return ""
+}
+
+type ListModelsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Available     []*AvailableModel      `protobuf:"bytes,1,rep,name=available,proto3" json:"available,omitempty"`
+	Loaded        []*ModelInfo           `protobuf:"bytes,2,rep,name=loaded,proto3" json:"loaded,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListModelsResponse) Reset() {
+	*x = ListModelsResponse{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListModelsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListModelsResponse) ProtoMessage() {}
+
+func (x *ListModelsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListModelsResponse.ProtoReflect.Descriptor instead.
+func (*ListModelsResponse) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListModelsResponse) GetAvailable() []*AvailableModel {
+	if x != nil {
+		return x.Available
+	}
+	return nil
+}
+
+func (x *ListModelsResponse) GetLoaded() []*ModelInfo {
+	if x != nil {
+		return x.Loaded
+	}
+	return nil
+}
+
+type SessionSettings struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	MaxTokens         int32                  `protobuf:"varint,1,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`
+	Temperature       float64                `protobuf:"fixed64,2,opt,name=temperature,proto3" json:"temperature,omitempty"`
+	TopP              float64                `protobuf:"fixed64,3,opt,name=top_p,json=topP,proto3" json:"top_p,omitempty"`
+	RepetitionPenalty float64                `protobuf:"fixed64,4,opt,name=repetition_penalty,json=repetitionPenalty,proto3" json:"repetition_penalty,omitempty"`
+	ContextWindow     int32                  `protobuf:"varint,5,opt,name=context_window,json=contextWindow,proto3" json:"context_window,omitempty"`
+	AutoSave          bool                   `protobuf:"varint,6,opt,name=auto_save,json=autoSave,proto3" json:"auto_save,omitempty"`
+	PersistMemory     bool                   `protobuf:"varint,7,opt,name=persist_memory,json=persistMemory,proto3" json:"persist_memory,omitempty"`
+	EnableTools       bool                   `protobuf:"varint,8,opt,name=enable_tools,json=enableTools,proto3" json:"enable_tools,omitempty"`
+	EnableCodeExec    bool                   `protobuf:"varint,9,opt,name=enable_code_exec,json=enableCodeExec,proto3" json:"enable_code_exec,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *SessionSettings) Reset() {
+	*x = SessionSettings{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionSettings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionSettings) ProtoMessage() {}
+
+func (x *SessionSettings) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionSettings.ProtoReflect.Descriptor instead.
+func (*SessionSettings) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SessionSettings) GetMaxTokens() int32 {
+	if x != nil {
+		return x.MaxTokens
+	}
+	return 0
+}
+
+func (x *SessionSettings) GetTemperature() float64 {
+	if x != nil {
+		return x.Temperature
+	}
+	return 0
+}
+
+func (x *SessionSettings) GetTopP() float64 {
+	if x != nil {
+		return x.TopP
+	}
+	return 0
+}
+
+func (x *SessionSettings) GetRepetitionPenalty() float64 {
+	if x != nil {
+		return x.RepetitionPenalty
+	}
+	return 0
+}
+
+func (x *SessionSettings) GetContextWindow() int32 {
+	if x != nil {
+		return x.ContextWindow
+	}
+	return 0
+}
+
+func (x *SessionSettings) GetAutoSave() bool {
+	if x != nil {
+		return x.AutoSave
+	}
+	return false
+}
+
+func (x *SessionSettings) GetPersistMemory() bool {
+	if x != nil {
+		return x.PersistMemory
+	}
+	return false
+}
+
+func (x *SessionSettings) GetEnableTools() bool {
+	if x != nil {
+		return x.EnableTools
+	}
+	return false
+}
+
+func (x *SessionSettings) GetEnableCodeExec() bool {
+	if x != nil {
+		return x.EnableCodeExec
+	}
+	return false
+}
+
+type Session struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Title         string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	CreatedAt     string                 `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	LastActivity  string                 `protobuf:"bytes,5,opt,name=last_activity,json=lastActivity,proto3" json:"last_activity,omitempty"`
+	MessageCount  int32                  `protobuf:"varint,6,opt,name=message_count,json=messageCount,proto3" json:"message_count,omitempty"`
+	TokensUsed    int64                  `protobuf:"varint,7,opt,name=tokens_used,json=tokensUsed,proto3" json:"tokens_used,omitempty"`
+	ModelName     string                 `protobuf:"bytes,8,opt,name=model_name,json=modelName,proto3" json:"model_name,omitempty"`
+	IsActive      bool                   `protobuf:"varint,9,opt,name=is_active,json=isActive,proto3" json:"is_active,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Session) Reset() {
+	*x = Session{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Session) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Session) ProtoMessage() {}
+
+func (x *Session) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Session.ProtoReflect.Descriptor instead.
+func (*Session) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Session) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Session) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Session) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Session) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+func (x *Session) GetLastActivity() string {
+	if x != nil {
+		return x.LastActivity
+	}
+	return ""
+}
+
+func (x *Session) GetMessageCount() int32 {
+	if x != nil {
+		return x.MessageCount
+	}
+	return 0
+}
+
+func (x *Session) GetTokensUsed() int64 {
+	if x != nil {
+		return x.TokensUsed
+	}
+	return 0
+}
+
+func (x *Session) GetModelName() string {
+	if x != nil {
+		return x.ModelName
+	}
+	return ""
+}
+
+func (x *Session) GetIsActive() bool {
+	if x != nil {
+		return x.IsActive
+	}
+	return false
+}
+
+type CreateSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	ModelName     string                 `protobuf:"bytes,2,opt,name=model_name,json=modelName,proto3" json:"model_name,omitempty"`
+	Settings      *SessionSettings       `protobuf:"bytes,3,opt,name=settings,proto3" json:"settings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSessionRequest) Reset() {
+	*x = CreateSessionRequest{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSessionRequest) ProtoMessage() {}
+
+func (x *CreateSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSessionRequest.ProtoReflect.Descriptor instead.
+func (*CreateSessionRequest) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CreateSessionRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *CreateSessionRequest) GetModelName() string {
+	if x != nil {
+		return x.ModelName
+	}
+	return ""
+}
+
+func (x *CreateSessionRequest) GetSettings() *SessionSettings {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+type CreateSessionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Session       *Session               `protobuf:"bytes,1,opt,name=session,proto3" json:"session,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSessionResponse) Reset() {
+	*x = CreateSessionResponse{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSessionResponse) ProtoMessage() {}
+
+func (x *CreateSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSessionResponse.ProtoReflect.Descriptor instead.
+func (*CreateSessionResponse) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CreateSessionResponse) GetSession() *Session {
+	if x != nil {
+		return x.Session
+	}
+	return nil
+}
+
+type GetSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSessionRequest) Reset() {
+	*x = GetSessionRequest{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSessionRequest) ProtoMessage() {}
+
+func (x *GetSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSessionRequest.ProtoReflect.Descriptor instead.
+func (*GetSessionRequest) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type GetSessionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Session       *Session               `protobuf:"bytes,1,opt,name=session,proto3" json:"session,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSessionResponse) Reset() {
+	*x = GetSessionResponse{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSessionResponse) ProtoMessage() {}
+
+func (x *GetSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSessionResponse.ProtoReflect.Descriptor instead.
+func (*GetSessionResponse) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetSessionResponse) GetSession() *Session {
+	if x != nil {
+		return x.Session
+	}
+	return nil
+}
+
+type Message struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Role          string                 `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"`
+	Content       string                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	Timestamp     string                 `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Tokens        int32                  `protobuf:"varint,5,opt,name=tokens,proto3" json:"tokens,omitempty"`
+	ModelUsed     string                 `protobuf:"bytes,6,opt,name=model_used,json=modelUsed,proto3" json:"model_used,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Message) Reset() {
+	*x = Message{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Message) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Message) ProtoMessage() {}
+
+func (x *Message) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Message) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Message) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *Message) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *Message) GetTimestamp() string {
+	if x != nil {
+		return x.Timestamp
+	}
+	return ""
+}
+
+func (x *Message) GetTokens() int32 {
+	if x != nil {
+		return x.Tokens
+	}
+	return 0
+}
+
+func (x *Message) GetModelUsed() string {
+	if x != nil {
+		return x.ModelUsed
+	}
+	return ""
+}
+
+type AddMessageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Role          string                 `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"`
+	Content       string                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	Metadata      *structpb.Struct       `protobuf:"bytes,4,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddMessageRequest) Reset() {
+	*x = AddMessageRequest{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddMessageRequest) ProtoMessage() {}
+
+func (x *AddMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddMessageRequest.ProtoReflect.Descriptor instead.
+func (*AddMessageRequest) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *AddMessageRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *AddMessageRequest) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *AddMessageRequest) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *AddMessageRequest) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type AddMessageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       *Message               `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddMessageResponse) Reset() {
+	*x = AddMessageResponse{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddMessageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddMessageResponse) ProtoMessage() {}
+
+func (x *AddMessageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddMessageResponse.ProtoReflect.Descriptor instead.
+func (*AddMessageResponse) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *AddMessageResponse) GetMessage() *Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+type ProcessInferenceRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	UserId    string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	SessionId string                 `protobuf:"bytes,2,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Prompt    string                 `protobuf:"bytes,3,opt,name=prompt,proto3" json:"prompt,omitempty"`
+	ModelName string                 `protobuf:"bytes,4,opt,name=model_name,json=modelName,proto3" json:"model_name,omitempty"`
+	// One of: chat, code, reasoning
+	InferenceType string           `protobuf:"bytes,5,opt,name=inference_type,json=inferenceType,proto3" json:"inference_type,omitempty"`
+	Parameters    *structpb.Struct `protobuf:"bytes,6,opt,name=parameters,proto3" json:"parameters,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessInferenceRequest) Reset() {
+	*x = ProcessInferenceRequest{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessInferenceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessInferenceRequest) ProtoMessage() {}
+
+func (x *ProcessInferenceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessInferenceRequest.ProtoReflect.Descriptor instead.
+func (*ProcessInferenceRequest) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ProcessInferenceRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ProcessInferenceRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ProcessInferenceRequest) GetPrompt() string {
+	if x != nil {
+		return x.Prompt
+	}
+	return ""
+}
+
+func (x *ProcessInferenceRequest) GetModelName() string {
+	if x != nil {
+		return x.ModelName
+	}
+	return ""
+}
+
+func (x *ProcessInferenceRequest) GetInferenceType() string {
+	if x != nil {
+		return x.InferenceType
+	}
+	return ""
+}
+
+func (x *ProcessInferenceRequest) GetParameters() *structpb.Struct {
+	if x != nil {
+		return x.Parameters
+	}
+	return nil
+}
+
+type TokenUsage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	InputTokens   int32                  `protobuf:"varint,1,opt,name=input_tokens,json=inputTokens,proto3" json:"input_tokens,omitempty"`
+	OutputTokens  int32                  `protobuf:"varint,2,opt,name=output_tokens,json=outputTokens,proto3" json:"output_tokens,omitempty"`
+	TotalTokens   int32                  `protobuf:"varint,3,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`
+	CachedTokens  int32                  `protobuf:"varint,4,opt,name=cached_tokens,json=cachedTokens,proto3" json:"cached_tokens,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TokenUsage) Reset() {
+	*x = TokenUsage{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TokenUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TokenUsage) ProtoMessage() {}
+
+func (x *TokenUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TokenUsage.ProtoReflect.Descriptor instead.
+func (*TokenUsage) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *TokenUsage) GetInputTokens() int32 {
+	if x != nil {
+		return x.InputTokens
+	}
+	return 0
+}
+
+func (x *TokenUsage) GetOutputTokens() int32 {
+	if x != nil {
+		return x.OutputTokens
+	}
+	return 0
+}
+
+func (x *TokenUsage) GetTotalTokens() int32 {
+	if x != nil {
+		return x.TotalTokens
+	}
+	return 0
+}
+
+func (x *TokenUsage) GetCachedTokens() int32 {
+	if x != nil {
+		return x.CachedTokens
+	}
+	return 0
+}
+
+type PerformanceStats struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	ProcessingTimeMs int64                  `protobuf:"varint,1,opt,name=processing_time_ms,json=processingTimeMs,proto3" json:"processing_time_ms,omitempty"`
+	FirstTokenTimeMs int64                  `protobuf:"varint,2,opt,name=first_token_time_ms,json=firstTokenTimeMs,proto3" json:"first_token_time_ms,omitempty"`
+	TokensPerSecond  float64                `protobuf:"fixed64,3,opt,name=tokens_per_second,json=tokensPerSecond,proto3" json:"tokens_per_second,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *PerformanceStats) Reset() {
+	*x = PerformanceStats{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PerformanceStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PerformanceStats) ProtoMessage() {}
+
+func (x *PerformanceStats) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PerformanceStats.ProtoReflect.Descriptor instead.
+func (*PerformanceStats) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *PerformanceStats) GetProcessingTimeMs() int64 {
+	if x != nil {
+		return x.ProcessingTimeMs
+	}
+	return 0
+}
+
+func (x *PerformanceStats) GetFirstTokenTimeMs() int64 {
+	if x != nil {
+		return x.FirstTokenTimeMs
+	}
+	return 0
+}
+
+func (x *PerformanceStats) GetTokensPerSecond() float64 {
+	if x != nil {
+		return x.TokensPerSecond
+	}
+	return 0
+}
+
+type ProcessInferenceResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	RequestId        string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Content          string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	ModelUsed        string                 `protobuf:"bytes,3,opt,name=model_used,json=modelUsed,proto3" json:"model_used,omitempty"`
+	FinishReason     string                 `protobuf:"bytes,4,opt,name=finish_reason,json=finishReason,proto3" json:"finish_reason,omitempty"`
+	Usage            *TokenUsage            `protobuf:"bytes,5,opt,name=usage,proto3" json:"usage,omitempty"`
+	PerformanceStats *PerformanceStats      `protobuf:"bytes,6,opt,name=performance_stats,json=performanceStats,proto3" json:"performance_stats,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ProcessInferenceResponse) Reset() {
+	*x = ProcessInferenceResponse{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessInferenceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessInferenceResponse) ProtoMessage() {}
+
+func (x *ProcessInferenceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessInferenceResponse.ProtoReflect.Descriptor instead.
+func (*ProcessInferenceResponse) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ProcessInferenceResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ProcessInferenceResponse) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *ProcessInferenceResponse) GetModelUsed() string {
+	if x != nil {
+		return x.ModelUsed
+	}
+	return ""
+}
+
+func (x *ProcessInferenceResponse) GetFinishReason() string {
+	if x != nil {
+		return x.FinishReason
+	}
+	return ""
+}
+
+func (x *ProcessInferenceResponse) GetUsage() *TokenUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *ProcessInferenceResponse) GetPerformanceStats() *PerformanceStats {
+	if x != nil {
+		return x.PerformanceStats
+	}
+	return nil
+}
+
+type StreamInferenceResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	RequestId  string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Content    string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	Done       bool                   `protobuf:"varint,3,opt,name=done,proto3" json:"done,omitempty"`
+	TokenCount int32                  `protobuf:"varint,4,opt,name=token_count,json=tokenCount,proto3" json:"token_count,omitempty"`
+	Error      string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	// Set on the final chunk only
+	Usage            *TokenUsage       `protobuf:"bytes,6,opt,name=usage,proto3" json:"usage,omitempty"`
+	PerformanceStats *PerformanceStats `protobuf:"bytes,7,opt,name=performance_stats,json=performanceStats,proto3" json:"performance_stats,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *StreamInferenceResponse) Reset() {
+	*x = StreamInferenceResponse{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamInferenceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamInferenceResponse) ProtoMessage() {}
+
+func (x *StreamInferenceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamInferenceResponse.ProtoReflect.Descriptor instead.
+func (*StreamInferenceResponse) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *StreamInferenceResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *StreamInferenceResponse) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *StreamInferenceResponse) GetDone() bool {
+	if x != nil {
+		return x.Done
+	}
+	return false
+}
+
+func (x *StreamInferenceResponse) GetTokenCount() int32 {
+	if x != nil {
+		return x.TokenCount
+	}
+	return 0
+}
+
+func (x *StreamInferenceResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *StreamInferenceResponse) GetUsage() *TokenUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *StreamInferenceResponse) GetPerformanceStats() *PerformanceStats {
+	if x != nil {
+		return x.PerformanceStats
+	}
+	return nil
+}
+
+type ExecuteToolRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Arguments     *structpb.Struct       `protobuf:"bytes,2,opt,name=arguments,proto3" json:"arguments,omitempty"`
+	SessionId     string                 `protobuf:"bytes,3,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteToolRequest) Reset() {
+	*x = ExecuteToolRequest{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteToolRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteToolRequest) ProtoMessage() {}
+
+func (x *ExecuteToolRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteToolRequest.ProtoReflect.Descriptor instead.
+func (*ExecuteToolRequest) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ExecuteToolRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ExecuteToolRequest) GetArguments() *structpb.Struct {
+	if x != nil {
+		return x.Arguments
+	}
+	return nil
+}
+
+func (x *ExecuteToolRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type ExecuteToolResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Content       string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteToolResponse) Reset() {
+	*x = ExecuteToolResponse{}
+	mi := &file_ocs_v1_ocs_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteToolResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteToolResponse) ProtoMessage() {}
+
+func (x *ExecuteToolResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ocs_v1_ocs_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteToolResponse.ProtoReflect.Descriptor instead.
+func (*ExecuteToolResponse) Descriptor() ([]byte, []int) {
+	return file_ocs_v1_ocs_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ExecuteToolResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ExecuteToolResponse) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *ExecuteToolResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_ocs_v1_ocs_proto protoreflect.FileDescriptor
+
+const file_ocs_v1_ocs_proto_rawDesc = "" +
+	"\n" +
+	"\x10ocs/v1/ocs.proto\x12\x06ocs.v1\x1a\x1cgoogle/protobuf/struct.proto\"\x13\n" +
+	"\x11ListModelsRequest\"Y\n" +
+	"\x0eAvailableModel\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x1f\n" +
+	"\vmodified_at\x18\x03 \x01(\tR\n" +
+	"modifiedAt\"\xe6\x01\n" +
+	"\tModelInfo\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x1b\n" +
+	"\tloaded_at\x18\x03 \x01(\tR\bloadedAt\x12\x1b\n" +
+	"\tlast_used\x18\x04 \x01(\tR\blastUsed\x12&\n" +
+	"\x0especialization\x18\x05 \x01(\tR\x0especialization\x12\x1a\n" +
+	"\bpriority\x18\x06 \x01(\x05R\bpriority\x12\x16\n" +
+	"\x06status\x18\a \x01(\tR\x06status\x12\x1b\n" +
+	"\terror_msg\x18\b \x01(\tR\berrorMsg\"u\n" +
+	"\x12ListModelsResponse\x124\n" +
+	"\tavailable\x18\x01 \x03(\v2\x16.ocs.v1.AvailableModelR\tavailable\x12)\n" +
+	"\x06loaded\x18\x02 \x03(\v2\x11.ocs.v1.ModelInfoR\x06loaded\"\xce\x02\n" +
+	"\x0fSessionSettings\x12\x1d\n" +
+	"\n" +
+	"max_tokens\x18\x01 \x01(\x05R\tmaxTokens\x12 \n" +
+	"\vtemperature\x18\x02 \x01(\x01R\vtemperature\x12\x13\n" +
+	"\x05top_p\x18\x03 \x01(\x01R\x04topP\x12-\n" +
+	"\x12repetition_penalty\x18\x04 \x01(\x01R\x11repetitionPenalty\x12%\n" +
+	"\x0econtext_window\x18\x05 \x01(\x05R\rcontextWindow\x12\x1b\n" +
+	"\tauto_save\x18\x06 \x01(\bR\bautoSave\x12%\n" +
+	"\x0epersist_memory\x18\a \x01(\bR\rpersistMemory\x12!\n" +
+	"\fenable_tools\x18\b \x01(\bR\venableTools\x12(\n" +
+	"\x10enable_code_exec\x18\t \x01(\bR\x0eenableCodeExec\"\x8e\x02\n" +
+	"\aSession\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05title\x18\x03 \x01(\tR\x05title\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\tR\tcreatedAt\x12#\n" +
+	"\rlast_activity\x18\x05 \x01(\tR\flastActivity\x12#\n" +
+	"\rmessage_count\x18\x06 \x01(\x05R\fmessageCount\x12\x1f\n" +
+	"\vtokens_used\x18\a \x01(\x03R\n" +
+	"tokensUsed\x12\x1d\n" +
+	"\n" +
+	"model_name\x18\b \x01(\tR\tmodelName\x12\x1b\n" +
+	"\tis_active\x18\t \x01(\bR\bisActive\"\x83\x01\n" +
+	"\x14CreateSessionRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"model_name\x18\x02 \x01(\tR\tmodelName\x123\n" +
+	"\bsettings\x18\x03 \x01(\v2\x17.ocs.v1.SessionSettingsR\bsettings\"B\n" +
+	"\x15CreateSessionResponse\x12)\n" +
+	"\asession\x18\x01 \x01(\v2\x0f.ocs.v1.SessionR\asession\"2\n" +
+	"\x11GetSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"?\n" +
+	"\x12GetSessionResponse\x12)\n" +
+	"\asession\x18\x01 \x01(\v2\x0f.ocs.v1.SessionR\asession\"\x9c\x01\n" +
+	"\aMessage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04role\x18\x02 \x01(\tR\x04role\x12\x18\n" +
+	"\acontent\x18\x03 \x01(\tR\acontent\x12\x1c\n" +
+	"\ttimestamp\x18\x04 \x01(\tR\ttimestamp\x12\x16\n" +
+	"\x06tokens\x18\x05 \x01(\x05R\x06tokens\x12\x1d\n" +
+	"\n" +
+	"model_used\x18\x06 \x01(\tR\tmodelUsed\"\x95\x01\n" +
+	"\x11AddMessageRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x12\n" +
+	"\x04role\x18\x02 \x01(\tR\x04role\x12\x18\n" +
+	"\acontent\x18\x03 \x01(\tR\acontent\x123\n" +
+	"\bmetadata\x18\x04 \x01(\v2\x17.google.protobuf.StructR\bmetadata\"?\n" +
+	"\x12AddMessageResponse\x12)\n" +
+	"\amessage\x18\x01 \x01(\v2\x0f.ocs.v1.MessageR\amessage\"\xe8\x01\n" +
+	"\x17ProcessInferenceRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x02 \x01(\tR\tsessionId\x12\x16\n" +
+	"\x06prompt\x18\x03 \x01(\tR\x06prompt\x12\x1d\n" +
+	"\n" +
+	"model_name\x18\x04 \x01(\tR\tmodelName\x12%\n" +
+	"\x0einference_type\x18\x05 \x01(\tR\rinferenceType\x127\n" +
+	"\n" +
+	"parameters\x18\x06 \x01(\v2\x17.google.protobuf.StructR\n" +
+	"parameters\"\x9c\x01\n" +
+	"\n" +
+	"TokenUsage\x12!\n" +
+	"\finput_tokens\x18\x01 \x01(\x05R\vinputTokens\x12#\n" +
+	"\routput_tokens\x18\x02 \x01(\x05R\foutputTokens\x12!\n" +
+	"\ftotal_tokens\x18\x03 \x01(\x05R\vtotalTokens\x12#\n" +
+	"\rcached_tokens\x18\x04 \x01(\x05R\fcachedTokens\"\x9b\x01\n" +
+	"\x10PerformanceStats\x12,\n" +
+	"\x12processing_time_ms\x18\x01 \x01(\x03R\x10processingTimeMs\x12-\n" +
+	"\x13first_token_time_ms\x18\x02 \x01(\x03R\x10firstTokenTimeMs\x12*\n" +
+	"\x11tokens_per_second\x18\x03 \x01(\x01R\x0ftokensPerSecond\"\x88\x02\n" +
+	"\x18ProcessInferenceResponse\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\x12\x1d\n" +
+	"\n" +
+	"model_used\x18\x03 \x01(\tR\tmodelUsed\x12#\n" +
+	"\rfinish_reason\x18\x04 \x01(\tR\ffinishReason\x12(\n" +
+	"\x05usage\x18\x05 \x01(\v2\x12.ocs.v1.TokenUsageR\x05usage\x12E\n" +
+	"\x11performance_stats\x18\x06 \x01(\v2\x18.ocs.v1.PerformanceStatsR\x10performanceStats\"\x8e\x02\n" +
+	"\x17StreamInferenceResponse\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\x12\x12\n" +
+	"\x04done\x18\x03 \x01(\bR\x04done\x12\x1f\n" +
+	"\vtoken_count\x18\x04 \x01(\x05R\n" +
+	"tokenCount\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x12(\n" +
+	"\x05usage\x18\x06 \x01(\v2\x12.ocs.v1.TokenUsageR\x05usage\x12E\n" +
+	"\x11performance_stats\x18\a \x01(\v2\x18.ocs.v1.PerformanceStatsR\x10performanceStats\"~\n" +
+	"\x12ExecuteToolRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x125\n" +
+	"\targuments\x18\x02 \x01(\v2\x17.google.protobuf.StructR\targuments\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x03 \x01(\tR\tsessionId\"_\n" +
+	"\x13ExecuteToolResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error2\x98\x04\n" +
+	"\x03OCS\x12C\n" +
+	"\n" +
+	"ListModels\x12\x19.ocs.v1.ListModelsRequest\x1a\x1a.ocs.v1.ListModelsResponse\x12L\n" +
+	"\rCreateSession\x12\x1c.ocs.v1.CreateSessionRequest\x1a\x1d.ocs.v1.CreateSessionResponse\x12C\n" +
+	"\n" +
+	"GetSession\x12\x19.ocs.v1.GetSessionRequest\x1a\x1a.ocs.v1.GetSessionResponse\x12C\n" +
+	"\n" +
+	"AddMessage\x12\x19.ocs.v1.AddMessageRequest\x1a\x1a.ocs.v1.AddMessageResponse\x12U\n" +
+	"\x10ProcessInference\x12\x1f.ocs.v1.ProcessInferenceRequest\x1a .ocs.v1.ProcessInferenceResponse\x12U\n" +
+	"\x0fStreamInference\x12\x1f.ocs.v1.ProcessInferenceRequest\x1a\x1f.ocs.v1.StreamInferenceResponse0\x01\x12F\n" +
+	"\vExecuteTool\x12\x1a.ocs.v1.ExecuteToolRequest\x1a\x1b.ocs.v1.ExecuteToolResponseB\x1cZ\x1aocs/api/proto/ocs/v1;ocsv1b\x06proto3"
+
+var (
+	file_ocs_v1_ocs_proto_rawDescOnce sync.Once
+	file_ocs_v1_ocs_proto_rawDescData []byte
+)
+
+func file_ocs_v1_ocs_proto_rawDescGZIP() []byte {
+	file_ocs_v1_ocs_proto_rawDescOnce.Do(func() {
+		file_ocs_v1_ocs_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_ocs_v1_ocs_proto_rawDesc), len(file_ocs_v1_ocs_proto_rawDesc)))
+	})
+	return file_ocs_v1_ocs_proto_rawDescData
+}
+
+var file_ocs_v1_ocs_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_ocs_v1_ocs_proto_goTypes = []any{
+	(*ListModelsRequest)(nil),        // 0: ocs.v1.ListModelsRequest
+	(*AvailableModel)(nil),           // 1: ocs.v1.AvailableModel
+	(*ModelInfo)(nil),                // 2: ocs.v1.ModelInfo
+	(*ListModelsResponse)(nil),       // 3: ocs.v1.ListModelsResponse
+	(*SessionSettings)(nil),          // 4: ocs.v1.SessionSettings
+	(*Session)(nil),                  // 5: ocs.v1.Session
+	(*CreateSessionRequest)(nil),     // 6: ocs.v1.CreateSessionRequest
+	(*CreateSessionResponse)(nil),    // 7: ocs.v1.CreateSessionResponse
+	(*GetSessionRequest)(nil),        // 8: ocs.v1.GetSessionRequest
+	(*GetSessionResponse)(nil),       // 9: ocs.v1.GetSessionResponse
+	(*Message)(nil),                  // 10: ocs.v1.Message
+	(*AddMessageRequest)(nil),        // 11: ocs.v1.AddMessageRequest
+	(*AddMessageResponse)(nil),       // 12: ocs.v1.AddMessageResponse
+	(*ProcessInferenceRequest)(nil),  // 13: ocs.v1.ProcessInferenceRequest
+	(*TokenUsage)(nil),               // 14: ocs.v1.TokenUsage
+	(*PerformanceStats)(nil),         // 15: ocs.v1.PerformanceStats
+	(*ProcessInferenceResponse)(nil), // 16: ocs.v1.ProcessInferenceResponse
+	(*StreamInferenceResponse)(nil),  // 17: ocs.v1.StreamInferenceResponse
+	(*ExecuteToolRequest)(nil),       // 18: ocs.v1.ExecuteToolRequest
+	(*ExecuteToolResponse)(nil),      // 19: ocs.v1.ExecuteToolResponse
+	(*structpb.Struct)(nil),          // 20: google.protobuf.Struct
+}
+var file_ocs_v1_ocs_proto_depIdxs = []int32{
+	1,  // 0: ocs.v1.ListModelsResponse.available:type_name -> ocs.v1.AvailableModel
+	2,  // 1: ocs.v1.ListModelsResponse.loaded:type_name -> ocs.v1.ModelInfo
+	4,  // 2: ocs.v1.CreateSessionRequest.settings:type_name -> ocs.v1.SessionSettings
+	5,  // 3: ocs.v1.CreateSessionResponse.session:type_name -> ocs.v1.Session
+	5,  // 4: ocs.v1.GetSessionResponse.session:type_name -> ocs.v1.Session
+	20, // 5: ocs.v1.AddMessageRequest.metadata:type_name -> google.protobuf.Struct
+	10, // 6: ocs.v1.AddMessageResponse.message:type_name -> ocs.v1.Message
+	20, // 7: ocs.v1.ProcessInferenceRequest.parameters:type_name -> google.protobuf.Struct
+	14, // 8: ocs.v1.ProcessInferenceResponse.usage:type_name -> ocs.v1.TokenUsage
+	15, // 9: ocs.v1.ProcessInferenceResponse.performance_stats:type_name -> ocs.v1.PerformanceStats
+	14, // 10: ocs.v1.StreamInferenceResponse.usage:type_name -> ocs.v1.TokenUsage
+	15, // 11: ocs.v1.StreamInferenceResponse.performance_stats:type_name -> ocs.v1.PerformanceStats
+	20, // 12: ocs.v1.ExecuteToolRequest.arguments:type_name -> google.protobuf.Struct
+	0,  // 13: ocs.v1.OCS.ListModels:input_type -> ocs.v1.ListModelsRequest
+	6,  // 14: ocs.v1.OCS.CreateSession:input_type -> ocs.v1.CreateSessionRequest
+	8,  // 15: ocs.v1.OCS.GetSession:input_type -> ocs.v1.GetSessionRequest
+	11, // 16: ocs.v1.OCS.AddMessage:input_type -> ocs.v1.AddMessageRequest
+	13, // 17: ocs.v1.OCS.ProcessInference:input_type -> ocs.v1.ProcessInferenceRequest
+	13, // 18: ocs.v1.OCS.StreamInference:input_type -> ocs.v1.ProcessInferenceRequest
+	18, // 19: ocs.v1.OCS.ExecuteTool:input_type -> ocs.v1.ExecuteToolRequest
+	3,  // 20: ocs.v1.OCS.ListModels:output_type -> ocs.v1.ListModelsResponse
+	7,  // 21: ocs.v1.OCS.CreateSession:output_type -> ocs.v1.CreateSessionResponse
+	9,  // 22: ocs.v1.OCS.GetSession:output_type -> ocs.v1.GetSessionResponse
+	12, // 23: ocs.v1.OCS.AddMessage:output_type -> ocs.v1.AddMessageResponse
+	16, // 24: ocs.v1.OCS.ProcessInference:output_type -> ocs.v1.ProcessInferenceResponse
+	17, // 25: ocs.v1.OCS.StreamInference:output_type -> ocs.v1.StreamInferenceResponse
+	19, // 26: ocs.v1.OCS.ExecuteTool:output_type -> ocs.v1.ExecuteToolResponse
+	20, // [20:27] is the sub-list for method output_type
+	13, // [13:20] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_ocs_v1_ocs_proto_init() }
+func file_ocs_v1_ocs_proto_init() {
+	if File_ocs_v1_ocs_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ocs_v1_ocs_proto_rawDesc), len(file_ocs_v1_ocs_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   20,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_ocs_v1_ocs_proto_goTypes,
+		DependencyIndexes: file_ocs_v1_ocs_proto_depIdxs,
+		MessageInfos:      file_ocs_v1_ocs_proto_msgTypes,
+	}.Build()
+	File_ocs_v1_ocs_proto = out.File
+	file_ocs_v1_ocs_proto_goTypes = nil
+	file_ocs_v1_ocs_proto_depIdxs = nil
+}
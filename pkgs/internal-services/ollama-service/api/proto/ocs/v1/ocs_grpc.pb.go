@@ -0,0 +1,353 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: ocs/v1/ocs.proto
+
+package ocsv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	OCS_ListModels_FullMethodName       = "/ocs.v1.OCS/ListModels"
+	OCS_CreateSession_FullMethodName    = "/ocs.v1.OCS/CreateSession"
+	OCS_GetSession_FullMethodName       = "/ocs.v1.OCS/GetSession"
+	OCS_AddMessage_FullMethodName       = "/ocs.v1.OCS/AddMessage"
+	OCS_ProcessInference_FullMethodName = "/ocs.v1.OCS/ProcessInference"
+	OCS_StreamInference_FullMethodName  = "/ocs.v1.OCS/StreamInference"
+	OCS_ExecuteTool_FullMethodName      = "/ocs.v1.OCS/ExecuteTool"
+)
+
+// OCSClient is the client API for OCS service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type OCSClient interface {
+	ListModels(ctx context.Context, in *ListModelsRequest, opts ...grpc.CallOption) (*ListModelsResponse, error)
+	CreateSession(ctx context.Context, in *CreateSessionRequest, opts ...grpc.CallOption) (*CreateSessionResponse, error)
+	GetSession(ctx context.Context, in *GetSessionRequest, opts ...grpc.CallOption) (*GetSessionResponse, error)
+	AddMessage(ctx context.Context, in *AddMessageRequest, opts ...grpc.CallOption) (*AddMessageResponse, error)
+	ProcessInference(ctx context.Context, in *ProcessInferenceRequest, opts ...grpc.CallOption) (*ProcessInferenceResponse, error)
+	StreamInference(ctx context.Context, in *ProcessInferenceRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamInferenceResponse], error)
+	ExecuteTool(ctx context.Context, in *ExecuteToolRequest, opts ...grpc.CallOption) (*ExecuteToolResponse, error)
+}
+
+type oCSClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOCSClient(cc grpc.ClientConnInterface) OCSClient {
+	return &oCSClient{cc}
+}
+
+func (c *oCSClient) ListModels(ctx context.Context, in *ListModelsRequest, opts ...grpc.CallOption) (*ListModelsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListModelsResponse)
+	err := c.cc.Invoke(ctx, OCS_ListModels_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oCSClient) CreateSession(ctx context.Context, in *CreateSessionRequest, opts ...grpc.CallOption) (*CreateSessionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateSessionResponse)
+	err := c.cc.Invoke(ctx, OCS_CreateSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oCSClient) GetSession(ctx context.Context, in *GetSessionRequest, opts ...grpc.CallOption) (*GetSessionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetSessionResponse)
+	err := c.cc.Invoke(ctx, OCS_GetSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oCSClient) AddMessage(ctx context.Context, in *AddMessageRequest, opts ...grpc.CallOption) (*AddMessageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddMessageResponse)
+	err := c.cc.Invoke(ctx, OCS_AddMessage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oCSClient) ProcessInference(ctx context.Context, in *ProcessInferenceRequest, opts ...grpc.CallOption) (*ProcessInferenceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ProcessInferenceResponse)
+	err := c.cc.Invoke(ctx, OCS_ProcessInference_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oCSClient) StreamInference(ctx context.Context, in *ProcessInferenceRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamInferenceResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &OCS_ServiceDesc.Streams[0], OCS_StreamInference_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ProcessInferenceRequest, StreamInferenceResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OCS_StreamInferenceClient = grpc.ServerStreamingClient[StreamInferenceResponse]
+
+func (c *oCSClient) ExecuteTool(ctx context.Context, in *ExecuteToolRequest, opts ...grpc.CallOption) (*ExecuteToolResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExecuteToolResponse)
+	err := c.cc.Invoke(ctx, OCS_ExecuteTool_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OCSServer is the server API for OCS service.
+// All implementations must embed UnimplementedOCSServer
+// for forward compatibility.
+type OCSServer interface {
+	ListModels(context.Context, *ListModelsRequest) (*ListModelsResponse, error)
+	CreateSession(context.Context, *CreateSessionRequest) (*CreateSessionResponse, error)
+	GetSession(context.Context, *GetSessionRequest) (*GetSessionResponse, error)
+	AddMessage(context.Context, *AddMessageRequest) (*AddMessageResponse, error)
+	ProcessInference(context.Context, *ProcessInferenceRequest) (*ProcessInferenceResponse, error)
+	StreamInference(*ProcessInferenceRequest, grpc.ServerStreamingServer[StreamInferenceResponse]) error
+	ExecuteTool(context.Context, *ExecuteToolRequest) (*ExecuteToolResponse, error)
+	mustEmbedUnimplementedOCSServer()
+}
+
+// UnimplementedOCSServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedOCSServer struct{}
+
+func (UnimplementedOCSServer) ListModels(context.Context, *ListModelsRequest) (*ListModelsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListModels not implemented")
+}
+func (UnimplementedOCSServer) CreateSession(context.Context, *CreateSessionRequest) (*CreateSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSession not implemented")
+}
+func (UnimplementedOCSServer) GetSession(context.Context, *GetSessionRequest) (*GetSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSession not implemented")
+}
+func (UnimplementedOCSServer) AddMessage(context.Context, *AddMessageRequest) (*AddMessageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddMessage not implemented")
+}
+func (UnimplementedOCSServer) ProcessInference(context.Context, *ProcessInferenceRequest) (*ProcessInferenceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ProcessInference not implemented")
+}
+func (UnimplementedOCSServer) StreamInference(*ProcessInferenceRequest, grpc.ServerStreamingServer[StreamInferenceResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamInference not implemented")
+}
+func (UnimplementedOCSServer) ExecuteTool(context.Context, *ExecuteToolRequest) (*ExecuteToolResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExecuteTool not implemented")
+}
+func (UnimplementedOCSServer) mustEmbedUnimplementedOCSServer() {}
+func (UnimplementedOCSServer) testEmbeddedByValue()             {}
+
+// UnsafeOCSServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OCSServer will
+// result in compilation errors.
+type UnsafeOCSServer interface {
+	mustEmbedUnimplementedOCSServer()
+}
+
+func RegisterOCSServer(s grpc.ServiceRegistrar, srv OCSServer) {
+	// If the following call pancis, it indicates UnimplementedOCSServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&OCS_ServiceDesc, srv)
+}
+
+func _OCS_ListModels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListModelsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OCSServer).ListModels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OCS_ListModels_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OCSServer).ListModels(ctx, req.(*ListModelsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OCS_CreateSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OCSServer).CreateSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OCS_CreateSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OCSServer).CreateSession(ctx, req.(*CreateSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OCS_GetSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OCSServer).GetSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OCS_GetSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OCSServer).GetSession(ctx, req.(*GetSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OCS_AddMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OCSServer).AddMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OCS_AddMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OCSServer).AddMessage(ctx, req.(*AddMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OCS_ProcessInference_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProcessInferenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OCSServer).ProcessInference(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OCS_ProcessInference_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OCSServer).ProcessInference(ctx, req.(*ProcessInferenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OCS_StreamInference_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ProcessInferenceRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OCSServer).StreamInference(m, &grpc.GenericServerStream[ProcessInferenceRequest, StreamInferenceResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type OCS_StreamInferenceServer = grpc.ServerStreamingServer[StreamInferenceResponse]
+
+func _OCS_ExecuteTool_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExecuteToolRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OCSServer).ExecuteTool(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OCS_ExecuteTool_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OCSServer).ExecuteTool(ctx, req.(*ExecuteToolRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// OCS_ServiceDesc is the grpc.ServiceDesc for OCS service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OCS_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ocs.v1.OCS",
+	HandlerType: (*OCSServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListModels",
+			Handler:    _OCS_ListModels_Handler,
+		},
+		{
+			MethodName: "CreateSession",
+			Handler:    _OCS_CreateSession_Handler,
+		},
+		{
+			MethodName: "GetSession",
+			Handler:    _OCS_GetSession_Handler,
+		},
+		{
+			MethodName: "AddMessage",
+			Handler:    _OCS_AddMessage_Handler,
+		},
+		{
+			MethodName: "ProcessInference",
+			Handler:    _OCS_ProcessInference_Handler,
+		},
+		{
+			MethodName: "ExecuteTool",
+			Handler:    _OCS_ExecuteTool_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamInference",
+			Handler:       _OCS_StreamInference_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "ocs/v1/ocs.proto",
+}
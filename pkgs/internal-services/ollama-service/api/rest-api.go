@@ -8,8 +8,10 @@ import (
 	// stdlib
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,6 +22,7 @@ import (
 	// internal
 	"ocs/managers"
 	"ocs/src/tools"
+	"ocs/src/types"
 )
 
 // RESTAPI handles HTTP REST endpoints for OCS
@@ -31,9 +34,7 @@ type RESTAPI struct {
 	tokenManager     *managers.TokenManager
 	diskManager      *managers.DiskManager
 	conversationMgr  *managers.ConversationManager
-	codeTool         *tools.CodeTool
-	fileTool         *tools.FileTool
-	searchTool       *tools.SearchTool
+	toolRegistry     *tools.ToolRegistry
 }
 
 // NewRESTAPI creates a new REST API instance
@@ -45,9 +46,7 @@ func NewRESTAPI(
 	tokenManager *managers.TokenManager,
 	diskManager *managers.DiskManager,
 	conversationMgr *managers.ConversationManager,
-	codeTool *tools.CodeTool,
-	fileTool *tools.FileTool,
-	searchTool *tools.SearchTool,
+	toolRegistry *tools.ToolRegistry,
 ) *RESTAPI {
 	return &RESTAPI{
 		configManager:    configManager,
@@ -57,25 +56,15 @@ func NewRESTAPI(
 		tokenManager:     tokenManager,
 		diskManager:      diskManager,
 		conversationMgr:  conversationMgr,
-		codeTool:         codeTool,
-		fileTool:         fileTool,
-		searchTool:       searchTool,
+		toolRegistry:     toolRegistry,
 	}
 }
 
 // Start starts the REST API server
 func (api *RESTAPI) Start(ctx context.Context, addr string) error {
-	router := mux.NewRouter()
-	router.HandleFunc("/api/v1/models", api.handleListModels).Methods("GET")
-	router.HandleFunc("/api/v1/sessions", api.handleCreateSession).Methods("POST")
-	router.HandleFunc("/api/v1/sessions/{sessionID}", api.handleGetSession).Methods("GET")
-	router.HandleFunc("/api/v1/sessions/{sessionID}/messages", api.handleAddMessage).Methods("POST")
-	router.HandleFunc("/api/v1/inference", api.handleInference).Methods("POST")
-	router.HandleFunc("/api/v1/tools", api.handleToolCall).Methods("POST")
-
 	srv := &http.Server{
 		Addr:         addr,
-		Handler:      router,
+		Handler:      api.Handler(),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
@@ -90,10 +79,43 @@ func (api *RESTAPI) Start(ctx context.Context, addr string) error {
 	return srv.Shutdown(context.Background())
 }
 
+// Handler returns the router serving every REST and OpenAI-compatible endpoint
+func (api *RESTAPI) Handler() http.Handler {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/models", api.handleListModels).Methods("GET")
+	router.HandleFunc("/api/v1/sessions", api.handleCreateSession).Methods("POST")
+	router.HandleFunc("/api/v1/sessions/{sessionID}", api.handleGetSession).Methods("GET")
+	router.HandleFunc("/api/v1/sessions/{sessionID}/messages", api.handleAddMessage).Methods("POST")
+	router.HandleFunc("/api/v1/sessions/{sessionID}/branches", api.handleListBranches).Methods("GET")
+	router.HandleFunc("/api/v1/sessions/{sessionID}/branches", api.handleForkBranch).Methods("POST")
+	router.HandleFunc("/api/v1/sessions/{sessionID}/branches/{branchID}/activate", api.handleSwitchBranch).Methods("POST")
+	router.HandleFunc("/api/v1/sessions/{sessionID}/branches/{branchID}/merge", api.handleMergeBranch).Methods("POST")
+	router.HandleFunc("/api/v1/conversations/{conversationID}/flow", api.handleStartFlow).Methods("POST")
+	router.HandleFunc("/api/v1/conversations/{conversationID}/flow/advance", api.handleAdvanceFlow).Methods("POST")
+	router.HandleFunc("/api/v1/conversations/{conversationID}/flow/actions/{actionID}", api.handleConfirmFlowAction).Methods("POST")
+	router.HandleFunc("/api/v1/inference", api.handleInference).Methods("POST")
+	router.HandleFunc("/api/v1/prompts/preview", api.handlePromptPreview).Methods("POST")
+	router.HandleFunc("/api/v1/approvals", api.handleListApprovals).Methods("GET")
+	router.HandleFunc("/api/v1/approvals/audit", api.handleApprovalAudit).Methods("GET")
+	router.HandleFunc("/api/v1/approvals/{actionID}", api.handleResolveApproval).Methods("POST")
+	router.HandleFunc("/api/v1/routing/explain", api.handleExplainRouting).Methods("POST")
+	router.HandleFunc("/api/v1/routing/pins", api.handleListRoutingPins).Methods("GET")
+	router.HandleFunc("/api/v1/routing/pins", api.handlePinModel).Methods("PUT")
+	router.HandleFunc("/api/v1/routing/pins", api.handleUnpinModel).Methods("DELETE")
+	router.HandleFunc("/api/v1/tools", api.handleListTools).Methods("GET")
+	router.HandleFunc("/api/v1/tools", api.handleToolCall).Methods("POST")
+
+	// OpenAI-compatible surface
+	router.HandleFunc("/v1/chat/completions", api.handleOpenAIChatCompletions).Methods("POST")
+	router.HandleFunc("/v1/embeddings", api.handleOpenAIEmbeddings).Methods("POST")
+	router.HandleFunc("/v1/models", api.handleOpenAIListModels).Methods("GET")
+	return router
+}
+
 // handleListModels returns available models
 func (api *RESTAPI) handleListModels(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	availableModels, err := api.modelManager.listAvailableModels(ctx)
+	availableModels, err := api.modelManager.ListAvailableModels(ctx)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("failed to list models: %v", err), http.StatusInternalServerError)
 		return
@@ -103,6 +125,7 @@ func (api *RESTAPI) handleListModels(w http.ResponseWriter, r *http.Request) {
 	response := map[string]interface{}{
 		"available": availableModels.Models,
 		"loaded":    loadedModels,
+		"backends":  api.modelManager.GetBackendStatus(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -176,63 +199,492 @@ func (api *RESTAPI) handleAddMessage(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleInference processes an inference request
+// callerSession checks that the authenticated caller owns sessionID, answering 401 when
+// there is no caller and 403 when the session is missing or someone else's
+func (api *RESTAPI) callerSession(w http.ResponseWriter, r *http.Request, sessionID string) (*managers.Caller, bool) {
+	caller, ok := managers.CallerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return nil, false
+	}
+	if session, exists := api.sessionManager.GetSession(sessionID); !exists || session.UserID != caller.UserID {
+		http.Error(w, "session not found: "+sessionID, http.StatusForbidden)
+		return nil, false
+	}
+	return caller, true
+}
+
+// handleListBranches returns a session's branch tree
+func (api *RESTAPI) handleListBranches(w http.ResponseWriter, r *http.Request) {
+	sessionID := mux.Vars(r)["sessionID"]
+	if _, ok := api.callerSession(w, r, sessionID); !ok {
+		return
+	}
+
+	tree, err := api.sessionManager.GetBranchTree(sessionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tree); err != nil {
+		log.Error().Err(err).Msg("Failed to encode branch tree response")
+	}
+}
+
+// handleForkBranch forks a session at a message and makes the new branch active
+func (api *RESTAPI) handleForkBranch(w http.ResponseWriter, r *http.Request) {
+	sessionID := mux.Vars(r)["sessionID"]
+	if _, ok := api.callerSession(w, r, sessionID); !ok {
+		return
+	}
+
+	var req struct {
+		MessageID string `json:"message_id"`
+		Name      string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MessageID == "" {
+		http.Error(w, "message_id is required", http.StatusBadRequest)
+		return
+	}
+
+	branch, err := api.sessionManager.ForkSession(sessionID, req.MessageID, req.Name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to fork session: %v", err), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(branch); err != nil {
+		log.Error().Err(err).Msg("Failed to encode branch response")
+	}
+}
+
+// handleSwitchBranch makes a branch the one session context follows
+func (api *RESTAPI) handleSwitchBranch(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if _, ok := api.callerSession(w, r, vars["sessionID"]); !ok {
+		return
+	}
+	if err := api.sessionManager.SwitchBranch(vars["sessionID"], vars["branchID"]); err != nil {
+		http.Error(w, fmt.Sprintf("failed to switch branch: %v", err), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMergeBranch merges a branch summary into its parent branch, summarizing the
+// branch with the summary model when no summary is given
+func (api *RESTAPI) handleMergeBranch(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if _, ok := api.callerSession(w, r, vars["sessionID"]); !ok {
+		return
+	}
+
+	var req struct {
+		Summary string `json:"summary"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.Summary == "" {
+		summary, err := api.inferenceManager.SummarizeBranch(r.Context(), vars["sessionID"], vars["branchID"])
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to summarize branch: %v", err), http.StatusUnprocessableEntity)
+			return
+		}
+		req.Summary = summary
+	}
+
+	mergePoint, err := api.sessionManager.MergeBranch(vars["sessionID"], vars["branchID"], req.Summary)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to merge branch: %v", err), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(mergePoint); err != nil {
+		log.Error().Err(err).Msg("Failed to encode merge response")
+	}
+}
+
+// callerConversation checks that the authenticated caller owns conversationID, answering
+// 401 when there is no caller and 403 when the conversation is missing or someone else's
+func (api *RESTAPI) callerConversation(w http.ResponseWriter, r *http.Request, conversationID string) bool {
+	caller, ok := managers.CallerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return false
+	}
+	if conversation, exists := api.conversationMgr.GetConversation(conversationID); !exists || conversation.UserID != caller.UserID {
+		http.Error(w, "conversation not found: "+conversationID, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// handleStartFlow starts a flow from configs/flows.yaml on a conversation
+func (api *RESTAPI) handleStartFlow(w http.ResponseWriter, r *http.Request) {
+	conversationID := mux.Vars(r)["conversationID"]
+	if !api.callerConversation(w, r, conversationID) {
+		return
+	}
+
+	var req struct {
+		Flow string                 `json:"flow"`
+		Vars map[string]interface{} `json:"vars"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Flow == "" {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.conversationMgr.StartFlow(r.Context(), conversationID, req.Flow, req.Vars)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to start flow: %v", err), http.StatusConflict)
+		return
+	}
+	api.writeFlowResponse(w, response)
+}
+
+// handleAdvanceFlow runs the next step of a flow that does not auto-continue
+func (api *RESTAPI) handleAdvanceFlow(w http.ResponseWriter, r *http.Request) {
+	conversationID := mux.Vars(r)["conversationID"]
+	if !api.callerConversation(w, r, conversationID) {
+		return
+	}
+
+	response, err := api.conversationMgr.AdvanceFlow(r.Context(), conversationID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to advance flow: %v", err), http.StatusConflict)
+		return
+	}
+	api.writeFlowResponse(w, response)
+}
+
+// handleConfirmFlowAction approves or rejects a flow step waiting for confirmation
+func (api *RESTAPI) handleConfirmFlowAction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if !api.callerConversation(w, r, vars["conversationID"]) {
+		return
+	}
+
+	var req struct {
+		Approved bool `json:"approved"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	response, err := api.conversationMgr.ConfirmFlowAction(r.Context(), vars["conversationID"], vars["actionID"], req.Approved)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to confirm flow action: %v", err), http.StatusConflict)
+		return
+	}
+	api.writeFlowResponse(w, response)
+}
+
+func (api *RESTAPI) writeFlowResponse(w http.ResponseWriter, response *managers.ConversationResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error().Err(err).Msg("Failed to encode flow response")
+	}
+}
+
+// handleListApprovals lists the caller's tool calls waiting for approval, optionally for one session
+func (api *RESTAPI) handleListApprovals(w http.ResponseWriter, r *http.Request) {
+	caller, ok := managers.CallerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	approvals := api.inferenceManager.GetToolApprovals().Pending(caller.UserID, r.URL.Query().Get("session_id"))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"approvals": approvals}); err != nil {
+		log.Error().Err(err).Msg("Failed to encode approvals response")
+	}
+}
+
+// handleApprovalAudit lists the caller's decided tool approvals, optionally for one session
+func (api *RESTAPI) handleApprovalAudit(w http.ResponseWriter, r *http.Request) {
+	caller, ok := managers.CallerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	records := api.inferenceManager.GetToolApprovals().Audit(caller.UserID, r.URL.Query().Get("session_id"))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"records": records}); err != nil {
+		log.Error().Err(err).Msg("Failed to encode approval audit response")
+	}
+}
+
+// handleResolveApproval approves or rejects one of the caller's parked tool calls
+func (api *RESTAPI) handleResolveApproval(w http.ResponseWriter, r *http.Request) {
+	caller, ok := managers.CallerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Approved bool   `json:"approved"`
+		Reason   string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	approval, err := api.inferenceManager.GetToolApprovals().Resolve(mux.Vars(r)["actionID"], req.Approved, caller.UserID, req.Reason)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to resolve approval: %v", err), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(approval); err != nil {
+		log.Error().Err(err).Msg("Failed to encode approval response")
+	}
+}
+
+// handleExplainRouting reports which model the caller's request would be routed to and
+// why, without running inference
+func (api *RESTAPI) handleExplainRouting(w http.ResponseWriter, r *http.Request) {
+	caller, ok := managers.CallerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Prompt        string             `json:"prompt"`
+		Messages      []managers.Message `json:"messages"` // used instead of prompt when set
+		ModelName     string             `json:"model_name"`
+		InferenceType string             `json:"inference_type"`
+		MaxTokens     int                `json:"max_tokens"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	messages := req.Messages
+	if len(messages) == 0 {
+		messages = []managers.Message{{Role: "user", Content: req.Prompt}}
+	}
+	decision, err := api.inferenceManager.GetRoutingEngine().Route(&managers.InferenceRequest{
+		UserID:      caller.UserID,
+		ModelName:   req.ModelName,
+		RequestType: managers.InferenceType(strings.ToLower(req.InferenceType)),
+		Messages:    messages,
+		Parameters:  &managers.InferenceParameters{MaxTokens: req.MaxTokens},
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("no route: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(decision); err != nil {
+		log.Error().Err(err).Msg("Failed to encode routing decision")
+	}
+}
+
+// handleListRoutingPins lists the caller's pinned models by intent
+func (api *RESTAPI) handleListRoutingPins(w http.ResponseWriter, r *http.Request) {
+	caller, ok := managers.CallerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	pins := api.inferenceManager.GetRoutingEngine().Pins(caller.UserID)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"pins": pins}); err != nil {
+		log.Error().Err(err).Msg("Failed to encode routing pins response")
+	}
+}
+
+// handlePinModel pins a model for the caller's requests with an intent, or all intents
+func (api *RESTAPI) handlePinModel(w http.ResponseWriter, r *http.Request) {
+	caller, ok := managers.CallerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Intent string `json:"intent"` // empty pins every intent
+		Model  string `json:"model"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := api.inferenceManager.GetRoutingEngine().PinModel(caller.UserID, req.Intent, req.Model); err != nil {
+		http.Error(w, fmt.Sprintf("failed to pin model: %v", err), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleUnpinModel removes the caller's pin for an intent
+func (api *RESTAPI) handleUnpinModel(w http.ResponseWriter, r *http.Request) {
+	caller, ok := managers.CallerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
+	api.inferenceManager.GetRoutingEngine().UnpinModel(caller.UserID, r.URL.Query().Get("intent"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePromptPreview renders a prompt template, persona or inline template against
+// caller-supplied data without running inference
+func (api *RESTAPI) handlePromptPreview(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Template string `json:"template"`
+		Persona  string `json:"persona"`
+		Text     string `json:"text"` // inline template, used when template and persona are empty
+		managers.PromptData
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	engine := managers.NewPromptEngine(api.configManager)
+	var prompt string
+	var err error
+	switch {
+	case req.Persona != "":
+		fallback := req.Template
+		if fallback == "" {
+			fallback = managers.SessionSystemTemplate
+		}
+		prompt, err = engine.RenderPersona(req.Persona, fallback, &req.PromptData)
+	case req.Template != "":
+		prompt, err = engine.Render(req.Template, &req.PromptData)
+	case req.Text != "":
+		prompt, err = engine.RenderText(req.Text, &req.PromptData)
+	default:
+		http.Error(w, "template, persona or text is required", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to render prompt: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"prompt": prompt}); err != nil {
+		log.Error().Err(err).Msg("Failed to encode prompt preview response")
+	}
+}
+
+// handleInference processes an inference request made by the authenticated caller; the
+// caller's user keys cache isolation, fair queueing and approvals
 func (api *RESTAPI) handleInference(w http.ResponseWriter, r *http.Request) {
+	caller, ok := managers.CallerFromContext(r.Context())
+	if !ok {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+
 	var req struct {
-		UserID        string                 `json:"user_id"`
-		SessionID     string                 `json:"session_id"`
-		Prompt        string                 `json:"prompt"`
-		ModelName     string                 `json:"model_name"`
-		InferenceType string                 `json:"inference_type"`
-		Parameters    map[string]interface{} `json:"parameters"`
-		Metadata      map[string]interface{} `json:"metadata"`
+		SessionID     string                       `json:"session_id"`
+		Prompt        string                       `json:"prompt"`
+		ModelName     string                       `json:"model_name"`
+		InferenceType string                       `json:"inference_type"`
+		Stream        bool                         `json:"stream"`
+		Priority      string                       `json:"priority"`        // interactive (default) or batch
+		Cache         bool                         `json:"cache"`           // serve repeats from the response cache
+		Schema        map[string]interface{}       `json:"response_schema"` // JSON Schema the reply must satisfy
+		SchemaRetries int                          `json:"schema_retries"`
+		Parameters    managers.InferenceParameters `json:"parameters"` // same names as InferenceParameters' JSON tags
+		Metadata      map[string]interface{}       `json:"metadata"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
+	if req.SessionID != "" {
+		if session, exists := api.sessionManager.GetSession(req.SessionID); !exists || session.UserID != caller.UserID {
+			http.Error(w, "session not found: "+req.SessionID, http.StatusForbidden)
+			return
+		}
+	}
 
 	var inferenceReq *managers.InferenceRequest
 	switch strings.ToLower(req.InferenceType) {
 	case "code":
 		inferenceReq = &managers.InferenceRequest{
-			ID:          fmt.Sprintf("code_%s", req.UserID),
-			UserID:      req.UserID,
+			ID:          fmt.Sprintf("code_%s_%d", caller.UserID, time.Now().UnixNano()),
+			UserID:      caller.UserID,
 			SessionID:   req.SessionID,
 			ModelName:   req.ModelName,
 			RequestType: managers.InferenceTypeCode,
 			Messages:    []managers.Message{{Role: "user", Content: req.Prompt}},
-			Parameters:  &managers.InferenceParameters{Parameters: req.Parameters},
+			Parameters:  &req.Parameters,
 		}
 	case "chat":
 		inferenceReq = &managers.InferenceRequest{
-			ID:          fmt.Sprintf("chat_%s", req.UserID),
-			UserID:      req.UserID,
+			ID:          fmt.Sprintf("chat_%s_%d", caller.UserID, time.Now().UnixNano()),
+			UserID:      caller.UserID,
 			SessionID:   req.SessionID,
 			ModelName:   req.ModelName,
 			RequestType: managers.InferenceTypeChat,
 			Messages:    []managers.Message{{Role: "user", Content: req.Prompt}},
-			Parameters:  &managers.InferenceParameters{Parameters: req.Parameters},
+			Parameters:  &req.Parameters,
 		}
 	case "reasoning":
 		inferenceReq = &managers.InferenceRequest{
-			ID:          fmt.Sprintf("reasoning_%s", req.UserID),
-			UserID:      req.UserID,
+			ID:          fmt.Sprintf("reasoning_%s_%d", caller.UserID, time.Now().UnixNano()),
+			UserID:      caller.UserID,
 			SessionID:   req.SessionID,
 			ModelName:   req.ModelName,
 			RequestType: managers.InferenceTypeReasoning,
 			Messages:    []managers.Message{{Role: "user", Content: req.Prompt}},
-			Parameters:  &managers.InferenceParameters{Parameters: req.Parameters},
+			Parameters:  &req.Parameters,
 		}
 	default:
 		http.Error(w, "invalid inference type", http.StatusBadRequest)
 		return
 	}
 
+	switch priority := managers.InferencePriority(req.Priority); priority {
+	case "", managers.PriorityInteractive, managers.PriorityBatch:
+		inferenceReq.Priority = priority
+	default:
+		http.Error(w, "invalid priority", http.StatusBadRequest)
+		return
+	}
+
+	inferenceReq.Parameters.UseCache = req.Cache
+	inferenceReq.Parameters.ResponseSchema = req.Schema
+	inferenceReq.Parameters.SchemaRetries = req.SchemaRetries
+
+	if req.Stream || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		api.streamInference(w, r, inferenceReq)
+		return
+	}
+
 	result, err := api.inferenceManager.ProcessInference(r.Context(), inferenceReq)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("inference failed: %v", err), http.StatusInternalServerError)
+		writeInferenceError(w, err)
 		return
 	}
 
@@ -242,28 +694,133 @@ func (api *RESTAPI) handleInference(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// sseHeartbeatInterval keeps idle SSE connections open through proxies
+var sseHeartbeatInterval = 15 * time.Second
+
+// streamInference relays stream chunks as Server-Sent Events
+func (api *RESTAPI) streamInference(w http.ResponseWriter, r *http.Request, inferenceReq *managers.InferenceRequest) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// The upstream request is cancelled explicitly when the client goes away
+	streamChan, err := api.inferenceManager.ProcessStreamingInference(context.WithoutCancel(r.Context()), inferenceReq)
+	if err != nil {
+		writeInferenceError(w, err)
+		return
+	}
+
+	// Long generations outlive the server write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	writeEvent := func(event string, payload interface{}) error {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	abort := func(reason string) {
+		if err := api.inferenceManager.CancelInference(inferenceReq.ID); err != nil {
+			log.Debug().Err(err).Str("request_id", inferenceReq.ID).Msg("Inference already finished")
+		}
+		// Drain so the producer never blocks on a full channel
+		go func() {
+			for range streamChan {
+			}
+		}()
+		log.Info().Str("request_id", inferenceReq.ID).Str("reason", reason).Msg("Streaming inference cancelled")
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case chunk, ok := <-streamChan:
+			if !ok {
+				return
+			}
+			if chunk.Error != "" {
+				writeEvent("error", map[string]string{"request_id": inferenceReq.ID, "error": chunk.Error})
+				return
+			}
+			if chunk.Content != "" || len(chunk.ToolCalls) > 0 || len(chunk.Steps) > 0 {
+				if err := writeEvent("chunk", chunk); err != nil {
+					abort("write failed")
+					return
+				}
+			}
+			if chunk.Done {
+				writeEvent("done", map[string]interface{}{
+					"request_id":        inferenceReq.ID,
+					"model_used":        inferenceReq.ModelName,
+					"structured_output": chunk.StructuredOutput,
+					"usage":             chunk.Usage,
+					"performance_stats": chunk.PerformanceStats,
+				})
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				abort("write failed")
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			abort("client disconnected")
+			return
+		}
+	}
+}
+
+// handleListTools returns the tool catalogue with argument schemas
+func (api *RESTAPI) handleListTools(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"tools": api.toolRegistry.List()}); err != nil {
+		log.Error().Err(err).Msg("Failed to encode tools response")
+	}
+}
+
 // handleToolCall processes a tool call
 func (api *RESTAPI) handleToolCall(w http.ResponseWriter, r *http.Request) {
-	var toolCall managers.ToolCall
-	if err := json.NewDecoder(r.Body).Decode(&toolCall); err != nil {
+	var req struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
+	if _, ok := api.toolRegistry.Get(req.Name); !ok {
+		http.Error(w, fmt.Sprintf("unknown tool: %s", req.Name), http.StatusBadRequest)
+		return
+	}
 
-	var result *managers.ToolResult
-	var err error
-	switch {
-	case strings.HasPrefix(toolCall.Name, "execute_code") || strings.HasPrefix(toolCall.Name, "format_code") || strings.HasPrefix(toolCall.Name, "validate_code"):
-		result, err = api.codeTool.Execute(r.Context(), &toolCall)
-	case strings.HasPrefix(toolCall.Name, "read_file") || strings.HasPrefix(toolCall.Name, "write_file") || strings.HasPrefix(toolCall.Name, "delete_file") || strings.HasPrefix(toolCall.Name, "list_files"):
-		result, err = api.fileTool.Execute(r.Context(), &toolCall)
-	case strings.HasPrefix(toolCall.Name, "search_memory") || strings.HasPrefix(toolCall.Name, "search_conversation"):
-		result, err = api.searchTool.Execute(r.Context(), &toolCall)
-	default:
-		http.Error(w, fmt.Sprintf("unknown tool: %s", toolCall.Name), http.StatusBadRequest)
+	if _, ok := managers.CallerFromContext(r.Context()); !ok {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
 		return
 	}
 
+	// The call runs in the authenticated caller's session, whatever the arguments say
+	toolCall := &types.ToolCall{Name: req.Name, Arguments: req.Arguments}
+	result, err := api.toolRegistry.Execute(r.Context(), toolCall)
+	if errors.Is(err, tools.ErrToolPermission) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, fmt.Sprintf("tool execution failed: %v", err), http.StatusInternalServerError)
 		return
@@ -274,3 +831,31 @@ func (api *RESTAPI) handleToolCall(w http.ResponseWriter, r *http.Request) {
 		log.Error().Err(err).Msg("Failed to encode tool result")
 	}
 }
+
+// writeInferenceError reports a failed inference; a full queue is a 429 the client may retry
+func writeInferenceError(w http.ResponseWriter, err error) {
+	if setRetryAfter(w, err) {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	var schemaErr *managers.StructuredOutputError
+	if errors.As(err, &schemaErr) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	http.Error(w, fmt.Sprintf("inference failed: %v", err), http.StatusInternalServerError)
+}
+
+// setRetryAfter sets the Retry-After header when err is a scheduler rejection
+func setRetryAfter(w http.ResponseWriter, err error) bool {
+	var queueErr *managers.QueueFullError
+	if !errors.As(err, &queueErr) {
+		return false
+	}
+	seconds := int(queueErr.RetryAfter.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	return true
+}
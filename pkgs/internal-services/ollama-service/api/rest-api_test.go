@@ -0,0 +1,541 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = api/rest-api_test.go
+
+package api
+
+import (
+	// stdlib
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	// internal
+	"ocs/managers"
+	"ocs/src/tools"
+)
+
+// restSuite is the REST API served by httptest over a fake Ollama
+type restSuite struct {
+	server           *httptest.Server
+	inferenceManager *managers.InferenceManager
+	sessionManager   *managers.SessionManager
+	tokenManager     *managers.TokenManager
+	conversationMgr  *managers.ConversationManager
+}
+
+func newRESTSuite(t *testing.T, ollamaURL string) *restSuite {
+	t.Helper()
+	configManager := loadTestConfig(t)
+
+	modelManager := managers.NewModelManager(ollamaURL, configManager)
+	memoryManager := managers.NewMemoryManager(configManager)
+	sessionManager := managers.NewSessionManager(configManager, nil, memoryManager)
+	tokenManager := managers.NewTokenManager(configManager)
+	inferenceManager := managers.NewInferenceManager(configManager, modelManager, tokenManager, memoryManager, sessionManager, ollamaURL)
+	conversationMgr := managers.NewConversationManager(configManager, sessionManager, memoryManager, tokenManager, inferenceManager)
+	t.Cleanup(func() {
+		memoryManager.Shutdown(context.Background())
+		sessionManager.Shutdown(context.Background())
+	})
+
+	registry := tools.NewToolRegistry(sessionManager)
+	if err := registry.Register(echoTool{}); err != nil {
+		t.Fatal(err)
+	}
+	api := NewRESTAPI(configManager, modelManager, sessionManager, inferenceManager, tokenManager, nil, conversationMgr, registry)
+	server := httptest.NewServer(authenticated(api.Handler()))
+	t.Cleanup(server.Close)
+	return &restSuite{server: server, inferenceManager: inferenceManager, sessionManager: sessionManager, tokenManager: tokenManager, conversationMgr: conversationMgr}
+}
+
+// authenticated stands in for the JWT middleware, resolving tokens with testAuthenticator
+func authenticated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller, err := testAuthenticator(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(managers.WithCaller(r.Context(), caller)))
+	})
+}
+
+// do sends a JSON request with token as the bearer credential
+func (s *restSuite) do(t *testing.T, ctx context.Context, method, path, token, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, method, s.server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// sseEvent is one Server-Sent Event, or a comment line when name is empty
+type sseEvent struct {
+	name    string
+	data    string
+	comment string
+}
+
+// readSSE parses events from body until it ends or stop returns true
+func readSSE(t *testing.T, body *bufio.Reader, stop func(sseEvent) bool) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var current sseEvent
+	for {
+		line, err := body.ReadString('\n')
+		if err != nil {
+			return events
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, ":"):
+			comment := sseEvent{comment: strings.TrimSpace(line[1:])}
+			events = append(events, comment)
+			if stop(comment) {
+				return events
+			}
+		case strings.HasPrefix(line, "event: "):
+			current.name = line[len("event: "):]
+		case strings.HasPrefix(line, "data: "):
+			current.data = line[len("data: "):]
+		case line == "" && current.name != "":
+			events = append(events, current)
+			if stop(current) {
+				return events
+			}
+			current = sseEvent{}
+		}
+	}
+}
+
+// postInference sends an SSE inference request as alice and returns the open response
+func postInference(t *testing.T, ctx context.Context, url, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+"/api/v1/inference", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", "Bearer alice:chat")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /api/v1/inference: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		resp.Body.Close()
+		t.Fatalf("status = %d, content type = %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	return resp
+}
+
+// newStallingOllama streams one chunk per /api/chat call, then waits for release or the
+// caller to go away; aborted receives once for every request cancelled upstream
+func newStallingOllama(t *testing.T, release <-chan struct{}, aborted chan<- struct{}) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tags", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"models":[{"name":"` + testModel + `"}]}`))
+	})
+	mux.HandleFunc("GET /api/ps", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"models":[]}`))
+	})
+	mux.HandleFunc("POST /api/generate", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"done":true}`))
+	})
+	mux.HandleFunc("POST /api/chat", func(w http.ResponseWriter, r *http.Request) {
+		encoder := json.NewEncoder(w)
+		encoder.Encode(&managers.OllamaResponse{Model: testModel, Message: &managers.OllamaMessage{Role: "assistant", Content: "thinking "}})
+		w.(http.Flusher).Flush()
+
+		select {
+		case <-release:
+			encoder.Encode(&managers.OllamaResponse{Model: testModel, Message: &managers.OllamaMessage{Role: "assistant", Content: "done"}})
+			encoder.Encode(&managers.OllamaResponse{Model: testModel, Message: &managers.OllamaMessage{Role: "assistant"}, Done: true, PromptEvalCount: 5, EvalCount: 2})
+		case <-r.Context().Done():
+			aborted <- struct{}{}
+		}
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRESTInferenceSSE(t *testing.T) {
+	suite := newRESTSuite(t, newFakeOllama(t).URL)
+
+	resp := postInference(t, context.Background(), suite.server.URL,
+		`{"user_id":"alice","model_name":"llama3.2","inference_type":"chat","prompt":"stream these words","parameters":{"temperature":0.2,"max_tokens":64}}`)
+	defer resp.Body.Close()
+	events := readSSE(t, bufio.NewReader(resp.Body), func(event sseEvent) bool { return event.name == "done" })
+
+	var content strings.Builder
+	chunks := 0
+	for _, event := range events[:len(events)-1] {
+		if event.comment != "" {
+			continue
+		}
+		if event.name != "chunk" {
+			t.Fatalf("unexpected event %+v", event)
+		}
+		var chunk managers.StreamChunk
+		if err := json.Unmarshal([]byte(event.data), &chunk); err != nil {
+			t.Fatalf("chunk %q: %v", event.data, err)
+		}
+		content.WriteString(chunk.Content)
+		chunks++
+	}
+	if got := strings.TrimSpace(content.String()); got != "echo: stream these words" || chunks < 4 {
+		t.Errorf("content = %q over %d chunks", got, chunks)
+	}
+
+	last := events[len(events)-1]
+	var done struct {
+		RequestID  string               `json:"request_id"`
+		ModelUsed  string               `json:"model_used"`
+		Usage      *managers.TokenUsage `json:"usage"`
+		Statistics interface{}          `json:"performance_stats"`
+	}
+	if err := json.Unmarshal([]byte(last.data), &done); err != nil {
+		t.Fatalf("done %q: %v", last.data, err)
+	}
+	if done.RequestID == "" || done.ModelUsed != testModel || done.Usage == nil || done.Usage.TotalTokens != 10 || done.Statistics == nil {
+		t.Errorf("done = %s", last.data)
+	}
+
+	// A streamed reply that misses the response schema ends in an error, not done
+	resp = postInference(t, context.Background(), suite.server.URL,
+		`{"model_name":"llama3.2","inference_type":"chat","prompt":"not json","response_schema":{"type":"object"}}`)
+	defer resp.Body.Close()
+	events = readSSE(t, bufio.NewReader(resp.Body), func(event sseEvent) bool { return event.name == "done" || event.name == "error" })
+	if last := events[len(events)-1]; last.name != "error" || !strings.Contains(last.data, "did not match the schema") {
+		t.Errorf("schema stream ended with %+v", last)
+	}
+}
+
+func TestRESTInferenceSSEHeartbeat(t *testing.T) {
+	defer func(interval time.Duration) { sseHeartbeatInterval = interval }(sseHeartbeatInterval)
+	sseHeartbeatInterval = 10 * time.Millisecond
+
+	release := make(chan struct{})
+	suite := newRESTSuite(t, newStallingOllama(t, release, make(chan struct{}, 1)).URL)
+
+	resp := postInference(t, context.Background(), suite.server.URL, `{"user_id":"alice","model_name":"llama3.2","inference_type":"chat","prompt":"hi"}`)
+	defer resp.Body.Close()
+	body := bufio.NewReader(resp.Body)
+
+	// Heartbeats keep the connection alive while the model is silent
+	heartbeats := 0
+	events := readSSE(t, body, func(event sseEvent) bool {
+		if event.comment == "heartbeat" {
+			heartbeats++
+		}
+		return heartbeats == 2
+	})
+	chunks := 0
+	for _, event := range events {
+		if event.name == "chunk" {
+			chunks++
+		}
+	}
+	if chunks != 1 {
+		t.Errorf("events before release = %+v", events)
+	}
+
+	close(release)
+	events = readSSE(t, body, func(event sseEvent) bool { return event.name == "done" })
+	if last := events[len(events)-1]; last.name != "done" || !strings.Contains(last.data, `"total_tokens":7`) {
+		t.Errorf("events after release = %+v", events)
+	}
+}
+
+func TestRESTInferenceSSECancelOnDisconnect(t *testing.T) {
+	aborted := make(chan struct{}, 1)
+	suite := newRESTSuite(t, newStallingOllama(t, make(chan struct{}), aborted).URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	resp := postInference(t, ctx, suite.server.URL, `{"user_id":"alice","model_name":"llama3.2","inference_type":"chat","prompt":"hi"}`)
+	defer resp.Body.Close()
+
+	events := readSSE(t, bufio.NewReader(resp.Body), func(event sseEvent) bool { return event.name == "chunk" })
+	if len(events) != 1 || !strings.Contains(events[0].data, "thinking") {
+		t.Fatalf("events = %+v", events)
+	}
+
+	// Dropping the client cancels the upstream Ollama request
+	cancel()
+	select {
+	case <-aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream request was not cancelled")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(suite.inferenceManager.GetActiveInferences()) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("active inferences = %v", suite.inferenceManager.GetActiveInferences())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRESTToolCallScope(t *testing.T) {
+	suite := newRESTSuite(t, newFakeOllama(t).URL)
+	call := func(token, body string) (int, string) {
+		t.Helper()
+		resp := suite.do(t, context.Background(), http.MethodPost, "/api/v1/tools", token, body)
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	// A caller-chosen session_id or user_id never reaches the tool
+	status, body := call("alice:tools", `{"name":"echo","session_id":"bob-session","arguments":{"text":"hi","session_id":"bob-session","user_id":"bob"}}`)
+	if status != http.StatusOK || !strings.Contains(body, `\"session_id\":\"alice-session\"`) || !strings.Contains(body, `\"user_id\":\"alice\"`) {
+		t.Errorf("echo = %d %s", status, body)
+	}
+	if status, body := call("alice:tools", `{"name":"run","arguments":{"text":"hi"}}`); status != http.StatusForbidden {
+		t.Errorf("run without execute = %d %s", status, body)
+	}
+	if status, body := call("alice:tools,execute", `{"name":"run","arguments":{"text":"hi"}}`); status != http.StatusOK {
+		t.Errorf("run with execute = %d %s", status, body)
+	}
+}
+
+func TestRESTApprovalsUseCaller(t *testing.T) {
+	suite := newRESTSuite(t, newFakeOllama(t).URL)
+	approvals := suite.inferenceManager.GetToolApprovals()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go approvals.Request(ctx, &managers.InferenceRequest{ID: "r1", UserID: "alice", SessionID: "alice-session"}, &managers.FunctionCall{Name: "write_file"}, managers.ToolScopeFiles)
+
+	list := func(token string) []*managers.ToolApproval {
+		t.Helper()
+		var body struct {
+			Approvals []*managers.ToolApproval `json:"approvals"`
+		}
+		resp := suite.do(t, context.Background(), http.MethodGet, "/api/v1/approvals", token, "")
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("GET /api/v1/approvals: %d %v", resp.StatusCode, err)
+		}
+		return body.Approvals
+	}
+	var pending []*managers.ToolApproval
+	deadline := time.Now().Add(2 * time.Second)
+	for pending = list("alice:chat"); len(pending) == 0; pending = list("alice:chat") {
+		if time.Now().After(deadline) {
+			t.Fatal("call was not parked")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if others := list("bob:chat"); len(others) != 0 {
+		t.Errorf("bob sees %+v", others)
+	}
+
+	// The approver is the token's user, whatever the body claims
+	path := "/api/v1/approvals/" + pending[0].ID
+	if resp := suite.do(t, context.Background(), http.MethodPost, path, "bob:chat", `{"approved":true,"user_id":"alice"}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("bob approving as alice = %d", resp.StatusCode)
+	}
+	resp := suite.do(t, context.Background(), http.MethodPost, path, "alice:chat", `{"approved":true}`)
+	var decided managers.ToolApproval
+	if err := json.NewDecoder(resp.Body).Decode(&decided); err != nil || decided.Status != managers.ApprovalApproved || decided.DecidedBy != "alice" {
+		t.Errorf("alice approving = %d %+v, %v", resp.StatusCode, decided, err)
+	}
+
+	audit := func(token string) int {
+		var body struct {
+			Records []*managers.ToolApproval `json:"records"`
+		}
+		json.NewDecoder(suite.do(t, context.Background(), http.MethodGet, "/api/v1/approvals/audit", token, "").Body).Decode(&body)
+		return len(body.Records)
+	}
+	if alice, bob := audit("alice:chat"), audit("bob:chat"); alice != 1 || bob != 0 {
+		t.Errorf("audit: alice = %d, bob = %d", alice, bob)
+	}
+}
+
+// enableResponseCache turns on the exact-match response cache for the test
+func enableResponseCache(t *testing.T) {
+	t.Helper()
+	configManager := managers.GetConfigManager()
+	load := func(body string) {
+		t.Helper()
+		if err := os.WriteFile("configs/features.yaml", []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := configManager.LoadConfig("configs/features.yaml", &managers.FeatureConfig{}); err != nil {
+			t.Fatalf("LoadConfig(features.yaml): %v", err)
+		}
+	}
+	load("response_cache:\n  enabled: true\n")
+	t.Cleanup(func() { load("{}\n") })
+}
+
+func TestRESTInferenceRunsAsCaller(t *testing.T) {
+	suite := newRESTSuite(t, newFakeOllama(t).URL)
+	enableResponseCache(t)
+	infer := func(token, body string) *managers.InferenceResult {
+		t.Helper()
+		resp := suite.do(t, context.Background(), http.MethodPost, "/api/v1/inference", token, body)
+		var result managers.InferenceResult
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("POST /api/v1/inference as %s: %d %v", token, resp.StatusCode, err)
+		}
+		return &result
+	}
+
+	// Claiming to be alice in the body neither reads her cache nor spends her budget
+	body := `{"user_id":"alice","model_name":"llama3.2","inference_type":"chat","prompt":"what did I ask before?","cache":true}`
+	infer("alice:chat", body)
+	if hit := infer("alice:chat", body); hit.Metadata["cache"] != "exact" {
+		t.Fatalf("alice's repeat was not cached: %v", hit.Metadata)
+	}
+	if bob := infer("bob:chat", body); bob.Metadata["cache"] != nil {
+		t.Errorf("bob was served alice's cached reply: %v", bob.Metadata)
+	}
+	if hit := infer("bob:chat", body); hit.Metadata["cache"] != "exact" {
+		t.Errorf("bob's repeat was not cached under bob: %v", hit.Metadata)
+	}
+	if bob, _, err := suite.tokenManager.GetUserUsage("bob"); err != nil || bob.TotalTokens == 0 {
+		t.Errorf("bob's usage = %+v, %v", bob, err)
+	}
+
+	// Naming another user's session is refused
+	session, err := suite.sessionManager.CreateSession(context.Background(), "alice", "llama3.2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := suite.do(t, context.Background(), http.MethodPost, "/api/v1/inference", "bob:chat",
+		`{"session_id":"`+session.ID+`","model_name":"llama3.2","inference_type":"chat","prompt":"hi"}`)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("bob in alice's session = %d", resp.StatusCode)
+	}
+}
+
+func TestRESTBranchesCheckOwner(t *testing.T) {
+	suite := newRESTSuite(t, newFakeOllama(t).URL)
+	session, err := suite.sessionManager.CreateSession(context.Background(), "alice", "llama3.2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := suite.sessionManager.AddMessage(session.ID, "user", "hello", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	branches := "/api/v1/sessions/" + session.ID + "/branches"
+
+	resp := suite.do(t, context.Background(), http.MethodPost, branches, "alice:chat", `{"message_id":"`+message.ID+`","name":"retry"}`)
+	var branch managers.ThreadBranch
+	if err := json.NewDecoder(resp.Body).Decode(&branch); err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("alice's fork = %d, %v", resp.StatusCode, err)
+	}
+
+	// Another user can neither read nor change alice's branches
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodGet, branches, ""},
+		{http.MethodPost, branches, `{"message_id":"` + message.ID + `"}`},
+		{http.MethodPost, branches + "/" + managers.MainBranchID + "/activate", ""},
+		{http.MethodPost, branches + "/" + branch.ID + "/merge", `{"summary":"bob was here"}`},
+	} {
+		if resp := suite.do(t, context.Background(), req.method, req.path, "bob:chat", req.body); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("bob's %s %s = %d", req.method, req.path, resp.StatusCode)
+		}
+	}
+
+	resp = suite.do(t, context.Background(), http.MethodGet, branches, "alice:chat", "")
+	var tree managers.BranchTree
+	if err := json.NewDecoder(resp.Body).Decode(&tree); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("alice's tree = %d, %v", resp.StatusCode, err)
+	}
+	if tree.CurrentBranch != branch.ID || len(tree.Branches) != 2 || len(tree.MergePoints) != 0 {
+		t.Errorf("tree = %+v", tree)
+	}
+}
+
+func TestRESTFlowsCheckOwner(t *testing.T) {
+	suite := newRESTSuite(t, newFakeOllama(t).URL)
+	conversation, err := suite.conversationMgr.StartConversation(context.Background(), "alice", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flow := "/api/v1/conversations/" + conversation.ID + "/flow"
+
+	// Another user cannot start, advance or confirm a flow on alice's conversation
+	for _, req := range []struct{ path, body string }{
+		{flow, `{"flow":"missing"}`},
+		{flow + "/advance", ""},
+		{flow + "/actions/act_1", `{"approved":true}`},
+	} {
+		if resp := suite.do(t, context.Background(), http.MethodPost, req.path, "bob:chat", req.body); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("bob's POST %s = %d", req.path, resp.StatusCode)
+		}
+	}
+
+	// The owner reaches the conversation manager, which knows no such flow
+	if resp := suite.do(t, context.Background(), http.MethodPost, flow, "alice:chat", `{"flow":"missing"}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("alice's POST %s = %d", flow, resp.StatusCode)
+	}
+}
+
+func TestRESTRoutingPinsUseCaller(t *testing.T) {
+	suite := newRESTSuite(t, newFakeOllama(t).URL)
+	pins := func(token string) map[string]string {
+		t.Helper()
+		resp := suite.do(t, context.Background(), http.MethodGet, "/api/v1/routing/pins?user_id=alice", token, "")
+		var body struct {
+			Pins map[string]string `json:"pins"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET pins as %s: %d %v", token, resp.StatusCode, err)
+		}
+		return body.Pins
+	}
+	explain := func(token string) *managers.RoutingDecision {
+		t.Helper()
+		resp := suite.do(t, context.Background(), http.MethodPost, "/api/v1/routing/explain", token, `{"user_id":"alice","prompt":"hello"}`)
+		var decision managers.RoutingDecision
+		if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("POST explain as %s: %d %v", token, resp.StatusCode, err)
+		}
+		return &decision
+	}
+
+	if resp := suite.do(t, context.Background(), http.MethodPut, "/api/v1/routing/pins", "alice:chat", `{"intent":"chat","model":"llama3.2"}`); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("alice's pin = %d", resp.StatusCode)
+	}
+
+	// Naming alice in the query or body reaches only the caller's own pins
+	if alice, bob := explain("alice:chat"), explain("bob:chat"); !alice.Pinned || bob.Pinned {
+		t.Errorf("alice's route = %+v, bob's route = %+v", alice, bob)
+	}
+	if got := pins("bob:chat"); len(got) != 0 {
+		t.Errorf("bob's pins = %v", got)
+	}
+	suite.do(t, context.Background(), http.MethodDelete, "/api/v1/routing/pins?user_id=alice&intent=chat", "bob:chat", "")
+	if resp := suite.do(t, context.Background(), http.MethodPut, "/api/v1/routing/pins", "bob:chat", `{"user_id":"alice","intent":"code","model":"llama3.2"}`); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("bob's pin = %d", resp.StatusCode)
+	}
+	if got := pins("alice:chat"); len(got) != 1 || got["chat"] != "llama3.2" {
+		t.Errorf("alice's pins = %v", got)
+	}
+	if got := pins("bob:chat"); len(got) != 1 || got["code"] != "llama3.2" {
+		t.Errorf("bob's pins = %v", got)
+	}
+}
@@ -7,6 +7,7 @@ package main
 import (
 	// stdlib
 	"context"
+	"flag"
 	"log"
 	"net/http"
 	"os"
@@ -27,9 +28,16 @@ import (
 )
 
 func main() {
-	// Initialize logger
+	mcpStdio := flag.Bool("mcp-stdio", false, "serve MCP on stdin/stdout as the user in OCS_MCP_TOKEN instead of starting the HTTP and gRPC servers")
+	flag.Parse()
+
+	// Initialize logger; stdout carries the protocol in MCP stdio mode
 	zerolog.TimeFieldFormat = zerolog.TimeFormatRFC3339
-	log.Logger = zerolog.New(os.Stdout).With().Timestamp().Logger()
+	logOutput := os.Stdout
+	if *mcpStdio {
+		logOutput = os.Stderr
+	}
+	log.Logger = zerolog.New(logOutput).With().Timestamp().Logger()
 
 	// Create context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
@@ -39,6 +47,9 @@ func main() {
 	configManager := managers.NewConfigManager()
 	modelManager := managers.NewModelManager("http://localhost:11434", configManager)
 	memoryManager := managers.NewMemoryManager(configManager)
+	if embeddingConfig, err := configManager.GetEmbeddingModelConfig(); err == nil {
+		memoryManager.SetEmbeddingProvider(managers.NewOllamaEmbeddingProvider("http://localhost:11434", embeddingConfig))
+	}
 	sessionManager := managers.NewSessionManager(configManager, nil, memoryManager)
 	tokenManager := managers.NewTokenManager(configManager)
 	inferenceManager := managers.NewInferenceManager(modelManager, tokenManager, configManager)
@@ -47,7 +58,16 @@ func main() {
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to initialize disk manager")
 	}
-	wsManager := managers.NewWebSocketManager(configManager, sessionManager, modelManager, tokenManager)
+
+	// Persist conversation flows and resume the ones interrupted by a restart
+	conversationManager.SetFlowStore(diskManager)
+	if _, err := conversationManager.RestoreFlows(); err != nil {
+		log.Error().Err(err).Msg("Failed to restore conversation flows")
+	}
+	wsManager := managers.NewWebSocketManager(configManager, sessionManager, modelManager, tokenManager, inferenceManager)
+
+	// Ask connected users to approve destructive tool calls
+	inferenceManager.GetToolApprovals().AddNotifier(wsManager)
 
 	// Initialize models
 	codeModel := models.NewCodeModel("codellama", &managers.ModelConfig{
@@ -70,6 +90,23 @@ func main() {
 	codeTool := tools.NewCodeTool(diskManager, sessionManager)
 	fileTool := tools.NewFileTool(diskManager)
 	searchTool := tools.NewSearchTool(memoryManager, sessionManager)
+	mcpTool, err := tools.NewMCPTool(ctx, configManager)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load MCP servers")
+	}
+	toolRegistry := tools.NewToolRegistry(sessionManager)
+	if err := toolRegistry.Register(codeTool, fileTool, searchTool, mcpTool); err != nil {
+		log.Fatal().Err(err).Msg("Failed to register tools")
+	}
+
+	// Expose the catalogue to model tool calling
+	inferenceManager.RegisterToolProvider(toolRegistry)
+
+	// Serve WebSocket code.execute and file.operation from the same catalogue
+	wsManager.SetSessionTools(toolRegistry)
+
+	// Expose OCS itself to editors and agents over MCP
+	mcpServer := tools.NewMCPServer(searchTool, sessionManager, inferenceManager)
 
 	// Initialize model router
 	modelRouter := routing.NewModelRouter(codeModel, chatModel, reasoningModel, modelManager)
@@ -79,9 +116,10 @@ func main() {
 
 	// Initialize API handlers
 	authHandler := handler.NewAuthenticationHandler(configManager, sessionManager, tokenManager)
-	wsHandler := handler.NewWebSocketHandler(wsManager, inferenceManager, codeTool, fileTool, searchTool)
-	restAPI := api.NewRESTAPI(configManager, modelManager, sessionManager, inferenceManager, tokenManager, diskManager, conversationManager, codeTool, fileTool, searchTool)
-	grpcServer := api.NewOCSGrpcServer(configManager, modelManager, sessionManager, inferenceManager, tokenManager, diskManager, conversationManager, codeTool, fileTool, searchTool)
+	wsHandler := handler.NewWebSocketHandler(wsManager, inferenceManager, toolRegistry)
+	restAPI := api.NewRESTAPI(configManager, modelManager, sessionManager, inferenceManager, tokenManager, diskManager, conversationManager, toolRegistry)
+	grpcServer := api.NewOCSGrpcServer(configManager, modelManager, sessionManager, inferenceManager, tokenManager, diskManager, conversationManager, toolRegistry)
+	grpcServer.SetAuthenticator(authHandler.ValidateCaller)
 
 	// Initialize models
 	if err := modelManager.Initialize(ctx); err != nil {
@@ -97,15 +135,29 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to initialize reasoning model")
 	}
 
+	if *mcpStdio {
+		caller, err := authHandler.ValidateCaller(os.Getenv("OCS_MCP_TOKEN"))
+		if err != nil {
+			log.Fatal().Err(err).Msg("MCP stdio mode requires a valid OCS_MCP_TOKEN")
+		}
+		mcpCtx := managers.WithCaller(ctx, caller)
+
+		log.Info().Str("user_id", caller.UserID).Msg("Serving MCP on stdio")
+		if err := mcpServer.ServeStdio(mcpCtx, os.Stdin, os.Stdout); err != nil {
+			log.Error().Err(err).Msg("MCP stdio server failed")
+		}
+		shutdown(diskManager, mcpTool, codeModel, chatModel, reasoningModel)
+		return
+	}
+
 	// Set up HTTP server with authentication
 	router := mux.NewRouter()
 	router.HandleFunc("/api/v1/auth", authHandler.Authenticate).Methods("POST")
 	protected := router.PathPrefix("/api/v1").Subrouter()
 	protected.Use(authHandler.Middleware)
 	protected.HandleFunc("/ws", wsHandler.HandleWebSocket).Methods("GET")
-	protected.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		restAPI.handleListModels(w, r)
-	})
+	protected.Handle("/mcp", mcpServer).Methods("POST", "GET", "DELETE")
+	router.PathPrefix("/").Handler(authHandler.Middleware(restAPI.Handler()))
 
 	// Start servers
 	httpServer := &http.Server{
@@ -138,9 +190,17 @@ func main() {
 	if err := httpServer.Shutdown(context.Background()); err != nil {
 		log.Error().Err(err).Msg("Failed to shutdown HTTP server")
 	}
+	shutdown(diskManager, mcpTool, codeModel, chatModel, reasoningModel)
+}
+
+// shutdown releases storage, MCP servers and models
+func shutdown(diskManager *managers.DiskManager, mcpTool *tools.MCPTool, codeModel *models.CodeModel, chatModel *models.ChatModel, reasoningModel *models.ReasoningModel) {
 	if err := diskManager.Shutdown(context.Background()); err != nil {
 		log.Error().Err(err).Msg("Failed to shutdown disk manager")
 	}
+	if err := mcpTool.Close(); err != nil {
+		log.Error().Err(err).Msg("Failed to close MCP servers")
+	}
 	if err := codeModel.Shutdown(context.Background()); err != nil {
 		log.Error().Err(err).Msg("Failed to shutdown code model")
 	}
@@ -0,0 +1,360 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/backend-pool.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+)
+
+// OllamaBackendConfig declares one Ollama server in server.yaml
+type OllamaBackendConfig struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+}
+
+// OllamaBackend is one Ollama server in the pool
+type OllamaBackend struct {
+	Name string
+	URL  string
+
+	mu        sync.RWMutex
+	healthy   bool
+	lastCheck time.Time
+	lastError string
+	failures  int
+	resident  map[string]time.Time // model -> expires_at, from /api/ps
+	inFlight  int
+}
+
+// BackendStatus is a snapshot of a backend for stats and APIs
+type BackendStatus struct {
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Healthy   bool      `json:"healthy"`
+	LastCheck time.Time `json:"last_check"`
+	LastError string    `json:"last_error,omitempty"`
+	Failures  int       `json:"consecutive_failures"`
+	InFlight  int       `json:"in_flight"`
+	Models    []string  `json:"loaded_models"`
+}
+
+// OllamaProcessResponse represents Ollama's /api/ps response
+type OllamaProcessResponse struct {
+	Models []struct {
+		Name      string    `json:"name"`
+		Model     string    `json:"model"`
+		Size      int64     `json:"size"`
+		SizeVRAM  int64     `json:"size_vram"`
+		ExpiresAt time.Time `json:"expires_at"`
+	} `json:"models"`
+}
+
+// BackendPool routes requests across Ollama servers, preferring those with the model warm
+type BackendPool struct {
+	mu       sync.RWMutex
+	backends []*OllamaBackend
+	client   *http.Client
+}
+
+// NewBackendPool creates a pool from base URLs; backends start healthy until a check says otherwise
+func NewBackendPool(client *http.Client, configs ...OllamaBackendConfig) *BackendPool {
+	pool := &BackendPool{client: client}
+	for _, config := range configs {
+		pool.Add(config)
+	}
+	return pool
+}
+
+// Add registers a backend unless one with the same URL exists
+func (p *BackendPool) Add(config OllamaBackendConfig) *OllamaBackend {
+	url := strings.TrimRight(config.URL, "/")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, backend := range p.backends {
+		if backend.URL == url {
+			return backend
+		}
+	}
+	name := config.Name
+	if name == "" {
+		name = url
+	}
+	backend := &OllamaBackend{
+		Name:     name,
+		URL:      url,
+		healthy:  true,
+		resident: make(map[string]time.Time),
+	}
+	p.backends = append(p.backends, backend)
+	return backend
+}
+
+// Backends returns every backend in declaration order
+func (p *BackendPool) Backends() []*OllamaBackend {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]*OllamaBackend(nil), p.backends...)
+}
+
+// Primary returns the first declared backend
+func (p *BackendPool) Primary() *OllamaBackend {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if len(p.backends) == 0 {
+		return nil
+	}
+	return p.backends[0]
+}
+
+// Candidates orders backends for a model: healthy before unhealthy, warm before cold,
+// then least busy. Unhealthy backends stay at the end as a last resort.
+func (p *BackendPool) Candidates(modelName string) []*OllamaBackend {
+	backends := p.Backends()
+	model := normalizeModelName(modelName)
+	now := time.Now()
+
+	type ranked struct {
+		backend  *OllamaBackend
+		healthy  bool
+		warm     bool
+		inFlight int
+	}
+	ranks := make([]ranked, len(backends))
+	for i, backend := range backends {
+		backend.mu.RLock()
+		expires, warm := backend.resident[model]
+		ranks[i] = ranked{
+			backend:  backend,
+			healthy:  backend.healthy,
+			warm:     warm && (expires.IsZero() || expires.After(now)),
+			inFlight: backend.inFlight,
+		}
+		backend.mu.RUnlock()
+	}
+
+	sort.SliceStable(ranks, func(i, j int) bool {
+		a, b := ranks[i], ranks[j]
+		if a.healthy != b.healthy {
+			return a.healthy
+		}
+		if a.warm != b.warm {
+			return a.warm
+		}
+		return a.inFlight < b.inFlight
+	})
+
+	ordered := make([]*OllamaBackend, len(ranks))
+	for i, r := range ranks {
+		ordered[i] = r.backend
+	}
+	return ordered
+}
+
+// WarmBackends returns the healthy backends that have the model loaded
+func (p *BackendPool) WarmBackends(modelName string) []*OllamaBackend {
+	model := normalizeModelName(modelName)
+	var warm []*OllamaBackend
+	for _, backend := range p.Backends() {
+		if backend.Healthy() && backend.HasModel(model) {
+			warm = append(warm, backend)
+		}
+	}
+	return warm
+}
+
+// Refresh health-checks every backend and reloads which models each has resident
+func (p *BackendPool) Refresh(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, backend := range p.Backends() {
+		wg.Add(1)
+		go func(backend *OllamaBackend) {
+			defer wg.Done()
+			p.refreshBackend(ctx, backend)
+		}(backend)
+	}
+	wg.Wait()
+}
+
+// refreshBackend pings one backend and, when it answers, reads /api/ps
+func (p *BackendPool) refreshBackend(ctx context.Context, backend *OllamaBackend) {
+	if err := pingOllama(ctx, p.client, backend.URL); err != nil {
+		backend.MarkFailed(err)
+		log.Warn().Err(err).Str("backend", backend.Name).Msg("Ollama backend health check failed")
+		return
+	}
+
+	resident, err := p.listResident(ctx, backend)
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	if !backend.healthy {
+		log.Info().Str("backend", backend.Name).Msg("Ollama backend recovered")
+	}
+	backend.healthy = true
+	backend.failures = 0
+	backend.lastError = ""
+	backend.lastCheck = time.Now()
+	if err != nil {
+		log.Debug().Err(err).Str("backend", backend.Name).Msg("Failed to list resident models")
+		return
+	}
+	backend.resident = resident
+}
+
+// listResident reads a backend's loaded models from /api/ps
+func (p *BackendPool) listResident(ctx context.Context, backend *OllamaBackend) (map[string]time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", backend.URL+"/api/ps", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ollama returned status: %d", resp.StatusCode)
+	}
+
+	var ps OllamaProcessResponse
+	if err := json.NewDecoder(resp.Body).Decode(&ps); err != nil {
+		return nil, err
+	}
+	resident := make(map[string]time.Time, len(ps.Models))
+	for _, model := range ps.Models {
+		name := model.Name
+		if name == "" {
+			name = model.Model
+		}
+		resident[normalizeModelName(name)] = model.ExpiresAt
+	}
+	return resident, nil
+}
+
+// Status snapshots every backend
+func (p *BackendPool) Status() []BackendStatus {
+	backends := p.Backends()
+	statuses := make([]BackendStatus, len(backends))
+	for i, backend := range backends {
+		statuses[i] = backend.Status()
+	}
+	return statuses
+}
+
+// Healthy reports the result of the last check or request
+func (b *OllamaBackend) Healthy() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.healthy
+}
+
+// HasModel reports whether the backend has the model resident
+func (b *OllamaBackend) HasModel(modelName string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	expires, ok := b.resident[normalizeModelName(modelName)]
+	return ok && (expires.IsZero() || expires.After(time.Now()))
+}
+
+// MarkFailed takes the backend out of rotation until a health check succeeds
+func (b *OllamaBackend) MarkFailed(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.healthy = false
+	b.failures++
+	b.lastError = err.Error()
+	b.lastCheck = time.Now()
+	b.resident = make(map[string]time.Time)
+}
+
+// MarkLoaded records that a request just ran the model on this backend
+func (b *OllamaBackend) MarkLoaded(modelName string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.healthy = true
+	b.failures = 0
+	// Zero expiry: resident until the next /api/ps says otherwise
+	b.resident[normalizeModelName(modelName)] = time.Time{}
+}
+
+// MarkUnloaded records that the model was evicted from this backend
+func (b *OllamaBackend) MarkUnloaded(modelName string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.resident, normalizeModelName(modelName))
+}
+
+// begin counts a request against the backend and returns the function that ends it
+func (b *OllamaBackend) begin() func() {
+	b.mu.Lock()
+	b.inFlight++
+	b.mu.Unlock()
+	return func() {
+		b.mu.Lock()
+		b.inFlight--
+		b.mu.Unlock()
+	}
+}
+
+// Status snapshots the backend
+func (b *OllamaBackend) Status() BackendStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	models := make([]string, 0, len(b.resident))
+	for model := range b.resident {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	return BackendStatus{
+		Name:      b.Name,
+		URL:       b.URL,
+		Healthy:   b.healthy,
+		LastCheck: b.lastCheck,
+		LastError: b.lastError,
+		Failures:  b.failures,
+		InFlight:  b.inFlight,
+		Models:    models,
+	}
+}
+
+// pingOllama checks if an Ollama server is accessible
+func pingOllama(ctx context.Context, client *http.Client, baseURL string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/api/tags", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ollama returned status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// normalizeModelName adds Ollama's implicit :latest tag so config names match /api/ps names
+func normalizeModelName(name string) string {
+	if name == "" || strings.Contains(name, ":") {
+		return name
+	}
+	return name + ":latest"
+}
@@ -0,0 +1,313 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/backend-pool_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeOllama is one Ollama server: it reports resident models on /api/ps, answers
+// generate and chat, and can be taken down or made to fail with a status
+type fakeOllama struct {
+	*httptest.Server
+	name string
+
+	mu        sync.Mutex
+	resident  []string
+	down      bool
+	status    int
+	calls     map[string]int
+	replies   []string                 // chat replies served in order before the default
+	toolCalls [][]OllamaToolCall       // chat tool calls served in order before replies
+	bodies    []map[string]interface{} // decoded chat requests
+}
+
+func newFakeOllama(t *testing.T, name string, resident ...string) *fakeOllama {
+	t.Helper()
+	f := &fakeOllama{name: name, resident: resident, calls: make(map[string]int)}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeOllama) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.calls[r.Method+" "+r.URL.Path]++
+	down, status := f.down, f.status
+	resident := append([]string(nil), f.resident...)
+	f.mu.Unlock()
+
+	if down {
+		// Drop the connection the way a dead server would
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+		return
+	}
+
+	switch r.URL.Path {
+	case "/api/tags":
+		fmt.Fprintf(w, `{"models":[{"name":"shared:latest"},{"name":"%s-only:latest"}]}`, f.name)
+	case "/api/ps":
+		models := make([]map[string]interface{}, len(resident))
+		for i, name := range resident {
+			models[i] = map[string]interface{}{"name": name, "expires_at": time.Now().Add(time.Minute)}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"models": models})
+	case "/api/generate", "/api/chat":
+		if status != 0 {
+			http.Error(w, "failing on purpose", status)
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		message := &OllamaMessage{Role: "assistant", Content: "from " + f.name}
+		if r.URL.Path == "/api/chat" {
+			f.mu.Lock()
+			f.bodies = append(f.bodies, body)
+			if len(f.toolCalls) > 0 {
+				message.Content, message.ToolCalls, f.toolCalls = "", f.toolCalls[0], f.toolCalls[1:]
+			} else if len(f.replies) > 0 {
+				message.Content, f.replies = f.replies[0], f.replies[1:]
+			}
+			f.mu.Unlock()
+		}
+		json.NewEncoder(w).Encode(&OllamaResponse{
+			Model:           fmt.Sprint(body["model"]),
+			Message:         message,
+			Done:            true,
+			PromptEvalCount: 7,
+			EvalCount:       3,
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeOllama) set(down bool, status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down, f.status = down, status
+}
+
+func (f *fakeOllama) count(call string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[call]
+}
+
+// poolOf builds a pool over the fakes in order
+func poolOf(fakes ...*fakeOllama) *BackendPool {
+	pool := NewBackendPool(&http.Client{Timeout: 5 * time.Second})
+	for _, f := range fakes {
+		pool.Add(OllamaBackendConfig{Name: f.name, URL: f.URL})
+	}
+	return pool
+}
+
+// poolInference is an inference manager with only what callOllama needs
+func poolInference(pool *BackendPool) *InferenceManager {
+	return &InferenceManager{
+		backends:   pool,
+		client:     &http.Client{Timeout: 5 * time.Second},
+		maxRetries: 3,
+		retryDelay: 10 * time.Millisecond,
+	}
+}
+
+func chatWith(im *InferenceManager, model string) (string, error) {
+	resp, err := im.callOllama(context.Background(), &OllamaRequest{
+		Model:    model,
+		Messages: []OllamaMessage{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(resp.Message.Content, "from "), nil
+}
+
+func TestPoolRoutesToWarmBackend(t *testing.T) {
+	cold := newFakeOllama(t, "cold")
+	warm := newFakeOllama(t, "warm", "llama3.2:latest")
+	pool := poolOf(cold, warm)
+	pool.Refresh(context.Background())
+
+	if got := pool.Candidates("llama3.2")[0].Name; got != "warm" {
+		t.Fatalf("first candidate for a warm model = %s, want warm", got)
+	}
+	if got := pool.Candidates("mistral")[0].Name; got != "cold" {
+		t.Errorf("first candidate for a cold model = %s, want the first declared", got)
+	}
+
+	im := poolInference(pool)
+	if got, err := chatWith(im, "llama3.2"); err != nil || got != "warm" {
+		t.Fatalf("chat = %q, %v, want it served by warm", got, err)
+	}
+
+	// A model that ran on a backend stays routed there until /api/ps says otherwise
+	if got, err := chatWith(im, "mistral"); err != nil || got != "cold" {
+		t.Fatalf("chat = %q, %v", got, err)
+	}
+	if !pool.Candidates("mistral")[0].HasModel("mistral:latest") {
+		t.Errorf("backend that served mistral is not marked warm")
+	}
+}
+
+func TestPoolFailsOverOnErrors(t *testing.T) {
+	first := newFakeOllama(t, "first", "llama3.2:latest")
+	second := newFakeOllama(t, "second")
+	pool := poolOf(first, second)
+	pool.Refresh(context.Background())
+	im := poolInference(pool)
+
+	// A dead backend is skipped and taken out of rotation
+	first.set(true, 0)
+	if got, err := chatWith(im, "llama3.2"); err != nil || got != "second" {
+		t.Fatalf("chat with first down = %q, %v, want second", got, err)
+	}
+	if status := pool.Status()[0]; status.Healthy || status.LastError == "" {
+		t.Errorf("dead backend status = %+v, want unhealthy", status)
+	}
+	if got := pool.Candidates("llama3.2")[0].Name; got != "second" {
+		t.Errorf("first candidate after a failure = %s, want second", got)
+	}
+
+	// A 5xx fails over without marking the backend down
+	first.set(false, 0)
+	pool.Refresh(context.Background())
+	first.set(false, http.StatusInternalServerError)
+	if got, err := chatWith(im, "llama3.2"); err != nil || got != "second" {
+		t.Fatalf("chat with first failing = %q, %v, want second", got, err)
+	}
+	if !pool.Status()[0].Healthy {
+		t.Errorf("backend answering 500 was marked unhealthy")
+	}
+
+	// A 4xx is the request's fault and is not retried elsewhere
+	before := second.count("POST /api/chat")
+	first.set(false, http.StatusBadRequest)
+	if _, err := chatWith(im, "llama3.2"); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("chat rejected with 400 = %v", err)
+	}
+	if second.count("POST /api/chat") != before {
+		t.Errorf("a 400 was retried on another backend")
+	}
+}
+
+func TestPoolRetriesWithinMaxRetries(t *testing.T) {
+	only := newFakeOllama(t, "only")
+	only.set(false, http.StatusServiceUnavailable)
+	im := poolInference(poolOf(only))
+
+	start := time.Now()
+	if _, err := chatWith(im, "llama3.2"); err == nil {
+		t.Fatal("chat against a failing backend succeeded")
+	}
+	if n := only.count("POST /api/chat"); n != im.maxRetries+1 {
+		t.Errorf("attempts = %d, want %d", n, im.maxRetries+1)
+	}
+	if elapsed := time.Since(start); elapsed < time.Duration(im.maxRetries)*im.retryDelay {
+		t.Errorf("retries took %s, want retryDelay between attempts", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := im.callOllama(ctx, &OllamaRequest{Model: "llama3.2"}); err == nil {
+		t.Error("cancelled request succeeded")
+	}
+}
+
+func TestPoolRefreshTracksHealth(t *testing.T) {
+	a := newFakeOllama(t, "a")
+	pool := poolOf(a)
+
+	a.set(true, 0)
+	pool.Refresh(context.Background())
+	if pool.Status()[0].Healthy {
+		t.Fatal("down backend reported healthy")
+	}
+
+	a.set(false, 0)
+	a.mu.Lock()
+	a.resident = []string{"qwen2.5:7b"}
+	a.mu.Unlock()
+	pool.Refresh(context.Background())
+	status := pool.Status()[0]
+	if !status.Healthy || status.Failures != 0 || len(status.Models) != 1 || status.Models[0] != "qwen2.5:7b" {
+		t.Errorf("status after recovery = %+v", status)
+	}
+}
+
+func TestModelManagerPlacesModelsAcrossBackends(t *testing.T) {
+	a := newFakeOllama(t, "a")
+	b := newFakeOllama(t, "b", "codellama:latest")
+
+	cm := schedulerConfig(&LimitsConfig{},
+		ModelConfig{Name: "codellama", Specialization: "code"},
+		ModelConfig{Name: "llama3.2", Specialization: "chat"},
+	)
+	cm.configs["configs/server.yaml"] = &ServerConfig{OllamaBackends: []OllamaBackendConfig{
+		{Name: "a", URL: a.URL},
+		{Name: "b", URL: b.URL},
+	}}
+	mm := NewModelManager("http://unused.invalid", cm)
+	t.Cleanup(func() { mm.Shutdown(context.Background()) })
+
+	if err := mm.Initialize(context.Background()); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	if n := len(mm.GetBackendStatus()); n != 2 {
+		t.Fatalf("backends = %d, want the two from server.yaml", n)
+	}
+
+	available, err := mm.ListAvailableModels(context.Background())
+	if err != nil || len(available.Models) != 3 {
+		t.Fatalf("available = %+v, %v, want the union of both backends", available, err)
+	}
+
+	// Loading a model already warm on b goes to b
+	if err := mm.LoadModel(context.Background(), "codellama"); err != nil {
+		t.Fatalf("LoadModel(codellama): %v", err)
+	}
+	if info, _ := mm.GetModelInfo("codellama"); len(info.Backends) != 1 || info.Backends[0] != "b" {
+		t.Errorf("codellama placed on %v, want b", info.Backends)
+	}
+	if a.count("POST /api/generate") != 0 {
+		t.Errorf("warm model was loaded on a cold backend")
+	}
+
+	// A cold model fails over past a dead backend
+	a.set(true, 0)
+	if err := mm.LoadModel(context.Background(), "llama3.2"); err != nil {
+		t.Fatalf("LoadModel(llama3.2) with a down: %v", err)
+	}
+	if info, _ := mm.GetModelInfo("llama3.2"); info.Backends[0] != "b" {
+		t.Errorf("llama3.2 placed on %v, want b", info.Backends)
+	}
+
+	// With every backend down there is nothing to initialize against
+	b.set(true, 0)
+	if err := mm.Initialize(context.Background()); err == nil || !strings.Contains(err.Error(), "no healthy backend") {
+		t.Errorf("Initialize with every backend down = %v", err)
+	}
+
+	b.set(false, 0)
+	if err := mm.UnloadModel(context.Background(), "codellama"); err != nil {
+		t.Fatalf("UnloadModel: %v", err)
+	}
+	if b.count("POST /api/generate") != 3 {
+		t.Errorf("generate calls on b = %d, want two loads and one unload", b.count("POST /api/generate"))
+	}
+}
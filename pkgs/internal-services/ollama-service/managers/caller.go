@@ -0,0 +1,45 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/caller.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+)
+
+// callerKey is the context key the authenticated caller is stored under
+type callerKey struct{}
+
+// DefaultPermissions are granted to tokens when server.yaml sets no token_permissions;
+// file writes and code execution must be granted explicitly
+var DefaultPermissions = []string{"chat", ToolScopeTools}
+
+// Caller is the authenticated principal a request acts for
+type Caller struct {
+	UserID      string   `json:"user_id"`
+	SessionID   string   `json:"session_id"`
+	Permissions []string `json:"permissions"`
+}
+
+// Can reports whether the caller holds a permission
+func (c *Caller) Can(permission string) bool {
+	for _, granted := range c.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// WithCaller returns a context carrying the authenticated caller
+func WithCaller(ctx context.Context, caller *Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller the authentication layer attached to ctx
+func CallerFromContext(ctx context.Context) (*Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(*Caller)
+	return caller, ok && caller != nil && caller.UserID != ""
+}
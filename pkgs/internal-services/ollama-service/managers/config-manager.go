@@ -29,19 +29,32 @@ type ServerConfig struct {
 	WriteTimeout int    `yaml:"write_timeout"`
 	IdleTimeout  int    `yaml:"idle_timeout"`
 	Environment  string `yaml:"environment"`
+	TokenizerDir string `yaml:"tokenizer_dir"` // holds <family>/tokenizer.json vocab files
+
+	TokenPermissions []string `yaml:"token_permissions"` // granted to issued tokens; DefaultPermissions when unset
+
+	OllamaBackends       []OllamaBackendConfig `yaml:"ollama_backends"`
+	BackendCheckInterval int                   `yaml:"backend_check_interval"` // seconds between backend health checks
 }
 
 // ModelConfig represents model configuration
 type ModelConfig struct {
-	Name           string            `yaml:"name"`
-	MaxTokens      int               `yaml:"max_tokens"`
-	Temperature    float64           `yaml:"temperature"`
-	SystemPrompt   string            `yaml:"system_prompt"`
-	Specialization string            `yaml:"specialization"`
-	ContextWindow  int               `yaml:"context_window"`
-	Parameters     map[string]string `yaml:"parameters"`
-	LoadOnStartup  bool              `yaml:"load_on_startup"`
-	Priority       int               `yaml:"priority"`
+	Name            string             `yaml:"name"`
+	MaxTokens       int                `yaml:"max_tokens"`
+	Temperature     float64            `yaml:"temperature"`
+	SystemPrompt    string             `yaml:"system_prompt"`
+	Specialization  string             `yaml:"specialization"`
+	ContextWindow   int                `yaml:"context_window"`
+	Parameters      map[string]string  `yaml:"parameters"`
+	LoadOnStartup   bool               `yaml:"load_on_startup"`
+	Priority        int                `yaml:"priority"`
+	TokenizerFamily string             `yaml:"tokenizer_family"` // llama, qwen, mistral; derived from name when empty
+	MaxConcurrency  int                `yaml:"max_concurrency"`  // overrides limits.max_concurrent_per_model
+	Backend         string             `yaml:"backend"`          // ollama (default) or openai for llama.cpp server, vLLM, LM Studio
+	BackendURL      string             `yaml:"backend_url"`      // OpenAI-compatible base URL, e.g. http://gpu-2:8000/v1
+	APIKey          string             `yaml:"api_key"`          // bearer token for the backend; ${VAR} is expanded
+	RemoteModel     string             `yaml:"remote_model"`     // model name the backend serves, when it differs from name
+	Routing         ModelRoutingPolicy `yaml:"routing"`          // how automatic model routing weighs this model
 }
 
 // LimitsConfig represents rate limiting and quotas
@@ -52,6 +65,12 @@ type LimitsConfig struct {
 	MaxConcurrentChats   int   `yaml:"max_concurrent_chats"`
 	TokenBudgetPerUser   int64 `yaml:"token_budget_per_user"`
 	ResetIntervalHours   int   `yaml:"reset_interval_hours"`
+
+	// Inference scheduling
+	MaxConcurrentPerModel int `yaml:"max_concurrent_per_model"`
+	MaxQueuedRequests     int `yaml:"max_queued_requests"`
+	MaxQueuedPerUser      int `yaml:"max_queued_per_user"`
+	MaxQueueWaitSeconds   int `yaml:"max_queue_wait_seconds"`
 }
 
 // FeatureConfig represents feature flags
@@ -63,6 +82,37 @@ type FeatureConfig struct {
 	EnableModelRouting   bool `yaml:"enable_model_routing"`
 	EnableTokenOptimizer bool `yaml:"enable_token_optimizer"`
 	EnableAutoBackup     bool `yaml:"enable_auto_backup"`
+
+	ResponseCache    ResponseCacheConfig    `yaml:"response_cache"`
+	ToolApproval     ToolApprovalConfig     `yaml:"tool_approval"`
+	IntentClassifier IntentClassifierConfig `yaml:"intent_classifier"`
+}
+
+// ResponseCacheConfig configures the inference response cache in features.yaml
+type ResponseCacheConfig struct {
+	Enabled             bool    `yaml:"enabled"`
+	TTLSeconds          int     `yaml:"ttl_seconds"`
+	MaxEntries          int     `yaml:"max_entries"`
+	Semantic            bool    `yaml:"semantic"`             // also match by embedding similarity
+	SimilarityThreshold float64 `yaml:"similarity_threshold"` // cosine similarity needed for a semantic hit
+}
+
+// ToolApprovalConfig configures human approval of destructive tool calls in features.yaml
+type ToolApprovalConfig struct {
+	Enabled        bool     `yaml:"enabled"`         // confirm for every request, not only conversations requiring confirmation
+	Scopes         []string `yaml:"scopes"`          // tool scopes needing approval; files and execute when empty
+	TimeoutSeconds int      `yaml:"timeout_seconds"` // undecided calls expire and are rejected
+	WebhookURL     string   `yaml:"webhook_url"`     // also POST approval requests here
+	WebhookSecret  string   `yaml:"webhook_secret"`  // signs webhook bodies in X-OCS-Signature
+	AuditLimit     int      `yaml:"audit_limit"`     // decisions kept in memory
+}
+
+// IntentClassifierConfig configures model-based message analysis in features.yaml. The
+// classifier model is the first in models.yaml with specialization classifier.
+type IntentClassifierConfig struct {
+	MinConfidence  float64 `yaml:"min_confidence"`  // below this the keyword heuristics are used instead
+	CacheEntries   int     `yaml:"cache_entries"`   // analyses kept per message hash
+	TimeoutSeconds int     `yaml:"timeout_seconds"` // classifier calls slower than this fall back to the heuristics
 }
 
 // PersonaConfig represents AI personality configurations
@@ -73,13 +123,15 @@ type PersonaConfig struct {
 	Prompts     map[string]string `yaml:"prompts"`
 	Temperature float64           `yaml:"temperature"`
 	MaxTokens   int               `yaml:"max_tokens"`
+	Template    string            `yaml:"template"` // prompt template for the system prompt; overrides prompts.system
 }
 
 // PromptTemplate represents reusable prompt templates
 type PromptTemplate struct {
 	Name        string            `yaml:"name"`
+	Extends     string            `yaml:"extends"` // parent template whose blocks this one overrides
 	Template    string            `yaml:"template"`
-	Variables   []string          `yaml:"variables"`
+	Variables   []string          `yaml:"variables"` // keys required in PromptData.Vars
 	Category    string            `yaml:"category"`
 	Description string            `yaml:"description"`
 	Examples    map[string]string `yaml:"examples"`
@@ -137,6 +189,7 @@ func (cm *ConfigManager) LoadAllConfigs() error {
 		"configs/features.yaml":               &FeatureConfig{},
 		"configs/model/personas.yaml":         &[]PersonaConfig{},
 		"configs/model/prompt-templates.yaml": &[]PromptTemplate{},
+		"configs/flows.yaml":                  &[]FlowDefinition{},
 	}
 
 	for path, target := range configs {
@@ -203,6 +256,54 @@ func (cm *ConfigManager) GetModelConfig(modelName string) (*ModelConfig, error)
 	return nil, fmt.Errorf("model config not found: %s", modelName)
 }
 
+// GetEmbeddingModelConfig returns the first model configured for embeddings
+func (cm *ConfigManager) GetEmbeddingModelConfig() (*ModelConfig, error) {
+	configs, err := cm.GetModelConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, config := range configs {
+		if config.Specialization == "embedding" {
+			return &config, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no embedding model configured")
+}
+
+// GetSummaryModelConfig returns the first model configured for context summarization
+func (cm *ConfigManager) GetSummaryModelConfig() (*ModelConfig, error) {
+	configs, err := cm.GetModelConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, config := range configs {
+		if config.Specialization == "summary" {
+			return &config, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no summary model configured")
+}
+
+// GetClassifierModelConfig returns the first model configured for intent classification
+func (cm *ConfigManager) GetClassifierModelConfig() (*ModelConfig, error) {
+	configs, err := cm.GetModelConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, config := range configs {
+		if config.Specialization == "classifier" {
+			return &config, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no classifier model configured")
+}
+
 // GetLimitsConfig returns rate limiting configuration
 func (cm *ConfigManager) GetLimitsConfig() (*LimitsConfig, error) {
 	config, exists := cm.GetConfig("configs/limits.yaml")
@@ -295,6 +396,37 @@ func (cm *ConfigManager) GetPromptTemplate(templateName string) (*PromptTemplate
 	return nil, fmt.Errorf("prompt template not found: %s", templateName)
 }
 
+// GetFlowDefinitions returns all conversation flow definitions
+func (cm *ConfigManager) GetFlowDefinitions() ([]FlowDefinition, error) {
+	config, exists := cm.GetConfig("configs/flows.yaml")
+	if !exists {
+		return nil, fmt.Errorf("flow definitions not loaded")
+	}
+
+	flows, ok := config.(*[]FlowDefinition)
+	if !ok {
+		return nil, fmt.Errorf("invalid flow definitions type")
+	}
+
+	return *flows, nil
+}
+
+// GetFlowDefinition returns specific flow definition
+func (cm *ConfigManager) GetFlowDefinition(flowName string) (*FlowDefinition, error) {
+	flows, err := cm.GetFlowDefinitions()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, flow := range flows {
+		if flow.Name == flowName {
+			return &flow, nil
+		}
+	}
+
+	return nil, fmt.Errorf("flow definition not found: %s", flowName)
+}
+
 // WatchConfig adds a watcher for configuration changes
 func (cm *ConfigManager) WatchConfig(configPath string, callback func(interface{})) {
 	cm.mu.Lock()
@@ -330,6 +462,8 @@ func (cm *ConfigManager) IsFeatureEnabled(featureName string) bool {
 		return featureConfig.EnableTokenOptimizer
 	case "auto_backup":
 		return featureConfig.EnableAutoBackup
+	case "response_cache":
+		return featureConfig.ResponseCache.Enabled
 	default:
 		return false
 	}
@@ -365,6 +499,18 @@ func (cm *ConfigManager) ValidateConfigs() error {
 		if config.Temperature < 0 || config.Temperature > 2 {
 			return fmt.Errorf("invalid temperature for model %s: %f", config.Name, config.Temperature)
 		}
+		switch config.BackendKind() {
+		case BackendOllama:
+		case BackendOpenAI:
+			if config.BackendURL == "" {
+				return fmt.Errorf("model %s uses backend %s but has no backend_url", config.Name, config.Backend)
+			}
+		default:
+			return fmt.Errorf("unknown backend for model %s: %s", config.Name, config.Backend)
+		}
+		if err := validateRoutingPolicy(&config); err != nil {
+			return err
+		}
 	}
 
 	// Validate limits config
@@ -376,5 +522,18 @@ func (cm *ConfigManager) ValidateConfigs() error {
 		return fmt.Errorf("invalid max_requests_per_minute: %d", limitsConfig.MaxRequestsPerMinute)
 	}
 
+	// Validate personas and prompt templates; both files are optional
+	templates, _ := cm.GetPromptTemplates()
+	personas, _ := cm.GetPersonaConfigs()
+	if err := ValidatePromptConfigs(templates, personas); err != nil {
+		return err
+	}
+
+	// Validate flow definitions; the file is optional
+	flows, _ := cm.GetFlowDefinitions()
+	if err := ValidateFlowDefinitions(flows); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -32,6 +32,10 @@ type ConversationManager struct {
 	memoryManager           *MemoryManager
 	tokenManager            *TokenManager
 	inferenceManager        *InferenceManager
+	prompts                 *PromptEngine
+	flowStore               FlowStore
+	classifier              MessageClassifier
+	analyses                *analysisCache
 	conversationTimeout     time.Duration
 	maxConversationsPerUser int
 	shutdown                chan struct{}
@@ -81,6 +85,9 @@ type ConversationFlow struct {
 	FlowState        map[string]interface{} `json:"flow_state"`
 	AutoContinue     bool                   `json:"auto_continue"`
 	MaxSteps         int                    `json:"max_steps"`
+	Name             string                 `json:"name,omitempty"`      // flow definition being run
+	NextStep         string                 `json:"next_step,omitempty"` // definition step to run next
+	StepsRun         int                    `json:"steps_run"`           // definition steps executed, bounded by MaxSteps
 }
 
 // ConversationStep represents a step in conversation flow
@@ -127,17 +134,18 @@ const (
 
 // ConversationContext holds contextual information
 type ConversationContext struct {
-	Topic          string                 `json:"topic"`
-	Intent         ConversationIntent     `json:"intent"`
-	Entities       []*Entity              `json:"entities"`
-	CurrentTask    string                 `json:"current_task,omitempty"`
-	PreviousTask   string                 `json:"previous_task,omitempty"`
-	UserGoals      []string               `json:"user_goals"`
-	CompletedGoals []string               `json:"completed_goals"`
-	ContextHistory []*ContextSnapshot     `json:"context_history"`
-	WorkingMemory  map[string]interface{} `json:"working_memory"`
-	SharedState    map[string]interface{} `json:"shared_state"`
-	LastUpdated    time.Time              `json:"last_updated"`
+	Topic            string                 `json:"topic"`
+	Intent           ConversationIntent     `json:"intent"`
+	IntentConfidence float64                `json:"intent_confidence"`
+	Entities         []*Entity              `json:"entities"`
+	CurrentTask      string                 `json:"current_task,omitempty"`
+	PreviousTask     string                 `json:"previous_task,omitempty"`
+	UserGoals        []string               `json:"user_goals"`
+	CompletedGoals   []string               `json:"completed_goals"`
+	ContextHistory   []*ContextSnapshot     `json:"context_history"`
+	WorkingMemory    map[string]interface{} `json:"working_memory"`
+	SharedState      map[string]interface{} `json:"shared_state"`
+	LastUpdated      time.Time              `json:"last_updated"`
 }
 
 // ConversationIntent defines user intent
@@ -221,6 +229,11 @@ type ThreadBranch struct {
 	CreatedAt   time.Time `json:"created_at"`
 	BranchPoint int       `json:"branch_point"` // Message index where branch occurred
 	IsActive    bool      `json:"is_active"`
+
+	ParentBranch  string `json:"parent_branch,omitempty"`   // branch this one was forked from
+	ForkMessageID string `json:"fork_message_id,omitempty"` // message the branch replaces
+	HeadMessageID string `json:"head_message_id,omitempty"` // latest message on the branch path
+	MergedInto    string `json:"merged_into,omitempty"`     // branch a summary was merged into
 }
 
 // MergePoint represents points where branches can merge
@@ -247,12 +260,12 @@ type PendingAction struct {
 
 // StepValidation defines validation rules for conversation steps
 type StepValidation struct {
-	Required      bool     `json:"required"`
-	MinLength     int      `json:"min_length,omitempty"`
-	MaxLength     int      `json:"max_length,omitempty"`
-	Pattern       string   `json:"pattern,omitempty"`
-	AllowedValues []string `json:"allowed_values,omitempty"`
-	ValidationFn  string   `json:"validation_fn,omitempty"`
+	Required      bool     `json:"required" yaml:"required"`
+	MinLength     int      `json:"min_length,omitempty" yaml:"min_length"`
+	MaxLength     int      `json:"max_length,omitempty" yaml:"max_length"`
+	Pattern       string   `json:"pattern,omitempty" yaml:"pattern"`
+	AllowedValues []string `json:"allowed_values,omitempty" yaml:"allowed_values"`
+	ValidationFn  string   `json:"validation_fn,omitempty" yaml:"validation_fn"`
 }
 
 // ContextSnapshot captures conversation context at a point in time
@@ -284,7 +297,7 @@ func NewConversationManager(
 	tokenManager *TokenManager,
 	inferenceManager *InferenceManager,
 ) *ConversationManager {
-	return &ConversationManager{
+	cm := &ConversationManager{
 		activeConversations:     make(map[string]*Conversation),
 		conversationsByUser:     make(map[string][]*Conversation),
 		conversationsBySession:  make(map[string]*Conversation),
@@ -293,10 +306,16 @@ func NewConversationManager(
 		memoryManager:           memoryManager,
 		tokenManager:            tokenManager,
 		inferenceManager:        inferenceManager,
+		prompts:                 NewPromptEngine(configManager),
+		analyses:                newAnalysisCache(),
 		conversationTimeout:     2 * time.Hour,
 		maxConversationsPerUser: 10,
 		shutdown:                make(chan struct{}),
 	}
+	if inferenceManager != nil {
+		cm.classifier = inferenceManager
+	}
+	return cm
 }
 
 // StartConversation initiates a new conversation
@@ -371,6 +390,13 @@ func (cm *ConversationManager) StartConversation(ctx context.Context, userID, se
 		conversation.Settings = settings
 	}
 
+	// Share the session's branch tree; the session manager owns it
+	if cm.sessionManager != nil {
+		if session, ok := cm.sessionManager.GetSession(sessionID); ok && session.Threading != nil {
+			conversation.Threading = session.Threading
+		}
+	}
+
 	// Register conversation
 	cm.activeConversations[conversation.ID] = conversation
 	cm.conversationsByUser[userID] = append(cm.conversationsByUser[userID], conversation)
@@ -387,7 +413,7 @@ func (cm *ConversationManager) StartConversation(ctx context.Context, userID, se
 
 // ProcessMessage handles a message in an active conversation
 func (cm *ConversationManager) ProcessMessage(ctx context.Context, conversationID string, message *Message) (*ConversationResponse, error) {
-	conversation, exists := cm.getConversation(conversationID)
+	conversation, exists := cm.GetConversation(conversationID)
 	if !exists {
 		return nil, fmt.Errorf("conversation not found: %s", conversationID)
 	}
@@ -400,7 +426,12 @@ func (cm *ConversationManager) ProcessMessage(ctx context.Context, conversationI
 	conversation.MessageCount++
 
 	// Analyze message intent and extract entities
-	cm.analyzeMessage(conversation, message)
+	cm.analyzeMessage(ctx, conversation, message)
+
+	// A flow waiting on a user_input step takes the message as its answer
+	if def, waiting := cm.awaitingFlowInput(conversation); waiting {
+		return cm.runFlow(ctx, conversation, def, message)
+	}
 
 	// Create conversation step for user input
 	userStep := &ConversationStep{
@@ -423,6 +454,7 @@ func (cm *ConversationManager) ProcessMessage(ctx context.Context, conversationI
 		ModelName:   conversation.CurrentModel,
 		RequestType: cm.mapIntentToInferenceType(conversation.Context.Intent),
 		Messages:    cm.buildInferenceMessages(conversation, message),
+		ToolPolicy:  toolPolicy(conversation.Settings),
 	}
 
 	// Process inference
@@ -432,6 +464,9 @@ func (cm *ConversationManager) ProcessMessage(ctx context.Context, conversationI
 		return nil, fmt.Errorf("inference failed: %w", err)
 	}
 
+	// Record tool calls the inference loop executed
+	conversation.ConversationFlow.Steps = append(conversation.ConversationFlow.Steps, result.Steps...)
+
 	// Create conversation step for AI response
 	aiStep := &ConversationStep{
 		ID:          cm.generateStepID(),
@@ -485,7 +520,7 @@ func (cm *ConversationManager) ProcessMessage(ctx context.Context, conversationI
 
 // ProcessStreamingMessage handles streaming message processing
 func (cm *ConversationManager) ProcessStreamingMessage(ctx context.Context, conversationID string, message *Message) (<-chan *ConversationStreamChunk, error) {
-	conversation, exists := cm.getConversation(conversationID)
+	conversation, exists := cm.GetConversation(conversationID)
 	if !exists {
 		return nil, fmt.Errorf("conversation not found: %s", conversationID)
 	}
@@ -504,7 +539,7 @@ func (cm *ConversationManager) ProcessStreamingMessage(ctx context.Context, conv
 		conversation.MessageCount++
 
 		// Analyze message
-		cm.analyzeMessage(conversation, message)
+		cm.analyzeMessage(ctx, conversation, message)
 
 		// Create user step
 		userStep := &ConversationStep{
@@ -609,9 +644,8 @@ type ConversationStreamChunk struct {
 	Timestamp      time.Time `json:"timestamp"`
 }
 
-// Helper functions
-
-func (cm *ConversationManager) getConversation(id string) (*Conversation, bool) {
+// GetConversation returns an active conversation
+func (cm *ConversationManager) GetConversation(id string) (*Conversation, bool) {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
 
@@ -619,6 +653,19 @@ func (cm *ConversationManager) getConversation(id string) (*Conversation, bool)
 	return conv, exists
 }
 
+// Helper functions
+
+// toolPolicy maps conversation settings onto the tool loop
+func toolPolicy(settings *ConversationSettings) *ToolPolicy {
+	if settings == nil {
+		return nil
+	}
+	return &ToolPolicy{
+		RequireConfirmation: settings.RequireConfirmation,
+		AllowCodeExecution:  settings.AllowCodeExecution,
+	}
+}
+
 func (cm *ConversationManager) generateConversationID() string {
 	bytes := make([]byte, 8)
 	rand.Read(bytes)
@@ -643,6 +690,12 @@ func (cm *ConversationManager) generateMessageID() string {
 	return "msg_" + hex.EncodeToString(bytes)
 }
 
+func (cm *ConversationManager) generateActionID() string {
+	bytes := make([]byte, 4)
+	rand.Read(bytes)
+	return "action_" + hex.EncodeToString(bytes)
+}
+
 func (cm *ConversationManager) getDefaultSettings() *ConversationSettings {
 	return &ConversationSettings{
 		AllowMultiTurn:      true,
@@ -660,44 +713,61 @@ func (cm *ConversationManager) getDefaultSettings() *ConversationSettings {
 	}
 }
 
-func (cm *ConversationManager) analyzeMessage(conversation *Conversation, message *Message) {
-	// Simple intent analysis (in practice, you'd use NLP models)
-	content := strings.ToLower(message.Content)
+func (cm *ConversationManager) analyzeMessage(ctx context.Context, conversation *Conversation, message *Message) {
+	analysis := cm.classifyMessage(ctx, message.Content)
 
-	// Determine intent
-	if strings.Contains(content, "?") {
-		conversation.Context.Intent = IntentQuestion
-	} else if strings.Contains(content, "please") || strings.Contains(content, "can you") {
-		conversation.Context.Intent = IntentRequest
-	} else if strings.Contains(content, "debug") || strings.Contains(content, "error") {
-		conversation.Context.Intent = IntentDebug
-	} else if strings.Contains(content, "learn") || strings.Contains(content, "teach") {
-		conversation.Context.Intent = IntentLearning
-	} else {
-		conversation.Context.Intent = IntentInstruction
+	conversation.Context.Intent = analysis.Intent
+	conversation.Context.IntentConfidence = analysis.Confidence
+	if analysis.Source != AnalysisSourceHeuristic && analysis.Topic != "" {
+		conversation.Context.Topic = analysis.Topic
 	}
-
-	// Extract simple entities (placeholder implementation)
-	entities := cm.extractEntities(message.Content)
-	conversation.Context.Entities = append(conversation.Context.Entities, entities...)
+	conversation.Context.Entities = append(conversation.Context.Entities, analysis.Entities...)
 
 	// Update context snapshot
 	snapshot := &ContextSnapshot{
 		Timestamp: time.Now(),
 		MessageID: message.ID,
-		Topic:     cm.extractTopic(message.Content),
-		Intent:    conversation.Context.Intent,
-		Entities:  entities,
-		UserState: make(map[string]interface{}),
+		Topic:     analysis.Topic,
+		Intent:    analysis.Intent,
+		Entities:  analysis.Entities,
+		UserState: map[string]interface{}{
+			"intent_confidence": analysis.Confidence,
+			"analysis_source":   analysis.Source,
+		},
 	}
 
 	conversation.Context.ContextHistory = append(conversation.Context.ContextHistory, snapshot)
 	conversation.Context.LastUpdated = time.Now()
 }
 
+// extractEntities finds URLs, inline code and file paths in a message
 func (cm *ConversationManager) extractEntities(content string) []*Entity {
-	// Placeholder entity extraction
-	return []*Entity{}
+	entities := []*Entity{}
+	seen := make(map[string]bool)
+	add := func(entityType, value string, position int) {
+		if value == "" || seen[entityType+":"+value] {
+			return
+		}
+		seen[entityType+":"+value] = true
+		entities = append(entities, &Entity{
+			Name:       value,
+			Type:       entityType,
+			Value:      value,
+			Confidence: heuristicEntityConfidence,
+			Position:   position,
+		})
+	}
+
+	for _, match := range urlPattern.FindAllStringIndex(content, -1) {
+		add("url", content[match[0]:match[1]], match[0])
+	}
+	for _, match := range codeSpanPattern.FindAllStringSubmatchIndex(content, -1) {
+		add("code", content[match[2]:match[3]], match[2])
+	}
+	for _, match := range filePattern.FindAllStringSubmatchIndex(content, -1) {
+		add("file", content[match[2]:match[3]], match[2])
+	}
+	return entities
 }
 
 func (cm *ConversationManager) extractTopic(content string) string {
@@ -781,17 +851,34 @@ func (cm *ConversationManager) buildInferenceMessages(conversation *Conversation
 	return messages
 }
 
+// buildSystemPrompt renders the conversation-system template, through the session's
+// persona when it has one
 func (cm *ConversationManager) buildSystemPrompt(conversation *Conversation) string {
-	// Build system prompt based on conversation context
-	prompt := fmt.Sprintf("You are a helpful AI assistant. The user's current intent is %s.", conversation.Context.Intent)
-
-	if conversation.Context.Topic != "" {
-		prompt += fmt.Sprintf(" The conversation topic is: %s.", conversation.Context.Topic)
+	data := &PromptData{
+		Model:        conversation.CurrentModel,
+		Conversation: conversation.Context,
 	}
 
-	if len(conversation.Context.UserGoals) > 0 {
-		prompt += fmt.Sprintf(" The user's goals are: %s.", strings.Join(conversation.Context.UserGoals, ", "))
+	persona := ""
+	if cm.sessionManager != nil {
+		if session, ok := cm.sessionManager.GetSession(conversation.SessionID); ok {
+			persona = session.Persona
+			data.User = session.Context.UserProfile
+			data.Code = session.Context.CodeContext
+			data.Project = session.Context.ProjectContext
+		}
 	}
 
+	var prompt string
+	var err error
+	if persona != "" {
+		prompt, err = cm.prompts.RenderPersona(persona, ConversationSystemTemplate, data)
+	} else {
+		prompt, err = cm.prompts.Render(ConversationSystemTemplate, data)
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("conversation_id", conversation.ID).Msg("Failed to render system prompt, using built-in default")
+		prompt, _ = renderPromptTemplate(promptTemplateIndex(builtinPromptTemplates), ConversationSystemTemplate, prepareData(data))
+	}
 	return prompt
 }
@@ -6,6 +6,9 @@ package managers
 
 import (
 	// stdlib
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -20,27 +23,32 @@ import (
 
 // DiskManager handles persistent storage and file operations
 type DiskManager struct {
-	mu              sync.RWMutex
-	configManager   *ConfigManager
-	memoryManager   *MemoryManager
-	sessionManager  *SessionManager
-	conversationMgr *ConversationManager
-	dataDir         string        // Base directory for storage
-	backupDir       string        // Directory for backups
-	maxDiskUsage    int64         // Max disk usage in bytes
-	retentionDays   int           // Days to retain data
-	backupInterval  time.Duration // Time between backups
-	shutdown        chan struct{}
-	backupTicker    *time.Ticker
+	mu                sync.RWMutex
+	configManager     *ConfigManager
+	memoryManager     *MemoryManager
+	sessionManager    *SessionManager
+	conversationMgr   *ConversationManager
+	dataDir           string        // Base directory for storage
+	backupDir         string        // Directory for backups
+	maxDiskUsage      int64         // Max disk usage in bytes
+	workspaceMaxBytes int64         // Per-user workspace byte quota
+	workspaceMaxFiles int           // Per-user workspace file-count quota
+	auditMu           sync.Mutex    // Serializes file audit log appends
+	retentionDays     int           // Days to retain data
+	backupInterval    time.Duration // Time between backups
+	shutdown          chan struct{}
+	backupTicker      *time.Ticker
 }
 
 // StorageConfig holds disk-related configuration
 type StorageConfig struct {
-	DataDir        string `yaml:"data_dir"`
-	BackupDir      string `yaml:"backup_dir"`
-	MaxDiskUsage   int64  `yaml:"max_disk_usage"`  // In bytes
-	RetentionDays  int    `yaml:"retention_days"`  // Days to keep data
-	BackupInterval int    `yaml:"backup_interval"` // Hours between backups
+	DataDir           string `yaml:"data_dir"`
+	BackupDir         string `yaml:"backup_dir"`
+	MaxDiskUsage      int64  `yaml:"max_disk_usage"`      // In bytes
+	RetentionDays     int    `yaml:"retention_days"`      // Days to keep data
+	BackupInterval    int    `yaml:"backup_interval"`     // Hours between backups
+	WorkspaceMaxBytes int64  `yaml:"workspace_max_bytes"` // Per-user tool workspace quota
+	WorkspaceMaxFiles int    `yaml:"workspace_max_files"` // Per-user tool workspace file limit
 }
 
 // FileMetadata holds metadata for stored files
@@ -57,11 +65,13 @@ type FileMetadata struct {
 // NewDiskManager creates a new disk manager
 func NewDiskManager(cfgMgr *ConfigManager, memMgr *MemoryManager, sessMgr *SessionManager, convMgr *ConversationManager) (*DiskManager, error) {
 	storageConfig := &StorageConfig{
-		DataDir:        "../data",
-		BackupDir:      "../backups",
-		MaxDiskUsage:   10 * 1024 * 1024 * 1024,
-		RetentionDays:  30,
-		BackupInterval: 24,
+		DataDir:           "../data",
+		BackupDir:         "../backups",
+		MaxDiskUsage:      10 * 1024 * 1024 * 1024,
+		RetentionDays:     30,
+		BackupInterval:    24,
+		WorkspaceMaxBytes: 100 * 1024 * 1024,
+		WorkspaceMaxFiles: 1000,
 	}
 
 	if err := cfgMgr.LoadConfig("configs/storage.yaml", storageConfig); err != nil {
@@ -69,23 +79,29 @@ func NewDiskManager(cfgMgr *ConfigManager, memMgr *MemoryManager, sessMgr *Sessi
 	}
 
 	dm := &DiskManager{
-		configManager:   cfgMgr,
-		memoryManager:   memMgr,
-		sessionManager:  sessMgr,
-		conversationMgr: convMgr,
-		dataDir:         storageConfig.DataDir,
-		backupDir:       storageConfig.BackupDir,
-		maxDiskUsage:    storageConfig.MaxDiskUsage,
-		retentionDays:   storageConfig.RetentionDays,
-		backupInterval:  time.Duration(storageConfig.BackupInterval) * time.Hour,
-		shutdown:        make(chan struct{}),
-		backupTicker:    time.NewTicker(time.Duration(storageConfig.BackupInterval) * time.Hour),
+		configManager:     cfgMgr,
+		memoryManager:     memMgr,
+		sessionManager:    sessMgr,
+		conversationMgr:   convMgr,
+		dataDir:           storageConfig.DataDir,
+		backupDir:         storageConfig.BackupDir,
+		maxDiskUsage:      storageConfig.MaxDiskUsage,
+		workspaceMaxBytes: storageConfig.WorkspaceMaxBytes,
+		workspaceMaxFiles: storageConfig.WorkspaceMaxFiles,
+		retentionDays:     storageConfig.RetentionDays,
+		backupInterval:    time.Duration(storageConfig.BackupInterval) * time.Hour,
+		shutdown:          make(chan struct{}),
+		backupTicker:      time.NewTicker(time.Duration(storageConfig.BackupInterval) * time.Hour),
 	}
 
 	if err := dm.ensureDirectories(); err != nil {
 		return nil, fmt.Errorf("failed to initialize directories: %w", err)
 	}
 
+	if err := dm.LoadMemoryIndex(); err != nil {
+		log.Warn().Err(err).Msg("Failed to rebuild memory index from disk")
+	}
+
 	go dm.runBackgroundTasks()
 
 	return dm, nil
@@ -98,6 +114,8 @@ func (dm *DiskManager) ensureDirectories() error {
 		filepath.Join(dm.dataDir, "memories"),
 		filepath.Join(dm.dataDir, "sessions"),
 		filepath.Join(dm.dataDir, "conversations"),
+		filepath.Join(dm.dataDir, "indexes"),
+		filepath.Join(dm.dataDir, "workspaces"),
 		dm.backupDir,
 	}
 	for _, dir := range dirs {
@@ -117,6 +135,9 @@ func (dm *DiskManager) SaveMemory(memory *Memory) error {
 		return err
 	}
 	path := filepath.Join(dm.dataDir, "memories", memory.UserID, fmt.Sprintf("%s.json", memory.ID))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create memory dir: %w", err)
+	}
 	data, err := json.MarshalIndent(memory, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal memory: %w", err)
@@ -144,6 +165,78 @@ func (dm *DiskManager) LoadMemory(userID, memoryID string) (*Memory, error) {
 	return &memory, nil
 }
 
+// persistedVectorIndex wraps an ANN graph snapshot with its index key
+type persistedVectorIndex struct {
+	Key      string        `json:"key"`
+	Snapshot *HNSWSnapshot `json:"snapshot"`
+}
+
+// SaveMemoryIndex persists the ANN graphs of all users
+func (dm *DiskManager) SaveMemoryIndex() error {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	if err := dm.checkDiskUsage(); err != nil {
+		return err
+	}
+	for key, snapshot := range dm.memoryManager.memoryIndex.VectorSnapshots() {
+		data, err := json.Marshal(&persistedVectorIndex{Key: key, Snapshot: snapshot})
+		if err != nil {
+			return fmt.Errorf("marshal vector index: %w", err)
+		}
+		if err := os.WriteFile(dm.getVectorIndexFilePath(key), data, 0644); err != nil {
+			return fmt.Errorf("write vector index: %w", err)
+		}
+	}
+	log.Info().Msg("Saved memory vector indexes")
+	return nil
+}
+
+// LoadMemoryIndex rebuilds memory stores and ANN indexes from persisted memories
+func (dm *DiskManager) LoadMemoryIndex() error {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	memories := make([]*Memory, 0)
+	err := filepath.Walk(filepath.Join(dm.dataDir, "memories"), func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Failed to read memory")
+			return nil
+		}
+		var memory Memory
+		if err := json.Unmarshal(data, &memory); err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Failed to unmarshal memory")
+			return nil
+		}
+		memories = append(memories, &memory)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("walk memories: %w", err)
+	}
+
+	snapshots := make(map[string]*HNSWSnapshot)
+	indexFiles, _ := filepath.Glob(filepath.Join(dm.dataDir, "indexes", "*.json"))
+	for _, path := range indexFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var persisted persistedVectorIndex
+		if err := json.Unmarshal(data, &persisted); err != nil || persisted.Snapshot == nil {
+			log.Warn().Err(err).Str("path", path).Msg("Ignoring invalid vector index")
+			continue
+		}
+		snapshots[persisted.Key] = persisted.Snapshot
+	}
+
+	dm.memoryManager.RestoreMemories(memories, snapshots)
+	return nil
+}
+
 // SaveSession persists a session to disk
 func (dm *DiskManager) SaveSession(session *Session) error {
 	dm.mu.Lock()
@@ -214,6 +307,31 @@ func (dm *DiskManager) LoadConversation(conversationID string) (*Conversation, e
 	return &conv, nil
 }
 
+// LoadConversations retrieves every saved conversation, skipping unreadable files
+func (dm *DiskManager) LoadConversations() ([]*Conversation, error) {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	paths, err := filepath.Glob(filepath.Join(dm.dataDir, "conversations", "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("list conversations: %w", err)
+	}
+	conversations := make([]*Conversation, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Skipping unreadable conversation")
+			continue
+		}
+		var conv Conversation
+		if err := json.Unmarshal(data, &conv); err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Skipping corrupt conversation")
+			continue
+		}
+		conversations = append(conversations, &conv)
+	}
+	return conversations, nil
+}
+
 // CreateBackup creates a backup of specified data
 func (dm *DiskManager) CreateBackup(dataType, userID string) error {
 	dm.mu.Lock()
@@ -319,6 +437,9 @@ func (dm *DiskManager) runBackgroundTasks() {
 				}
 			}
 			dm.cleanupOldData()
+			if err := dm.SaveMemoryIndex(); err != nil {
+				log.Error().Err(err).Msg("Failed to save memory index")
+			}
 		case <-dm.shutdown:
 			dm.backupTicker.Stop()
 			return
@@ -331,11 +452,36 @@ func (dm *DiskManager) getMemoryFilePath(userID, memoryID string) string {
 	return filepath.Join(dm.dataDir, "memories", userID, fmt.Sprintf("%s.json", memoryID))
 }
 
+// getVectorIndexFilePath generates file path for a persisted ANN graph
+func (dm *DiskManager) getVectorIndexFilePath(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(dm.dataDir, "indexes", fmt.Sprintf("%s.json", hex.EncodeToString(hash[:8])))
+}
+
 // getSessionFilePath generates file path for session storage
 func (dm *DiskManager) getSessionFilePath(sessionID string) string {
 	return filepath.Join(dm.dataDir, "sessions", fmt.Sprintf("%s.json", sessionID))
 }
 
+// SessionFilePath returns the file a session is stored in; tools keep scratch files beside it
+func (dm *DiskManager) SessionFilePath(sessionID string) string {
+	return dm.getSessionFilePath(sessionID)
+}
+
+// WriteFile writes content to path, creating its directory
+func (dm *DiskManager) WriteFile(ctx context.Context, path, content string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
 // getConversationFilePath generates file path for conversation storage
 func (dm *DiskManager) getConversationFilePath(conversationID string) string {
 	return filepath.Join(dm.dataDir, "conversations", fmt.Sprintf("%s.json", conversationID))
@@ -344,6 +490,9 @@ func (dm *DiskManager) getConversationFilePath(conversationID string) string {
 // Shutdown gracefully shuts down the disk manager
 func (dm *DiskManager) Shutdown() {
 	close(dm.shutdown)
+	if err := dm.SaveMemoryIndex(); err != nil {
+		log.Error().Err(err).Msg("Failed to save memory index")
+	}
 	for uidInt := range dm.memoryManager.GetAllUsers() {
 		uid := fmt.Sprintf("%d", uidInt)
 		for _, dt := range []string{"memory", "session", "conversation"} {
@@ -0,0 +1,168 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/embedding-provider.go
+
+package managers
+
+import (
+	// stdlib
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	// third-party
+)
+
+// EmbeddingProvider generates vector embeddings for memory content
+type EmbeddingProvider interface {
+	Name() string
+	Dimensions() int // 0 until the provider has produced its first vector
+	Embed(ctx context.Context, text string) ([]float64, error)
+}
+
+// OllamaEmbeddingProvider calls Ollama's /api/embeddings endpoint
+type OllamaEmbeddingProvider struct {
+	mu         sync.RWMutex
+	baseURL    string
+	modelName  string
+	client     *http.Client
+	dimensions int
+}
+
+// HashEmbeddingProvider builds hashed word-count vectors; used offline and as a fallback
+type HashEmbeddingProvider struct {
+	dimensions int
+}
+
+// OllamaEmbeddingRequest represents request to Ollama's /api/embeddings
+type OllamaEmbeddingRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+}
+
+// OllamaEmbeddingResponse represents response from Ollama's /api/embeddings
+type OllamaEmbeddingResponse struct {
+	Embedding []float64 `json:"embedding"`
+}
+
+// NewOllamaEmbeddingProvider creates a provider for the given embedding model config
+func NewOllamaEmbeddingProvider(ollamaBaseURL string, config *ModelConfig) *OllamaEmbeddingProvider {
+	return &OllamaEmbeddingProvider{
+		baseURL:   strings.TrimRight(ollamaBaseURL, "/"),
+		modelName: config.Name,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the provider identifier
+func (op *OllamaEmbeddingProvider) Name() string {
+	return "ollama:" + op.modelName
+}
+
+// Dimensions returns the vector size reported by the model
+func (op *OllamaEmbeddingProvider) Dimensions() int {
+	op.mu.RLock()
+	defer op.mu.RUnlock()
+	return op.dimensions
+}
+
+// Embed requests an embedding for text from Ollama
+func (op *OllamaEmbeddingProvider) Embed(ctx context.Context, text string) ([]float64, error) {
+	reqBody, err := json.Marshal(&OllamaEmbeddingRequest{
+		Model:  op.modelName,
+		Prompt: text,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embedding request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", op.baseURL+"/api/embeddings", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := op.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding request failed with status %d", resp.StatusCode)
+	}
+
+	var embeddingResp OllamaEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embeddingResp); err != nil {
+		return nil, fmt.Errorf("failed to decode embedding response: %w", err)
+	}
+	if len(embeddingResp.Embedding) == 0 {
+		return nil, fmt.Errorf("empty embedding returned for model %s", op.modelName)
+	}
+
+	op.mu.Lock()
+	op.dimensions = len(embeddingResp.Embedding)
+	op.mu.Unlock()
+
+	return embeddingResp.Embedding, nil
+}
+
+// NewHashEmbeddingProvider creates a hashed word-count embedder
+func NewHashEmbeddingProvider(dimensions int) *HashEmbeddingProvider {
+	if dimensions <= 0 {
+		dimensions = 300
+	}
+	return &HashEmbeddingProvider{dimensions: dimensions}
+}
+
+// Name returns the provider identifier
+func (hp *HashEmbeddingProvider) Name() string {
+	return "hash"
+}
+
+// Dimensions returns the fixed vector size
+func (hp *HashEmbeddingProvider) Dimensions() int {
+	return hp.dimensions
+}
+
+// Embed builds a normalized word frequency vector
+func (hp *HashEmbeddingProvider) Embed(ctx context.Context, text string) ([]float64, error) {
+	embedding := make([]float64, hp.dimensions)
+	words := strings.Fields(strings.ToLower(text))
+
+	for i, word := range words {
+		if i >= hp.dimensions {
+			break
+		}
+		// Simple hash-based embedding
+		embedding[simpleHash(word)%hp.dimensions] += 1.0
+	}
+
+	// Normalize
+	magnitude := 0.0
+	for _, val := range embedding {
+		magnitude += val * val
+	}
+	magnitude = math.Sqrt(magnitude)
+
+	if magnitude > 0 {
+		for i := range embedding {
+			embedding[i] /= magnitude
+		}
+	}
+
+	return embedding, nil
+}
+
+func simpleHash(s string) int {
+	var hash uint32
+	for _, char := range s {
+		hash = hash*31 + uint32(char)
+	}
+	return int(hash)
+}
@@ -0,0 +1,362 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/embedding-provider_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// embeddingVocabulary gives the Ollama stand-in one dimension per topic word
+var embeddingVocabulary = []string{"golang", "python", "database", "kubernetes"}
+
+// ollamaStandIn serves /api/embeddings like Ollama, embedding prompts by topic word counts
+type ollamaStandIn struct {
+	*httptest.Server
+	requests atomic.Int64
+	mu       sync.Mutex
+	status   int
+	padding  int // extra zero dimensions, to simulate swapping the model
+	models   []string
+}
+
+func newOllamaStandIn(t *testing.T) *ollamaStandIn {
+	t.Helper()
+	s := &ollamaStandIn{status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *ollamaStandIn) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/api/embeddings" {
+		http.NotFound(w, r)
+		return
+	}
+	s.requests.Add(1)
+
+	var req OllamaEmbeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	status, padding := s.status, s.padding
+	s.models = append(s.models, req.Model)
+	s.mu.Unlock()
+	if status != http.StatusOK {
+		http.Error(w, "model not loaded", status)
+		return
+	}
+
+	embedding := make([]float64, len(embeddingVocabulary)+padding)
+	for _, word := range strings.Fields(strings.ToLower(req.Prompt)) {
+		for i, topic := range embeddingVocabulary {
+			if strings.Trim(word, ".,?!") == topic {
+				embedding[i]++
+			}
+		}
+	}
+	json.NewEncoder(w).Encode(&OllamaEmbeddingResponse{Embedding: embedding})
+}
+
+func (s *ollamaStandIn) set(status, padding int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+	s.padding = padding
+}
+
+func newTestMemoryManager(t *testing.T) *MemoryManager {
+	t.Helper()
+	mm := NewMemoryManager(GetConfigManager())
+	t.Cleanup(func() { mm.Shutdown(context.Background()) })
+	return mm
+}
+
+func TestOllamaEmbeddingProviderEmbed(t *testing.T) {
+	ollama := newOllamaStandIn(t)
+	provider := NewOllamaEmbeddingProvider(ollama.URL+"/", &ModelConfig{Name: "nomic-embed-text"})
+
+	if got := provider.Name(); got != "ollama:nomic-embed-text" {
+		t.Errorf("Name() = %q", got)
+	}
+	if got := provider.Dimensions(); got != 0 {
+		t.Errorf("Dimensions() before first embed = %d, want 0", got)
+	}
+
+	embedding, err := provider.Embed(context.Background(), "golang and a database")
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	want := []float64{1, 0, 1, 0}
+	if len(embedding) != len(want) {
+		t.Fatalf("embedding = %v, want %v", embedding, want)
+	}
+	for i := range want {
+		if embedding[i] != want[i] {
+			t.Fatalf("embedding = %v, want %v", embedding, want)
+		}
+	}
+	if got := provider.Dimensions(); got != len(want) {
+		t.Errorf("Dimensions() = %d, want %d", got, len(want))
+	}
+	if len(ollama.models) != 1 || ollama.models[0] != "nomic-embed-text" {
+		t.Errorf("requested models = %v", ollama.models)
+	}
+}
+
+func TestOllamaEmbeddingProviderErrors(t *testing.T) {
+	ollama := newOllamaStandIn(t)
+	provider := NewOllamaEmbeddingProvider(ollama.URL, &ModelConfig{Name: "nomic-embed-text"})
+
+	ollama.set(http.StatusInternalServerError, 0)
+	if _, err := provider.Embed(context.Background(), "golang"); err == nil {
+		t.Error("Embed succeeded against a failing server")
+	}
+
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&OllamaEmbeddingResponse{})
+	}))
+	defer empty.Close()
+	provider = NewOllamaEmbeddingProvider(empty.URL, &ModelConfig{Name: "nomic-embed-text"})
+	if _, err := provider.Embed(context.Background(), "golang"); err == nil {
+		t.Error("Embed accepted an empty embedding")
+	}
+}
+
+func TestMemoryManagerFallsBackToHashEmbeddings(t *testing.T) {
+	ollama := newOllamaStandIn(t)
+	ollama.set(http.StatusServiceUnavailable, 0)
+
+	mm := newTestMemoryManager(t)
+	mm.SetEmbeddingProvider(NewOllamaEmbeddingProvider(ollama.URL, &ModelConfig{Name: "nomic-embed-text"}))
+
+	memory := &Memory{UserID: "u1", Content: "golang services talk to the database"}
+	if err := mm.StoreMemory(context.Background(), memory); err != nil {
+		t.Fatalf("StoreMemory: %v", err)
+	}
+	if memory.EmbeddingModel != "hash" {
+		t.Errorf("EmbeddingModel = %q, want hash fallback", memory.EmbeddingModel)
+	}
+	if len(memory.Embedding) != 300 {
+		t.Errorf("len(Embedding) = %d, want 300", len(memory.Embedding))
+	}
+}
+
+func TestMemoryManagerCachesEmbeddingsPerDimension(t *testing.T) {
+	ollama := newOllamaStandIn(t)
+	mm := newTestMemoryManager(t)
+	provider := NewOllamaEmbeddingProvider(ollama.URL, &ModelConfig{Name: "nomic-embed-text"})
+	mm.SetEmbeddingProvider(provider)
+	ctx := context.Background()
+
+	if _, _, err := mm.generateEmbedding(ctx, "golang"); err != nil {
+		t.Fatalf("generateEmbedding: %v", err)
+	}
+	if _, _, err := mm.generateEmbedding(ctx, "golang"); err != nil {
+		t.Fatalf("generateEmbedding: %v", err)
+	}
+	if got := ollama.requests.Load(); got != 1 {
+		t.Fatalf("requests after repeated content = %d, want 1 (cached)", got)
+	}
+
+	// The model behind the provider changes and reports a different size
+	ollama.set(http.StatusOK, 4)
+	embedding, _, err := mm.generateEmbedding(ctx, "python")
+	if err != nil {
+		t.Fatalf("generateEmbedding: %v", err)
+	}
+	if len(embedding) != 8 {
+		t.Fatalf("len(embedding) = %d, want 8", len(embedding))
+	}
+
+	embedding, _, err = mm.generateEmbedding(ctx, "golang")
+	if err != nil {
+		t.Fatalf("generateEmbedding: %v", err)
+	}
+	if len(embedding) != 8 {
+		t.Errorf("cached vector of the old size survived the dimension change: len = %d", len(embedding))
+	}
+	if got := ollama.requests.Load(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+}
+
+func TestMemoryManagerSwitchingProviderDropsCache(t *testing.T) {
+	ollama := newOllamaStandIn(t)
+	mm := newTestMemoryManager(t)
+	ctx := context.Background()
+
+	mm.SetEmbeddingProvider(NewOllamaEmbeddingProvider(ollama.URL, &ModelConfig{Name: "first"}))
+	if _, _, err := mm.generateEmbedding(ctx, "golang"); err != nil {
+		t.Fatalf("generateEmbedding: %v", err)
+	}
+
+	mm.SetEmbeddingProvider(NewOllamaEmbeddingProvider(ollama.URL, &ModelConfig{Name: "second"}))
+	if len(mm.embeddingCache) != 0 {
+		t.Errorf("embedding cache kept %d entries after the provider changed", len(mm.embeddingCache))
+	}
+	_, provider, err := mm.generateEmbedding(ctx, "golang")
+	if err != nil {
+		t.Fatalf("generateEmbedding: %v", err)
+	}
+	if provider != "ollama:second" {
+		t.Errorf("provider = %q, want ollama:second", provider)
+	}
+}
+
+func TestRetrieveMemoriesRanksByOllamaSimilarity(t *testing.T) {
+	ollama := newOllamaStandIn(t)
+	mm := newTestMemoryManager(t)
+	mm.SetEmbeddingProvider(NewOllamaEmbeddingProvider(ollama.URL, &ModelConfig{Name: "nomic-embed-text"}))
+	ctx := context.Background()
+
+	for _, content := range []string{
+		"deploying python workers on kubernetes",
+		"tuning the database connection pool",
+		"golang generics in the scheduler",
+	} {
+		if err := mm.StoreMemory(ctx, &Memory{UserID: "u1", Content: content, Importance: 0.8}); err != nil {
+			t.Fatalf("StoreMemory: %v", err)
+		}
+	}
+
+	results, err := mm.RetrieveMemories(ctx, &MemoryQuery{
+		UserID:              "u1",
+		Content:             "which database settings did we change?",
+		SimilarityThreshold: 0.5,
+	})
+	if err != nil {
+		t.Fatalf("RetrieveMemories: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	if got := results[0].Memory.Content; !strings.Contains(got, "database") {
+		t.Errorf("top result = %q", got)
+	}
+	if results[0].Similarity < 0.99 {
+		t.Errorf("similarity = %f, want ~1", results[0].Similarity)
+	}
+}
+
+// gatedEmbedder embeds like the hash provider, but calls for texts in slow wait for
+// release; calls counts every Embed per text
+type gatedEmbedder struct {
+	*HashEmbeddingProvider
+	slow    map[string]bool
+	release chan struct{}
+	mu      sync.Mutex
+	calls   map[string]int
+}
+
+func (ge *gatedEmbedder) Name() string { return "gated" }
+
+func (ge *gatedEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	ge.mu.Lock()
+	ge.calls[text]++
+	ge.mu.Unlock()
+	if ge.slow[text] {
+		select {
+		case <-ge.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return ge.HashEmbeddingProvider.Embed(ctx, text)
+}
+
+func (ge *gatedEmbedder) count(text string) int {
+	ge.mu.Lock()
+	defer ge.mu.Unlock()
+	return ge.calls[text]
+}
+
+func TestRetrieveMemoriesEmbedsOutsideTheLock(t *testing.T) {
+	mm := newTestMemoryManager(t)
+	query := "which database settings did we change?"
+	embedder := &gatedEmbedder{HashEmbeddingProvider: NewHashEmbeddingProvider(300), slow: map[string]bool{query: true}, release: make(chan struct{}), calls: make(map[string]int)}
+	mm.SetEmbeddingProvider(embedder)
+	if err := mm.StoreMemory(context.Background(), &Memory{UserID: "u1", Content: "tuning the database connection pool", Importance: 0.8}); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan []*MemoryResult)
+	go func() {
+		results, _ := mm.RetrieveMemories(context.Background(), &MemoryQuery{UserID: "u1", Content: query})
+		done <- results
+	}()
+	for embedder.count(query) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Writers are not held up while the query is embedded
+	if !mm.mu.TryLock() {
+		t.Error("memory lock held while embedding the query")
+	} else {
+		mm.mu.Unlock()
+	}
+	close(embedder.release)
+	if results := <-done; len(results) != 1 || results[0].Memory.AccessCount != 2 {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestRetrieveMemoriesRefreshesOncePerUser(t *testing.T) {
+	mm := newTestMemoryManager(t)
+	ctx := context.Background()
+	contents := []string{"deploying python workers on kubernetes", "tuning the database connection pool"}
+	for _, content := range contents {
+		if err := mm.StoreMemory(ctx, &Memory{UserID: "u1", Content: content, Importance: 0.8}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Memories embedded by the previous provider are refreshed in the background; while
+	// that is blocked, further searches must not start another refresh
+	slow := map[string]bool{contents[0]: true, contents[1]: true}
+	embedder := &gatedEmbedder{HashEmbeddingProvider: NewHashEmbeddingProvider(300), slow: slow, release: make(chan struct{}), calls: make(map[string]int)}
+	mm.SetEmbeddingProvider(embedder)
+	for i := 0; i < 5; i++ {
+		if _, err := mm.RetrieveMemories(ctx, &MemoryQuery{UserID: "u1", Content: "database"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := embedder.count(contents[0]) + embedder.count(contents[1]); got != 1 {
+		t.Errorf("%d refreshes started, want 1", got)
+	}
+
+	close(embedder.release)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		memories, _ := mm.GetUserMemories("u1")
+		mm.mu.RLock()
+		refreshed := memories[0].EmbeddingModel == "gated" && memories[1].EmbeddingModel == "gated"
+		mm.mu.RUnlock()
+		if refreshed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("embeddings were not refreshed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	for _, content := range contents {
+		if got := embedder.count(content); got != 1 {
+			t.Errorf("%q embedded %d times, want 1", content, got)
+		}
+	}
+}
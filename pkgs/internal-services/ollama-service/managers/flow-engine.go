@@ -0,0 +1,730 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/flow-engine.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+)
+
+// FlowDefinition is a multi-step workflow from configs/flows.yaml
+type FlowDefinition struct {
+	Name         string               `yaml:"name"`
+	Description  string               `yaml:"description"`
+	Type         ConversationFlowType `yaml:"type"`
+	AutoContinue bool                 `yaml:"auto_continue"` // run steps back to back instead of one per AdvanceFlow
+	MaxSteps     int                  `yaml:"max_steps"`     // steps executed before the flow pauses
+	Steps        []FlowStepDefinition `yaml:"steps"`
+}
+
+// FlowStepDefinition is one step of a flow. Prompt, string arguments, input and
+// branch conditions are text/templates rendered against the flow state.
+type FlowStepDefinition struct {
+	ID                  string                 `yaml:"id"`
+	Type                ConversationStepType   `yaml:"type"`
+	Description         string                 `yaml:"description"`
+	Prompt              string                 `yaml:"prompt"`     // model prompt, or the question a user_input step asks
+	Tool                string                 `yaml:"tool"`       // tool_execution
+	Arguments           map[string]interface{} `yaml:"arguments"`  // tool_execution
+	Input               string                 `yaml:"input"`      // validation: value to check, default the last output
+	Validation          *StepValidation        `yaml:"validation"` // user_input and validation
+	Branches            []FlowBranch           `yaml:"branches"`   // decision; the first match wins
+	SaveAs              string                 `yaml:"save_as"`    // flow state key for the step's output
+	Next                string                 `yaml:"next"`       // default the following step; "end" finishes
+	OnFailure           string                 `yaml:"on_failure"` // default stop the flow with an error
+	RequireConfirmation bool                   `yaml:"require_confirmation"`
+}
+
+// FlowBranch sends a decision step to Next when When renders "true"
+type FlowBranch struct {
+	When string `yaml:"when"`
+	Next string `yaml:"next"`
+}
+
+// FlowStore persists conversations so running flows survive a restart
+type FlowStore interface {
+	SaveConversation(conversation *Conversation) error
+	LoadConversations() ([]*Conversation, error)
+}
+
+// Flow bookkeeping
+const (
+	flowEnd             = "end"
+	flowLastOutputKey   = "last_output"
+	flowStopReasonKey   = "stop_reason"
+	flowActionType      = "flow_step"
+	actionStatusPending = "pending"
+	actionApproved      = "approved"
+	actionRejected      = "rejected"
+	actionExecuted      = "executed"
+	defaultSummaryStep  = "Summarize the conversation so far."
+)
+
+// flowStepResult is what executing one step produced
+type flowStepResult struct {
+	output string
+	next   string // empty means the following step
+	wait   bool   // the step needs user input before it can complete
+	step   *ConversationStep
+	tokens int64
+	model  string
+}
+
+// SetFlowStore persists flow progress after every step
+func (cm *ConversationManager) SetFlowStore(store FlowStore) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.flowStore = store
+}
+
+// RestoreFlows re-registers persisted conversations whose flow had not finished
+func (cm *ConversationManager) RestoreFlows() (int, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if cm.flowStore == nil {
+		return 0, nil
+	}
+	conversations, err := cm.flowStore.LoadConversations()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load conversations: %w", err)
+	}
+
+	restored := 0
+	for _, conversation := range conversations {
+		flow := conversation.ConversationFlow
+		if flow == nil || flow.Name == "" || flowFinished(conversation) {
+			continue
+		}
+		if _, exists := cm.activeConversations[conversation.ID]; exists {
+			continue
+		}
+		cm.activeConversations[conversation.ID] = conversation
+		cm.conversationsByUser[conversation.UserID] = append(cm.conversationsByUser[conversation.UserID], conversation)
+		if conversation.SessionID != "" {
+			cm.conversationsBySession[conversation.SessionID] = conversation
+		}
+		restored++
+	}
+
+	log.Info().Int("flows", restored).Msg("Restored conversation flows")
+	return restored, nil
+}
+
+// StartFlow begins a flow definition on a conversation and runs it until it waits,
+// pauses or ends
+func (cm *ConversationManager) StartFlow(ctx context.Context, conversationID, flowName string, vars map[string]interface{}) (*ConversationResponse, error) {
+	conversation, exists := cm.GetConversation(conversationID)
+	if !exists {
+		return nil, fmt.Errorf("conversation not found: %s", conversationID)
+	}
+	def, err := cm.configManager.GetFlowDefinition(flowName)
+	if err != nil {
+		return nil, err
+	}
+	if len(def.Steps) == 0 {
+		return nil, fmt.Errorf("flow %s has no steps", flowName)
+	}
+
+	conversation.mu.Lock()
+	defer conversation.mu.Unlock()
+
+	flow := conversation.ConversationFlow
+	if flow.Name != "" && !flowFinished(conversation) {
+		return nil, fmt.Errorf("conversation %s is already running flow %s", conversationID, flow.Name)
+	}
+
+	state := make(map[string]interface{}, len(vars)+1)
+	for k, v := range vars {
+		state[k] = v
+	}
+	flow.Name = def.Name
+	if def.Type != "" {
+		flow.FlowType = def.Type
+	}
+	flow.AutoContinue = def.AutoContinue
+	if def.MaxSteps > 0 {
+		flow.MaxSteps = def.MaxSteps
+	}
+	flow.NextStep = def.Steps[0].ID
+	flow.StepsRun = 0
+	flow.FlowState = state
+	conversation.Status = ConversationStatusActive
+
+	log.Info().
+		Str("conversation_id", conversationID).
+		Str("flow", def.Name).
+		Msg("Started conversation flow")
+
+	return cm.runFlow(ctx, conversation, def, nil)
+}
+
+// AdvanceFlow runs the next step of a flow that does not auto-continue, or resumes one
+// paused at its step limit
+func (cm *ConversationManager) AdvanceFlow(ctx context.Context, conversationID string) (*ConversationResponse, error) {
+	conversation, def, err := cm.lockFlow(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer conversation.mu.Unlock()
+
+	switch conversation.Status {
+	case ConversationStatusWaiting:
+		return nil, fmt.Errorf("flow %s is waiting for input or confirmation", def.Name)
+	case ConversationStatusPaused:
+		// Resuming grants another MaxSteps steps
+		conversation.ConversationFlow.StepsRun = 0
+		delete(conversation.ConversationFlow.FlowState, flowStopReasonKey)
+		conversation.Status = ConversationStatusActive
+	}
+	return cm.runFlow(ctx, conversation, def, nil)
+}
+
+// ConfirmFlowAction approves or rejects a step waiting for confirmation. A rejected
+// step goes to its on_failure step, or ends the flow.
+func (cm *ConversationManager) ConfirmFlowAction(ctx context.Context, conversationID, actionID string, approved bool) (*ConversationResponse, error) {
+	conversation, def, err := cm.lockFlow(conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer conversation.mu.Unlock()
+
+	flow := conversation.ConversationFlow
+	var action *PendingAction
+	for _, candidate := range flow.PendingActions {
+		if candidate.ID == actionID && candidate.Type == flowActionType {
+			action = candidate
+		}
+	}
+	if action == nil || action.Status != actionStatusPending {
+		return nil, fmt.Errorf("no pending flow action: %s", actionID)
+	}
+
+	conversation.Status = ConversationStatusActive
+	if approved {
+		action.Status = actionApproved
+		return cm.runFlow(ctx, conversation, def, nil)
+	}
+
+	action.Status = actionRejected
+	stepID, _ := action.Parameters["step_id"].(string)
+	if step := def.step(stepID); step != nil && step.OnFailure != "" {
+		flow.NextStep = step.OnFailure
+		return cm.runFlow(ctx, conversation, def, nil)
+	}
+	flow.FlowState[flowStopReasonKey] = "rejected"
+	flow.NextStep = flowEnd
+	return cm.runFlow(ctx, conversation, def, nil)
+}
+
+// lockFlow returns a conversation running a flow, locked, with its definition
+func (cm *ConversationManager) lockFlow(conversationID string) (*Conversation, *FlowDefinition, error) {
+	conversation, exists := cm.GetConversation(conversationID)
+	if !exists {
+		return nil, nil, fmt.Errorf("conversation not found: %s", conversationID)
+	}
+
+	conversation.mu.Lock()
+	flow := conversation.ConversationFlow
+	if flow.Name == "" || flowFinished(conversation) {
+		conversation.mu.Unlock()
+		return nil, nil, fmt.Errorf("conversation %s is not running a flow", conversationID)
+	}
+	def, err := cm.configManager.GetFlowDefinition(flow.Name)
+	if err != nil {
+		conversation.mu.Unlock()
+		return nil, nil, err
+	}
+	return conversation, def, nil
+}
+
+// awaitingFlowInput returns the flow definition when the conversation's flow is
+// waiting on a user_input step; callers hold conversation.mu
+func (cm *ConversationManager) awaitingFlowInput(conversation *Conversation) (*FlowDefinition, bool) {
+	flow := conversation.ConversationFlow
+	if flow == nil || flow.Name == "" || conversation.Status != ConversationStatusWaiting {
+		return nil, false
+	}
+	def, err := cm.configManager.GetFlowDefinition(flow.Name)
+	if err != nil {
+		return nil, false
+	}
+	if step := def.step(flow.NextStep); step == nil || step.Type != StepTypeUserInput {
+		return nil, false
+	}
+	return def, true
+}
+
+// runFlow executes steps until the flow waits, pauses, ends or, without AutoContinue,
+// after one step. input answers a waiting user_input step. Callers hold conversation.mu.
+func (cm *ConversationManager) runFlow(ctx context.Context, conversation *Conversation, def *FlowDefinition, input *Message) (*ConversationResponse, error) {
+	flow := conversation.ConversationFlow
+	response := &ConversationResponse{
+		ConversationID: conversation.ID,
+		MessageID:      cm.generateMessageID(),
+		Type:           "flow",
+		ModelUsed:      conversation.CurrentModel,
+	}
+
+	for {
+		if flow.NextStep == "" || flow.NextStep == flowEnd {
+			conversation.Status = ConversationStatusCompleted
+			flow.NextStep = ""
+			log.Info().Str("conversation_id", conversation.ID).Str("flow", def.Name).Msg("Conversation flow completed")
+			break
+		}
+		if flow.MaxSteps > 0 && flow.StepsRun >= flow.MaxSteps {
+			conversation.Status = ConversationStatusPaused
+			flow.FlowState[flowStopReasonKey] = "max_steps"
+			log.Warn().Str("conversation_id", conversation.ID).Str("flow", def.Name).Int("steps", flow.StepsRun).Msg("Conversation flow reached its step limit")
+			break
+		}
+
+		stepDef := def.step(flow.NextStep)
+		if stepDef == nil {
+			conversation.Status = ConversationStatusError
+			cm.persistFlow(conversation)
+			return nil, fmt.Errorf("flow %s has no step %s", def.Name, flow.NextStep)
+		}
+
+		if cm.needsConfirmation(conversation, stepDef) {
+			action := flowAction(flow, stepDef.ID)
+			if action == nil || action.Status == actionStatusPending {
+				if action == nil {
+					action = cm.requestConfirmation(conversation, stepDef)
+				}
+				conversation.Status = ConversationStatusWaiting
+				response.Content = action.Description
+				response.Metadata = map[string]interface{}{"pending_action": action.ID}
+				break
+			}
+			action.Status = actionExecuted
+		}
+
+		result, err := cm.executeFlowStep(ctx, conversation, stepDef, input)
+		input = nil
+		if result.wait {
+			conversation.Status = ConversationStatusWaiting
+			response.Content = result.output
+			break
+		}
+
+		flow.Steps = append(flow.Steps, result.step)
+		flow.CurrentStep = len(flow.Steps) - 1
+		flow.StepsRun++
+		conversation.TokensUsed += result.tokens
+		response.TokensUsed += result.tokens
+		if result.model != "" {
+			response.ModelUsed = result.model
+		}
+
+		if err != nil {
+			flow.FlowState["last_error"] = err.Error()
+			if stepDef.OnFailure == "" {
+				conversation.Status = ConversationStatusError
+				cm.persistFlow(conversation)
+				return nil, fmt.Errorf("flow %s step %s failed: %w", def.Name, stepDef.ID, err)
+			}
+			flow.NextStep = stepDef.OnFailure
+		} else {
+			response.Content = result.output
+			flow.FlowState[flowLastOutputKey] = result.output
+			if stepDef.SaveAs != "" {
+				flow.FlowState[stepDef.SaveAs] = result.output
+			}
+			flow.NextStep = result.next
+			if flow.NextStep == "" {
+				flow.NextStep = def.following(stepDef.ID)
+			}
+		}
+		cm.persistFlow(conversation)
+
+		if !flow.AutoContinue && flow.NextStep != "" && flow.NextStep != flowEnd {
+			break
+		}
+	}
+
+	cm.persistFlow(conversation)
+	response.Status = string(conversation.Status)
+	response.Timestamp = time.Now()
+	if response.Metadata == nil {
+		response.Metadata = make(map[string]interface{})
+	}
+	response.Metadata["flow"] = def.Name
+	response.Metadata["next_step"] = flow.NextStep
+	response.Metadata["steps_run"] = flow.StepsRun
+	return response, nil
+}
+
+// executeFlowStep runs one step; a returned error is a step failure the flow may route
+// to on_failure, recorded in the result's step
+func (cm *ConversationManager) executeFlowStep(ctx context.Context, conversation *Conversation, def *FlowStepDefinition, input *Message) (*flowStepResult, error) {
+	state := conversation.ConversationFlow.FlowState
+	description := def.Description
+	if description == "" {
+		description = fmt.Sprintf("Flow step %s", def.ID)
+	}
+	result := &flowStepResult{step: &ConversationStep{
+		ID:          cm.generateStepID(),
+		Type:        def.Type,
+		Description: description,
+		Required:    true,
+		Validation:  def.Validation,
+		Metadata: map[string]interface{}{
+			"flow":      conversation.ConversationFlow.Name,
+			"flow_step": def.ID,
+		},
+	}}
+
+	var err error
+	switch def.Type {
+	case StepTypeUserInput:
+		if input == nil {
+			result.wait = true
+			result.output, err = renderFlowTemplate(def.Prompt, state)
+			return result, err
+		}
+		if err := validateStepValue(def.Validation, input.Content); err != nil {
+			// Ask again rather than failing the flow
+			result.wait = true
+			result.output = fmt.Sprintf("Invalid input: %v", err)
+			return result, nil
+		}
+		result.output = input.Content
+		result.step.UserInput = input.Content
+
+	case StepTypeToolExecution:
+		call := &FunctionCall{Name: def.Tool, Arguments: make(map[string]interface{}, len(def.Arguments))}
+		for key, value := range def.Arguments {
+			if text, ok := value.(string); ok {
+				if value, err = renderFlowTemplate(text, state); err != nil {
+					break
+				}
+			}
+			call.Arguments[key] = value
+		}
+		if err == nil {
+			err = cm.checkFlowTool(conversation, def.Tool)
+		}
+		if err == nil {
+			result.output, err = cm.inferenceManager.callTool(ctx, conversation.SessionID, call)
+		}
+		toolCall := ToolCall{ID: result.step.ID, Type: "function", Function: call, Result: result.output}
+		if err != nil {
+			toolCall.Error = err.Error()
+		}
+		result.step.ToolCalls = []ToolCall{toolCall}
+
+	case StepTypeValidation:
+		value := fmt.Sprint(state[flowLastOutputKey])
+		if def.Input != "" {
+			value, err = renderFlowTemplate(def.Input, state)
+		}
+		if err == nil {
+			err = validateStepValue(def.Validation, value)
+		}
+		result.output = value
+
+	case StepTypeDecision:
+		for _, branch := range def.Branches {
+			var matched string
+			if matched, err = renderFlowTemplate(branch.When, state); err != nil {
+				break
+			}
+			if matched == "true" {
+				result.next = branch.Next
+				break
+			}
+		}
+		if result.next == "" {
+			result.next = def.Next
+		}
+		result.output = result.next
+
+	default: // ai_response, followup, summary
+		prompt := def.Prompt
+		if prompt == "" && def.Type == StepTypeSummary {
+			prompt = defaultSummaryStep
+		}
+		if prompt, err = renderFlowTemplate(prompt, state); err == nil {
+			err = cm.runFlowInference(ctx, conversation, prompt, result)
+		}
+	}
+
+	if result.next == "" && def.Type != StepTypeDecision {
+		result.next = def.Next
+	}
+	result.step.Completed = err == nil
+	result.step.CompletedAt = time.Now()
+	if err != nil {
+		result.step.Metadata["error"] = err.Error()
+	}
+	return result, err
+}
+
+// runFlowInference asks the conversation's model the step prompt with the conversation history
+func (cm *ConversationManager) runFlowInference(ctx context.Context, conversation *Conversation, prompt string, result *flowStepResult) error {
+	message := &Message{Role: "user", Content: prompt, Timestamp: time.Now()}
+	inference, err := cm.inferenceManager.ProcessInference(ctx, &InferenceRequest{
+		ID:          cm.generateRequestID(),
+		UserID:      conversation.UserID,
+		SessionID:   conversation.SessionID,
+		ModelName:   conversation.CurrentModel,
+		RequestType: cm.mapIntentToInferenceType(conversation.Context.Intent),
+		Messages:    cm.buildInferenceMessages(conversation, message),
+		Parameters:  &InferenceParameters{},
+		ToolPolicy:  toolPolicy(conversation.Settings),
+	})
+	if err != nil {
+		return fmt.Errorf("inference failed: %w", err)
+	}
+	result.output = inference.Content
+	result.step.UserInput = prompt
+	result.step.AIResponse = inference.Content
+	result.step.ToolCalls = inference.ToolCalls
+	result.tokens = int64(inference.Usage.TotalTokens)
+	result.model = inference.ModelUsed
+	return nil
+}
+
+// needsConfirmation reports whether a step waits for approval: steps that ask for it,
+// and tool steps when the conversation requires confirmation
+func (cm *ConversationManager) needsConfirmation(conversation *Conversation, def *FlowStepDefinition) bool {
+	if def.RequireConfirmation {
+		return true
+	}
+	settings := conversation.Settings
+	return def.Type == StepTypeToolExecution && settings != nil && settings.RequireConfirmation
+}
+
+// checkFlowTool refuses code execution tools in conversations that do not allow them
+func (cm *ConversationManager) checkFlowTool(conversation *Conversation, name string) error {
+	scope := cm.inferenceManager.registeredToolScope(name)
+	if scope == ToolScopeExecute && (conversation.Settings == nil || !conversation.Settings.AllowCodeExecution) {
+		return fmt.Errorf("conversation does not allow code execution")
+	}
+	return nil
+}
+
+// requestConfirmation records a pending action for a step
+func (cm *ConversationManager) requestConfirmation(conversation *Conversation, def *FlowStepDefinition) *PendingAction {
+	description := fmt.Sprintf("Confirm flow step %s", def.ID)
+	if def.Type == StepTypeToolExecution {
+		description = fmt.Sprintf("Confirm running tool %s", def.Tool)
+	}
+	action := &PendingAction{
+		ID:          cm.generateActionID(),
+		Type:        flowActionType,
+		Description: description,
+		Parameters: map[string]interface{}{
+			"step_id":   def.ID,
+			"tool":      def.Tool,
+			"arguments": def.Arguments,
+		},
+		CreatedAt: time.Now(),
+		Status:    actionStatusPending,
+	}
+	flow := conversation.ConversationFlow
+	flow.PendingActions = append(flow.PendingActions, action)
+	flow.RequiresFollowup = true
+	return action
+}
+
+// persistFlow saves the conversation when a store is set; callers hold conversation.mu
+func (cm *ConversationManager) persistFlow(conversation *Conversation) {
+	cm.mu.RLock()
+	store := cm.flowStore
+	cm.mu.RUnlock()
+	if store == nil {
+		return
+	}
+	if err := store.SaveConversation(conversation); err != nil {
+		log.Error().Err(err).Str("conversation_id", conversation.ID).Msg("Failed to persist conversation flow")
+	}
+}
+
+// flowAction returns the latest unresolved or approved action for a step
+func flowAction(flow *ConversationFlow, stepID string) *PendingAction {
+	for i := len(flow.PendingActions) - 1; i >= 0; i-- {
+		action := flow.PendingActions[i]
+		if action.Type != flowActionType || action.Parameters["step_id"] != stepID {
+			continue
+		}
+		if action.Status == actionStatusPending || action.Status == actionApproved {
+			return action
+		}
+	}
+	return nil
+}
+
+func flowFinished(conversation *Conversation) bool {
+	switch conversation.Status {
+	case ConversationStatusCompleted, ConversationStatusError, ConversationStatusArchived:
+		return true
+	}
+	return false
+}
+
+// step returns the step with the given ID
+func (fd *FlowDefinition) step(id string) *FlowStepDefinition {
+	for i := range fd.Steps {
+		if fd.Steps[i].ID == id {
+			return &fd.Steps[i]
+		}
+	}
+	return nil
+}
+
+// following returns the step after id in definition order, or end
+func (fd *FlowDefinition) following(id string) string {
+	for i := range fd.Steps {
+		if fd.Steps[i].ID == id && i+1 < len(fd.Steps) {
+			return fd.Steps[i+1].ID
+		}
+	}
+	return flowEnd
+}
+
+// renderFlowTemplate renders a step template against the flow state
+func renderFlowTemplate(text string, state map[string]interface{}) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("flow").Funcs(promptFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, state); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// validateStepValue checks a value against a step's validation rules
+func validateStepValue(validation *StepValidation, value string) error {
+	if validation == nil {
+		return nil
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		if validation.Required {
+			return fmt.Errorf("a value is required")
+		}
+		return nil
+	}
+	if validation.MinLength > 0 && len(value) < validation.MinLength {
+		return fmt.Errorf("must be at least %d characters", validation.MinLength)
+	}
+	if validation.MaxLength > 0 && len(value) > validation.MaxLength {
+		return fmt.Errorf("must be at most %d characters", validation.MaxLength)
+	}
+	if validation.Pattern != "" {
+		pattern, err := regexp.Compile(validation.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		if !pattern.MatchString(value) {
+			return fmt.Errorf("must match %s", validation.Pattern)
+		}
+	}
+	if len(validation.AllowedValues) > 0 && !containsString(validation.AllowedValues, value) {
+		return fmt.Errorf("must be one of %s", strings.Join(validation.AllowedValues, ", "))
+	}
+	return nil
+}
+
+// ValidateFlowDefinitions checks flow names, step types, references and templates
+func ValidateFlowDefinitions(flows []FlowDefinition) error {
+	names := make(map[string]bool)
+	for _, flow := range flows {
+		if flow.Name == "" {
+			return fmt.Errorf("flow definition missing name")
+		}
+		if names[flow.Name] {
+			return fmt.Errorf("duplicate flow definition: %s", flow.Name)
+		}
+		names[flow.Name] = true
+		if len(flow.Steps) == 0 {
+			return fmt.Errorf("flow %s has no steps", flow.Name)
+		}
+
+		ids := make(map[string]bool)
+		for _, step := range flow.Steps {
+			if step.ID == "" || step.ID == flowEnd {
+				return fmt.Errorf("flow %s has a step with a missing or reserved id", flow.Name)
+			}
+			if ids[step.ID] {
+				return fmt.Errorf("flow %s has duplicate step: %s", flow.Name, step.ID)
+			}
+			ids[step.ID] = true
+		}
+
+		for _, step := range flow.Steps {
+			if err := validateFlowStep(&flow, &step, ids); err != nil {
+				return fmt.Errorf("flow %s step %s: %w", flow.Name, step.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateFlowStep(flow *FlowDefinition, step *FlowStepDefinition, ids map[string]bool) error {
+	switch step.Type {
+	case StepTypeUserInput, StepTypeAIResponse, StepTypeValidation, StepTypeSummary, StepTypeFollowup:
+	case StepTypeToolExecution:
+		if step.Tool == "" {
+			return fmt.Errorf("tool_execution needs a tool")
+		}
+	case StepTypeDecision:
+		if len(step.Branches) == 0 {
+			return fmt.Errorf("decision needs branches")
+		}
+	default:
+		return fmt.Errorf("unknown step type: %q", step.Type)
+	}
+
+	targets := []string{step.Next, step.OnFailure}
+	templates := []string{step.Prompt, step.Input}
+	for _, branch := range step.Branches {
+		targets = append(targets, branch.Next)
+		templates = append(templates, branch.When)
+	}
+	for _, value := range step.Arguments {
+		if text, ok := value.(string); ok {
+			templates = append(templates, text)
+		}
+	}
+	for _, target := range targets {
+		if target != "" && target != flowEnd && !ids[target] {
+			return fmt.Errorf("unknown step: %s", target)
+		}
+	}
+	for _, text := range templates {
+		if _, err := template.New("flow").Funcs(promptFuncs).Parse(text); err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	}
+
+	if validation := step.Validation; validation != nil {
+		if validation.ValidationFn != "" {
+			return fmt.Errorf("validation_fn is not supported")
+		}
+		if validation.Pattern != "" {
+			if _, err := regexp.Compile(validation.Pattern); err != nil {
+				return fmt.Errorf("invalid pattern: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/flow-engine_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// recordingTools is a tool provider that records the calls it receives
+type recordingTools struct {
+	calls []*FunctionCall
+}
+
+func (rt *recordingTools) ToolDefinitions() []ToolDefinition {
+	return []ToolDefinition{
+		{Type: "function", Function: &FunctionDefinition{Name: "write_file"}, Scope: ToolScopeFiles},
+		{Type: "function", Function: &FunctionDefinition{Name: "read_file"}, Scope: ToolScopeTools},
+		{Type: "function", Function: &FunctionDefinition{Name: codeExecutionToolName}, Scope: ToolScopeExecute},
+	}
+}
+
+func (rt *recordingTools) CallTool(ctx context.Context, sessionID string, call *FunctionCall) (string, error) {
+	rt.calls = append(rt.calls, call)
+	return fmt.Sprintf("wrote %v", call.Arguments["path"]), nil
+}
+
+// memoryFlowStore keeps JSON copies of saved conversations, like the disk manager
+type memoryFlowStore struct {
+	saved map[string][]byte
+}
+
+func (ms *memoryFlowStore) SaveConversation(conversation *Conversation) error {
+	data, err := json.Marshal(conversation)
+	ms.saved[conversation.ID] = data
+	return err
+}
+
+func (ms *memoryFlowStore) LoadConversations() ([]*Conversation, error) {
+	var conversations []*Conversation
+	for _, data := range ms.saved {
+		var conversation Conversation
+		if err := json.Unmarshal(data, &conversation); err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, &conversation)
+	}
+	return conversations, nil
+}
+
+var bugReportFlow = FlowDefinition{
+	Name:         "bug-report",
+	AutoContinue: true,
+	MaxSteps:     10,
+	Steps: []FlowStepDefinition{
+		{ID: "ask", Type: StepTypeUserInput, Prompt: "Which component?", SaveAs: "component",
+			Validation: &StepValidation{Required: true, AllowedValues: []string{"api", "ui"}}},
+		{ID: "draft", Type: StepTypeAIResponse, Prompt: "Write a bug report for the {{.component}} {{.team}} component.", SaveAs: "report"},
+		{ID: "route", Type: StepTypeDecision, Branches: []FlowBranch{{When: `{{eq .component "ui"}}`, Next: "file"}}, Next: "end"},
+		{ID: "file", Type: StepTypeToolExecution, Tool: "write_file", RequireConfirmation: true,
+			Arguments: map[string]interface{}{"path": "reports/{{.component}}.md", "content": "{{.report}}"}},
+	},
+}
+
+// flowFixture is a conversation manager over a fake Ollama with the given flows
+func flowFixture(t *testing.T, flows ...FlowDefinition) (*ConversationManager, *fakeOllama, *recordingTools, *memoryFlowStore) {
+	t.Helper()
+	ollama := newFakeOllama(t, "ollama")
+	cm := schedulerConfig(&LimitsConfig{MaxRequestsPerMinute: 100, MaxTokensPerRequest: 4096, TokenBudgetPerUser: 100000, ResetIntervalHours: 24},
+		ModelConfig{Name: "llama3.2", Specialization: "chat"})
+	cm.configs["configs/flows.yaml"] = &flows
+	mm := NewModelManager(ollama.URL, cm)
+	t.Cleanup(func() { mm.Shutdown(context.Background()) })
+	im := NewInferenceManager(cm, mm, NewTokenManager(cm), nil, nil, ollama.URL)
+	tools := &recordingTools{}
+	im.RegisterToolProvider(tools)
+
+	store := &memoryFlowStore{saved: make(map[string][]byte)}
+	conversations := NewConversationManager(cm, nil, nil, nil, im)
+	conversations.SetFlowStore(store)
+	return conversations, ollama, tools, store
+}
+
+func TestConversationFlowRunsToCompletion(t *testing.T) {
+	conversations, ollama, tools, store := flowFixture(t, bugReportFlow)
+	ctx := context.Background()
+	conversation, err := conversations.StartConversation(ctx, "alice", "s1", nil)
+	if err != nil {
+		t.Fatalf("StartConversation: %v", err)
+	}
+
+	response, err := conversations.StartFlow(ctx, conversation.ID, "bug-report", map[string]interface{}{"team": "frontend"})
+	if err != nil {
+		t.Fatalf("StartFlow: %v", err)
+	}
+	if response.Status != "waiting" || response.Content != "Which component?" {
+		t.Fatalf("start = %+v", response)
+	}
+	if _, err := conversations.StartFlow(ctx, conversation.ID, "bug-report", nil); err == nil {
+		t.Error("second flow started on a busy conversation")
+	}
+
+	// Invalid input is asked again without advancing
+	response, err = conversations.ProcessMessage(ctx, conversation.ID, &Message{Role: "user", Content: "database"})
+	if err != nil || response.Status != "waiting" || !strings.Contains(response.Content, "must be one of api, ui") {
+		t.Fatalf("invalid input = %+v, %v", response, err)
+	}
+
+	ollama.replies = []string{"The button does nothing."}
+	response, err = conversations.ProcessMessage(ctx, conversation.ID, &Message{Role: "user", Content: "ui"})
+	if err != nil {
+		t.Fatalf("ProcessMessage: %v", err)
+	}
+	actionID, _ := response.Metadata["pending_action"].(string)
+	if response.Status != "waiting" || actionID == "" || response.Metadata["next_step"] != "file" {
+		t.Fatalf("before tool = %+v", response)
+	}
+	if len(tools.calls) != 0 {
+		t.Fatal("tool ran before confirmation")
+	}
+
+	// The model saw the rendered prompt as the latest message
+	messages, _ := ollama.bodies[len(ollama.bodies)-1]["messages"].([]interface{})
+	last, _ := messages[len(messages)-1].(map[string]interface{})
+	if last["content"] != "Write a bug report for the ui frontend component." {
+		t.Errorf("prompt sent = %v", last["content"])
+	}
+
+	// The paused flow survives a restart
+	restarted := NewConversationManager(conversations.configManager, nil, nil, nil, conversations.inferenceManager)
+	restarted.SetFlowStore(store)
+	if n, err := restarted.RestoreFlows(); err != nil || n != 1 {
+		t.Fatalf("RestoreFlows = %d, %v", n, err)
+	}
+
+	response, err = restarted.ConfirmFlowAction(ctx, conversation.ID, actionID, true)
+	if err != nil {
+		t.Fatalf("ConfirmFlowAction: %v", err)
+	}
+	if response.Status != "completed" || response.Content != "wrote reports/ui.md" || response.Metadata["steps_run"] != 4 {
+		t.Errorf("completed = %+v", response)
+	}
+	if len(tools.calls) != 1 || tools.calls[0].Arguments["content"] != "The button does nothing." {
+		t.Errorf("tool calls = %+v", tools.calls)
+	}
+	if _, err := restarted.ConfirmFlowAction(ctx, conversation.ID, actionID, true); err == nil {
+		t.Error("action confirmed twice")
+	}
+}
+
+func TestConversationFlowStepLimits(t *testing.T) {
+	flow := FlowDefinition{
+		Name:     "checklist",
+		MaxSteps: 2,
+		Steps: []FlowStepDefinition{
+			{ID: "one", Type: StepTypeValidation, Input: "{{.item}}", Validation: &StepValidation{Pattern: "^[a-z]+$"}, OnFailure: "fix"},
+			{ID: "two", Type: StepTypeValidation, Input: "ok"},
+			{ID: "three", Type: StepTypeValidation, Input: "ok"},
+			{ID: "fix", Type: StepTypeValidation, Input: "fixed", Next: "end"},
+		},
+	}
+	conversations, _, _, _ := flowFixture(t, flow)
+	ctx := context.Background()
+	conversation, _ := conversations.StartConversation(ctx, "alice", "s1", nil)
+
+	// Without auto_continue each call runs one step
+	response, err := conversations.StartFlow(ctx, conversation.ID, "checklist", map[string]interface{}{"item": "abc"})
+	if err != nil || response.Status != "active" || response.Metadata["next_step"] != "two" {
+		t.Fatalf("first step = %+v, %v", response, err)
+	}
+	response, _ = conversations.AdvanceFlow(ctx, conversation.ID)
+	if response.Metadata["next_step"] != "three" {
+		t.Fatalf("second step = %+v", response)
+	}
+	response, _ = conversations.AdvanceFlow(ctx, conversation.ID)
+	if response.Status != "paused" || response.Metadata["steps_run"] != 2 {
+		t.Fatalf("step limit = %+v", response)
+	}
+	// Resuming grants more steps
+	response, _ = conversations.AdvanceFlow(ctx, conversation.ID)
+	if response.Status != "active" || response.Metadata["next_step"] != "fix" {
+		t.Fatalf("resumed = %+v", response)
+	}
+
+	// A failed validation takes the on_failure step
+	conversation, _ = conversations.StartConversation(ctx, "alice", "s2", nil)
+	conversations.StartFlow(ctx, conversation.ID, "checklist", map[string]interface{}{"item": "A1"})
+	if next := conversation.ConversationFlow.NextStep; next != "fix" {
+		t.Errorf("after failed validation next = %q", next)
+	}
+}
+
+func TestValidateFlowDefinitions(t *testing.T) {
+	if err := ValidateFlowDefinitions([]FlowDefinition{bugReportFlow}); err != nil {
+		t.Fatalf("valid flow: %v", err)
+	}
+
+	step := func(s FlowStepDefinition) []FlowDefinition {
+		if s.ID == "" {
+			s.ID = "a"
+		}
+		return []FlowDefinition{{Name: "f", Steps: []FlowStepDefinition{s}}}
+	}
+	for name, tc := range map[string]struct {
+		flows []FlowDefinition
+		want  string
+	}{
+		"duplicate flow": {flows: []FlowDefinition{bugReportFlow, bugReportFlow}, want: "duplicate flow"},
+		"no steps":       {flows: []FlowDefinition{{Name: "f"}}, want: "no steps"},
+		"unknown type":   {flows: step(FlowStepDefinition{Type: "dance"}), want: "unknown step type"},
+		"unknown next":   {flows: step(FlowStepDefinition{Type: StepTypeAIResponse, Next: "b"}), want: "unknown step: b"},
+		"tool missing":   {flows: step(FlowStepDefinition{Type: StepTypeToolExecution}), want: "needs a tool"},
+		"bad template":   {flows: step(FlowStepDefinition{Type: StepTypeAIResponse, Prompt: "{{.x"}), want: "invalid template"},
+		"bad pattern":    {flows: step(FlowStepDefinition{Type: StepTypeValidation, Validation: &StepValidation{Pattern: "("}}), want: "invalid pattern"},
+		"validation fn":  {flows: step(FlowStepDefinition{Type: StepTypeValidation, Validation: &StepValidation{ValidationFn: "x"}}), want: "not supported"},
+		"reserved id":    {flows: step(FlowStepDefinition{ID: "end", Type: StepTypeAIResponse}), want: "reserved id"},
+	} {
+		if err := ValidateFlowDefinitions(tc.flows); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", name, err, tc.want)
+		}
+	}
+}
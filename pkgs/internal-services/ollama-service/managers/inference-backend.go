@@ -0,0 +1,419 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/inference-backend.go
+
+package managers
+
+import (
+	// stdlib
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Runtimes a model can be served by, set with backend in models.yaml
+const (
+	BackendOllama = "ollama" // the Ollama pool; the default
+	BackendOpenAI = "openai" // any OpenAI-compatible server: llama.cpp server, vLLM, LM Studio
+)
+
+// InferenceBackend runs chat requests against one model runtime. Requests and
+// responses use the Ollama shape OCS works in internally; adapters translate.
+type InferenceBackend interface {
+	Name() string
+	Chat(ctx context.Context, req *OllamaRequest) (*OllamaResponse, error)
+	// ChatStream calls onChunk for every partial response; the last one has Done set
+	ChatStream(ctx context.Context, req *OllamaRequest, onChunk func(*OllamaResponse) error) error
+}
+
+// BackendKind returns the runtime serving the model; llama.cpp, vLLM and LM Studio are OpenAI-compatible
+func (mc *ModelConfig) BackendKind() string {
+	switch strings.ToLower(mc.Backend) {
+	case "", BackendOllama:
+		return BackendOllama
+	case BackendOpenAI, "llama.cpp", "llamacpp", "vllm", "lmstudio":
+		return BackendOpenAI
+	default:
+		return mc.Backend
+	}
+}
+
+// servedByOllama reports whether the Ollama pool loads and serves the model
+func (mc *ModelConfig) servedByOllama() bool {
+	return mc == nil || mc.BackendKind() == BackendOllama
+}
+
+// backendFor returns the backend configured for a model
+func (im *InferenceManager) backendFor(modelName string) InferenceBackend {
+	if im.configManager != nil {
+		if config, err := im.configManager.GetModelConfig(modelName); err == nil && config.BackendKind() == BackendOpenAI {
+			backend := NewOpenAIBackend(config, im.client)
+			backend.maxRetries, backend.retryDelay = im.maxRetries, im.retryDelay
+			return backend
+		}
+	}
+	return &ollamaBackend{im: im}
+}
+
+// ollamaBackend sends requests through the Ollama backend pool
+type ollamaBackend struct {
+	im *InferenceManager
+}
+
+func (b *ollamaBackend) Name() string {
+	return BackendOllama
+}
+
+func (b *ollamaBackend) Chat(ctx context.Context, req *OllamaRequest) (*OllamaResponse, error) {
+	return b.im.callOllama(ctx, req)
+}
+
+func (b *ollamaBackend) ChatStream(ctx context.Context, req *OllamaRequest, onChunk func(*OllamaResponse) error) error {
+	return b.im.callOllamaStream(ctx, req, onChunk)
+}
+
+// OpenAIBackend calls an OpenAI-compatible /chat/completions endpoint
+type OpenAIBackend struct {
+	baseURL     string
+	apiKey      string
+	remoteModel string
+	client      *http.Client
+	maxRetries  int
+	retryDelay  time.Duration
+}
+
+// openAIBackendMessage is a chat message in the OpenAI wire format
+type openAIBackendMessage struct {
+	Role       string                  `json:"role"`
+	Content    string                  `json:"content"`
+	ToolCalls  []openAIBackendToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string                  `json:"tool_call_id,omitempty"`
+}
+
+// openAIBackendToolCall is a tool call; arguments travel as a JSON string
+type openAIBackendToolCall struct {
+	Index    *int   `json:"index,omitempty"` // set on streamed deltas
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
+
+// openAIBackendResponse covers both full responses and streamed chunks
+type openAIBackendResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      openAIBackendMessage `json:"message"`
+		Delta        openAIBackendMessage `json:"delta"`
+		FinishReason string               `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIBackendUsage `json:"usage"`
+}
+
+// openAIBackendUsage is the token accounting of a completion
+type openAIBackendUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// NewOpenAIBackend creates an adapter for the model's OpenAI-compatible server
+func NewOpenAIBackend(config *ModelConfig, client *http.Client) *OpenAIBackend {
+	remoteModel := config.RemoteModel
+	if remoteModel == "" {
+		remoteModel = config.Name
+	}
+	return &OpenAIBackend{
+		baseURL:     strings.TrimRight(config.BackendURL, "/"),
+		apiKey:      os.ExpandEnv(config.APIKey),
+		remoteModel: remoteModel,
+		client:      client,
+	}
+}
+
+// Name returns the backend identifier
+func (b *OpenAIBackend) Name() string {
+	return BackendOpenAI
+}
+
+// Chat sends a non-streaming chat completion
+func (b *OpenAIBackend) Chat(ctx context.Context, req *OllamaRequest) (*OllamaResponse, error) {
+	start := time.Now()
+	resp, err := b.post(ctx, b.buildRequest(req, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var completion openAIBackendResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("backend returned no choices")
+	}
+
+	message := completion.Choices[0].Message
+	result := &OllamaResponse{
+		Model:     req.Model,
+		CreatedAt: time.Now(),
+		Message:   &OllamaMessage{Role: "assistant", Content: message.Content},
+		Done:      true,
+	}
+	for _, call := range message.ToolCalls {
+		result.Message.ToolCalls = append(result.Message.ToolCalls, OllamaToolCall{Function: call.function()})
+	}
+	b.finish(result, completion.Usage, start, start)
+	return result, nil
+}
+
+// ChatStream sends a streaming chat completion and reads its server-sent events
+func (b *OpenAIBackend) ChatStream(ctx context.Context, req *OllamaRequest, onChunk func(*OllamaResponse) error) error {
+	start := time.Now()
+	resp, err := b.post(ctx, b.buildRequest(req, true))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var firstToken time.Time
+	var usage *openAIBackendUsage
+	// Tool call fragments arrive spread over deltas, keyed by index
+	var calls []*openAIBackendToolCall
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIBackendResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to decode stream response: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta
+		for _, fragment := range delta.ToolCalls {
+			index := len(calls)
+			if fragment.Index != nil {
+				index = *fragment.Index
+			}
+			for len(calls) <= index {
+				calls = append(calls, &openAIBackendToolCall{})
+			}
+			call := calls[index]
+			if fragment.ID != "" {
+				call.ID = fragment.ID
+			}
+			if fragment.Function.Name != "" {
+				call.Function.Name = fragment.Function.Name
+			}
+			call.Function.Arguments += fragment.Function.Arguments
+		}
+		if delta.Content == "" {
+			continue
+		}
+		if firstToken.IsZero() {
+			firstToken = time.Now()
+		}
+		if err := onChunk(&OllamaResponse{
+			Model:     req.Model,
+			CreatedAt: time.Now(),
+			Message:   &OllamaMessage{Role: "assistant", Content: delta.Content},
+		}); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream response: %w", err)
+	}
+
+	done := &OllamaResponse{
+		Model:     req.Model,
+		CreatedAt: time.Now(),
+		Message:   &OllamaMessage{Role: "assistant"},
+		Done:      true,
+	}
+	for _, call := range calls {
+		done.Message.ToolCalls = append(done.Message.ToolCalls, OllamaToolCall{Function: call.function()})
+	}
+	if firstToken.IsZero() {
+		firstToken = start
+	}
+	b.finish(done, usage, start, firstToken)
+	return onChunk(done)
+}
+
+// buildRequest translates an Ollama-shaped request into a chat completion body
+func (b *OpenAIBackend) buildRequest(req *OllamaRequest, stream bool) map[string]interface{} {
+	body := map[string]interface{}{
+		"model":    b.remoteModel,
+		"messages": openAIMessages(req),
+		"stream":   stream,
+	}
+	if stream {
+		body["stream_options"] = map[string]interface{}{"include_usage": true}
+	}
+	if len(req.Tools) > 0 {
+		body["tools"] = req.Tools
+	}
+	if string(req.Format) == `"json"` {
+		body["response_format"] = map[string]interface{}{"type": "json_object"}
+	} else if len(req.Format) > 0 {
+		body["response_format"] = map[string]interface{}{
+			"type":        "json_schema",
+			"json_schema": map[string]interface{}{"name": "response", "schema": req.Format},
+		}
+	}
+
+	// Standard sampling options are renamed; the rest (top_k, repeat_penalty, min_p...)
+	// keep their Ollama names, which llama.cpp's server shares
+	for key, value := range req.Options {
+		switch key {
+		case "num_predict":
+			body["max_tokens"] = value
+		case "num_ctx", "num_gpu", "num_thread", "keep_alive":
+			// Ollama runtime settings with no OpenAI equivalent
+		default:
+			body[key] = value
+		}
+	}
+	return body
+}
+
+// openAIMessages converts the conversation, pairing tool results with the calls
+// they answer since OpenAI matches them by ID rather than by name
+func openAIMessages(req *OllamaRequest) []openAIBackendMessage {
+	messages := make([]openAIBackendMessage, 0, len(req.Messages)+1)
+	var pending []string
+	for i, msg := range req.Messages {
+		converted := openAIBackendMessage{Role: msg.Role, Content: msg.Content}
+		switch {
+		case msg.Role == "assistant" && len(msg.ToolCalls) > 0:
+			pending = pending[:0]
+			for j, call := range msg.ToolCalls {
+				if call.Function == nil {
+					continue
+				}
+				id := fmt.Sprintf("call_%d_%d", i, j)
+				args, _ := json.Marshal(call.Function.Arguments)
+				tc := openAIBackendToolCall{ID: id, Type: "function"}
+				tc.Function.Name = call.Function.Name
+				tc.Function.Arguments = string(args)
+				converted.ToolCalls = append(converted.ToolCalls, tc)
+				pending = append(pending, id)
+			}
+		case msg.Role == "tool" && len(pending) > 0:
+			converted.ToolCallID = pending[0]
+			pending = pending[1:]
+		}
+		messages = append(messages, converted)
+	}
+	if req.Prompt != "" {
+		messages = append(messages, openAIBackendMessage{Role: "user", Content: req.Prompt})
+	}
+	return messages
+}
+
+// function decodes a tool call's JSON-string arguments
+func (tc *openAIBackendToolCall) function() *FunctionCall {
+	args := make(map[string]interface{})
+	if tc.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+			args = map[string]interface{}{"_raw": tc.Function.Arguments}
+		}
+	}
+	return &FunctionCall{Name: tc.Function.Name, Arguments: args}
+}
+
+// finish fills the token counts and timings the inference loop reads from Ollama responses
+func (b *OpenAIBackend) finish(resp *OllamaResponse, usage *openAIBackendUsage, start, firstToken time.Time) {
+	if usage != nil {
+		resp.PromptEvalCount = usage.PromptTokens
+		resp.EvalCount = usage.CompletionTokens
+	}
+	resp.TotalDuration = int64(time.Since(start))
+	resp.LoadDuration = int64(firstToken.Sub(start))
+	resp.EvalDuration = int64(time.Since(firstToken))
+}
+
+// post sends the body, retrying connection errors and 5xx replies
+func (b *OpenAIBackend) post(ctx context.Context, body map[string]interface{}) (*http.Response, error) {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= b.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(b.retryDelay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", b.baseURL+"/chat/completions", bytes.NewReader(reqBody))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		if b.apiKey != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+b.apiKey)
+		}
+
+		resp, err := b.client.Do(httpReq)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = fmt.Errorf("request failed on %s: %w", b.baseURL, err)
+			continue
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+
+		lastErr = fmt.Errorf("request failed with status %d on %s: %s", resp.StatusCode, b.baseURL, readErrorMessage(resp.Body))
+		resp.Body.Close()
+		if resp.StatusCode < 500 {
+			return nil, lastErr
+		}
+	}
+	return nil, lastErr
+}
+
+// readErrorMessage extracts error.message from an OpenAI-style error body
+func readErrorMessage(body io.Reader) string {
+	raw, _ := io.ReadAll(io.LimitReader(body, 4096))
+	var parsed struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(raw, &parsed) == nil && parsed.Error.Message != "" {
+		return parsed.Error.Message
+	}
+	return strings.TrimSpace(string(raw))
+}
@@ -0,0 +1,239 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/inference-backend_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeOpenAI is an OpenAI-compatible server replying with a canned body or event stream
+type fakeOpenAI struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []map[string]interface{}
+	auth     string
+	status   int
+	reply    string   // JSON body for non-streaming requests
+	events   []string // data lines for streaming requests
+}
+
+func newFakeOpenAI(t *testing.T) *fakeOpenAI {
+	t.Helper()
+	f := &fakeOpenAI{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		f.mu.Lock()
+		f.requests = append(f.requests, body)
+		f.auth = r.Header.Get("Authorization")
+		status, reply, events := f.status, f.reply, f.events
+		f.mu.Unlock()
+
+		if status != 0 {
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"error":{"message":"model is not served here","type":"invalid_request_error"}}`)
+			return
+		}
+		if body["stream"] != true {
+			fmt.Fprint(w, reply)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeOpenAI) lastRequest() map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[len(f.requests)-1]
+}
+
+func (f *fakeOpenAI) backend(t *testing.T) *OpenAIBackend {
+	t.Helper()
+	t.Setenv("OCS_TEST_BACKEND_KEY", "sk-test")
+	backend := NewOpenAIBackend(&ModelConfig{
+		Name:        "qwen-coder",
+		Backend:     "vllm",
+		BackendURL:  f.URL + "/v1/",
+		APIKey:      "${OCS_TEST_BACKEND_KEY}",
+		RemoteModel: "Qwen/Qwen2.5-Coder-7B-Instruct",
+	}, &http.Client{Timeout: 5 * time.Second})
+	backend.maxRetries, backend.retryDelay = 2, time.Millisecond
+	return backend
+}
+
+func TestOpenAIBackendChat(t *testing.T) {
+	f := newFakeOpenAI(t)
+	f.reply = `{"model":"Qwen/Qwen2.5-Coder-7B-Instruct","choices":[{"message":{"role":"assistant","content":"",
+		"tool_calls":[{"id":"x1","type":"function","function":{"name":"read_file","arguments":"{\"path\":\"main.go\"}"}}]},
+		"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":42,"completion_tokens":9}}`
+
+	resp, err := f.backend(t).Chat(context.Background(), &OllamaRequest{
+		Model: "qwen-coder",
+		Messages: []OllamaMessage{
+			{Role: "user", Content: "what is in main.go?"},
+			{Role: "assistant", ToolCalls: []OllamaToolCall{{Function: &FunctionCall{Name: "list_files", Arguments: map[string]interface{}{"dir": "."}}}}},
+			{Role: "tool", Content: "main.go", ToolName: "list_files"},
+		},
+		Format:  json.RawMessage(`"json"`),
+		Options: map[string]interface{}{"num_predict": 256, "temperature": 0.1, "top_k": 20, "num_ctx": 8192},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	if f.auth != "Bearer sk-test" {
+		t.Errorf("Authorization = %q", f.auth)
+	}
+	body := f.lastRequest()
+	if body["model"] != "Qwen/Qwen2.5-Coder-7B-Instruct" || body["max_tokens"] != float64(256) || body["top_k"] != float64(20) {
+		t.Errorf("request = %v", body)
+	}
+	if _, ok := body["num_ctx"]; ok {
+		t.Errorf("Ollama-only option num_ctx was forwarded")
+	}
+	if format, _ := body["response_format"].(map[string]interface{}); format["type"] != "json_object" {
+		t.Errorf("response_format = %v", body["response_format"])
+	}
+
+	// Tool results are paired with the call they answer
+	messages := body["messages"].([]interface{})
+	call := messages[1].(map[string]interface{})["tool_calls"].([]interface{})[0].(map[string]interface{})
+	result := messages[2].(map[string]interface{})
+	if result["tool_call_id"] != call["id"] || call["function"].(map[string]interface{})["arguments"] != `{"dir":"."}` {
+		t.Errorf("tool messages = %v", messages[1:])
+	}
+
+	if resp.PromptEvalCount != 42 || resp.EvalCount != 9 || !resp.Done || resp.TotalDuration <= 0 {
+		t.Errorf("response = %+v", resp)
+	}
+	calls := resp.Message.ToolCalls
+	if len(calls) != 1 || calls[0].Function.Name != "read_file" || calls[0].Function.Arguments["path"] != "main.go" {
+		t.Errorf("tool calls = %+v", calls)
+	}
+}
+
+func TestOpenAIBackendStream(t *testing.T) {
+	f := newFakeOpenAI(t)
+	f.events = []string{
+		`{"choices":[{"delta":{"role":"assistant","content":"Hel"}}]}`,
+		`{"choices":[{"delta":{"content":"lo"}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"c1","function":{"name":"search","arguments":"{\"q\":"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"go\"}"}}]}}]}`,
+		`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":5,"completion_tokens":4}}`,
+	}
+
+	var chunks []*OllamaResponse
+	err := f.backend(t).ChatStream(context.Background(), &OllamaRequest{
+		Model:    "qwen-coder",
+		Messages: []OllamaMessage{{Role: "user", Content: "hi"}},
+		Stream:   true,
+	}, func(chunk *OllamaResponse) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+
+	if options, _ := f.lastRequest()["stream_options"].(map[string]interface{}); options["include_usage"] != true {
+		t.Errorf("stream_options = %v", f.lastRequest()["stream_options"])
+	}
+	var text strings.Builder
+	for _, chunk := range chunks[:len(chunks)-1] {
+		if chunk.Done {
+			t.Errorf("intermediate chunk marked done: %+v", chunk)
+		}
+		text.WriteString(chunk.Message.Content)
+	}
+	if text.String() != "Hello" {
+		t.Errorf("streamed text = %q", text.String())
+	}
+
+	done := chunks[len(chunks)-1]
+	if !done.Done || done.PromptEvalCount != 5 || done.EvalCount != 4 {
+		t.Errorf("final chunk = %+v", done)
+	}
+	if calls := done.Message.ToolCalls; len(calls) != 1 || calls[0].Function.Arguments["q"] != "go" {
+		t.Errorf("streamed tool calls = %+v", calls)
+	}
+}
+
+func TestOpenAIBackendErrors(t *testing.T) {
+	f := newFakeOpenAI(t)
+	backend := f.backend(t)
+	req := &OllamaRequest{Model: "qwen-coder", Messages: []OllamaMessage{{Role: "user", Content: "hi"}}}
+
+	f.status = http.StatusNotFound
+	_, err := backend.Chat(context.Background(), req)
+	if err == nil || !strings.Contains(err.Error(), "model is not served here") {
+		t.Errorf("404 = %v, want the server's message", err)
+	}
+	if len(f.requests) != 1 {
+		t.Errorf("a 404 was retried %d times", len(f.requests)-1)
+	}
+
+	f.status = http.StatusBadGateway
+	if _, err := backend.Chat(context.Background(), req); err == nil {
+		t.Error("502 succeeded")
+	}
+	if len(f.requests) != 1+backend.maxRetries+1 {
+		t.Errorf("requests after a 502 = %d, want one try and %d retries", len(f.requests)-1, backend.maxRetries)
+	}
+}
+
+func TestBackendForFollowsModelConfig(t *testing.T) {
+	im := &InferenceManager{
+		configManager: schedulerConfig(&LimitsConfig{},
+			ModelConfig{Name: "llama3.2"},
+			ModelConfig{Name: "qwen-coder", Backend: "llama.cpp", BackendURL: "http://gpu-2:8080/v1"},
+		),
+		client: http.DefaultClient,
+	}
+
+	if backend := im.backendFor("llama3.2"); backend.Name() != BackendOllama {
+		t.Errorf("backend for an Ollama model = %s", backend.Name())
+	}
+	if backend := im.backendFor("unknown"); backend.Name() != BackendOllama {
+		t.Errorf("backend for an unconfigured model = %s", backend.Name())
+	}
+	backend, ok := im.backendFor("qwen-coder").(*OpenAIBackend)
+	if !ok || backend.baseURL != "http://gpu-2:8080/v1" || backend.remoteModel != "qwen-coder" {
+		t.Errorf("backend for a llama.cpp model = %+v", backend)
+	}
+
+	// External models are registered, not loaded through Ollama
+	mm := NewModelManager("http://unused.invalid", im.configManager)
+	t.Cleanup(func() { mm.Shutdown(context.Background()) })
+	if err := mm.LoadModel(context.Background(), "qwen-coder"); err != nil {
+		t.Fatalf("LoadModel on an external backend: %v", err)
+	}
+	if info, _ := mm.GetModelInfo("qwen-coder"); info.Status != "loaded" || info.Backends[0] != "http://gpu-2:8080/v1" {
+		t.Errorf("external model info = %+v", info)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -29,11 +30,18 @@ type InferenceManager struct {
 	memoryManager    *MemoryManager
 	sessionManager   *SessionManager
 	activeInferences map[string]*InferenceRequest
-	ollamaBaseURL    string
+	backends         *BackendPool
 	client           *http.Client
 	requestTimeout   time.Duration
 	maxRetries       int
 	retryDelay       time.Duration
+	toolProviders    map[string]ToolProvider
+	toolDefinitions  map[string]ToolDefinition
+	maxToolIters     int
+	scheduler        *InferenceScheduler
+	cache            *ResponseCache
+	approvals        *ToolApprovals
+	router           *RoutingEngine
 	shutdown         chan struct{}
 }
 
@@ -44,6 +52,7 @@ type InferenceRequest struct {
 	SessionID     string                 `json:"session_id,omitempty"`
 	ModelName     string                 `json:"model_name"`
 	RequestType   InferenceType          `json:"request_type"`
+	Priority      InferencePriority      `json:"priority,omitempty"` // interactive when empty
 	Messages      []Message              `json:"messages"`
 	Parameters    *InferenceParameters   `json:"parameters"`
 	Status        InferenceStatus        `json:"status"`
@@ -56,6 +65,9 @@ type InferenceRequest struct {
 	Context       context.Context        `json:"-"`
 	CancelFunc    context.CancelFunc     `json:"-"`
 	Metadata      map[string]interface{} `json:"metadata"`
+	ToolPolicy    *ToolPolicy            `json:"-"` // conversation settings for server-side tools
+
+	slot *modelSlot // the scheduler slot the request holds while it runs
 }
 
 // InferenceParameters holds model parameters for inference
@@ -71,8 +83,12 @@ type InferenceParameters struct {
 	UseMemory       bool                   `json:"use_memory"`
 	MemoryDepth     int                    `json:"memory_depth,omitempty"`
 	Tools           []string               `json:"tools,omitempty"`
+	ToolDefinitions []ToolDefinition       `json:"tool_definitions,omitempty"`
 	SystemPrompt    string                 `json:"system_prompt,omitempty"`
 	ContextOptimize bool                   `json:"context_optimize"`
+	UseCache        bool                   `json:"use_cache"`                 // serve repeats from the response cache when enabled
+	ResponseSchema  map[string]interface{} `json:"response_schema,omitempty"` // JSON Schema the reply must satisfy
+	SchemaRetries   int                    `json:"schema_retries,omitempty"`  // corrections requested before giving up
 	CustomOptions   map[string]interface{} `json:"custom_options,omitempty"`
 }
 
@@ -86,6 +102,7 @@ type InferenceResult struct {
 	FinishReason     string                 `json:"finish_reason"`
 	Usage            *TokenUsage            `json:"usage"`
 	ToolCalls        []ToolCall             `json:"tool_calls,omitempty"`
+	Steps            []*ConversationStep    `json:"steps,omitempty"` // server-side tool executions
 	Metadata         map[string]interface{} `json:"metadata,omitempty"`
 	PerformanceStats *PerformanceStats      `json:"performance_stats,omitempty"`
 }
@@ -142,6 +159,20 @@ type FunctionCall struct {
 	Arguments map[string]interface{} `json:"arguments"`
 }
 
+// ToolDefinition describes a function the model may call
+type ToolDefinition struct {
+	Type     string              `json:"type"`
+	Function *FunctionDefinition `json:"function"`
+	Scope    string              `json:"-"` // permission scope of a registered tool
+}
+
+// FunctionDefinition holds a callable function's name and JSON schema
+type FunctionDefinition struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
 // PerformanceStats tracks inference performance
 type PerformanceStats struct {
 	QueueTime       time.Duration `json:"queue_time"`
@@ -158,7 +189,8 @@ type OllamaRequest struct {
 	Messages []OllamaMessage        `json:"messages,omitempty"`
 	Prompt   string                 `json:"prompt,omitempty"`
 	Stream   bool                   `json:"stream"`
-	Format   string                 `json:"format,omitempty"`
+	Format   json.RawMessage        `json:"format,omitempty"` // "json" or a JSON Schema
+	Tools    []ToolDefinition       `json:"tools,omitempty"`
 	Options  map[string]interface{} `json:"options,omitempty"`
 	Template string                 `json:"template,omitempty"`
 	Context  []int                  `json:"context,omitempty"`
@@ -167,8 +199,15 @@ type OllamaRequest struct {
 
 // OllamaMessage represents message format for Ollama
 type OllamaMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []OllamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"` // set on tool-role messages
+}
+
+// OllamaToolCall represents a tool call in Ollama's chat format
+type OllamaToolCall struct {
+	Function *FunctionCall `json:"function"`
 }
 
 // OllamaResponse represents response from Ollama
@@ -187,7 +226,8 @@ type OllamaResponse struct {
 	EvalDuration       int64          `json:"eval_duration,omitempty"`
 }
 
-// NewInferenceManager creates a new inference manager
+// NewInferenceManager creates a new inference manager. Requests are routed through
+// the model manager's backend pool; ollamaBaseURL seeds the pool when it is empty.
 func NewInferenceManager(
 	configManager *ConfigManager,
 	modelManager *ModelManager,
@@ -196,20 +236,53 @@ func NewInferenceManager(
 	sessionManager *SessionManager,
 	ollamaBaseURL string,
 ) *InferenceManager {
-	return &InferenceManager{
+	im := &InferenceManager{
 		configManager:    configManager,
 		modelManager:     modelManager,
 		tokenManager:     tokenManager,
 		memoryManager:    memoryManager,
 		sessionManager:   sessionManager,
 		activeInferences: make(map[string]*InferenceRequest),
-		ollamaBaseURL:    ollamaBaseURL,
+		backends:         modelManager.Backends(),
 		client:           &http.Client{Timeout: 5 * time.Minute},
 		requestTimeout:   5 * time.Minute,
 		maxRetries:       3,
 		retryDelay:       time.Second,
+		toolProviders:    make(map[string]ToolProvider),
+		toolDefinitions:  make(map[string]ToolDefinition),
+		maxToolIters:     defaultMaxToolIterations,
+		scheduler:        NewInferenceScheduler(configManager),
+		router:           NewRoutingEngine(configManager, modelManager, tokenManager),
 		shutdown:         make(chan struct{}),
 	}
+
+	if len(im.backends.Backends()) == 0 && ollamaBaseURL != "" {
+		im.backends.Add(OllamaBackendConfig{Name: "default", URL: ollamaBaseURL})
+	}
+
+	im.cache = NewResponseCache(func() ResponseCacheConfig {
+		if featureConfig, err := configManager.GetFeatureConfig(); err == nil {
+			return featureConfig.ResponseCache
+		}
+		return ResponseCacheConfig{}
+	}, func() EmbeddingProvider {
+		if memoryManager == nil {
+			return nil
+		}
+		return memoryManager.GetEmbeddingProvider()
+	})
+
+	im.approvals = NewToolApprovals(func() ToolApprovalConfig {
+		if featureConfig, err := configManager.GetFeatureConfig(); err == nil {
+			return featureConfig.ToolApproval
+		}
+		return ToolApprovalConfig{}
+	})
+
+	// Context trimming summarizes evicted turns through the summary model
+	tokenManager.SetSummarizer(im)
+
+	return im
 }
 
 // ProcessInference handles a complete inference request
@@ -247,15 +320,39 @@ func (im *InferenceManager) ProcessInference(ctx context.Context, req *Inference
 
 	// Optimize context if requested
 	if req.Parameters.ContextOptimize {
-		if err := im.optimizeContext(req); err != nil {
+		if err := im.optimizeContext(ctx, req); err != nil {
 			log.Warn().Err(err).Msg("Failed to optimize context, using original")
 		}
 	}
 
-	// Register active inference
+	// Serve repeated requests from the response cache
+	cacheable := im.cacheable(req)
+	if cacheable {
+		if hit, ok := im.cache.Lookup(ctx, req); ok {
+			return im.serveCached(ctx, req, hit), nil
+		}
+		im.modelManager.RecordCacheLookup(req.ModelName, "", 0)
+	}
+
+	// Register active inference; CancelInference also withdraws it from the queue
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req.Context = ctx
+	req.CancelFunc = cancel
 	im.registerInference(req)
 	defer im.unregisterInference(req.ID)
 
+	// Wait for a slot on the model
+	slot, queueTime, err := im.scheduler.acquireSlot(ctx, req.ModelName, req.UserID, req.Priority)
+	if err != nil {
+		req.Status = StatusFailed
+		req.Error = err.Error()
+		req.EndTime = time.Now()
+		return nil, err
+	}
+	req.slot = slot
+	defer slot.Release()
+
 	// Execute inference
 	result, err := im.executeInference(ctx, req)
 	if err != nil {
@@ -265,8 +362,14 @@ func (im *InferenceManager) ProcessInference(ctx context.Context, req *Inference
 		return nil, err
 	}
 
+	result.PerformanceStats.QueueTime = queueTime
+
 	// Record usage statistics
-	im.recordInferenceStats(req, result)
+	im.recordInferenceStats(req, result, nil)
+
+	if cacheable && result.FinishReason != "tool_calls" {
+		im.cache.Store(ctx, req, result)
+	}
 
 	// Store important interactions in memory
 	if req.Parameters.UseMemory {
@@ -313,20 +416,48 @@ func (im *InferenceManager) ProcessStreamingInference(ctx context.Context, req *
 		im.enhanceWithMemory(ctx, req)
 	}
 	if req.Parameters.ContextOptimize {
-		im.optimizeContext(req)
+		im.optimizeContext(ctx, req)
 	}
 
-	// Register and execute
+	// Register and execute; CancelInference aborts the upstream request
+	ctx, cancel := context.WithCancel(ctx)
+	req.Context = ctx
+	req.CancelFunc = cancel
 	im.registerInference(req)
 
+	// Queue before returning so a full queue is reported to the caller directly
+	slot, queueTime, err := im.scheduler.acquireSlot(ctx, req.ModelName, req.UserID, req.Priority)
+	if err != nil {
+		cancel()
+		im.unregisterInference(req.ID)
+		return nil, err
+	}
+	req.slot = slot
+
 	// Start streaming in background
 	go func() {
 		defer func() {
+			slot.Release()
+			cancel()
 			close(req.StreamChannel)
 			im.unregisterInference(req.ID)
 		}()
 
-		if err := im.executeStreamingInference(ctx, req); err != nil {
+		startTime := time.Now()
+		usage := &TokenUsage{}
+		err := im.executeStreamingInference(ctx, req, queueTime, usage)
+
+		// Tokens spent before a failure still count; a cancelled stream is not a model error.
+		// The done chunk shares usage with the consumer, so the totals are taken from a copy.
+		spent := *usage
+		spent.TotalTokens = spent.InputTokens + spent.OutputTokens
+		recordErr := err
+		if errors.Is(err, context.Canceled) {
+			recordErr = nil
+		}
+		im.recordInferenceStats(req, &InferenceResult{Usage: &spent, Duration: time.Since(startTime)}, recordErr)
+
+		if err != nil {
 			req.StreamChannel <- &StreamChunk{
 				Error: err.Error(),
 				Done:  true,
@@ -348,37 +479,94 @@ func (im *InferenceManager) executeInference(ctx context.Context, req *Inference
 		return nil, fmt.Errorf("failed to build request: %w", err)
 	}
 
-	// Execute request
-	ollamaResp, err := im.callOllama(ctx, ollamaReq)
-	if err != nil {
-		return nil, fmt.Errorf("ollama request failed: %w", err)
+	// Advertise registered tools alongside any the caller defined
+	tools := im.resolveTools(req)
+	ollamaReq.Tools = append(ollamaReq.Tools, tools...)
+
+	// Execute request, running server-side tool calls until the model answers
+	backend := im.backendFor(req.ModelName)
+	var ollamaResp *OllamaResponse
+	var toolCalls []ToolCall
+	var steps []*ConversationStep
+	usage := &TokenUsage{}
+	for iteration := 0; ; iteration++ {
+		ollamaResp, err = backend.Chat(ctx, ollamaReq)
+		if err != nil {
+			return nil, fmt.Errorf("%s request failed: %w", backend.Name(), err)
+		}
+		im.calibrateTokens(ollamaReq, ollamaResp)
+		usage.InputTokens += ollamaResp.PromptEvalCount
+		usage.OutputTokens += ollamaResp.EvalCount
+
+		toolCalls = im.extractToolCalls(ollamaResp)
+		if len(toolCalls) == 0 || len(tools) == 0 || !canExecuteToolCalls(toolCalls, tools) {
+			break
+		}
+		if iteration >= im.maxToolIters {
+			log.Warn().Str("request_id", req.ID).Int("iterations", iteration).Msg("Tool-calling iteration limit reached")
+			break
+		}
+		executed, aborted, err := im.executeToolCalls(ctx, req, ollamaReq, ollamaResp, toolCalls)
+		steps = append(steps, executed...)
+		if err != nil {
+			return nil, err
+		}
+		if aborted {
+			// A refused call ends tool use; the model answers with what it has
+			tools, ollamaReq.Tools = nil, nil
+		}
+	}
+
+	// Hold the answer to the response schema, asking for corrections when it misses
+	var structured interface{}
+	schemaAttempts := 0
+	if req.Parameters.ResponseSchema != nil && len(toolCalls) == 0 {
+		ollamaResp, structured, schemaAttempts, err = im.enforceSchema(ctx, req, backend, ollamaReq, ollamaResp, usage)
+		if err != nil {
+			return nil, err
+		}
 	}
+	usage.TotalTokens = usage.InputTokens + usage.OutputTokens
 
 	// Build result
+	finishReason := "stop"
+	if len(toolCalls) > 0 {
+		finishReason = "tool_calls"
+	} else if req.Parameters.MaxTokens > 0 && ollamaResp.EvalCount >= req.Parameters.MaxTokens {
+		finishReason = "length"
+	}
+
 	result := &InferenceResult{
 		Content:         im.extractContent(ollamaResp),
-		TokensGenerated: ollamaResp.EvalCount,
-		TokensInput:     ollamaResp.PromptEvalCount,
+		TokensGenerated: usage.OutputTokens,
+		TokensInput:     usage.InputTokens,
 		Duration:        time.Since(startTime),
 		ModelUsed:       req.ModelName,
-		FinishReason:    "stop", // TODO: Determine actual finish reason
-		Usage: &TokenUsage{
-			InputTokens:  ollamaResp.PromptEvalCount,
-			OutputTokens: ollamaResp.EvalCount,
-			TotalTokens:  ollamaResp.PromptEvalCount + ollamaResp.EvalCount,
-		},
+		FinishReason:    finishReason,
+		ToolCalls:       toolCalls,
+		Steps:           steps,
+		Usage:           usage,
 		PerformanceStats: &PerformanceStats{
 			ProcessingTime:  time.Duration(ollamaResp.TotalDuration),
 			FirstTokenTime:  time.Duration(ollamaResp.LoadDuration),
 			TokensPerSecond: im.calculateTokensPerSecond(ollamaResp),
 		},
 	}
+	if schemaAttempts > 0 {
+		result.Metadata = map[string]interface{}{
+			"structured_output": structured,
+			"schema_attempts":   schemaAttempts,
+		}
+	}
 
 	return result, nil
 }
 
-// executeStreamingInference performs streaming inference
-func (im *InferenceManager) executeStreamingInference(ctx context.Context, req *InferenceRequest) error {
+// executeStreamingInference performs streaming inference. Server-side tool calls run
+// between rounds as in executeInference: each round's content is streamed, executed
+// calls are reported as steps, and only the last chunk is marked done. Tokens are
+// added to usage as rounds finish, so it holds what was spent even when the stream fails.
+func (im *InferenceManager) executeStreamingInference(ctx context.Context, req *InferenceRequest, queueTime time.Duration, usage *TokenUsage) error {
 	req.Status = StatusStreaming
 
 	// Build Ollama request
@@ -387,8 +575,95 @@ func (im *InferenceManager) executeStreamingInference(ctx context.Context, req *
 		return err
 	}
 
-	// Make streaming request
-	return im.callOllamaStream(ctx, ollamaReq, req.StreamChannel)
+	// Advertise registered tools alongside any the caller defined
+	tools := im.resolveTools(req)
+	ollamaReq.Tools = append(ollamaReq.Tools, tools...)
+
+	send := func(chunk *StreamChunk) error {
+		select {
+		case req.StreamChannel <- chunk:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// Make streaming requests, converting backend chunks as they arrive
+	backend := im.backendFor(req.ModelName)
+	totalTokens := 0
+	for iteration := 0; ; iteration++ {
+		var content strings.Builder
+		var toolCalls []ToolCall
+		var final *OllamaResponse
+		err := backend.ChatStream(ctx, ollamaReq, func(ollamaResp *OllamaResponse) error {
+			// Tool calls are held back until the round ends and it is known who runs them
+			toolCalls = append(toolCalls, im.extractToolCalls(ollamaResp)...)
+			if ollamaResp.Done {
+				im.calibrateTokens(ollamaReq, ollamaResp)
+				usage.InputTokens += ollamaResp.PromptEvalCount
+				usage.OutputTokens += ollamaResp.EvalCount
+				final = ollamaResp
+			}
+
+			text := im.extractContent(ollamaResp)
+			if text == "" {
+				return nil
+			}
+			content.WriteString(text)
+			totalTokens += len(strings.Fields(text))
+			return send(&StreamChunk{Content: text, TokenCount: totalTokens})
+		})
+		if err != nil {
+			return err
+		}
+		if final == nil {
+			return fmt.Errorf("%s stream ended before completion", backend.Name())
+		}
+
+		if len(toolCalls) > 0 && len(tools) > 0 && canExecuteToolCalls(toolCalls, tools) {
+			if iteration < im.maxToolIters {
+				resp := &OllamaResponse{Message: &OllamaMessage{Role: "assistant", Content: content.String()}}
+				steps, aborted, err := im.executeToolCalls(ctx, req, ollamaReq, resp, toolCalls)
+				if err != nil {
+					return err
+				}
+				if aborted {
+					// A refused call ends tool use; the model answers with what it has
+					tools, ollamaReq.Tools = nil, nil
+				}
+				if err := send(&StreamChunk{TokenCount: totalTokens, Steps: steps}); err != nil {
+					return err
+				}
+				continue
+			}
+			log.Warn().Str("request_id", req.ID).Int("iterations", iteration).Msg("Tool-calling iteration limit reached")
+		}
+
+		// Streamed text cannot be taken back, so a reply that misses the response schema
+		// fails the stream instead of being corrected
+		var structured interface{}
+		if req.Parameters.ResponseSchema != nil && len(toolCalls) == 0 {
+			structured, err = parseStructuredOutput(content.String(), req.Parameters.ResponseSchema)
+			if err != nil {
+				return &StructuredOutputError{Attempts: 1, Content: content.String(), Err: err}
+			}
+		}
+
+		usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+		return send(&StreamChunk{
+			Done:             true,
+			TokenCount:       totalTokens,
+			ToolCalls:        toolCalls,
+			StructuredOutput: structured,
+			Usage:            usage,
+			PerformanceStats: &PerformanceStats{
+				QueueTime:       queueTime,
+				ProcessingTime:  time.Duration(final.TotalDuration),
+				FirstTokenTime:  time.Duration(final.LoadDuration),
+				TokensPerSecond: im.calculateTokensPerSecond(final),
+			},
+		})
+	}
 }
 
 // buildOllamaRequest converts our request to Ollama format
@@ -407,8 +682,23 @@ func (im *InferenceManager) buildOllamaRequest(req *InferenceRequest) (*OllamaRe
 				Role:    msg.Role,
 				Content: msg.Content,
 			}
+			if name, ok := msg.Metadata["tool_name"].(string); ok {
+				ollamaReq.Messages[i].ToolName = name
+			}
+			if calls, ok := msg.Metadata["tool_calls"].([]ToolCall); ok {
+				for _, call := range calls {
+					ollamaReq.Messages[i].ToolCalls = append(ollamaReq.Messages[i].ToolCalls, OllamaToolCall{Function: call.Function})
+				}
+			}
 		}
 	}
+	ollamaReq.Tools = req.Parameters.ToolDefinitions
+
+	format, err := schemaFormat(req.Parameters.ResponseSchema)
+	if err != nil {
+		return nil, err
+	}
+	ollamaReq.Format = format
 
 	// Set parameters
 	if req.Parameters.Temperature > 0 {
@@ -450,23 +740,13 @@ func (im *InferenceManager) callOllama(ctx context.Context, req *OllamaRequest)
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", im.ollamaBaseURL+endpoint, bytes.NewBuffer(reqBody))
+	resp, done, err := im.postOllama(ctx, req.Model, endpoint, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := im.client.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
+	defer done()
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed with status %d", resp.StatusCode)
-	}
-
 	var ollamaResp OllamaResponse
 	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
@@ -475,8 +755,8 @@ func (im *InferenceManager) callOllama(ctx context.Context, req *OllamaRequest)
 	return &ollamaResp, nil
 }
 
-// callOllamaStream makes a streaming call to Ollama
-func (im *InferenceManager) callOllamaStream(ctx context.Context, req *OllamaRequest, streamChan chan<- *StreamChunk) error {
+// callOllamaStream makes a streaming call to Ollama, passing each decoded chunk to onChunk
+func (im *InferenceManager) callOllamaStream(ctx context.Context, req *OllamaRequest, onChunk func(*OllamaResponse) error) error {
 	endpoint := "/api/chat"
 	if req.Prompt != "" {
 		endpoint = "/api/generate"
@@ -487,26 +767,16 @@ func (im *InferenceManager) callOllamaStream(ctx context.Context, req *OllamaReq
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", im.ollamaBaseURL+endpoint, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := im.client.Do(httpReq)
+	// Failover only happens before the first chunk; a stream cut mid-way is an error
+	resp, done, err := im.postOllama(ctx, req.Model, endpoint, reqBody)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return err
 	}
+	defer done()
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("request failed with status %d", resp.StatusCode)
-	}
-
 	// Stream responses
 	decoder := json.NewDecoder(resp.Body)
-	totalTokens := 0
 
 	for {
 		var ollamaResp OllamaResponse
@@ -517,19 +787,8 @@ func (im *InferenceManager) callOllamaStream(ctx context.Context, req *OllamaReq
 			return fmt.Errorf("failed to decode stream response: %w", err)
 		}
 
-		content := im.extractContent(&ollamaResp)
-		totalTokens += len(strings.Fields(content))
-
-		chunk := &StreamChunk{
-			Content:    content,
-			Done:       ollamaResp.Done,
-			TokenCount: totalTokens,
-		}
-
-		select {
-		case streamChan <- chunk:
-		case <-ctx.Done():
-			return ctx.Err()
+		if err := onChunk(&ollamaResp); err != nil {
+			return err
 		}
 
 		if ollamaResp.Done {
@@ -540,6 +799,114 @@ func (im *InferenceManager) callOllamaStream(ctx context.Context, req *OllamaReq
 	return nil
 }
 
+// postOllama sends a request to the best backend for the model, failing over to the
+// next candidate on connection errors and 5xx replies. Each backend is tried once
+// before any is retried, and retries wait retryDelay. The caller closes the body and
+// calls done once it has read the response.
+func (im *InferenceManager) postOllama(ctx context.Context, modelName, endpoint string, reqBody []byte) (*http.Response, func(), error) {
+	candidates := im.backends.Candidates(modelName)
+	if len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("request failed: no Ollama backends configured")
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= im.maxRetries; attempt++ {
+		backend := candidates[attempt%len(candidates)]
+		if attempt >= len(candidates) {
+			select {
+			case <-time.After(im.retryDelay):
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			}
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", backend.URL+endpoint, bytes.NewReader(reqBody))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+
+		done := backend.begin()
+		resp, err := im.client.Do(httpReq)
+		if err != nil {
+			done()
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			backend.MarkFailed(err)
+			lastErr = fmt.Errorf("request failed on %s: %w", backend.Name, err)
+			log.Warn().Err(err).Str("backend", backend.Name).Str("model", modelName).Msg("Ollama backend unreachable, failing over")
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			done()
+			lastErr = fmt.Errorf("request failed with status %d on %s", resp.StatusCode, backend.Name)
+			if resp.StatusCode < 500 {
+				// The request itself is bad; another backend will reject it too
+				return nil, nil, lastErr
+			}
+			log.Warn().Int("status", resp.StatusCode).Str("backend", backend.Name).Str("model", modelName).Msg("Ollama backend error, failing over")
+			continue
+		}
+
+		backend.MarkLoaded(modelName)
+		return resp, done, nil
+	}
+
+	return nil, nil, lastErr
+}
+
+// cacheable reports whether a request may use the response cache. Requests that
+// can run server-side tools are never cached since the tools have side effects.
+func (im *InferenceManager) cacheable(req *InferenceRequest) bool {
+	if !req.Parameters.UseCache || im.cache == nil || !im.cache.Enabled() {
+		return false
+	}
+	return len(im.resolveTools(req)) == 0
+}
+
+// serveCached completes a request with a cached result. The tokens are reported
+// as cached and are not charged to the user's budget.
+func (im *InferenceManager) serveCached(ctx context.Context, req *InferenceRequest, hit *CacheHit) *InferenceResult {
+	result := *hit.Result
+	usage := TokenUsage{}
+	if result.Usage != nil {
+		usage = *result.Usage
+	}
+	usage.CachedTokens = usage.TotalTokens
+	result.Usage = &usage
+	result.Duration = 0
+	result.PerformanceStats = &PerformanceStats{}
+
+	result.Metadata = make(map[string]interface{}, len(hit.Result.Metadata)+3)
+	for k, v := range hit.Result.Metadata {
+		result.Metadata[k] = v
+	}
+	result.Metadata["cache"] = hit.Layer
+	result.Metadata["cache_similarity"] = hit.Similarity
+	result.Metadata["cache_age_seconds"] = int(hit.Age.Seconds())
+
+	im.modelManager.RecordCacheLookup(req.ModelName, hit.Layer, int64(usage.CachedTokens))
+	if req.Parameters.UseMemory {
+		im.storeInferenceMemory(ctx, req, &result)
+	}
+
+	req.Status = StatusCompleted
+	req.StartTime = time.Now()
+	req.EndTime = req.StartTime
+	req.Result = &result
+
+	log.Debug().
+		Str("request_id", req.ID).
+		Str("model", req.ModelName).
+		Str("layer", hit.Layer).
+		Float64("similarity", hit.Similarity).
+		Msg("Served inference from response cache")
+	return &result
+}
+
 // Helper functions
 
 func (im *InferenceManager) validateRequest(req *InferenceRequest) error {
@@ -583,20 +950,18 @@ func (im *InferenceManager) checkTokenBudget(req *InferenceRequest) error {
 }
 
 func (im *InferenceManager) selectBestModel(req *InferenceRequest) (string, error) {
-	// Determine specialization based on request type
-	specialization := ""
-	switch req.RequestType {
-	case InferenceTypeCode:
-		specialization = "code"
-	case InferenceTypeReasoning:
-		specialization = "reasoning"
-	case InferenceTypeAnalysis:
-		specialization = "analysis"
-	default:
-		specialization = "chat"
+	decision, err := im.router.Route(req)
+	if err != nil {
+		return "", err
 	}
 
-	return im.modelManager.GetBestModel(specialization)
+	log.Debug().
+		Str("request_id", req.ID).
+		Str("model", decision.Model).
+		Str("intent", decision.Intent).
+		Str("reason", decision.Reason).
+		Msg("Routed inference request")
+	return decision.Model, nil
 }
 
 func (im *InferenceManager) ensureModelLoaded(ctx context.Context, modelName string) error {
@@ -637,13 +1002,13 @@ func (im *InferenceManager) enhanceWithMemory(ctx context.Context, req *Inferenc
 	return nil
 }
 
-func (im *InferenceManager) optimizeContext(req *InferenceRequest) error {
+func (im *InferenceManager) optimizeContext(ctx context.Context, req *InferenceRequest) error {
 	maxTokens := 4096 // Default context window
 	if config, err := im.configManager.GetModelConfig(req.ModelName); err == nil {
 		maxTokens = config.ContextWindow
 	}
 
-	optimized, _, err := im.tokenManager.OptimizeContext(req.Messages, maxTokens, req.ModelName)
+	optimized, _, err := im.tokenManager.OptimizeContext(ctx, req.SessionID, req.Messages, maxTokens, req.ModelName)
 	if err != nil {
 		return err
 	}
@@ -668,13 +1033,15 @@ func (im *InferenceManager) unregisterInference(reqID string) {
 	delete(im.activeInferences, reqID)
 }
 
-func (im *InferenceManager) recordInferenceStats(req *InferenceRequest, result *InferenceResult) {
+// recordInferenceStats records a finished inference against the model and the user's
+// budget; err marks an inference that failed after spending result.Usage
+func (im *InferenceManager) recordInferenceStats(req *InferenceRequest, result *InferenceResult, err error) {
 	// Record in model manager
 	im.modelManager.RecordModelUsage(
 		req.ModelName,
 		int64(result.Usage.TotalTokens),
 		result.Duration,
-		nil,
+		err,
 	)
 
 	// Record token usage
@@ -725,6 +1092,24 @@ func (im *InferenceManager) extractContent(resp *OllamaResponse) string {
 	return resp.Response
 }
 
+func (im *InferenceManager) extractToolCalls(resp *OllamaResponse) []ToolCall {
+	if resp.Message == nil || len(resp.Message.ToolCalls) == 0 {
+		return nil
+	}
+	calls := make([]ToolCall, 0, len(resp.Message.ToolCalls))
+	for i, call := range resp.Message.ToolCalls {
+		if call.Function == nil {
+			continue
+		}
+		calls = append(calls, ToolCall{
+			ID:       fmt.Sprintf("call_%d_%d", time.Now().UnixNano(), i),
+			Type:     "function",
+			Function: call.Function,
+		})
+	}
+	return calls
+}
+
 func (im *InferenceManager) extractQueryContent(messages []Message) string {
 	// Extract content from user messages for memory search
 	var content []string
@@ -762,6 +1147,17 @@ func (im *InferenceManager) insertMemoryMessage(messages []Message, memoryMsg Me
 	return result
 }
 
+func (im *InferenceManager) calibrateTokens(req *OllamaRequest, resp *OllamaResponse) {
+	if resp.PromptEvalCount == 0 || len(req.Messages) == 0 {
+		return
+	}
+	prompt := make([]string, len(req.Messages))
+	for i, msg := range req.Messages {
+		prompt[i] = msg.Content
+	}
+	im.tokenManager.CalibrateTokens(req.Model, prompt, resp.PromptEvalCount)
+}
+
 func (im *InferenceManager) calculateTokensPerSecond(resp *OllamaResponse) float64 {
 	if resp.EvalDuration == 0 {
 		return 0
@@ -770,6 +1166,105 @@ func (im *InferenceManager) calculateTokensPerSecond(resp *OllamaResponse) float
 	return float64(resp.EvalCount) / duration.Seconds()
 }
 
+// CreateEmbedding embeds text with the given model, or the configured embedding model when empty
+func (im *InferenceManager) CreateEmbedding(ctx context.Context, modelName, text string) ([]float64, string, error) {
+	config := &ModelConfig{Name: modelName}
+	if modelName == "" {
+		embeddingConfig, err := im.configManager.GetEmbeddingModelConfig()
+		if err != nil {
+			return nil, "", err
+		}
+		config = embeddingConfig
+	}
+
+	// Embedding requests are short; a failed backend is retried on the next candidate
+	var lastErr error
+	for _, backend := range im.backends.Candidates(config.Name) {
+		embedding, err := NewOllamaEmbeddingProvider(backend.URL, config).Embed(ctx, text)
+		if err == nil {
+			backend.MarkLoaded(config.Name)
+			return embedding, config.Name, nil
+		}
+		if ctx.Err() != nil {
+			return nil, "", err
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no Ollama backends configured")
+	}
+	return nil, "", lastErr
+}
+
+// SummarizeContext folds messages into a running conversation summary using the summary model
+func (im *InferenceManager) SummarizeContext(ctx context.Context, previousSummary string, messages []Message, maxTokens int) (string, error) {
+	config, err := im.configManager.GetSummaryModelConfig()
+	if err != nil {
+		return "", err
+	}
+
+	var transcript strings.Builder
+	for _, msg := range messages {
+		fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, msg.Content)
+	}
+
+	prompt := ""
+	if previousSummary != "" {
+		prompt = fmt.Sprintf("Existing summary:\n%s\n\nExtend it with the following turns.\n\n", previousSummary)
+	}
+	prompt += fmt.Sprintf("Conversation:\n%s", transcript.String())
+
+	systemPrompt := config.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = "Summarize the conversation for use as context in later turns. Keep facts, decisions, names, code identifiers, open questions and user preferences. Reply with the summary only."
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	resp, err := im.backendFor(config.Name).Chat(ctx, &OllamaRequest{
+		Model: config.Name,
+		Messages: []OllamaMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: prompt},
+		},
+		Options: map[string]interface{}{
+			"num_predict": maxTokens,
+			"temperature": 0.2,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("summary request failed: %w", err)
+	}
+
+	im.modelManager.RecordModelUsage(config.Name, int64(resp.PromptEvalCount+resp.EvalCount), time.Duration(resp.TotalDuration), nil)
+	return strings.TrimSpace(im.extractContent(resp)), nil
+}
+
+// SummarizerBudget returns how many input tokens the summary model accepts
+func (im *InferenceManager) SummarizerBudget() int {
+	config, err := im.configManager.GetSummaryModelConfig()
+	if err != nil || config.ContextWindow <= 0 {
+		return 4096
+	}
+	return config.ContextWindow
+}
+
+// GetToolApprovals returns the gate holding tool calls that wait for approval
+func (im *InferenceManager) GetToolApprovals() *ToolApprovals {
+	return im.approvals
+}
+
+// GetRoutingEngine returns the engine choosing models for requests that name none
+func (im *InferenceManager) GetRoutingEngine() *RoutingEngine {
+	return im.router
+}
+
+// GetQueueStats reports running and waiting requests per model
+func (im *InferenceManager) GetQueueStats() map[string]*QueueStats {
+	return im.scheduler.Stats()
+}
+
 // GetActiveInferences returns currently active inference requests
 func (im *InferenceManager) GetActiveInferences() map[string]*InferenceRequest {
 	im.mu.RLock()
@@ -0,0 +1,418 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/inference-scheduler.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+)
+
+// InferencePriority is the scheduling class of a request
+type InferencePriority string
+
+const (
+	PriorityInteractive InferencePriority = "interactive" // a user is waiting on the answer
+	PriorityBatch       InferencePriority = "batch"       // pipelines and background jobs
+)
+
+// Scheduler defaults, used when limits.yaml leaves a field unset
+const (
+	defaultMaxConcurrentPerModel = 2
+	defaultMaxQueuedRequests     = 64
+	defaultMaxQueuedPerUser      = 16
+	defaultMaxQueueWait          = 2 * time.Minute
+)
+
+// QueueFullError reports that a request was turned away by the scheduler
+type QueueFullError struct {
+	Model      string
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *QueueFullError) Error() string {
+	return fmt.Sprintf("inference queue full for model %s: %s (retry after %s)", e.Model, e.Reason, e.RetryAfter)
+}
+
+// InferenceScheduler bounds concurrent inferences per model. Waiting requests are
+// served interactive before batch, and round-robin between users within a class so
+// one user's burst cannot starve everyone else.
+type InferenceScheduler struct {
+	mu            sync.Mutex
+	configManager *ConfigManager
+	models        map[string]*modelQueue
+	queued        int
+	queuedByUser  map[string]int
+}
+
+// modelQueue tracks one model's running slots and waiting requests
+type modelQueue struct {
+	limit    int
+	running  int
+	classes  []*fairQueue // in priority order
+	avgHold  time.Duration
+	finished int64
+}
+
+// fairQueue holds one priority class: a FIFO per user, served round-robin
+type fairQueue struct {
+	byUser map[string][]*queueTicket
+	users  []string
+}
+
+// queueTicket is a request waiting for a slot
+type queueTicket struct {
+	userID  string
+	ready   chan struct{}
+	granted bool
+}
+
+// QueueStats is a snapshot of one model's queue
+type QueueStats struct {
+	Model       string        `json:"model"`
+	Running     int           `json:"running"`
+	Limit       int           `json:"limit"`
+	Interactive int           `json:"interactive_waiting"`
+	Batch       int           `json:"batch_waiting"`
+	AvgHoldTime time.Duration `json:"avg_hold_time"`
+}
+
+// NewInferenceScheduler creates a scheduler whose limits come from limits.yaml and models.yaml
+func NewInferenceScheduler(configManager *ConfigManager) *InferenceScheduler {
+	return &InferenceScheduler{
+		configManager: configManager,
+		models:        make(map[string]*modelQueue),
+		queuedByUser:  make(map[string]int),
+	}
+}
+
+// Acquire waits for a slot on the model and returns the function that frees it and
+// how long the request queued. It fails with a QueueFullError when the queue is full
+// or the wait exceeds the configured maximum, and with ctx's error when cancelled.
+func (s *InferenceScheduler) Acquire(ctx context.Context, modelName, userID string, priority InferencePriority) (func(), time.Duration, error) {
+	maxQueued, maxPerUser, maxWait := s.limits()
+	start := time.Now()
+
+	s.mu.Lock()
+	mq := s.queueFor(modelName)
+	if mq.running < mq.limit && mq.waiting() == 0 {
+		mq.running++
+		s.mu.Unlock()
+		return s.releaser(modelName, mq), 0, nil
+	}
+	if s.queued >= maxQueued {
+		err := &QueueFullError{Model: modelName, Reason: "too many queued requests", RetryAfter: mq.retryAfter()}
+		s.mu.Unlock()
+		return nil, 0, err
+	}
+	if s.queuedByUser[userID] >= maxPerUser {
+		err := &QueueFullError{Model: modelName, Reason: "too many queued requests for this user", RetryAfter: mq.retryAfter()}
+		s.mu.Unlock()
+		return nil, 0, err
+	}
+
+	ticket := &queueTicket{userID: userID, ready: make(chan struct{})}
+	mq.class(priority).push(ticket)
+	s.queued++
+	s.queuedByUser[userID]++
+	s.mu.Unlock()
+
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+
+	var failure error
+	select {
+	case <-ticket.ready:
+		return s.releaser(modelName, mq), time.Since(start), nil
+	case <-ctx.Done():
+		failure = ctx.Err()
+	case <-timer.C:
+		failure = &QueueFullError{Model: modelName, Reason: "timed out waiting for a slot", RetryAfter: mq.retryAfter()}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ticket.granted {
+		// The slot arrived as we gave up; hand it on
+		mq.running--
+		s.dispatch(mq)
+		return nil, 0, failure
+	}
+	mq.class(priority).remove(ticket)
+	s.dequeued(userID)
+	return nil, 0, failure
+}
+
+// Stats returns a snapshot of every model queue
+func (s *InferenceScheduler) Stats() map[string]*QueueStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make(map[string]*QueueStats, len(s.models))
+	for name, mq := range s.models {
+		stats[name] = &QueueStats{
+			Model:       name,
+			Running:     mq.running,
+			Limit:       mq.limit,
+			Interactive: mq.classes[0].len(),
+			Batch:       mq.classes[1].len(),
+			AvgHoldTime: mq.avgHold,
+		}
+	}
+	return stats
+}
+
+// modelSlot is a held slot a request can give up while it waits on something other
+// than the model, such as a tool approval, and queue for again afterwards. A nil slot
+// does nothing.
+type modelSlot struct {
+	scheduler *InferenceScheduler
+	modelName string
+	userID    string
+	priority  InferencePriority
+
+	mu      sync.Mutex
+	release func()
+}
+
+// acquireSlot is Acquire returning a slot that can be released and reacquired
+func (s *InferenceScheduler) acquireSlot(ctx context.Context, modelName, userID string, priority InferencePriority) (*modelSlot, time.Duration, error) {
+	release, queueTime, err := s.Acquire(ctx, modelName, userID, priority)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &modelSlot{scheduler: s, modelName: modelName, userID: userID, priority: priority, release: release}, queueTime, nil
+}
+
+// Release frees the slot if it is held
+func (ms *modelSlot) Release() {
+	if ms == nil {
+		return
+	}
+	ms.mu.Lock()
+	release := ms.release
+	ms.release = nil
+	ms.mu.Unlock()
+	if release != nil {
+		release()
+	}
+}
+
+// Reacquire queues for the slot again after Release
+func (ms *modelSlot) Reacquire(ctx context.Context) error {
+	if ms == nil {
+		return nil
+	}
+	ms.mu.Lock()
+	held := ms.release != nil
+	ms.mu.Unlock()
+	if held {
+		return nil
+	}
+
+	release, _, err := ms.scheduler.Acquire(ctx, ms.modelName, ms.userID, ms.priority)
+	if err != nil {
+		return err
+	}
+	ms.mu.Lock()
+	ms.release = release
+	ms.mu.Unlock()
+	return nil
+}
+
+// releaser frees a held slot exactly once and records how long it was held
+func (s *InferenceScheduler) releaser(modelName string, mq *modelQueue) func() {
+	var once sync.Once
+	granted := time.Now()
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			mq.running--
+			mq.recordHold(time.Since(granted))
+			s.dispatch(mq)
+		})
+	}
+}
+
+// dispatch grants free slots to waiting requests; callers hold s.mu
+func (s *InferenceScheduler) dispatch(mq *modelQueue) {
+	for mq.running < mq.limit {
+		var ticket *queueTicket
+		for _, class := range mq.classes {
+			if ticket = class.pop(); ticket != nil {
+				break
+			}
+		}
+		if ticket == nil {
+			return
+		}
+		ticket.granted = true
+		mq.running++
+		s.dequeued(ticket.userID)
+		close(ticket.ready)
+	}
+}
+
+// dequeued updates the queue counters for a request leaving the queue; callers hold s.mu
+func (s *InferenceScheduler) dequeued(userID string) {
+	s.queued--
+	if s.queuedByUser[userID]--; s.queuedByUser[userID] <= 0 {
+		delete(s.queuedByUser, userID)
+	}
+}
+
+// queueFor returns the model's queue, creating it with the configured concurrency; callers hold s.mu
+func (s *InferenceScheduler) queueFor(modelName string) *modelQueue {
+	if mq, ok := s.models[modelName]; ok {
+		return mq
+	}
+
+	limit := defaultMaxConcurrentPerModel
+	if limits, err := s.configManager.GetLimitsConfig(); err == nil && limits.MaxConcurrentPerModel > 0 {
+		limit = limits.MaxConcurrentPerModel
+	}
+	if config, err := s.configManager.GetModelConfig(modelName); err == nil && config.MaxConcurrency > 0 {
+		limit = config.MaxConcurrency
+	}
+
+	mq := &modelQueue{
+		limit:   limit,
+		classes: []*fairQueue{newFairQueue(), newFairQueue()},
+	}
+	s.models[modelName] = mq
+	log.Debug().Str("model", modelName).Int("limit", limit).Msg("Created inference queue")
+	return mq
+}
+
+// limits reads the queue bounds from limits.yaml
+func (s *InferenceScheduler) limits() (maxQueued, maxPerUser int, maxWait time.Duration) {
+	maxQueued, maxPerUser, maxWait = defaultMaxQueuedRequests, defaultMaxQueuedPerUser, defaultMaxQueueWait
+	limits, err := s.configManager.GetLimitsConfig()
+	if err != nil {
+		return
+	}
+	if limits.MaxQueuedRequests > 0 {
+		maxQueued = limits.MaxQueuedRequests
+	}
+	if limits.MaxQueuedPerUser > 0 {
+		maxPerUser = limits.MaxQueuedPerUser
+	}
+	if limits.MaxQueueWaitSeconds > 0 {
+		maxWait = time.Duration(limits.MaxQueueWaitSeconds) * time.Second
+	}
+	return
+}
+
+// class returns the queue for a priority; anything but batch is interactive
+func (mq *modelQueue) class(priority InferencePriority) *fairQueue {
+	if priority == PriorityBatch {
+		return mq.classes[1]
+	}
+	return mq.classes[0]
+}
+
+// waiting counts requests queued in every class
+func (mq *modelQueue) waiting() int {
+	total := 0
+	for _, class := range mq.classes {
+		total += class.len()
+	}
+	return total
+}
+
+// recordHold folds a finished request's slot time into the running average
+func (mq *modelQueue) recordHold(held time.Duration) {
+	mq.finished++
+	if mq.finished == 1 {
+		mq.avgHold = held
+		return
+	}
+	// Exponential moving average so the estimate follows load changes
+	mq.avgHold += (held - mq.avgHold) / 8
+}
+
+// retryAfter estimates when a slot will be free for a request queued now
+func (mq *modelQueue) retryAfter() time.Duration {
+	hold := mq.avgHold
+	if hold <= 0 {
+		hold = time.Second
+	}
+	wait := hold * time.Duration(mq.waiting()+1) / time.Duration(mq.limit)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait.Round(time.Second)
+}
+
+func newFairQueue() *fairQueue {
+	return &fairQueue{byUser: make(map[string][]*queueTicket)}
+}
+
+// push appends a ticket to its user's FIFO, adding the user to the rotation
+func (fq *fairQueue) push(ticket *queueTicket) {
+	if len(fq.byUser[ticket.userID]) == 0 {
+		fq.users = append(fq.users, ticket.userID)
+	}
+	fq.byUser[ticket.userID] = append(fq.byUser[ticket.userID], ticket)
+}
+
+// pop takes the oldest ticket of the next user in the rotation
+func (fq *fairQueue) pop() *queueTicket {
+	if len(fq.users) == 0 {
+		return nil
+	}
+	userID := fq.users[0]
+	fq.users = fq.users[1:]
+
+	tickets := fq.byUser[userID]
+	ticket := tickets[0]
+	if len(tickets) == 1 {
+		delete(fq.byUser, userID)
+	} else {
+		fq.byUser[userID] = tickets[1:]
+		fq.users = append(fq.users, userID)
+	}
+	return ticket
+}
+
+// remove drops a ticket that gave up waiting
+func (fq *fairQueue) remove(ticket *queueTicket) {
+	tickets := fq.byUser[ticket.userID]
+	for i, t := range tickets {
+		if t != ticket {
+			continue
+		}
+		tickets = append(tickets[:i], tickets[i+1:]...)
+		break
+	}
+	if len(tickets) > 0 {
+		fq.byUser[ticket.userID] = tickets
+		return
+	}
+
+	delete(fq.byUser, ticket.userID)
+	for i, userID := range fq.users {
+		if userID == ticket.userID {
+			fq.users = append(fq.users[:i], fq.users[i+1:]...)
+			break
+		}
+	}
+}
+
+// len counts waiting tickets
+func (fq *fairQueue) len() int {
+	total := 0
+	for _, tickets := range fq.byUser {
+		total += len(tickets)
+	}
+	return total
+}
@@ -0,0 +1,226 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/inference-scheduler_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// schedulerConfig is a private config manager holding only limits and model configs
+func schedulerConfig(limits *LimitsConfig, models ...ModelConfig) *ConfigManager {
+	cm := &ConfigManager{
+		configs:  make(map[string]interface{}),
+		watchers: make(map[string][]func(interface{})),
+	}
+	cm.configs["configs/limits.yaml"] = limits
+	cm.configs["configs/models.yaml"] = &models
+	return cm
+}
+
+// acquired is the outcome of an Acquire call made in the background
+type acquired struct {
+	label     string
+	release   func()
+	queueTime time.Duration
+	err       error
+}
+
+// enqueue calls Acquire in the background and waits until the request is queued
+func enqueue(t *testing.T, s *InferenceScheduler, model, user string, priority InferencePriority, label string, out chan<- acquired) {
+	t.Helper()
+	before := waiting(s, model)
+	go func() {
+		release, queueTime, err := s.Acquire(context.Background(), model, user, priority)
+		out <- acquired{label, release, queueTime, err}
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for waiting(s, model) == before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s was not queued", label)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func waiting(s *InferenceScheduler, model string) int {
+	stats, ok := s.Stats()[model]
+	if !ok {
+		return 0
+	}
+	return stats.Interactive + stats.Batch
+}
+
+func mustAcquire(t *testing.T, s *InferenceScheduler, model, user string) func() {
+	t.Helper()
+	release, queueTime, err := s.Acquire(context.Background(), model, user, PriorityInteractive)
+	if err != nil {
+		t.Fatalf("Acquire(%s, %s): %v", model, user, err)
+	}
+	if queueTime != 0 {
+		t.Errorf("uncontended Acquire queued for %s", queueTime)
+	}
+	return release
+}
+
+// drain releases slots one at a time and returns the order waiting requests were served in
+func drain(t *testing.T, release func(), out <-chan acquired, n int) []string {
+	t.Helper()
+	var order []string
+	for i := 0; i < n; i++ {
+		release()
+		select {
+		case got := <-out:
+			if got.err != nil {
+				t.Fatalf("%s: %v", got.label, got.err)
+			}
+			order = append(order, got.label)
+			release = got.release
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no request was served after %v", order)
+		}
+	}
+	release()
+	return order
+}
+
+func TestSchedulerBoundsConcurrencyPerModel(t *testing.T) {
+	s := NewInferenceScheduler(schedulerConfig(
+		&LimitsConfig{MaxConcurrentPerModel: 2},
+		ModelConfig{Name: "small", MaxConcurrency: 1},
+	))
+
+	first := mustAcquire(t, s, "llama", "alice")
+	mustAcquire(t, s, "llama", "bob")
+	mustAcquire(t, s, "small", "alice")
+
+	out := make(chan acquired, 2)
+	enqueue(t, s, "llama", "carol", PriorityInteractive, "llama", out)
+	enqueue(t, s, "small", "carol", PriorityInteractive, "small", out)
+
+	// Other models are unaffected by a full one
+	mustAcquire(t, s, "mistral", "carol")
+
+	stats := s.Stats()
+	if stats["llama"].Running != 2 || stats["llama"].Limit != 2 || stats["small"].Limit != 1 {
+		t.Errorf("stats = %+v %+v", stats["llama"], stats["small"])
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	first()
+	got := <-out
+	if got.err != nil || got.label != "llama" {
+		t.Fatalf("after a release got %+v, want the queued llama request", got)
+	}
+	if got.queueTime < 20*time.Millisecond {
+		t.Errorf("queue time = %s, want at least the time spent waiting", got.queueTime)
+	}
+
+	// Releasing twice frees one slot only
+	first()
+	if stats := s.Stats()["llama"]; stats.Running != 2 {
+		t.Errorf("running after a double release = %d, want 2", stats.Running)
+	}
+}
+
+func TestSchedulerIsFairAcrossUsers(t *testing.T) {
+	s := NewInferenceScheduler(schedulerConfig(&LimitsConfig{MaxConcurrentPerModel: 1}))
+	release := mustAcquire(t, s, "llama", "alice")
+
+	out := make(chan acquired, 4)
+	enqueue(t, s, "llama", "alice", PriorityInteractive, "alice-1", out)
+	enqueue(t, s, "llama", "alice", PriorityInteractive, "alice-2", out)
+	enqueue(t, s, "llama", "alice", PriorityInteractive, "alice-3", out)
+	enqueue(t, s, "llama", "bob", PriorityInteractive, "bob-1", out)
+
+	order := drain(t, release, out, 4)
+	want := []string{"alice-1", "bob-1", "alice-2", "alice-3"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("served %v, want %v", order, want)
+		}
+	}
+}
+
+func TestSchedulerServesInteractiveBeforeBatch(t *testing.T) {
+	s := NewInferenceScheduler(schedulerConfig(&LimitsConfig{MaxConcurrentPerModel: 1}))
+	release := mustAcquire(t, s, "llama", "alice")
+
+	out := make(chan acquired, 3)
+	enqueue(t, s, "llama", "pipeline", PriorityBatch, "batch", out)
+	enqueue(t, s, "llama", "bob", PriorityInteractive, "interactive", out)
+	enqueue(t, s, "llama", "carol", "", "default", out)
+
+	order := drain(t, release, out, 3)
+	if order[0] != "interactive" || order[1] != "default" || order[2] != "batch" {
+		t.Fatalf("served %v, want interactive requests before batch", order)
+	}
+}
+
+func TestSchedulerRejectsWhenQueueFull(t *testing.T) {
+	s := NewInferenceScheduler(schedulerConfig(&LimitsConfig{
+		MaxConcurrentPerModel: 1,
+		MaxQueuedRequests:     2,
+		MaxQueuedPerUser:      1,
+	}))
+	release := mustAcquire(t, s, "llama", "alice")
+
+	out := make(chan acquired, 2)
+	enqueue(t, s, "llama", "alice", PriorityInteractive, "alice", out)
+
+	var queueErr *QueueFullError
+	_, _, err := s.Acquire(context.Background(), "llama", "alice", PriorityInteractive)
+	if !errors.As(err, &queueErr) || queueErr.Reason != "too many queued requests for this user" {
+		t.Fatalf("second request from alice = %v, want the per-user limit", err)
+	}
+
+	enqueue(t, s, "llama", "bob", PriorityInteractive, "bob", out)
+	_, _, err = s.Acquire(context.Background(), "llama", "carol", PriorityInteractive)
+	if !errors.As(err, &queueErr) || queueErr.Reason != "too many queued requests" {
+		t.Fatalf("request into a full queue = %v, want the queue limit", err)
+	}
+	if queueErr.RetryAfter < time.Second || queueErr.Model != "llama" {
+		t.Errorf("rejection = %+v, want a retry hint of at least a second", queueErr)
+	}
+
+	drain(t, release, out, 2)
+	mustAcquire(t, s, "llama", "carol")
+}
+
+func TestSchedulerCancelAndTimeout(t *testing.T) {
+	s := NewInferenceScheduler(schedulerConfig(&LimitsConfig{MaxConcurrentPerModel: 1, MaxQueueWaitSeconds: 1}))
+	release := mustAcquire(t, s, "llama", "alice")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := s.Acquire(ctx, "llama", "bob", PriorityInteractive)
+		done <- err
+	}()
+	for waiting(s, "llama") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled Acquire = %v", err)
+	}
+	if n := waiting(s, "llama"); n != 0 {
+		t.Fatalf("%d requests still queued after cancelling", n)
+	}
+
+	var queueErr *QueueFullError
+	start := time.Now()
+	_, _, err := s.Acquire(context.Background(), "llama", "bob", PriorityInteractive)
+	if !errors.As(err, &queueErr) || time.Since(start) < time.Second {
+		t.Fatalf("Acquire past the wait limit = %v after %s", err, time.Since(start))
+	}
+
+	// The slot still passes on normally
+	release()
+	mustAcquire(t, s, "llama", "bob")
+}
@@ -0,0 +1,274 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/intent-classifier.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+)
+
+// Where a MessageAnalysis came from
+const (
+	AnalysisSourceModel     = "model"
+	AnalysisSourceCache     = "cache"
+	AnalysisSourceHeuristic = "heuristic"
+)
+
+// Intent classifier defaults, used when features.yaml leaves a field unset
+const (
+	defaultIntentMinConfidence = 0.6
+	defaultIntentCacheEntries  = 2048
+	defaultClassifyTimeout     = 10 * time.Second
+	heuristicIntentConfidence  = 0.3
+	heuristicEntityConfidence  = 0.5
+)
+
+// MessageAnalysis is the intent, topic and entities found in a user message
+type MessageAnalysis struct {
+	Intent     ConversationIntent `json:"intent"`
+	Confidence float64            `json:"confidence"`
+	Topic      string             `json:"topic"`
+	Entities   []*Entity          `json:"entities"`
+	Source     string             `json:"source,omitempty"`
+}
+
+// MessageClassifier analyzes user messages with a model
+type MessageClassifier interface {
+	ClassifyMessage(ctx context.Context, content string) (*MessageAnalysis, error)
+}
+
+var conversationIntents = []string{
+	string(IntentQuestion), string(IntentRequest), string(IntentInstruction), string(IntentCollaboration),
+	string(IntentDebug), string(IntentLearning), string(IntentCreative), string(IntentAnalysis),
+}
+
+// messageAnalysisSchema is the structured output the classifier model must return
+var messageAnalysisSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []string{"intent", "confidence", "topic", "entities"},
+	"properties": map[string]interface{}{
+		"intent":     map[string]interface{}{"type": "string", "enum": conversationIntents},
+		"confidence": map[string]interface{}{"type": "number", "minimum": 0, "maximum": 1},
+		"topic":      map[string]interface{}{"type": "string", "maxLength": 80},
+		"entities": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type":     "object",
+				"required": []string{"name", "type"},
+				"properties": map[string]interface{}{
+					"name":       map[string]interface{}{"type": "string"},
+					"type":       map[string]interface{}{"type": "string"},
+					"value":      map[string]interface{}{"type": "string"},
+					"confidence": map[string]interface{}{"type": "number", "minimum": 0, "maximum": 1},
+				},
+			},
+		},
+	},
+}
+
+const defaultClassifierPrompt = `Classify the user's message for an AI assistant. Reply with JSON only.
+intent is one of: question (wants information), request (asks the assistant to do something),
+instruction (tells the assistant how to behave or states a task without asking), collaboration
+(wants to work on something together), debug (an error, failure or bug), learning (wants to be
+taught or understand a concept), creative (stories, poems, names, ideas), analysis (compare,
+evaluate or review something).
+confidence is how sure you are, from 0 to 1. topic is a short noun phrase. entities are the
+named things mentioned, such as languages, libraries, files, errors, people or products, each
+with a type.`
+
+// ClassifyMessage asks the classifier model for a message's intent, topic and entities
+func (im *InferenceManager) ClassifyMessage(ctx context.Context, content string) (*MessageAnalysis, error) {
+	config, err := im.configManager.GetClassifierModelConfig()
+	if err != nil {
+		return nil, err
+	}
+	format, err := schemaFormat(messageAnalysisSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	systemPrompt := config.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = defaultClassifierPrompt
+	}
+	timeout := defaultClassifyTimeout
+	if features, err := im.configManager.GetFeatureConfig(); err == nil && features.IntentClassifier.TimeoutSeconds > 0 {
+		timeout = time.Duration(features.IntentClassifier.TimeoutSeconds) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := im.backendFor(config.Name).Chat(ctx, &OllamaRequest{
+		Model: config.Name,
+		Messages: []OllamaMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: content},
+		},
+		Format: format,
+		Options: map[string]interface{}{
+			"num_predict": 256,
+			"temperature": 0,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("classifier request failed: %w", err)
+	}
+	im.modelManager.RecordModelUsage(config.Name, int64(resp.PromptEvalCount+resp.EvalCount), time.Duration(resp.TotalDuration), nil)
+
+	value, err := parseStructuredOutput(im.extractContent(resp), messageAnalysisSchema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid classifier output: %w", err)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var analysis MessageAnalysis
+	if err := json.Unmarshal(data, &analysis); err != nil {
+		return nil, fmt.Errorf("invalid classifier output: %w", err)
+	}
+	analysis.Source = AnalysisSourceModel
+	return &analysis, nil
+}
+
+// SetClassifier replaces the model used to analyze messages; nil keeps only the heuristics
+func (cm *ConversationManager) SetClassifier(classifier MessageClassifier) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.classifier = classifier
+}
+
+// classifyMessage analyzes a message with the classifier model, reusing earlier results
+// for the same text, and falls back to the heuristics when the model fails or is unsure
+func (cm *ConversationManager) classifyMessage(ctx context.Context, content string) *MessageAnalysis {
+	config := cm.intentClassifierConfig()
+	key := messageHash(content)
+	if cached, ok := cm.analyses.get(key); ok {
+		cached.Source = AnalysisSourceCache
+		return cached
+	}
+
+	cm.mu.RLock()
+	classifier := cm.classifier
+	cm.mu.RUnlock()
+	if classifier != nil {
+		analysis, err := classifier.ClassifyMessage(ctx, content)
+		switch {
+		case err != nil:
+			log.Debug().Err(err).Msg("Intent classifier unavailable, using heuristics")
+		case analysis.Confidence < config.MinConfidence:
+			log.Debug().
+				Str("intent", string(analysis.Intent)).
+				Float64("confidence", analysis.Confidence).
+				Msg("Intent classifier unsure, using heuristics")
+		default:
+			cm.analyses.put(key, analysis, config.CacheEntries)
+			return analysis
+		}
+	}
+	return cm.heuristicAnalysis(content)
+}
+
+// heuristicAnalysis is the keyword fallback
+func (cm *ConversationManager) heuristicAnalysis(content string) *MessageAnalysis {
+	lower := strings.ToLower(content)
+
+	intent := IntentInstruction
+	switch {
+	case strings.Contains(lower, "?"):
+		intent = IntentQuestion
+	case strings.Contains(lower, "please") || strings.Contains(lower, "can you"):
+		intent = IntentRequest
+	case strings.Contains(lower, "debug") || strings.Contains(lower, "error"):
+		intent = IntentDebug
+	case strings.Contains(lower, "learn") || strings.Contains(lower, "teach"):
+		intent = IntentLearning
+	}
+
+	return &MessageAnalysis{
+		Intent:     intent,
+		Confidence: heuristicIntentConfidence,
+		Topic:      cm.extractTopic(content),
+		Entities:   cm.extractEntities(content),
+		Source:     AnalysisSourceHeuristic,
+	}
+}
+
+// intentClassifierConfig reads features.yaml with defaults applied
+func (cm *ConversationManager) intentClassifierConfig() IntentClassifierConfig {
+	var config IntentClassifierConfig
+	if features, err := cm.configManager.GetFeatureConfig(); err == nil {
+		config = features.IntentClassifier
+	}
+	if config.MinConfidence <= 0 {
+		config.MinConfidence = defaultIntentMinConfidence
+	}
+	if config.CacheEntries <= 0 {
+		config.CacheEntries = defaultIntentCacheEntries
+	}
+	return config
+}
+
+// Patterns for the heuristic entity extraction
+var (
+	urlPattern      = regexp.MustCompile(`https?://[^\s<>"')]+`)
+	codeSpanPattern = regexp.MustCompile("`([^`\n]+)`")
+	filePattern     = regexp.MustCompile(`(?:^|[\s(])((?:[\w.-]+/)*[\w-]+\.(?:go|py|js|ts|tsx|java|rs|c|h|cpp|rb|php|sql|yaml|yml|json|toml|md|sh))\b`)
+)
+
+// messageHash keys the analysis cache on whitespace-normalized text
+func messageHash(content string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(content), " ")))
+	return hex.EncodeToString(sum[:])
+}
+
+// analysisCache keeps model analyses per message hash, evicting the oldest first
+type analysisCache struct {
+	mu      sync.Mutex
+	entries map[string]*MessageAnalysis
+	order   []string
+}
+
+func newAnalysisCache() *analysisCache {
+	return &analysisCache{entries: make(map[string]*MessageAnalysis)}
+}
+
+func (ac *analysisCache) get(key string) (*MessageAnalysis, bool) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	analysis, ok := ac.entries[key]
+	if !ok {
+		return nil, false
+	}
+	copied := *analysis
+	copied.Entities = append([]*Entity(nil), analysis.Entities...)
+	return &copied, true
+}
+
+func (ac *analysisCache) put(key string, analysis *MessageAnalysis, limit int) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if _, exists := ac.entries[key]; !exists {
+		ac.order = append(ac.order, key)
+	}
+	copied := *analysis
+	ac.entries[key] = &copied
+	for len(ac.order) > limit {
+		delete(ac.entries, ac.order[0])
+		ac.order = ac.order[1:]
+	}
+}
@@ -0,0 +1,170 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/intent-classifier_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// intentFixture is a labelled message from testdata/intent-fixtures.json
+type intentFixture struct {
+	Message string             `json:"message"`
+	Intent  ConversationIntent `json:"intent"`
+}
+
+func loadIntentFixtures(t *testing.T) []intentFixture {
+	t.Helper()
+	data, err := os.ReadFile("testdata/intent-fixtures.json")
+	if err != nil {
+		t.Fatalf("read fixtures: %v", err)
+	}
+	var fixtures []intentFixture
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		t.Fatalf("parse fixtures: %v", err)
+	}
+	return fixtures
+}
+
+// intentAccuracy is the share of fixtures classified with their labelled intent
+func intentAccuracy(t *testing.T, fixtures []intentFixture, classify func(string) ConversationIntent) float64 {
+	t.Helper()
+	correct := 0
+	for _, fixture := range fixtures {
+		if got := classify(fixture.Message); got == fixture.Intent {
+			correct++
+		} else {
+			t.Logf("%q: got %s, want %s", fixture.Message, got, fixture.Intent)
+		}
+	}
+	return float64(correct) / float64(len(fixtures))
+}
+
+// classifierFixture is a conversation manager whose classifier model runs on a fake Ollama
+func classifierFixture(t *testing.T, url string, classifierModel string) *ConversationManager {
+	t.Helper()
+	cm := schedulerConfig(&LimitsConfig{MaxRequestsPerMinute: 1000, MaxTokensPerRequest: 4096, TokenBudgetPerUser: 100000, ResetIntervalHours: 24},
+		ModelConfig{Name: "llama3.2", Specialization: "chat"},
+		ModelConfig{Name: classifierModel, Specialization: "classifier"})
+	mm := NewModelManager(url, cm)
+	t.Cleanup(func() { mm.Shutdown(context.Background()) })
+	im := NewInferenceManager(cm, mm, NewTokenManager(cm), nil, nil, url)
+	return NewConversationManager(cm, nil, nil, nil, im)
+}
+
+func TestHeuristicIntentBaseline(t *testing.T) {
+	fixtures := loadIntentFixtures(t)
+	covered := make(map[ConversationIntent]bool)
+	for _, fixture := range fixtures {
+		covered[fixture.Intent] = true
+	}
+	if len(covered) != len(conversationIntents) {
+		t.Fatalf("fixtures cover %d of %d intents", len(covered), len(conversationIntents))
+	}
+
+	conversations := NewConversationManager(schedulerConfig(nil), nil, nil, nil, nil)
+	accuracy := intentAccuracy(t, fixtures, func(message string) ConversationIntent {
+		return conversations.classifyMessage(context.Background(), message).Intent
+	})
+	t.Logf("heuristic accuracy: %.2f", accuracy)
+	if accuracy < 0.45 {
+		t.Errorf("heuristic accuracy dropped to %.2f", accuracy)
+	}
+
+	entities := conversations.extractEntities("See https://go.dev/doc and fix `nil map` in managers/config-manager.go")
+	var found []string
+	for _, entity := range entities {
+		found = append(found, entity.Type+"="+entity.Value)
+	}
+	if strings.Join(found, ",") != "url=https://go.dev/doc,code=nil map,file=managers/config-manager.go" {
+		t.Errorf("entities = %v", found)
+	}
+}
+
+func TestModelIntentClassification(t *testing.T) {
+	ollama := newFakeOllama(t, "ollama")
+	conversations := classifierFixture(t, ollama.URL, "intent-mini")
+	ctx := context.Background()
+	conversation, err := conversations.StartConversation(ctx, "alice", "s1", nil)
+	if err != nil {
+		t.Fatalf("StartConversation: %v", err)
+	}
+
+	// The model's analysis is applied and cached per message
+	ollama.replies = []string{`{"intent":"creative","confidence":0.92,"topic":"lighthouse poem","entities":[{"name":"lighthouse","type":"subject"}]}`}
+	message := &Message{ID: "m1", Role: "user", Content: "Write a short poem about a lighthouse keeper"}
+	conversations.analyzeMessage(ctx, conversation, message)
+	if conversation.Context.Intent != IntentCreative || conversation.Context.IntentConfidence != 0.92 || conversation.Context.Topic != "lighthouse poem" {
+		t.Errorf("context = %+v", conversation.Context)
+	}
+	if len(conversation.Context.Entities) != 1 || conversation.Context.Entities[0].Name != "lighthouse" {
+		t.Errorf("entities = %+v", conversation.Context.Entities)
+	}
+	body := ollama.bodies[0]
+	if body["model"] != "intent-mini" {
+		t.Errorf("classified with %v", body["model"])
+	}
+	if format, _ := json.Marshal(body["format"]); !strings.Contains(string(format), `"enum":["question"`) {
+		t.Errorf("format = %s", format)
+	}
+
+	message = &Message{ID: "m2", Role: "user", Content: "  Write a short poem\nabout a lighthouse keeper "}
+	conversations.analyzeMessage(ctx, conversation, message)
+	snapshot := conversation.Context.ContextHistory[len(conversation.Context.ContextHistory)-1]
+	if len(ollama.bodies) != 1 || snapshot.Intent != IntentCreative || snapshot.UserState["analysis_source"] != AnalysisSourceCache {
+		t.Errorf("cached: calls = %d, snapshot = %+v", len(ollama.bodies), snapshot)
+	}
+
+	// Unsure or invalid answers fall back to the heuristics and are not cached
+	for _, reply := range []string{
+		`{"intent":"analysis","confidence":0.2,"topic":"errors","entities":[]}`,
+		`{"intent":"gossip","confidence":0.9,"topic":"errors","entities":[]}`,
+		`not json`,
+	} {
+		ollama.replies = []string{reply}
+		analysis := conversations.classifyMessage(ctx, "I keep getting an error from the parser")
+		if analysis.Source != AnalysisSourceHeuristic || analysis.Intent != IntentDebug || analysis.Confidence != heuristicIntentConfidence {
+			t.Errorf("reply %s: analysis = %+v", reply, analysis)
+		}
+	}
+
+	// A failing classifier model falls back too
+	ollama.set(false, http.StatusInternalServerError)
+	if analysis := conversations.classifyMessage(ctx, "Teach me Rust?"); analysis.Source != AnalysisSourceHeuristic || analysis.Intent != IntentQuestion {
+		t.Errorf("failing model: analysis = %+v", analysis)
+	}
+}
+
+// TestLiveIntentAccuracy measures a real classifier model against the fixtures. It runs
+// when OCS_INTENT_MODEL names a model on the Ollama at OCS_OLLAMA_URL (default localhost).
+func TestLiveIntentAccuracy(t *testing.T) {
+	model := os.Getenv("OCS_INTENT_MODEL")
+	if model == "" {
+		t.Skip("OCS_INTENT_MODEL is not set")
+	}
+	url := os.Getenv("OCS_OLLAMA_URL")
+	if url == "" {
+		url = "http://localhost:11434"
+	}
+	conversations := classifierFixture(t, url, model)
+	fixtures := loadIntentFixtures(t)
+
+	baseline := NewConversationManager(schedulerConfig(nil), nil, nil, nil, nil)
+	heuristic := intentAccuracy(t, fixtures, func(message string) ConversationIntent {
+		return baseline.heuristicAnalysis(message).Intent
+	})
+	accuracy := intentAccuracy(t, fixtures, func(message string) ConversationIntent {
+		return conversations.classifyMessage(context.Background(), message).Intent
+	})
+	t.Logf("%s accuracy: %.2f, heuristic: %.2f", model, accuracy, heuristic)
+	if accuracy <= heuristic {
+		t.Errorf("%s is no better than the heuristics", model)
+	}
+}
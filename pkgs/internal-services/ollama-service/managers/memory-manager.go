@@ -24,10 +24,17 @@ import (
 type MemoryManager struct {
 	mu                  sync.RWMutex
 	userMemories        map[string]*UserMemoryStore
-	embeddingCache      map[string][]float64
+	embeddingMu         sync.Mutex
+	embeddingCache      map[string][]float64 // provider:contentHash -> embedding
+	embeddingDims       map[string]int       // provider -> last seen dimensions
+	embeddingProvider   EmbeddingProvider
+	fallbackEmbedder    EmbeddingProvider
 	memoryIndex         *MemoryIndex
 	configManager       *ConfigManager
 	maxMemoriesPerUser  int
+	exhaustiveLimit     int // above this many memories, similarity search uses the ANN index
+	refreshMu           sync.Mutex
+	refreshing          map[string]bool // users whose stale embeddings are being re-embedded
 	memoryRetention     time.Duration
 	importanceThreshold float64
 	shutdown            chan struct{}
@@ -65,6 +72,7 @@ type Memory struct {
 	AccessCount     int                    `json:"access_count"`
 	Tags            []string               `json:"tags"`
 	Embedding       []float64              `json:"embedding,omitempty"`
+	EmbeddingModel  string                 `json:"embedding_model,omitempty"` // provider that produced Embedding
 	Source          MemorySource           `json:"source"`
 	RelatedMemories []string               `json:"related_memories"`
 	Metadata        map[string]interface{} `json:"metadata"`
@@ -156,6 +164,8 @@ type MemoryIndex struct {
 	embeddingIndex  map[string]*EmbeddingEntry // Fast similarity search
 	temporalIndex   map[string][]string        // date -> memory IDs
 	importanceIndex map[float64][]string       // importance -> memory IDs
+	vectorIndexes   map[string]*HNSWIndex      // userID|provider -> ANN index
+	vectorKeys      map[string]string          // memory ID -> vector index key
 }
 
 // EmbeddingEntry for similarity search
@@ -190,9 +200,14 @@ func NewMemoryManager(configManager *ConfigManager) *MemoryManager {
 	mm := &MemoryManager{
 		userMemories:        make(map[string]*UserMemoryStore),
 		embeddingCache:      make(map[string][]float64),
+		embeddingDims:       make(map[string]int),
+		embeddingProvider:   NewHashEmbeddingProvider(300),
+		fallbackEmbedder:    NewHashEmbeddingProvider(300),
 		memoryIndex:         NewMemoryIndex(),
 		configManager:       configManager,
 		maxMemoriesPerUser:  10000,
+		exhaustiveLimit:     2000,
+		refreshing:          make(map[string]bool),
 		memoryRetention:     365 * 24 * time.Hour, // 1 year
 		importanceThreshold: 0.3,
 		shutdown:            make(chan struct{}),
@@ -207,6 +222,15 @@ func NewMemoryManager(configManager *ConfigManager) *MemoryManager {
 
 // StoreMemory stores a new memory for a user
 func (mm *MemoryManager) StoreMemory(ctx context.Context, memory *Memory) error {
+	// Generate embedding before taking the lock; providers may call out to Ollama
+	if len(memory.Content) > 10 {
+		embedding, provider, err := mm.generateEmbedding(ctx, memory.Content)
+		if err == nil {
+			memory.Embedding = embedding
+			memory.EmbeddingModel = provider
+		}
+	}
+
 	mm.mu.Lock()
 	defer mm.mu.Unlock()
 
@@ -221,14 +245,6 @@ func (mm *MemoryManager) StoreMemory(ctx context.Context, memory *Memory) error
 	// Calculate freshness
 	memory.Freshness = 1.0
 
-	// Generate embedding if content is meaningful
-	if len(memory.Content) > 10 {
-		embedding, err := mm.generateEmbedding(memory.Content)
-		if err == nil {
-			memory.Embedding = embedding
-		}
-	}
-
 	// Set defaults
 	if memory.ID == "" {
 		memory.ID = mm.generateMemoryID(memory)
@@ -265,20 +281,72 @@ func (mm *MemoryManager) StoreMemory(ctx context.Context, memory *Memory) error
 
 // RetrieveMemories retrieves memories based on query
 func (mm *MemoryManager) RetrieveMemories(ctx context.Context, query *MemoryQuery) ([]*MemoryResult, error) {
-	mm.mu.RLock()
-	defer mm.mu.RUnlock()
+	// Embed the query once for all candidates, before taking the lock; providers may
+	// call out to Ollama
+	var queryEmbedding []float64
+	queryProvider := ""
+	if query.Content != "" {
+		embedding, provider, err := mm.generateEmbedding(ctx, query.Content)
+		if err == nil {
+			queryEmbedding = embedding
+			queryProvider = provider
+		}
+	}
+
+	results, staleEmbeddings := mm.searchMemories(query, queryEmbedding, queryProvider)
+	if staleEmbeddings {
+		mm.scheduleRefresh(query.UserID)
+	}
+	return results, nil
+}
+
+// searchMemories scores a user's memories against the query and records access to the
+// ones returned; stale reports vectors from another provider that need re-embedding
+func (mm *MemoryManager) searchMemories(query *MemoryQuery, queryEmbedding []float64, queryProvider string) (results []*MemoryResult, stale bool) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
 
 	userStore, exists := mm.userMemories[query.UserID]
 	if !exists {
-		return []*MemoryResult{}, nil
+		return []*MemoryResult{}, false
 	}
 
-	var results []*MemoryResult
-
 	// Search through all memories
-	allMemories := append(userStore.ShortTermMemory, userStore.LongTermMemory...)
+	allMemories := make([]*Memory, 0, len(userStore.ShortTermMemory)+len(userStore.LongTermMemory))
+	allMemories = append(allMemories, userStore.ShortTermMemory...)
+	allMemories = append(allMemories, userStore.LongTermMemory...)
+	staleEmbeddings := false
+
+	// Large stores narrow candidates through the ANN index instead of scoring everything;
+	// without an index for the provider, or with no candidates, every memory is scored
+	var annMatches map[string]float64
+	if len(queryEmbedding) > 0 && len(allMemories) > mm.exhaustiveLimit {
+		k := query.Limit * 4
+		if k < 200 {
+			k = 200
+		}
+		matches := mm.memoryIndex.SearchSimilar(query.UserID, queryProvider, queryEmbedding, k)
+		if len(matches) > 0 {
+			annMatches = make(map[string]float64, len(matches))
+			for _, match := range matches {
+				annMatches[match.MemoryID] = match.Similarity
+			}
+		} else {
+			log.Debug().
+				Str("user_id", query.UserID).
+				Str("provider", queryProvider).
+				Int("memories", len(allMemories)).
+				Msg("No ANN candidates, falling back to an exhaustive scan")
+		}
+	}
 
 	for _, memory := range allMemories {
+		if annMatches != nil {
+			if _, candidate := annMatches[memory.ID]; !candidate {
+				continue
+			}
+		}
+
 		// Skip if memory doesn't match filters
 		if !mm.matchesQuery(memory, query) {
 			continue
@@ -289,11 +357,17 @@ func (mm *MemoryManager) RetrieveMemories(ctx context.Context, query *MemoryQuer
 			Relevance: mm.calculateRelevance(memory, query),
 		}
 
-		// Calculate similarity if content query provided
-		if query.Content != "" && len(memory.Embedding) > 0 {
-			queryEmbedding, err := mm.generateEmbedding(query.Content)
-			if err == nil {
-				result.Similarity = mm.calculateCosineSimilarity(queryEmbedding, memory.Embedding)
+		// Calculate similarity if content query provided; vectors from another
+		// provider are not comparable and get re-embedded in the background
+		if len(queryEmbedding) > 0 && len(memory.Embedding) > 0 {
+			if memory.EmbeddingModel != queryProvider || len(memory.Embedding) != len(queryEmbedding) {
+				staleEmbeddings = true
+			} else {
+				if similarity, found := annMatches[memory.ID]; found {
+					result.Similarity = similarity
+				} else {
+					result.Similarity = mm.calculateCosineSimilarity(queryEmbedding, memory.Embedding)
+				}
 
 				// Skip if below similarity threshold
 				if query.SimilarityThreshold > 0 && result.Similarity < query.SimilarityThreshold {
@@ -303,10 +377,6 @@ func (mm *MemoryManager) RetrieveMemories(ctx context.Context, query *MemoryQuer
 		}
 
 		results = append(results, result)
-
-		// Update access statistics
-		memory.LastAccessed = time.Now()
-		memory.AccessCount++
 	}
 
 	// Sort by relevance and similarity
@@ -321,7 +391,39 @@ func (mm *MemoryManager) RetrieveMemories(ctx context.Context, query *MemoryQuer
 		results = results[:query.Limit]
 	}
 
-	return results, nil
+	// Update access statistics
+	now := time.Now()
+	for _, result := range results {
+		result.Memory.LastAccessed = now
+		result.Memory.AccessCount++
+	}
+
+	return results, staleEmbeddings
+}
+
+// SetEmbeddingProvider switches the embedding backend; cached vectors from
+// the previous provider are dropped and stored memories re-embed lazily
+func (mm *MemoryManager) SetEmbeddingProvider(provider EmbeddingProvider) {
+	mm.embeddingMu.Lock()
+	defer mm.embeddingMu.Unlock()
+
+	previous := mm.embeddingProvider.Name()
+	mm.embeddingProvider = provider
+	if previous != provider.Name() {
+		mm.embeddingCache = make(map[string][]float64)
+	}
+
+	log.Info().
+		Str("previous", previous).
+		Str("provider", provider.Name()).
+		Msg("Embedding provider configured")
+}
+
+// GetEmbeddingProvider returns the active embedding provider
+func (mm *MemoryManager) GetEmbeddingProvider() EmbeddingProvider {
+	mm.embeddingMu.Lock()
+	defer mm.embeddingMu.Unlock()
+	return mm.embeddingProvider
 }
 
 func (mm *MemoryManager) GetUserMemories(userID string) ([]*Memory, error) {
@@ -346,6 +448,49 @@ func (mm *MemoryManager) GetAllUsers() []string {
 	return users
 }
 
+// RestoreMemories loads persisted memories into the stores and indexes. ANN graphs
+// with a snapshot are restored from it; all others are rebuilt by insertion.
+func (mm *MemoryManager) RestoreMemories(memories []*Memory, snapshots map[string]*HNSWSnapshot) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	byIndexKey := make(map[string][]*Memory)
+	for _, memory := range memories {
+		userStore := mm.getUserMemoryStore(memory.UserID)
+		if memory.Importance >= mm.importanceThreshold {
+			userStore.LongTermMemory = append(userStore.LongTermMemory, memory)
+		} else {
+			userStore.ShortTermMemory = append(userStore.ShortTermMemory, memory)
+		}
+		mm.storeSpecializedMemory(userStore, memory)
+		userStore.TotalMemories++
+
+		mm.memoryIndex.mu.Lock()
+		mm.memoryIndex.indexMetadata(memory)
+		mm.memoryIndex.mu.Unlock()
+
+		if len(memory.Embedding) > 0 {
+			key := vectorIndexKey(memory.UserID, memory.EmbeddingModel)
+			byIndexKey[key] = append(byIndexKey[key], memory)
+		}
+	}
+
+	for key, group := range byIndexKey {
+		if snapshot, exists := snapshots[key]; exists {
+			mm.memoryIndex.RestoreVectorIndex(key, snapshot, group)
+			continue
+		}
+		for _, memory := range group {
+			mm.memoryIndex.IndexEmbedding(memory)
+		}
+	}
+
+	log.Info().
+		Int("memories", len(memories)).
+		Int("vector_indexes", len(byIndexKey)).
+		Msg("Restored memories from disk")
+}
+
 // GetUserProfile builds a comprehensive user profile from memories
 func (mm *MemoryManager) GetUserProfile(userID string) (*UserProfile, error) {
 	mm.mu.RLock()
@@ -404,6 +549,8 @@ func (mm *MemoryManager) ConsolidateMemories(ctx context.Context, userID string)
 	for _, memory := range userStore.ShortTermMemory {
 		if !memory.IsConsolidated {
 			newShortTerm = append(newShortTerm, memory)
+		} else {
+			mm.memoryIndex.RemoveMemory(memory)
 		}
 	}
 	userStore.ShortTermMemory = newShortTerm
@@ -486,51 +633,125 @@ func (mm *MemoryManager) calculateImportance(memory *Memory) float64 {
 	return importance
 }
 
-func (mm *MemoryManager) generateEmbedding(content string) ([]float64, error) {
+// generateEmbedding embeds content with the active provider, falling back to
+// the hash embedder when it is unavailable. Returns the provider name used.
+func (mm *MemoryManager) generateEmbedding(ctx context.Context, content string) ([]float64, string, error) {
+	mm.embeddingMu.Lock()
+	provider := mm.embeddingProvider
+	fallback := mm.fallbackEmbedder
+	mm.embeddingMu.Unlock()
+
+	embedding, err := mm.embedWithProvider(ctx, provider, content)
+	if err == nil {
+		return embedding, provider.Name(), nil
+	}
+	if provider.Name() == fallback.Name() {
+		return nil, "", err
+	}
+
+	log.Warn().Err(err).Str("provider", provider.Name()).Msg("Embedding provider failed, using fallback")
+	embedding, err = mm.embedWithProvider(ctx, fallback, content)
+	if err != nil {
+		return nil, "", err
+	}
+	return embedding, fallback.Name(), nil
+}
+
+func (mm *MemoryManager) embedWithProvider(ctx context.Context, provider EmbeddingProvider, content string) ([]float64, error) {
 	// Check cache first
-	hash := mm.hashContent(content)
-	if embedding, exists := mm.embeddingCache[hash]; exists {
+	key := provider.Name() + ":" + mm.hashContent(content)
+	mm.embeddingMu.Lock()
+	if embedding, exists := mm.embeddingCache[key]; exists {
+		mm.embeddingMu.Unlock()
 		return embedding, nil
 	}
+	mm.embeddingMu.Unlock()
 
-	// Simple embedding generation (in practice, you'd use a real embedding model)
-	// This creates a basic word frequency vector
-	embedding := mm.createSimpleEmbedding(content)
+	embedding, err := provider.Embed(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+
+	mm.embeddingMu.Lock()
+	defer mm.embeddingMu.Unlock()
+
+	// A dimension change means the model behind the provider was swapped
+	if dims, tracked := mm.embeddingDims[provider.Name()]; tracked && dims != len(embedding) {
+		prefix := provider.Name() + ":"
+		for k := range mm.embeddingCache {
+			if strings.HasPrefix(k, prefix) {
+				delete(mm.embeddingCache, k)
+			}
+		}
+		log.Info().
+			Str("provider", provider.Name()).
+			Int("old_dimensions", dims).
+			Int("new_dimensions", len(embedding)).
+			Msg("Embedding dimensions changed, cache invalidated")
+	}
+	mm.embeddingDims[provider.Name()] = len(embedding)
 
 	// Cache the result
-	mm.embeddingCache[hash] = embedding
+	mm.embeddingCache[key] = embedding
 
 	return embedding, nil
 }
 
-func (mm *MemoryManager) createSimpleEmbedding(content string) []float64 {
-	// Simple word frequency embedding (300 dimensions)
-	embedding := make([]float64, 300)
-	words := strings.Fields(strings.ToLower(content))
-
-	for i, word := range words {
-		if i >= 300 {
-			break
-		}
-		// Simple hash-based embedding
-		hash := mm.simpleHash(word)
-		embedding[hash%300] += 1.0
+// scheduleRefresh re-embeds a user's stale memories in the background unless a refresh
+// for the user is already running
+func (mm *MemoryManager) scheduleRefresh(userID string) {
+	mm.refreshMu.Lock()
+	defer mm.refreshMu.Unlock()
+	if mm.refreshing[userID] {
+		return
 	}
+	mm.refreshing[userID] = true
+
+	go func() {
+		defer func() {
+			mm.refreshMu.Lock()
+			delete(mm.refreshing, userID)
+			mm.refreshMu.Unlock()
+		}()
+		mm.refreshEmbeddings(userID)
+	}()
+}
 
-	// Normalize
-	magnitude := 0.0
-	for _, val := range embedding {
-		magnitude += val * val
+// refreshEmbeddings re-embeds a user's memories produced by another provider
+func (mm *MemoryManager) refreshEmbeddings(userID string) {
+	memories, err := mm.GetUserMemories(userID)
+	if err != nil {
+		return
 	}
-	magnitude = math.Sqrt(magnitude)
 
-	if magnitude > 0 {
-		for i := range embedding {
-			embedding[i] /= magnitude
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	current := mm.GetEmbeddingProvider().Name()
+	refreshed := 0
+	for _, memory := range memories {
+		mm.mu.RLock()
+		stale := len(memory.Embedding) > 0 && memory.EmbeddingModel != current
+		content := memory.Content
+		mm.mu.RUnlock()
+		if !stale {
+			continue
 		}
+
+		embedding, provider, err := mm.generateEmbedding(ctx, content)
+		if err != nil || provider != current {
+			continue
+		}
+
+		mm.mu.Lock()
+		memory.Embedding = embedding
+		memory.EmbeddingModel = provider
+		mm.mu.Unlock()
+		mm.memoryIndex.IndexEmbedding(memory)
+		refreshed++
 	}
 
-	return embedding
+	log.Debug().Str("user_id", userID).Int("refreshed", refreshed).Msg("Refreshed memory embeddings")
 }
 
 func (mm *MemoryManager) calculateCosineSimilarity(a, b []float64) float64 {
@@ -569,17 +790,6 @@ func (mm *MemoryManager) hashContent(content string) string {
 	return hex.EncodeToString(hash[:])
 }
 
-func (mm *MemoryManager) simpleHash(s string) int {
-	hash := 0
-	for _, char := range s {
-		hash = hash*31 + int(char)
-	}
-	if hash < 0 {
-		hash = -hash
-	}
-	return hash
-}
-
 func (mm *MemoryManager) matchesQuery(memory *Memory, query *MemoryQuery) bool {
 	// Check memory types
 	if len(query.MemoryTypes) > 0 {
@@ -710,6 +920,8 @@ func (mm *MemoryManager) performWeeklyCleanup() {
 		for _, memory := range userStore.ShortTermMemory {
 			if memory.Importance > 0.2 || time.Since(memory.CreatedAt) < 30*24*time.Hour {
 				newShortTerm = append(newShortTerm, memory)
+			} else {
+				mm.memoryIndex.RemoveMemory(memory)
 			}
 		}
 		userStore.ShortTermMemory = newShortTerm
@@ -783,6 +995,8 @@ func NewMemoryIndex() *MemoryIndex {
 		embeddingIndex:  make(map[string]*EmbeddingEntry),
 		temporalIndex:   make(map[string][]string),
 		importanceIndex: make(map[float64][]string),
+		vectorIndexes:   make(map[string]*HNSWIndex),
+		vectorKeys:      make(map[string]string),
 	}
 }
 
@@ -790,6 +1004,13 @@ func (mi *MemoryIndex) IndexMemory(memory *Memory) {
 	mi.mu.Lock()
 	defer mi.mu.Unlock()
 
+	mi.indexMetadata(memory)
+
+	// Index embedding
+	mi.indexEmbedding(memory)
+}
+
+func (mi *MemoryIndex) indexMetadata(memory *Memory) {
 	// Index by tags
 	for _, tag := range memory.Tags {
 		mi.tagIndex[tag] = append(mi.tagIndex[tag], memory.ID)
@@ -805,21 +1026,137 @@ func (mi *MemoryIndex) IndexMemory(memory *Memory) {
 	// Index by importance (rounded to 1 decimal place)
 	importanceKey := math.Round(memory.Importance*10) / 10
 	mi.importanceIndex[importanceKey] = append(mi.importanceIndex[importanceKey], memory.ID)
+}
 
-	// Index embedding
-	if len(memory.Embedding) > 0 {
-		magnitude := 0.0
-		for _, val := range memory.Embedding {
-			magnitude += val * val
+// IndexEmbedding replaces the indexed embedding of an already indexed memory
+func (mi *MemoryIndex) IndexEmbedding(memory *Memory) {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	mi.indexEmbedding(memory)
+}
+
+func (mi *MemoryIndex) indexEmbedding(memory *Memory) {
+	if len(memory.Embedding) == 0 {
+		return
+	}
+
+	magnitude := 0.0
+	for _, val := range memory.Embedding {
+		magnitude += val * val
+	}
+	magnitude = math.Sqrt(magnitude)
+
+	mi.embeddingIndex[memory.ID] = &EmbeddingEntry{
+		MemoryID:  memory.ID,
+		Embedding: memory.Embedding,
+		Magnitude: magnitude,
+	}
+
+	mi.indexVector(memory)
+}
+
+// indexVector moves the memory's vector into the ANN index for its provider
+func (mi *MemoryIndex) indexVector(memory *Memory) {
+	key := vectorIndexKey(memory.UserID, memory.EmbeddingModel)
+	if previous, exists := mi.vectorKeys[memory.ID]; exists && previous != key {
+		if index, ok := mi.vectorIndexes[previous]; ok {
+			index.Delete(memory.ID)
+		}
+	}
+	index, exists := mi.vectorIndexes[key]
+	if !exists {
+		index = NewHNSWIndex(16, 200, 64)
+		mi.vectorIndexes[key] = index
+	}
+	if err := index.Insert(memory.ID, memory.Embedding); err != nil {
+		log.Warn().Err(err).Str("memory_id", memory.ID).Msg("Failed to add memory to vector index")
+		return
+	}
+	mi.vectorKeys[memory.ID] = key
+}
+
+// RemoveMemory drops a memory from every index
+func (mi *MemoryIndex) RemoveMemory(memory *Memory) {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	for _, tag := range memory.Tags {
+		mi.tagIndex[tag] = removeString(mi.tagIndex[tag], memory.ID)
+	}
+	mi.typeIndex[memory.MemoryType] = removeString(mi.typeIndex[memory.MemoryType], memory.ID)
+
+	dateKey := memory.CreatedAt.Format("2006-01-02")
+	mi.temporalIndex[dateKey] = removeString(mi.temporalIndex[dateKey], memory.ID)
+
+	importanceKey := math.Round(memory.Importance*10) / 10
+	mi.importanceIndex[importanceKey] = removeString(mi.importanceIndex[importanceKey], memory.ID)
+
+	delete(mi.embeddingIndex, memory.ID)
+	if key, exists := mi.vectorKeys[memory.ID]; exists {
+		if index, ok := mi.vectorIndexes[key]; ok {
+			index.Delete(memory.ID)
 		}
-		magnitude = math.Sqrt(magnitude)
+		delete(mi.vectorKeys, memory.ID)
+	}
+}
+
+// SearchSimilar returns the k nearest memories for a user from the ANN index
+func (mi *MemoryIndex) SearchSimilar(userID, provider string, query []float64, k int) []VectorMatch {
+	mi.mu.RLock()
+	index, exists := mi.vectorIndexes[vectorIndexKey(userID, provider)]
+	mi.mu.RUnlock()
 
-		mi.embeddingIndex[memory.ID] = &EmbeddingEntry{
-			MemoryID:  memory.ID,
-			Embedding: memory.Embedding,
-			Magnitude: magnitude,
+	if !exists {
+		return nil
+	}
+	return index.Search(query, k)
+}
+
+// VectorSnapshots exports all ANN graphs keyed by userID|provider
+func (mi *MemoryIndex) VectorSnapshots() map[string]*HNSWSnapshot {
+	mi.mu.RLock()
+	defer mi.mu.RUnlock()
+
+	snapshots := make(map[string]*HNSWSnapshot, len(mi.vectorIndexes))
+	for key, index := range mi.vectorIndexes {
+		if index.Len() > 0 {
+			snapshots[key] = index.Snapshot()
+		}
+	}
+	return snapshots
+}
+
+// RestoreVectorIndex installs a persisted graph, reconciled against the given memories
+func (mi *MemoryIndex) RestoreVectorIndex(key string, snapshot *HNSWSnapshot, memories []*Memory) {
+	mi.mu.Lock()
+	defer mi.mu.Unlock()
+
+	vectors := make(map[string][]float64, len(memories))
+	for _, memory := range memories {
+		if len(memory.Embedding) > 0 && vectorIndexKey(memory.UserID, memory.EmbeddingModel) == key {
+			vectors[memory.ID] = memory.Embedding
+			mi.vectorKeys[memory.ID] = key
+			mi.embeddingIndex[memory.ID] = &EmbeddingEntry{
+				MemoryID:  memory.ID,
+				Embedding: memory.Embedding,
+				Magnitude: math.Sqrt(dotProduct(memory.Embedding, memory.Embedding)),
+			}
 		}
 	}
+	mi.vectorIndexes[key] = RestoreHNSWIndex(snapshot, vectors)
+}
+
+func dotProduct(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func vectorIndexKey(userID, provider string) string {
+	return userID + "|" + provider
 }
 
 // Shutdown gracefully shuts down the memory manager
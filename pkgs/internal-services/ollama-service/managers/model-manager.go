@@ -15,14 +15,14 @@ import (
 	"sync"
 	"time"
 
-	"github.com/rs/zerolog/log"
 	// third-party
+	"github.com/rs/zerolog/log"
 )
 
 // ModelManager handles all model operations and lifecycle
 type ModelManager struct {
 	mu            sync.RWMutex
-	ollamaBaseURL string
+	backends      *BackendPool
 	client        *http.Client
 	loadedModels  map[string]*ModelInfo
 	modelStats    map[string]*ModelStats
@@ -44,6 +44,7 @@ type ModelInfo struct {
 	Status         string            `json:"status"` // loading, loaded, error, unloading
 	ErrorMsg       string            `json:"error_msg,omitempty"`
 	Parameters     map[string]string `json:"parameters"`
+	Backends       []string          `json:"backends"` // Ollama backends holding the model
 }
 
 // ModelStats tracks model usage statistics
@@ -55,6 +56,12 @@ type ModelStats struct {
 	LastError        string        `json:"last_error,omitempty"`
 	LastErrorAt      time.Time     `json:"last_error_at,omitempty"`
 	ThroughputPerSec float64       `json:"throughput_per_sec"`
+
+	// Response cache lookups; hits are not counted as requests
+	CacheHits         int64 `json:"cache_hits"`
+	SemanticCacheHits int64 `json:"semantic_cache_hits"` // subset of CacheHits
+	CacheMisses       int64 `json:"cache_misses"`
+	CachedTokens      int64 `json:"cached_tokens"` // tokens served without running the model
 }
 
 // ModelLoadRequest represents a request to load a model
@@ -97,11 +104,26 @@ type MemoryStats struct {
 	UsedVRAM  uint64 `json:"used_vram"`
 }
 
-// NewModelManager creates a new model manager
+// defaultBackendCheckInterval is how often backends are health-checked when server.yaml leaves it unset
+const defaultBackendCheckInterval = 15 * time.Second
+
+// NewModelManager creates a new model manager. The Ollama backends come from
+// server.yaml's ollama_backends; ollamaBaseURL is used when none are declared.
 func NewModelManager(ollamaBaseURL string, configManager *ConfigManager) *ModelManager {
+	client := &http.Client{Timeout: 300 * time.Second} // Extended timeout for model loading
+	backends := NewBackendPool(client)
+	if serverConfig, err := configManager.GetServerConfig(); err == nil {
+		for _, backend := range serverConfig.OllamaBackends {
+			backends.Add(backend)
+		}
+	}
+	if len(backends.Backends()) == 0 {
+		backends.Add(OllamaBackendConfig{Name: "default", URL: ollamaBaseURL})
+	}
+
 	mm := &ModelManager{
-		ollamaBaseURL: ollamaBaseURL,
-		client:        &http.Client{Timeout: 300 * time.Second}, // Extended timeout for model loading
+		backends:      backends,
+		client:        client,
 		loadedModels:  make(map[string]*ModelInfo),
 		modelStats:    make(map[string]*ModelStats),
 		configManager: configManager,
@@ -113,6 +135,7 @@ func NewModelManager(ollamaBaseURL string, configManager *ConfigManager) *ModelM
 	// Start background workers
 	go mm.processLoadQueue()
 	go mm.monitorModels()
+	go mm.monitorBackends()
 
 	return mm
 }
@@ -121,9 +144,18 @@ func NewModelManager(ollamaBaseURL string, configManager *ConfigManager) *ModelM
 func (mm *ModelManager) Initialize(ctx context.Context) error {
 	log.Info().Msg("Initializing model manager")
 
-	// Validate Ollama connection
-	if err := mm.pingOllama(ctx); err != nil {
-		return fmt.Errorf("failed to connect to Ollama: %w", err)
+	// Validate Ollama connection; one healthy backend is enough to serve
+	mm.backends.Refresh(ctx)
+	healthy := 0
+	for _, status := range mm.backends.Status() {
+		if status.Healthy {
+			healthy++
+			continue
+		}
+		log.Warn().Str("backend", status.Name).Str("error", status.LastError).Msg("Ollama backend unavailable")
+	}
+	if healthy == 0 {
+		return fmt.Errorf("failed to connect to Ollama: no healthy backend out of %d", len(mm.backends.Backends()))
 	}
 
 	// Get available models from Ollama
@@ -153,14 +185,15 @@ func (mm *ModelManager) Initialize(ctx context.Context) error {
 	return nil
 }
 
-// LoadModel loads a model into memory
+// LoadModel loads a model into memory on the backend best placed to serve it:
+// one that already has it warm, else the least busy healthy one
 func (mm *ModelManager) LoadModel(ctx context.Context, modelName string) error {
 	mm.mu.Lock()
 	defer mm.mu.Unlock()
 
 	// Check if already loaded
 	if info, exists := mm.loadedModels[modelName]; exists {
-		if info.Status == "loaded" {
+		if info.Status == "loaded" && (!info.Config.servedByOllama() || len(mm.backends.WarmBackends(modelName)) > 0) {
 			info.LastUsed = time.Now()
 			return nil
 		}
@@ -191,6 +224,14 @@ func (mm *ModelManager) LoadModel(ctx context.Context, modelName string) error {
 		mm.modelStats[modelName] = &ModelStats{}
 	}
 
+	// Other runtimes manage their own models; record them as available
+	if !modelConfig.servedByOllama() {
+		modelInfo.Status = "loaded"
+		modelInfo.Backends = []string{modelConfig.BackendURL}
+		log.Info().Str("model", modelName).Str("backend", modelConfig.BackendKind()).Msg("Registered model on external backend")
+		return nil
+	}
+
 	// Send load request to Ollama
 	req := &OllamaGenerateRequest{
 		Model:     modelName,
@@ -206,10 +247,41 @@ func (mm *ModelManager) LoadModel(ctx context.Context, modelName string) error {
 		return fmt.Errorf("failed to marshal load request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", mm.ollamaBaseURL+"/api/generate", bytes.NewBuffer(reqBody))
+	// Fall through to the next backend when one cannot load the model
+	var lastErr error
+	for _, backend := range mm.backends.Candidates(modelName) {
+		if err := mm.loadOn(ctx, backend, reqBody); err != nil {
+			if ctx.Err() != nil {
+				lastErr = err
+				break
+			}
+			log.Warn().Err(err).Str("model", modelName).Str("backend", backend.Name).Msg("Failed to load model on backend")
+			lastErr = err
+			continue
+		}
+
+		backend.MarkLoaded(modelName)
+		modelInfo.Status = "loaded"
+		modelInfo.Backends = []string{backend.Name}
+		log.Info().Str("model", modelName).Str("backend", backend.Name).Msg("Model loaded successfully")
+		return nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no Ollama backends configured")
+	}
+	modelInfo.Status = "error"
+	modelInfo.ErrorMsg = fmt.Sprintf("failed to load model: %v", lastErr)
+	return fmt.Errorf("failed to load model: %w", lastErr)
+}
+
+// loadOn sends a load request to one backend, marking it failed when unreachable
+func (mm *ModelManager) loadOn(ctx context.Context, backend *OllamaBackend, reqBody []byte) error {
+	done := backend.begin()
+	defer done()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", backend.URL+"/api/generate", bytes.NewBuffer(reqBody))
 	if err != nil {
-		modelInfo.Status = "error"
-		modelInfo.ErrorMsg = fmt.Sprintf("failed to create request: %v", err)
 		return fmt.Errorf("failed to create load request: %w", err)
 	}
 
@@ -217,25 +289,20 @@ func (mm *ModelManager) LoadModel(ctx context.Context, modelName string) error {
 
 	resp, err := mm.client.Do(httpReq)
 	if err != nil {
-		modelInfo.Status = "error"
-		modelInfo.ErrorMsg = fmt.Sprintf("failed to load model: %v", err)
-		return fmt.Errorf("failed to load model: %w", err)
+		if ctx.Err() == nil {
+			backend.MarkFailed(err)
+		}
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		modelInfo.Status = "error"
-		modelInfo.ErrorMsg = fmt.Sprintf("load request failed with status: %d", resp.StatusCode)
 		return fmt.Errorf("load request failed with status: %d", resp.StatusCode)
 	}
-
-	modelInfo.Status = "loaded"
-	log.Info().Str("model", modelName).Msg("Model loaded successfully")
-
 	return nil
 }
 
-// UnloadModel unloads a model from memory
+// UnloadModel unloads a model from memory on every backend holding it
 func (mm *ModelManager) UnloadModel(ctx context.Context, modelName string) error {
 	mm.mu.Lock()
 	defer mm.mu.Unlock()
@@ -245,6 +312,11 @@ func (mm *ModelManager) UnloadModel(ctx context.Context, modelName string) error
 		return fmt.Errorf("model not loaded: %s", modelName)
 	}
 
+	if !modelInfo.Config.servedByOllama() {
+		delete(mm.loadedModels, modelName)
+		return nil
+	}
+
 	modelInfo.Status = "unloading"
 
 	// Send unload request to Ollama (set keep_alive to 0)
@@ -258,7 +330,31 @@ func (mm *ModelManager) UnloadModel(ctx context.Context, modelName string) error
 		return fmt.Errorf("failed to marshal unload request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", mm.ollamaBaseURL+"/api/generate", bytes.NewBuffer(reqBody))
+	var lastErr error
+	for _, backend := range mm.backends.Backends() {
+		if !backend.HasModel(modelName) && !containsString(modelInfo.Backends, backend.Name) {
+			continue
+		}
+		if err := mm.unloadOn(ctx, backend, reqBody); err != nil {
+			log.Warn().Err(err).Str("model", modelName).Str("backend", backend.Name).Msg("Failed to unload model on backend")
+			lastErr = err
+			continue
+		}
+		backend.MarkUnloaded(modelName)
+	}
+	if lastErr != nil {
+		return fmt.Errorf("failed to unload model: %w", lastErr)
+	}
+
+	delete(mm.loadedModels, modelName)
+	log.Info().Str("model", modelName).Msg("Model unloaded successfully")
+
+	return nil
+}
+
+// unloadOn sends an unload request to one backend
+func (mm *ModelManager) unloadOn(ctx context.Context, backend *OllamaBackend, reqBody []byte) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", backend.URL+"/api/generate", bytes.NewBuffer(reqBody))
 	if err != nil {
 		return fmt.Errorf("failed to create unload request: %w", err)
 	}
@@ -267,13 +363,9 @@ func (mm *ModelManager) UnloadModel(ctx context.Context, modelName string) error
 
 	resp, err := mm.client.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("failed to unload model: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
-
-	delete(mm.loadedModels, modelName)
-	log.Info().Str("model", modelName).Msg("Model unloaded successfully")
-
+	resp.Body.Close()
 	return nil
 }
 
@@ -341,28 +433,28 @@ func (mm *ModelManager) RecordModelUsage(modelName string, tokens int64, latency
 	}
 }
 
-// GetBestModel returns the best model for a given specialization
-func (mm *ModelManager) GetBestModel(specialization string) (string, error) {
-	mm.mu.RLock()
-	defer mm.mu.RUnlock()
-
-	var bestModel string
-	var bestPriority int = -1
+// RecordCacheLookup records a response cache lookup for a model; layer is
+// exact or semantic for a hit and empty for a miss
+func (mm *ModelManager) RecordCacheLookup(modelName, layer string, tokens int64) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
 
-	for name, info := range mm.loadedModels {
-		if info.Status == "loaded" &&
-			(info.Specialization == specialization || specialization == "") &&
-			info.Priority > bestPriority {
-			bestModel = name
-			bestPriority = info.Priority
-		}
+	stats, exists := mm.modelStats[modelName]
+	if !exists {
+		stats = &ModelStats{}
+		mm.modelStats[modelName] = stats
 	}
 
-	if bestModel == "" {
-		return "", fmt.Errorf("no suitable model found for specialization: %s", specialization)
+	switch layer {
+	case "":
+		stats.CacheMisses++
+	case "semantic":
+		stats.SemanticCacheHits++
+		fallthrough
+	default:
+		stats.CacheHits++
+		stats.CachedTokens += tokens
 	}
-
-	return bestModel, nil
 }
 
 // GetMemoryStats returns current memory usage
@@ -380,29 +472,58 @@ func (mm *ModelManager) GetMemoryStats() *MemoryStats {
 	}
 }
 
-// pingOllama checks if Ollama is accessible
-func (mm *ModelManager) pingOllama(ctx context.Context) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", mm.ollamaBaseURL+"/api/tags", nil)
-	if err != nil {
-		return err
-	}
+// ListAvailableModels returns the models installed on any healthy Ollama backend
+func (mm *ModelManager) ListAvailableModels(ctx context.Context) (*OllamaListResponse, error) {
+	return mm.listAvailableModels(ctx)
+}
 
-	resp, err := mm.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+// Backends returns the Ollama backend pool requests are routed through
+func (mm *ModelManager) Backends() *BackendPool {
+	return mm.backends
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("ollama returned status: %d", resp.StatusCode)
+// GetBackendStatus returns the health and resident models of every Ollama backend
+func (mm *ModelManager) GetBackendStatus() []BackendStatus {
+	return mm.backends.Status()
+}
+
+func (mm *ModelManager) listAvailableModels(ctx context.Context) (*OllamaListResponse, error) {
+	var merged OllamaListResponse
+	seen := make(map[string]bool)
+	var lastErr error
+	listed := 0
+
+	for _, backend := range mm.backends.Backends() {
+		if !backend.Healthy() {
+			continue
+		}
+		listResp, err := mm.listBackendModels(ctx, backend)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		listed++
+		for _, model := range listResp.Models {
+			if seen[model.Name] {
+				continue
+			}
+			seen[model.Name] = true
+			merged.Models = append(merged.Models, model)
+		}
 	}
 
-	return nil
+	if listed == 0 {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no healthy Ollama backend")
+		}
+		return nil, lastErr
+	}
+	return &merged, nil
 }
 
-// listAvailableModels gets available models from Ollama
-func (mm *ModelManager) listAvailableModels(ctx context.Context) (*OllamaListResponse, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", mm.ollamaBaseURL+"/api/tags", nil)
+// listBackendModels gets the models installed on one backend
+func (mm *ModelManager) listBackendModels(ctx context.Context, backend *OllamaBackend) (*OllamaListResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", backend.URL+"/api/tags", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -457,6 +578,46 @@ func (mm *ModelManager) monitorModels() {
 	}
 }
 
+// monitorBackends periodically health-checks the Ollama backends and refreshes
+// which backends hold each loaded model
+func (mm *ModelManager) monitorBackends() {
+	interval := defaultBackendCheckInterval
+	if serverConfig, err := mm.configManager.GetServerConfig(); err == nil && serverConfig.BackendCheckInterval > 0 {
+		interval = time.Duration(serverConfig.BackendCheckInterval) * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			mm.backends.Refresh(ctx)
+			cancel()
+			mm.syncModelBackends()
+		case <-mm.shutdown:
+			return
+		}
+	}
+}
+
+// syncModelBackends records which backends hold each loaded model after a refresh
+func (mm *ModelManager) syncModelBackends() {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+
+	for name, info := range mm.loadedModels {
+		if !info.Config.servedByOllama() {
+			continue
+		}
+		var holders []string
+		for _, backend := range mm.backends.WarmBackends(name) {
+			holders = append(holders, backend.Name)
+		}
+		info.Backends = holders
+	}
+}
+
 // cleanupUnusedModels unloads models that haven't been used recently
 func (mm *ModelManager) cleanupUnusedModels() {
 	mm.mu.Lock()
@@ -0,0 +1,351 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/model-routing.go
+
+package managers
+
+import (
+	// stdlib
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Routing intents a request can be routed by
+const (
+	RouteIntentChat      = "chat"
+	RouteIntentCode      = "code"
+	RouteIntentReasoning = "reasoning"
+	RouteIntentAnalysis  = "analysis"
+)
+
+// Routing score weights and thresholds, used when models.yaml leaves a policy field unset
+const (
+	routePriorityWeight    = 10.0 // per point of Priority
+	routeLoadedBonus       = 5.0  // already loaded models skip the load time
+	routeLatencyWeight     = 2.0  // per second of observed average latency
+	routeErrorWeight       = 20.0 // per unit of observed error rate
+	routeCostWeight        = 1.0  // per unit of cost_per_1k_tokens, times up to 5 as the budget runs out
+	routeMinStatsSamples   = 5    // requests observed before the error rate is trusted
+	defaultRouteMaxErrRate = 0.5
+)
+
+// ModelRoutingPolicy is the routing block of a model in models.yaml
+type ModelRoutingPolicy struct {
+	Intents         []string `yaml:"intents"`            // intents served besides the model's specialization
+	CostPer1KTokens float64  `yaml:"cost_per_1k_tokens"` // relative cost; weighs more as the user's budget runs low
+	MaxLatencyMs    int      `yaml:"max_latency_ms"`     // skipped while the observed average latency is higher
+	MaxErrorRate    float64  `yaml:"max_error_rate"`     // skipped while more requests fail; 0.5 when unset
+	MinBudgetRatio  float64  `yaml:"min_budget_ratio"`   // only chosen while this share of the user's budget is left
+	Exclude         bool     `yaml:"exclude"`            // never chosen automatically, only when requested or pinned
+}
+
+// RoutingDecision is the model chosen for a request and why
+type RoutingDecision struct {
+	Model        string              `json:"model"`
+	Intent       string              `json:"intent"`
+	Pinned       bool                `json:"pinned"`
+	PromptTokens int                 `json:"prompt_tokens"`
+	BudgetRatio  float64             `json:"budget_ratio"` // share of the user's token budget left
+	Reason       string              `json:"reason"`
+	Candidates   []*RoutingCandidate `json:"candidates"`
+}
+
+// RoutingCandidate is a model the router considered, best score first
+type RoutingCandidate struct {
+	Model    string   `json:"model"`
+	Eligible bool     `json:"eligible"`
+	Score    float64  `json:"score"`
+	Reasons  []string `json:"reasons"`
+}
+
+// RoutingEngine picks the model for requests that do not name one, weighing the
+// request's intent and length against each model's context window, observed latency
+// and error rate, cost and the user's remaining budget. Users can pin a model per intent.
+type RoutingEngine struct {
+	mu            sync.RWMutex
+	configManager *ConfigManager
+	modelManager  *ModelManager
+	tokenManager  *TokenManager
+	pins          map[string]map[string]string // user -> intent ("" for all) -> model
+}
+
+// NewRoutingEngine creates a routing engine reading policies from models.yaml on each decision
+func NewRoutingEngine(configManager *ConfigManager, modelManager *ModelManager, tokenManager *TokenManager) *RoutingEngine {
+	return &RoutingEngine{
+		configManager: configManager,
+		modelManager:  modelManager,
+		tokenManager:  tokenManager,
+		pins:          make(map[string]map[string]string),
+	}
+}
+
+// PinModel makes model the choice for a user's requests with intent, or all intents when
+// intent is empty
+func (re *RoutingEngine) PinModel(userID, intent, model string) error {
+	if userID == "" {
+		return fmt.Errorf("user is required")
+	}
+	if _, err := re.configManager.GetModelConfig(model); err != nil {
+		return err
+	}
+
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	if re.pins[userID] == nil {
+		re.pins[userID] = make(map[string]string)
+	}
+	re.pins[userID][intent] = model
+	return nil
+}
+
+// UnpinModel removes a user's pin for intent
+func (re *RoutingEngine) UnpinModel(userID, intent string) {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	delete(re.pins[userID], intent)
+	if len(re.pins[userID]) == 0 {
+		delete(re.pins, userID)
+	}
+}
+
+// Pins returns a user's pinned models by intent
+func (re *RoutingEngine) Pins(userID string) map[string]string {
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+	pins := make(map[string]string, len(re.pins[userID]))
+	for intent, model := range re.pins[userID] {
+		pins[intent] = model
+	}
+	return pins
+}
+
+// Route chooses a model for req without changing it; a request naming a model keeps it
+func (re *RoutingEngine) Route(req *InferenceRequest) (*RoutingDecision, error) {
+	decision := &RoutingDecision{
+		Intent:      routeIntent(req),
+		BudgetRatio: re.budgetRatio(req.UserID),
+	}
+	if req.ModelName != "" {
+		decision.Model = req.ModelName
+		decision.Pinned = true
+		decision.Reason = "model requested explicitly"
+		return decision, nil
+	}
+
+	configs, err := re.configManager.GetModelConfigs()
+	if err != nil {
+		return nil, err
+	}
+	decision.PromptTokens = re.promptTokens(req)
+
+	re.mu.RLock()
+	pinned, ok := re.pins[req.UserID][decision.Intent]
+	if !ok {
+		pinned, ok = re.pins[req.UserID][""]
+	}
+	re.mu.RUnlock()
+
+	// Score the models serving the intent; reasoning and then chat models stand in
+	// when none of them is eligible
+	intents := []string{decision.Intent}
+	if decision.Intent == RouteIntentAnalysis {
+		intents = append(intents, RouteIntentReasoning)
+	}
+	if decision.Intent != RouteIntentChat {
+		intents = append(intents, RouteIntentChat)
+	}
+	scored := make(map[string]bool)
+	for _, intent := range intents {
+		eligible := false
+		for i := range configs {
+			if scored[configs[i].Name] || !(servesIntent(&configs[i], intent) || configs[i].Name == pinned) {
+				continue
+			}
+			scored[configs[i].Name] = true
+			candidate := re.scoreModel(&configs[i], decision)
+			decision.Candidates = append(decision.Candidates, candidate)
+			eligible = eligible || candidate.Eligible
+		}
+		if eligible {
+			if intent != decision.Intent {
+				decision.Reason = fmt.Sprintf("no eligible model serves %s, using %s models; ", decision.Intent, intent)
+			}
+			break
+		}
+	}
+	sort.SliceStable(decision.Candidates, func(i, j int) bool {
+		a, b := decision.Candidates[i], decision.Candidates[j]
+		if a.Eligible != b.Eligible {
+			return a.Eligible
+		}
+		return a.Score > b.Score
+	})
+
+	// A pin wins unless the model cannot hold the prompt
+	if ok {
+		for i := range configs {
+			if configs[i].Name == pinned && (configs[i].ContextWindow == 0 || decision.PromptTokens <= configs[i].ContextWindow) {
+				decision.Model = pinned
+				decision.Pinned = true
+				decision.Reason += "pinned by the user"
+				return decision, nil
+			}
+		}
+	}
+
+	if len(decision.Candidates) == 0 || !decision.Candidates[0].Eligible {
+		var skipped []string
+		for _, candidate := range decision.Candidates {
+			skipped = append(skipped, fmt.Sprintf("%s: %s", candidate.Model, strings.Join(candidate.Reasons, ", ")))
+		}
+		if len(skipped) == 0 {
+			return nil, fmt.Errorf("no model configured for intent: %s", decision.Intent)
+		}
+		return nil, fmt.Errorf("no model can serve intent %s (%s)", decision.Intent, strings.Join(skipped, "; "))
+	}
+
+	best := decision.Candidates[0]
+	decision.Model = best.Model
+	decision.Reason += fmt.Sprintf("highest score %.1f: %s", best.Score, strings.Join(best.Reasons, ", "))
+	return decision, nil
+}
+
+// scoreModel applies a model's policy to the request. Reasons read as an explanation:
+// the first one of an ineligible model says why it was skipped.
+func (re *RoutingEngine) scoreModel(config *ModelConfig, decision *RoutingDecision) *RoutingCandidate {
+	candidate := &RoutingCandidate{Model: config.Name, Eligible: true}
+	policy := config.Routing
+	skip := func(reason string, args ...interface{}) {
+		if candidate.Eligible {
+			candidate.Reasons = append([]string{fmt.Sprintf(reason, args...)}, candidate.Reasons...)
+		}
+		candidate.Eligible = false
+	}
+	note := func(delta float64, reason string, args ...interface{}) {
+		candidate.Score += delta
+		candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("%s (%+.1f)", fmt.Sprintf(reason, args...), delta))
+	}
+
+	if config.ContextWindow > 0 && decision.PromptTokens > config.ContextWindow {
+		skip("prompt needs %d tokens, context window is %d", decision.PromptTokens, config.ContextWindow)
+	}
+	if policy.Exclude {
+		skip("excluded from automatic routing")
+	}
+	if policy.MinBudgetRatio > 0 && decision.BudgetRatio < policy.MinBudgetRatio {
+		skip("%.0f%% of the budget left, needs %.0f%%", decision.BudgetRatio*100, policy.MinBudgetRatio*100)
+	}
+
+	note(float64(config.Priority)*routePriorityWeight, "priority %d", config.Priority)
+	if info, ok := re.modelManager.GetModelInfo(config.Name); ok && info.Status == "loaded" {
+		note(routeLoadedBonus, "loaded")
+	}
+
+	if stats, ok := re.modelManager.GetModelStats(config.Name); ok {
+		if stats.AverageLatency > 0 {
+			latencyMs := stats.AverageLatency.Milliseconds()
+			if policy.MaxLatencyMs > 0 && latencyMs > int64(policy.MaxLatencyMs) {
+				skip("average latency %dms exceeds %dms", latencyMs, policy.MaxLatencyMs)
+			}
+			note(-stats.AverageLatency.Seconds()*routeLatencyWeight, "average latency %dms", latencyMs)
+		}
+		if stats.RequestCount >= routeMinStatsSamples {
+			errorRate := float64(stats.ErrorCount) / float64(stats.RequestCount)
+			maxErrorRate := policy.MaxErrorRate
+			if maxErrorRate <= 0 {
+				maxErrorRate = defaultRouteMaxErrRate
+			}
+			if errorRate > maxErrorRate {
+				skip("error rate %.0f%% exceeds %.0f%%", errorRate*100, maxErrorRate*100)
+			}
+			if errorRate > 0 {
+				note(-errorRate*routeErrorWeight, "error rate %.0f%%", errorRate*100)
+			}
+		}
+	}
+
+	if policy.CostPer1KTokens > 0 {
+		pressure := 1 + 4*(1-decision.BudgetRatio)
+		note(-policy.CostPer1KTokens*routeCostWeight*pressure, "cost %.2f per 1k tokens at %.0f%% budget", policy.CostPer1KTokens, decision.BudgetRatio*100)
+	}
+	return candidate
+}
+
+// promptTokens estimates the context a request needs: its messages plus the reply
+func (re *RoutingEngine) promptTokens(req *InferenceRequest) int {
+	tokens := 0
+	for _, msg := range req.Messages {
+		tokens += re.tokenManager.EstimateTokens(msg.Content, "")
+	}
+	if req.Parameters != nil {
+		tokens += req.Parameters.MaxTokens
+	}
+	return tokens
+}
+
+// budgetRatio is the share of the user's token budget left, 1 when unlimited
+func (re *RoutingEngine) budgetRatio(userID string) float64 {
+	if userID == "" {
+		return 1
+	}
+	budget, err := re.tokenManager.GetUserBudget(userID)
+	if err != nil || budget.IsUnlimited || budget.TotalBudget <= 0 {
+		return 1
+	}
+	ratio := float64(budget.RemainingTokens) / float64(budget.TotalBudget)
+	if ratio < 0 {
+		return 0
+	}
+	return ratio
+}
+
+// routeIntent derives the routing intent from the request type; chat requests
+// carrying code blocks are routed as code
+func routeIntent(req *InferenceRequest) string {
+	switch req.RequestType {
+	case InferenceTypeCode:
+		return RouteIntentCode
+	case InferenceTypeReasoning:
+		return RouteIntentReasoning
+	case InferenceTypeAnalysis:
+		return RouteIntentAnalysis
+	}
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			if strings.Contains(req.Messages[i].Content, "```") {
+				return RouteIntentCode
+			}
+			break
+		}
+	}
+	return RouteIntentChat
+}
+
+// servesIntent reports whether a model's specialization or routing intents cover intent
+func servesIntent(config *ModelConfig, intent string) bool {
+	return config.Specialization == intent || containsString(config.Routing.Intents, intent)
+}
+
+// validateRoutingPolicy checks a model's routing block in models.yaml
+func validateRoutingPolicy(config *ModelConfig) error {
+	policy := config.Routing
+	for _, intent := range policy.Intents {
+		switch intent {
+		case RouteIntentChat, RouteIntentCode, RouteIntentReasoning, RouteIntentAnalysis:
+		default:
+			return fmt.Errorf("model %s routes unknown intent: %s", config.Name, intent)
+		}
+	}
+	if policy.MaxErrorRate < 0 || policy.MaxErrorRate > 1 {
+		return fmt.Errorf("invalid max_error_rate for model %s: %f", config.Name, policy.MaxErrorRate)
+	}
+	if policy.MinBudgetRatio < 0 || policy.MinBudgetRatio > 1 {
+		return fmt.Errorf("invalid min_budget_ratio for model %s: %f", config.Name, policy.MinBudgetRatio)
+	}
+	if policy.CostPer1KTokens < 0 || policy.MaxLatencyMs < 0 {
+		return fmt.Errorf("invalid routing costs for model %s", config.Name)
+	}
+	return nil
+}
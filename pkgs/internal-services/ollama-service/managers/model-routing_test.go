@@ -0,0 +1,168 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/model-routing_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// routingFixture is a routing engine over the given models.yaml entries
+func routingFixture(t *testing.T, models ...ModelConfig) (*RoutingEngine, *ModelManager, *TokenManager) {
+	t.Helper()
+	ollama := newFakeOllama(t, "ollama")
+	cm := schedulerConfig(&LimitsConfig{MaxRequestsPerMinute: 100, MaxTokensPerRequest: 4096, TokenBudgetPerUser: 100000, ResetIntervalHours: 24}, models...)
+	mm := NewModelManager(ollama.URL, cm)
+	t.Cleanup(func() { mm.Shutdown(context.Background()) })
+	tm := NewTokenManager(cm)
+	return NewRoutingEngine(cm, mm, tm), mm, tm
+}
+
+func routeRequest(requestType InferenceType, content string) *InferenceRequest {
+	return &InferenceRequest{UserID: "alice", RequestType: requestType, Messages: []Message{{Role: "user", Content: content}}}
+}
+
+func TestRoutingByIntentAndContext(t *testing.T) {
+	router, _, _ := routingFixture(t,
+		ModelConfig{Name: "small-chat", Specialization: "chat", Priority: 3, ContextWindow: 50},
+		ModelConfig{Name: "big-chat", Specialization: "chat", Priority: 1, ContextWindow: 32000},
+		ModelConfig{Name: "coder", Specialization: "code", Priority: 1, Routing: ModelRoutingPolicy{Intents: []string{RouteIntentReasoning}}},
+		ModelConfig{Name: "embedder", Specialization: "embedding", Priority: 9},
+	)
+
+	for name, tc := range map[string]struct {
+		req    *InferenceRequest
+		model  string
+		reason string
+	}{
+		"chat":                   {req: routeRequest(InferenceTypeChat, "hi"), model: "small-chat", reason: "priority 3"},
+		"code":                   {req: routeRequest(InferenceTypeCode, "hi"), model: "coder"},
+		"code block":             {req: routeRequest(InferenceTypeChat, "why?\n```go\nx := 1\n```"), model: "coder"},
+		"routing intents":        {req: routeRequest(InferenceTypeReasoning, "hi"), model: "coder"},
+		"reasoning for analysis": {req: routeRequest(InferenceTypeAnalysis, "hi"), model: "coder"},
+		"context window":         {req: routeRequest(InferenceTypeChat, strings.Repeat("word ", 200)), model: "big-chat"},
+		"explicit request":       {req: &InferenceRequest{ModelName: "embedder"}, model: "embedder", reason: "requested explicitly"},
+	} {
+		decision, err := router.Route(tc.req)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if decision.Model != tc.model || !strings.Contains(decision.Reason, tc.reason) {
+			t.Errorf("%s: decision = %+v", name, decision)
+		}
+	}
+
+	decision, _ := router.Route(routeRequest(InferenceTypeChat, strings.Repeat("word ", 200)))
+	if skipped := decision.Candidates[1]; skipped.Model != "small-chat" || skipped.Eligible || !strings.Contains(skipped.Reasons[0], "context window is 50") {
+		t.Errorf("skipped = %+v", skipped)
+	}
+	decision, _ = router.Route(routeRequest(InferenceTypeCompletion, "hi"))
+	if len(decision.Candidates) != 2 {
+		t.Errorf("candidates = %+v", decision.Candidates)
+	}
+	if _, err := router.Route(&InferenceRequest{Messages: []Message{{Role: "user", Content: strings.Repeat("word ", 50000)}}}); err == nil || !strings.Contains(err.Error(), "no model can serve intent chat") {
+		t.Errorf("oversized prompt err = %v", err)
+	}
+}
+
+func TestRoutingByHealthAndBudget(t *testing.T) {
+	router, mm, tm := routingFixture(t,
+		ModelConfig{Name: "fast", Specialization: "chat", Priority: 2, Routing: ModelRoutingPolicy{MaxLatencyMs: 2000}},
+		ModelConfig{Name: "premium", Specialization: "chat", Priority: 3, Routing: ModelRoutingPolicy{CostPer1KTokens: 3, MinBudgetRatio: 0.2}},
+		ModelConfig{Name: "backup", Specialization: "chat", Priority: 1},
+	)
+	route := func() *RoutingDecision {
+		t.Helper()
+		decision, err := router.Route(routeRequest(InferenceTypeChat, "hello"))
+		if err != nil {
+			t.Fatalf("Route: %v", err)
+		}
+		return decision
+	}
+
+	// Priority outweighs the premium model's cost while the budget is full
+	if decision := route(); decision.Model != "premium" || decision.BudgetRatio != 1 {
+		t.Errorf("full budget = %+v", decision)
+	}
+
+	// Cost weighs more as the budget runs low, and a nearly spent budget rules the model out
+	tm.SetUserBudget("alice", &UserTokenBudget{TotalBudget: 1000, UsedTokens: 600})
+	if decision := route(); decision.Model != "fast" {
+		t.Errorf("40%% budget = %+v", decision)
+	}
+	tm.SetUserBudget("alice", &UserTokenBudget{TotalBudget: 1000, UsedTokens: 900})
+	decision := route()
+	for _, candidate := range decision.Candidates {
+		if candidate.Model == "premium" && (candidate.Eligible || !strings.Contains(candidate.Reasons[0], "10% of the budget left")) {
+			t.Errorf("premium = %+v", candidate)
+		}
+	}
+
+	// Slow and failing models are skipped
+	mm.RecordModelUsage("fast", 10, 3*time.Second, nil)
+	if decision := route(); decision.Model != "backup" {
+		t.Errorf("slow fast = %+v", decision)
+	}
+	tm.SetUserBudget("alice", &UserTokenBudget{TotalBudget: 1000})
+	for i := 0; i < routeMinStatsSamples; i++ {
+		mm.RecordModelUsage("premium", 0, 0, errors.New("boom"))
+	}
+	if decision := route(); decision.Model != "backup" || strings.Contains(decision.Reason, "error rate") {
+		t.Errorf("failing premium = %+v", decision)
+	}
+}
+
+func TestRoutingPins(t *testing.T) {
+	router, _, _ := routingFixture(t,
+		ModelConfig{Name: "chat-a", Specialization: "chat", Priority: 3},
+		ModelConfig{Name: "chat-b", Specialization: "chat", Priority: 1, ContextWindow: 100},
+		ModelConfig{Name: "coder", Specialization: "code", Routing: ModelRoutingPolicy{Exclude: true}},
+	)
+
+	if err := router.PinModel("alice", "", "missing"); err == nil {
+		t.Error("pinned an unknown model")
+	}
+	router.PinModel("alice", RouteIntentChat, "chat-b")
+	router.PinModel("alice", "", "coder")
+	if pins := router.Pins("alice"); len(pins) != 2 || pins[RouteIntentChat] != "chat-b" {
+		t.Errorf("pins = %v", pins)
+	}
+
+	// Pins win for their intent, the catch-all pin covers the rest, even excluded models
+	if decision, _ := router.Route(routeRequest(InferenceTypeChat, "hi")); decision.Model != "chat-b" || !decision.Pinned {
+		t.Errorf("chat = %+v", decision)
+	}
+	if decision, _ := router.Route(routeRequest(InferenceTypeReasoning, "hi")); decision.Model != "coder" || !decision.Pinned {
+		t.Errorf("reasoning = %+v", decision)
+	}
+	// ...unless the pinned model cannot hold the prompt
+	if decision, _ := router.Route(routeRequest(InferenceTypeChat, strings.Repeat("word ", 200))); decision.Model != "chat-a" || decision.Pinned {
+		t.Errorf("long chat = %+v", decision)
+	}
+	// Other users are not affected and excluded models are not chosen automatically
+	bob := routeRequest(InferenceTypeCode, "hi")
+	bob.UserID = "bob"
+	if decision, _ := router.Route(bob); decision.Model != "chat-a" || !strings.Contains(decision.Reason, "no eligible model serves code") {
+		t.Errorf("bob = %+v", decision)
+	}
+
+	router.UnpinModel("alice", RouteIntentChat)
+	router.UnpinModel("alice", "")
+	if decision, _ := router.Route(routeRequest(InferenceTypeChat, "hi")); decision.Model != "chat-a" || len(router.Pins("alice")) != 0 {
+		t.Errorf("unpinned = %+v", decision)
+	}
+
+	if err := validateRoutingPolicy(&ModelConfig{Name: "m", Routing: ModelRoutingPolicy{Intents: []string{"poetry"}}}); err == nil {
+		t.Error("unknown routing intent accepted")
+	}
+	if err := validateRoutingPolicy(&ModelConfig{Name: "m", Routing: ModelRoutingPolicy{MaxErrorRate: 2}}); err == nil {
+		t.Error("error rate above 1 accepted")
+	}
+}
@@ -0,0 +1,290 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/prompt-templates.go
+
+package managers
+
+import (
+	// stdlib
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// Built-in templates, used unless prompt-templates.yaml defines one with the same name
+const (
+	SessionSystemTemplate      = "session-system"
+	ConversationSystemTemplate = "conversation-system"
+)
+
+var builtinPromptTemplates = []PromptTemplate{
+	{
+		Name:        SessionSystemTemplate,
+		Category:    "system",
+		Description: "Default system prompt for new sessions",
+		Template: `{{block "identity" .}}You are a helpful AI assistant using the {{.Model}} model.{{end}}` +
+			`{{block "style" .}}{{with .User}} The user prefers a {{.CommunicationStyle}} communication style.{{end}}{{end}}` +
+			`{{block "context" .}}{{end}}`,
+	},
+	{
+		Name:        ConversationSystemTemplate,
+		Category:    "system",
+		Description: "Default system prompt for conversation turns",
+		Template: `{{block "identity" .}}You are a helpful AI assistant.{{end}}` +
+			`{{with .Conversation}} The user's current intent is {{.Intent}}.` +
+			`{{with .Topic}} The conversation topic is: {{.}}.{{end}}` +
+			`{{with .UserGoals}} The user's goals are: {{join . ", "}}.{{end}}{{end}}`,
+	},
+}
+
+// PromptData is what templates render against
+type PromptData struct {
+	Model        string                 `json:"model"`
+	Persona      *PersonaConfig         `json:"persona,omitempty"`
+	User         *UserProfile           `json:"user,omitempty"`
+	Code         *CodeContext           `json:"code,omitempty"`
+	Project      *ProjectContext        `json:"project,omitempty"`
+	Conversation *ConversationContext   `json:"conversation,omitempty"`
+	Vars         map[string]interface{} `json:"vars,omitempty"` // values for a template's declared variables
+	Now          time.Time              `json:"-"`
+}
+
+// promptFuncs are available to every template
+var promptFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+	"default": func(fallback, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// PromptEngine renders prompt templates and personas from configs/model. A
+// template may extend another: it inherits the parent's text and overrides the
+// parent's {{block}} sections with {{define}}, or replaces the body outright.
+type PromptEngine struct {
+	configManager *ConfigManager
+}
+
+// NewPromptEngine creates an engine reading templates from the config manager on each render
+func NewPromptEngine(configManager *ConfigManager) *PromptEngine {
+	return &PromptEngine{configManager: configManager}
+}
+
+// Templates returns configured templates merged over the built-in ones, sorted by name
+func (pe *PromptEngine) Templates() []PromptTemplate {
+	return mergePromptTemplates(pe.configuredTemplates())
+}
+
+// Render executes the named template
+func (pe *PromptEngine) Render(name string, data *PromptData) (string, error) {
+	byName := promptTemplateIndex(pe.Templates())
+	return renderPromptTemplate(byName, name, prepareData(data))
+}
+
+// RenderText executes an inline template
+func (pe *PromptEngine) RenderText(text string, data *PromptData) (string, error) {
+	byName := promptTemplateIndex(pe.Templates())
+	byName[""] = PromptTemplate{Template: text}
+	return renderPromptTemplate(byName, "", prepareData(data))
+}
+
+// RenderPersona builds the system prompt for a persona: its template, or its inline
+// "system" prompt, rendered with the persona attached to the data
+func (pe *PromptEngine) RenderPersona(personaName, fallback string, data *PromptData) (string, error) {
+	persona, err := pe.configManager.GetPersonaConfig(personaName)
+	if err != nil {
+		return "", err
+	}
+	data = prepareData(data)
+	data.Persona = persona
+
+	switch {
+	case persona.Template != "":
+		return pe.Render(persona.Template, data)
+	case persona.Prompts["system"] != "":
+		return pe.RenderText(persona.Prompts["system"], data)
+	default:
+		return pe.Render(fallback, data)
+	}
+}
+
+// configuredTemplates reads prompt-templates.yaml, treating an unloaded file as empty
+func (pe *PromptEngine) configuredTemplates() []PromptTemplate {
+	if pe.configManager == nil {
+		return nil
+	}
+	templates, err := pe.configManager.GetPromptTemplates()
+	if err != nil {
+		return nil
+	}
+	return templates
+}
+
+// ValidatePromptConfigs checks that every template parses, extends an existing
+// template without cycles and that personas reference known templates
+func ValidatePromptConfigs(templates []PromptTemplate, personas []PersonaConfig) error {
+	seen := make(map[string]bool)
+	for _, tmpl := range templates {
+		if tmpl.Name == "" {
+			return fmt.Errorf("prompt template missing name")
+		}
+		if seen[tmpl.Name] {
+			return fmt.Errorf("duplicate prompt template: %s", tmpl.Name)
+		}
+		seen[tmpl.Name] = true
+	}
+
+	byName := promptTemplateIndex(mergePromptTemplates(templates))
+	for _, tmpl := range templates {
+		if _, err := compilePromptTemplate(byName, tmpl.Name); err != nil {
+			return err
+		}
+	}
+
+	seen = make(map[string]bool)
+	for _, persona := range personas {
+		if persona.Name == "" {
+			return fmt.Errorf("persona config missing name")
+		}
+		if seen[persona.Name] {
+			return fmt.Errorf("duplicate persona: %s", persona.Name)
+		}
+		seen[persona.Name] = true
+
+		if persona.Template != "" {
+			if _, ok := byName[persona.Template]; !ok {
+				return fmt.Errorf("persona %s uses unknown prompt template: %s", persona.Name, persona.Template)
+			}
+		}
+		if text := persona.Prompts["system"]; text != "" {
+			byName[""] = PromptTemplate{Template: text}
+			if _, err := compilePromptTemplate(byName, ""); err != nil {
+				return fmt.Errorf("persona %s: %w", persona.Name, err)
+			}
+		}
+		if persona.Temperature < 0 || persona.Temperature > 2 {
+			return fmt.Errorf("invalid temperature for persona %s: %f", persona.Name, persona.Temperature)
+		}
+	}
+	return nil
+}
+
+// mergePromptTemplates overlays configured templates on the built-ins
+func mergePromptTemplates(configured []PromptTemplate) []PromptTemplate {
+	byName := promptTemplateIndex(builtinPromptTemplates)
+	for _, tmpl := range configured {
+		byName[tmpl.Name] = tmpl
+	}
+	merged := make([]PromptTemplate, 0, len(byName))
+	for _, tmpl := range byName {
+		merged = append(merged, tmpl)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name < merged[j].Name })
+	return merged
+}
+
+func promptTemplateIndex(templates []PromptTemplate) map[string]PromptTemplate {
+	byName := make(map[string]PromptTemplate, len(templates))
+	for _, tmpl := range templates {
+		byName[tmpl.Name] = tmpl
+	}
+	return byName
+}
+
+// compiledPrompt is a parsed inheritance chain and the layer whose body is executed
+type compiledPrompt struct {
+	set      *template.Template
+	body     string
+	required []string
+}
+
+// compilePromptTemplate parses a template after its ancestors, root first, so each
+// layer's {{define}}s override the blocks above it
+func compilePromptTemplate(byName map[string]PromptTemplate, name string) (*compiledPrompt, error) {
+	var chain []PromptTemplate
+	visited := make(map[string]bool)
+	for current := name; ; {
+		tmpl, ok := byName[current]
+		if !ok {
+			if current == name {
+				return nil, fmt.Errorf("prompt template not found: %s", name)
+			}
+			return nil, fmt.Errorf("prompt template %s extends unknown template: %s", chain[0].Name, current)
+		}
+		if visited[current] {
+			return nil, fmt.Errorf("prompt template %s has an inheritance cycle through %s", name, current)
+		}
+		visited[current] = true
+		chain = append([]PromptTemplate{tmpl}, chain...)
+		if tmpl.Extends == "" {
+			break
+		}
+		current = tmpl.Extends
+	}
+
+	compiled := &compiledPrompt{set: template.New("prompt").Funcs(promptFuncs)}
+	for _, tmpl := range chain {
+		layer, err := compiled.set.New(layerName(tmpl.Name)).Parse(tmpl.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt template %s: %w", displayName(tmpl.Name), err)
+		}
+		if layer.Tree != nil && !parse.IsEmptyTree(layer.Tree.Root) {
+			compiled.body = layerName(tmpl.Name)
+		}
+		compiled.required = append(compiled.required, tmpl.Variables...)
+	}
+	if compiled.body == "" {
+		return nil, fmt.Errorf("prompt template %s renders nothing", displayName(name))
+	}
+	return compiled, nil
+}
+
+// renderPromptTemplate compiles and executes a template, checking its declared variables
+func renderPromptTemplate(byName map[string]PromptTemplate, name string, data *PromptData) (string, error) {
+	compiled, err := compilePromptTemplate(byName, name)
+	if err != nil {
+		return "", err
+	}
+	for _, variable := range compiled.required {
+		if _, ok := data.Vars[variable]; !ok {
+			return "", fmt.Errorf("prompt template %s requires variable: %s", displayName(name), variable)
+		}
+	}
+
+	var out strings.Builder
+	if err := compiled.set.ExecuteTemplate(&out, compiled.body, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %s: %w", displayName(name), err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+func prepareData(data *PromptData) *PromptData {
+	prepared := &PromptData{}
+	if data != nil {
+		*prepared = *data
+	}
+	if prepared.Now.IsZero() {
+		prepared.Now = time.Now()
+	}
+	return prepared
+}
+
+// layerName namespaces template bodies so they cannot collide with {{define}} names
+func layerName(name string) string {
+	return "template:" + name
+}
+
+func displayName(name string) string {
+	if name == "" {
+		return "(inline)"
+	}
+	return name
+}
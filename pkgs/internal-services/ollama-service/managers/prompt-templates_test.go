@@ -0,0 +1,143 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/prompt-templates_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"strings"
+	"testing"
+)
+
+// promptConfig is a config manager holding the given templates and personas
+func promptConfig(templates []PromptTemplate, personas []PersonaConfig) *ConfigManager {
+	cm := schedulerConfig(&LimitsConfig{})
+	cm.configs["configs/model/prompt-templates.yaml"] = &templates
+	cm.configs["configs/model/personas.yaml"] = &personas
+	return cm
+}
+
+var reviewTemplates = []PromptTemplate{
+	{
+		Name:    "code-review",
+		Extends: SessionSystemTemplate,
+		Template: `{{define "identity"}}You are {{.Persona.Name}}, a meticulous code reviewer.{{end}}
+{{define "context"}}{{with .Code}} The user works with {{join .ProgrammingLangs ", "}}.{{end}}{{end}}`,
+	},
+	{
+		Name:     "strict-review",
+		Extends:  "code-review",
+		Template: `{{define "style"}} Be blunt.{{end}}`,
+	},
+	{
+		Name:      "ticket-triage",
+		Variables: []string{"queue"},
+		Template:  `Classify the ticket for the {{.Vars.queue}} queue.{{with .User}} Reply in {{default "en" .Language}}.{{end}}`,
+	},
+}
+
+func TestPromptTemplateInheritance(t *testing.T) {
+	engine := NewPromptEngine(promptConfig(reviewTemplates, nil))
+	data := &PromptData{
+		Model:   "llama3.2",
+		Persona: &PersonaConfig{Name: "Rex"},
+		User:    &UserProfile{CommunicationStyle: "concise"},
+		Code:    &CodeContext{ProgrammingLangs: []string{"Go", "SQL"}},
+	}
+
+	prompt, err := engine.Render("code-review", data)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if want := "You are Rex, a meticulous code reviewer. The user prefers a concise communication style. The user works with Go, SQL."; prompt != want {
+		t.Errorf("child prompt = %q, want %q", prompt, want)
+	}
+
+	// A grandchild keeps the blocks its parent overrode and replaces another
+	prompt, err = engine.Render("strict-review", data)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if want := "You are Rex, a meticulous code reviewer. Be blunt. The user works with Go, SQL."; prompt != want {
+		t.Errorf("grandchild prompt = %q, want %q", prompt, want)
+	}
+
+	if _, err := engine.Render("ticket-triage", data); err == nil || !strings.Contains(err.Error(), "queue") {
+		t.Errorf("missing variable = %v", err)
+	}
+	data.Vars = map[string]interface{}{"queue": "billing"}
+	if prompt, _ := engine.Render("ticket-triage", data); prompt != "Classify the ticket for the billing queue. Reply in en." {
+		t.Errorf("triage prompt = %q", prompt)
+	}
+}
+
+func TestValidatePromptConfigs(t *testing.T) {
+	if err := ValidatePromptConfigs(reviewTemplates, []PersonaConfig{{Name: "reviewer", Template: "code-review"}}); err != nil {
+		t.Fatalf("valid configs: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		templates []PromptTemplate
+		personas  []PersonaConfig
+		want      string
+	}{
+		"parse error":     {templates: []PromptTemplate{{Name: "a", Template: "{{.Model"}}, want: "invalid prompt template a"},
+		"unknown parent":  {templates: []PromptTemplate{{Name: "a", Extends: "missing", Template: "x"}}, want: "extends unknown template: missing"},
+		"cycle":           {templates: []PromptTemplate{{Name: "a", Extends: "b", Template: "x"}, {Name: "b", Extends: "a", Template: "y"}}, want: "cycle"},
+		"duplicate":       {templates: []PromptTemplate{{Name: "a", Template: "x"}, {Name: "a", Template: "y"}}, want: "duplicate"},
+		"empty body":      {templates: []PromptTemplate{{Name: "a", Template: `{{define "x"}}y{{end}}`}}, want: "renders nothing"},
+		"persona target":  {personas: []PersonaConfig{{Name: "p", Template: "missing"}}, want: "unknown prompt template"},
+		"persona inline":  {personas: []PersonaConfig{{Name: "p", Prompts: map[string]string{"system": "{{if}}"}}}, want: "persona p"},
+		"persona unnamed": {personas: []PersonaConfig{{Template: SessionSystemTemplate}}, want: "missing name"},
+	} {
+		if err := ValidatePromptConfigs(tc.templates, tc.personas); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", name, err, tc.want)
+		}
+	}
+}
+
+func TestSessionPersonaSelectsSystemPrompt(t *testing.T) {
+	cm := promptConfig(reviewTemplates, []PersonaConfig{
+		{Name: "reviewer", Template: "code-review", Temperature: 0.2},
+		{Name: "tutor", Prompts: map[string]string{"system": "You are a patient tutor for {{.User.Name}}."}},
+	})
+	sm := NewSessionManager(cm, nil, nil)
+	t.Cleanup(func() { sm.Shutdown(context.Background()) })
+	ctx := context.Background()
+
+	plain, err := sm.CreateSession(ctx, "alice", "llama3.2", nil)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if want := "You are a helpful AI assistant using the llama3.2 model. The user prefers a helpful communication style."; plain.Context.SystemPrompt != want {
+		t.Errorf("default prompt = %q", plain.Context.SystemPrompt)
+	}
+
+	settings := sm.getDefaultSettings()
+	settings.Persona = "reviewer"
+	session, err := sm.CreateSession(ctx, "alice", "llama3.2", settings)
+	if err != nil {
+		t.Fatalf("CreateSession with persona: %v", err)
+	}
+	if !strings.HasPrefix(session.Context.SystemPrompt, "You are reviewer, a meticulous code reviewer.") || session.Settings.Temperature != 0.2 {
+		t.Errorf("persona session prompt = %q, settings = %+v", session.Context.SystemPrompt, session.Settings)
+	}
+
+	if err := sm.SetSessionPersona(session.ID, "tutor"); err != nil {
+		t.Fatalf("SetSessionPersona: %v", err)
+	}
+	if session.Context.SystemPrompt != "You are a patient tutor for User." || session.Persona != "tutor" {
+		t.Errorf("switched prompt = %q", session.Context.SystemPrompt)
+	}
+	if err := sm.SetSessionPersona(session.ID, "missing"); err == nil || session.Persona != "tutor" {
+		t.Errorf("unknown persona = %v, persona now %q", err, session.Persona)
+	}
+
+	settings = sm.getDefaultSettings()
+	settings.Persona = "missing"
+	if _, err := sm.CreateSession(ctx, "alice", "llama3.2", settings); err == nil {
+		t.Error("session created with an unknown persona")
+	}
+}
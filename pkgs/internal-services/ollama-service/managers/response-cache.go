@@ -0,0 +1,281 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/response-cache.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+)
+
+// Response cache defaults, used when features.yaml leaves a field unset
+const (
+	defaultCacheTTL                 = time.Hour
+	defaultCacheMaxEntries          = 1024
+	defaultCacheSimilarityThreshold = 0.95
+)
+
+// ResponseCache stores inference results per user. An exact layer matches on a hash
+// of model, parameters and normalized messages; a semantic layer matches requests
+// whose context is identical and whose last message embeds close to a cached one.
+type ResponseCache struct {
+	mu       sync.Mutex
+	config   func() ResponseCacheConfig
+	embedder func() EmbeddingProvider
+	exact    map[string]*cacheEntry
+	contexts map[string][]*cacheEntry // context key -> entries for the semantic layer
+}
+
+// cacheEntry is one cached result
+type cacheEntry struct {
+	key        string
+	contextKey string
+	embedding  []float64 // normalized; nil when the semantic layer is off
+	provider   string    // embedding provider that produced the vector
+	result     *InferenceResult
+	createdAt  time.Time
+	expiresAt  time.Time
+}
+
+// CacheHit is a result served from the cache
+type CacheHit struct {
+	Result     *InferenceResult
+	Layer      string  // exact or semantic
+	Similarity float64 // 1 for exact hits
+	Age        time.Duration
+}
+
+// cacheKeys identifies a request: key for the exact layer, contextKey for everything
+// but the final message, and the text the semantic layer embeds
+type cacheKeys struct {
+	key        string
+	contextKey string
+	query      string
+}
+
+// NewResponseCache creates a cache reading its settings and embedding provider on each use
+func NewResponseCache(config func() ResponseCacheConfig, embedder func() EmbeddingProvider) *ResponseCache {
+	return &ResponseCache{
+		config:   config,
+		embedder: embedder,
+		exact:    make(map[string]*cacheEntry),
+		contexts: make(map[string][]*cacheEntry),
+	}
+}
+
+// Enabled reports whether the cache is switched on in features.yaml
+func (rc *ResponseCache) Enabled() bool {
+	return rc.config().Enabled
+}
+
+// Lookup returns a cached result for the request, trying the exact layer first
+func (rc *ResponseCache) Lookup(ctx context.Context, req *InferenceRequest) (*CacheHit, bool) {
+	config := rc.settings()
+	keys := cacheKeysFor(req)
+	now := time.Now()
+
+	rc.mu.Lock()
+	if entry, ok := rc.exact[keys.key]; ok && now.Before(entry.expiresAt) {
+		rc.mu.Unlock()
+		return &CacheHit{Result: entry.result, Layer: "exact", Similarity: 1, Age: now.Sub(entry.createdAt)}, true
+	}
+	candidates := append([]*cacheEntry(nil), rc.contexts[keys.contextKey]...)
+	rc.mu.Unlock()
+
+	if !config.Semantic || keys.query == "" || len(candidates) == 0 {
+		return nil, false
+	}
+
+	provider := rc.embedder()
+	if provider == nil {
+		return nil, false
+	}
+	embedding, err := provider.Embed(ctx, keys.query)
+	if err != nil {
+		log.Debug().Err(err).Msg("Response cache could not embed request")
+		return nil, false
+	}
+	embedding = normalizeVector(embedding)
+
+	var best *cacheEntry
+	bestScore := config.SimilarityThreshold
+	for _, entry := range candidates {
+		if entry.provider != provider.Name() || !now.Before(entry.expiresAt) || len(entry.embedding) != len(embedding) {
+			continue
+		}
+		if score := dotProduct(entry.embedding, embedding); score >= bestScore {
+			best, bestScore = entry, score
+		}
+	}
+	if best == nil {
+		return nil, false
+	}
+	if bestScore > 1 {
+		bestScore = 1 // rounding on identical vectors
+	}
+	return &CacheHit{Result: best.result, Layer: "semantic", Similarity: bestScore, Age: now.Sub(best.createdAt)}, true
+}
+
+// Store caches a completed result for the request
+func (rc *ResponseCache) Store(ctx context.Context, req *InferenceRequest, result *InferenceResult) {
+	config := rc.settings()
+	keys := cacheKeysFor(req)
+	now := time.Now()
+
+	stored := *result
+	entry := &cacheEntry{
+		key:        keys.key,
+		contextKey: keys.contextKey,
+		result:     &stored,
+		createdAt:  now,
+		expiresAt:  now.Add(time.Duration(config.TTLSeconds) * time.Second),
+	}
+	if config.Semantic && keys.query != "" {
+		if provider := rc.embedder(); provider != nil {
+			if embedding, err := provider.Embed(ctx, keys.query); err == nil {
+				entry.embedding = normalizeVector(embedding)
+				entry.provider = provider.Name()
+			}
+		}
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if previous, ok := rc.exact[keys.key]; ok {
+		rc.remove(previous)
+	}
+	rc.exact[keys.key] = entry
+	rc.contexts[keys.contextKey] = append(rc.contexts[keys.contextKey], entry)
+	rc.evict(now, config.MaxEntries)
+}
+
+// Len returns the number of cached entries, expired ones included until evicted
+func (rc *ResponseCache) Len() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.exact)
+}
+
+// Clear drops every cached entry
+func (rc *ResponseCache) Clear() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.exact = make(map[string]*cacheEntry)
+	rc.contexts = make(map[string][]*cacheEntry)
+}
+
+// settings fills in defaults for unset fields
+func (rc *ResponseCache) settings() ResponseCacheConfig {
+	config := rc.config()
+	if config.TTLSeconds <= 0 {
+		config.TTLSeconds = int(defaultCacheTTL / time.Second)
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultCacheMaxEntries
+	}
+	if config.SimilarityThreshold <= 0 || config.SimilarityThreshold > 1 {
+		config.SimilarityThreshold = defaultCacheSimilarityThreshold
+	}
+	return config
+}
+
+// evict drops expired entries, then the oldest ones beyond maxEntries; callers hold rc.mu
+func (rc *ResponseCache) evict(now time.Time, maxEntries int) {
+	for _, entry := range rc.exact {
+		if !now.Before(entry.expiresAt) {
+			rc.remove(entry)
+		}
+	}
+	for len(rc.exact) > maxEntries {
+		var oldest *cacheEntry
+		for _, entry := range rc.exact {
+			if oldest == nil || entry.createdAt.Before(oldest.createdAt) {
+				oldest = entry
+			}
+		}
+		rc.remove(oldest)
+	}
+}
+
+// remove unlinks an entry from both layers; callers hold rc.mu
+func (rc *ResponseCache) remove(entry *cacheEntry) {
+	if rc.exact[entry.key] == entry {
+		delete(rc.exact, entry.key)
+	}
+	entries := rc.contexts[entry.contextKey]
+	for i, e := range entries {
+		if e == entry {
+			entries = append(entries[:i], entries[i+1:]...)
+			break
+		}
+	}
+	if len(entries) == 0 {
+		delete(rc.contexts, entry.contextKey)
+	} else {
+		rc.contexts[entry.contextKey] = entries
+	}
+}
+
+// cacheKeysFor hashes the parts of a request that determine its answer. The user
+// is part of every key so cached answers never cross users.
+func cacheKeysFor(req *InferenceRequest) cacheKeys {
+	messages := make([][2]string, len(req.Messages))
+	for i, msg := range req.Messages {
+		messages[i] = [2]string{msg.Role, normalizeCacheText(msg.Content)}
+	}
+
+	var query string
+	contextMessages := messages
+	if n := len(messages); n > 0 {
+		query = messages[n-1][1]
+		contextMessages = messages[:n-1]
+	}
+
+	params := req.Parameters
+	if params == nil {
+		params = &InferenceParameters{}
+	}
+	scope := map[string]interface{}{
+		"user":           req.UserID,
+		"model":          req.ModelName,
+		"type":           req.RequestType,
+		"temperature":    params.Temperature,
+		"top_k":          params.TopK,
+		"top_p":          params.TopP,
+		"repeat_penalty": params.RepeatPenalty,
+		"seed":           params.Seed,
+		"max_tokens":     params.MaxTokens,
+		"stop":           params.Stop,
+		"system_prompt":  normalizeCacheText(params.SystemPrompt),
+		"custom_options": params.CustomOptions,
+		"schema":         params.ResponseSchema,
+	}
+
+	scope["messages"] = contextMessages
+	contextKey := hashCacheKey(scope)
+	scope["messages"] = messages
+	return cacheKeys{key: hashCacheKey(scope), contextKey: contextKey, query: query}
+}
+
+// hashCacheKey hashes a JSON encoding; map keys are encoded sorted so equal scopes hash equally
+func hashCacheKey(scope map[string]interface{}) string {
+	data, _ := json.Marshal(scope)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeCacheText trims and collapses whitespace so formatting differences still hit
+func normalizeCacheText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
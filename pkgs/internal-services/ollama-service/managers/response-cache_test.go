@@ -0,0 +1,179 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/response-cache_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"testing"
+	"time"
+)
+
+// testCache builds a cache with fixed settings and the offline hash embedder
+func testCache(config ResponseCacheConfig) *ResponseCache {
+	config.Enabled = true
+	embedder := NewHashEmbeddingProvider(256)
+	return NewResponseCache(
+		func() ResponseCacheConfig { return config },
+		func() EmbeddingProvider { return embedder },
+	)
+}
+
+func cacheRequest(userID, model string, messages ...string) *InferenceRequest {
+	req := &InferenceRequest{
+		UserID:     userID,
+		ModelName:  model,
+		Parameters: &InferenceParameters{Temperature: 0.2, UseCache: true},
+	}
+	for i, content := range messages {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		req.Messages = append(req.Messages, Message{Role: role, Content: content})
+	}
+	return req
+}
+
+func cachedResult(content string) *InferenceResult {
+	return &InferenceResult{
+		Content:  content,
+		Usage:    &TokenUsage{InputTokens: 20, OutputTokens: 10, TotalTokens: 30},
+		Metadata: map[string]interface{}{"source": "test"},
+	}
+}
+
+func TestResponseCacheExactLayer(t *testing.T) {
+	cache := testCache(ResponseCacheConfig{})
+	ctx := context.Background()
+	cache.Store(ctx, cacheRequest("alice", "llama3.2", "Daily digest for   today"), cachedResult("digest"))
+
+	hit, ok := cache.Lookup(ctx, cacheRequest("alice", "llama3.2", " Daily digest for today\n"))
+	if !ok || hit.Layer != "exact" || hit.Result.Content != "digest" {
+		t.Fatalf("lookup with different whitespace = %+v, %v", hit, ok)
+	}
+
+	for name, req := range map[string]*InferenceRequest{
+		"another user":  cacheRequest("bob", "llama3.2", "Daily digest for today"),
+		"another model": cacheRequest("alice", "mistral", "Daily digest for today"),
+		"another text":  cacheRequest("alice", "llama3.2", "Weekly digest"),
+	} {
+		if hit, ok := cache.Lookup(ctx, req); ok {
+			t.Errorf("%s hit %+v", name, hit)
+		}
+	}
+
+	req := cacheRequest("alice", "llama3.2", "Daily digest for today")
+	req.Parameters.Temperature = 0.9
+	if _, ok := cache.Lookup(ctx, req); ok {
+		t.Error("request with other sampling parameters hit")
+	}
+}
+
+func TestResponseCacheSemanticLayer(t *testing.T) {
+	ctx := context.Background()
+	cache := testCache(ResponseCacheConfig{Semantic: true, SimilarityThreshold: 0.9})
+	cache.Store(ctx, cacheRequest("alice", "llama3.2", "classify: the invoice is overdue"), cachedResult("billing"))
+
+	hit, ok := cache.Lookup(ctx, cacheRequest("alice", "llama3.2", "Classify: The invoice is OVERDUE"))
+	if !ok || hit.Layer != "semantic" || hit.Similarity < 0.9 || hit.Similarity > 1 {
+		t.Fatalf("near-identical prompt = %+v, %v", hit, ok)
+	}
+
+	if _, ok := cache.Lookup(ctx, cacheRequest("alice", "llama3.2", "write a poem about autumn leaves")); ok {
+		t.Error("unrelated prompt hit")
+	}
+	if _, ok := cache.Lookup(ctx, cacheRequest("bob", "llama3.2", "Classify: The invoice is OVERDUE")); ok {
+		t.Error("semantic hit crossed users")
+	}
+
+	// Earlier turns must match exactly; only the last message is compared by meaning
+	withHistory := cacheRequest("alice", "llama3.2", "hello", "hi", "Classify: The invoice is OVERDUE")
+	if _, ok := cache.Lookup(ctx, withHistory); ok {
+		t.Error("semantic hit despite a different conversation")
+	}
+
+	exactOnly := testCache(ResponseCacheConfig{SimilarityThreshold: 0.9})
+	exactOnly.Store(ctx, cacheRequest("alice", "llama3.2", "classify: the invoice is overdue"), cachedResult("billing"))
+	if _, ok := exactOnly.Lookup(ctx, cacheRequest("alice", "llama3.2", "Classify: The invoice is OVERDUE")); ok {
+		t.Error("semantic hit with the semantic layer off")
+	}
+}
+
+func TestResponseCacheExpiryAndEviction(t *testing.T) {
+	ctx := context.Background()
+	cache := testCache(ResponseCacheConfig{MaxEntries: 2, Semantic: true})
+
+	cache.Store(ctx, cacheRequest("alice", "llama3.2", "one"), cachedResult("1"))
+	cache.Store(ctx, cacheRequest("alice", "llama3.2", "two"), cachedResult("2"))
+	for _, entry := range cache.exact {
+		if entry.result.Content == "1" {
+			entry.expiresAt = time.Now().Add(-time.Second)
+		}
+	}
+	if _, ok := cache.Lookup(ctx, cacheRequest("alice", "llama3.2", "one")); ok {
+		t.Error("expired entry hit")
+	}
+
+	cache.Store(ctx, cacheRequest("alice", "llama3.2", "three"), cachedResult("3"))
+	cache.Store(ctx, cacheRequest("alice", "llama3.2", "four"), cachedResult("4"))
+	if n := cache.Len(); n != 2 {
+		t.Fatalf("entries = %d, want the limit of 2", n)
+	}
+	if _, ok := cache.Lookup(ctx, cacheRequest("alice", "llama3.2", "two")); ok {
+		t.Error("oldest entry survived eviction")
+	}
+	if _, ok := cache.Lookup(ctx, cacheRequest("alice", "llama3.2", "four")); !ok {
+		t.Error("newest entry was evicted")
+	}
+	if n := len(cache.contexts[cacheKeysFor(cacheRequest("alice", "llama3.2", "x")).contextKey]); n != 2 {
+		t.Errorf("semantic layer holds %d entries, want 2", n)
+	}
+}
+
+func TestInferenceServesRepeatsFromCache(t *testing.T) {
+	ollama := newFakeOllama(t, "ollama")
+	cm := schedulerConfig(&LimitsConfig{MaxRequestsPerMinute: 100, MaxTokensPerRequest: 4096, TokenBudgetPerUser: 100000, ResetIntervalHours: 24},
+		ModelConfig{Name: "llama3.2", Specialization: "chat"})
+	cm.configs["configs/features.yaml"] = &FeatureConfig{ResponseCache: ResponseCacheConfig{Enabled: true}}
+
+	mm := NewModelManager(ollama.URL, cm)
+	t.Cleanup(func() { mm.Shutdown(context.Background()) })
+	im := NewInferenceManager(cm, mm, NewTokenManager(cm), nil, nil, ollama.URL)
+
+	run := func(useCache bool) *InferenceResult {
+		t.Helper()
+		req := cacheRequest("alice", "llama3.2", "what changed today?")
+		req.ID = "cache-test"
+		req.Parameters.UseCache = useCache
+		result, err := im.ProcessInference(context.Background(), req)
+		if err != nil {
+			t.Fatalf("ProcessInference: %v", err)
+		}
+		return result
+	}
+
+	first := run(true)
+	second := run(true)
+	if n := ollama.count("POST /api/chat"); n != 1 {
+		t.Fatalf("model calls = %d, want the repeat served from cache", n)
+	}
+	if second.Content != first.Content || second.Metadata["cache"] != "exact" {
+		t.Errorf("cached result = %+v", second)
+	}
+	if second.Usage.CachedTokens != 10 || second.Usage.TotalTokens != 10 || first.Usage.CachedTokens != 0 {
+		t.Errorf("usage = %+v then %+v, want the repeat's tokens reported as cached", first.Usage, second.Usage)
+	}
+
+	run(false)
+	if n := ollama.count("POST /api/chat"); n != 2 {
+		t.Errorf("model calls = %d, want requests without use_cache to bypass the cache", n)
+	}
+
+	stats, _ := mm.GetModelStats("llama3.2")
+	if stats.CacheHits != 1 || stats.CacheMisses != 1 || stats.CachedTokens != int64(second.Usage.TotalTokens) {
+		t.Errorf("stats = %+v, want one hit and one miss", stats)
+	}
+}
@@ -0,0 +1,363 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/session-branches.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+)
+
+// MainBranchID is the branch every session starts on
+const MainBranchID = "main"
+
+// BranchTree is a session's branches with the messages added on each
+type BranchTree struct {
+	SessionID     string        `json:"session_id"`
+	CurrentBranch string        `json:"current_branch"`
+	Branches      []*BranchNode `json:"branches"`
+	MergePoints   []*MergePoint `json:"merge_points"`
+}
+
+// BranchNode is one branch in a BranchTree
+type BranchNode struct {
+	ThreadBranch
+	Messages []Message `json:"messages"` // messages added on this branch, oldest first
+	Children []string  `json:"children"` // branches forked from this one
+}
+
+// newThreadingInfo starts a session on the main branch
+func newThreadingInfo() *ThreadingInfo {
+	return &ThreadingInfo{
+		ChildConversations: make([]string, 0),
+		ThreadBranches: []*ThreadBranch{{
+			ID:        MainBranchID,
+			Name:      "Main",
+			CreatedAt: time.Now(),
+			IsActive:  true,
+		}},
+		CurrentBranch: MainBranchID,
+		MergePoints:   make([]*MergePoint, 0),
+	}
+}
+
+// ForkSession starts a branch beside messageID: the new branch holds the history up
+// to that message's parent, so the next message added replaces it ("edit and
+// regenerate"). The original branch is kept and the new one becomes active.
+func (sm *SessionManager) ForkSession(sessionID, messageID, name string) (*ThreadBranch, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	threading := sm.threadingFor(session)
+
+	byID := messageIndex(session)
+	message, ok := byID[messageID]
+	if !ok {
+		return nil, fmt.Errorf("message not found in session: %s", messageID)
+	}
+
+	branchID, err := generateThreadID("branch_")
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate branch ID: %w", err)
+	}
+	if name == "" {
+		name = fmt.Sprintf("Branch %d", len(threading.ThreadBranches))
+	}
+
+	branch := &ThreadBranch{
+		ID:            branchID,
+		Name:          name,
+		Description:   fmt.Sprintf("Forked at message %s", messageID),
+		CreatedAt:     time.Now(),
+		BranchPoint:   len(pathTo(byID, message.ParentID)),
+		ParentBranch:  message.BranchID,
+		ForkMessageID: messageID,
+		HeadMessageID: message.ParentID,
+	}
+	threading.ThreadBranches = append(threading.ThreadBranches, branch)
+	if depth := branchDepth(threading, branch); depth > threading.ThreadDepth {
+		threading.ThreadDepth = depth
+	}
+	activateBranch(threading, branch)
+	session.LastActivity = time.Now()
+
+	log.Info().
+		Str("session_id", sessionID).
+		Str("branch_id", branchID).
+		Str("message_id", messageID).
+		Msg("Forked session branch")
+
+	copied := *branch
+	return &copied, nil
+}
+
+// SwitchBranch makes branchID the branch new messages and session context follow
+func (sm *SessionManager) SwitchBranch(sessionID, branchID string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	threading := sm.threadingFor(session)
+
+	branch := findBranch(threading, branchID)
+	if branch == nil {
+		return fmt.Errorf("branch not found: %s", branchID)
+	}
+	activateBranch(threading, branch)
+	session.LastActivity = time.Now()
+	return nil
+}
+
+// MergeBranch adds a summary of branchID to the branch it was forked from as a system
+// message, records the merge point and switches back to that branch
+func (sm *SessionManager) MergeBranch(sessionID, branchID, summary string) (*MergePoint, error) {
+	if summary == "" {
+		return nil, fmt.Errorf("merge summary is required")
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	threading := sm.threadingFor(session)
+
+	branch := findBranch(threading, branchID)
+	if branch == nil {
+		return nil, fmt.Errorf("branch not found: %s", branchID)
+	}
+	if branch.MergedInto != "" {
+		return nil, fmt.Errorf("branch %s is already merged into %s", branchID, branch.MergedInto)
+	}
+	target := findBranch(threading, branch.ParentBranch)
+	if target == nil {
+		return nil, fmt.Errorf("branch %s has no parent branch to merge into", branchID)
+	}
+
+	messageID, err := generateMessageID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate message ID: %w", err)
+	}
+	content := fmt.Sprintf("Summary of branch %q: %s", branch.Name, summary)
+	message := Message{
+		ID:        messageID,
+		Role:      "system",
+		Content:   content,
+		Timestamp: time.Now(),
+		Tokens:    estimateTokens(content),
+		ParentID:  target.HeadMessageID,
+		BranchID:  target.ID,
+		Metadata:  map[string]interface{}{"merged_branch": branch.ID},
+	}
+	session.Context.ConversationLog = append(session.Context.ConversationLog, message)
+	session.MessageCount++
+	target.HeadMessageID = message.ID
+
+	mergeID, err := generateThreadID("merge_")
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate merge ID: %w", err)
+	}
+	mergePoint := &MergePoint{
+		ID:         mergeID,
+		MessageID:  message.ID,
+		BranchIDs:  []string{branch.ID, target.ID},
+		MergeType:  "summary",
+		CreatedAt:  time.Now(),
+		IsResolved: true,
+	}
+	threading.MergePoints = append(threading.MergePoints, mergePoint)
+	branch.MergedInto = target.ID
+	activateBranch(threading, target)
+	session.LastActivity = time.Now()
+
+	copied := *mergePoint
+	return &copied, nil
+}
+
+// GetBranchTree lists a session's branches and the messages on each
+func (sm *SessionManager) GetBranchTree(sessionID string) (*BranchTree, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	threading := sm.threadingFor(session)
+
+	tree := &BranchTree{
+		SessionID:     sessionID,
+		CurrentBranch: threading.CurrentBranch,
+		Branches:      make([]*BranchNode, 0, len(threading.ThreadBranches)),
+		MergePoints:   make([]*MergePoint, 0, len(threading.MergePoints)),
+	}
+	nodes := make(map[string]*BranchNode, len(threading.ThreadBranches))
+	for _, branch := range threading.ThreadBranches {
+		node := &BranchNode{ThreadBranch: *branch, Messages: make([]Message, 0), Children: make([]string, 0)}
+		nodes[branch.ID] = node
+		tree.Branches = append(tree.Branches, node)
+	}
+	for _, node := range tree.Branches {
+		if parent, ok := nodes[node.ParentBranch]; ok {
+			parent.Children = append(parent.Children, node.ID)
+		}
+	}
+	for _, message := range session.Context.ConversationLog {
+		if node, ok := nodes[message.BranchID]; ok {
+			node.Messages = append(node.Messages, message)
+		}
+	}
+	for _, mergePoint := range threading.MergePoints {
+		copied := *mergePoint
+		tree.MergePoints = append(tree.MergePoints, &copied)
+	}
+	return tree, nil
+}
+
+// BranchMessages returns the messages added on a branch, oldest first
+func (sm *SessionManager) BranchMessages(sessionID, branchID string) ([]Message, error) {
+	tree, err := sm.GetBranchTree(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range tree.Branches {
+		if node.ID == branchID {
+			return node.Messages, nil
+		}
+	}
+	return nil, fmt.Errorf("branch not found: %s", branchID)
+}
+
+// SummarizeBranch summarizes the messages added on a branch, for merges without a
+// caller-written summary
+func (im *InferenceManager) SummarizeBranch(ctx context.Context, sessionID, branchID string) (string, error) {
+	if im.sessionManager == nil {
+		return "", fmt.Errorf("sessions are not available")
+	}
+	messages, err := im.sessionManager.BranchMessages(sessionID, branchID)
+	if err != nil {
+		return "", err
+	}
+	if len(messages) == 0 {
+		return "", fmt.Errorf("branch %s has no messages to summarize", branchID)
+	}
+	return im.SummarizeContext(ctx, "", messages, 512)
+}
+
+// threadingFor returns the session's threading info, creating it for sessions that
+// predate branching; callers hold sm.mu
+func (sm *SessionManager) threadingFor(session *Session) *ThreadingInfo {
+	if session.Threading == nil {
+		session.Threading = newThreadingInfo()
+		// Existing messages become the main branch, in order
+		parentID := ""
+		for i := range session.Context.ConversationLog {
+			message := &session.Context.ConversationLog[i]
+			message.ParentID, message.BranchID = parentID, MainBranchID
+			parentID = message.ID
+		}
+		session.Threading.ThreadBranches[0].HeadMessageID = parentID
+	}
+	return session.Threading
+}
+
+// attachToBranch links a new message under the active branch's head; callers hold sm.mu
+func (sm *SessionManager) attachToBranch(session *Session, message *Message) {
+	threading := sm.threadingFor(session)
+	branch := findBranch(threading, threading.CurrentBranch)
+	if branch == nil {
+		return
+	}
+	message.ParentID = branch.HeadMessageID
+	message.BranchID = branch.ID
+	branch.HeadMessageID = message.ID
+}
+
+// activePath returns the messages from the root to the active branch's head; callers hold sm.mu
+func (sm *SessionManager) activePath(session *Session) []Message {
+	threading := sm.threadingFor(session)
+	branch := findBranch(threading, threading.CurrentBranch)
+	if branch == nil {
+		return session.Context.ConversationLog
+	}
+	return pathTo(messageIndex(session), branch.HeadMessageID)
+}
+
+// messageIndex maps message IDs to messages
+func messageIndex(session *Session) map[string]*Message {
+	byID := make(map[string]*Message, len(session.Context.ConversationLog))
+	for i := range session.Context.ConversationLog {
+		message := &session.Context.ConversationLog[i]
+		byID[message.ID] = message
+	}
+	return byID
+}
+
+// pathTo walks parent links from headID to the root. Messages trimmed out of the
+// context window end the walk early.
+func pathTo(byID map[string]*Message, headID string) []Message {
+	var path []Message
+	for id := headID; id != ""; {
+		message, ok := byID[id]
+		if !ok {
+			break
+		}
+		path = append(path, *message)
+		id = message.ParentID
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+func findBranch(threading *ThreadingInfo, branchID string) *ThreadBranch {
+	for _, branch := range threading.ThreadBranches {
+		if branch.ID == branchID {
+			return branch
+		}
+	}
+	return nil
+}
+
+func activateBranch(threading *ThreadingInfo, active *ThreadBranch) {
+	for _, branch := range threading.ThreadBranches {
+		branch.IsActive = branch == active
+	}
+	threading.CurrentBranch = active.ID
+}
+
+// branchDepth counts forks between a branch and main
+func branchDepth(threading *ThreadingInfo, branch *ThreadBranch) int {
+	depth := 0
+	for branch != nil && branch.ParentBranch != "" && depth <= len(threading.ThreadBranches) {
+		depth++
+		branch = findBranch(threading, branch.ParentBranch)
+	}
+	return depth
+}
+
+func generateThreadID(prefix string) (string, error) {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(bytes), nil
+}
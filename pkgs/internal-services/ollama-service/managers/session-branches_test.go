@@ -0,0 +1,125 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/session-branches_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"reflect"
+	"testing"
+)
+
+// contextContents returns the non-system contents GetSessionContext would send
+func contextContents(t *testing.T, sm *SessionManager, sessionID string) []string {
+	t.Helper()
+	messages, err := sm.GetSessionContext(sessionID, false)
+	if err != nil {
+		t.Fatalf("GetSessionContext: %v", err)
+	}
+	var contents []string
+	for _, msg := range messages {
+		if msg.Role != "system" || msg.Metadata["merged_branch"] != nil {
+			contents = append(contents, msg.Content)
+		}
+	}
+	return contents
+}
+
+func TestSessionBranching(t *testing.T) {
+	sm := NewSessionManager(schedulerConfig(&LimitsConfig{}), nil, nil)
+	t.Cleanup(func() { sm.Shutdown(context.Background()) })
+	session, err := sm.CreateSession(context.Background(), "alice", "llama3.2", nil)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	add := func(role, content string) *Message {
+		t.Helper()
+		msg, err := sm.AddMessage(session.ID, role, content, nil)
+		if err != nil {
+			t.Fatalf("AddMessage: %v", err)
+		}
+		return msg
+	}
+	add("user", "write a haiku")
+	add("assistant", "haiku one")
+	question := add("user", "now make it rhyme")
+	add("assistant", "rhyming haiku")
+
+	// Edit the second question on a new branch
+	branch, err := sm.ForkSession(session.ID, question.ID, "rewrite")
+	if err != nil {
+		t.Fatalf("ForkSession: %v", err)
+	}
+	if branch.ParentBranch != MainBranchID || branch.BranchPoint != 2 || session.Threading.CurrentBranch != branch.ID {
+		t.Errorf("branch = %+v, current %s", branch, session.Threading.CurrentBranch)
+	}
+	add("user", "now make it about autumn")
+	add("assistant", "autumn haiku")
+
+	want := []string{"write a haiku", "haiku one", "now make it about autumn", "autumn haiku"}
+	if got := contextContents(t, sm, session.ID); !reflect.DeepEqual(got, want) {
+		t.Errorf("branch context = %q, want %q", got, want)
+	}
+
+	// The sibling branch is kept and can be switched back to
+	if err := sm.SwitchBranch(session.ID, MainBranchID); err != nil {
+		t.Fatalf("SwitchBranch: %v", err)
+	}
+	want = []string{"write a haiku", "haiku one", "now make it rhyme", "rhyming haiku"}
+	if got := contextContents(t, sm, session.ID); !reflect.DeepEqual(got, want) {
+		t.Errorf("main context = %q, want %q", got, want)
+	}
+
+	mergePoint, err := sm.MergeBranch(session.ID, branch.ID, "an autumn variant was written")
+	if err != nil {
+		t.Fatalf("MergeBranch: %v", err)
+	}
+	if mergePoint.MergeType != "summary" || !reflect.DeepEqual(mergePoint.BranchIDs, []string{branch.ID, MainBranchID}) {
+		t.Errorf("merge point = %+v", mergePoint)
+	}
+	got := contextContents(t, sm, session.ID)
+	if len(got) != 5 || got[4] != `Summary of branch "rewrite": an autumn variant was written` {
+		t.Errorf("context after merge = %q", got)
+	}
+	if _, err := sm.MergeBranch(session.ID, branch.ID, "again"); err == nil {
+		t.Error("branch merged twice")
+	}
+	if _, err := sm.MergeBranch(session.ID, MainBranchID, "x"); err == nil {
+		t.Error("main branch merged without a parent")
+	}
+
+	tree, err := sm.GetBranchTree(session.ID)
+	if err != nil {
+		t.Fatalf("GetBranchTree: %v", err)
+	}
+	if tree.CurrentBranch != MainBranchID || len(tree.Branches) != 2 || len(tree.MergePoints) != 1 {
+		t.Fatalf("tree = %+v", tree)
+	}
+	main, fork := tree.Branches[0], tree.Branches[1]
+	if len(main.Messages) != 5 || len(fork.Messages) != 2 || !reflect.DeepEqual(main.Children, []string{branch.ID}) || fork.MergedInto != MainBranchID {
+		t.Errorf("main = %+v\nfork = %+v", main, fork)
+	}
+
+	if _, err := sm.ForkSession(session.ID, "msg_missing", ""); err == nil {
+		t.Error("fork at an unknown message succeeded")
+	}
+}
+
+func TestThreadingForLegacySession(t *testing.T) {
+	sm := &SessionManager{sessions: map[string]*Session{}}
+	session := &Session{ID: "s1", Context: &SessionContext{ConversationLog: []Message{
+		{ID: "m1", Role: "user", Content: "a"},
+		{ID: "m2", Role: "assistant", Content: "b"},
+	}}}
+	sm.sessions["s1"] = session
+
+	if got := contextContents(t, sm, "s1"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("legacy context = %q", got)
+	}
+	if log := session.Context.ConversationLog; log[1].ParentID != "m1" || log[1].BranchID != MainBranchID {
+		t.Errorf("legacy messages were not linked: %+v", log)
+	}
+}
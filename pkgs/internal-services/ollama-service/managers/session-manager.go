@@ -25,6 +25,7 @@ type SessionManager struct {
 	configManager      *ConfigManager
 	conversationMgr    *ConversationManager
 	memoryManager      *MemoryManager
+	prompts            *PromptEngine
 	cleanupInterval    time.Duration
 	sessionTimeout     time.Duration
 	maxSessionsPerUser int
@@ -46,6 +47,7 @@ type Session struct {
 	Metadata     map[string]interface{} `json:"metadata"`
 	IsActive     bool                   `json:"is_active"`
 	Settings     *SessionSettings       `json:"settings"`
+	Threading    *ThreadingInfo         `json:"threading,omitempty"` // branches of the conversation log
 }
 
 // SessionContext holds conversation context and memory
@@ -71,6 +73,7 @@ type SessionSettings struct {
 	PersistMemory     bool    `json:"persist_memory"`
 	EnableTools       bool    `json:"enable_tools"`
 	EnableCodeExec    bool    `json:"enable_code_exec"`
+	Persona           string  `json:"persona,omitempty"` // persona from configs/model/personas.yaml
 }
 
 // Message represents a single message in conversation
@@ -81,6 +84,8 @@ type Message struct {
 	Timestamp time.Time              `json:"timestamp"`
 	Tokens    int                    `json:"tokens"`
 	ModelUsed string                 `json:"model_used,omitempty"`
+	ParentID  string                 `json:"parent_id,omitempty"` // previous message on the branch path
+	BranchID  string                 `json:"branch_id,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 }
 
@@ -142,6 +147,7 @@ func NewSessionManager(configManager *ConfigManager, conversationMgr *Conversati
 		configManager:      configManager,
 		conversationMgr:    conversationMgr,
 		memoryManager:      memoryManager,
+		prompts:            NewPromptEngine(configManager),
 		cleanupInterval:    30 * time.Minute,
 		sessionTimeout:     24 * time.Hour,
 		maxSessionsPerUser: 50,
@@ -182,16 +188,21 @@ func (sm *SessionManager) CreateSession(ctx context.Context, userID, modelName s
 	if settings == nil {
 		settings = sm.getDefaultSettings()
 	}
+	if settings.Persona != "" {
+		if err := sm.applyPersona(settings); err != nil {
+			return nil, err
+		}
+	}
 
 	// Create session context
 	context := &SessionContext{
-		SystemPrompt:    sm.buildSystemPrompt(modelName, userProfile),
 		UserProfile:     userProfile,
 		ConversationLog: make([]Message, 0),
 		ShortTermMemory: make([]MemoryItem, 0),
 		LongTermMemory:  make([]MemoryItem, 0),
 		CustomContext:   make(map[string]interface{}),
 	}
+	context.SystemPrompt = sm.buildSystemPrompt(modelName, settings.Persona, context)
 
 	// Create session
 	session := &Session{
@@ -203,10 +214,12 @@ func (sm *SessionManager) CreateSession(ctx context.Context, userID, modelName s
 		MessageCount: 0,
 		TokensUsed:   0,
 		ModelName:    modelName,
+		Persona:      settings.Persona,
 		Context:      context,
 		Metadata:     make(map[string]interface{}),
 		IsActive:     true,
 		Settings:     settings,
+		Threading:    newThreadingInfo(),
 	}
 
 	// Store session
@@ -281,7 +294,8 @@ func (sm *SessionManager) AddMessage(sessionID string, role, content string, met
 		Metadata:  metadata,
 	}
 
-	// Add to conversation log
+	// Add to conversation log on the active branch
+	sm.attachToBranch(session, &message)
 	session.Context.ConversationLog = append(session.Context.ConversationLog, message)
 	session.MessageCount++
 	session.TokensUsed += int64(message.Tokens)
@@ -304,6 +318,31 @@ func (sm *SessionManager) AddMessage(sessionID string, role, content string, met
 	return &message, nil
 }
 
+// SetSessionPersona switches a session to another persona and rebuilds its system prompt;
+// an empty name returns to the default prompt
+func (sm *SessionManager) SetSessionPersona(sessionID, persona string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	settings := *session.Settings
+	settings.Persona = persona
+	if persona != "" {
+		if err := sm.applyPersona(&settings); err != nil {
+			return err
+		}
+	}
+	*session.Settings = settings
+	session.Persona = persona
+	session.Context.SystemPrompt = sm.buildSystemPrompt(session.ModelName, persona, session.Context)
+	session.LastActivity = time.Now()
+	return nil
+}
+
 // UpdateSessionTitle updates the session title
 func (sm *SessionManager) UpdateSessionTitle(sessionID, title string) error {
 	sm.mu.Lock()
@@ -355,8 +394,8 @@ func (sm *SessionManager) DeleteSession(ctx context.Context, sessionID string) e
 
 // GetSessionContext gets formatted context for model inference
 func (sm *SessionManager) GetSessionContext(sessionID string, includeMemories bool) ([]Message, error) {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
 
 	session, exists := sm.sessions[sessionID]
 	if !exists {
@@ -384,8 +423,8 @@ func (sm *SessionManager) GetSessionContext(sessionID string, includeMemories bo
 		}
 	}
 
-	// Add conversation history
-	messages = append(messages, session.Context.ConversationLog...)
+	// Add conversation history along the active branch
+	messages = append(messages, sm.activePath(session)...)
 
 	return messages, nil
 }
@@ -441,10 +480,46 @@ func (sm *SessionManager) createDefaultUserProfile(userID string) *UserProfile {
 	}
 }
 
-func (sm *SessionManager) buildSystemPrompt(modelName string, userProfile *UserProfile) string {
-	// TODO: Build dynamic system prompt based on model and user profile
-	return fmt.Sprintf("You are a helpful AI assistant using the %s model. The user prefers a %s communication style.",
-		modelName, userProfile.CommunicationStyle)
+// applyPersona checks the persona exists and adopts its sampling settings
+func (sm *SessionManager) applyPersona(settings *SessionSettings) error {
+	persona, err := sm.configManager.GetPersonaConfig(settings.Persona)
+	if err != nil {
+		return err
+	}
+	if persona.Temperature > 0 {
+		settings.Temperature = persona.Temperature
+	}
+	if persona.MaxTokens > 0 {
+		settings.MaxTokens = persona.MaxTokens
+	}
+	return nil
+}
+
+// buildSystemPrompt renders the persona's prompt, or the session-system template
+// without one. A broken template falls back to the built-in default.
+func (sm *SessionManager) buildSystemPrompt(modelName, persona string, context *SessionContext) string {
+	data := &PromptData{
+		Model:   modelName,
+		User:    context.UserProfile,
+		Code:    context.CodeContext,
+		Project: context.ProjectContext,
+		Vars:    context.CustomContext,
+	}
+
+	var prompt string
+	var err error
+	if persona != "" {
+		prompt, err = sm.prompts.RenderPersona(persona, SessionSystemTemplate, data)
+	} else {
+		prompt, err = sm.prompts.Render(SessionSystemTemplate, data)
+	}
+	if err == nil {
+		return prompt
+	}
+
+	log.Warn().Err(err).Str("persona", persona).Msg("Failed to render system prompt, using built-in default")
+	prompt, _ = renderPromptTemplate(promptTemplateIndex(builtinPromptTemplates), SessionSystemTemplate, prepareData(data))
+	return prompt
 }
 
 func (sm *SessionManager) trimContextWindow(session *Session) {
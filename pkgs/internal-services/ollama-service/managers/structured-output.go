@@ -0,0 +1,115 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/structured-output.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+
+	// internal
+	"ocs/src/utils"
+)
+
+// defaultSchemaRetries is how many corrections are requested when schema_retries is unset
+const defaultSchemaRetries = 2
+
+// StructuredOutputError reports a reply that still failed the response schema after every retry
+type StructuredOutputError struct {
+	Attempts int
+	Content  string // the last reply
+	Err      error
+}
+
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("response did not match the schema after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *StructuredOutputError) Unwrap() error {
+	return e.Err
+}
+
+// schemaFormat encodes a response schema as Ollama's structured output format
+func schemaFormat(schema map[string]interface{}) (json.RawMessage, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	format, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response schema: %w", err)
+	}
+	return format, nil
+}
+
+// parseStructuredOutput decodes a reply and checks it against the schema. A
+// markdown code fence around the JSON is tolerated.
+func parseStructuredOutput(content string, schema map[string]interface{}) (interface{}, error) {
+	text := strings.TrimSpace(content)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return nil, fmt.Errorf("reply is not valid JSON: %w", err)
+	}
+	if err := utils.ValidateJSONSchema(schema, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// enforceSchema validates the model's reply against the request's response schema,
+// asking the model to correct itself until it complies or the retries run out.
+// Correction turns are added to usage.
+func (im *InferenceManager) enforceSchema(ctx context.Context, req *InferenceRequest, backend InferenceBackend, ollamaReq *OllamaRequest, resp *OllamaResponse, usage *TokenUsage) (*OllamaResponse, interface{}, int, error) {
+	schema := req.Parameters.ResponseSchema
+	retries := req.Parameters.SchemaRetries
+	if retries <= 0 {
+		retries = defaultSchemaRetries
+	}
+
+	for attempt := 1; ; attempt++ {
+		content := im.extractContent(resp)
+		value, err := parseStructuredOutput(content, schema)
+		if err == nil {
+			return resp, value, attempt, nil
+		}
+		if attempt > retries {
+			return nil, nil, attempt, &StructuredOutputError{Attempts: attempt, Content: content, Err: err}
+		}
+
+		log.Debug().
+			Str("request_id", req.ID).
+			Int("attempt", attempt).
+			Err(err).
+			Msg("Structured output failed validation, requesting a correction")
+
+		ollamaReq.Messages = append(ollamaReq.Messages,
+			OllamaMessage{Role: "assistant", Content: content},
+			OllamaMessage{Role: "user", Content: correctionPrompt(err)},
+		)
+		resp, err = backend.Chat(ctx, ollamaReq)
+		if err != nil {
+			return nil, nil, attempt, fmt.Errorf("%s request failed: %w", backend.Name(), err)
+		}
+		im.calibrateTokens(ollamaReq, resp)
+		usage.InputTokens += resp.PromptEvalCount
+		usage.OutputTokens += resp.EvalCount
+	}
+}
+
+// correctionPrompt tells the model what was wrong with its last reply
+func correctionPrompt(err error) string {
+	return fmt.Sprintf("Your previous reply does not match the required JSON schema: %v\n"+
+		"Reply again with only the corrected JSON document, no explanation or code fences.", err)
+}
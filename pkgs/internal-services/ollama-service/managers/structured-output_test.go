@@ -0,0 +1,122 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/structured-output_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var ticketSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []interface{}{"category", "urgent"},
+	"properties": map[string]interface{}{
+		"category": map[string]interface{}{"type": "string", "enum": []interface{}{"billing", "shipping"}},
+		"urgent":   map[string]interface{}{"type": "boolean"},
+	},
+}
+
+func TestParseStructuredOutput(t *testing.T) {
+	value, err := parseStructuredOutput("```json\n{\"category\":\"billing\",\"urgent\":true}\n```", ticketSchema)
+	if err != nil {
+		t.Fatalf("fenced reply: %v", err)
+	}
+	if value.(map[string]interface{})["category"] != "billing" {
+		t.Errorf("value = %v", value)
+	}
+
+	for name, reply := range map[string]string{
+		"prose":         "The category is billing.",
+		"missing field": `{"category":"billing"}`,
+		"wrong enum":    `{"category":"refunds","urgent":false}`,
+	} {
+		if _, err := parseStructuredOutput(reply, ticketSchema); err == nil {
+			t.Errorf("%s passed validation", name)
+		}
+	}
+}
+
+func TestInferenceEnforcesResponseSchema(t *testing.T) {
+	ollama := newFakeOllama(t, "ollama")
+	cm := schedulerConfig(&LimitsConfig{MaxRequestsPerMinute: 100, MaxTokensPerRequest: 4096, TokenBudgetPerUser: 100000, ResetIntervalHours: 24},
+		ModelConfig{Name: "llama3.2", Specialization: "chat"})
+	mm := NewModelManager(ollama.URL, cm)
+	t.Cleanup(func() { mm.Shutdown(context.Background()) })
+	im := NewInferenceManager(cm, mm, NewTokenManager(cm), nil, nil, ollama.URL)
+
+	run := func(retries int) (*InferenceResult, error) {
+		return im.ProcessInference(context.Background(), &InferenceRequest{
+			ID:         "schema-test",
+			UserID:     "alice",
+			ModelName:  "llama3.2",
+			Messages:   []Message{{Role: "user", Content: "My invoice was charged twice!"}},
+			Parameters: &InferenceParameters{ResponseSchema: ticketSchema, SchemaRetries: retries},
+		})
+	}
+
+	ollama.replies = []string{`{"category":"refunds"}`, `{"category":"billing","urgent":true}`}
+	result, err := run(0)
+	if err != nil {
+		t.Fatalf("ProcessInference: %v", err)
+	}
+	parsed, _ := result.Metadata["structured_output"].(map[string]interface{})
+	if parsed["category"] != "billing" || parsed["urgent"] != true || result.Metadata["schema_attempts"] != 2 {
+		t.Errorf("metadata = %v", result.Metadata)
+	}
+	if result.Usage.InputTokens != 14 || result.Usage.OutputTokens != 6 {
+		t.Errorf("usage = %+v, want both attempts counted", result.Usage)
+	}
+
+	// The schema went to Ollama as the format and the correction quoted the error
+	retry := ollama.bodies[1]
+	if format, _ := retry["format"].(map[string]interface{}); format["type"] != "object" {
+		t.Errorf("format = %v", retry["format"])
+	}
+	messages := retry["messages"].([]interface{})
+	correction := messages[len(messages)-1].(map[string]interface{})["content"].(string)
+	if len(messages) != 3 || !strings.Contains(correction, "category") {
+		t.Errorf("correction turn = %v", messages)
+	}
+
+	ollama.replies = []string{"billing", "still prose"}
+	_, err = run(1)
+	var schemaErr *StructuredOutputError
+	if !errors.As(err, &schemaErr) || schemaErr.Attempts != 2 || schemaErr.Content != "still prose" {
+		t.Errorf("err = %v, want a StructuredOutputError after one retry", err)
+	}
+
+	// A streamed reply cannot be corrected, so it is checked once before the done chunk
+	stream := func(reply string) *StreamChunk {
+		ollama.replies = []string{reply}
+		chunks, err := im.ProcessStreamingInference(context.Background(), &InferenceRequest{
+			ID:         "schema-stream",
+			UserID:     "alice",
+			ModelName:  "llama3.2",
+			Messages:   []Message{{Role: "user", Content: "My parcel is late"}},
+			Parameters: &InferenceParameters{ResponseSchema: ticketSchema},
+		})
+		if err != nil {
+			t.Fatalf("ProcessStreamingInference: %v", err)
+		}
+		var last *StreamChunk
+		for chunk := range chunks {
+			last = chunk
+		}
+		return last
+	}
+	last := stream(`{"category":"shipping","urgent":false}`)
+	if parsed, _ := last.StructuredOutput.(map[string]interface{}); !last.Done || last.Error != "" || parsed["category"] != "shipping" {
+		t.Errorf("done chunk = %+v", last)
+	}
+	if last := stream(`{"category":"shipping"}`); !last.Done || !strings.Contains(last.Error, "did not match the schema") || last.StructuredOutput != nil {
+		t.Errorf("invalid stream ended with %+v", last)
+	}
+	if stats, _ := mm.GetModelStats("llama3.2"); stats.RequestCount != 3 || stats.ErrorCount != 1 || !strings.Contains(stats.LastError, "schema") {
+		t.Errorf("model stats = %+v", stats)
+	}
+}
@@ -7,8 +7,11 @@ package managers
 import (
 	// stdlib
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"math"
+	"strings"
 	"sync"
 	"time"
 
@@ -102,8 +105,15 @@ type RateLimiter struct {
 type ContextOptimizer struct {
 	mu               sync.RWMutex
 	compressionRules map[string]*CompressionRule
-	summaryCache     map[string]*ContextSummary
+	summaryCache     map[string]*ContextSummary // rolling summaries by session ID
 	tokenEstimator   *TokenEstimator
+	summarizer       ContextSummarizer
+}
+
+// ContextSummarizer compresses evicted messages into a summary, folding in the previous one
+type ContextSummarizer interface {
+	SummarizeContext(ctx context.Context, previousSummary string, messages []Message, maxTokens int) (string, error)
+	SummarizerBudget() int // max input tokens the summarizer accepts
 }
 
 // CompressionRule defines how to compress different types of content
@@ -124,21 +134,28 @@ type ContextSummary struct {
 	CreatedAt        time.Time              `json:"created_at"`
 	KeyPoints        []string               `json:"key_points"`
 	Metadata         map[string]interface{} `json:"metadata"`
+	CoveredMessages  int                    `json:"covered_messages"`          // evicted messages folded in so far
+	CoveredHash      string                 `json:"covered_hash,omitempty"`    // fingerprint of those messages
+	LastMessageID    string                 `json:"last_message_id,omitempty"` // newest message covered
+	Strategy         string                 `json:"strategy"`                  // llm or truncate
 }
 
 // TokenEstimator provides accurate token counting
 type TokenEstimator struct {
 	mu            sync.RWMutex
 	modelEncoders map[string]*ModelEncoder
-	fallbackRatio float64 // chars per token for unknown models
+	tokenizers    map[string]Tokenizer // by model family
+	fallbackRatio float64              // chars per token for unknown models
 }
 
 // ModelEncoder handles model-specific token encoding
 type ModelEncoder struct {
 	ModelName     string  `json:"model_name"`
-	CharsPerToken float64 `json:"chars_per_token"`
-	Overhead      int     `json:"overhead"` // Additional tokens per message
+	Family        string  `json:"family,omitempty"` // tokenizer family; derived from the name when empty
+	CharsPerToken float64 `json:"chars_per_token"`  // fallback when no tokenizer is loaded
+	Overhead      int     `json:"overhead"`         // Additional tokens per message
 	MaxTokens     int     `json:"max_tokens"`
+	Samples       int     `json:"samples"` // calibration samples folded into CharsPerToken
 }
 
 // TokenUsageRequest represents a token usage request
@@ -172,6 +189,21 @@ func NewTokenManager(configManager *ConfigManager) *TokenManager {
 		shutdown:         make(chan struct{}),
 	}
 
+	// Load vocab files and per-model tokenizer families
+	tokenizerDir := "./data/tokenizers"
+	if serverConfig, err := configManager.GetServerConfig(); err == nil && serverConfig.TokenizerDir != "" {
+		tokenizerDir = serverConfig.TokenizerDir
+	}
+	estimator := tm.contextOptimizer.tokenEstimator
+	estimator.LoadTokenizers(tokenizerDir)
+	if modelConfigs, err := configManager.GetModelConfigs(); err == nil {
+		for _, config := range modelConfigs {
+			if config.TokenizerFamily != "" {
+				estimator.SetModelFamily(config.Name, config.TokenizerFamily)
+			}
+		}
+	}
+
 	// Start periodic reset
 	tm.resetTicker = time.NewTicker(time.Hour)
 	go tm.runPeriodicReset()
@@ -246,9 +278,30 @@ func (tm *TokenManager) EstimateTokens(text, modelName string) int {
 	return tm.contextOptimizer.tokenEstimator.EstimateTokens(text, modelName)
 }
 
+// CalibrateTokens feeds the prompt token count reported by Ollama back into the estimator
+func (tm *TokenManager) CalibrateTokens(modelName string, prompt []string, promptTokens int) {
+	chars := 0
+	for _, text := range prompt {
+		chars += len(text)
+	}
+	tm.contextOptimizer.tokenEstimator.Calibrate(modelName, chars, len(prompt), promptTokens)
+}
+
 // OptimizeContext optimizes context to fit within token limits
-func (tm *TokenManager) OptimizeContext(messages []Message, maxTokens int, modelName string) ([]Message, *ContextSummary, error) {
-	return tm.contextOptimizer.OptimizeContext(messages, maxTokens, modelName)
+func (tm *TokenManager) OptimizeContext(ctx context.Context, sessionID string, messages []Message, maxTokens int, modelName string) ([]Message, *ContextSummary, error) {
+	return tm.contextOptimizer.OptimizeContext(ctx, sessionID, messages, maxTokens, modelName)
+}
+
+// SetSummarizer sets the model-backed summarizer used when trimming context
+func (tm *TokenManager) SetSummarizer(summarizer ContextSummarizer) {
+	tm.contextOptimizer.SetSummarizer(summarizer)
+}
+
+// ClearContextSummary drops the cached rolling summary for a session
+func (tm *TokenManager) ClearContextSummary(sessionID string) {
+	tm.contextOptimizer.mu.Lock()
+	defer tm.contextOptimizer.mu.Unlock()
+	delete(tm.contextOptimizer.summaryCache, sessionID)
 }
 
 // GetUserUsage returns detailed usage statistics for a user
@@ -265,6 +318,18 @@ func (tm *TokenManager) GetUserUsage(userID string) (*TokenCounter, *UserTokenBu
 	return counter, budget, nil
 }
 
+// GetUserBudget returns a copy of a user's token budget, created from limits.yaml on first use
+func (tm *TokenManager) GetUserBudget(userID string) (*UserTokenBudget, error) {
+	if _, err := tm.configManager.GetLimitsConfig(); err != nil {
+		return nil, err
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	budget := *tm.getUserBudget(userID)
+	return &budget, nil
+}
+
 // SetUserBudget sets token budget for a user
 func (tm *TokenManager) SetUserBudget(userID string, budget *UserTokenBudget) error {
 	tm.mu.Lock()
@@ -477,7 +542,14 @@ func NewContextOptimizer() *ContextOptimizer {
 	}
 }
 
-func (co *ContextOptimizer) OptimizeContext(messages []Message, maxTokens int, modelName string) ([]Message, *ContextSummary, error) {
+// SetSummarizer sets the summarizer; nil restores truncation only
+func (co *ContextOptimizer) SetSummarizer(summarizer ContextSummarizer) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	co.summarizer = summarizer
+}
+
+func (co *ContextOptimizer) OptimizeContext(ctx context.Context, sessionID string, messages []Message, maxTokens int, modelName string) ([]Message, *ContextSummary, error) {
 	currentTokens := co.tokenEstimator.EstimateMessagesTokens(messages, modelName)
 
 	if currentTokens <= maxTokens {
@@ -501,8 +573,12 @@ func (co *ContextOptimizer) OptimizeContext(messages []Message, maxTokens int, m
 		recentMessages = messages
 	}
 
-	// Create summary of compressible messages
-	summary := co.createSummary(compressibleMessages, modelName)
+	// Summarize with the model when possible, otherwise truncate
+	summary, err := co.summarizeWithModel(ctx, sessionID, compressibleMessages, maxTokens, modelName)
+	if err != nil {
+		log.Warn().Err(err).Str("session_id", sessionID).Msg("Context summarization unavailable, truncating")
+		summary = co.createSummary(compressibleMessages, modelName)
+	}
 
 	// Create system message with summary
 	summaryMessage := Message{
@@ -518,6 +594,151 @@ func (co *ContextOptimizer) OptimizeContext(messages []Message, maxTokens int, m
 	return optimizedMessages, summary, nil
 }
 
+// summarizeWithModel extends the session's rolling summary with newly evicted messages,
+// folding input larger than the summarizer accepts in chunks
+func (co *ContextOptimizer) summarizeWithModel(ctx context.Context, sessionID string, evicted []Message, maxTokens int, modelName string) (*ContextSummary, error) {
+	co.mu.RLock()
+	summarizer := co.summarizer
+	cached := co.summaryCache[sessionID]
+	co.mu.RUnlock()
+
+	if summarizer == nil {
+		return nil, fmt.Errorf("no summarizer configured")
+	}
+	if len(evicted) == 0 {
+		return nil, fmt.Errorf("nothing to summarize")
+	}
+
+	// Only messages evicted since the cached summary need summarizing
+	previous := ""
+	pending := evicted
+	if cached != nil && sessionID != "" {
+		if start := co.coveredPrefix(cached, evicted); start >= 0 {
+			if start == len(evicted) {
+				return cached, nil
+			}
+			previous = cached.Summary
+			pending = evicted[start:]
+		}
+	}
+
+	summaryTokens := maxTokens / 4
+	if summaryTokens > 512 {
+		summaryTokens = 512
+	}
+
+	// Each call carries the summary so far, which is at most summaryTokens once folded
+	reserved := co.tokenEstimator.EstimateTokens(previous, modelName)
+	if reserved < summaryTokens {
+		reserved = summaryTokens
+	}
+	chunkTokens := summarizer.SummarizerBudget() - reserved
+	if chunkTokens <= 0 {
+		return nil, fmt.Errorf("summarizer budget of %d tokens leaves no room for messages", summarizer.SummarizerBudget())
+	}
+
+	chunks := co.chunkMessages(pending, chunkTokens, modelName)
+	text := previous
+	for _, chunk := range chunks {
+		folded, err := summarizer.SummarizeContext(ctx, text, chunk, summaryTokens)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(folded) == "" {
+			return nil, fmt.Errorf("summarizer returned empty summary")
+		}
+		text = folded
+	}
+
+	summary := &ContextSummary{
+		OriginalTokens:   co.tokenEstimator.EstimateMessagesTokens(evicted, modelName),
+		CompressedTokens: co.tokenEstimator.EstimateTokens(text, modelName),
+		Summary:          text,
+		CreatedAt:        time.Now(),
+		KeyPoints:        []string{},
+		Metadata:         map[string]interface{}{"chunks": len(chunks)},
+		CoveredMessages:  len(evicted),
+		CoveredHash:      messagesFingerprint(evicted),
+		LastMessageID:    evicted[len(evicted)-1].ID,
+		Strategy:         "llm",
+	}
+
+	if sessionID != "" {
+		co.mu.Lock()
+		co.summaryCache[sessionID] = summary
+		co.mu.Unlock()
+	}
+
+	return summary, nil
+}
+
+// coveredPrefix returns how many leading evicted messages the summary covers, or -1 when
+// they are not the messages it summarized, as after a branch switch or an edit
+func (co *ContextOptimizer) coveredPrefix(summary *ContextSummary, evicted []Message) int {
+	if summary.CoveredMessages > len(evicted) || messagesFingerprint(evicted[:summary.CoveredMessages]) != summary.CoveredHash {
+		return -1
+	}
+	return summary.CoveredMessages
+}
+
+// messagesFingerprint hashes the IDs, roles and content of messages
+func messagesFingerprint(messages []Message) string {
+	hash := sha256.New()
+	for _, msg := range messages {
+		fmt.Fprintf(hash, "%s\x00%s\x00%d:%s\x00", msg.ID, msg.Role, len(msg.Content), msg.Content)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// chunkMessages splits messages into runs of at most limit tokens, cutting up any message
+// too large for a run of its own
+func (co *ContextOptimizer) chunkMessages(messages []Message, limit int, modelName string) [][]Message {
+	var chunks [][]Message
+	var current []Message
+	currentTokens := 0
+	for _, msg := range messages {
+		for _, part := range co.splitMessage(msg, limit, modelName) {
+			tokens := co.tokenEstimator.EstimateMessagesTokens([]Message{part}, modelName)
+			if len(current) > 0 && currentTokens+tokens > limit {
+				chunks = append(chunks, current)
+				current, currentTokens = nil, 0
+			}
+			current = append(current, part)
+			currentTokens += tokens
+		}
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// splitMessage cuts a message over limit tokens into consecutive parts that fit, halving
+// the part size until every part does
+func (co *ContextOptimizer) splitMessage(msg Message, limit int, modelName string) []Message {
+	runes := []rune(msg.Content)
+	if len(runes) <= 1 || co.tokenEstimator.EstimateMessagesTokens([]Message{msg}, modelName) <= limit {
+		return []Message{msg}
+	}
+	for pieces := 2; ; pieces *= 2 {
+		size := (len(runes) + pieces - 1) / pieces
+		parts := make([]Message, 0, pieces)
+		for start := 0; start < len(runes); start += size {
+			part := msg
+			part.Content = string(runes[start:min(start+size, len(runes))])
+			part.Tokens = 0
+			if size > 1 && co.tokenEstimator.EstimateTokens(part.Content, modelName) > limit {
+				parts = nil
+				break
+			}
+			parts = append(parts, part)
+		}
+		if parts != nil {
+			return parts
+		}
+	}
+}
+
 func (co *ContextOptimizer) createSummary(messages []Message, modelName string) *ContextSummary {
 	// Simple summarization - in practice, you'd use a model to create better summaries
 	var totalContent string
@@ -543,6 +764,8 @@ func (co *ContextOptimizer) createSummary(messages []Message, modelName string)
 		Summary:          summary,
 		CreatedAt:        time.Now(),
 		KeyPoints:        []string{}, // TODO: Extract key points
+		CoveredMessages:  len(messages),
+		Strategy:         "truncate",
 	}
 }
 
@@ -573,14 +796,97 @@ func NewTokenEstimator() *TokenEstimator {
 
 func (te *TokenEstimator) EstimateTokens(text, modelName string) int {
 	te.mu.RLock()
-	defer te.mu.RUnlock()
+	encoder := te.lookupEncoder(modelName)
+	family := tokenizerFamily(modelName)
+	ratio := te.fallbackRatio
+	overhead := 0
+	if encoder != nil {
+		ratio = encoder.CharsPerToken
+		overhead = encoder.Overhead
+		if encoder.Family != "" {
+			family = encoder.Family
+		}
+	}
+	tokenizer := te.tokenizers[family]
+	te.mu.RUnlock()
 
-	encoder, exists := te.modelEncoders[modelName]
-	if !exists {
-		return int(math.Ceil(float64(len(text)) / te.fallbackRatio))
+	if tokenizer != nil {
+		return tokenizer.CountTokens(text) + overhead
 	}
+	return int(math.Ceil(float64(len(text))/ratio)) + overhead
+}
+
+// LoadTokenizers loads vocab files for the known model families from dir
+func (te *TokenEstimator) LoadTokenizers(dir string) {
+	tokenizers := LoadTokenizers(dir)
 
-	return int(math.Ceil(float64(len(text))/encoder.CharsPerToken)) + encoder.Overhead
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	te.tokenizers = tokenizers
+}
+
+// SetModelFamily pins the tokenizer family used for a model
+func (te *TokenEstimator) SetModelFamily(modelName, family string) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	encoder := te.lookupEncoder(modelName)
+	if encoder == nil {
+		encoder = &ModelEncoder{ModelName: modelName, CharsPerToken: te.fallbackRatio}
+		te.modelEncoders[modelName] = encoder
+	}
+	encoder.Family = family
+}
+
+// Calibrate moves a model's chars-per-token ratio towards the ratio Ollama observed for a prompt
+func (te *TokenEstimator) Calibrate(modelName string, chars, messages, promptTokens int) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	encoder := te.lookupEncoder(modelName)
+	if encoder == nil {
+		encoder = &ModelEncoder{ModelName: baseModelName(modelName), CharsPerToken: te.fallbackRatio}
+		te.modelEncoders[encoder.ModelName] = encoder
+	}
+
+	family := encoder.Family
+	if family == "" {
+		family = tokenizerFamily(modelName)
+	}
+	if te.tokenizers[family] != nil {
+		return // Counted exactly, the ratio is unused
+	}
+
+	contentTokens := promptTokens - encoder.Overhead*messages
+	if chars == 0 || contentTokens <= 0 {
+		return
+	}
+
+	// Prompt cache hits report only part of the prompt; ignore implausible ratios
+	observed := float64(chars) / float64(contentTokens)
+	if observed < 1.0 || observed > 8.0 {
+		return
+	}
+
+	alpha := math.Max(0.1, 1.0/float64(encoder.Samples+2))
+	encoder.CharsPerToken += alpha * (observed - encoder.CharsPerToken)
+	encoder.Samples++
+}
+
+// lookupEncoder finds an encoder by full name, then by name without the tag
+func (te *TokenEstimator) lookupEncoder(modelName string) *ModelEncoder {
+	if encoder, exists := te.modelEncoders[modelName]; exists {
+		return encoder
+	}
+	return te.modelEncoders[baseModelName(modelName)]
+}
+
+// baseModelName strips the ":tag" suffix from an Ollama model name
+func baseModelName(modelName string) string {
+	if i := strings.Index(modelName, ":"); i >= 0 {
+		return modelName[:i]
+	}
+	return modelName
 }
 
 func (te *TokenEstimator) EstimateMessagesTokens(messages []Message, modelName string) int {
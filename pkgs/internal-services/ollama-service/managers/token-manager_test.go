@@ -0,0 +1,166 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/token-manager_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeSummarizer records each fold and answers with S<n>, or fails when err is set
+type fakeSummarizer struct {
+	budget int
+	err    error
+	calls  []summarizeCall
+}
+
+type summarizeCall struct {
+	previous string
+	messages []Message
+}
+
+func (fs *fakeSummarizer) SummarizeContext(ctx context.Context, previousSummary string, messages []Message, maxTokens int) (string, error) {
+	if fs.err != nil {
+		return "", fs.err
+	}
+	fs.calls = append(fs.calls, summarizeCall{previous: previousSummary, messages: messages})
+	return fmt.Sprintf("S%d", len(fs.calls)), nil
+}
+
+func (fs *fakeSummarizer) SummarizerBudget() int { return fs.budget }
+
+// conversation returns n turns of about 25 tokens each with IDs prefix-0, prefix-1, ...
+func conversation(prefix string, n int) []Message {
+	messages := make([]Message, n)
+	for i := range messages {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		messages[i] = Message{ID: fmt.Sprintf("%s-%d", prefix, i), Role: role, Content: fmt.Sprintf("%s turn %02d ", prefix, i) + strings.Repeat("x", 64)}
+	}
+	return messages
+}
+
+// ids lists the IDs of messages
+func ids(messages []Message) string {
+	list := make([]string, len(messages))
+	for i, msg := range messages {
+		list[i] = msg.ID
+	}
+	return strings.Join(list, ",")
+}
+
+func TestContextSummaryRollsForward(t *testing.T) {
+	co := NewContextOptimizer()
+	summarizer := &fakeSummarizer{budget: 10000}
+	co.SetSummarizer(summarizer)
+	ctx := context.Background()
+	optimize := func(messages []Message) ([]Message, *ContextSummary) {
+		t.Helper()
+		optimized, summary, err := co.OptimizeContext(ctx, "s1", messages, 200, "llama3.2")
+		if err != nil || summary == nil {
+			t.Fatalf("OptimizeContext = %v, %v", summary, err)
+		}
+		return optimized, summary
+	}
+
+	// Over budget: the 15 oldest of 20 turns are summarized by the model
+	messages := conversation("main", 20)
+	optimized, summary := optimize(messages)
+	if summary.Strategy != "llm" || len(summarizer.calls) != 1 || summarizer.calls[0].previous != "" || len(summarizer.calls[0].messages) != 15 {
+		t.Fatalf("summary = %+v, calls = %+v", summary, summarizer.calls)
+	}
+	if len(optimized) != 6 || optimized[0].Role != "system" || !strings.Contains(optimized[0].Content, "S1") || optimized[1].ID != "main-15" {
+		t.Errorf("optimized = %+v", optimized)
+	}
+
+	// The same history is served from the cache
+	if _, again := optimize(messages); again != summary || len(summarizer.calls) != 1 {
+		t.Errorf("cache miss: summary = %+v, calls = %d", again, len(summarizer.calls))
+	}
+
+	// Four more turns fold only the newly evicted ones into the previous summary
+	messages = append(messages, conversation("more", 4)...)
+	_, summary = optimize(messages)
+	if len(summarizer.calls) != 2 || summarizer.calls[1].previous != "S1" || ids(summarizer.calls[1].messages) != "main-15,main-16,main-17" {
+		t.Fatalf("fold = %+v", summarizer.calls[1])
+	}
+	if summary.Summary != "S2" || summary.CoveredMessages != 18 {
+		t.Errorf("folded summary = %+v", summary)
+	}
+
+	// A different history with the same message IDs, as after a branch switch, starts over
+	branch := append(conversation("main", 20), conversation("more", 4)...)
+	branch[3].Content = "edited on another branch"
+	optimize(branch)
+	if len(summarizer.calls) != 3 || summarizer.calls[2].previous != "" || len(summarizer.calls[2].messages) != 18 {
+		t.Errorf("branch = %+v", summarizer.calls[2])
+	}
+}
+
+func TestContextSummaryChunksOverBudgetInput(t *testing.T) {
+	co := NewContextOptimizer()
+	summarizer := &fakeSummarizer{budget: 150}
+	co.SetSummarizer(summarizer)
+
+	// With 50 tokens reserved for the summary, each call takes at most 100 tokens of turns
+	messages := conversation("main", 20)
+	messages[2].Content = strings.Repeat("long message ", 60) // ~210 tokens on its own
+	_, summary, err := co.OptimizeContext(context.Background(), "s1", messages, 200, "llama3.2")
+	if err != nil || summary.Strategy != "llm" {
+		t.Fatalf("summary = %+v, %v", summary, err)
+	}
+	if len(summarizer.calls) < 3 || summary.Metadata["chunks"] != len(summarizer.calls) {
+		t.Fatalf("calls = %d, summary = %+v", len(summarizer.calls), summary)
+	}
+
+	var content strings.Builder
+	for i, call := range summarizer.calls {
+		if tokens := co.tokenEstimator.EstimateMessagesTokens(call.messages, "llama3.2"); tokens > 100 {
+			t.Errorf("chunk %d has %d tokens", i, tokens)
+		}
+		if want := fmt.Sprintf("S%d", i); i > 0 && call.previous != want {
+			t.Errorf("chunk %d folded into %q, want %q", i, call.previous, want)
+		}
+		for _, msg := range call.messages {
+			content.WriteString(msg.Content)
+		}
+	}
+
+	// Nothing is dropped: the chunks hold every evicted turn in order
+	var evicted strings.Builder
+	for _, msg := range messages[:15] {
+		evicted.WriteString(msg.Content)
+	}
+	if content.String() != evicted.String() {
+		t.Error("chunks do not add up to the evicted turns")
+	}
+}
+
+func TestContextSummaryFallsBackToTruncation(t *testing.T) {
+	co := NewContextOptimizer()
+	messages := conversation("main", 20)
+
+	// Without a summarizer, or when it fails, the evicted turns are truncated
+	for _, summarizer := range []ContextSummarizer{nil, &fakeSummarizer{budget: 10000, err: fmt.Errorf("model unavailable")}} {
+		co.SetSummarizer(summarizer)
+		optimized, summary, err := co.OptimizeContext(context.Background(), "s1", messages, 200, "llama3.2")
+		if err != nil || summary.Strategy != "truncate" || !strings.HasPrefix(summary.Summary, "user: main turn 00") {
+			t.Errorf("summary = %+v, %v", summary, err)
+		}
+		if len(optimized) != 6 || !strings.HasPrefix(optimized[0].Content, "Previous conversation summary: ") {
+			t.Errorf("optimized = %+v", optimized)
+		}
+	}
+
+	// Under budget nothing changes
+	if optimized, summary, err := co.OptimizeContext(context.Background(), "s1", messages[:3], 200, "llama3.2"); err != nil || summary != nil || len(optimized) != 3 {
+		t.Errorf("under budget = %d messages, %+v, %v", len(optimized), summary, err)
+	}
+}
@@ -0,0 +1,525 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/tokenizer.go
+
+package managers
+
+import (
+	// stdlib
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+)
+
+// Tokenizer counts tokens the way a model family's vocabulary does
+type Tokenizer interface {
+	Name() string
+	CountTokens(text string) int
+}
+
+// TokenizerFamilies lists the model families with loadable vocab files
+var TokenizerFamilies = []string{"llama", "qwen", "mistral"}
+
+// BPETokenizer implements byte-level (GPT/tiktoken style) and SentencePiece-style BPE
+// from a Hugging Face tokenizer.json file
+type BPETokenizer struct {
+	mu           sync.Mutex
+	name         string
+	vocab        map[string]int
+	merges       map[[2]int]mergeRule
+	byteLevel    bool
+	byteFallback bool
+	splitter     *regexp.Regexp
+	byteEncoder  [256]rune
+	cache        map[string]int
+}
+
+// tokenizerFile is the subset of tokenizer.json used for counting
+type tokenizerFile struct {
+	Model struct {
+		Type         string          `json:"type"`
+		Vocab        json.RawMessage `json:"vocab"`
+		Merges       json.RawMessage `json:"merges"`
+		ByteFallback bool            `json:"byte_fallback"`
+	} `json:"model"`
+	PreTokenizer json.RawMessage `json:"pre_tokenizer"`
+	Decoder      json.RawMessage `json:"decoder"`
+}
+
+const (
+	// defaultSplitPattern is the llama3/qwen2 pre-tokenizer pattern without its lookahead branch
+	defaultSplitPattern  = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`
+	tokenizerCacheLimit  = 50000
+	maxCachedWordBytes   = 256
+	sentencePieceSpace   = "▁"
+	tokenizerFileName    = "tokenizer.json"
+	lookaheadWhitespaces = `\s+(?!\S)|`
+)
+
+// LoadTokenizers loads <dir>/<family>/tokenizer.json or <dir>/<family>.json for each known family
+func LoadTokenizers(dir string) map[string]Tokenizer {
+	tokenizers := make(map[string]Tokenizer)
+	for _, family := range TokenizerFamilies {
+		candidates := []string{
+			filepath.Join(dir, family, tokenizerFileName),
+			filepath.Join(dir, family+".json"),
+		}
+		for _, path := range candidates {
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			tokenizer, err := LoadBPETokenizer(family, path)
+			if err != nil {
+				log.Warn().Err(err).Str("family", family).Str("path", path).Msg("Failed to load tokenizer")
+				break
+			}
+			tokenizers[family] = tokenizer
+			log.Info().Str("family", family).Str("path", path).Msg("Loaded tokenizer")
+			break
+		}
+	}
+	return tokenizers
+}
+
+// LoadBPETokenizer parses a Hugging Face tokenizer.json with a BPE model
+func LoadBPETokenizer(name, path string) (*BPETokenizer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read tokenizer: %w", err)
+	}
+
+	var file tokenizerFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse tokenizer: %w", err)
+	}
+	if file.Model.Type != "" && file.Model.Type != "BPE" {
+		return nil, fmt.Errorf("unsupported tokenizer model type %q", file.Model.Type)
+	}
+
+	bt := &BPETokenizer{
+		name:         name,
+		vocab:        make(map[string]int),
+		merges:       make(map[[2]int]mergeRule),
+		byteFallback: file.Model.ByteFallback,
+		cache:        make(map[string]int),
+	}
+	if err := json.Unmarshal(file.Model.Vocab, &bt.vocab); err != nil {
+		return nil, fmt.Errorf("parse vocab: %w", err)
+	}
+	if err := bt.loadMerges(file.Model.Merges); err != nil {
+		return nil, err
+	}
+
+	// Byte-level vocabularies announce themselves through their pre-tokenizer or decoder
+	bt.byteLevel = strings.Contains(string(file.PreTokenizer), "ByteLevel") || strings.Contains(string(file.Decoder), "ByteLevel")
+	if bt.byteLevel {
+		bt.byteEncoder = bytesToUnicode()
+		bt.splitter = compileSplitPattern(file.PreTokenizer)
+	}
+
+	return bt, nil
+}
+
+// Name returns the tokenizer family
+func (bt *BPETokenizer) Name() string {
+	return bt.name
+}
+
+// CountTokens returns the number of tokens text encodes to
+func (bt *BPETokenizer) CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	count := 0
+	for _, word := range bt.preTokenize(text) {
+		count += bt.countWord(word)
+	}
+	return count
+}
+
+// preTokenize splits text into the units BPE merges are applied within
+func (bt *BPETokenizer) preTokenize(text string) []string {
+	if !bt.byteLevel {
+		return sentencePieceWords(text)
+	}
+
+	words := make([]string, 0)
+	for pos := 0; pos < len(text); {
+		loc := bt.splitter.FindStringIndex(text[pos:])
+		if loc == nil || loc[1] == 0 {
+			_, size := utf8.DecodeRuneInString(text[pos:])
+			words = append(words, text[pos:pos+size])
+			pos += size
+			continue
+		}
+		end := pos + loc[1]
+		// Emulate \s+(?!\S): leave the last space for the following word. Runs ending in
+		// a line break came from the \s*[\r\n]+ branch and stay whole.
+		match := text[pos:end]
+		if end < len(text) && strings.TrimSpace(match) == "" && utf8.RuneCountInString(match) > 1 && !strings.ContainsAny(match[len(match)-1:], "\r\n") {
+			if next, _ := utf8.DecodeRuneInString(text[end:]); !unicode.IsSpace(next) {
+				_, size := utf8.DecodeLastRuneInString(match)
+				end -= size
+			}
+		}
+		words = append(words, text[pos:end])
+		pos = end
+	}
+	return words
+}
+
+// countWord applies BPE merges to a single pre-tokenized word
+func (bt *BPETokenizer) countWord(word string) int {
+	bt.mu.Lock()
+	if count, ok := bt.cache[word]; ok {
+		bt.mu.Unlock()
+		return count
+	}
+	bt.mu.Unlock()
+
+	count := 0
+	for _, symbol := range bt.merge(bt.initialSymbols(word)) {
+		if symbol.id < 0 && bt.byteFallback {
+			// Unknown pieces become one <0xNN> token per byte
+			count += symbol.size
+			continue
+		}
+		count++
+	}
+
+	// Long words are rare repeats and would crowd out the common ones
+	if len(word) > maxCachedWordBytes {
+		return count
+	}
+	bt.mu.Lock()
+	if len(bt.cache) >= tokenizerCacheLimit {
+		bt.cache = make(map[string]int)
+	}
+	bt.cache[word] = count
+	bt.mu.Unlock()
+
+	return count
+}
+
+// merge applies merges lowest rank first, leftmost first on ties, and returns the
+// resulting pieces. Symbols form a linked list and candidate pairs wait in a heap, so a
+// word of n symbols merges in O(n log n); entries whose symbols changed since they were
+// queued are skipped when popped.
+func (bt *BPETokenizer) merge(symbols []string) []bpeSymbol {
+	nodes := make([]bpeSymbol, len(symbols))
+	for i, symbol := range symbols {
+		id, ok := bt.vocab[symbol]
+		if !ok {
+			id = unknownSymbol
+		}
+		nodes[i] = bpeSymbol{id: id, size: len(symbol), prev: i - 1, next: i + 1}
+	}
+	if len(nodes) < 2 {
+		return nodes
+	}
+	nodes[len(nodes)-1].next = -1
+
+	queue := make(mergeQueue, 0, len(nodes))
+	push := func(left int) {
+		right := nodes[left].next
+		if right < 0 {
+			return
+		}
+		if rule, ok := bt.merges[[2]int{nodes[left].id, nodes[right].id}]; ok {
+			queue.push(bpeMerge{rule: rule, left: left, leftID: nodes[left].id, rightID: nodes[right].id})
+		}
+	}
+	for i := 0; i < len(nodes)-1; i++ {
+		push(i)
+	}
+
+	live := len(nodes)
+	for len(queue) > 0 {
+		candidate := queue.pop()
+		left := &nodes[candidate.left]
+		right := left.next
+		if left.id != candidate.leftID || right < 0 || nodes[right].id != candidate.rightID {
+			continue
+		}
+
+		left.id = candidate.rule.id
+		left.size += nodes[right].size
+		left.next = nodes[right].next
+		if left.next >= 0 {
+			nodes[left.next].prev = candidate.left
+		}
+		nodes[right].id = mergedSymbol
+		live--
+
+		if left.prev >= 0 {
+			push(left.prev)
+		}
+		push(candidate.left)
+	}
+
+	pieces := make([]bpeSymbol, 0, live)
+	for i := 0; i >= 0; i = nodes[i].next {
+		pieces = append(pieces, nodes[i])
+	}
+	return pieces
+}
+
+// Symbol IDs outside the vocabulary; neither takes part in any merge
+const (
+	unknownSymbol = -1
+	mergedSymbol  = -2
+)
+
+// mergeRule is a merge's priority and the vocabulary ID it produces
+type mergeRule struct {
+	rank int
+	id   int
+}
+
+// bpeSymbol is a piece of a word being merged, linked to its live neighbours
+type bpeSymbol struct {
+	id         int
+	size       int // bytes of the word it covers
+	prev, next int
+}
+
+// bpeMerge is a queued merge of the symbol at left with the one after it
+type bpeMerge struct {
+	rule    mergeRule
+	left    int
+	leftID  int
+	rightID int
+}
+
+// mergeQueue is a binary min-heap of merges ordered by rank, then by position
+type mergeQueue []bpeMerge
+
+func (mq mergeQueue) less(i, j int) bool {
+	if mq[i].rule.rank != mq[j].rule.rank {
+		return mq[i].rule.rank < mq[j].rule.rank
+	}
+	return mq[i].left < mq[j].left
+}
+
+func (mq *mergeQueue) push(m bpeMerge) {
+	*mq = append(*mq, m)
+	q := *mq
+	for i := len(q) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if !q.less(i, parent) {
+			break
+		}
+		q[i], q[parent] = q[parent], q[i]
+		i = parent
+	}
+}
+
+func (mq *mergeQueue) pop() bpeMerge {
+	q := *mq
+	top := q[0]
+	last := len(q) - 1
+	q[0] = q[last]
+	q = q[:last]
+	for i := 0; ; {
+		smallest, left, right := i, 2*i+1, 2*i+2
+		if left < len(q) && q.less(left, smallest) {
+			smallest = left
+		}
+		if right < len(q) && q.less(right, smallest) {
+			smallest = right
+		}
+		if smallest == i {
+			break
+		}
+		q[i], q[smallest] = q[smallest], q[i]
+		i = smallest
+	}
+	*mq = q
+	return top
+}
+
+// sentencePieceWords splits text the way a SentencePiece normalizer sees it: spaces
+// become ▁ and each word carries the ▁ before it. Longer runs of spaces keep the rest
+// together so multi-space pieces can merge, and line breaks, which these vocabularies
+// only encode through byte fallback, stand on their own.
+func sentencePieceWords(text string) []string {
+	const (
+		otherRun = iota
+		spaceRun
+		breakRun
+	)
+	type run struct{ start, end, class int }
+
+	normalized := sentencePieceSpace + strings.ReplaceAll(text, " ", sentencePieceSpace)
+	runs := make([]run, 0)
+	for i, r := range normalized {
+		class := otherRun
+		switch r {
+		case '▁':
+			class = spaceRun
+		case '\n', '\r':
+			class = breakRun
+		}
+		if n := len(runs); n > 0 && runs[n-1].class == class {
+			runs[n-1].end = i + utf8.RuneLen(r)
+			continue
+		}
+		runs = append(runs, run{start: i, end: i + utf8.RuneLen(r), class: class})
+	}
+
+	words := make([]string, 0, len(runs))
+	for i, current := range runs {
+		if current.class == spaceRun && i+1 < len(runs) && runs[i+1].class == otherRun {
+			// The last ▁ leads the following word
+			lead := current.end - len(sentencePieceSpace)
+			if lead > current.start {
+				words = append(words, normalized[current.start:lead])
+			}
+			runs[i+1].start = lead
+			continue
+		}
+		words = append(words, normalized[current.start:current.end])
+	}
+	return words
+}
+
+// initialSymbols splits a word into single characters in vocabulary space
+func (bt *BPETokenizer) initialSymbols(word string) []string {
+	if bt.byteLevel {
+		symbols := make([]string, 0, len(word))
+		for i := 0; i < len(word); i++ {
+			symbols = append(symbols, string(bt.byteEncoder[word[i]]))
+		}
+		return symbols
+	}
+
+	symbols := make([]string, 0, utf8.RuneCountInString(word))
+	for _, r := range word {
+		symbols = append(symbols, string(r))
+	}
+	return symbols
+}
+
+// loadMerges accepts both "a b" and ["a", "b"] merge encodings
+func (bt *BPETokenizer) loadMerges(raw json.RawMessage) error {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var pairs []string
+	if err := json.Unmarshal(raw, &pairs); err == nil {
+		for rank, pair := range pairs {
+			parts := strings.SplitN(pair, " ", 2)
+			if len(parts) == 2 {
+				bt.addMerge(rank, parts[0], parts[1])
+			}
+		}
+		return nil
+	}
+
+	var tuples [][2]string
+	if err := json.Unmarshal(raw, &tuples); err != nil {
+		return fmt.Errorf("parse merges: %w", err)
+	}
+	for rank, tuple := range tuples {
+		bt.addMerge(rank, tuple[0], tuple[1])
+	}
+	return nil
+}
+
+// addMerge indexes a merge by the vocabulary IDs of its pair. Merges involving pieces
+// missing from the vocabulary could never produce a known token and are dropped.
+func (bt *BPETokenizer) addMerge(rank int, first, second string) {
+	left, ok := bt.vocab[first]
+	if !ok {
+		return
+	}
+	right, ok := bt.vocab[second]
+	if !ok {
+		return
+	}
+	merged, ok := bt.vocab[first+second]
+	if !ok {
+		return
+	}
+	pair := [2]int{left, right}
+	if _, exists := bt.merges[pair]; !exists {
+		bt.merges[pair] = mergeRule{rank: rank, id: merged}
+	}
+}
+
+// compileSplitPattern uses the file's Split regex when RE2 can run it
+func compileSplitPattern(preTokenizer json.RawMessage) *regexp.Regexp {
+	var split struct {
+		Pattern struct {
+			Regex string `json:"Regex"`
+		} `json:"pattern"`
+		Pretokenizers []struct {
+			Type    string `json:"type"`
+			Pattern struct {
+				Regex string `json:"Regex"`
+			} `json:"pattern"`
+		} `json:"pretokenizers"`
+	}
+	pattern := ""
+	if err := json.Unmarshal(preTokenizer, &split); err == nil {
+		pattern = split.Pattern.Regex
+		for _, pre := range split.Pretokenizers {
+			if pre.Type == "Split" && pre.Pattern.Regex != "" {
+				pattern = pre.Pattern.Regex
+				break
+			}
+		}
+	}
+
+	if pattern != "" {
+		// RE2 has no lookahead; preTokenize emulates it instead
+		pattern = strings.Replace(pattern, lookaheadWhitespaces, "", 1)
+		if re, err := regexp.Compile("^(?:" + pattern + ")"); err == nil {
+			return re
+		}
+	}
+	return regexp.MustCompile("^(?:" + defaultSplitPattern + ")")
+}
+
+// bytesToUnicode builds the GPT-2 reversible byte to printable rune table
+func bytesToUnicode() [256]rune {
+	var table [256]rune
+	printable := func(b int) bool {
+		return (b >= '!' && b <= '~') || (b >= 0xA1 && b <= 0xAC) || (b >= 0xAE && b <= 0xFF)
+	}
+	n := 0
+	for b := 0; b < 256; b++ {
+		if printable(b) {
+			table[b] = rune(b)
+			continue
+		}
+		table[b] = rune(256 + n)
+		n++
+	}
+	return table
+}
+
+// tokenizerFamily maps an Ollama model name onto a tokenizer family
+func tokenizerFamily(modelName string) string {
+	name := strings.ToLower(modelName)
+	switch {
+	case strings.Contains(name, "qwen"):
+		return "qwen"
+	case strings.Contains(name, "mistral"), strings.Contains(name, "mixtral"):
+		return "mistral"
+	case strings.Contains(name, "llama"):
+		return "llama"
+	}
+	return ""
+}
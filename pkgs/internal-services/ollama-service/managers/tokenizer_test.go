@@ -0,0 +1,357 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/tokenizer_test.go
+
+package managers
+
+import (
+	// stdlib
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// byteLevelTokenizer is a tiny GPT-style vocabulary; Ġ is the byte-level space
+const byteLevelTokenizer = `{
+  "model": {
+    "type": "BPE",
+    "vocab": {"h": 0, "e": 1, "l": 2, "o": 3, "p": 4, "w": 5, "r": 6, "d": 7, "Ġ": 8,
+              "he": 9, "ll": 10, "hell": 11, "hello": 12, "Ġw": 13, "or": 14, "Ġwor": 15, "Ġworl": 16, "Ġworld": 17},
+    "merges": ["h e", "l l", "he ll", "hell o", "Ġ w", "o r", "Ġw or", "Ġwor l", "Ġworl d"]
+  },
+  "pre_tokenizer": {"type": "ByteLevel", "add_prefix_space": false}
+}`
+
+// sentencePieceTokenizer is a tiny llama2/mistral-style vocabulary with byte fallback
+const sentencePieceTokenizer = `{
+  "model": {
+    "type": "BPE",
+    "byte_fallback": true,
+    "vocab": {"▁": 0, "h": 1, "i": 2, "t": 3, "e": 4, "r": 5, "▁h": 6, "▁hi": 7, "▁t": 8, "he": 9, "▁the": 10, "re": 11, "▁there": 12},
+    "merges": [["▁", "h"], ["▁h", "i"], ["▁", "t"], ["h", "e"], ["▁t", "he"], ["r", "e"], ["▁the", "re"]]
+  },
+  "decoder": {"type": "Sequence", "decoders": [{"type": "ByteFallback"}]}
+}`
+
+func writeTokenizer(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func loadTestTokenizer(t *testing.T, body string) *BPETokenizer {
+	t.Helper()
+	tokenizer, err := LoadBPETokenizer("test", writeTokenizer(t, t.TempDir(), "tokenizer.json", body))
+	if err != nil {
+		t.Fatalf("LoadBPETokenizer: %v", err)
+	}
+	return tokenizer
+}
+
+func TestBPETokenizerByteLevel(t *testing.T) {
+	tokenizer := loadTestTokenizer(t, byteLevelTokenizer)
+	if !tokenizer.byteLevel {
+		t.Fatal("ByteLevel pre-tokenizer not detected")
+	}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello", 1},
+		{"hello world", 2},
+		{"help", 3},        // he l p
+		{"hello hello", 3}, // no Ġh merge, so the second word is Ġ + hello
+		{"world", 4},       // w or l d without the leading space
+		{"hé", 3},          // h plus é's two bytes
+	}
+	for _, tt := range tests {
+		if got := tokenizer.CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBPETokenizerPreTokenize(t *testing.T) {
+	tokenizer := loadTestTokenizer(t, byteLevelTokenizer)
+
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"hello world", []string{"hello", " world"}},
+		{"don't", []string{"don", "'t"}},
+		{"1234567", []string{"123", "456", "7"}},
+		{"a   b", []string{"a", "  ", " b"}}, // the last space stays with the word
+		{"end  ", []string{"end", "  "}},
+		{"x\n\ny", []string{"x", "\n\n", "y"}},
+		{"f(x);", []string{"f", "(x", ");"}},
+		{"a  \n  b", []string{"a", "  \n", " ", " b"}},
+	}
+	for _, tt := range tests {
+		if got := tokenizer.preTokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("preTokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBPETokenizerSentencePiece(t *testing.T) {
+	tokenizer := loadTestTokenizer(t, sentencePieceTokenizer)
+	if tokenizer.byteLevel {
+		t.Fatal("SentencePiece vocabulary treated as byte-level")
+	}
+
+	if got := tokenizer.preTokenize("hi there"); !reflect.DeepEqual(got, []string{"▁hi", "▁there"}) {
+		t.Errorf("preTokenize = %q", got)
+	}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"hi", 1},
+		{"hi there", 2},
+		{"the", 1},
+		{"hit", 2}, // ▁hi t
+		{"hé", 3},  // ▁h plus é through byte fallback as two tokens
+	}
+	for _, tt := range tests {
+		if got := tokenizer.CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBPETokenizerSentencePieceWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"a   b", []string{"▁a", "▁▁", "▁b"}}, // extra spaces merge on their own
+		{"end  ", []string{"▁end", "▁▁"}},
+		{"x\n\ny", []string{"▁x", "\n\n", "y"}},
+		{"if x:\n    return", []string{"▁if", "▁x:", "\n", "▁▁▁", "▁return"}},
+		{"\r\nhi", []string{"▁", "\r\n", "hi"}},
+		{"日本語", []string{"▁日本語"}},
+	}
+	for _, tt := range tests {
+		if got := sentencePieceWords(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sentencePieceWords(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBPETokenizerMergeOrder(t *testing.T) {
+	// Lower ranks merge first wherever they are, and equal ranks merge left to right
+	body := `{
+  "model": {
+    "type": "BPE",
+    "vocab": {"a": 0, "b": 1, "c": 2, "aa": 3, "bc": 4, "abc": 5},
+    "merges": ["b c", "a a", "a bc"]
+  },
+  "pre_tokenizer": {"type": "ByteLevel"}
+}`
+	tokenizer := loadTestTokenizer(t, body)
+	pieces := make(map[int]string, len(tokenizer.vocab))
+	for piece, id := range tokenizer.vocab {
+		pieces[id] = piece
+	}
+	tests := []struct {
+		symbols []string
+		want    []string
+	}{
+		{[]string{"a", "b", "c"}, []string{"abc"}},
+		{[]string{"a", "a", "a"}, []string{"aa", "a"}},
+		{[]string{"a", "a", "b", "c"}, []string{"aa", "bc"}}, // b c first, then a a, so a bc never forms
+		{[]string{"c", "a", "a", "a", "a", "b"}, []string{"c", "aa", "aa", "b"}},
+		{[]string{"a"}, []string{"a"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, symbol := range tokenizer.merge(tt.symbols) {
+			got = append(got, pieces[symbol.id])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("merge(%q) = %q, want %q", tt.symbols, got, tt.want)
+		}
+	}
+}
+
+func TestBPETokenizerLongWords(t *testing.T) {
+	byteLevel := loadTestTokenizer(t, byteLevelTokenizer)
+	if got := byteLevel.CountTokens(strings.Repeat("hello", 2000)); got != 2000 {
+		t.Errorf("byte-level long word = %d tokens, want 2000", got)
+	}
+	sentencePiece := loadTestTokenizer(t, sentencePieceTokenizer)
+	if got := sentencePiece.CountTokens(strings.Repeat("he", 2000)); got != 2001 {
+		t.Errorf("SentencePiece long word = %d tokens, want 2001", got) // ▁h e, then he × 1999
+	}
+
+	// Long words are counted but not cached
+	if len(byteLevel.cache) != 0 || len(sentencePiece.cache) != 0 {
+		t.Errorf("cached %d and %d long words", len(byteLevel.cache), len(sentencePiece.cache))
+	}
+}
+
+// pairTokenizer is a byte-level vocabulary of every lowercase letter and letter pair,
+// so long runs of letters take hundreds of distinct merges
+func pairTokenizer() string {
+	vocab := make(map[string]int)
+	var merges []string
+	for a := 'a'; a <= 'z'; a++ {
+		vocab[string(a)] = len(vocab)
+	}
+	for a := 'a'; a <= 'z'; a++ {
+		for b := 'a'; b <= 'z'; b++ {
+			vocab[string(a)+string(b)] = len(vocab)
+			merges = append(merges, string(a)+" "+string(b))
+		}
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"model":         map[string]interface{}{"type": "BPE", "vocab": vocab, "merges": merges},
+		"pre_tokenizer": map[string]string{"type": "ByteLevel"},
+	})
+	return string(data)
+}
+
+func BenchmarkBPETokenizerLongWord(b *testing.B) {
+	random := rand.New(rand.NewPCG(1, 2))
+	letters := make([]byte, 50000)
+	for i := range letters {
+		letters[i] = byte('a' + random.IntN(26))
+	}
+
+	for _, bench := range []struct {
+		name, tokenizer, text string
+	}{
+		{"repeated", byteLevelTokenizer, strings.Repeat("hello", 10000)},
+		{"varied", pairTokenizer(), string(letters)},
+	} {
+		for _, size := range []int{500, 5000, 50000} {
+			b.Run(fmt.Sprintf("%s/%dB", bench.name, size), func(b *testing.B) {
+				path := filepath.Join(b.TempDir(), "tokenizer.json")
+				if err := os.WriteFile(path, []byte(bench.tokenizer), 0644); err != nil {
+					b.Fatal(err)
+				}
+				tokenizer, err := LoadBPETokenizer("bench", path)
+				if err != nil {
+					b.Fatal(err)
+				}
+				text := bench.text[:size]
+				b.SetBytes(int64(size))
+				b.ResetTimer()
+				for range b.N {
+					tokenizer.CountTokens(text)
+				}
+			})
+		}
+	}
+}
+
+func TestBPETokenizerSplitPatternFromFile(t *testing.T) {
+	// A Sequence pre-tokenizer carrying a Split regex with the lookahead RE2 cannot run
+	body := `{
+  "model": {"type": "BPE", "vocab": {}, "merges": []},
+  "pre_tokenizer": {"type": "Sequence", "pretokenizers": [
+    {"type": "Split", "pattern": {"Regex": "\\p{N}|\\p{L}+|\\s+(?!\\S)|\\s+|[^\\s\\p{L}\\p{N}]+"}},
+    {"type": "ByteLevel"}
+  ]}
+}`
+	tokenizer := loadTestTokenizer(t, body)
+	if got := tokenizer.preTokenize("ab 123  c"); !reflect.DeepEqual(got, []string{"ab", " ", "1", "2", "3", " ", " ", "c"}) {
+		t.Errorf("preTokenize = %q", got)
+	}
+
+	// A pattern RE2 rejects falls back to the default split
+	if re := compileSplitPattern([]byte(`{"pattern": {"Regex": "(?<=a)b"}}`)); re.String() != "^(?:"+defaultSplitPattern+")" {
+		t.Errorf("invalid pattern compiled to %s", re)
+	}
+}
+
+func TestLoadBPETokenizerErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"wordpiece.json": `{"model": {"type": "WordPiece", "vocab": {}}}`,
+		"broken.json":    `{"model": `,
+		"merges.json":    `{"model": {"type": "BPE", "vocab": {}, "merges": [1, 2]}}`,
+	} {
+		if _, err := LoadBPETokenizer(name, writeTokenizer(t, dir, name, body)); err == nil {
+			t.Errorf("LoadBPETokenizer(%s) succeeded", name)
+		}
+	}
+	if _, err := LoadBPETokenizer("missing", filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadBPETokenizer succeeded without a file")
+	}
+}
+
+func TestLoadTokenizers(t *testing.T) {
+	dir := t.TempDir()
+	writeTokenizer(t, dir, "llama/tokenizer.json", byteLevelTokenizer)
+	writeTokenizer(t, dir, "qwen.json", byteLevelTokenizer)
+	writeTokenizer(t, dir, "mistral.json", `{"model": {"type": "Unigram"}}`)
+
+	tokenizers := LoadTokenizers(dir)
+	if len(tokenizers) != 2 || tokenizers["llama"] == nil || tokenizers["qwen"] == nil {
+		t.Fatalf("LoadTokenizers = %v, want llama and qwen", tokenizers)
+	}
+	if got := tokenizers["qwen"].Name(); got != "qwen" {
+		t.Errorf("Name() = %q", got)
+	}
+}
+
+func TestTokenizerFamily(t *testing.T) {
+	for model, want := range map[string]string{
+		"llama3.2:latest":  "llama",
+		"codellama":        "llama",
+		"qwen2.5-coder:7b": "qwen",
+		"Mistral-Nemo":     "mistral",
+		"mixtral:8x7b":     "mistral",
+		"phi3":             "",
+		"nomic-embed-text": "",
+	} {
+		if got := tokenizerFamily(model); got != want {
+			t.Errorf("tokenizerFamily(%q) = %q, want %q", model, got, want)
+		}
+	}
+}
+
+func TestTokenEstimatorUsesTokenizers(t *testing.T) {
+	dir := t.TempDir()
+	writeTokenizer(t, dir, "llama/tokenizer.json", byteLevelTokenizer)
+
+	te := NewTokenEstimator()
+	before := te.EstimateTokens("hello world", "llama3.2")
+	te.LoadTokenizers(dir)
+
+	// llama3.2 keeps its per-message overhead on top of the exact count
+	if got := te.EstimateTokens("hello world", "llama3.2:latest"); got != 2+5 {
+		t.Errorf("EstimateTokens with a tokenizer = %d, want 7 (ratio estimate was %d)", got, before)
+	}
+	// Models without a loaded family still use the character ratio
+	if got := te.EstimateTokens("hello world", "qwen2.5"); got != 3 {
+		t.Errorf("EstimateTokens without a tokenizer = %d, want 3", got)
+	}
+
+	te.SetModelFamily("house-model", "llama")
+	if got := te.EstimateTokens("hello world", "house-model"); got != 2 {
+		t.Errorf("EstimateTokens with a pinned family = %d, want 2", got)
+	}
+
+	// Exact counts are not calibrated
+	te.Calibrate("house-model", 1000, 1, 100)
+	if encoder := te.lookupEncoder("house-model"); encoder.Samples != 0 {
+		t.Errorf("Calibrate adjusted a model counted by its tokenizer")
+	}
+}
@@ -0,0 +1,316 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/tool-approval.go
+
+package managers
+
+import (
+	// stdlib
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+)
+
+// Tool approval defaults, used when features.yaml leaves a field unset
+const (
+	defaultApprovalTimeout = 2 * time.Minute
+	defaultApprovalAudit   = 1000
+	toolApprovalActionType = "tool_call"
+)
+
+// Approval decisions recorded in the audit log
+const (
+	ApprovalApproved  = "approved"
+	ApprovalRejected  = "rejected"
+	ApprovalExpired   = "expired"
+	ApprovalCancelled = "cancelled"
+)
+
+// defaultApprovalScopes are the destructive scopes confirmed when features.yaml lists none
+var defaultApprovalScopes = []string{ToolScopeFiles, ToolScopeExecute}
+
+// ToolPolicy carries conversation settings the tool loop enforces
+type ToolPolicy struct {
+	RequireConfirmation bool // park destructive calls until the user approves them
+	AllowCodeExecution  bool // advertise execute-scope tools
+}
+
+// ToolApproval is a tool call parked as a PendingAction and, once decided, its audit
+// record. Parameters hold the tool, scope and arguments.
+type ToolApproval struct {
+	PendingAction
+	SessionID string    `json:"session_id,omitempty"`
+	UserID    string    `json:"user_id"`
+	RequestID string    `json:"request_id"`
+	DecidedBy string    `json:"decided_by,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	DecidedAt time.Time `json:"decided_at,omitempty"`
+}
+
+// ApprovalNotifier pushes approval requests to whoever can decide them
+type ApprovalNotifier interface {
+	NotifyApproval(approval *ToolApproval)
+}
+
+// ToolApprovals parks destructive tool calls until they are approved, rejected or expire,
+// notifying the registered notifiers and the configured webhook, and keeps an audit
+// log of decisions
+type ToolApprovals struct {
+	mu        sync.Mutex
+	config    func() ToolApprovalConfig
+	client    *http.Client
+	pending   map[string]*pendingApproval
+	audit     []*ToolApproval
+	notifiers []ApprovalNotifier
+}
+
+type pendingApproval struct {
+	approval *ToolApproval
+	decided  chan struct{}
+}
+
+// NewToolApprovals creates an approval gate reading its settings on each use
+func NewToolApprovals(config func() ToolApprovalConfig) *ToolApprovals {
+	return &ToolApprovals{
+		config:  config,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		pending: make(map[string]*pendingApproval),
+	}
+}
+
+// AddNotifier registers a notifier for new approval requests
+func (ta *ToolApprovals) AddNotifier(notifier ApprovalNotifier) {
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+	ta.notifiers = append(ta.notifiers, notifier)
+}
+
+// Required reports whether a call in scope needs approval: when the request asks for
+// confirmation or approvals are enabled for everyone, and the scope is destructive
+func (ta *ToolApprovals) Required(scope string, confirm bool) bool {
+	config := ta.config()
+	if !confirm && !config.Enabled {
+		return false
+	}
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultApprovalScopes
+	}
+	return containsString(scopes, scope)
+}
+
+// Request parks a tool call and blocks until it is decided, times out or ctx ends.
+// The returned record carries the decision in Status.
+func (ta *ToolApprovals) Request(ctx context.Context, req *InferenceRequest, call *FunctionCall, scope string) *ToolApproval {
+	config := ta.config()
+	timeout := defaultApprovalTimeout
+	if config.TimeoutSeconds > 0 {
+		timeout = time.Duration(config.TimeoutSeconds) * time.Second
+	}
+
+	now := time.Now()
+	approval := &ToolApproval{
+		PendingAction: PendingAction{
+			ID:          generateApprovalID(),
+			Type:        toolApprovalActionType,
+			Description: fmt.Sprintf("Run tool %s (%s scope)", call.Name, scope),
+			Parameters: map[string]interface{}{
+				"tool":      call.Name,
+				"scope":     scope,
+				"arguments": call.Arguments,
+			},
+			CreatedAt: now,
+			ExpiresAt: now.Add(timeout),
+			Status:    actionStatusPending,
+		},
+		SessionID: req.SessionID,
+		UserID:    req.UserID,
+		RequestID: req.ID,
+	}
+	waiting := &pendingApproval{approval: approval, decided: make(chan struct{})}
+
+	ta.mu.Lock()
+	ta.pending[approval.ID] = waiting
+	notifiers := append([]ApprovalNotifier(nil), ta.notifiers...)
+	snapshot := *approval
+	ta.mu.Unlock()
+
+	log.Info().
+		Str("action_id", approval.ID).
+		Str("request_id", req.ID).
+		Str("session_id", req.SessionID).
+		Str("tool", call.Name).
+		Msg("Tool call waiting for approval")
+
+	for _, notifier := range notifiers {
+		notifier.NotifyApproval(&snapshot)
+	}
+	if config.WebhookURL != "" {
+		go ta.postWebhook(config, &snapshot)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-waiting.decided:
+	case <-timer.C:
+		ta.decide(approval.ID, ApprovalExpired, "system", "no decision before the timeout")
+	case <-ctx.Done():
+		ta.decide(approval.ID, ApprovalCancelled, "system", ctx.Err().Error())
+	}
+
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+	decided := *approval
+	return &decided
+}
+
+// Resolve approves or rejects a pending call on behalf of decidedBy, the authenticated
+// user, who must be the user the call runs for
+func (ta *ToolApprovals) Resolve(actionID string, approved bool, decidedBy, reason string) (*ToolApproval, error) {
+	ta.mu.Lock()
+	waiting, ok := ta.pending[actionID]
+	ta.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no pending tool approval: %s", actionID)
+	}
+	if decidedBy == "" {
+		return nil, fmt.Errorf("approver is required")
+	}
+	if waiting.approval.UserID != decidedBy {
+		return nil, fmt.Errorf("tool approval %s belongs to another user", actionID)
+	}
+
+	decision := ApprovalRejected
+	if approved {
+		decision = ApprovalApproved
+	}
+	return ta.decide(actionID, decision, decidedBy, reason)
+}
+
+// Pending lists a user's calls waiting for a decision, for one session or all of them
+// when sessionID is empty
+func (ta *ToolApprovals) Pending(userID, sessionID string) []*ToolApproval {
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+
+	approvals := make([]*ToolApproval, 0)
+	for _, waiting := range ta.pending {
+		if approvalVisible(waiting.approval, userID, sessionID) {
+			copied := *waiting.approval
+			approvals = append(approvals, &copied)
+		}
+	}
+	return approvals
+}
+
+// Audit lists a user's decided calls oldest first, for one session or all of them when
+// sessionID is empty
+func (ta *ToolApprovals) Audit(userID, sessionID string) []*ToolApproval {
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+
+	records := make([]*ToolApproval, 0)
+	for _, record := range ta.audit {
+		if approvalVisible(record, userID, sessionID) {
+			copied := *record
+			records = append(records, &copied)
+		}
+	}
+	return records
+}
+
+// approvalVisible reports whether a record belongs to the user and, when given, the session
+func approvalVisible(approval *ToolApproval, userID, sessionID string) bool {
+	return approval.UserID == userID && (sessionID == "" || approval.SessionID == sessionID)
+}
+
+// decide records a decision once and wakes the waiting tool loop
+func (ta *ToolApprovals) decide(actionID, decision, decidedBy, reason string) (*ToolApproval, error) {
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+
+	waiting, ok := ta.pending[actionID]
+	if !ok {
+		return nil, fmt.Errorf("no pending tool approval: %s", actionID)
+	}
+	delete(ta.pending, actionID)
+
+	approval := waiting.approval
+	approval.Status = decision
+	approval.DecidedBy = decidedBy
+	approval.Reason = reason
+	approval.DecidedAt = time.Now()
+
+	limit := ta.config().AuditLimit
+	if limit <= 0 {
+		limit = defaultApprovalAudit
+	}
+	ta.audit = append(ta.audit, approval)
+	if len(ta.audit) > limit {
+		ta.audit = ta.audit[len(ta.audit)-limit:]
+	}
+	close(waiting.decided)
+
+	log.Info().
+		Str("action_id", actionID).
+		Str("request_id", approval.RequestID).
+		Str("session_id", approval.SessionID).
+		Str("user_id", approval.UserID).
+		Interface("tool", approval.Parameters["tool"]).
+		Str("decision", decision).
+		Str("decided_by", decidedBy).
+		Str("reason", reason).
+		Msg("Tool approval decided")
+
+	copied := *approval
+	return &copied, nil
+}
+
+// postWebhook sends an approval request to the configured webhook, signing the body
+// with the webhook secret when one is set
+func (ta *ToolApprovals) postWebhook(config ToolApprovalConfig, approval *ToolApproval) {
+	body, err := json.Marshal(approval)
+	if err != nil {
+		log.Error().Err(err).Str("action_id", approval.ID).Msg("Failed to encode approval webhook")
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		log.Error().Err(err).Str("action_id", approval.ID).Msg("Failed to build approval webhook")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if config.WebhookSecret != "" {
+		mac := hmac.New(sha256.New, []byte(config.WebhookSecret))
+		mac.Write(body)
+		req.Header.Set("X-OCS-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := ta.client.Do(req)
+	if err != nil {
+		log.Warn().Err(err).Str("action_id", approval.ID).Msg("Approval webhook failed")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Warn().Int("status", resp.StatusCode).Str("action_id", approval.ID).Msg("Approval webhook rejected the request")
+	}
+}
+
+func generateApprovalID() string {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return "approval_" + hex.EncodeToString(bytes)
+}
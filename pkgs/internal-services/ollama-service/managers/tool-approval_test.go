@@ -0,0 +1,278 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/tool-approval_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// awaitApproval waits until one of the user's tool calls is parked and returns it
+func awaitApproval(t *testing.T, approvals *ToolApprovals, userID string) *ToolApproval {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if pending := approvals.Pending(userID, ""); len(pending) > 0 {
+			return pending[0]
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no tool call was parked for approval")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestToolLoopWaitsForApproval(t *testing.T) {
+	ollama := newFakeOllama(t, "ollama")
+	cm := schedulerConfig(&LimitsConfig{MaxRequestsPerMinute: 100, MaxTokensPerRequest: 4096, TokenBudgetPerUser: 100000, ResetIntervalHours: 24, MaxConcurrentPerModel: 1},
+		ModelConfig{Name: "llama3.2", Specialization: "chat"})
+	mm := NewModelManager(ollama.URL, cm)
+	t.Cleanup(func() { mm.Shutdown(context.Background()) })
+	im := NewInferenceManager(cm, mm, NewTokenManager(cm), nil, nil, ollama.URL)
+	tools := &recordingTools{}
+	im.RegisterToolProvider(tools)
+	approvals := im.GetToolApprovals()
+
+	run := func() <-chan *InferenceResult {
+		done := make(chan *InferenceResult, 1)
+		go func() {
+			result, err := im.ProcessInference(context.Background(), &InferenceRequest{
+				ID:         "approval-test",
+				UserID:     "alice",
+				ModelName:  "llama3.2",
+				Messages:   []Message{{Role: "user", Content: "Save the notes"}},
+				Parameters: &InferenceParameters{Tools: []string{"write_file", "read_file", codeExecutionToolName}},
+				ToolPolicy: &ToolPolicy{RequireConfirmation: true},
+			})
+			if err != nil {
+				t.Errorf("ProcessInference: %v", err)
+			}
+			done <- result
+		}()
+		return done
+	}
+	writeCall := []OllamaToolCall{{Function: &FunctionCall{Name: "write_file", Arguments: map[string]interface{}{"path": "notes.md"}}}}
+
+	// A rejected call is not run and the model answers without tools
+	ollama.toolCalls = [][]OllamaToolCall{writeCall}
+	ollama.replies = []string{"I did not save the notes."}
+	done := run()
+	parked := awaitApproval(t, approvals, "alice")
+	if parked.Parameters["tool"] != "write_file" || parked.Parameters["scope"] != ToolScopeFiles || parked.Status != "pending" {
+		t.Errorf("parked = %+v", parked)
+	}
+	if _, err := approvals.Resolve(parked.ID, true, "mallory", ""); err == nil {
+		t.Error("another user approved the call")
+	}
+	if _, err := approvals.Resolve(parked.ID, false, "alice", "not now"); err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	result := <-done
+	if len(tools.calls) != 0 || result.Content != "I did not save the notes." || result.Steps[0].Metadata["approval"] != ApprovalRejected {
+		t.Errorf("rejected: calls = %d, result = %+v", len(tools.calls), result)
+	}
+	if _, ok := ollama.bodies[len(ollama.bodies)-1]["tools"]; ok {
+		t.Error("tools were still offered after the rejection")
+	}
+
+	// Execute-scope tools are not offered when the conversation disallows code execution
+	for _, tool := range ollama.bodies[0]["tools"].([]interface{}) {
+		if name := tool.(map[string]interface{})["function"].(map[string]interface{})["name"]; name == codeExecutionToolName {
+			t.Error("code execution was offered")
+		}
+	}
+
+	// The model's only slot is free while the call waits, so other requests still run
+	ollama.toolCalls = [][]OllamaToolCall{writeCall}
+	done = run()
+	parked = awaitApproval(t, approvals, "alice")
+	other := make(chan error, 1)
+	go func() {
+		_, err := im.ProcessInference(context.Background(), &InferenceRequest{
+			ID:         "while-parked",
+			UserID:     "bob",
+			ModelName:  "llama3.2",
+			Messages:   []Message{{Role: "user", Content: "Hello"}},
+			Parameters: &InferenceParameters{},
+		})
+		other <- err
+	}()
+	select {
+	case err := <-other:
+		if err != nil {
+			t.Errorf("request while parked: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the parked call held the model slot")
+	}
+
+	// An approved call runs and the loop continues on a reacquired slot
+	ollama.mu.Lock()
+	ollama.replies = []string{"Saved."}
+	ollama.mu.Unlock()
+	approvals.Resolve(parked.ID, true, "alice", "")
+	if result := <-done; len(tools.calls) != 1 || result.Content != "Saved." {
+		t.Errorf("approved: calls = %d, result = %+v", len(tools.calls), result)
+	}
+
+	// Read-only tools run without approval
+	ollama.toolCalls = [][]OllamaToolCall{{{Function: &FunctionCall{Name: "read_file"}}}}
+	if result := <-run(); len(tools.calls) != 2 || len(result.Steps) != 1 {
+		t.Errorf("read-only: calls = %d, result = %+v", len(tools.calls), result)
+	}
+
+	audit := approvals.Audit("alice", "")
+	if len(audit) != 2 || audit[0].Status != ApprovalRejected || audit[0].DecidedBy != "alice" || audit[0].Reason != "not now" || audit[1].Status != ApprovalApproved {
+		t.Errorf("audit = %+v", audit)
+	}
+}
+
+func TestToolApprovalTimeouts(t *testing.T) {
+	config := ToolApprovalConfig{TimeoutSeconds: 1}
+	approvals := NewToolApprovals(func() ToolApprovalConfig { return config })
+	req := &InferenceRequest{ID: "r1", UserID: "alice", SessionID: "s1"}
+	call := &FunctionCall{Name: "delete_file"}
+
+	if approval := approvals.Request(context.Background(), req, call, ToolScopeFiles); approval.Status != ApprovalExpired || approval.DecidedBy != "system" {
+		t.Errorf("expired = %+v", approval)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if approval := approvals.Request(ctx, req, call, ToolScopeFiles); approval.Status != ApprovalCancelled {
+		t.Errorf("cancelled = %+v", approval)
+	}
+	if got := len(approvals.Audit("alice", "s1")); got != 2 || len(approvals.Pending("alice", "")) != 0 {
+		t.Errorf("audit = %d, pending = %d", got, len(approvals.Pending("alice", "")))
+	}
+
+	if approvals.Required(ToolScopeFiles, false) || !approvals.Required(ToolScopeExecute, true) || approvals.Required(ToolScopeTools, true) {
+		t.Error("default scopes are not confirmed only on request")
+	}
+	config = ToolApprovalConfig{Enabled: true, Scopes: []string{ToolScopeExecute}}
+	if !approvals.Required(ToolScopeExecute, false) || approvals.Required(ToolScopeFiles, true) {
+		t.Error("configured scopes are not applied to every request")
+	}
+}
+
+func TestToolApprovalsScopedToUser(t *testing.T) {
+	approvals := NewToolApprovals(func() ToolApprovalConfig { return ToolApprovalConfig{} })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, req := range []*InferenceRequest{
+		{ID: "r1", UserID: "alice", SessionID: "alice-1"},
+		{ID: "r2", UserID: "alice", SessionID: "alice-2"},
+		{ID: "r3", UserID: "bob", SessionID: "bob-1"},
+	} {
+		go approvals.Request(ctx, req, &FunctionCall{Name: "write_file"}, ToolScopeFiles)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(approvals.Pending("alice", ""))+len(approvals.Pending("bob", "")) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("calls were not parked")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Listings only ever show the user's own calls
+	if pending := approvals.Pending("alice", ""); len(pending) != 2 || pending[0].UserID != "alice" || pending[1].UserID != "alice" {
+		t.Errorf("alice's pending = %+v", pending)
+	}
+	if pending := approvals.Pending("alice", "bob-1"); len(pending) != 0 {
+		t.Errorf("alice sees bob's session: %+v", pending)
+	}
+	if pending := approvals.Pending("", ""); len(pending) != 0 {
+		t.Errorf("anonymous listing = %+v", pending)
+	}
+
+	bobs := approvals.Pending("bob", "")[0]
+	if _, err := approvals.Resolve(bobs.ID, true, "alice", ""); err == nil {
+		t.Error("alice approved bob's call")
+	}
+	if _, err := approvals.Resolve(bobs.ID, true, "", ""); err == nil {
+		t.Error("an anonymous approver was accepted")
+	}
+	if _, err := approvals.Resolve(bobs.ID, false, "bob", "no"); err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if _, err := approvals.Resolve(bobs.ID, true, "bob", ""); err == nil {
+		t.Error("a decided call was decided again")
+	}
+	if audit := approvals.Audit("alice", ""); len(audit) != 0 {
+		t.Errorf("alice's audit = %+v", audit)
+	}
+	if audit := approvals.Audit("bob", "bob-1"); len(audit) != 1 || audit[0].Status != ApprovalRejected || audit[0].DecidedBy != "bob" {
+		t.Errorf("bob's audit = %+v", audit)
+	}
+}
+
+// recordingNotifier collects the approvals it is told about
+type recordingNotifier chan *ToolApproval
+
+func (rn recordingNotifier) NotifyApproval(approval *ToolApproval) { rn <- approval }
+
+func TestToolApprovalWebhook(t *testing.T) {
+	type delivery struct {
+		body      []byte
+		signature string
+	}
+	deliveries := make(chan delivery, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- delivery{body: body, signature: r.Header.Get("X-OCS-Signature")}
+	}))
+	defer webhook.Close()
+
+	approvals := NewToolApprovals(func() ToolApprovalConfig {
+		return ToolApprovalConfig{WebhookURL: webhook.URL, WebhookSecret: "s3cret"}
+	})
+	notified := make(recordingNotifier, 1)
+	approvals.AddNotifier(notified)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go approvals.Request(ctx, &InferenceRequest{ID: "r1", UserID: "alice", SessionID: "s1"}, &FunctionCall{Name: "execute_code"}, ToolScopeExecute)
+
+	var approval *ToolApproval
+	select {
+	case approval = <-notified:
+	case <-time.After(2 * time.Second):
+		t.Fatal("notifier was not called")
+	}
+	select {
+	case got := <-deliveries:
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(got.body)
+		if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); got.signature != want {
+			t.Errorf("signature = %q, want %q", got.signature, want)
+		}
+		var posted ToolApproval
+		if err := json.Unmarshal(got.body, &posted); err != nil || posted.ID != approval.ID || posted.UserID != "alice" || posted.Parameters["scope"] != ToolScopeExecute {
+			t.Errorf("posted = %s, %v", got.body, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
+
+func TestToolApprovalAuditLimit(t *testing.T) {
+	approvals := NewToolApprovals(func() ToolApprovalConfig { return ToolApprovalConfig{AuditLimit: 2} })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, id := range []string{"r1", "r2", "r3"} {
+		approvals.Request(ctx, &InferenceRequest{ID: id, UserID: "alice"}, &FunctionCall{Name: "write_file"}, ToolScopeFiles)
+	}
+	if audit := approvals.Audit("alice", ""); len(audit) != 2 || audit[0].RequestID != "r2" || audit[1].RequestID != "r3" {
+		t.Errorf("audit = %+v", audit)
+	}
+}
@@ -0,0 +1,251 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/tool-calling.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+)
+
+// ToolProvider exposes server-side tools to the inference loop
+type ToolProvider interface {
+	// ToolDefinitions returns the JSON schemas advertised to the model
+	ToolDefinitions() []ToolDefinition
+	// CallTool executes one call on behalf of a session and returns the text fed back to the model
+	CallTool(ctx context.Context, sessionID string, call *FunctionCall) (string, error)
+}
+
+// Permission scopes a caller must hold to run a tool
+const (
+	ToolScopeTools   = "tools"   // read-only tools
+	ToolScopeFiles   = "files"   // workspace writes and deletes
+	ToolScopeExecute = "execute" // code execution
+)
+
+const (
+	defaultMaxToolIterations = 5
+	maxToolResultChars       = 16000
+	codeExecutionToolName    = "execute_code"
+)
+
+// RegisterToolProvider makes a provider's tools available to tool-calling inference
+func (im *InferenceManager) RegisterToolProvider(provider ToolProvider) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	for _, def := range provider.ToolDefinitions() {
+		if def.Function == nil {
+			continue
+		}
+		im.toolProviders[def.Function.Name] = provider
+		im.toolDefinitions[def.Function.Name] = def
+	}
+	log.Info().Int("tools", len(im.toolDefinitions)).Msg("Registered tool provider")
+}
+
+// resolveTools returns the registered tools a request may call: those named in
+// Parameters.Tools, or every tool when the session enables tools
+func (im *InferenceManager) resolveTools(req *InferenceRequest) []ToolDefinition {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	if len(im.toolDefinitions) == 0 {
+		return nil
+	}
+
+	allowCodeExec := false
+	wanted := make(map[string]bool)
+	for _, name := range req.Parameters.Tools {
+		wanted[name] = true
+	}
+	if req.SessionID != "" && im.sessionManager != nil {
+		if session, ok := im.sessionManager.GetSession(req.SessionID); ok && session.Settings != nil {
+			allowCodeExec = session.Settings.EnableCodeExec
+			if session.Settings.EnableTools && len(wanted) == 0 {
+				for name := range im.toolDefinitions {
+					wanted[name] = true
+				}
+			}
+		}
+	}
+
+	defs := make([]ToolDefinition, 0, len(wanted))
+	for name := range wanted {
+		def, ok := im.toolDefinitions[name]
+		if !ok {
+			continue
+		}
+		if name == codeExecutionToolName && req.SessionID != "" && !allowCodeExec {
+			continue
+		}
+		if toolScope(def) == ToolScopeExecute && req.ToolPolicy != nil && !req.ToolPolicy.AllowCodeExecution {
+			continue
+		}
+		defs = append(defs, def)
+	}
+	// Stable ordering keeps prompts cacheable across requests
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Function.Name < defs[j].Function.Name })
+	return defs
+}
+
+// toolProvider returns the provider registered for a tool name
+func (im *InferenceManager) toolProvider(name string) (ToolProvider, bool) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+	provider, ok := im.toolProviders[name]
+	return provider, ok
+}
+
+// canExecuteToolCalls reports whether every call targets a tool advertised for this request;
+// calls to client-defined tools are returned to the caller instead
+func canExecuteToolCalls(calls []ToolCall, advertised []ToolDefinition) bool {
+	names := make(map[string]bool, len(advertised))
+	for _, def := range advertised {
+		names[def.Function.Name] = true
+	}
+	for _, call := range calls {
+		if call.Function == nil || !names[call.Function.Name] {
+			return false
+		}
+	}
+	return true
+}
+
+// executeToolCalls runs the model's calls, appends the assistant and tool messages to the
+// Ollama conversation, and records one step per call. Destructive calls wait for approval
+// first; aborted reports that one was refused, which ends the tool loop, and err that
+// the request could not resume after waiting.
+func (im *InferenceManager) executeToolCalls(ctx context.Context, req *InferenceRequest, ollamaReq *OllamaRequest, resp *OllamaResponse, calls []ToolCall) (steps []*ConversationStep, aborted bool, err error) {
+	assistant := OllamaMessage{Role: "assistant", Content: im.extractContent(resp)}
+	for _, call := range calls {
+		assistant.ToolCalls = append(assistant.ToolCalls, OllamaToolCall{Function: call.Function})
+	}
+	ollamaReq.Messages = append(ollamaReq.Messages, assistant)
+
+	steps = make([]*ConversationStep, 0, len(calls))
+	for _, call := range calls {
+		start := time.Now()
+		var output string
+		var callErr error
+		var approval *ToolApproval
+		if aborted {
+			callErr = fmt.Errorf("skipped after an earlier call was refused")
+		} else if approval, err = im.approveToolCall(ctx, req, call.Function); err != nil {
+			return steps, true, err
+		} else if approval != nil && approval.Status != ApprovalApproved {
+			aborted = true
+			callErr = approvalError(approval)
+		} else {
+			output, callErr = im.callTool(ctx, req.SessionID, call.Function)
+		}
+		if callErr != nil {
+			call.Error = callErr.Error()
+			output = fmt.Sprintf("error: %v", callErr)
+		} else {
+			call.Result = output
+		}
+
+		ollamaReq.Messages = append(ollamaReq.Messages, OllamaMessage{
+			Role:     "tool",
+			Content:  output,
+			ToolName: call.Function.Name,
+		})
+
+		args, _ := json.Marshal(call.Function.Arguments)
+		step := &ConversationStep{
+			ID:          call.ID,
+			Type:        StepTypeToolExecution,
+			Description: fmt.Sprintf("Executed tool %s", call.Function.Name),
+			Required:    true,
+			Completed:   callErr == nil,
+			ToolCalls:   []ToolCall{call},
+			Metadata: map[string]interface{}{
+				"request_id": req.ID,
+				"arguments":  string(args),
+				"duration":   time.Since(start),
+			},
+			CompletedAt: time.Now(),
+		}
+		if approval != nil {
+			step.Metadata["approval_id"] = approval.ID
+			step.Metadata["approval"] = approval.Status
+			step.Metadata["approved_by"] = approval.DecidedBy
+		}
+		steps = append(steps, step)
+
+		log.Info().
+			Str("request_id", req.ID).
+			Str("session_id", req.SessionID).
+			Str("tool", call.Function.Name).
+			Bool("success", callErr == nil).
+			Dur("duration", time.Since(start)).
+			Msg("Executed tool call")
+	}
+	return steps, aborted, nil
+}
+
+// approveToolCall parks a destructive call until it is decided; a nil approval means none
+// was needed. The model slot is given up while the user decides and queued for again
+// afterwards, failing when that wait fails.
+func (im *InferenceManager) approveToolCall(ctx context.Context, req *InferenceRequest, call *FunctionCall) (*ToolApproval, error) {
+	scope := im.registeredToolScope(call.Name)
+	confirm := req.ToolPolicy != nil && req.ToolPolicy.RequireConfirmation
+	if !im.approvals.Required(scope, confirm) {
+		return nil, nil
+	}
+
+	req.slot.Release()
+	approval := im.approvals.Request(ctx, req, call, scope)
+	if err := req.slot.Reacquire(ctx); err != nil {
+		return approval, fmt.Errorf("failed to resume after tool approval: %w", err)
+	}
+	return approval, nil
+}
+
+// registeredToolScope returns the scope of a registered tool
+func (im *InferenceManager) registeredToolScope(name string) string {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+	return toolScope(im.toolDefinitions[name])
+}
+
+// approvalError explains a refused call to the model
+func approvalError(approval *ToolApproval) error {
+	if approval.Reason == "" {
+		return fmt.Errorf("tool call %s by %s", approval.Status, approval.DecidedBy)
+	}
+	return fmt.Errorf("tool call %s by %s: %s", approval.Status, approval.DecidedBy, approval.Reason)
+}
+
+// toolScope returns a registered tool's scope, treating unscoped code execution as execute
+func toolScope(def ToolDefinition) string {
+	if def.Scope == "" && def.Function != nil && def.Function.Name == codeExecutionToolName {
+		return ToolScopeExecute
+	}
+	return def.Scope
+}
+
+// callTool dispatches a single call and caps the output fed back to the model
+func (im *InferenceManager) callTool(ctx context.Context, sessionID string, call *FunctionCall) (string, error) {
+	provider, ok := im.toolProvider(call.Name)
+	if !ok {
+		return "", fmt.Errorf("unknown tool: %s", call.Name)
+	}
+	output, err := provider.CallTool(ctx, sessionID, call)
+	if err != nil {
+		return "", err
+	}
+	if len(output) > maxToolResultChars {
+		output = output[:maxToolResultChars] + "\n[output truncated]"
+	}
+	return output, nil
+}
@@ -0,0 +1,187 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/tool-calling_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"fmt"
+	"testing"
+)
+
+// failingTools is a tool provider whose lookup tool always fails
+type failingTools struct{}
+
+func (failingTools) ToolDefinitions() []ToolDefinition {
+	return []ToolDefinition{{Type: "function", Function: &FunctionDefinition{Name: "lookup"}, Scope: ToolScopeTools}}
+}
+
+func (failingTools) CallTool(ctx context.Context, sessionID string, call *FunctionCall) (string, error) {
+	return "", fmt.Errorf("index offline")
+}
+
+// toolLoopFixture is an inference manager over a fake Ollama with recordingTools and failingTools registered
+func toolLoopFixture(t *testing.T) (*InferenceManager, *fakeOllama, *recordingTools) {
+	t.Helper()
+	ollama := newFakeOllama(t, "ollama")
+	cm := schedulerConfig(&LimitsConfig{MaxRequestsPerMinute: 100, MaxTokensPerRequest: 4096, TokenBudgetPerUser: 100000, ResetIntervalHours: 24},
+		ModelConfig{Name: "llama3.2", Specialization: "chat"})
+	mm := NewModelManager(ollama.URL, cm)
+	t.Cleanup(func() { mm.Shutdown(context.Background()) })
+	im := NewInferenceManager(cm, mm, NewTokenManager(cm), nil, nil, ollama.URL)
+	tools := &recordingTools{}
+	im.RegisterToolProvider(tools)
+	im.RegisterToolProvider(failingTools{})
+	return im, ollama, tools
+}
+
+func toolRequest(id string, tools ...string) *InferenceRequest {
+	return &InferenceRequest{
+		ID:         id,
+		UserID:     "alice",
+		ModelName:  "llama3.2",
+		Messages:   []Message{{Role: "user", Content: "Look it up"}},
+		Parameters: &InferenceParameters{Tools: tools},
+	}
+}
+
+func call(name string, args map[string]interface{}) []OllamaToolCall {
+	return []OllamaToolCall{{Function: &FunctionCall{Name: name, Arguments: args}}}
+}
+
+func TestToolLoopFeedsResultsAndErrorsBack(t *testing.T) {
+	im, ollama, tools := toolLoopFixture(t)
+	ollama.toolCalls = [][]OllamaToolCall{call("read_file", map[string]interface{}{"path": "a.md"}), call("lookup", nil)}
+	ollama.replies = []string{"The index is offline."}
+
+	result, err := im.ProcessInference(context.Background(), toolRequest("feedback", "read_file", "lookup"))
+	if err != nil {
+		t.Fatalf("ProcessInference: %v", err)
+	}
+	if result.Content != "The index is offline." || result.FinishReason != "stop" || len(result.ToolCalls) != 0 || len(tools.calls) != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+	if usage := result.Usage; usage.InputTokens != 21 || usage.OutputTokens != 9 || usage.TotalTokens != 30 {
+		t.Errorf("usage = %+v", usage)
+	}
+
+	// Each call is recorded as a step with its outcome
+	if len(result.Steps) != 2 {
+		t.Fatalf("steps = %+v", result.Steps)
+	}
+	read, lookup := result.Steps[0], result.Steps[1]
+	if !read.Completed || read.ToolCalls[0].Result != "wrote a.md" || read.Metadata["request_id"] != "feedback" || read.Metadata["arguments"] != `{"path":"a.md"}` {
+		t.Errorf("read step = %+v", read)
+	}
+	if lookup.Completed || lookup.ToolCalls[0].Error != "index offline" || lookup.Type != StepTypeToolExecution {
+		t.Errorf("lookup step = %+v", lookup)
+	}
+
+	// The model sees each result, and the error, as a tool message after its own call
+	if len(ollama.bodies) != 3 {
+		t.Fatalf("chat calls = %d", len(ollama.bodies))
+	}
+	messages := ollama.bodies[2]["messages"].([]interface{})
+	assistant := messages[len(messages)-2].(map[string]interface{})
+	feedback := messages[len(messages)-1].(map[string]interface{})
+	if assistant["role"] != "assistant" || assistant["tool_calls"] == nil {
+		t.Errorf("assistant message = %v", assistant)
+	}
+	if feedback["role"] != "tool" || feedback["tool_name"] != "lookup" || feedback["content"] != "error: index offline" {
+		t.Errorf("tool message = %v", feedback)
+	}
+}
+
+func TestToolLoopStopsAtIterationLimit(t *testing.T) {
+	im, ollama, tools := toolLoopFixture(t)
+	im.maxToolIters = 2
+	for range 5 {
+		ollama.toolCalls = append(ollama.toolCalls, call("read_file", map[string]interface{}{"path": "loop.md"}))
+	}
+
+	result, err := im.ProcessInference(context.Background(), toolRequest("limit", "read_file"))
+	if err != nil {
+		t.Fatalf("ProcessInference: %v", err)
+	}
+
+	// Two rounds run; the third call is handed back instead of executed
+	if len(ollama.bodies) != 3 || len(tools.calls) != 2 || len(result.Steps) != 2 {
+		t.Errorf("chat calls = %d, tool calls = %d, steps = %d", len(ollama.bodies), len(tools.calls), len(result.Steps))
+	}
+	if result.FinishReason != "tool_calls" || len(result.ToolCalls) != 1 || result.ToolCalls[0].Function.Name != "read_file" {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+// collectStream drains a stream, failing on an error chunk or a chunk after the done one
+func collectStream(t *testing.T, stream <-chan *StreamChunk) []*StreamChunk {
+	t.Helper()
+	var chunks []*StreamChunk
+	for chunk := range stream {
+		if chunk.Error != "" {
+			t.Fatalf("stream error: %s", chunk.Error)
+		}
+		if len(chunks) > 0 && chunks[len(chunks)-1].Done {
+			t.Fatalf("chunk after done: %+v", chunk)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if len(chunks) == 0 || !chunks[len(chunks)-1].Done {
+		t.Fatal("stream ended without a done chunk")
+	}
+	return chunks
+}
+
+func TestStreamingToolLoop(t *testing.T) {
+	im, ollama, tools := toolLoopFixture(t)
+	ollama.toolCalls = [][]OllamaToolCall{call("read_file", map[string]interface{}{"path": "a.md"}), call("lookup", nil)}
+	ollama.replies = []string{"Read a.md; the index is offline."}
+
+	stream, err := im.ProcessStreamingInference(context.Background(), toolRequest("stream-tools", "read_file", "lookup"))
+	if err != nil {
+		t.Fatalf("ProcessStreamingInference: %v", err)
+	}
+	chunks := collectStream(t, stream)
+
+	// Executed calls arrive as steps, not as tool calls for the client to run
+	var steps []*ConversationStep
+	content := ""
+	for _, chunk := range chunks {
+		steps = append(steps, chunk.Steps...)
+		content += chunk.Content
+		if len(chunk.ToolCalls) > 0 {
+			t.Errorf("server-side call streamed to the client: %+v", chunk.ToolCalls)
+		}
+	}
+	if len(tools.calls) != 1 || len(steps) != 2 || !steps[0].Completed || steps[1].ToolCalls[0].Error != "index offline" {
+		t.Errorf("tool calls = %d, steps = %+v", len(tools.calls), steps)
+	}
+	if content != "Read a.md; the index is offline." {
+		t.Errorf("content = %q", content)
+	}
+	if usage := chunks[len(chunks)-1].Usage; usage == nil || usage.TotalTokens != 30 {
+		t.Errorf("usage = %+v", usage)
+	}
+
+	// The stream is charged to the model and the user like a non-streaming request
+	if stats, _ := im.modelManager.GetModelStats("llama3.2"); stats == nil || stats.RequestCount != 1 || stats.TotalTokens != 30 {
+		t.Errorf("model stats = %+v", stats)
+	}
+	if counter, _, err := im.tokenManager.GetUserUsage("alice"); err != nil || counter.OutputTokens != 9 {
+		t.Errorf("alice's usage = %+v, %v", counter, err)
+	}
+
+	// At the iteration limit the pending call is handed back in the done chunk
+	im.maxToolIters = 0
+	ollama.toolCalls = [][]OllamaToolCall{call("read_file", nil)}
+	stream, err = im.ProcessStreamingInference(context.Background(), toolRequest("stream-limit", "read_file"))
+	if err != nil {
+		t.Fatalf("ProcessStreamingInference: %v", err)
+	}
+	chunks = collectStream(t, stream)
+	if last := chunks[len(chunks)-1]; len(last.ToolCalls) != 1 || last.ToolCalls[0].Function.Name != "read_file" || len(tools.calls) != 1 {
+		t.Errorf("done chunk = %+v, tool calls = %d", last, len(tools.calls))
+	}
+}
@@ -0,0 +1,535 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/vector-index.go
+
+package managers
+
+import (
+	// stdlib
+	"container/heap"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+	// third-party
+)
+
+// HNSWIndex is an approximate nearest neighbour index over memory embeddings
+// (Hierarchical Navigable Small World graph, cosine similarity)
+type HNSWIndex struct {
+	mu             sync.RWMutex
+	m              int // max neighbours per node on upper layers
+	m0             int // max neighbours per node on layer 0
+	efConstruction int
+	efSearch       int
+	levelMult      float64
+	dimensions     int
+	nodes          map[string]*hnswNode
+	entryPoint     string
+	maxLevel       int
+	rng            *rand.Rand
+}
+
+// hnswNode is a single vector in the graph
+type hnswNode struct {
+	id        string
+	vector    []float64 // normalized
+	level     int
+	neighbors [][]string // per layer
+}
+
+// VectorMatch is a search hit from the vector index
+type VectorMatch struct {
+	MemoryID   string  `json:"memory_id"`
+	Similarity float64 `json:"similarity"`
+}
+
+// HNSWSnapshot is the persisted graph layout; vectors are restored from memories
+type HNSWSnapshot struct {
+	M              int                `json:"m"`
+	EfConstruction int                `json:"ef_construction"`
+	EfSearch       int                `json:"ef_search"`
+	Dimensions     int                `json:"dimensions"`
+	EntryPoint     string             `json:"entry_point"`
+	MaxLevel       int                `json:"max_level"`
+	Nodes          []HNSWNodeSnapshot `json:"nodes"`
+	SavedAt        time.Time          `json:"saved_at"`
+}
+
+// HNSWNodeSnapshot is the persisted form of a graph node
+type HNSWNodeSnapshot struct {
+	ID        string     `json:"id"`
+	Level     int        `json:"level"`
+	Neighbors [][]string `json:"neighbors"`
+}
+
+// NewHNSWIndex creates an empty index
+func NewHNSWIndex(m, efConstruction, efSearch int) *HNSWIndex {
+	if m <= 1 {
+		m = 16
+	}
+	if efConstruction <= 0 {
+		efConstruction = 200
+	}
+	if efSearch <= 0 {
+		efSearch = 64
+	}
+	return &HNSWIndex{
+		m:              m,
+		m0:             m * 2,
+		efConstruction: efConstruction,
+		efSearch:       efSearch,
+		levelMult:      1 / math.Log(float64(m)),
+		nodes:          make(map[string]*hnswNode),
+		maxLevel:       -1,
+		rng:            rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Len returns the number of indexed vectors
+func (hi *HNSWIndex) Len() int {
+	hi.mu.RLock()
+	defer hi.mu.RUnlock()
+	return len(hi.nodes)
+}
+
+// Contains reports whether id is indexed
+func (hi *HNSWIndex) Contains(id string) bool {
+	hi.mu.RLock()
+	defer hi.mu.RUnlock()
+	_, exists := hi.nodes[id]
+	return exists
+}
+
+// Insert adds or replaces a vector
+func (hi *HNSWIndex) Insert(id string, vector []float64) error {
+	hi.mu.Lock()
+	defer hi.mu.Unlock()
+
+	if len(vector) == 0 {
+		return fmt.Errorf("empty vector for %s", id)
+	}
+	if hi.dimensions == 0 || len(hi.nodes) == 0 {
+		hi.dimensions = len(vector)
+	}
+	if len(vector) != hi.dimensions {
+		return fmt.Errorf("dimension mismatch for %s: %d != %d", id, len(vector), hi.dimensions)
+	}
+
+	if _, exists := hi.nodes[id]; exists {
+		hi.delete(id)
+	}
+
+	level := int(math.Floor(-math.Log(1-hi.rng.Float64()) * hi.levelMult))
+	node := &hnswNode{
+		id:        id,
+		vector:    normalizeVector(vector),
+		level:     level,
+		neighbors: make([][]string, level+1),
+	}
+	hi.link(node)
+	return nil
+}
+
+// Delete removes a vector and repairs the links of its neighbours
+func (hi *HNSWIndex) Delete(id string) {
+	hi.mu.Lock()
+	defer hi.mu.Unlock()
+	hi.delete(id)
+}
+
+// Search returns the k most similar vectors to query
+func (hi *HNSWIndex) Search(query []float64, k int) []VectorMatch {
+	hi.mu.RLock()
+	defer hi.mu.RUnlock()
+
+	if len(hi.nodes) == 0 || len(query) != hi.dimensions || k <= 0 {
+		return nil
+	}
+
+	q := normalizeVector(query)
+	ep := hi.entryPoint
+	for l := hi.maxLevel; l > 0; l-- {
+		ep = hi.greedyClosest(q, ep, l)
+	}
+
+	ef := hi.efSearch
+	if ef < k {
+		ef = k
+	}
+	candidates := hi.searchLayer(q, []string{ep}, ef, 0)
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+
+	matches := make([]VectorMatch, len(candidates))
+	for i, c := range candidates {
+		matches[i] = VectorMatch{MemoryID: c.id, Similarity: 1 - c.distance}
+	}
+	return matches
+}
+
+// Snapshot exports the graph structure for persistence
+func (hi *HNSWIndex) Snapshot() *HNSWSnapshot {
+	hi.mu.RLock()
+	defer hi.mu.RUnlock()
+
+	snapshot := &HNSWSnapshot{
+		M:              hi.m,
+		EfConstruction: hi.efConstruction,
+		EfSearch:       hi.efSearch,
+		Dimensions:     hi.dimensions,
+		EntryPoint:     hi.entryPoint,
+		MaxLevel:       hi.maxLevel,
+		Nodes:          make([]HNSWNodeSnapshot, 0, len(hi.nodes)),
+		SavedAt:        time.Now(),
+	}
+	for _, node := range hi.nodes {
+		neighbors := make([][]string, len(node.neighbors))
+		for l, ids := range node.neighbors {
+			neighbors[l] = append([]string{}, ids...)
+		}
+		snapshot.Nodes = append(snapshot.Nodes, HNSWNodeSnapshot{
+			ID:        node.id,
+			Level:     node.level,
+			Neighbors: neighbors,
+		})
+	}
+	return snapshot
+}
+
+// RestoreHNSWIndex rebuilds an index from a snapshot and the current vectors.
+// Nodes whose vector is gone are dropped; vectors missing from the snapshot are inserted.
+func RestoreHNSWIndex(snapshot *HNSWSnapshot, vectors map[string][]float64) *HNSWIndex {
+	hi := NewHNSWIndex(snapshot.M, snapshot.EfConstruction, snapshot.EfSearch)
+	hi.dimensions = snapshot.Dimensions
+
+	stale := make([]string, 0)
+	for _, ns := range snapshot.Nodes {
+		vector, exists := vectors[ns.ID]
+		if !exists || len(vector) != snapshot.Dimensions {
+			stale = append(stale, ns.ID)
+		}
+		node := &hnswNode{
+			id:        ns.ID,
+			vector:    normalizeVector(vector),
+			level:     ns.Level,
+			neighbors: ns.Neighbors,
+		}
+		if len(node.neighbors) < ns.Level+1 {
+			node.neighbors = append(node.neighbors, make([][]string, ns.Level+1-len(node.neighbors))...)
+		}
+		hi.nodes[ns.ID] = node
+	}
+	hi.entryPoint = snapshot.EntryPoint
+	hi.maxLevel = snapshot.MaxLevel
+	if _, exists := hi.nodes[hi.entryPoint]; !exists {
+		hi.resetEntryPoint()
+	}
+
+	for _, id := range stale {
+		hi.delete(id)
+	}
+	for id, vector := range vectors {
+		if _, exists := hi.nodes[id]; !exists {
+			hi.Insert(id, vector)
+		}
+	}
+
+	return hi
+}
+
+// link connects a new node into every layer up to its level
+func (hi *HNSWIndex) link(node *hnswNode) {
+	hi.nodes[node.id] = node
+
+	if hi.entryPoint == "" {
+		hi.entryPoint = node.id
+		hi.maxLevel = node.level
+		return
+	}
+
+	ep := hi.entryPoint
+	for l := hi.maxLevel; l > node.level; l-- {
+		ep = hi.greedyClosest(node.vector, ep, l)
+	}
+
+	entryPoints := []string{ep}
+	for l := minInt(node.level, hi.maxLevel); l >= 0; l-- {
+		candidates := hi.searchLayer(node.vector, entryPoints, hi.efConstruction, l)
+		neighbors := hi.selectNeighbors(candidates, hi.maxConnections(l), node.id)
+		node.neighbors[l] = neighbors
+
+		for _, neighborID := range neighbors {
+			neighbor := hi.nodes[neighborID]
+			neighbor.neighbors[l] = append(neighbor.neighbors[l], node.id)
+			if len(neighbor.neighbors[l]) > hi.maxConnections(l) {
+				hi.pruneNeighbors(neighbor, l)
+			}
+		}
+
+		entryPoints = entryPoints[:0]
+		for _, c := range candidates {
+			entryPoints = append(entryPoints, c.id)
+		}
+	}
+
+	if node.level > hi.maxLevel {
+		hi.entryPoint = node.id
+		hi.maxLevel = node.level
+	}
+}
+
+func (hi *HNSWIndex) delete(id string) {
+	node, exists := hi.nodes[id]
+	if !exists {
+		return
+	}
+	delete(hi.nodes, id)
+
+	for l, neighborIDs := range node.neighbors {
+		for _, neighborID := range neighborIDs {
+			neighbor, ok := hi.nodes[neighborID]
+			if !ok || len(neighbor.neighbors) <= l {
+				continue
+			}
+			neighbor.neighbors[l] = removeString(neighbor.neighbors[l], id)
+
+			// Reconnect through the deleted node's other neighbours
+			for _, candidateID := range neighborIDs {
+				if candidateID == neighborID || len(neighbor.neighbors[l]) >= hi.maxConnections(l) {
+					continue
+				}
+				if _, ok := hi.nodes[candidateID]; ok && !containsString(neighbor.neighbors[l], candidateID) {
+					neighbor.neighbors[l] = append(neighbor.neighbors[l], candidateID)
+				}
+			}
+		}
+	}
+
+	if hi.entryPoint == id {
+		hi.resetEntryPoint()
+	}
+}
+
+func (hi *HNSWIndex) resetEntryPoint() {
+	hi.entryPoint = ""
+	hi.maxLevel = -1
+	for nodeID, node := range hi.nodes {
+		if node.level > hi.maxLevel {
+			hi.entryPoint = nodeID
+			hi.maxLevel = node.level
+		}
+	}
+}
+
+func (hi *HNSWIndex) maxConnections(level int) int {
+	if level == 0 {
+		return hi.m0
+	}
+	return hi.m
+}
+
+// greedyClosest walks a layer towards the closest node to q
+func (hi *HNSWIndex) greedyClosest(q []float64, ep string, level int) string {
+	current := ep
+	currentDist := hi.distance(q, current)
+	for changed := true; changed; {
+		changed = false
+		node := hi.nodes[current]
+		if node == nil || len(node.neighbors) <= level {
+			break
+		}
+		for _, neighborID := range node.neighbors[level] {
+			if d := hi.distance(q, neighborID); d < currentDist {
+				current = neighborID
+				currentDist = d
+				changed = true
+			}
+		}
+	}
+	return current
+}
+
+// searchLayer returns up to ef closest nodes on a layer, sorted by distance
+func (hi *HNSWIndex) searchLayer(q []float64, entryPoints []string, ef, level int) []hnswCandidate {
+	visited := make(map[string]bool)
+	candidates := &candidateHeap{}
+	results := &candidateHeap{max: true}
+
+	for _, ep := range entryPoints {
+		if _, exists := hi.nodes[ep]; !exists || visited[ep] {
+			continue
+		}
+		visited[ep] = true
+		c := hnswCandidate{id: ep, distance: hi.distance(q, ep)}
+		heap.Push(candidates, c)
+		heap.Push(results, c)
+	}
+
+	for candidates.Len() > 0 {
+		closest := heap.Pop(candidates).(hnswCandidate)
+		if results.Len() >= ef && closest.distance > results.items[0].distance {
+			break
+		}
+
+		node := hi.nodes[closest.id]
+		if node == nil || len(node.neighbors) <= level {
+			continue
+		}
+		for _, neighborID := range node.neighbors[level] {
+			if visited[neighborID] {
+				continue
+			}
+			visited[neighborID] = true
+			if _, exists := hi.nodes[neighborID]; !exists {
+				continue
+			}
+
+			d := hi.distance(q, neighborID)
+			if results.Len() < ef || d < results.items[0].distance {
+				heap.Push(candidates, hnswCandidate{id: neighborID, distance: d})
+				heap.Push(results, hnswCandidate{id: neighborID, distance: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	sorted := append([]hnswCandidate{}, results.items...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].distance < sorted[j].distance })
+	return sorted
+}
+
+// selectNeighbors picks up to max candidates, which come sorted by distance to self.
+// A candidate closer to an already picked neighbour than to self is set aside so links
+// also reach other clusters; set-aside candidates fill any slots left over.
+func (hi *HNSWIndex) selectNeighbors(candidates []hnswCandidate, max int, self string) []string {
+	neighbors := make([]string, 0, max)
+	var setAside []string
+	for _, c := range candidates {
+		if len(neighbors) >= max {
+			break
+		}
+		if c.id == self {
+			continue
+		}
+		diverse := true
+		if node := hi.nodes[c.id]; node != nil {
+			for _, picked := range neighbors {
+				if hi.distance(node.vector, picked) < c.distance {
+					diverse = false
+					break
+				}
+			}
+		}
+		if diverse {
+			neighbors = append(neighbors, c.id)
+		} else {
+			setAside = append(setAside, c.id)
+		}
+	}
+	for _, id := range setAside {
+		if len(neighbors) >= max {
+			break
+		}
+		neighbors = append(neighbors, id)
+	}
+	return neighbors
+}
+
+// pruneNeighbors keeps a node's closest links on a layer
+func (hi *HNSWIndex) pruneNeighbors(node *hnswNode, level int) {
+	candidates := make([]hnswCandidate, 0, len(node.neighbors[level]))
+	for _, neighborID := range node.neighbors[level] {
+		candidates = append(candidates, hnswCandidate{id: neighborID, distance: hi.distance(node.vector, neighborID)})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+	node.neighbors[level] = hi.selectNeighbors(candidates, hi.maxConnections(level), node.id)
+}
+
+func (hi *HNSWIndex) distance(q []float64, id string) float64 {
+	node, exists := hi.nodes[id]
+	if !exists || len(node.vector) != len(q) {
+		return math.MaxFloat64
+	}
+	dot := 0.0
+	for i := range q {
+		dot += q[i] * node.vector[i]
+	}
+	return 1 - dot
+}
+
+// hnswCandidate pairs a node with its distance to the query
+type hnswCandidate struct {
+	id       string
+	distance float64
+}
+
+// candidateHeap is a min-heap by distance, or a max-heap when max is set
+type candidateHeap struct {
+	items []hnswCandidate
+	max   bool
+}
+
+func (h candidateHeap) Len() int { return len(h.items) }
+func (h candidateHeap) Less(i, j int) bool {
+	if h.max {
+		return h.items[i].distance > h.items[j].distance
+	}
+	return h.items[i].distance < h.items[j].distance
+}
+func (h candidateHeap) Swap(i, j int)       { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *candidateHeap) Push(x interface{}) { h.items = append(h.items, x.(hnswCandidate)) }
+func (h *candidateHeap) Pop() interface{} {
+	old := h.items
+	n := len(old)
+	item := old[n-1]
+	h.items = old[:n-1]
+	return item
+}
+
+func normalizeVector(vector []float64) []float64 {
+	magnitude := 0.0
+	for _, val := range vector {
+		magnitude += val * val
+	}
+	magnitude = math.Sqrt(magnitude)
+
+	normalized := make([]float64, len(vector))
+	if magnitude == 0 {
+		return normalized
+	}
+	for i, val := range vector {
+		normalized[i] = val / magnitude
+	}
+	return normalized
+}
+
+func removeString(values []string, target string) []string {
+	for i, v := range values {
+		if v == target {
+			return append(values[:i], values[i+1:]...)
+		}
+	}
+	return values
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,339 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/vector-index_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// testVectors returns n vectors of dim gaussian components around a few cluster
+// centres, which is closer to real embeddings than uniform noise
+func testVectors(rng *rand.Rand, n, dim int) map[string][]float64 {
+	centres := make([][]float64, 8)
+	for i := range centres {
+		centres[i] = make([]float64, dim)
+		for d := range centres[i] {
+			centres[i][d] = rng.NormFloat64() * 3
+		}
+	}
+
+	vectors := make(map[string][]float64, n)
+	for i := 0; i < n; i++ {
+		centre := centres[rng.Intn(len(centres))]
+		vector := make([]float64, dim)
+		for d := range vector {
+			vector[d] = centre[d] + rng.NormFloat64()
+		}
+		vectors[fmt.Sprintf("m%05d", i)] = vector
+	}
+	return vectors
+}
+
+// exhaustiveSearch is the linear scan the index replaces
+func exhaustiveSearch(vectors map[string][]float64, query []float64, k int) []VectorMatch {
+	mm := &MemoryManager{}
+	matches := make([]VectorMatch, 0, len(vectors))
+	for id, vector := range vectors {
+		matches = append(matches, VectorMatch{MemoryID: id, Similarity: mm.calculateCosineSimilarity(query, vector)})
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Similarity > matches[j].Similarity })
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// testQueries returns n queries near randomly chosen stored vectors
+func testQueries(rng *rand.Rand, vectors map[string][]float64, n int) [][]float64 {
+	ids := make([]string, 0, len(vectors))
+	for id := range vectors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	queries := make([][]float64, n)
+	for i := range queries {
+		base := vectors[ids[rng.Intn(len(ids))]]
+		queries[i] = make([]float64, len(base))
+		for d := range base {
+			queries[i][d] = base[d] + rng.NormFloat64()*0.5
+		}
+	}
+	return queries
+}
+
+// buildTestIndex inserts vectors in a fixed order with a seeded level generator
+func buildTestIndex(t testing.TB, vectors map[string][]float64) *HNSWIndex {
+	t.Helper()
+	ids := make([]string, 0, len(vectors))
+	for id := range vectors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	index := NewHNSWIndex(16, 200, 64)
+	index.rng = rand.New(rand.NewSource(42))
+	for _, id := range ids {
+		if err := index.Insert(id, vectors[id]); err != nil {
+			t.Fatalf("Insert(%s): %v", id, err)
+		}
+	}
+	return index
+}
+
+// recallAt is the fraction of the exact top k the index found
+func recallAt(exact, approx []VectorMatch) float64 {
+	if len(exact) == 0 {
+		return 1
+	}
+	found := make(map[string]bool, len(approx))
+	for _, m := range approx {
+		found[m.MemoryID] = true
+	}
+	hits := 0
+	for _, m := range exact {
+		if found[m.MemoryID] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(exact))
+}
+
+func TestHNSWIndexFindsInsertedVector(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	vectors := testVectors(rng, 500, 16)
+	index := buildTestIndex(t, vectors)
+
+	if got := index.Len(); got != len(vectors) {
+		t.Fatalf("Len() = %d, want %d", got, len(vectors))
+	}
+	for _, id := range []string{"m00000", "m00123", "m00499"} {
+		matches := index.Search(vectors[id], 1)
+		if len(matches) != 1 || matches[0].MemoryID != id {
+			t.Errorf("Search(%s) = %v", id, matches)
+			continue
+		}
+		if matches[0].Similarity < 0.9999 {
+			t.Errorf("self similarity of %s = %f", id, matches[0].Similarity)
+		}
+	}
+}
+
+func TestHNSWIndexRejectsBadVectors(t *testing.T) {
+	index := NewHNSWIndex(16, 200, 64)
+	if err := index.Insert("empty", nil); err == nil {
+		t.Error("Insert accepted an empty vector")
+	}
+	if err := index.Insert("a", []float64{1, 0, 0}); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	if err := index.Insert("b", []float64{1, 0}); err == nil {
+		t.Error("Insert accepted a vector of another dimension")
+	}
+	if matches := index.Search([]float64{1, 0}, 1); matches != nil {
+		t.Errorf("Search with the wrong dimension = %v", matches)
+	}
+	if matches := index.Search([]float64{1, 0, 0}, 0); matches != nil {
+		t.Errorf("Search with k=0 = %v", matches)
+	}
+}
+
+func TestHNSWIndexReplaceKeepsOneNode(t *testing.T) {
+	index := NewHNSWIndex(16, 200, 64)
+	index.Insert("a", []float64{1, 0})
+	index.Insert("b", []float64{0, 1})
+	index.Insert("a", []float64{0, 1})
+
+	if got := index.Len(); got != 2 {
+		t.Fatalf("Len() = %d, want 2", got)
+	}
+	for _, m := range index.Search([]float64{0, 1}, 2) {
+		if m.Similarity < 0.9999 {
+			t.Errorf("%s similarity = %f; replacement vector not used", m.MemoryID, m.Similarity)
+		}
+	}
+}
+
+func TestHNSWIndexDelete(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	vectors := testVectors(rng, 300, 16)
+	index := buildTestIndex(t, vectors)
+
+	// Remove half, including whatever is the current entry point
+	deleted := map[string]bool{index.entryPoint: true}
+	index.Delete(index.entryPoint)
+	for id := range vectors {
+		if len(deleted) >= len(vectors)/2 {
+			break
+		}
+		if !deleted[id] {
+			index.Delete(id)
+			deleted[id] = true
+		}
+	}
+	for id := range deleted {
+		delete(vectors, id)
+	}
+
+	if got := index.Len(); got != len(vectors) {
+		t.Fatalf("Len() = %d, want %d", got, len(vectors))
+	}
+	for id := range vectors {
+		for _, m := range index.Search(vectors[id], 10) {
+			if deleted[m.MemoryID] {
+				t.Fatalf("Search returned deleted %s", m.MemoryID)
+			}
+		}
+		if matches := index.Search(vectors[id], 1); len(matches) == 0 || matches[0].MemoryID != id {
+			t.Errorf("Search(%s) after deletes = %v", id, matches)
+		}
+	}
+
+	for id := range vectors {
+		index.Delete(id)
+	}
+	if got := index.Len(); got != 0 {
+		t.Errorf("Len() after deleting everything = %d", got)
+	}
+	if matches := index.Search(make([]float64, 16), 5); matches != nil {
+		t.Errorf("Search on an empty index = %v", matches)
+	}
+}
+
+func TestHNSWIndexRecall(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	vectors := testVectors(rng, 3000, 32)
+	index := buildTestIndex(t, vectors)
+
+	const k, queries = 10, 100
+	total := 0.0
+	for _, query := range testQueries(rng, vectors, queries) {
+		total += recallAt(exhaustiveSearch(vectors, query, k), index.Search(query, k))
+	}
+	if recall := total / queries; recall < 0.95 {
+		t.Errorf("recall@%d = %.3f, want >= 0.95", k, recall)
+	}
+}
+
+func TestHNSWSnapshotRestore(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	vectors := testVectors(rng, 400, 16)
+	snapshot := buildTestIndex(t, vectors).Snapshot()
+
+	// Between save and load some memories were deleted and others added
+	removed := []string{"m00001", "m00002", snapshot.EntryPoint}
+	for _, id := range removed {
+		delete(vectors, id)
+	}
+	for id, vector := range testVectors(rng, 20, 16) {
+		vectors["new-"+id] = vector
+	}
+
+	restored := RestoreHNSWIndex(snapshot, vectors)
+	if got := restored.Len(); got != len(vectors) {
+		t.Fatalf("Len() = %d, want %d", got, len(vectors))
+	}
+	for _, id := range removed {
+		if restored.Contains(id) {
+			t.Errorf("restored index still holds removed %s", id)
+		}
+	}
+	for id, vector := range vectors {
+		if matches := restored.Search(vector, 1); len(matches) == 0 || matches[0].MemoryID != id {
+			t.Errorf("Search(%s) after restore = %v", id, matches)
+		}
+	}
+}
+
+func TestRetrieveMemoriesIndexMatchesExhaustive(t *testing.T) {
+	mm := newTestMemoryManager(t)
+	ctx := context.Background()
+
+	topics := []string{"golang", "python", "database", "kubernetes", "frontend", "testing"}
+	rng := rand.New(rand.NewSource(5))
+	for i := 0; i < 300; i++ {
+		content := fmt.Sprintf("note %d about %s and %s with %s", i,
+			topics[rng.Intn(len(topics))], topics[rng.Intn(len(topics))], topics[rng.Intn(len(topics))])
+		if err := mm.StoreMemory(ctx, &Memory{UserID: "u1", Content: content, Importance: 0.8}); err != nil {
+			t.Fatalf("StoreMemory: %v", err)
+		}
+	}
+
+	query := &MemoryQuery{UserID: "u1", Content: "golang database", Limit: 5}
+	search := func(limit int) []string {
+		mm.exhaustiveLimit = limit
+		results, err := mm.RetrieveMemories(ctx, query)
+		if err != nil {
+			t.Fatalf("RetrieveMemories: %v", err)
+		}
+		similarities := make([]string, len(results))
+		for i, r := range results {
+			similarities[i] = fmt.Sprintf("%.4f", r.Similarity)
+		}
+		return similarities
+	}
+
+	exhaustive := search(1 << 30)
+	indexed := search(0)
+	if len(indexed) != len(exhaustive) {
+		t.Fatalf("indexed returned %d results, exhaustive %d", len(indexed), len(exhaustive))
+	}
+	for i := range exhaustive {
+		if indexed[i] != exhaustive[i] {
+			t.Errorf("result %d similarity: indexed %s, exhaustive %s", i, indexed[i], exhaustive[i])
+		}
+	}
+
+	// Without an index for the provider the search scans everything instead of finding nothing
+	mm.memoryIndex.mu.Lock()
+	for key := range mm.memoryIndex.vectorIndexes {
+		delete(mm.memoryIndex.vectorIndexes, key)
+	}
+	mm.memoryIndex.mu.Unlock()
+	unindexed := search(0)
+	if len(unindexed) != len(exhaustive) {
+		t.Fatalf("unindexed returned %d results, exhaustive %d", len(unindexed), len(exhaustive))
+	}
+	for i := range exhaustive {
+		if unindexed[i] != exhaustive[i] {
+			t.Errorf("result %d similarity: unindexed %s, exhaustive %s", i, unindexed[i], exhaustive[i])
+		}
+	}
+}
+
+// BenchmarkVectorSearch compares the ANN index with the exhaustive scan; the
+// hnsw case reports recall@10 against the scan alongside its latency
+func BenchmarkVectorSearch(b *testing.B) {
+	for _, size := range []int{1000, 10000} {
+		rng := rand.New(rand.NewSource(6))
+		vectors := testVectors(rng, size, 64)
+		queries := testQueries(rng, vectors, 64)
+
+		b.Run(fmt.Sprintf("exhaustive/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				exhaustiveSearch(vectors, queries[i%len(queries)], 10)
+			}
+		})
+
+		index := buildTestIndex(b, vectors)
+		b.Run(fmt.Sprintf("hnsw/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				index.Search(queries[i%len(queries)], 10)
+			}
+
+			b.StopTimer()
+			total := 0.0
+			for _, q := range queries {
+				total += recallAt(exhaustiveSearch(vectors, q, 10), index.Search(q, 10))
+			}
+			b.ReportMetric(total/float64(len(queries)), "recall@10")
+		})
+	}
+}
@@ -7,7 +7,11 @@ package managers
 import (
 	// stdlib
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,6 +20,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	// internal
+    "ocs/src/types"
     "ocs/src/utils"
 )
 
@@ -30,6 +35,9 @@ type WebSocketManager struct {
 	sessionManager     *SessionManager
 	modelManager       *ModelManager
 	tokenManager       *TokenManager
+	inferenceManager   *InferenceManager
+	tools              SessionTools                   // runs code.execute and file.operation
+	activeChats        map[chatKey]context.CancelFunc // chats in flight, queued or streaming
 	messageBuffer      chan *WSMessage
 	shutdown           chan struct{}
 	pingInterval       time.Duration
@@ -38,6 +46,11 @@ type WebSocketManager struct {
 	maxMessageSize     int64
 }
 
+// SessionTools runs registered tools in a session as the caller on ctx
+type SessionTools interface {
+	ExecuteInSession(ctx context.Context, sessionID string, call *types.ToolCall) (*types.ToolResult, error)
+}
+
 // ClientConnection represents a WebSocket client connection
 type ClientConnection struct {
 	ID              string                 `json:"id"`
@@ -52,6 +65,13 @@ type ClientConnection struct {
 	SendChan        chan *WSMessage        `json:"-"`
 	CloseChan       chan struct{}          `json:"-"`
 	mu              sync.Mutex             `json:"-"`
+	closeOnce       sync.Once
+}
+
+// close signals the connection's goroutines to stop; it is safe to call more than once.
+// SendChan stays open so late senders select on CloseChan instead of panicking.
+func (c *ClientConnection) close() {
+	c.closeOnce.Do(func() { close(c.CloseChan) })
 }
 
 // WSMessage represents a WebSocket message
@@ -63,6 +83,23 @@ type WSMessage struct {
 	Payload   map[string]interface{} `json:"payload"`
 	Timestamp time.Time              `json:"timestamp"`
 	RequestID string                 `json:"request_id,omitempty"`
+	caller    *Caller                // the sending connection's credentials, never read from the wire
+}
+
+// sender returns the caller a message acts for; messages built without a connection get
+// no permissions
+func (msg *WSMessage) sender() *Caller {
+	if msg.caller != nil {
+		return msg.caller
+	}
+	return &Caller{UserID: msg.UserID}
+}
+
+// chatKey identifies a chat by the user who sent it and their request ID, so one user
+// can never cancel another's chat
+type chatKey struct {
+	userID    string
+	requestID string
 }
 
 // ChatMessage represents a chat message payload
@@ -78,10 +115,15 @@ type ChatMessage struct {
 
 // StreamChunk represents a streaming response chunk
 type StreamChunk struct {
-	Content    string `json:"content"`
-	Done       bool   `json:"done"`
-	TokenCount int    `json:"token_count,omitempty"`
-	Error      string `json:"error,omitempty"`
+	Content          string              `json:"content"`
+	Done             bool                `json:"done"`
+	TokenCount       int                 `json:"token_count,omitempty"`
+	ToolCalls        []ToolCall          `json:"tool_calls,omitempty"`
+	Steps            []*ConversationStep `json:"steps,omitempty"`             // server-side tool executions
+	StructuredOutput interface{}         `json:"structured_output,omitempty"` // the decoded reply when a response schema was set
+	Usage            *TokenUsage         `json:"usage,omitempty"`
+	PerformanceStats *PerformanceStats   `json:"performance_stats,omitempty"`
+	Error            string              `json:"error,omitempty"`
 }
 
 // SystemEvent represents system events
@@ -98,6 +140,7 @@ type SystemEvent struct {
 type CodeExecution struct {
 	Language    string                 `json:"language"`
 	Code        string                 `json:"code"`
+	Stdin       string                 `json:"stdin,omitempty"`
 	Environment string                 `json:"environment,omitempty"`
 	Timeout     int                    `json:"timeout,omitempty"`
 	Args        []string               `json:"args,omitempty"`
@@ -117,9 +160,11 @@ type CodeResult struct {
 // FileOperation represents file operations
 type FileOperation struct {
 	Operation string                 `json:"operation"` // read, write, delete, list
-	Path      string                 `json:"path"`
+	SessionID string                 `json:"session_id,omitempty"`
+	UserID    string                 `json:"user_id,omitempty"`
+	Path      string                 `json:"path"` // relative to the session workspace
 	Content   string                 `json:"content,omitempty"`
-	Mode      string                 `json:"mode,omitempty"`
+	Mode      string                 `json:"mode,omitempty"` // write: overwrite (default) or append
 	Recursive bool                   `json:"recursive,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 }
@@ -145,7 +190,7 @@ type FileInfo struct {
 }
 
 // NewWebSocketManager creates a new WebSocket manager
-func NewWebSocketManager(configManager *ConfigManager, sessionManager *SessionManager, modelManager *ModelManager, tokenManager *TokenManager) *WebSocketManager {
+func NewWebSocketManager(configManager *ConfigManager, sessionManager *SessionManager, modelManager *ModelManager, tokenManager *TokenManager, inferenceManager *InferenceManager) *WebSocketManager {
 	wsm := &WebSocketManager{
 		connections:        make(map[string]*ClientConnection),
 		userConnections:    make(map[string][]*ClientConnection),
@@ -161,13 +206,15 @@ func NewWebSocketManager(configManager *ConfigManager, sessionManager *SessionMa
 		configManager:  configManager,
 		sessionManager: sessionManager,
 		modelManager:   modelManager,
-		tokenManager:   tokenManager,
-		messageBuffer:  make(chan *WSMessage, 1000),
-		shutdown:       make(chan struct{}),
-		pingInterval:   54 * time.Second,
-		pongWait:       60 * time.Second,
-		writeWait:      10 * time.Second,
-		maxMessageSize: 512 * 1024, // 512KB
+		tokenManager:     tokenManager,
+		inferenceManager: inferenceManager,
+		activeChats:      make(map[chatKey]context.CancelFunc),
+		messageBuffer:    make(chan *WSMessage, 1000),
+		shutdown:         make(chan struct{}),
+		pingInterval:     54 * time.Second,
+		pongWait:         60 * time.Second,
+		writeWait:        10 * time.Second,
+		maxMessageSize:   512 * 1024, // 512KB
 	}
 
 	// Start message processor
@@ -176,6 +223,13 @@ func NewWebSocketManager(configManager *ConfigManager, sessionManager *SessionMa
 	return wsm
 }
 
+// SetSessionTools routes code.execute and file.operation through the tool registry
+func (wsm *WebSocketManager) SetSessionTools(tools SessionTools) {
+	wsm.mu.Lock()
+	defer wsm.mu.Unlock()
+	wsm.tools = tools
+}
+
 // HandleWebSocket upgrades HTTP connection to WebSocket
 func (wsm *WebSocketManager) HandleWebSocket(w http.ResponseWriter, r *http.Request, userID string) error {
 	conn, err := wsm.upgrader.Upgrade(w, r, nil)
@@ -190,13 +244,18 @@ func (wsm *WebSocketManager) HandleWebSocket(w http.ResponseWriter, r *http.Requ
 		ConnectedAt:     time.Now(),
 		LastActivity:    time.Now(),
 		IsAuthenticated: userID != "",
-		Permissions:     []string{"chat", "tools"},
+		Permissions:     DefaultPermissions,
 		Metadata:        make(map[string]interface{}),
 		Connection:      conn,
 		SendChan:        make(chan *WSMessage, 256),
 		CloseChan:       make(chan struct{}),
 	}
 
+	if caller, ok := CallerFromContext(r.Context()); ok && caller.UserID == userID {
+		client.SessionID = caller.SessionID
+		client.Permissions = caller.Permissions
+	}
+
 	// Configure connection
 	conn.SetReadLimit(wsm.maxMessageSize)
 	conn.SetReadDeadline(time.Now().Add(wsm.pongWait))
@@ -276,8 +335,7 @@ func (wsm *WebSocketManager) unregisterClient(client *ClientConnection) {
 		}
 	}
 
-	close(client.SendChan)
-	close(client.CloseChan)
+	client.close()
 
 	log.Info().
 		Str("connection_id", client.ID).
@@ -309,6 +367,7 @@ func (wsm *WebSocketManager) handleClientRead(client *ClientConnection) {
 			client.LastActivity = time.Now()
 			msg.UserID = client.UserID
 			msg.Timestamp = time.Now()
+			msg.caller = &Caller{UserID: client.UserID, SessionID: client.SessionID, Permissions: client.Permissions}
 
 			// Add to message buffer for processing
 			select {
@@ -362,7 +421,7 @@ func (wsm *WebSocketManager) handleClientPing(client *ClientConnection) {
 			client.mu.Lock()
 			if time.Since(client.LastActivity) > wsm.pongWait {
 				client.mu.Unlock()
-				close(client.CloseChan)
+				client.close()
 				return
 			}
 			client.mu.Unlock()
@@ -391,12 +450,24 @@ func (wsm *WebSocketManager) handleMessage(msg *WSMessage) {
 	switch msg.Type {
 	case "chat.send":
 		wsm.handleChatMessage(msg)
+	case "chat.cancel":
+		wsm.handleChatCancel(msg)
 	case "session.create":
 		wsm.handleSessionCreate(msg)
 	case "session.join":
 		wsm.handleSessionJoin(msg)
 	case "session.leave":
 		wsm.handleSessionLeave(msg)
+	case "branch.list":
+		wsm.handleBranchList(msg)
+	case "branch.fork":
+		wsm.handleBranchFork(msg)
+	case "branch.switch":
+		wsm.handleBranchSwitch(msg)
+	case "branch.merge":
+		wsm.handleBranchMerge(msg)
+	case "tool.approve":
+		wsm.handleToolApproval(msg)
 	case "code.execute":
 		wsm.handleCodeExecution(msg)
 	case "file.operation":
@@ -416,7 +487,60 @@ func (wsm *WebSocketManager) handleChatMessage(msg *WSMessage) {
 		wsm.sendError(msg.UserID, "invalid_payload", "Invalid chat message payload", msg.RequestID)
 		return
 	}
+	if msg.SessionID != "" {
+		if _, ok := wsm.ownedSession(msg, msg.SessionID); !ok {
+			return
+		}
+	}
+	wsm.startChat(msg, func(ctx context.Context) { wsm.runChat(ctx, msg, &chatMsg) })
+}
+
+// ownedSession returns a session that belongs to the sender, answering session_not_found
+// for sessions that are missing or owned by someone else
+func (wsm *WebSocketManager) ownedSession(msg *WSMessage, sessionID string) (*Session, bool) {
+	if wsm.sessionManager != nil && msg.UserID != "" {
+		if session, exists := wsm.sessionManager.GetSession(sessionID); exists && session.UserID == msg.UserID {
+			return session, true
+		}
+	}
+	wsm.sendError(msg.UserID, "session_not_found", "Session not found: "+sessionID, msg.RequestID)
+	return nil, false
+}
+
+// startChat runs a chat in the background so that queueing and streaming never hold up
+// the message loop. The chat is registered under the sender's request ID before it
+// queues, so chat.cancel reaches it at any point, and runs as the sender so tools get
+// the connection's permissions.
+func (wsm *WebSocketManager) startChat(msg *WSMessage, run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(WithCaller(context.Background(), msg.sender()))
+	key := chatKey{userID: msg.UserID, requestID: msg.RequestID}
+	if msg.RequestID != "" {
+		wsm.mu.Lock()
+		if _, exists := wsm.activeChats[key]; exists {
+			wsm.mu.Unlock()
+			cancel()
+			wsm.sendError(msg.UserID, "duplicate_request", "A chat is already running for request "+msg.RequestID, msg.RequestID)
+			return
+		}
+		wsm.activeChats[key] = cancel
+		wsm.mu.Unlock()
+	}
 
+	go func() {
+		defer func() {
+			cancel()
+			if msg.RequestID != "" {
+				wsm.mu.Lock()
+				delete(wsm.activeChats, key)
+				wsm.mu.Unlock()
+			}
+		}()
+		run(ctx)
+	}()
+}
+
+// runChat checks the token budget, records the user's message and streams the reply
+func (wsm *WebSocketManager) runChat(ctx context.Context, msg *WSMessage, chatMsg *ChatMessage) {
 	// Check token budget
 	estimatedTokens := wsm.tokenManager.EstimateTokens(chatMsg.Content, chatMsg.ModelName)
 	tokenReq := &TokenUsageRequest{
@@ -430,7 +554,7 @@ func (wsm *WebSocketManager) handleChatMessage(msg *WSMessage) {
 	tokenResp, err := wsm.tokenManager.CheckTokenUsage(tokenReq)
 	if err != nil || !tokenResp.Allowed {
 		reason := "Token limit exceeded"
-		if tokenResp.BlockReason != "" {
+		if tokenResp != nil && tokenResp.BlockReason != "" {
 			reason = tokenResp.BlockReason
 		}
 		wsm.sendError(msg.UserID, "token_limit", reason, msg.RequestID)
@@ -446,11 +570,11 @@ func (wsm *WebSocketManager) handleChatMessage(msg *WSMessage) {
 	}
 
 	// Send chat request to model
-	wsm.sendChatToModel(msg, &chatMsg)
+	wsm.sendChatToModel(ctx, msg, chatMsg)
 }
 
 // sendChatToModel sends chat request to model and streams response
-func (wsm *WebSocketManager) sendChatToModel(msg *WSMessage, chatMsg *ChatMessage) {
+func (wsm *WebSocketManager) sendChatToModel(ctx context.Context, msg *WSMessage, chatMsg *ChatMessage) {
 	// Get session context if available
 	var messages []Message
 	var err error
@@ -461,27 +585,57 @@ func (wsm *WebSocketManager) sendChatToModel(msg *WSMessage, chatMsg *ChatMessag
 			log.Error().Err(err).Msg("Failed to get session context")
 		}
 	}
+	if len(messages) == 0 {
+		messages = []Message{{Role: "user", Content: chatMsg.Content, Timestamp: time.Now()}}
+	}
 
-	// TODO: Send request to Ollama via model manager
-	// This would be integrated with your model manager to actually call Ollama
-
-	// For now, simulate streaming response
-	go wsm.simulateStreamingResponse(msg, chatMsg, messages)
-}
+	req := &InferenceRequest{
+		ID:          fmt.Sprintf("ws_%s_%d", msg.UserID, time.Now().UnixNano()),
+		UserID:      msg.UserID,
+		SessionID:   msg.SessionID,
+		ModelName:   chatMsg.ModelName,
+		RequestType: InferenceTypeChat,
+		Messages:    messages,
+		Parameters: &InferenceParameters{
+			Temperature: chatMsg.Temperature,
+			MaxTokens:   chatMsg.MaxTokens,
+			Stream:      true,
+		},
+		Metadata: chatMsg.Metadata,
+	}
 
-// simulateStreamingResponse simulates a streaming response (replace with actual Ollama integration)
-func (wsm *WebSocketManager) simulateStreamingResponse(msg *WSMessage, chatMsg *ChatMessage, messages []Message) {
-	response := "This is a simulated streaming response to: " + chatMsg.Content
+	streamChan, err := wsm.inferenceManager.ProcessStreamingInference(ctx, req)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Cancelled while queued; chat.cancel has already answered
+			return
+		}
+		code := "inference_failed"
+		var queueErr *QueueFullError
+		if errors.As(err, &queueErr) {
+			code = "queue_full"
+		}
+		wsm.sendError(msg.UserID, code, err.Error(), msg.RequestID)
+		return
+	}
 
-	// Send streaming chunks
-	words := []string{"This", "is", "a", "simulated", "streaming", "response", "to:", chatMsg.Content}
+	wsm.relayStream(msg, req, streamChan)
+}
 
-	for i, word := range words {
-		chunk := &StreamChunk{
-			Content: word + " ",
-			Done:    i == len(words)-1,
+// relayStream forwards model chunks to the user and persists the reply once complete
+func (wsm *WebSocketManager) relayStream(msg *WSMessage, req *InferenceRequest, streamChan <-chan *StreamChunk) {
+	var response strings.Builder
+	for chunk := range streamChan {
+		if chunk.Error != "" {
+			// A cancelled request ends its context; only report real failures
+			if req.Context.Err() == nil {
+				wsm.sendError(msg.UserID, "inference_failed", chunk.Error, msg.RequestID)
+			}
+			continue
 		}
 
+		response.WriteString(chunk.Content)
+
 		streamMsg := &WSMessage{
 			ID:        utils.GenerateMessageID(),
 			Type:      "chat.stream",
@@ -494,14 +648,76 @@ func (wsm *WebSocketManager) simulateStreamingResponse(msg *WSMessage, chatMsg *
 			Timestamp: time.Now(),
 		}
 
-		wsm.sendToUser(msg.UserID, streamMsg)
-		time.Sleep(100 * time.Millisecond) // Simulate streaming delay
+		if !wsm.sendStreamToUser(msg.UserID, streamMsg) {
+			log.Warn().Str("user_id", msg.UserID).Str("request_id", req.ID).Msg("Client too slow, cancelling stream")
+			wsm.inferenceManager.CancelInference(req.ID)
+			for range streamChan {
+			}
+			return
+		}
+
+		if chunk.Done {
+			// Persist only complete replies
+			if msg.SessionID != "" {
+				metadata := map[string]interface{}{
+					"model_used": req.ModelName,
+					"request_id": req.ID,
+				}
+				if chunk.Usage != nil {
+					metadata["usage"] = chunk.Usage
+				}
+				if _, err := wsm.sessionManager.AddMessage(msg.SessionID, "assistant", response.String(), metadata); err != nil {
+					log.Error().Err(err).Msg("Failed to add assistant message to session")
+				}
+			}
+		}
 	}
+}
 
-	// Add assistant response to session
-	if msg.SessionID != "" {
-		wsm.sessionManager.AddMessage(msg.SessionID, "assistant", response, nil)
+// sendStreamToUser delivers a stream message, waiting up to writeWait for a full send buffer to drain
+func (wsm *WebSocketManager) sendStreamToUser(userID string, msg *WSMessage) bool {
+	wsm.mu.RLock()
+	connections := wsm.userConnections[userID]
+	wsm.mu.RUnlock()
+
+	delivered := false
+	for _, conn := range connections {
+		select {
+		case conn.SendChan <- msg:
+			delivered = true
+		case <-conn.CloseChan:
+		case <-time.After(wsm.writeWait):
+			log.Warn().Str("connection_id", conn.ID).Msg("Send buffer full, stream message not delivered")
+		}
 	}
+	return delivered
+}
+
+// handleChatCancel cancels one of the sender's chats, whether queued or streaming
+func (wsm *WebSocketManager) handleChatCancel(msg *WSMessage) {
+	requestID, _ := msg.Payload["request_id"].(string)
+	if requestID == "" {
+		requestID = msg.RequestID
+	}
+
+	wsm.mu.RLock()
+	cancel, exists := wsm.activeChats[chatKey{userID: msg.UserID, requestID: requestID}]
+	wsm.mu.RUnlock()
+	if !exists {
+		wsm.sendError(msg.UserID, "request_not_found", "No active chat for request "+requestID, msg.RequestID)
+		return
+	}
+	cancel()
+
+	wsm.sendToUser(msg.UserID, &WSMessage{
+		ID:     utils.GenerateMessageID(),
+		Type:   "chat.cancelled",
+		UserID: msg.UserID,
+		Payload: map[string]interface{}{
+			"request_id": requestID,
+		},
+		Timestamp: time.Now(),
+	})
 }
 
 // sendToUser sends a message to all connections for a user
@@ -589,9 +805,8 @@ func (wsm *WebSocketManager) handleSessionJoin(msg *WSMessage) {
 		return
 	}
 
-	session, exists := wsm.sessionManager.GetSession(sessionID)
-	if !exists {
-		wsm.sendError(msg.UserID, "session_not_found", "Session not found", msg.RequestID)
+	session, ok := wsm.ownedSession(msg, sessionID)
+	if !ok {
 		return
 	}
 
@@ -621,6 +836,169 @@ func (wsm *WebSocketManager) handleSessionJoin(msg *WSMessage) {
 	wsm.sendToUser(msg.UserID, responseMsg)
 }
 
+// branchSessionID reads the session a branch message targets, defaulting to the joined session
+func branchSessionID(msg *WSMessage) string {
+	if sessionID, _ := msg.Payload["session_id"].(string); sessionID != "" {
+		return sessionID
+	}
+	return msg.SessionID
+}
+
+// sendBranchReply answers a branch message
+func (wsm *WebSocketManager) sendBranchReply(msg *WSMessage, msgType, sessionID string, payload map[string]interface{}) {
+	payload["session_id"] = sessionID
+	payload["request_id"] = msg.RequestID
+	wsm.sendToUser(msg.UserID, &WSMessage{
+		ID:        utils.GenerateMessageID(),
+		Type:      msgType,
+		UserID:    msg.UserID,
+		SessionID: sessionID,
+		Payload:   payload,
+		Timestamp: time.Now(),
+	})
+}
+
+// handleBranchList sends the session's branch tree
+func (wsm *WebSocketManager) handleBranchList(msg *WSMessage) {
+	sessionID := branchSessionID(msg)
+	if _, ok := wsm.ownedSession(msg, sessionID); !ok {
+		return
+	}
+	tree, err := wsm.sessionManager.GetBranchTree(sessionID)
+	if err != nil {
+		wsm.sendError(msg.UserID, "session_not_found", err.Error(), msg.RequestID)
+		return
+	}
+	wsm.sendBranchReply(msg, "branch.tree", sessionID, map[string]interface{}{"tree": tree})
+}
+
+// handleBranchFork forks the session at a message. With content the edited message is
+// added to the new branch and answered; with regenerate the model answers the branch as is.
+func (wsm *WebSocketManager) handleBranchFork(msg *WSMessage) {
+	sessionID := branchSessionID(msg)
+	messageID, _ := msg.Payload["message_id"].(string)
+	name, _ := msg.Payload["name"].(string)
+	content, _ := msg.Payload["content"].(string)
+	regenerate, _ := msg.Payload["regenerate"].(bool)
+
+	session, ok := wsm.ownedSession(msg, sessionID)
+	if !ok {
+		return
+	}
+	branch, err := wsm.sessionManager.ForkSession(sessionID, messageID, name)
+	if err != nil {
+		wsm.sendError(msg.UserID, "branch_fork_failed", err.Error(), msg.RequestID)
+		return
+	}
+	wsm.sendBranchReply(msg, "branch.forked", sessionID, map[string]interface{}{"branch": branch})
+
+	if content == "" && !regenerate {
+		return
+	}
+	if content != "" {
+		if _, err := wsm.sessionManager.AddMessage(sessionID, "user", content, nil); err != nil {
+			wsm.sendError(msg.UserID, "branch_fork_failed", err.Error(), msg.RequestID)
+			return
+		}
+	}
+
+	chatMsg := &ChatMessage{Content: content, Role: "user"}
+	if modelName, _ := msg.Payload["model_name"].(string); modelName != "" {
+		chatMsg.ModelName = modelName
+	} else {
+		chatMsg.ModelName = session.ModelName
+	}
+	forkMsg := *msg
+	forkMsg.SessionID = sessionID
+	wsm.startChat(&forkMsg, func(ctx context.Context) { wsm.sendChatToModel(ctx, &forkMsg, chatMsg) })
+}
+
+// handleBranchSwitch changes the session's active branch
+func (wsm *WebSocketManager) handleBranchSwitch(msg *WSMessage) {
+	sessionID := branchSessionID(msg)
+	branchID, _ := msg.Payload["branch_id"].(string)
+	if _, ok := wsm.ownedSession(msg, sessionID); !ok {
+		return
+	}
+	if err := wsm.sessionManager.SwitchBranch(sessionID, branchID); err != nil {
+		wsm.sendError(msg.UserID, "branch_switch_failed", err.Error(), msg.RequestID)
+		return
+	}
+	wsm.sendBranchReply(msg, "branch.switched", sessionID, map[string]interface{}{"branch_id": branchID})
+}
+
+// handleBranchMerge merges a branch summary into its parent branch
+func (wsm *WebSocketManager) handleBranchMerge(msg *WSMessage) {
+	sessionID := branchSessionID(msg)
+	branchID, _ := msg.Payload["branch_id"].(string)
+	summary, _ := msg.Payload["summary"].(string)
+	if _, ok := wsm.ownedSession(msg, sessionID); !ok {
+		return
+	}
+
+	go func() {
+		if summary == "" {
+			var err error
+			summary, err = wsm.inferenceManager.SummarizeBranch(context.Background(), sessionID, branchID)
+			if err != nil {
+				wsm.sendError(msg.UserID, "branch_merge_failed", err.Error(), msg.RequestID)
+				return
+			}
+		}
+		mergePoint, err := wsm.sessionManager.MergeBranch(sessionID, branchID, summary)
+		if err != nil {
+			wsm.sendError(msg.UserID, "branch_merge_failed", err.Error(), msg.RequestID)
+			return
+		}
+		wsm.sendBranchReply(msg, "branch.merged", sessionID, map[string]interface{}{"merge_point": mergePoint})
+	}()
+}
+
+// NotifyApproval asks the user's connections to approve a parked tool call
+func (wsm *WebSocketManager) NotifyApproval(approval *ToolApproval) {
+	wsm.sendToUser(approval.UserID, &WSMessage{
+		ID:        utils.GenerateMessageID(),
+		Type:      "tool.approval_required",
+		UserID:    approval.UserID,
+		SessionID: approval.SessionID,
+		Payload: map[string]interface{}{
+			"action_id":   approval.ID,
+			"description": approval.Description,
+			"tool":        approval.Parameters["tool"],
+			"scope":       approval.Parameters["scope"],
+			"arguments":   approval.Parameters["arguments"],
+			"expires_at":  approval.ExpiresAt,
+			"request_id":  approval.RequestID,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// handleToolApproval approves or rejects a parked tool call on behalf of the sender
+func (wsm *WebSocketManager) handleToolApproval(msg *WSMessage) {
+	actionID, _ := msg.Payload["action_id"].(string)
+	approved, _ := msg.Payload["approved"].(bool)
+	reason, _ := msg.Payload["reason"].(string)
+
+	approval, err := wsm.inferenceManager.GetToolApprovals().Resolve(actionID, approved, msg.UserID, reason)
+	if err != nil {
+		wsm.sendError(msg.UserID, "tool_approval_failed", err.Error(), msg.RequestID)
+		return
+	}
+	wsm.sendToUser(msg.UserID, &WSMessage{
+		ID:        utils.GenerateMessageID(),
+		Type:      "tool.approval_resolved",
+		UserID:    msg.UserID,
+		SessionID: approval.SessionID,
+		Payload: map[string]interface{}{
+			"action_id":  approval.ID,
+			"decision":   approval.Status,
+			"request_id": msg.RequestID,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
 // handlePing responds to ping messages
 func (wsm *WebSocketManager) handlePing(msg *WSMessage) {
 	pongMsg := &WSMessage{
@@ -637,16 +1015,162 @@ func (wsm *WebSocketManager) handlePing(msg *WSMessage) {
 	wsm.sendToUser(msg.UserID, pongMsg)
 }
 
-// handleCodeExecution processes code execution requests
+// fileOperationTools maps file.operation operations to the file tools that run them
+var fileOperationTools = map[string]string{
+	"read":   "read_file",
+	"write":  "write_file",
+	"delete": "delete_file",
+	"list":   "list_files",
+}
+
+// toolSession returns the session a code or file message runs in: the one the message
+// names, or else the connection's; it must belong to the sender
+func (wsm *WebSocketManager) toolSession(msg *WSMessage) (string, bool) {
+	sessionID := msg.SessionID
+	if sessionID == "" {
+		sessionID = msg.sender().SessionID
+	}
+	if sessionID == "" {
+		wsm.sendError(msg.UserID, "missing_session_id", "Session ID is required", msg.RequestID)
+		return "", false
+	}
+	if _, ok := wsm.ownedSession(msg, sessionID); !ok {
+		return "", false
+	}
+	return sessionID, true
+}
+
+// runTool executes a tool in the session as the sender, off the message loop, and hands
+// the result to reply; failures are answered with errorCode
+func (wsm *WebSocketManager) runTool(msg *WSMessage, sessionID, errorCode string, call *types.ToolCall, reply func(*types.ToolResult)) {
+	wsm.mu.RLock()
+	tools := wsm.tools
+	wsm.mu.RUnlock()
+	if tools == nil {
+		wsm.sendError(msg.UserID, errorCode, "Tools are not available", msg.RequestID)
+		return
+	}
+
+	go func() {
+		ctx := WithCaller(context.Background(), msg.sender())
+		result, err := tools.ExecuteInSession(ctx, sessionID, call)
+		if err != nil {
+			wsm.sendError(msg.UserID, errorCode, err.Error(), msg.RequestID)
+			return
+		}
+		reply(result)
+	}()
+}
+
+// sendToolReply answers a code or file message
+func (wsm *WebSocketManager) sendToolReply(msg *WSMessage, msgType, sessionID string, result interface{}) {
+	wsm.sendToUser(msg.UserID, &WSMessage{
+		ID:        utils.GenerateMessageID(),
+		Type:      msgType,
+		UserID:    msg.UserID,
+		SessionID: sessionID,
+		Payload: map[string]interface{}{
+			"result":     result,
+			"request_id": msg.RequestID,
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// handleCodeExecution runs code in the session's sandbox through the execute_code tool;
+// the sandbox's own limits apply, so Timeout, Args and Env are not honoured
 func (wsm *WebSocketManager) handleCodeExecution(msg *WSMessage) {
-	// TODO: Implement code execution
-	wsm.sendError(msg.UserID, "not_implemented", "Code execution not yet implemented", msg.RequestID)
+	var execution CodeExecution
+	if err := utils.MapToStruct(msg.Payload, &execution); err != nil || execution.Code == "" || execution.Language == "" {
+		wsm.sendError(msg.UserID, "invalid_payload", "Code and language are required", msg.RequestID)
+		return
+	}
+	sessionID, ok := wsm.toolSession(msg)
+	if !ok {
+		return
+	}
+
+	arguments := map[string]interface{}{"code": execution.Code, "language": execution.Language}
+	if execution.Stdin != "" {
+		arguments["stdin"] = execution.Stdin
+	}
+	call := &types.ToolCall{Name: "execute_code", Arguments: arguments}
+	wsm.runTool(msg, sessionID, "code_execution_failed", call, func(result *types.ToolResult) {
+		wsm.sendToolReply(msg, "code.result", sessionID, codeResult(result))
+	})
 }
 
-// handleFileOperation processes file operations
+// codeResult converts an execute_code result, whose content is the sandbox's JSON report
+func codeResult(result *types.ToolResult) *CodeResult {
+	var run struct {
+		ExitCode int    `json:"exit_code"`
+		Stdout   string `json:"stdout"`
+		Stderr   string `json:"stderr"`
+		Usage    *struct {
+			WallTime time.Duration `json:"wall_time"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal([]byte(result.Content), &run); err != nil {
+		return &CodeResult{Error: result.Error, ExitCode: -1}
+	}
+
+	codeResult := &CodeResult{
+		Output:   run.Stdout,
+		Error:    run.Stderr,
+		ExitCode: run.ExitCode,
+		Success:  result.Success,
+	}
+	if codeResult.Error == "" {
+		codeResult.Error = result.Error
+	}
+	if run.Usage != nil {
+		codeResult.Duration = run.Usage.WallTime.Milliseconds()
+	}
+	return codeResult
+}
+
+// handleFileOperation runs a file operation in the session workspace through the file
+// tools; user_id and session_id in the payload are ignored
 func (wsm *WebSocketManager) handleFileOperation(msg *WSMessage) {
-	// TODO: Implement file operations
-	wsm.sendError(msg.UserID, "not_implemented", "File operations not yet implemented", msg.RequestID)
+	var op FileOperation
+	if err := utils.MapToStruct(msg.Payload, &op); err != nil {
+		wsm.sendError(msg.UserID, "invalid_payload", "Invalid file operation payload", msg.RequestID)
+		return
+	}
+	toolName, ok := fileOperationTools[op.Operation]
+	if !ok {
+		wsm.sendError(msg.UserID, "invalid_payload", "Unknown file operation: "+op.Operation, msg.RequestID)
+		return
+	}
+	sessionID, ok := wsm.toolSession(msg)
+	if !ok {
+		return
+	}
+
+	arguments := map[string]interface{}{"path": op.Path}
+	switch op.Operation {
+	case "write":
+		arguments["content"] = op.Content
+		if op.Mode != "" {
+			arguments["mode"] = op.Mode
+		}
+	case "delete", "list":
+		arguments["recursive"] = op.Recursive
+		if op.Operation == "list" && op.Path == "" {
+			arguments["path"] = "."
+		}
+	}
+	call := &types.ToolCall{Name: toolName, Arguments: arguments}
+	wsm.runTool(msg, sessionID, "file_operation_failed", call, func(result *types.ToolResult) {
+		fileResult := &FileResult{Success: result.Success, Error: result.Error}
+		if op.Operation == "read" {
+			fileResult.Content = result.Content
+		}
+		if files, ok := result.Data.([]FileInfo); ok {
+			fileResult.Files = files
+		}
+		wsm.sendToolReply(msg, "file.result", sessionID, fileResult)
+	})
 }
 
 // handleSessionLeave leaves a session
@@ -688,7 +1212,7 @@ func (wsm *WebSocketManager) Shutdown(ctx context.Context) error {
 	// Close all connections
 	wsm.mu.Lock()
 	for _, client := range wsm.connections {
-		close(client.CloseChan)
+		client.close()
 		client.Connection.Close()
 	}
 	wsm.mu.Unlock()
@@ -0,0 +1,379 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/websocket-manager_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	// third-party
+	"github.com/gorilla/websocket"
+
+	// internal
+	"ocs/src/types"
+	"ocs/src/utils"
+)
+
+// newStallingChat serves /api/chat by streaming one chunk and holding the request open
+// until the caller goes away; aborted receives once per cancelled request
+func newStallingChat(t *testing.T, aborted chan<- string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tags", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"models":[{"name":"llama3.2"}]}`))
+	})
+	mux.HandleFunc("GET /api/ps", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"models":[]}`))
+	})
+	mux.HandleFunc("POST /api/generate", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"done":true}`))
+	})
+	mux.HandleFunc("POST /api/chat", func(w http.ResponseWriter, r *http.Request) {
+		var body OllamaRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		prompt := body.Messages[len(body.Messages)-1].Content
+		json.NewEncoder(w).Encode(&OllamaResponse{Model: "llama3.2", Message: &OllamaMessage{Role: "assistant", Content: "thinking"}})
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		aborted <- prompt
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// wsFixture is a WebSocket manager over a model with a single slot; users connect with
+// ?user=<id>
+type wsFixture struct {
+	wsm    *WebSocketManager
+	im     *InferenceManager
+	sm     *SessionManager
+	server *httptest.Server
+}
+
+func newWSFixture(t *testing.T, ollamaURL string) *wsFixture {
+	t.Helper()
+	cm := schedulerConfig(&LimitsConfig{MaxRequestsPerMinute: 100, MaxTokensPerRequest: 4096, TokenBudgetPerUser: 100000, ResetIntervalHours: 24, MaxConcurrentPerModel: 1},
+		ModelConfig{Name: "llama3.2", Specialization: "chat"})
+	mm := NewModelManager(ollamaURL, cm)
+	tm := NewTokenManager(cm)
+	sm := NewSessionManager(cm, nil, nil)
+	im := NewInferenceManager(cm, mm, tm, nil, sm, ollamaURL)
+	wsm := NewWebSocketManager(cm, sm, mm, tm, im)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wsm.HandleWebSocket(w, r, r.URL.Query().Get("user"))
+	}))
+	t.Cleanup(func() {
+		server.Close()
+		wsm.Shutdown(context.Background())
+		sm.Shutdown(context.Background())
+		mm.Shutdown(context.Background())
+	})
+	return &wsFixture{wsm: wsm, im: im, sm: sm, server: server}
+}
+
+// connect dials in as userID and consumes the welcome message
+func (f *wsFixture) connect(t *testing.T, userID string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(f.server.URL, "http")+"?user="+userID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	await(t, conn, "system.welcome", "")
+	return conn
+}
+
+// activeChats returns how many chats are registered
+func (f *wsFixture) activeChats() int {
+	f.wsm.mu.RLock()
+	defer f.wsm.mu.RUnlock()
+	return len(f.wsm.activeChats)
+}
+
+func send(t *testing.T, conn *websocket.Conn, msgType, requestID string, payload map[string]interface{}) {
+	t.Helper()
+	sendTo(t, conn, "", msgType, requestID, payload)
+}
+
+// sendTo sends a message naming sessionID as its session
+func sendTo(t *testing.T, conn *websocket.Conn, sessionID, msgType, requestID string, payload map[string]interface{}) {
+	t.Helper()
+	if err := conn.WriteJSON(&WSMessage{Type: msgType, SessionID: sessionID, RequestID: requestID, Payload: payload}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// await reads until a message of msgType for requestID arrives, failing on any error
+// message other than the one awaited
+func await(t *testing.T, conn *websocket.Conn, msgType, requestID string) *WSMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg WSMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("waiting for %s %s: %v", msgType, requestID, err)
+		}
+		id, _ := msg.Payload["request_id"].(string)
+		if msg.Type == msgType && (requestID == "" || id == requestID) {
+			return &msg
+		}
+		if msg.Type == "error" {
+			t.Fatalf("waiting for %s %s: got error %v", msgType, requestID, msg.Payload)
+		}
+	}
+}
+
+func TestWebSocketChatCancel(t *testing.T) {
+	aborted := make(chan string, 2)
+	f := newWSFixture(t, newStallingChat(t, aborted).URL)
+	alice := f.connect(t, "alice")
+	bob := f.connect(t, "bob")
+	chat := func(content string) map[string]interface{} {
+		return map[string]interface{}{"content": content, "model_name": "llama3.2"}
+	}
+
+	// r1 holds the model's only slot and r2 queues behind it
+	send(t, alice, "chat.send", "r1", chat("first"))
+	await(t, alice, "chat.stream", "r1")
+	send(t, alice, "chat.send", "r2", chat("second"))
+
+	// The message loop stays responsive while both chats wait
+	send(t, alice, "chat.send", "r1", chat("again"))
+	if msg := await(t, alice, "error", "r1"); msg.Payload["error_code"] != "duplicate_request" {
+		t.Errorf("duplicate = %v", msg.Payload)
+	}
+
+	// Another user cannot cancel alice's chat, even knowing its request ID
+	send(t, bob, "chat.cancel", "", map[string]interface{}{"request_id": "r1"})
+	if msg := await(t, bob, "error", ""); msg.Payload["error_code"] != "request_not_found" {
+		t.Errorf("bob's cancel = %v", msg.Payload)
+	}
+	if got := f.activeChats(); got != 2 {
+		t.Fatalf("active chats = %d, want 2", got)
+	}
+
+	// The queued chat is withdrawn before it reaches the model
+	send(t, alice, "chat.cancel", "c2", map[string]interface{}{"request_id": "r2"})
+	await(t, alice, "chat.cancelled", "r2")
+
+	// The streaming chat is aborted upstream
+	send(t, alice, "chat.cancel", "c1", map[string]interface{}{"request_id": "r1"})
+	await(t, alice, "chat.cancelled", "r1")
+	select {
+	case prompt := <-aborted:
+		if prompt != "first" {
+			t.Errorf("aborted %q", prompt)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream request was not cancelled")
+	}
+
+	// Cancelled chats unregister without reporting a failure
+	deadline := time.Now().Add(2 * time.Second)
+	for f.activeChats() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("active chats = %d", f.activeChats())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	send(t, alice, "system.ping", "p1", nil)
+	await(t, alice, "system.pong", "p1")
+	select {
+	case prompt := <-aborted:
+		t.Errorf("queued chat %q reached the model", prompt)
+	default:
+	}
+}
+
+// callerTools records the caller each tool call runs as
+type callerTools struct {
+	mu      sync.Mutex
+	callers []*Caller
+}
+
+func (ct *callerTools) ToolDefinitions() []ToolDefinition {
+	return []ToolDefinition{{Type: "function", Function: &FunctionDefinition{Name: "whoami"}, Scope: ToolScopeTools}}
+}
+
+func (ct *callerTools) CallTool(ctx context.Context, sessionID string, call *FunctionCall) (string, error) {
+	caller, _ := CallerFromContext(ctx)
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.callers = append(ct.callers, caller)
+	return "ok", nil
+}
+
+func TestWebSocketChatRunsAsSender(t *testing.T) {
+	ollama := newFakeOllama(t, "ollama")
+	f := newWSFixture(t, ollama.URL)
+	tools := &callerTools{}
+	f.im.RegisterToolProvider(tools)
+	alice := f.connect(t, "alice")
+	bob := f.connect(t, "bob")
+
+	send(t, alice, "session.create", "s1", map[string]interface{}{"model_name": "llama3.2"})
+	sessionID, _ := await(t, alice, "session.created", "s1").Payload["session_id"].(string)
+	chat := map[string]interface{}{"content": "who am I?", "model_name": "llama3.2"}
+
+	// Naming another user's session neither writes to it nor reads its history
+	sendTo(t, bob, sessionID, "chat.send", "b1", chat)
+	if msg := await(t, bob, "error", "b1"); msg.Payload["error_code"] != "session_not_found" {
+		t.Errorf("bob's chat = %v", msg.Payload)
+	}
+	if session, _ := f.sm.GetSession(sessionID); session.MessageCount != 0 || ollama.count("POST /api/chat") != 0 {
+		t.Fatalf("bob reached alice's session: %d messages, %d model calls", session.MessageCount, ollama.count("POST /api/chat"))
+	}
+
+	// The owner's chat calls tools as the connection's user with its permissions
+	ollama.toolCalls = [][]OllamaToolCall{call("whoami", nil)}
+	ollama.replies = []string{"You are alice."}
+	sendTo(t, alice, sessionID, "chat.send", "a1", chat)
+	for {
+		msg := await(t, alice, "chat.stream", "a1")
+		if chunk, _ := msg.Payload["chunk"].(map[string]interface{}); chunk["done"] == true {
+			break
+		}
+	}
+	tools.mu.Lock()
+	defer tools.mu.Unlock()
+	if len(tools.callers) != 1 || tools.callers[0] == nil || tools.callers[0].UserID != "alice" || !reflect.DeepEqual(tools.callers[0].Permissions, DefaultPermissions) {
+		t.Errorf("tool callers = %+v", tools.callers)
+	}
+}
+
+// sessionTools answers ExecuteInSession with a canned result and records each call
+type sessionTools struct {
+	mu     sync.Mutex
+	result *types.ToolResult
+	calls  []string // "<user> <session> <tool> <arguments>"
+}
+
+func (st *sessionTools) ExecuteInSession(ctx context.Context, sessionID string, call *types.ToolCall) (*types.ToolResult, error) {
+	caller, _ := CallerFromContext(ctx)
+	arguments, _ := json.Marshal(call.Arguments)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.calls = append(st.calls, strings.Join([]string{caller.UserID, sessionID, call.Name, string(arguments)}, " "))
+	return st.result, nil
+}
+
+func (st *sessionTools) answer(result *types.ToolResult) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.result = result
+}
+
+func (st *sessionTools) recorded() []string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return append([]string(nil), st.calls...)
+}
+
+func TestWebSocketCodeAndFileTools(t *testing.T) {
+	f := newWSFixture(t, newFakeOllama(t, "ollama").URL)
+	tools := &sessionTools{}
+	f.wsm.SetSessionTools(tools)
+	alice := f.connect(t, "alice")
+	bob := f.connect(t, "bob")
+	send(t, alice, "session.create", "s1", map[string]interface{}{"model_name": "llama3.2"})
+	sessionID, _ := await(t, alice, "session.created", "s1").Payload["session_id"].(string)
+
+	// Code runs through execute_code as the sender, and the sandbox report becomes a CodeResult
+	tools.answer(&types.ToolResult{Success: false, Error: "exit code 1", Content: `{"exit_code":1,"stdout":"hi\n","stderr":"boom","usage":{"wall_time":5000000}}`})
+	sendTo(t, alice, sessionID, "code.execute", "c1", map[string]interface{}{"language": "python", "code": "print('hi')", "stdin": "x"})
+	var code CodeResult
+	if err := utils.MapToStruct(await(t, alice, "code.result", "c1").Payload["result"].(map[string]interface{}), &code); err != nil {
+		t.Fatal(err)
+	}
+	if code != (CodeResult{Output: "hi\n", Error: "boom", ExitCode: 1, Duration: 5}) {
+		t.Errorf("code result = %+v", code)
+	}
+
+	// File operations map onto the file tools; the joined session is used when none is named
+	send(t, alice, "session.join", "j1", map[string]interface{}{"session_id": sessionID})
+	await(t, alice, "session.joined", "j1")
+	tools.answer(&types.ToolResult{Success: true, Content: "a.txt", Data: []FileInfo{{Name: "a.txt", Path: "a.txt", Size: 3}}})
+	send(t, alice, "file.operation", "f1", map[string]interface{}{"operation": "list", "user_id": "bob", "session_id": "elsewhere"})
+	files, _ := await(t, alice, "file.result", "f1").Payload["result"].(map[string]interface{})["files"].([]interface{})
+	if len(files) != 1 {
+		t.Errorf("files = %v", files)
+	}
+
+	// Another user's session is refused before any tool runs
+	sendTo(t, bob, sessionID, "code.execute", "b1", map[string]interface{}{"language": "python", "code": "print('hi')"})
+	if msg := await(t, bob, "error", "b1"); msg.Payload["error_code"] != "session_not_found" {
+		t.Errorf("bob's code = %v", msg.Payload)
+	}
+	send(t, alice, "file.operation", "f2", map[string]interface{}{"operation": "chmod", "path": "a.txt"})
+	if msg := await(t, alice, "error", "f2"); msg.Payload["error_code"] != "invalid_payload" {
+		t.Errorf("chmod = %v", msg.Payload)
+	}
+
+	want := []string{
+		"alice " + sessionID + ` execute_code {"code":"print('hi')","language":"python","stdin":"x"}`,
+		"alice " + sessionID + ` list_files {"path":".","recursive":false}`,
+	}
+	if got := tools.recorded(); !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %q, want %q", got, want)
+	}
+}
+
+func TestWebSocketBranchesCheckOwner(t *testing.T) {
+	ollama := newFakeOllama(t, "ollama")
+	f := newWSFixture(t, ollama.URL)
+	alice := f.connect(t, "alice")
+	bob := f.connect(t, "bob")
+	send(t, alice, "session.create", "s1", map[string]interface{}{"model_name": "llama3.2"})
+	sessionID, _ := await(t, alice, "session.created", "s1").Payload["session_id"].(string)
+	message, err := f.sm.AddMessage(sessionID, "user", "hello", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every session and branch message naming alice's session is refused for bob
+	for requestID, msgType := range map[string]string{
+		"join":   "session.join",
+		"list":   "branch.list",
+		"fork":   "branch.fork",
+		"switch": "branch.switch",
+		"merge":  "branch.merge",
+	} {
+		send(t, bob, msgType, requestID, map[string]interface{}{
+			"session_id": sessionID,
+			"message_id": message.ID,
+			"branch_id":  "main",
+			"content":    "edited",
+			"summary":    "merged",
+		})
+		if msg := await(t, bob, "error", requestID); msg.Payload["error_code"] != "session_not_found" {
+			t.Errorf("bob's %s = %v", msgType, msg.Payload)
+		}
+	}
+	if session, _ := f.sm.GetSession(sessionID); session.MessageCount != 1 || ollama.count("POST /api/chat") != 0 {
+		t.Errorf("bob reached alice's session: %d messages, %d model calls", session.MessageCount, ollama.count("POST /api/chat"))
+	}
+	f.wsm.mu.RLock()
+	joined := len(f.wsm.sessionConnections[sessionID])
+	f.wsm.mu.RUnlock()
+	if joined != 0 {
+		t.Errorf("bob joined alice's session: %d connections", joined)
+	}
+
+	// The owner still sees the untouched tree
+	send(t, alice, "branch.list", "a1", map[string]interface{}{"session_id": sessionID})
+	tree, _ := await(t, alice, "branch.tree", "a1").Payload["tree"].(map[string]interface{})
+	if branches, _ := tree["branches"].([]interface{}); len(branches) != 1 {
+		t.Errorf("tree = %v", tree)
+	}
+}
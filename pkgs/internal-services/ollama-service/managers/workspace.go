@@ -0,0 +1,462 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/workspace.go
+
+package managers
+
+import (
+	// stdlib
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+)
+
+// FileAuditEntry records a single tool file operation
+type FileAuditEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	SessionID string    `json:"session_id"`
+	UserID    string    `json:"user_id"`
+	Operation string    `json:"operation"`
+	Path      string    `json:"path"`
+	Bytes     int64     `json:"bytes,omitempty"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// WorkspaceUsage reports a user's workspace consumption
+type WorkspaceUsage struct {
+	Bytes int64 `json:"bytes"`
+	Files int   `json:"files"`
+}
+
+var workspaceNamePattern = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// ErrPathEscape is returned when a path resolves outside the session workspace
+var ErrPathEscape = errors.New("path escapes session workspace")
+
+// HandleFileOperation performs a file operation inside the session's workspace on behalf
+// of the authenticated user that owns the session
+func (dm *DiskManager) HandleFileOperation(ctx context.Context, op *FileOperation) (*FileResult, error) {
+	if op.SessionID == "" || op.UserID == "" {
+		return nil, fmt.Errorf("file operations require an authenticated session")
+	}
+	session, exists := dm.sessionManager.GetSession(op.SessionID)
+	if !exists {
+		return nil, fmt.Errorf("session not found: %s", op.SessionID)
+	}
+	if op.UserID != session.UserID {
+		return nil, fmt.Errorf("session %s does not belong to user %s", op.SessionID, op.UserID)
+	}
+
+	result, bytes, err := dm.runFileOperation(op)
+	dm.appendFileAudit(op, bytes, result, err)
+	return result, err
+}
+
+// runFileOperation dispatches an operation; failures the caller may fix are reported in the result
+func (dm *DiskManager) runFileOperation(op *FileOperation) (*FileResult, int64, error) {
+	root, err := dm.workspaceRoot(op.UserID, op.SessionID)
+	if err != nil {
+		return nil, 0, err
+	}
+	resolve := resolveWorkspacePath
+	if op.Operation == "delete" {
+		resolve = resolveWorkspaceEntry
+	}
+	path, err := resolve(root, op.Path)
+	if err != nil {
+		return &FileResult{Success: false, Error: err.Error()}, 0, nil
+	}
+
+	switch op.Operation {
+	case "read":
+		dm.mu.RLock()
+		defer dm.mu.RUnlock()
+		return dm.readWorkspaceFile(path)
+	case "write":
+		dm.mu.Lock()
+		defer dm.mu.Unlock()
+		return dm.writeWorkspaceFile(op, root, path)
+	case "delete":
+		dm.mu.Lock()
+		defer dm.mu.Unlock()
+		return dm.deleteWorkspaceFile(root, path, op.Recursive)
+	case "list":
+		dm.mu.RLock()
+		defer dm.mu.RUnlock()
+		return dm.listWorkspaceFiles(root, path, op.Recursive)
+	default:
+		return &FileResult{Success: false, Error: fmt.Sprintf("unknown file operation: %s", op.Operation)}, 0, nil
+	}
+}
+
+// readWorkspaceFile reads a regular file without following a final symlink
+func (dm *DiskManager) readWorkspaceFile(path string) (*FileResult, int64, error) {
+	file, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return &FileResult{Success: false, Error: fmt.Sprintf("failed to open file: %v", err)}, 0, nil
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return &FileResult{Success: false, Error: fmt.Sprintf("failed to stat file: %v", err)}, 0, nil
+	}
+	if !info.Mode().IsRegular() {
+		return &FileResult{Success: false, Error: "not a regular file"}, 0, nil
+	}
+	if dm.workspaceMaxBytes > 0 && info.Size() > dm.workspaceMaxBytes {
+		return &FileResult{Success: false, Error: "file exceeds workspace quota"}, 0, nil
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return &FileResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", err)}, 0, nil
+	}
+
+	return &FileResult{
+		Success:  true,
+		Content:  string(data),
+		Size:     int64(len(data)),
+		Modified: info.ModTime(),
+	}, int64(len(data)), nil
+}
+
+// writeWorkspaceFile writes or appends content after checking the user's quota
+func (dm *DiskManager) writeWorkspaceFile(op *FileOperation, root, path string) (*FileResult, int64, error) {
+	if err := dm.checkDiskUsage(); err != nil {
+		return &FileResult{Success: false, Error: err.Error()}, 0, nil
+	}
+
+	var existing int64
+	isNew := true
+	if info, err := os.Lstat(path); err == nil {
+		if !info.Mode().IsRegular() {
+			return &FileResult{Success: false, Error: "not a regular file"}, 0, nil
+		}
+		existing = info.Size()
+		isNew = false
+	}
+
+	size := int64(len(op.Content))
+	if op.Mode == "append" {
+		size += existing
+	}
+
+	usage, err := dm.userWorkspaceUsage(op.UserID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if dm.workspaceMaxBytes > 0 && usage.Bytes-existing+size > dm.workspaceMaxBytes {
+		return &FileResult{Success: false, Error: fmt.Sprintf("workspace byte quota exceeded: %d > %d", usage.Bytes-existing+size, dm.workspaceMaxBytes)}, 0, nil
+	}
+	if dm.workspaceMaxFiles > 0 && isNew && usage.Files+1 > dm.workspaceMaxFiles {
+		return &FileResult{Success: false, Error: fmt.Sprintf("workspace file quota exceeded: %d files", dm.workspaceMaxFiles)}, 0, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return &FileResult{Success: false, Error: fmt.Sprintf("failed to create directory: %v", err)}, 0, nil
+	}
+	// MkdirAll may have raced with a symlink swap; re-check before opening
+	if _, err := resolveWorkspacePath(root, op.Path); err != nil {
+		return &FileResult{Success: false, Error: err.Error()}, 0, nil
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | syscall.O_NOFOLLOW
+	if op.Mode == "append" {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return &FileResult{Success: false, Error: fmt.Sprintf("failed to open file: %v", err)}, 0, nil
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(op.Content); err != nil {
+		return &FileResult{Success: false, Error: fmt.Sprintf("failed to write file: %v", err)}, 0, nil
+	}
+
+	return &FileResult{
+		Success:  true,
+		Size:     size,
+		Modified: time.Now(),
+	}, int64(len(op.Content)), nil
+}
+
+// deleteWorkspaceFile removes a file, or a directory when recursive is set
+func (dm *DiskManager) deleteWorkspaceFile(root, path string, recursive bool) (*FileResult, int64, error) {
+	if path == root {
+		return &FileResult{Success: false, Error: "cannot delete workspace root"}, 0, nil
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return &FileResult{Success: false, Error: fmt.Sprintf("failed to stat file: %v", err)}, 0, nil
+	}
+
+	// Symlinks are removed themselves, never their targets
+	if info.IsDir() && recursive {
+		err = os.RemoveAll(path)
+	} else {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		return &FileResult{Success: false, Error: fmt.Sprintf("failed to delete: %v", err)}, 0, nil
+	}
+
+	return &FileResult{Success: true, Size: info.Size()}, info.Size(), nil
+}
+
+// listWorkspaceFiles lists a directory with paths relative to the workspace root
+func (dm *DiskManager) listWorkspaceFiles(root, path string, recursive bool) (*FileResult, int64, error) {
+	files := make([]FileInfo, 0)
+	addFile := func(p string, info fs.FileInfo) {
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return
+		}
+		files = append(files, FileInfo{
+			Name:     info.Name(),
+			Path:     filepath.ToSlash(rel),
+			Size:     info.Size(),
+			IsDir:    info.IsDir(),
+			Modified: info.ModTime(),
+		})
+	}
+
+	if recursive {
+		// WalkDir does not descend through symlinked directories
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || p == path {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				addFile(p, info)
+			}
+			return nil
+		})
+		if err != nil {
+			return &FileResult{Success: false, Error: fmt.Sprintf("failed to list files: %v", err)}, 0, nil
+		}
+	} else {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return &FileResult{Success: false, Error: fmt.Sprintf("failed to list files: %v", err)}, 0, nil
+		}
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil {
+				addFile(filepath.Join(path, entry.Name()), info)
+			}
+		}
+	}
+
+	return &FileResult{Success: true, Files: files}, 0, nil
+}
+
+// workspaceRoot returns the session's workspace directory, creating it if needed
+func (dm *DiskManager) workspaceRoot(userID, sessionID string) (string, error) {
+	root := filepath.Join(dm.getUserWorkspaceDir(userID), workspaceName(sessionID))
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return "", fmt.Errorf("failed to create workspace: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve workspace: %w", err)
+	}
+	return filepath.Abs(resolved)
+}
+
+// resolveWorkspacePath maps a model-supplied path onto root, rejecting anything that
+// escapes it lexically or through a symlink
+func resolveWorkspacePath(root, requested string) (string, error) {
+	if strings.ContainsRune(requested, 0) {
+		return "", fmt.Errorf("invalid path")
+	}
+	if filepath.IsAbs(requested) {
+		return "", fmt.Errorf("%w: absolute paths are not allowed", ErrPathEscape)
+	}
+
+	path := filepath.Join(root, filepath.Clean(requested))
+	if !withinRoot(root, path) {
+		return "", ErrPathEscape
+	}
+
+	// Resolve the longest existing prefix so symlinks anywhere in it are checked
+	existing := path
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path: %w", err)
+	}
+	if !withinRoot(root, resolved) {
+		return "", ErrPathEscape
+	}
+
+	rest, err := filepath.Rel(existing, path)
+	if err != nil {
+		return "", ErrPathEscape
+	}
+	return filepath.Join(resolved, rest), nil
+}
+
+// resolveWorkspaceEntry resolves only the parent directory of a path, so a final
+// symlink names the link itself rather than its target
+func resolveWorkspaceEntry(root, requested string) (string, error) {
+	cleaned := filepath.Clean(requested)
+	if cleaned == "." {
+		return resolveWorkspacePath(root, cleaned)
+	}
+	parent, err := resolveWorkspacePath(root, filepath.Dir(cleaned))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(parent, filepath.Base(cleaned)), nil
+}
+
+// withinRoot reports whether path is root or below it
+func withinRoot(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// userWorkspaceUsage sums the bytes and files across all of a user's session workspaces
+func (dm *DiskManager) userWorkspaceUsage(userID string) (*WorkspaceUsage, error) {
+	usage := &WorkspaceUsage{}
+	err := filepath.WalkDir(dm.getUserWorkspaceDir(userID), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				usage.Bytes += info.Size()
+				usage.Files++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute workspace usage: %w", err)
+	}
+	return usage, nil
+}
+
+// GetWorkspaceUsage returns a user's workspace consumption
+func (dm *DiskManager) GetWorkspaceUsage(userID string) (*WorkspaceUsage, error) {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return dm.userWorkspaceUsage(userID)
+}
+
+// appendFileAudit writes an audit entry next to the session file
+func (dm *DiskManager) appendFileAudit(op *FileOperation, bytes int64, result *FileResult, opErr error) {
+	entry := FileAuditEntry{
+		Timestamp: time.Now(),
+		SessionID: op.SessionID,
+		UserID:    op.UserID,
+		Operation: op.Operation,
+		Path:      op.Path,
+		Bytes:     bytes,
+		Success:   opErr == nil && result != nil && result.Success,
+	}
+	if opErr != nil {
+		entry.Error = opErr.Error()
+	} else if result != nil {
+		entry.Error = result.Error
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to marshal file audit entry")
+		return
+	}
+
+	dm.auditMu.Lock()
+	defer dm.auditMu.Unlock()
+	file, err := os.OpenFile(dm.getFileAuditPath(op.SessionID), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		log.Error().Err(err).Str("session_id", op.SessionID).Msg("Failed to open file audit log")
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		log.Error().Err(err).Str("session_id", op.SessionID).Msg("Failed to write file audit entry")
+	}
+}
+
+// GetFileAuditLog returns the file operations recorded for a session
+func (dm *DiskManager) GetFileAuditLog(sessionID string) ([]FileAuditEntry, error) {
+	dm.auditMu.Lock()
+	defer dm.auditMu.Unlock()
+
+	file, err := os.Open(dm.getFileAuditPath(sessionID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []FileAuditEntry{}, nil
+		}
+		return nil, fmt.Errorf("failed to open file audit log: %w", err)
+	}
+	defer file.Close()
+
+	entries := make([]FileAuditEntry, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry FileAuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// getUserWorkspaceDir generates the directory holding a user's session workspaces
+func (dm *DiskManager) getUserWorkspaceDir(userID string) string {
+	return filepath.Join(dm.dataDir, "workspaces", workspaceName(userID))
+}
+
+// getFileAuditPath generates the audit log path stored alongside the session file
+func (dm *DiskManager) getFileAuditPath(sessionID string) string {
+	return filepath.Join(dm.dataDir, "sessions", fmt.Sprintf("%s.audit.jsonl", workspaceName(sessionID)))
+}
+
+// workspaceName makes an ID safe to use as a single path element
+func workspaceName(id string) string {
+	name := workspaceNamePattern.ReplaceAllString(id, "_")
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
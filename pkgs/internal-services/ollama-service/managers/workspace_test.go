@@ -0,0 +1,216 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = managers/workspace_test.go
+
+package managers
+
+import (
+	// stdlib
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// workspaceFixture is a disk manager over a temporary data dir with a session for alice
+// and one for bob; outside is a directory beside the data dir holding secret.txt
+type workspaceFixture struct {
+	dm      *DiskManager
+	alice   *Session
+	bob     *Session
+	root    string
+	outside string
+}
+
+func newWorkspaceFixture(t *testing.T, maxBytes int64, maxFiles int) *workspaceFixture {
+	t.Helper()
+	sm := NewSessionManager(schedulerConfig(&LimitsConfig{}), nil, nil)
+	t.Cleanup(func() { sm.Shutdown(context.Background()) })
+	alice, err := sm.CreateSession(context.Background(), "alice", "llama3.2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := sm.CreateSession(context.Background(), "bob", "llama3.2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := t.TempDir()
+	dm := &DiskManager{
+		sessionManager:    sm,
+		dataDir:           filepath.Join(base, "data"),
+		backupDir:         filepath.Join(base, "backups"),
+		maxDiskUsage:      1 << 30,
+		workspaceMaxBytes: maxBytes,
+		workspaceMaxFiles: maxFiles,
+	}
+	if err := dm.ensureDirectories(); err != nil {
+		t.Fatal(err)
+	}
+	root, err := dm.workspaceRoot("alice", alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(base, "outside")
+	if err := os.MkdirAll(outside, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &workspaceFixture{dm: dm, alice: alice, bob: bob, root: root, outside: outside}
+}
+
+// run performs op as alice in her session
+func (f *workspaceFixture) run(t *testing.T, op *FileOperation) *FileResult {
+	t.Helper()
+	op.SessionID, op.UserID = f.alice.ID, "alice"
+	result, err := f.dm.HandleFileOperation(context.Background(), op)
+	if err != nil {
+		t.Fatalf("%s %s: %v", op.Operation, op.Path, err)
+	}
+	return result
+}
+
+func TestWorkspacePathResolution(t *testing.T) {
+	f := newWorkspaceFixture(t, 0, 0)
+	mustRun := func(op *FileOperation) {
+		t.Helper()
+		if result := f.run(t, op); !result.Success {
+			t.Fatalf("%s %s: %s", op.Operation, op.Path, result.Error)
+		}
+	}
+	mustRun(&FileOperation{Operation: "write", Path: "notes/a.txt", Content: "hello"})
+	if err := os.Symlink(filepath.Join(f.outside, "secret.txt"), filepath.Join(f.root, "secret-link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(f.outside, filepath.Join(f.root, "outside-dir")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("notes", filepath.Join(f.root, "inside-dir")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		op      FileOperation
+		content string // on success
+		errPart string // on failure
+	}{
+		{name: "relative", op: FileOperation{Operation: "read", Path: "notes/a.txt"}, content: "hello"},
+		{name: "cleaned dots", op: FileOperation{Operation: "read", Path: "notes/../notes/./a.txt"}, content: "hello"},
+		{name: "parent", op: FileOperation{Operation: "read", Path: "../../../outside/secret.txt"}, errPart: "escapes"},
+		{name: "parent write", op: FileOperation{Operation: "write", Path: "../escape.txt", Content: "x"}, errPart: "escapes"},
+		{name: "absolute", op: FileOperation{Operation: "read", Path: filepath.Join(f.outside, "secret.txt")}, errPart: "absolute"},
+		{name: "nul byte", op: FileOperation{Operation: "read", Path: "a\x00b"}, errPart: "invalid path"},
+		{name: "symlinked file", op: FileOperation{Operation: "read", Path: "secret-link"}, errPart: "escapes"},
+		{name: "symlinked dir", op: FileOperation{Operation: "read", Path: "outside-dir/secret.txt"}, errPart: "escapes"},
+		{name: "write through symlinked dir", op: FileOperation{Operation: "write", Path: "outside-dir/new.txt", Content: "x"}, errPart: "escapes"},
+		{name: "list through symlinked dir", op: FileOperation{Operation: "list", Path: "outside-dir"}, errPart: "escapes"},
+		{name: "symlink inside the workspace", op: FileOperation{Operation: "read", Path: "inside-dir/a.txt"}, content: "hello"},
+	} {
+		op := tc.op
+		result := f.run(t, &op)
+		switch {
+		case tc.errPart == "" && (!result.Success || result.Content != tc.content):
+			t.Errorf("%s: result = %+v", tc.name, result)
+		case tc.errPart != "" && (result.Success || !strings.Contains(result.Error, tc.errPart)):
+			t.Errorf("%s: result = %+v, want error containing %q", tc.name, result, tc.errPart)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(f.outside, "new.txt")); !os.IsNotExist(err) {
+		t.Error("write escaped through a symlinked directory")
+	}
+
+	// Deleting a symlink removes the link, never its target
+	mustRun(&FileOperation{Operation: "delete", Path: "outside-dir"})
+	mustRun(&FileOperation{Operation: "delete", Path: "inside-dir"})
+	if _, err := os.Stat(filepath.Join(f.outside, "secret.txt")); err != nil {
+		t.Errorf("symlink target removed: %v", err)
+	}
+	if result := f.run(t, &FileOperation{Operation: "read", Path: "notes/a.txt"}); result.Content != "hello" {
+		t.Errorf("symlinked directory removed: %+v", result)
+	}
+	if result := f.run(t, &FileOperation{Operation: "delete", Path: "."}); result.Success {
+		t.Error("deleted the workspace root")
+	}
+
+	// A directory swapped for a symlink after it was created is caught on the next call
+	mustRun(&FileOperation{Operation: "write", Path: "swap/a.txt", Content: "x"})
+	if err := os.RemoveAll(filepath.Join(f.root, "swap")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(f.outside, filepath.Join(f.root, "swap")); err != nil {
+		t.Fatal(err)
+	}
+	if result := f.run(t, &FileOperation{Operation: "write", Path: "swap/secret.txt", Content: "owned"}); result.Success {
+		t.Error("wrote through a swapped intermediate directory")
+	}
+	if data, _ := os.ReadFile(filepath.Join(f.outside, "secret.txt")); string(data) != "secret" {
+		t.Errorf("secret.txt = %q", data)
+	}
+
+	// Operations need the session's owner
+	for _, op := range []*FileOperation{
+		{Operation: "read", Path: "notes/a.txt", SessionID: f.alice.ID},
+		{Operation: "read", Path: "notes/a.txt", SessionID: f.alice.ID, UserID: "bob"},
+		{Operation: "read", Path: "notes/a.txt", SessionID: f.bob.ID, UserID: "alice"},
+	} {
+		if _, err := f.dm.HandleFileOperation(context.Background(), op); err == nil {
+			t.Errorf("%+v was allowed", op)
+		}
+	}
+}
+
+func TestWorkspaceQuotaAndAudit(t *testing.T) {
+	f := newWorkspaceFixture(t, 10, 2)
+
+	for _, tc := range []struct {
+		op      FileOperation
+		success bool
+		errPart string
+	}{
+		{op: FileOperation{Operation: "write", Path: "a.txt", Content: "12345"}, success: true},
+		{op: FileOperation{Operation: "write", Path: "a.txt", Content: "678", Mode: "append"}, success: true},
+		{op: FileOperation{Operation: "write", Path: "a.txt", Content: "9ab", Mode: "append"}, errPart: "byte quota exceeded: 11 > 10"},
+		{op: FileOperation{Operation: "write", Path: "a.txt", Content: "1234567890"}, success: true}, // overwrite frees the old bytes
+		{op: FileOperation{Operation: "write", Path: "b.txt", Content: "x"}, errPart: "byte quota exceeded"},
+		{op: FileOperation{Operation: "write", Path: "a.txt", Content: "1"}, success: true},
+		{op: FileOperation{Operation: "write", Path: "b.txt", Content: "2"}, success: true},
+		{op: FileOperation{Operation: "write", Path: "c.txt", Content: "3"}, errPart: "file quota exceeded: 2 files"},
+		{op: FileOperation{Operation: "delete", Path: "b.txt"}, success: true},
+		{op: FileOperation{Operation: "write", Path: "c.txt", Content: "3"}, success: true},
+	} {
+		op := tc.op
+		result := f.run(t, &op)
+		if result.Success != tc.success || !strings.Contains(result.Error, tc.errPart) {
+			t.Errorf("%s %s %q: result = %+v", op.Operation, op.Path, op.Content, result)
+		}
+	}
+	if usage, err := f.dm.GetWorkspaceUsage("alice"); err != nil || usage.Bytes != 2 || usage.Files != 2 {
+		t.Errorf("usage = %+v, %v", usage, err)
+	}
+
+	// Every operation, refused or not, is one JSONL line next to the session file
+	data, err := os.ReadFile(f.dm.getFileAuditPath(f.alice.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 10 {
+		t.Errorf("audit has %d lines, want 10", lines)
+	}
+	entries, err := f.dm.GetFileAuditLog(f.alice.ID)
+	if err != nil || len(entries) != 10 {
+		t.Fatalf("entries = %d, %v", len(entries), err)
+	}
+	if entry := entries[2]; entry.Success || entry.UserID != "alice" || entry.Operation != "write" || entry.Path != "a.txt" || !strings.Contains(entry.Error, "byte quota") {
+		t.Errorf("refused entry = %+v", entry)
+	}
+	if entry := entries[0]; !entry.Success || entry.Bytes != 5 || entry.SessionID != f.alice.ID {
+		t.Errorf("first entry = %+v", entry)
+	}
+	if entries, _ := f.dm.GetFileAuditLog(f.bob.ID); len(entries) != 0 {
+		t.Errorf("bob's audit = %+v", entries)
+	}
+}
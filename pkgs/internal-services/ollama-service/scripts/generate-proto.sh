@@ -0,0 +1,13 @@
+#!/bin/bash
+
+# generate-proto.sh - Regenerate Go stubs for the OCS gRPC API
+# Requires protoc, protoc-gen-go and protoc-gen-go-grpc on PATH
+
+set -euo pipefail
+
+cd "$(dirname "$0")/.."
+
+protoc -I api/proto \
+    --go_out=api/proto --go_opt=paths=source_relative \
+    --go-grpc_out=api/proto --go-grpc_opt=paths=source_relative \
+    api/proto/ocs/v1/ocs.proto
@@ -0,0 +1,203 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/mcp/client.go
+
+package mcp
+
+import (
+	// stdlib
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+)
+
+// Transport carries JSON-RPC messages to an MCP server
+type Transport interface {
+	// Request sends a request and waits for the matching response
+	Request(ctx context.Context, msg *Message) (*Message, error)
+	// Notify sends a notification
+	Notify(ctx context.Context, msg *Message) error
+	Close() error
+}
+
+// Config lists the MCP servers OCS mounts
+type Config struct {
+	Servers []ServerConfig `yaml:"servers"`
+}
+
+// ServerConfig describes how to reach one MCP server
+type ServerConfig struct {
+	Name      string            `yaml:"name"`
+	Transport string            `yaml:"transport"` // stdio or http
+	Command   string            `yaml:"command"`   // stdio
+	Args      []string          `yaml:"args"`      // stdio
+	Env       map[string]string `yaml:"env"`       // stdio
+	Dir       string            `yaml:"dir"`       // stdio
+	URL       string            `yaml:"url"`       // http
+	Headers   map[string]string `yaml:"headers"`   // http
+	Timeout   int               `yaml:"timeout"`   // seconds per call
+	Scope     string            `yaml:"scope"`     // permission scope for imported tools
+	Disabled  bool              `yaml:"disabled"`
+}
+
+// Client is a connection to a single MCP server
+type Client struct {
+	name       string
+	transport  Transport
+	timeout    time.Duration
+	nextID     atomic.Int64
+	serverInfo *InitializeResult
+}
+
+const defaultCallTimeout = 30 * time.Second
+
+// Connect starts or dials the server described by cfg and completes the handshake
+func Connect(ctx context.Context, cfg ServerConfig) (*Client, error) {
+	var transport Transport
+	var err error
+	switch cfg.Transport {
+	case "stdio", "":
+		transport, err = NewStdioTransport(cfg.Name, cfg.Command, cfg.Args, cfg.Env, cfg.Dir)
+	case "http":
+		transport, err = NewHTTPTransport(cfg.URL, cfg.Headers)
+	default:
+		return nil, fmt.Errorf("unsupported MCP transport: %s", cfg.Transport)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := defaultCallTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	client := NewClient(cfg.Name, transport, timeout)
+	if _, err := client.Initialize(ctx); err != nil {
+		transport.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewClient wraps an established transport
+func NewClient(name string, transport Transport, timeout time.Duration) *Client {
+	return &Client{
+		name:      name,
+		transport: transport,
+		timeout:   timeout,
+	}
+}
+
+// Name returns the configured server name
+func (c *Client) Name() string {
+	return c.name
+}
+
+// ServerInfo returns what the server reported during initialization
+func (c *Client) ServerInfo() *InitializeResult {
+	return c.serverInfo
+}
+
+// Initialize negotiates the protocol version and announces the client
+func (c *Client) Initialize(ctx context.Context) (*InitializeResult, error) {
+	params := &InitializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]interface{}{},
+		ClientInfo:      Implementation{Name: "ocs", Version: "1.0.0"},
+	}
+
+	var result InitializeResult
+	if err := c.call(ctx, "initialize", params, &result); err != nil {
+		return nil, fmt.Errorf("mcp initialize %s: %w", c.name, err)
+	}
+	if setter, ok := c.transport.(interface{ SetProtocolVersion(string) }); ok {
+		setter.SetProtocolVersion(result.ProtocolVersion)
+	}
+	if err := c.transport.Notify(ctx, newNotification("notifications/initialized")); err != nil {
+		return nil, fmt.Errorf("mcp initialized notification %s: %w", c.name, err)
+	}
+
+	c.serverInfo = &result
+	log.Info().
+		Str("server", c.name).
+		Str("server_name", result.ServerInfo.Name).
+		Str("protocol_version", result.ProtocolVersion).
+		Msg("Connected to MCP server")
+	return &result, nil
+}
+
+// ListTools returns every tool the server exposes
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	tools := make([]Tool, 0)
+	cursor := ""
+	for {
+		var page ListToolsResult
+		if err := c.call(ctx, "tools/list", &ListToolsParams{Cursor: cursor}, &page); err != nil {
+			return nil, fmt.Errorf("mcp tools/list %s: %w", c.name, err)
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool invokes a tool on the server
+func (c *Client) CallTool(ctx context.Context, name string, arguments map[string]interface{}) (*CallToolResult, error) {
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", &CallToolParams{Name: name, Arguments: arguments}, &result); err != nil {
+		return nil, fmt.Errorf("mcp tools/call %s/%s: %w", c.name, name, err)
+	}
+	return &result, nil
+}
+
+// Alive reports whether the connection can still carry calls; a stdio server that exited is not alive
+func (c *Client) Alive() bool {
+	if checker, ok := c.transport.(interface{ Alive() bool }); ok {
+		return checker.Alive()
+	}
+	return true
+}
+
+// Ping checks the server is responsive
+func (c *Client) Ping(ctx context.Context) error {
+	return c.call(ctx, "ping", nil, nil)
+}
+
+// Close shuts the connection and any launched process
+func (c *Client) Close() error {
+	return c.transport.Close()
+}
+
+// call sends a request under the client's timeout and decodes the result
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := newRequest(c.nextID.Add(1), method, params)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.transport.Request(ctx, req)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
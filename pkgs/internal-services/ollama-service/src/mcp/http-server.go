@@ -0,0 +1,138 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/mcp/http-server.go
+
+package mcp
+
+import (
+	// stdlib
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+)
+
+// httpSession is a client session opened by initialize
+type httpSession struct {
+	lastSeen time.Time
+}
+
+// sessionIdleTimeout is how long an unused HTTP session is kept
+const sessionIdleTimeout = time.Hour
+
+// ServeHTTP implements the server side of MCP's Streamable HTTP transport.
+// Every response is a single JSON body; the server does not open SSE streams.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if version := r.Header.Get(versionHeader); version != "" && !isSupportedVersion(version) {
+		http.Error(w, fmt.Sprintf("unsupported protocol version: %s", version), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.handlePost(w, r)
+	case http.MethodDelete:
+		if !s.endSession(r.Header.Get(sessionHeader)) {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handlePost answers one JSON-RPC message
+func (s *Server) handlePost(w http.ResponseWriter, r *http.Request) {
+	var msg Message
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxHTTPMessage)).Decode(&msg); err != nil {
+		writeHTTPMessage(w, http.StatusBadRequest, NewError(json.RawMessage("null"), CodeParseError, "invalid JSON"))
+		return
+	}
+
+	if msg.Method == "initialize" {
+		sessionID, err := s.startSession()
+		if err != nil {
+			writeHTTPMessage(w, http.StatusInternalServerError, NewError(msg.ID, CodeInternalError, err.Error()))
+			return
+		}
+		w.Header().Set(sessionHeader, sessionID)
+	} else {
+		sessionID := r.Header.Get(sessionHeader)
+		if sessionID == "" {
+			http.Error(w, "missing "+sessionHeader+" header", http.StatusBadRequest)
+			return
+		}
+		if !s.touchSession(sessionID) {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	reply := s.Handle(r.Context(), &msg)
+	if reply == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	writeHTTPMessage(w, http.StatusOK, reply)
+}
+
+// startSession allocates a session ID and drops sessions that went idle
+func (s *Server) startSession() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	sessionID := hex.EncodeToString(buf)
+
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	now := time.Now()
+	for id, session := range s.sessions {
+		if now.Sub(session.lastSeen) > sessionIdleTimeout {
+			delete(s.sessions, id)
+		}
+	}
+	s.sessions[sessionID] = &httpSession{lastSeen: now}
+	return sessionID, nil
+}
+
+// touchSession marks a session as used, reporting whether it exists
+func (s *Server) touchSession(sessionID string) bool {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	session, ok := s.sessions[sessionID]
+	if !ok || time.Since(session.lastSeen) > sessionIdleTimeout {
+		delete(s.sessions, sessionID)
+		return false
+	}
+	session.lastSeen = time.Now()
+	return true
+}
+
+// endSession forgets a session, reporting whether it existed
+func (s *Server) endSession(sessionID string) bool {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	if _, ok := s.sessions[sessionID]; !ok {
+		return false
+	}
+	delete(s.sessions, sessionID)
+	return true
+}
+
+// writeHTTPMessage encodes a JSON-RPC message as the response body
+func writeHTTPMessage(w http.ResponseWriter, status int, msg *Message) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(msg); err != nil {
+		log.Error().Err(err).Msg("Failed to encode MCP response")
+	}
+}
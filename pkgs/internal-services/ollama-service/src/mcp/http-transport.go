@@ -0,0 +1,187 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/mcp/http-transport.go
+
+package mcp
+
+import (
+	// stdlib
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// HTTPTransport implements MCP's Streamable HTTP transport
+type HTTPTransport struct {
+	url             string
+	headers         map[string]string
+	client          *http.Client
+	mu              sync.Mutex
+	sessionID       string
+	protocolVersion string
+}
+
+const (
+	sessionHeader  = "Mcp-Session-Id"
+	versionHeader  = "MCP-Protocol-Version"
+	maxHTTPMessage = 16 * 1024 * 1024
+)
+
+// NewHTTPTransport creates a transport for a server endpoint
+func NewHTTPTransport(url string, headers map[string]string) (*HTTPTransport, error) {
+	if url == "" {
+		return nil, fmt.Errorf("mcp http transport: url is required")
+	}
+	return &HTTPTransport{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{},
+	}, nil
+}
+
+// SetProtocolVersion records the negotiated version sent on later requests
+func (t *HTTPTransport) SetProtocolVersion(version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.protocolVersion = version
+}
+
+// Request posts a request and reads its response from JSON or an SSE stream
+func (t *HTTPTransport) Request(ctx context.Context, msg *Message) (*Message, error) {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if sid := resp.Header.Get(sessionHeader); sid != "" {
+		t.mu.Lock()
+		t.sessionID = sid
+		t.mu.Unlock()
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("mcp http status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return t.readStream(resp.Body, msg.ID)
+	}
+
+	var reply Message
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxHTTPMessage)).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("failed to decode mcp response: %w", err)
+	}
+	return &reply, nil
+}
+
+// Notify posts a notification; servers answer 202 Accepted
+func (t *HTTPTransport) Notify(ctx context.Context, msg *Message) error {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("mcp http status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close ends the server-side session
+func (t *HTTPTransport) Close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(sessionHeader, sessionID)
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// post sends one JSON-RPC message with the session headers
+func (t *HTTPTransport) post(ctx context.Context, msg *Message) (*http.Response, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set(sessionHeader, t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set(versionHeader, t.protocolVersion)
+	}
+	t.mu.Unlock()
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("mcp http request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// readStream scans SSE events until the response for id arrives
+func (t *HTTPTransport) readStream(body io.Reader, id json.RawMessage) (*Message, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxHTTPMessage)
+
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data:") {
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+
+		// Blank line ends the event
+		var msg Message
+		err := json.Unmarshal([]byte(data.String()), &msg)
+		data.Reset()
+		if err != nil {
+			continue
+		}
+		if msg.IsResponse() && string(msg.ID) == string(id) {
+			return &msg, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("mcp stream: %w", err)
+	}
+	return nil, fmt.Errorf("mcp stream ended without a response")
+}
@@ -0,0 +1,307 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/mcp/server.go
+
+package mcp
+
+import (
+	// stdlib
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+
+	// internal
+	"ocs/src/utils"
+)
+
+// supportedVersions are the MCP revisions the server accepts, newest first
+var supportedVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// ToolHandler runs a tool call; a returned error is reported to the client as a tool error
+type ToolHandler func(ctx context.Context, arguments map[string]interface{}) (*CallToolResult, error)
+
+// Server answers MCP requests for a set of tools over stdio or Streamable HTTP
+type Server struct {
+	info         Implementation
+	instructions string
+
+	mu       sync.RWMutex
+	tools    []Tool
+	handlers map[string]ToolHandler
+
+	sessionsMu sync.Mutex
+	sessions   map[string]*httpSession
+}
+
+// NewServer creates a server with no tools
+func NewServer(name, version, instructions string) *Server {
+	return &Server{
+		info:         Implementation{Name: name, Version: version},
+		instructions: instructions,
+		handlers:     make(map[string]ToolHandler),
+		sessions:     make(map[string]*httpSession),
+	}
+}
+
+// AddTool registers a tool, replacing any earlier tool of the same name
+func (s *Server) AddTool(tool Tool, handler ToolHandler) {
+	if tool.InputSchema == nil {
+		tool.InputSchema = map[string]interface{}{"type": "object"}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.handlers[tool.Name]; exists {
+		for i := range s.tools {
+			if s.tools[i].Name == tool.Name {
+				s.tools[i] = tool
+			}
+		}
+	} else {
+		s.tools = append(s.tools, tool)
+	}
+	s.handlers[tool.Name] = handler
+}
+
+// Tools returns the registered tools in the order they were added
+func (s *Server) Tools() []Tool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Tool(nil), s.tools...)
+}
+
+// TextResult builds a successful result holding a single text item
+func TextResult(text string) *CallToolResult {
+	return &CallToolResult{Content: []Content{{Type: "text", Text: text}}}
+}
+
+// ErrorResult builds a tool error the model can read and react to
+func ErrorResult(message string) *CallToolResult {
+	return &CallToolResult{Content: []Content{{Type: "text", Text: message}}, IsError: true}
+}
+
+// Handle answers one message; notifications and responses get no reply
+func (s *Server) Handle(ctx context.Context, msg *Message) *Message {
+	if !msg.IsRequest() {
+		if msg.IsNotification() {
+			log.Debug().Str("method", msg.Method).Msg("MCP notification")
+		}
+		return nil
+	}
+
+	var result interface{}
+	var rpcErr *RPCError
+	switch msg.Method {
+	case "initialize":
+		result, rpcErr = s.initialize(msg.Params)
+	case "ping":
+		result = map[string]interface{}{}
+	case "tools/list":
+		result = &ListToolsResult{Tools: s.Tools()}
+	case "tools/call":
+		result, rpcErr = s.callTool(ctx, msg.Params)
+	default:
+		rpcErr = &RPCError{Code: CodeMethodNotFound, Message: fmt.Sprintf("method not supported: %s", msg.Method)}
+	}
+
+	if rpcErr != nil {
+		return &Message{JSONRPC: "2.0", ID: msg.ID, Error: rpcErr}
+	}
+	reply, err := NewResult(msg.ID, result)
+	if err != nil {
+		return NewError(msg.ID, CodeInternalError, err.Error())
+	}
+	return reply
+}
+
+// initialize agrees on a protocol version and describes the server
+func (s *Server) initialize(params json.RawMessage) (*InitializeResult, *RPCError) {
+	var req InitializeParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, &RPCError{Code: CodeInvalidParams, Message: fmt.Sprintf("invalid initialize params: %v", err)}
+		}
+	}
+
+	version := ProtocolVersion
+	if isSupportedVersion(req.ProtocolVersion) {
+		version = req.ProtocolVersion
+	}
+
+	log.Info().
+		Str("client", req.ClientInfo.Name).
+		Str("client_version", req.ClientInfo.Version).
+		Str("protocol_version", version).
+		Msg("MCP client connected")
+
+	return &InitializeResult{
+		ProtocolVersion: version,
+		Capabilities: map[string]interface{}{
+			"tools": map[string]interface{}{"listChanged": false},
+		},
+		ServerInfo:   s.info,
+		Instructions: s.instructions,
+	}, nil
+}
+
+// callTool validates the arguments against the tool's schema and runs its handler
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (*CallToolResult, *RPCError) {
+	var req CallToolParams
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, &RPCError{Code: CodeInvalidParams, Message: fmt.Sprintf("invalid tools/call params: %v", err)}
+	}
+
+	s.mu.RLock()
+	handler, ok := s.handlers[req.Name]
+	var schema map[string]interface{}
+	for _, tool := range s.tools {
+		if tool.Name == req.Name {
+			schema = tool.InputSchema
+		}
+	}
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, &RPCError{Code: CodeInvalidParams, Message: fmt.Sprintf("unknown tool: %s", req.Name)}
+	}
+
+	arguments := req.Arguments
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	if err := utils.ValidateJSONSchema(schema, arguments); err != nil {
+		return ErrorResult(fmt.Sprintf("invalid arguments: %v", err)), nil
+	}
+
+	result, err := handler(ctx, arguments)
+	if err != nil {
+		log.Warn().Err(err).Str("tool", req.Name).Msg("MCP tool call failed")
+		return ErrorResult(err.Error()), nil
+	}
+	if result == nil {
+		result = &CallToolResult{}
+	}
+	if result.Content == nil {
+		result.Content = []Content{}
+	}
+	return result, nil
+}
+
+// ServeStdio serves newline-delimited messages from r until it closes or ctx ends.
+// Requests run concurrently so a slow tool does not block pings or cancellations.
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var writeMu sync.Mutex
+	write := func(msg *Message) {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to marshal MCP reply")
+			return
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if _, err := w.Write(append(data, '\n')); err != nil {
+			log.Error().Err(err).Msg("Failed to write MCP reply")
+		}
+	}
+
+	var wg sync.WaitGroup
+	var inflightMu sync.Mutex
+	inflight := make(map[string]context.CancelFunc)
+	defer wg.Wait()
+
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxStdioMessageSize)
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	for {
+		var line []byte
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			return err
+		case line = <-lines:
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		var msg Message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			write(NewError(json.RawMessage("null"), CodeParseError, "invalid JSON"))
+			continue
+		}
+
+		if msg.Method == "notifications/cancelled" {
+			var params struct {
+				RequestID json.RawMessage `json:"requestId"`
+			}
+			json.Unmarshal(msg.Params, &params)
+			inflightMu.Lock()
+			if cancelRequest, ok := inflight[string(params.RequestID)]; ok {
+				cancelRequest()
+			}
+			inflightMu.Unlock()
+			continue
+		}
+		if !msg.IsRequest() {
+			s.Handle(ctx, &msg)
+			continue
+		}
+
+		key := string(msg.ID)
+		reqCtx, cancelRequest := context.WithCancel(ctx)
+		inflightMu.Lock()
+		inflight[key] = cancelRequest
+		inflightMu.Unlock()
+
+		wg.Add(1)
+		go func(msg *Message) {
+			defer wg.Done()
+			reply := s.Handle(reqCtx, msg)
+
+			inflightMu.Lock()
+			delete(inflight, key)
+			inflightMu.Unlock()
+			cancelled := reqCtx.Err() != nil
+			cancelRequest()
+
+			// Cancelled requests get no response
+			if !cancelled {
+				write(reply)
+			}
+		}(&msg)
+	}
+}
+
+// isSupportedVersion reports whether the server can speak the given revision
+func isSupportedVersion(version string) bool {
+	for _, supported := range supportedVersions {
+		if version == supported {
+			return true
+		}
+	}
+	return false
+}
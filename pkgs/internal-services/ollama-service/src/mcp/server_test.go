@@ -0,0 +1,229 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/mcp/server_test.go
+
+package mcp
+
+import (
+	// stdlib
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer serves an echo tool, a failing tool and one that blocks until cancelled
+func newTestServer() *Server {
+	server := NewServer("test", "0.1.0", "for tests")
+	server.AddTool(Tool{
+		Name: "echo",
+		InputSchema: map[string]interface{}{
+			"type":       "object",
+			"required":   []interface{}{"text"},
+			"properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}},
+		},
+	}, func(ctx context.Context, arguments map[string]interface{}) (*CallToolResult, error) {
+		return TextResult(fmt.Sprint(arguments["text"])), nil
+	})
+	server.AddTool(Tool{Name: "fail"}, func(ctx context.Context, arguments map[string]interface{}) (*CallToolResult, error) {
+		return nil, fmt.Errorf("failed on purpose")
+	})
+	server.AddTool(Tool{Name: "block"}, func(ctx context.Context, arguments map[string]interface{}) (*CallToolResult, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	return server
+}
+
+func TestServerOverHTTP(t *testing.T) {
+	httpServer := httptest.NewServer(newTestServer())
+	t.Cleanup(httpServer.Close)
+
+	client, err := Connect(context.Background(), ServerConfig{Name: "test", Transport: "http", URL: httpServer.URL})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer client.Close()
+
+	info := client.ServerInfo()
+	if info.ServerInfo.Name != "test" || info.ProtocolVersion != ProtocolVersion || info.Instructions != "for tests" {
+		t.Errorf("server info = %+v", info)
+	}
+
+	tools, err := client.ListTools(context.Background())
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(tools) != 3 || tools[0].Name != "echo" || tools[1].InputSchema["type"] != "object" {
+		t.Errorf("tools = %+v", tools)
+	}
+
+	result, err := client.CallTool(context.Background(), "echo", map[string]interface{}{"text": "hi"})
+	if err != nil || result.IsError || result.Content[0].Text != "hi" {
+		t.Errorf("echo = %+v, %v", result, err)
+	}
+
+	result, err = client.CallTool(context.Background(), "echo", map[string]interface{}{"text": 3})
+	if err != nil || !result.IsError || !strings.Contains(result.Content[0].Text, "invalid arguments") {
+		t.Errorf("echo with a bad argument = %+v, %v", result, err)
+	}
+
+	result, err = client.CallTool(context.Background(), "fail", nil)
+	if err != nil || !result.IsError || result.Content[0].Text != "failed on purpose" {
+		t.Errorf("fail = %+v, %v", result, err)
+	}
+
+	_, err = client.CallTool(context.Background(), "missing", nil)
+	if rpcErr, ok := err.(interface{ Unwrap() error }); !ok || rpcErr.Unwrap().(*RPCError).Code != CodeInvalidParams {
+		t.Errorf("unknown tool error = %v", err)
+	}
+
+	if err := client.Ping(context.Background()); err != nil {
+		t.Errorf("Ping: %v", err)
+	}
+}
+
+func TestServerHTTPSessions(t *testing.T) {
+	server := newTestServer()
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	post := func(sessionID, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, httpServer.URL, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if sessionID != "" {
+			req.Header.Set(sessionHeader, sessionID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	ping := `{"jsonrpc":"2.0","id":2,"method":"ping"}`
+	if resp := post("", ping); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("request without a session = %d, want 400", resp.StatusCode)
+	}
+	if resp := post("unknown", ping); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("request with an unknown session = %d, want 404", resp.StatusCode)
+	}
+
+	resp := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`)
+	sessionID := resp.Header.Get(sessionHeader)
+	if resp.StatusCode != http.StatusOK || sessionID == "" {
+		t.Fatalf("initialize = %d, session %q", resp.StatusCode, sessionID)
+	}
+	if resp := post(sessionID, `{"jsonrpc":"2.0","method":"notifications/initialized"}`); resp.StatusCode != http.StatusAccepted {
+		t.Errorf("notification = %d, want 202", resp.StatusCode)
+	}
+	if resp := post(sessionID, ping); resp.StatusCode != http.StatusOK {
+		t.Errorf("ping = %d, want 200", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, httpServer.URL, nil)
+	req.Header.Set(sessionHeader, sessionID)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete = %v, %v", resp, err)
+	}
+	if resp := post(sessionID, ping); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("request after delete = %d, want 404", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, httpServer.URL, nil)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET = %v, %v, want 405", resp, err)
+	}
+}
+
+func TestServerNegotiatesVersion(t *testing.T) {
+	server := newTestServer()
+	for requested, want := range map[string]string{
+		"2024-11-05": "2024-11-05",
+		"1999-01-01": ProtocolVersion,
+	} {
+		reply := server.Handle(context.Background(), &Message{
+			JSONRPC: "2.0",
+			ID:      json.RawMessage("1"),
+			Method:  "initialize",
+			Params:  json.RawMessage(fmt.Sprintf(`{"protocolVersion":%q}`, requested)),
+		})
+		var result InitializeResult
+		if err := json.Unmarshal(reply.Result, &result); err != nil {
+			t.Fatal(err)
+		}
+		if result.ProtocolVersion != want {
+			t.Errorf("requested %s, got %s, want %s", requested, result.ProtocolVersion, want)
+		}
+	}
+}
+
+func TestServeStdio(t *testing.T) {
+	server := newTestServer()
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() { done <- server.ServeStdio(context.Background(), serverIn, serverOut) }()
+
+	replies := bufio.NewScanner(clientIn)
+	read := func() *Message {
+		t.Helper()
+		if !replies.Scan() {
+			t.Fatalf("no reply: %v", replies.Err())
+		}
+		var msg Message
+		if err := json.Unmarshal(replies.Bytes(), &msg); err != nil {
+			t.Fatalf("bad reply %s: %v", replies.Bytes(), err)
+		}
+		return &msg
+	}
+	send := func(line string) {
+		t.Helper()
+		if _, err := io.WriteString(clientOut, line+"\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	send(`not json`)
+	if msg := read(); msg.Error == nil || msg.Error.Code != CodeParseError {
+		t.Errorf("reply to garbage = %+v", msg)
+	}
+
+	// A blocked call does not hold up later requests, and is dropped once cancelled
+	send(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"block"}}`)
+	send(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"text":"after"}}}`)
+	msg := read()
+	if string(msg.ID) != "2" {
+		t.Fatalf("first reply was for %s, want 2", msg.ID)
+	}
+	var result CallToolResult
+	json.Unmarshal(msg.Result, &result)
+	if result.Content[0].Text != "after" {
+		t.Errorf("echo = %+v", result)
+	}
+
+	send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1}}`)
+	send(`{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+	if msg := read(); string(msg.ID) != "3" {
+		t.Errorf("reply after cancelling = %+v, want only the ping", msg)
+	}
+
+	clientOut.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ServeStdio = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeStdio did not return after stdin closed")
+	}
+}
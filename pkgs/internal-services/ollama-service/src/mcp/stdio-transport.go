@@ -0,0 +1,229 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/mcp/stdio-transport.go
+
+package mcp
+
+import (
+	// stdlib
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+)
+
+// StdioTransport talks to an MCP server launched as a child process, one JSON message per line
+type StdioTransport struct {
+	name    string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[string]chan *Message
+	done    chan struct{}
+	err     error
+}
+
+const maxStdioMessageSize = 16 * 1024 * 1024
+
+// NewStdioTransport launches the server process
+func NewStdioTransport(name, command string, args []string, env map[string]string, dir string) (*StdioTransport, error) {
+	if command == "" {
+		return nil, fmt.Errorf("mcp server %s: command is required for stdio", name)
+	}
+
+	cmd := exec.Command(command, args...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("mcp server %s: stdin: %w", name, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("mcp server %s: stdout: %w", name, err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("mcp server %s: stderr: %w", name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("mcp server %s: start: %w", name, err)
+	}
+
+	t := &StdioTransport{
+		name:    name,
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[string]chan *Message),
+		done:    make(chan struct{}),
+	}
+	go t.readLoop(stdout)
+	go t.logStderr(stderr)
+
+	return t, nil
+}
+
+// Request writes a request and waits for its response
+func (t *StdioTransport) Request(ctx context.Context, msg *Message) (*Message, error) {
+	ch := make(chan *Message, 1)
+	key := string(msg.ID)
+
+	t.mu.Lock()
+	if t.err != nil {
+		t.mu.Unlock()
+		return nil, t.err
+	}
+	t.pending[key] = ch
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, key)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(msg); err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-t.done:
+		return nil, t.err
+	case <-ctx.Done():
+		// Tell the server to stop working on it
+		t.cancelRequest(msg.ID, ctx.Err())
+		return nil, ctx.Err()
+	}
+}
+
+// Notify writes a notification
+func (t *StdioTransport) Notify(ctx context.Context, msg *Message) error {
+	return t.write(msg)
+}
+
+// Alive reports whether the server process is still connected
+func (t *StdioTransport) Alive() bool {
+	select {
+	case <-t.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Close ends stdin and stops the process if it does not exit promptly
+func (t *StdioTransport) Close() error {
+	t.stdin.Close()
+
+	exited := make(chan struct{})
+	go func() {
+		t.cmd.Wait()
+		close(exited)
+	}()
+
+	select {
+	case <-exited:
+	case <-time.After(2 * time.Second):
+		t.cmd.Process.Kill()
+		<-exited
+	}
+	return nil
+}
+
+// write serializes a message onto stdin
+func (t *StdioTransport) write(msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("mcp server %s: write: %w", t.name, err)
+	}
+	return nil
+}
+
+// readLoop routes responses to waiting requests and answers server-initiated requests
+func (t *StdioTransport) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxStdioMessageSize)
+
+	for scanner.Scan() {
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			log.Warn().Err(err).Str("server", t.name).Msg("Ignoring malformed MCP message")
+			continue
+		}
+
+		switch {
+		case msg.IsResponse():
+			t.mu.Lock()
+			ch, ok := t.pending[string(msg.ID)]
+			t.mu.Unlock()
+			if ok {
+				ch <- &msg
+			}
+		case msg.IsRequest():
+			t.answerServerRequest(&msg)
+		case msg.IsNotification():
+			log.Debug().Str("server", t.name).Str("method", msg.Method).Msg("MCP notification")
+		}
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = errors.New("server closed the connection")
+	}
+
+	t.mu.Lock()
+	t.err = fmt.Errorf("mcp server %s: %w", t.name, err)
+	t.mu.Unlock()
+	close(t.done)
+}
+
+// answerServerRequest replies to ping and rejects capabilities OCS does not offer
+func (t *StdioTransport) answerServerRequest(msg *Message) {
+	var reply *Message
+	if msg.Method == "ping" {
+		reply, _ = NewResult(msg.ID, map[string]interface{}{})
+	} else {
+		reply = NewError(msg.ID, CodeMethodNotFound, fmt.Sprintf("method not supported: %s", msg.Method))
+	}
+	if err := t.write(reply); err != nil {
+		log.Warn().Err(err).Str("server", t.name).Msg("Failed to answer MCP server request")
+	}
+}
+
+// cancelRequest notifies the server that a request was abandoned
+func (t *StdioTransport) cancelRequest(id json.RawMessage, reason error) {
+	params, _ := json.Marshal(map[string]interface{}{"requestId": id, "reason": reason.Error()})
+	msg := newNotification("notifications/cancelled")
+	msg.Params = params
+	t.write(msg)
+}
+
+// logStderr forwards the server's log output
+func (t *StdioTransport) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		log.Debug().Str("server", t.name).Msg(scanner.Text())
+	}
+}
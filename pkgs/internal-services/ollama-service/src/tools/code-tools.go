@@ -8,11 +8,11 @@ import (
 	// stdlib
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
-	"time"
 
 	// third-party
 	"github.com/rs/zerolog/log"
@@ -26,6 +26,7 @@ import (
 type CodeTool struct {
 	diskManager    *managers.DiskManager
 	sessionManager *managers.SessionManager
+	sandbox        *Sandbox
 }
 
 // NewCodeTool creates a new code tool
@@ -33,6 +34,7 @@ func NewCodeTool(diskManager *managers.DiskManager, sessionManager *managers.Ses
 	return &CodeTool{
 		diskManager:    diskManager,
 		sessionManager: sessionManager,
+		sandbox:        NewSandbox(DefaultSandboxConfig()),
 	}
 }
 
@@ -53,7 +55,42 @@ func (ct *CodeTool) Execute(ctx context.Context, toolCall *types.ToolCall) (*typ
 	}
 }
 
-// executeCode runs code in the specified language
+// ToolSpecs describes the calls CodeTool handles
+func (ct *CodeTool) ToolSpecs() []*ToolSpec {
+	languages := []string{"python", "javascript", "js", "go"}
+	return []*ToolSpec{
+		{
+			Name:        "execute_code",
+			Description: "Run code in an isolated sandbox and return exit code, stdout and stderr",
+			Scope:       ScopeExecute,
+			Parameters: objectSchema(map[string]interface{}{
+				"code":     stringProperty("Source code to run"),
+				"language": stringProperty("Programming language", languages...),
+				"stdin":    stringProperty("Optional standard input"),
+			}, "code", "language"),
+		},
+		{
+			Name:        "format_code",
+			Description: "Format source code with the language's standard formatter",
+			Scope:       ScopeTools,
+			Parameters: objectSchema(map[string]interface{}{
+				"code":     stringProperty("Source code to format"),
+				"language": stringProperty("Programming language", languages...),
+			}, "code", "language"),
+		},
+		{
+			Name:        "validate_code",
+			Description: "Check source code for syntax errors without running it",
+			Scope:       ScopeTools,
+			Parameters: objectSchema(map[string]interface{}{
+				"code":     stringProperty("Source code to validate"),
+				"language": stringProperty("Programming language", languages...),
+			}, "code", "language"),
+		},
+	}
+}
+
+// executeCode runs code in the session's sandbox and returns a structured result
 func (ct *CodeTool) executeCode(ctx context.Context, toolCall *types.ToolCall) (*types.ToolResult, error) {
 	code, ok := toolCall.Arguments["code"].(string)
 	if !ok || code == "" {
@@ -70,54 +107,41 @@ func (ct *CodeTool) executeCode(ctx context.Context, toolCall *types.ToolCall) (
 		}, nil
 	}
 	sessionID, _ := toolCall.Arguments["session_id"].(string)
+	stdin, _ := toolCall.Arguments["stdin"].(string)
 
-	// Save code to temporary file
-	filePath := ct.diskManager.getSessionFilePath(sessionID) + "_code_tmp"
-	if err := ct.diskManager.WriteFile(ctx, filePath, code); err != nil {
+	result, err := ct.sandbox.Run(ctx, &SandboxRequest{
+		SessionID: sessionID,
+		Language:  language,
+		Code:      code,
+		Stdin:     stdin,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("session_id", sessionID).Msg("Sandboxed execution failed")
 		return &types.ToolResult{
 			Success: false,
-			Error:   fmt.Sprintf("failed to save code: %v", err),
+			Error:   fmt.Sprintf("failed to execute code: %v", err),
 		}, nil
 	}
-	defer os.Remove(filePath)
 
-	var cmd *exec.Cmd
-	switch strings.ToLower(language) {
-	case "python":
-		cmd = exec.CommandContext(ctx, "python", filePath)
-	case "javascript", "js":
-		cmd = exec.CommandContext(ctx, "node", filePath)
-	case "go":
-		cmd = exec.CommandContext(ctx, "go", "run", filePath)
-	default:
+	content, err := json.Marshal(result)
+	if err != nil {
 		return &types.ToolResult{
 			Success: false,
-			Error:   fmt.Sprintf("unsupported language: %s", language),
+			Error:   fmt.Sprintf("failed to encode result: %v", err),
 		}, nil
 	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	start := time.Now()
-
-	err := cmd.Run()
-	output := stdout.String()
-	if err != nil {
-		output += stderr.String()
+	toolResult := &types.ToolResult{
+		Success: result.ExitCode == 0 && !result.TimedOut,
+		Content: string(content),
+		Data:    result,
 	}
-
-	log.Info().
-		Str("session_id", sessionID).
-		Str("language", language).
-		Dur("duration", time.Since(start)).
-		Msg("Executed code")
-
-	return &types.ToolResult{
-		Success: err == nil,
-		Content: output,
-		Error:   err.Error(),
-	}, nil
+	if result.TimedOut {
+		toolResult.Error = "execution timed out"
+	} else if result.ExitCode != 0 {
+		toolResult.Error = fmt.Sprintf("exit code %d", result.ExitCode)
+	}
+	return toolResult, nil
 }
 
 // formatCode formats code using language-specific tools
@@ -176,7 +200,7 @@ func (ct *CodeTool) formatCode(ctx context.Context, toolCall *types.ToolCall) (*
 	}
 
 	// Save formatted code
-	filePath := ct.diskManager.getSessionFilePath(sessionID) + "_formatted.txt"
+	filePath := ct.diskManager.SessionFilePath(sessionID) + "_formatted.txt"
 	if err := ct.diskManager.WriteFile(ctx, filePath, formatted); err != nil {
 		log.Warn().Err(err).Str("session_id", sessionID).Msg("Failed to save formatted code")
 	}
@@ -211,13 +235,17 @@ func (ct *CodeTool) validateCode(ctx context.Context, toolCall *types.ToolCall)
 	sessionID, _ := toolCall.Arguments["session_id"].(string)
 
 	var cmd *exec.Cmd
+	var extension string
 	switch strings.ToLower(language) {
 	case "python":
 		cmd = exec.CommandContext(ctx, "python", "-m", "py_compile")
+		extension = ".py"
 	case "javascript", "js":
 		cmd = exec.CommandContext(ctx, "node", "--check")
+		extension = ".js"
 	case "go":
 		cmd = exec.CommandContext(ctx, "go", "vet")
+		extension = ".go"
 	default:
 		return &types.ToolResult{
 			Success: false,
@@ -225,8 +253,8 @@ func (ct *CodeTool) validateCode(ctx context.Context, toolCall *types.ToolCall)
 		}, nil
 	}
 
-	// Save code to temporary file
-	filePath := ct.diskManager.getSessionFilePath(sessionID) + "_validate_tmp"
+	// Save code to temporary file; go vet only accepts files named *.go
+	filePath := ct.diskManager.SessionFilePath(sessionID) + "_validate_tmp" + extension
 	if err := ct.diskManager.WriteFile(ctx, filePath, code); err != nil {
 		return &types.ToolResult{
 			Success: false,
@@ -246,13 +274,13 @@ func (ct *CodeTool) validateCode(ctx context.Context, toolCall *types.ToolCall)
 		Msg("Validated code")
 
 	if err != nil {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   fmt.Sprintf("validation failed: %v, %s", err, stderr.String()),
 		}, nil
 	}
 
-	return &managers.ToolResult{
+	return &types.ToolResult{
 		Success: true,
 		Content: "Code is syntactically valid",
 	}, nil
@@ -42,37 +42,81 @@ func (ft *FileTool) Execute(ctx context.Context, toolCall *types.ToolCall) (*typ
 	case "list_files":
 		return ft.listFiles(ctx, toolCall)
 	default:
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   fmt.Sprintf("unknown tool call: %s", toolCall.Name),
 		}, nil
 	}
 }
 
+// ToolSpecs describes the calls FileTool handles
+func (ft *FileTool) ToolSpecs() []*ToolSpec {
+	return []*ToolSpec{
+		{
+			Name:        "read_file",
+			Description: "Read a file from the session workspace",
+			Scope:       ScopeTools,
+			Parameters: objectSchema(map[string]interface{}{
+				"path": stringProperty("Path relative to the workspace root"),
+			}, "path"),
+		},
+		{
+			Name:        "write_file",
+			Description: "Write a file in the session workspace",
+			Scope:       ScopeFiles,
+			Parameters: objectSchema(map[string]interface{}{
+				"path":    stringProperty("Path relative to the workspace root"),
+				"content": stringProperty("File content"),
+				"mode":    stringProperty("Write mode", "overwrite", "append"),
+			}, "path", "content"),
+		},
+		{
+			Name:        "delete_file",
+			Description: "Delete a file from the session workspace",
+			Scope:       ScopeFiles,
+			Parameters: objectSchema(map[string]interface{}{
+				"path":      stringProperty("Path relative to the workspace root"),
+				"recursive": boolProperty("Delete a directory and its contents"),
+			}, "path"),
+		},
+		{
+			Name:        "list_files",
+			Description: "List files in a session workspace directory",
+			Scope:       ScopeTools,
+			Parameters: objectSchema(map[string]interface{}{
+				"path":      stringProperty("Directory relative to the workspace root; use . for the root"),
+				"recursive": boolProperty("Include subdirectories"),
+			}, "path"),
+		},
+	}
+}
+
 // readFile reads a file's contents
 func (ft *FileTool) readFile(ctx context.Context, toolCall *types.ToolCall) (*types.ToolResult, error) {
 	path, ok := toolCall.Arguments["path"].(string)
 	if !ok || path == "" {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   "missing or invalid path argument",
 		}, nil
 	}
-	sessionID, _ := toolCall.Arguments["session_id"].(string)
+	sessionID, userID := scopedIDs(toolCall)
 
 	fileOp := &managers.FileOperation{
 		Operation: "read",
+		SessionID: sessionID,
+		UserID:    userID,
 		Path:      path,
 	}
 	result, err := ft.diskManager.HandleFileOperation(ctx, fileOp)
 	if err != nil {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   fmt.Sprintf("failed to read file: %v", err),
 		}, nil
 	}
 	if !result.Success {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   result.Error,
 		}, nil
@@ -83,7 +127,7 @@ func (ft *FileTool) readFile(ctx context.Context, toolCall *types.ToolCall) (*ty
 		Str("path", path).
 		Msg("Read file")
 
-	return &managers.ToolResult{
+	return &types.ToolResult{
 		Success: true,
 		Content: result.Content,
 	}, nil
@@ -93,34 +137,38 @@ func (ft *FileTool) readFile(ctx context.Context, toolCall *types.ToolCall) (*ty
 func (ft *FileTool) writeFile(ctx context.Context, toolCall *types.ToolCall) (*types.ToolResult, error) {
 	path, ok := toolCall.Arguments["path"].(string)
 	if !ok || path == "" {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   "missing or invalid path argument",
 		}, nil
 	}
 	content, ok := toolCall.Arguments["content"].(string)
 	if !ok {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   "missing or invalid content argument",
 		}, nil
 	}
-	sessionID, _ := toolCall.Arguments["session_id"].(string)
+	mode, _ := toolCall.Arguments["mode"].(string)
+	sessionID, userID := scopedIDs(toolCall)
 
 	fileOp := &managers.FileOperation{
 		Operation: "write",
+		SessionID: sessionID,
+		UserID:    userID,
 		Path:      path,
 		Content:   content,
+		Mode:      mode,
 	}
 	result, err := ft.diskManager.HandleFileOperation(ctx, fileOp)
 	if err != nil {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   fmt.Sprintf("failed to write file: %v", err),
 		}, nil
 	}
 	if !result.Success {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   result.Error,
 		}, nil
@@ -131,7 +179,7 @@ func (ft *FileTool) writeFile(ctx context.Context, toolCall *types.ToolCall) (*t
 		Str("path", path).
 		Msg("Wrote file")
 
-	return &managers.ToolResult{
+	return &types.ToolResult{
 		Success: true,
 		Content: "File written successfully",
 	}, nil
@@ -141,26 +189,30 @@ func (ft *FileTool) writeFile(ctx context.Context, toolCall *types.ToolCall) (*t
 func (ft *FileTool) deleteFile(ctx context.Context, toolCall *types.ToolCall) (*types.ToolResult, error) {
 	path, ok := toolCall.Arguments["path"].(string)
 	if !ok || path == "" {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   "missing or invalid path argument",
 		}, nil
 	}
-	sessionID, _ := toolCall.Arguments["session_id"].(string)
+	recursive, _ := toolCall.Arguments["recursive"].(bool)
+	sessionID, userID := scopedIDs(toolCall)
 
 	fileOp := &managers.FileOperation{
 		Operation: "delete",
+		SessionID: sessionID,
+		UserID:    userID,
 		Path:      path,
+		Recursive: recursive,
 	}
 	result, err := ft.diskManager.HandleFileOperation(ctx, fileOp)
 	if err != nil {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   fmt.Sprintf("failed to delete file: %v", err),
 		}, nil
 	}
 	if !result.Success {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   result.Error,
 		}, nil
@@ -171,7 +223,7 @@ func (ft *FileTool) deleteFile(ctx context.Context, toolCall *types.ToolCall) (*
 		Str("path", path).
 		Msg("Deleted file")
 
-	return &managers.ToolResult{
+	return &types.ToolResult{
 		Success: true,
 		Content: "File deleted successfully",
 	}, nil
@@ -181,28 +233,30 @@ func (ft *FileTool) deleteFile(ctx context.Context, toolCall *types.ToolCall) (*
 func (ft *FileTool) listFiles(ctx context.Context, toolCall *types.ToolCall) (*types.ToolResult, error) {
 	path, ok := toolCall.Arguments["path"].(string)
 	if !ok || path == "" {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   "missing or invalid path argument",
 		}, nil
 	}
 	recursive, _ := toolCall.Arguments["recursive"].(bool)
-	sessionID, _ := toolCall.Arguments["session_id"].(string)
+	sessionID, userID := scopedIDs(toolCall)
 
 	fileOp := &managers.FileOperation{
 		Operation: "list",
+		SessionID: sessionID,
+		UserID:    userID,
 		Path:      path,
 		Recursive: recursive,
 	}
 	result, err := ft.diskManager.HandleFileOperation(ctx, fileOp)
 	if err != nil {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   fmt.Sprintf("failed to list files: %v", err),
 		}, nil
 	}
 	if !result.Success {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   result.Error,
 		}, nil
@@ -219,8 +273,16 @@ func (ft *FileTool) listFiles(ctx context.Context, toolCall *types.ToolCall) (*t
 		Str("path", path).
 		Msg("Listed files")
 
-	return &managers.ToolResult{
+	return &types.ToolResult{
 		Success: true,
 		Content: content,
+		Data:    result.Files,
 	}, nil
 }
+
+// scopedIDs returns the session and user ToolRegistry.Execute bound the call to
+func scopedIDs(toolCall *types.ToolCall) (sessionID, userID string) {
+	sessionID, _ = toolCall.Arguments["session_id"].(string)
+	userID, _ = toolCall.Arguments["user_id"].(string)
+	return sessionID, userID
+}
@@ -0,0 +1,227 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/tools/mcp-server.go
+
+package tools
+
+import (
+	// stdlib
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	// internal
+	"ocs/managers"
+	"ocs/src/mcp"
+	"ocs/src/types"
+)
+
+// mcpServerInstructions tells connecting agents what OCS offers
+const mcpServerInstructions = "OCS exposes the caller's long-term memories, session history and local model inference. " +
+	"Searches and session listings only ever see the authenticated user's data."
+
+// NewMCPServer exposes OCS memory search, session history and inference as MCP tools.
+// Handlers act as the user and session the JWT middleware put on the request context.
+func NewMCPServer(searchTool *SearchTool, sessionManager *managers.SessionManager, inferenceManager *managers.InferenceManager) *mcp.Server {
+	server := mcp.NewServer("ocs", "1.0.0", mcpServerInstructions)
+	ms := &mcpServerTools{
+		searchTool:       searchTool,
+		sessionManager:   sessionManager,
+		inferenceManager: inferenceManager,
+	}
+
+	server.AddTool(mcp.Tool{
+		Name:        "search_memory",
+		Title:       "Search memory",
+		Description: "Search the user's long-term memories",
+		InputSchema: objectSchema(map[string]interface{}{
+			"query": stringProperty("What to look for"),
+		}, "query"),
+	}, ms.searchMemory)
+
+	server.AddTool(mcp.Tool{
+		Name:        "search_conversation",
+		Title:       "Search conversation",
+		Description: "Search the messages of one of the user's sessions, by default the authenticated one",
+		InputSchema: objectSchema(map[string]interface{}{
+			"query":      stringProperty("Text to look for"),
+			"session_id": stringProperty("Session to search; defaults to the caller's session"),
+		}, "query"),
+	}, ms.searchConversation)
+
+	server.AddTool(mcp.Tool{
+		Name:        "list_sessions",
+		Title:       "List sessions",
+		Description: "List the user's chat sessions",
+		InputSchema: objectSchema(map[string]interface{}{
+			"active_only": boolProperty("Only return sessions that are still active"),
+		}),
+	}, ms.listSessions)
+
+	server.AddTool(mcp.Tool{
+		Name:        "generate",
+		Title:       "Generate",
+		Description: "Run a prompt through an OCS model",
+		InputSchema: objectSchema(map[string]interface{}{
+			"prompt":         stringProperty("Prompt for the model"),
+			"system":         stringProperty("Optional system prompt"),
+			"model":          stringProperty("Model to use; OCS picks one for the inference type when omitted"),
+			"inference_type": stringProperty("Kind of request, used to pick a model", "chat", "code", "reasoning"),
+			"temperature":    numberProperty("Sampling temperature", 0, 2),
+			"max_tokens":     integerProperty("Maximum tokens to generate", 1),
+		}, "prompt"),
+	}, ms.generate)
+
+	return server
+}
+
+// mcpServerTools holds the handlers behind NewMCPServer
+type mcpServerTools struct {
+	searchTool       *SearchTool
+	sessionManager   *managers.SessionManager
+	inferenceManager *managers.InferenceManager
+}
+
+// searchMemory searches the caller's memories
+func (ms *mcpServerTools) searchMemory(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	userID, sessionID, err := mcpCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ms.searchTool.searchMemory(ctx, &types.ToolCall{
+		Name: "search_memory",
+		Arguments: map[string]interface{}{
+			"query":      arguments["query"],
+			"user_id":    userID,
+			"session_id": sessionID,
+		},
+	})
+	return mcpToolResult(result, err)
+}
+
+// searchConversation searches a session the caller owns
+func (ms *mcpServerTools) searchConversation(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	userID, sessionID, err := mcpCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if requested, _ := arguments["session_id"].(string); requested != "" {
+		sessionID = requested
+	}
+
+	// Another user's session is reported as missing rather than forbidden
+	session, exists := ms.sessionManager.GetSession(sessionID)
+	if !exists || session.UserID != userID {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	result, err := ms.searchTool.searchConversation(ctx, &types.ToolCall{
+		Name: "search_conversation",
+		Arguments: map[string]interface{}{
+			"query":      arguments["query"],
+			"session_id": sessionID,
+		},
+	})
+	return mcpToolResult(result, err)
+}
+
+// listSessions lists the caller's sessions
+func (ms *mcpServerTools) listSessions(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	userID, _, err := mcpCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	activeOnly, _ := arguments["active_only"].(bool)
+
+	sessions := make([]interface{}, 0)
+	var lines []string
+	for _, session := range ms.sessionManager.GetUserSessions(userID) {
+		if activeOnly && !session.IsActive {
+			continue
+		}
+		sessions = append(sessions, map[string]interface{}{
+			"id":            session.ID,
+			"title":         session.Title,
+			"model_name":    session.ModelName,
+			"message_count": session.MessageCount,
+			"created_at":    session.CreatedAt.Format(time.RFC3339),
+			"last_activity": session.LastActivity.Format(time.RFC3339),
+			"is_active":     session.IsActive,
+		})
+		lines = append(lines, fmt.Sprintf("Session ID: %s, Title: %s, Messages: %d, Last activity: %s",
+			session.ID, session.Title, session.MessageCount, session.LastActivity.Format(time.RFC3339)))
+	}
+
+	result := mcp.TextResult(strings.Join(lines, "\n"))
+	result.StructuredContent = map[string]interface{}{"sessions": sessions}
+	return result, nil
+}
+
+// generate runs a single-turn inference as the caller
+func (ms *mcpServerTools) generate(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResult, error) {
+	userID, sessionID, err := mcpCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	inferenceType := managers.InferenceTypeChat
+	if requested, _ := arguments["inference_type"].(string); requested != "" {
+		inferenceType = managers.InferenceType(requested)
+	}
+
+	parameters := &managers.InferenceParameters{}
+	parameters.SystemPrompt, _ = arguments["system"].(string)
+	if temperature, ok := arguments["temperature"].(float64); ok {
+		parameters.Temperature = temperature
+	}
+	if maxTokens, ok := arguments["max_tokens"].(float64); ok {
+		parameters.MaxTokens = int(maxTokens)
+	}
+
+	prompt, _ := arguments["prompt"].(string)
+	modelName, _ := arguments["model"].(string)
+	req := &managers.InferenceRequest{
+		ID:          fmt.Sprintf("mcp_%s_%s_%d", inferenceType, userID, time.Now().UnixNano()),
+		UserID:      userID,
+		SessionID:   sessionID,
+		ModelName:   modelName,
+		RequestType: inferenceType,
+		Messages:    []managers.Message{{Role: "user", Content: prompt}},
+		Parameters:  parameters,
+	}
+
+	inference, err := ms.inferenceManager.ProcessInference(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("inference failed: %w", err)
+	}
+
+	result := mcp.TextResult(inference.Content)
+	result.StructuredContent = map[string]interface{}{
+		"model_used":    inference.ModelUsed,
+		"finish_reason": inference.FinishReason,
+		"usage":         inference.Usage,
+	}
+	return result, nil
+}
+
+// mcpCaller returns the user and session the authentication middleware attached to ctx
+func mcpCaller(ctx context.Context) (userID, sessionID string, err error) {
+	caller, ok := managers.CallerFromContext(ctx)
+	if !ok || caller.SessionID == "" {
+		return "", "", fmt.Errorf("unauthenticated MCP request")
+	}
+	return caller.UserID, caller.SessionID, nil
+}
+
+// mcpToolResult converts a tool outcome to MCP form
+func mcpToolResult(result *types.ToolResult, err error) (*mcp.CallToolResult, error) {
+	if err != nil {
+		return nil, err
+	}
+	if !result.Success {
+		return mcp.ErrorResult(result.Error), nil
+	}
+	return mcp.TextResult(result.Content), nil
+}
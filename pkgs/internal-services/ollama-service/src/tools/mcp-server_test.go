@@ -0,0 +1,102 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/tools/mcp-server_test.go
+
+package tools
+
+import (
+	// stdlib
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	// internal
+	"ocs/managers"
+	"ocs/src/mcp"
+)
+
+// connectAs serves the OCS MCP server over HTTP with the caller identity the JWT middleware would set
+func connectAs(t *testing.T, server *mcp.Server, userID, sessionID string) *mcp.Client {
+	t.Helper()
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if userID != "" {
+			ctx = managers.WithCaller(ctx, &managers.Caller{UserID: userID, SessionID: sessionID, Permissions: managers.DefaultPermissions})
+		}
+		server.ServeHTTP(w, r.WithContext(ctx))
+	}))
+	t.Cleanup(httpServer.Close)
+
+	client, err := mcp.Connect(context.Background(), mcp.ServerConfig{Name: "ocs", Transport: "http", URL: httpServer.URL})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func callText(t *testing.T, client *mcp.Client, name string, arguments map[string]interface{}) (string, bool) {
+	t.Helper()
+	result, err := client.CallTool(context.Background(), name, arguments)
+	if err != nil {
+		t.Fatalf("CallTool(%s): %v", name, err)
+	}
+	return result.Content[0].Text, result.IsError
+}
+
+func TestMCPServerScopesToCaller(t *testing.T) {
+	sessionManager := managers.NewSessionManager(managers.GetConfigManager(), nil, nil)
+	t.Cleanup(func() { sessionManager.Shutdown(context.Background()) })
+
+	alice, err := sessionManager.CreateSession(context.Background(), "alice", "llama3.2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := sessionManager.CreateSession(context.Background(), "bob", "llama3.2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionManager.AddMessage(alice.ID, "user", "the deploy key rotates on Fridays", nil)
+	sessionManager.AddMessage(bob.ID, "user", "bob's deploy notes", nil)
+
+	server := NewMCPServer(NewSearchTool(nil, sessionManager), sessionManager, nil)
+	client := connectAs(t, server, "alice", alice.ID)
+
+	tools, err := client.ListTools(context.Background())
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+	if got := strings.Join(names, ","); got != "search_memory,search_conversation,list_sessions,generate" {
+		t.Errorf("tools = %s", got)
+	}
+
+	text, isError := callText(t, client, "search_conversation", map[string]interface{}{"query": "deploy"})
+	if isError || !strings.Contains(text, "Fridays") || strings.Contains(text, "bob") {
+		t.Errorf("search own session = %q (error %v)", text, isError)
+	}
+
+	text, isError = callText(t, client, "search_conversation", map[string]interface{}{"query": "deploy", "session_id": bob.ID})
+	if !isError || !strings.Contains(text, "session not found") {
+		t.Errorf("search another user's session = %q (error %v)", text, isError)
+	}
+
+	result, err := client.CallTool(context.Background(), "list_sessions", nil)
+	if err != nil {
+		t.Fatalf("list_sessions: %v", err)
+	}
+	sessions := result.StructuredContent["sessions"].([]interface{})
+	if len(sessions) != 1 || sessions[0].(map[string]interface{})["id"] != alice.ID {
+		t.Errorf("list_sessions = %v", sessions)
+	}
+
+	anonymous := connectAs(t, server, "", "")
+	if text, isError := callText(t, anonymous, "list_sessions", nil); !isError || !strings.Contains(text, "unauthenticated") {
+		t.Errorf("unauthenticated list_sessions = %q (error %v)", text, isError)
+	}
+}
@@ -0,0 +1,274 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/tools/mcp-tools.go
+
+package tools
+
+import (
+	// stdlib
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+
+	// internal
+	"ocs/managers"
+	"ocs/src/mcp"
+	"ocs/src/types"
+)
+
+// mcpToolSeparator joins the server and tool names of an imported tool
+const mcpToolSeparator = "__"
+
+// MCPTool proxies calls to tools served by external MCP servers
+type MCPTool struct {
+	mu      sync.RWMutex
+	clients map[string]*mcp.Client
+	configs map[string]mcp.ServerConfig
+	specs   []*ToolSpec
+	remote  map[string]mcpRemoteTool
+}
+
+// mcpRemoteTool locates an imported tool on its server
+type mcpRemoteTool struct {
+	server string
+	name   string
+}
+
+// NewMCPTool connects to the servers in configs/mcp.yaml and imports their tools.
+// ${VAR} references in the config are expanded from the environment.
+// Servers that fail to start are logged and skipped.
+func NewMCPTool(ctx context.Context, cfgMgr *managers.ConfigManager) (*MCPTool, error) {
+	mt := &MCPTool{
+		clients: make(map[string]*mcp.Client),
+		configs: make(map[string]mcp.ServerConfig),
+		remote:  make(map[string]mcpRemoteTool),
+	}
+
+	var config mcp.Config
+	if err := cfgMgr.LoadConfig("configs/mcp.yaml", &config); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return mt, nil
+		}
+		return nil, err
+	}
+
+	for _, server := range config.Servers {
+		if server.Disabled {
+			continue
+		}
+		if server.Name == "" || strings.Contains(server.Name, mcpToolSeparator) {
+			return nil, fmt.Errorf("invalid MCP server name: %q", server.Name)
+		}
+		if err := mt.mount(ctx, expandServerConfig(server)); err != nil {
+			log.Warn().Err(err).Str("server", server.Name).Msg("Failed to mount MCP server")
+		}
+	}
+
+	return mt, nil
+}
+
+// mount connects to one server and imports its tools
+func (mt *MCPTool) mount(ctx context.Context, server mcp.ServerConfig) error {
+	client, err := mcp.Connect(ctx, server)
+	if err != nil {
+		return err
+	}
+
+	remoteTools, err := client.ListTools(ctx)
+	if err != nil {
+		client.Close()
+		return err
+	}
+
+	scope := server.Scope
+	if scope == "" {
+		scope = ScopeExecute
+	}
+
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	mt.clients[server.Name] = client
+	mt.configs[server.Name] = server
+	for _, tool := range remoteTools {
+		name := server.Name + mcpToolSeparator + tool.Name
+		description := tool.Description
+		if description == "" {
+			description = tool.Title
+		}
+		parameters := tool.InputSchema
+		if parameters == nil {
+			parameters = map[string]interface{}{"type": "object"}
+		}
+
+		mt.specs = append(mt.specs, &ToolSpec{
+			Name:        name,
+			Description: description,
+			Parameters:  parameters,
+			Scope:       scope,
+		})
+		mt.remote[name] = mcpRemoteTool{server: server.Name, name: tool.Name}
+	}
+
+	log.Info().
+		Str("server", server.Name).
+		Int("tools", len(remoteTools)).
+		Msg("Mounted MCP server")
+	return nil
+}
+
+// ToolSpecs describes the imported tools
+func (mt *MCPTool) ToolSpecs() []*ToolSpec {
+	mt.mu.RLock()
+	defer mt.mu.RUnlock()
+	return append([]*ToolSpec(nil), mt.specs...)
+}
+
+// Execute forwards a tool call to the server that owns it. A stdio server that has
+// exited is restarted first, and a call it died during is retried once on a new one.
+func (mt *MCPTool) Execute(ctx context.Context, toolCall *types.ToolCall) (*types.ToolResult, error) {
+	mt.mu.RLock()
+	remote, ok := mt.remote[toolCall.Name]
+	client := mt.clients[remote.server]
+	mt.mu.RUnlock()
+
+	if !ok {
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("unknown tool call: %s", toolCall.Name),
+		}, nil
+	}
+
+	// Session scoping stays inside OCS
+	args := make(map[string]interface{}, len(toolCall.Arguments))
+	for k, v := range toolCall.Arguments {
+		if !scopedArguments[k] {
+			args[k] = v
+		}
+	}
+
+	var result *mcp.CallToolResult
+	var err error
+	if client == nil || !client.Alive() {
+		client, err = mt.restart(ctx, remote.server, client)
+	}
+	if err == nil {
+		result, err = client.CallTool(ctx, remote.name, args)
+		if err != nil && !client.Alive() && ctx.Err() == nil {
+			log.Warn().Err(err).Str("tool", toolCall.Name).Msg("MCP server exited during a call, retrying")
+			if client, err = mt.restart(ctx, remote.server, client); err == nil {
+				result, err = client.CallTool(ctx, remote.name, args)
+			}
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Str("tool", toolCall.Name).Msg("MCP tool call failed")
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("mcp call failed: %v", err),
+		}, nil
+	}
+
+	content := mcpContentText(result.Content)
+	if result.IsError {
+		return &types.ToolResult{
+			Success: false,
+			Error:   "tool reported an error",
+			Content: content,
+		}, nil
+	}
+
+	toolResult := &types.ToolResult{
+		Success: true,
+		Content: content,
+	}
+	if result.StructuredContent != nil {
+		toolResult.Data = result.StructuredContent
+	}
+	return toolResult, nil
+}
+
+// restart replaces a dead client with a fresh connection, unless another call already did
+func (mt *MCPTool) restart(ctx context.Context, server string, dead *mcp.Client) (*mcp.Client, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	if current := mt.clients[server]; current != nil && current != dead {
+		return current, nil
+	}
+	if dead != nil {
+		dead.Close()
+	}
+	delete(mt.clients, server)
+
+	client, err := mcp.Connect(ctx, mt.configs[server])
+	if err != nil {
+		return nil, err
+	}
+	mt.clients[server] = client
+	log.Info().Str("server", server).Msg("Restarted MCP server")
+	return client, nil
+}
+
+// Close disconnects from every server
+func (mt *MCPTool) Close() error {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	for name, client := range mt.clients {
+		if err := client.Close(); err != nil {
+			log.Warn().Err(err).Str("server", name).Msg("Failed to close MCP server")
+		}
+	}
+	mt.clients = make(map[string]*mcp.Client)
+	return nil
+}
+
+// expandServerConfig substitutes environment variables into a server's launch and connection settings
+func expandServerConfig(server mcp.ServerConfig) mcp.ServerConfig {
+	server.Command = os.ExpandEnv(server.Command)
+	server.Dir = os.ExpandEnv(server.Dir)
+	server.URL = os.ExpandEnv(server.URL)
+
+	args := make([]string, len(server.Args))
+	for i, arg := range server.Args {
+		args[i] = os.ExpandEnv(arg)
+	}
+	server.Args = args
+
+	env := make(map[string]string, len(server.Env))
+	for k, v := range server.Env {
+		env[k] = os.ExpandEnv(v)
+	}
+	server.Env = env
+
+	headers := make(map[string]string, len(server.Headers))
+	for k, v := range server.Headers {
+		headers[k] = os.ExpandEnv(v)
+	}
+	server.Headers = headers
+
+	return server
+}
+
+// mcpContentText flattens tool output into text for the model
+func mcpContentText(content []mcp.Content) string {
+	parts := make([]string, 0, len(content))
+	for _, item := range content {
+		switch item.Type {
+		case "text":
+			parts = append(parts, item.Text)
+		case "resource_link":
+			parts = append(parts, fmt.Sprintf("[resource %s %s]", item.Name, item.URI))
+		default:
+			parts = append(parts, fmt.Sprintf("[%s %s]", item.Type, item.MimeType))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,286 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/tools/mcp-tools_test.go
+
+package tools
+
+import (
+	// stdlib
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	// internal
+	"ocs/managers"
+	"ocs/src/mcp"
+	"ocs/src/types"
+)
+
+// TestMain doubles as the MCP server the tests mount: launched with
+// OCS_MCP_TEST_SERVER=1 the test binary serves MCP on stdio instead of running tests
+func TestMain(m *testing.M) {
+	if os.Getenv("OCS_MCP_TEST_SERVER") == "1" {
+		serveTestMCP()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// serveTestMCP is a tiny stdio MCP server exposing tools that report on and end its own
+// process. It answers JSON-RPC by hand so the client is tested against a peer that shares
+// none of its code.
+func serveTestMCP() {
+	encoder := json.NewEncoder(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var msg mcp.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil || !msg.IsRequest() {
+			continue
+		}
+
+		var result interface{}
+		switch msg.Method {
+		case "initialize":
+			result = &mcp.InitializeResult{
+				ProtocolVersion: mcp.ProtocolVersion,
+				Capabilities:    map[string]interface{}{"tools": map[string]interface{}{}},
+				ServerInfo:      mcp.Implementation{Name: "ocs-test", Version: "1.0.0"},
+			}
+		case "ping":
+			result = map[string]interface{}{}
+		case "tools/list":
+			result = &mcp.ListToolsResult{Tools: testMCPTools}
+		case "tools/call":
+			var params mcp.CallToolParams
+			json.Unmarshal(msg.Params, &params)
+			result = callTestMCPTool(params.Name, params.Arguments)
+		default:
+			encoder.Encode(mcp.NewError(msg.ID, mcp.CodeMethodNotFound, "method not supported: "+msg.Method))
+			continue
+		}
+		reply, _ := mcp.NewResult(msg.ID, result)
+		encoder.Encode(reply)
+	}
+}
+
+// testMCPTools are the tools serveTestMCP lists
+var testMCPTools = []mcp.Tool{
+	{
+		Name:        "echo",
+		Description: "Echo the arguments with the server's greeting",
+		InputSchema: map[string]interface{}{
+			"type":       "object",
+			"required":   []interface{}{"text"},
+			"properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}},
+		},
+	},
+	{Title: "Fail", Name: "fail"},
+	{Name: "quit", InputSchema: map[string]interface{}{"type": "object"}},
+	{Name: "crash", InputSchema: map[string]interface{}{"type": "object"}},
+}
+
+// callTestMCPTool runs one of testMCPTools
+func callTestMCPTool(name string, arguments map[string]interface{}) *mcp.CallToolResult {
+	text := func(text string, isError bool) *mcp.CallToolResult {
+		return &mcp.CallToolResult{Content: []mcp.Content{{Type: "text", Text: text}}, IsError: isError}
+	}
+
+	switch name {
+	case "echo":
+		value, ok := arguments["text"].(string)
+		if !ok {
+			return text("invalid arguments: text must be a string", true)
+		}
+		result := text(fmt.Sprintf("%s %s", os.Getenv("MCP_GREETING"), value), false)
+		result.StructuredContent = map[string]interface{}{
+			"arguments": arguments,
+			"args":      os.Args[1:],
+			"pid":       os.Getpid(),
+		}
+		return result
+	case "fail":
+		return text("failed on purpose", true)
+	case "quit":
+		// Answers, then the process exits as if it had crashed between calls
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			os.Exit(1)
+		}()
+		return text("bye", false)
+	case "crash":
+		// Exits without answering the first time it is called in a test
+		marker := os.Getenv("MCP_CRASH_MARKER")
+		if _, err := os.Stat(marker); os.IsNotExist(err) {
+			os.WriteFile(marker, nil, 0644)
+			os.Exit(2)
+		}
+		return text(fmt.Sprintf("survived in %d", os.Getpid()), false)
+	}
+	return text("unknown tool: "+name, true)
+}
+
+// testMCPConfig is a configs/mcp.yaml launching this test binary, with its command,
+// arguments and environment all taken from variables
+const testMCPConfig = `servers:
+  - name: local
+    transport: stdio
+    command: ${OCS_TEST_MCP_BINARY}
+    args: ["--greeting=${OCS_TEST_GREETING}"]
+    env:
+      OCS_MCP_TEST_SERVER: "1"
+      MCP_GREETING: ${OCS_TEST_GREETING}
+      MCP_CRASH_MARKER: ${OCS_TEST_CRASH_MARKER}
+    timeout: 5
+    scope: tools
+  - name: disabled
+    command: /nonexistent
+    disabled: true
+`
+
+// newTestMCPTool mounts the test server through configs/mcp.yaml
+func newTestMCPTool(t *testing.T) *MCPTool {
+	t.Helper()
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	t.Setenv("OCS_TEST_MCP_BINARY", binary)
+	t.Setenv("OCS_TEST_GREETING", "hello")
+	t.Setenv("OCS_TEST_CRASH_MARKER", filepath.Join(dir, "crashed"))
+
+	if err := os.MkdirAll(filepath.Join(dir, "configs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "configs", "mcp.yaml"), []byte(testMCPConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	mt, err := NewMCPTool(context.Background(), managers.GetConfigManager())
+	if err != nil {
+		t.Fatalf("NewMCPTool: %v", err)
+	}
+	t.Cleanup(func() { mt.Close() })
+	return mt
+}
+
+func execute(t *testing.T, mt *MCPTool, name string, arguments map[string]interface{}) *types.ToolResult {
+	t.Helper()
+	result, err := mt.Execute(context.Background(), &types.ToolCall{Name: name, Arguments: arguments})
+	if err != nil {
+		t.Fatalf("Execute(%s): %v", name, err)
+	}
+	return result
+}
+
+// serverPID is the process that answered an echo call
+func serverPID(t *testing.T, mt *MCPTool) float64 {
+	t.Helper()
+	result := execute(t, mt, "local__echo", map[string]interface{}{"text": "pid"})
+	if !result.Success {
+		t.Fatalf("echo failed: %s", result.Error)
+	}
+	return result.Data.(map[string]interface{})["pid"].(float64)
+}
+
+func TestMCPToolImportsServerTools(t *testing.T) {
+	mt := newTestMCPTool(t)
+
+	specs := map[string]*ToolSpec{}
+	for _, spec := range mt.ToolSpecs() {
+		specs[spec.Name] = spec
+	}
+	if len(specs) != 4 {
+		t.Fatalf("imported %d tools, want 4: %v", len(specs), specs)
+	}
+	echo, ok := specs["local__echo"]
+	if !ok {
+		t.Fatalf("local__echo not imported: %v", specs)
+	}
+	if echo.Scope != managers.ToolScopeTools || echo.Description == "" || echo.Parameters["required"] == nil {
+		t.Errorf("echo spec = %+v", echo)
+	}
+	if fail := specs["local__fail"]; fail.Description != "Fail" || fail.Parameters["type"] != "object" {
+		t.Errorf("fail spec = %+v, want the title as description and an object schema", fail)
+	}
+
+	info := mt.clients["local"].ServerInfo()
+	if info.ServerInfo.Name != "ocs-test" || info.ProtocolVersion != mcp.ProtocolVersion {
+		t.Errorf("server info = %+v", info)
+	}
+}
+
+func TestMCPToolExecute(t *testing.T) {
+	mt := newTestMCPTool(t)
+
+	result := execute(t, mt, "local__echo", map[string]interface{}{"text": "world", "user_id": "alice", "session_id": "s1"})
+	if !result.Success || result.Content != "hello world" {
+		t.Fatalf("echo = %+v", result)
+	}
+
+	data := result.Data.(map[string]interface{})
+	arguments := data["arguments"].(map[string]interface{})
+	if _, leaked := arguments["user_id"]; leaked || arguments["session_id"] != nil {
+		t.Errorf("session scoping reached the MCP server: %v", arguments)
+	}
+	if args := data["args"].([]interface{}); len(args) != 1 || args[0] != "--greeting=hello" {
+		t.Errorf("server args = %v, want the greeting expanded", args)
+	}
+
+	result = execute(t, mt, "local__fail", nil)
+	if result.Success || result.Content != "failed on purpose" {
+		t.Errorf("fail = %+v", result)
+	}
+
+	result = execute(t, mt, "local__echo", map[string]interface{}{"text": 42})
+	if result.Success || !strings.Contains(result.Content, "invalid arguments") {
+		t.Errorf("echo with a bad argument = %+v", result)
+	}
+
+	if result := execute(t, mt, "local__missing", nil); result.Success {
+		t.Errorf("unknown tool = %+v", result)
+	}
+}
+
+func TestMCPToolRestartsDeadServer(t *testing.T) {
+	mt := newTestMCPTool(t)
+	first := serverPID(t, mt)
+
+	// The server exits after answering; the next call starts a new one
+	if result := execute(t, mt, "local__quit", nil); !result.Success {
+		t.Fatalf("quit = %+v", result)
+	}
+	dead := mt.clients["local"]
+	deadline := time.Now().Add(5 * time.Second)
+	for dead.Alive() {
+		if time.Now().After(deadline) {
+			t.Fatal("server did not exit")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	second := serverPID(t, mt)
+	if second == first {
+		t.Fatalf("call after the server exited went to pid %v again", second)
+	}
+	if mt.clients["local"] == dead {
+		t.Error("dead client was not replaced")
+	}
+
+	// The server dies during a call; the call is retried once on a fresh server
+	result := execute(t, mt, "local__crash", nil)
+	if !result.Success || !strings.HasPrefix(result.Content, "survived in ") {
+		t.Fatalf("crash = %+v, want a retry on a restarted server", result)
+	}
+	if third := serverPID(t, mt); third == second {
+		t.Errorf("server was not restarted after crashing mid-call")
+	}
+}
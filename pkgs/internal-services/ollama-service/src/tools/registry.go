@@ -0,0 +1,262 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/tools/registry.go
+
+package tools
+
+import (
+	// stdlib
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+
+	// internal
+	"ocs/managers"
+	"ocs/src/types"
+	"ocs/src/utils"
+)
+
+// Permission scopes a caller must hold to run a tool
+const (
+	ScopeTools   = managers.ToolScopeTools
+	ScopeFiles   = managers.ToolScopeFiles
+	ScopeExecute = managers.ToolScopeExecute
+)
+
+// scopedArguments are set by the server to bind a call to the caller's session and user
+var scopedArguments = map[string]bool{"session_id": true, "user_id": true}
+
+// ErrToolPermission is returned when the caller is missing or lacks a tool's scope
+var ErrToolPermission = errors.New("tool permission denied")
+
+// internalPermissions are held by calls the server makes on a session's behalf; which
+// tools a model may call is decided by the inference tool policy
+var internalPermissions = []string{ScopeTools, ScopeFiles, ScopeExecute}
+
+// ToolSpec describes a registered tool
+type ToolSpec struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"` // JSON Schema for the arguments
+	Scope       string                 `json:"scope"`
+}
+
+// ToolRegistry is the single catalogue of tools used by the APIs and the model tool loop
+type ToolRegistry struct {
+	mu             sync.RWMutex
+	sessionManager *managers.SessionManager
+	specs          map[string]*ToolSpec
+	executors      map[string]toolExecutor
+}
+
+// toolExecutor is implemented by every tool in this package
+type toolExecutor interface {
+	Execute(ctx context.Context, toolCall *types.ToolCall) (*types.ToolResult, error)
+}
+
+// specProvider is a tool that describes the calls it handles
+type specProvider interface {
+	toolExecutor
+	ToolSpecs() []*ToolSpec
+}
+
+// NewToolRegistry creates an empty registry
+func NewToolRegistry(sessionManager *managers.SessionManager) *ToolRegistry {
+	return &ToolRegistry{
+		sessionManager: sessionManager,
+		specs:          make(map[string]*ToolSpec),
+		executors:      make(map[string]toolExecutor),
+	}
+}
+
+// Register adds every call the given tools handle
+func (tr *ToolRegistry) Register(tools ...specProvider) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	for _, tool := range tools {
+		for _, spec := range tool.ToolSpecs() {
+			if spec.Name == "" {
+				return fmt.Errorf("tool spec without a name")
+			}
+			if _, exists := tr.specs[spec.Name]; exists {
+				return fmt.Errorf("tool already registered: %s", spec.Name)
+			}
+			tr.specs[spec.Name] = spec
+			tr.executors[spec.Name] = tool
+		}
+	}
+	return nil
+}
+
+// List returns the registered tools sorted by name
+func (tr *ToolRegistry) List() []*ToolSpec {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	specs := make([]*ToolSpec, 0, len(tr.specs))
+	for _, spec := range tr.specs {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// Get returns a tool's spec
+func (tr *ToolRegistry) Get(name string) (*ToolSpec, bool) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	spec, ok := tr.specs[name]
+	return spec, ok
+}
+
+// Authorize checks that the granted permissions cover a tool's scope
+func (tr *ToolRegistry) Authorize(name string, permissions []string) error {
+	spec, ok := tr.Get(name)
+	if !ok {
+		return fmt.Errorf("unknown tool: %s", name)
+	}
+	for _, permission := range permissions {
+		if permission == spec.Scope {
+			return nil
+		}
+	}
+	return fmt.Errorf("tool %s requires %q permission", name, spec.Scope)
+}
+
+// Execute checks the caller on ctx holds the tool's scope, validates the arguments
+// against the tool's schema and dispatches the call bound to the caller's session and user
+func (tr *ToolRegistry) Execute(ctx context.Context, toolCall *types.ToolCall) (*types.ToolResult, error) {
+	caller, ok := managers.CallerFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%w: unauthenticated call to %s", ErrToolPermission, toolCall.Name)
+	}
+
+	tr.mu.RLock()
+	spec, ok := tr.specs[toolCall.Name]
+	executor := tr.executors[toolCall.Name]
+	tr.mu.RUnlock()
+
+	if !ok {
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("unknown tool: %s", toolCall.Name),
+		}, nil
+	}
+
+	if !caller.Can(spec.Scope) {
+		log.Warn().Str("tool", toolCall.Name).Str("user_id", caller.UserID).Str("scope", spec.Scope).Msg("Refused tool call without permission")
+		return nil, fmt.Errorf("%w: tool %s requires %q permission", ErrToolPermission, toolCall.Name, spec.Scope)
+	}
+
+	// Scoping arguments come from the caller, never from the request or the schema
+	args := make(map[string]interface{}, len(toolCall.Arguments)+2)
+	for k, v := range toolCall.Arguments {
+		if !scopedArguments[k] {
+			args[k] = v
+		}
+	}
+	if err := utils.ValidateJSONSchema(spec.Parameters, args); err != nil {
+		log.Warn().Err(err).Str("tool", toolCall.Name).Msg("Rejected tool call arguments")
+		return &types.ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("invalid arguments: %v", err),
+		}, nil
+	}
+	args["session_id"] = caller.SessionID
+	args["user_id"] = caller.UserID
+
+	return executor.Execute(ctx, &types.ToolCall{Name: toolCall.Name, Arguments: args})
+}
+
+// ToolDefinitions implements managers.ToolProvider
+func (tr *ToolRegistry) ToolDefinitions() []managers.ToolDefinition {
+	specs := tr.List()
+	defs := make([]managers.ToolDefinition, 0, len(specs))
+	for _, spec := range specs {
+		defs = append(defs, managers.ToolDefinition{
+			Type: "function",
+			Function: &managers.FunctionDefinition{
+				Name:        spec.Name,
+				Description: spec.Description,
+				Parameters:  spec.Parameters,
+			},
+			Scope: spec.Scope,
+		})
+	}
+	return defs
+}
+
+// CallTool implements managers.ToolProvider by flattening ExecuteInSession's result into
+// the text fed back to the model
+func (tr *ToolRegistry) CallTool(ctx context.Context, sessionID string, call *managers.FunctionCall) (string, error) {
+	result, err := tr.ExecuteInSession(ctx, sessionID, &types.ToolCall{Name: call.Name, Arguments: call.Arguments})
+	if err != nil {
+		return "", err
+	}
+	if !result.Success {
+		if result.Content != "" {
+			return "", fmt.Errorf("%s: %s", result.Error, result.Content)
+		}
+		return "", errors.New(result.Error)
+	}
+	return result.Content, nil
+}
+
+// ExecuteInSession implements managers.SessionTools; the call runs in the session as its
+// user, with the permissions of the authenticated caller on ctx when there is one
+func (tr *ToolRegistry) ExecuteInSession(ctx context.Context, sessionID string, call *types.ToolCall) (*types.ToolResult, error) {
+	session, exists := tr.sessionManager.GetSession(sessionID)
+	if !exists {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	permissions := internalPermissions
+	if caller, ok := managers.CallerFromContext(ctx); ok {
+		if caller.UserID != session.UserID {
+			return nil, fmt.Errorf("%w: session %s belongs to another user", ErrToolPermission, sessionID)
+		}
+		permissions = caller.Permissions
+	}
+	ctx = managers.WithCaller(ctx, &managers.Caller{UserID: session.UserID, SessionID: sessionID, Permissions: permissions})
+	return tr.Execute(ctx, call)
+}
+
+// objectSchema builds a closed argument schema
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// stringProperty describes a string argument
+func stringProperty(description string, enum ...string) map[string]interface{} {
+	prop := map[string]interface{}{"type": "string", "description": description}
+	if len(enum) > 0 {
+		prop["enum"] = enum
+	}
+	return prop
+}
+
+// boolProperty describes a boolean argument
+func boolProperty(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "boolean", "description": description}
+}
+
+// numberProperty describes a numeric argument within [minimum, maximum]
+func numberProperty(description string, minimum, maximum float64) map[string]interface{} {
+	return map[string]interface{}{"type": "number", "description": description, "minimum": minimum, "maximum": maximum}
+}
+
+// integerProperty describes an integer argument of at least minimum
+func integerProperty(description string, minimum int) map[string]interface{} {
+	return map[string]interface{}{"type": "integer", "description": description, "minimum": minimum}
+}
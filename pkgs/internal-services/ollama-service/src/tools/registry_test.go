@@ -0,0 +1,85 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/tools/registry_test.go
+
+package tools
+
+import (
+	// stdlib
+	"context"
+	"errors"
+	"testing"
+
+	// internal
+	"ocs/managers"
+	"ocs/src/types"
+)
+
+// scopedTool handles one call per scope and records the arguments it ran with
+type scopedTool struct {
+	calls []map[string]interface{}
+}
+
+func (st *scopedTool) ToolSpecs() []*ToolSpec {
+	schema := objectSchema(map[string]interface{}{"path": stringProperty("Path")})
+	return []*ToolSpec{
+		{Name: "read", Scope: ScopeTools, Parameters: schema},
+		{Name: "write", Scope: ScopeFiles, Parameters: schema},
+		{Name: "run", Scope: ScopeExecute, Parameters: schema},
+	}
+}
+
+func (st *scopedTool) Execute(ctx context.Context, toolCall *types.ToolCall) (*types.ToolResult, error) {
+	st.calls = append(st.calls, toolCall.Arguments)
+	return &types.ToolResult{Success: true, Content: toolCall.Name}, nil
+}
+
+func TestRegistryPermissions(t *testing.T) {
+	sessionManager := managers.NewSessionManager(managers.GetConfigManager(), nil, nil)
+	t.Cleanup(func() { sessionManager.Shutdown(context.Background()) })
+	session, err := sessionManager.CreateSession(context.Background(), "alice", "llama3.2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tool := &scopedTool{}
+	registry := NewToolRegistry(sessionManager)
+	if err := registry.Register(tool); err != nil {
+		t.Fatal(err)
+	}
+	asAlice := func(permissions ...string) context.Context {
+		return managers.WithCaller(context.Background(), &managers.Caller{UserID: "alice", SessionID: session.ID, Permissions: permissions})
+	}
+	call := func(name string) *types.ToolCall {
+		return &types.ToolCall{Name: name, Arguments: map[string]interface{}{"path": "a.txt"}}
+	}
+
+	// Every transport goes through Execute, which refuses anonymous and unscoped callers
+	if _, err := registry.Execute(context.Background(), call("read")); !errors.Is(err, ErrToolPermission) {
+		t.Errorf("anonymous read: %v", err)
+	}
+	if _, err := registry.Execute(asAlice(managers.DefaultPermissions...), call("write")); !errors.Is(err, ErrToolPermission) {
+		t.Errorf("write with default permissions: %v", err)
+	}
+	if result, err := registry.Execute(asAlice(ScopeTools, ScopeFiles), call("write")); err != nil || !result.Success {
+		t.Errorf("write with files permission = %+v, %v", result, err)
+	}
+	if len(tool.calls) != 1 {
+		t.Fatalf("executed %d calls, want 1", len(tool.calls))
+	}
+
+	// Model tool calls run as the session's user with the caller's permissions
+	if _, err := registry.CallTool(asAlice(ScopeTools), session.ID, &managers.FunctionCall{Name: "run", Arguments: map[string]interface{}{"path": "x"}}); !errors.Is(err, ErrToolPermission) {
+		t.Errorf("model run for a caller without execute: %v", err)
+	}
+	bob := managers.WithCaller(context.Background(), &managers.Caller{UserID: "bob", SessionID: "other", Permissions: internalPermissions})
+	if _, err := registry.CallTool(bob, session.ID, &managers.FunctionCall{Name: "read", Arguments: map[string]interface{}{"path": "x"}}); !errors.Is(err, ErrToolPermission) {
+		t.Errorf("bob calling into alice's session: %v", err)
+	}
+	if content, err := registry.CallTool(context.Background(), session.ID, &managers.FunctionCall{Name: "run", Arguments: map[string]interface{}{"path": "x", "user_id": "bob"}}); err != nil || content != "run" {
+		t.Errorf("server-side run = %q, %v", content, err)
+	}
+	if last := tool.calls[len(tool.calls)-1]; last["user_id"] != "alice" || last["session_id"] != session.ID {
+		t.Errorf("model call ran with %v", last)
+	}
+}
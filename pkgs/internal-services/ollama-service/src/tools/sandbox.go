@@ -0,0 +1,409 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/tools/sandbox.go
+
+package tools
+
+import (
+	// stdlib
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	// third-party
+	"github.com/rs/zerolog/log"
+)
+
+// Sandbox runs untrusted code in an isolated, resource-limited child process. The child
+// gets a private root with read-only system directories, no network unless allowed, and
+// a seccomp filter; it runs under bubblewrap when installed and under the service's own
+// namespace helper otherwise.
+type Sandbox struct {
+	config    *SandboxConfig
+	bwrapPath string
+}
+
+// SandboxConfig holds isolation and resource limits
+type SandboxConfig struct {
+	BaseDir        string        `json:"base_dir"` // per-session scratch dirs live here
+	CPUSeconds     int           `json:"cpu_seconds"`
+	MemoryMB       int           `json:"memory_mb"`
+	MaxFileSizeMB  int           `json:"max_file_size_mb"`
+	MaxProcesses   int           `json:"max_processes"`
+	MaxOpenFiles   int           `json:"max_open_files"`
+	WallTime       time.Duration `json:"wall_time"`
+	MaxOutputBytes int           `json:"max_output_bytes"`
+	AllowNetwork   bool          `json:"allow_network"`
+	CgroupParent   string        `json:"cgroup_parent"` // delegated cgroup v2 dir; memory falls back to RLIMIT_DATA without it
+}
+
+// SandboxRequest describes a single execution
+type SandboxRequest struct {
+	SessionID string
+	Language  string
+	Code      string
+	Stdin     string
+}
+
+// SandboxResult is the structured outcome of an execution
+type SandboxResult struct {
+	ExitCode        int            `json:"exit_code"`
+	Stdout          string         `json:"stdout"`
+	Stderr          string         `json:"stderr"`
+	StdoutTruncated bool           `json:"stdout_truncated"`
+	StderrTruncated bool           `json:"stderr_truncated"`
+	TimedOut        bool           `json:"timed_out"`
+	Isolation       string         `json:"isolation"`    // bwrap or namespaces
+	MemoryLimit     string         `json:"memory_limit"` // cgroup or rlimit
+	OOMKilled       bool           `json:"oom_killed"`
+	Usage           *ResourceUsage `json:"usage"`
+}
+
+// ResourceUsage reports what the child consumed
+type ResourceUsage struct {
+	WallTime   time.Duration `json:"wall_time"`
+	UserTime   time.Duration `json:"user_time"`
+	SystemTime time.Duration `json:"system_time"`
+	MaxRSSKB   int64         `json:"max_rss_kb"`
+}
+
+// sandboxLanguage maps a language to its source file and interpreter command
+type sandboxLanguage struct {
+	fileName string
+	command  []string
+}
+
+var sandboxLanguages = map[string]sandboxLanguage{
+	"python":     {fileName: "main.py", command: []string{"python3", "main.py"}},
+	"javascript": {fileName: "main.js", command: []string{"node", "main.js"}},
+	"js":         {fileName: "main.js", command: []string{"node", "main.js"}},
+	"go":         {fileName: "main.go", command: []string{"go", "run", "main.go"}},
+}
+
+var sessionDirPattern = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// maxSessionDirName bounds the readable part of a scratch dir name
+const maxSessionDirName = 64
+
+// sandboxReadOnlyPaths are host paths exposed read-only inside bwrap
+var sandboxReadOnlyPaths = []string{"/usr", "/bin", "/sbin", "/lib", "/lib64", "/etc/alternatives", "/etc/ssl", "/usr/local/go"}
+
+// DefaultSandboxConfig returns conservative limits
+func DefaultSandboxConfig() *SandboxConfig {
+	return &SandboxConfig{
+		BaseDir:        "./data/sandbox",
+		CPUSeconds:     10,
+		MemoryMB:       512,
+		MaxFileSizeMB:  16,
+		MaxProcesses:   64,
+		MaxOpenFiles:   256,
+		WallTime:       30 * time.Second,
+		MaxOutputBytes: 64 * 1024,
+		AllowNetwork:   false,
+	}
+}
+
+// NewSandbox creates a sandbox runner; bubblewrap is used when installed
+func NewSandbox(config *SandboxConfig) *Sandbox {
+	if config == nil {
+		config = DefaultSandboxConfig()
+	}
+	sb := &Sandbox{config: config}
+	if path, err := exec.LookPath("bwrap"); err == nil {
+		sb.bwrapPath = path
+	}
+	return sb
+}
+
+// Run executes code in the session's scratch directory under the configured limits
+func (sb *Sandbox) Run(ctx context.Context, req *SandboxRequest) (*SandboxResult, error) {
+	language, ok := sandboxLanguages[strings.ToLower(req.Language)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language: %s", req.Language)
+	}
+
+	workDir, err := sb.sessionDir(req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	sourcePath := filepath.Join(workDir, language.fileName)
+	if err := os.WriteFile(sourcePath, []byte(req.Code), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write source: %w", err)
+	}
+	defer os.Remove(sourcePath)
+
+	ctx, cancel := context.WithTimeout(ctx, sb.config.WallTime)
+	defer cancel()
+
+	run, err := sb.prepare(ctx, workDir, language.command)
+	if err != nil {
+		return nil, err
+	}
+	defer run.release()
+	cmd := run.cmd
+
+	stdout := newLimitedBuffer(sb.config.MaxOutputBytes)
+	stderr := newLimitedBuffer(sb.config.MaxOutputBytes)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Stdin = strings.NewReader(req.Stdin)
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	if err := run.start(); err != nil {
+		return nil, fmt.Errorf("failed to start sandbox: %w", err)
+	}
+	runErr := cmd.Wait()
+	result := &SandboxResult{
+		Stdout:          stdout.String(),
+		Stderr:          stderr.String(),
+		StdoutTruncated: stdout.truncated,
+		StderrTruncated: stderr.truncated,
+		TimedOut:        errors.Is(ctx.Err(), context.DeadlineExceeded),
+		Isolation:       run.isolation,
+		MemoryLimit:     run.memoryLimit(),
+		OOMKilled:       run.oomKilled(),
+		Usage:           &ResourceUsage{WallTime: time.Since(start)},
+	}
+
+	if state := cmd.ProcessState; state != nil {
+		result.ExitCode = state.ExitCode()
+		result.Usage.UserTime = state.UserTime()
+		result.Usage.SystemTime = state.SystemTime()
+		result.Usage.MaxRSSKB = maxRSSKB(state)
+	} else if runErr != nil {
+		return nil, fmt.Errorf("failed to run sandbox: %w", runErr)
+	}
+
+	log.Info().
+		Str("session_id", req.SessionID).
+		Str("language", req.Language).
+		Str("isolation", run.isolation).
+		Int("exit_code", result.ExitCode).
+		Bool("timed_out", result.TimedOut).
+		Dur("duration", result.Usage.WallTime).
+		Msg("Sandboxed execution finished")
+
+	return result, nil
+}
+
+// sessionDir returns the per-session scratch directory, creating it if needed
+func (sb *Sandbox) sessionDir(sessionID string) (string, error) {
+	dir, err := filepath.Abs(filepath.Join(sb.config.BaseDir, sessionDirName(sessionID)))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve scratch dir: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create scratch dir: %w", err)
+	}
+	return dir, nil
+}
+
+// sessionDirName maps a session ID to its scratch dir name. Short IDs of safe characters
+// are used as they are; any other ID is sanitised and suffixed with "." and a hash of the
+// original. No unchanged ID contains a ".", so two sessions never share a dir.
+func sessionDirName(sessionID string) string {
+	name := sessionDirPattern.ReplaceAllString(sessionID, "_")
+	if name == sessionID && name != "" && len(name) <= maxSessionDirName {
+		return name
+	}
+	if name == "" {
+		name = "anonymous"
+	}
+	if len(name) > maxSessionDirName {
+		name = name[:maxSessionDirName]
+	}
+	sum := sha256.Sum256([]byte(sessionID))
+	return name + "." + hex.EncodeToString(sum[:8])
+}
+
+// sandboxEnv is the interpreter's whole environment; /work is the session's scratch dir
+var sandboxEnv = []string{
+	"PATH=/usr/local/go/bin:/usr/local/bin:/usr/bin:/bin",
+	"HOME=/work",
+	"TMPDIR=/tmp",
+	"GOCACHE=/work/.cache/go-build",
+	"GOPATH=/work/.cache/go",
+	"GOFLAGS=-mod=mod",
+	"PYTHONDONTWRITEBYTECODE=1",
+}
+
+// sandboxRun is a prepared child process and the resources held for it
+type sandboxRun struct {
+	cmd       *exec.Cmd
+	isolation string
+	cgroupDir string   // per-run cgroup; empty when memory is capped by RLIMIT_DATA
+	status    *os.File // setup errors from the namespace helper; EOF once it execs
+	closers   []func()
+}
+
+// start launches the child and waits for its isolation to be in place
+func (run *sandboxRun) start() error {
+	if err := run.cmd.Start(); err != nil {
+		return err
+	}
+	if run.status == nil {
+		return nil
+	}
+
+	// The helper's end of the pipe closes when it execs, or carries why it could not
+	run.closeChildFiles()
+	message, _ := io.ReadAll(run.status)
+	if len(message) == 0 {
+		return nil
+	}
+	killProcessGroup(run.cmd)
+	run.cmd.Wait()
+	return errors.New(strings.TrimSpace(string(message)))
+}
+
+// closeChildFiles releases the parent's copies of files handed to the child
+func (run *sandboxRun) closeChildFiles() {
+	for _, file := range run.cmd.ExtraFiles {
+		file.Close()
+	}
+}
+
+// memoryLimit names the mechanism capping the child's memory
+func (run *sandboxRun) memoryLimit() string {
+	if run.cgroupDir != "" {
+		return "cgroup"
+	}
+	return "rlimit"
+}
+
+// oomKilled reports whether the kernel killed part of the run for exceeding memory.max
+func (run *sandboxRun) oomKilled() bool {
+	if run.cgroupDir == "" {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join(run.cgroupDir, "memory.events"))
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if count, ok := strings.CutPrefix(line, "oom_kill "); ok {
+			n, _ := strconv.Atoi(count)
+			return n > 0
+		}
+	}
+	return false
+}
+
+// release frees everything prepare acquired, in reverse order
+func (run *sandboxRun) release() {
+	run.closeChildFiles()
+	if run.status != nil {
+		run.status.Close()
+	}
+	for i := len(run.closers) - 1; i >= 0; i-- {
+		run.closers[i]()
+	}
+}
+
+// newSandboxCgroup creates a cgroup for one run under a delegated cgroup v2 parent
+// and caps its memory and process count
+func newSandboxCgroup(parent string, config *SandboxConfig) (string, error) {
+	dir, err := os.MkdirTemp(parent, "ocs-sandbox-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create cgroup: %w", err)
+	}
+	limits := []struct {
+		file, value string
+		optional    bool
+	}{
+		{"memory.max", strconv.Itoa(config.MemoryMB * 1024 * 1024), false},
+		{"memory.swap.max", "0", true}, // absent without swap accounting
+		{"pids.max", strconv.Itoa(config.MaxProcesses), false},
+	}
+	for _, limit := range limits {
+		if err := os.WriteFile(filepath.Join(dir, limit.file), []byte(limit.value), 0644); err != nil && !limit.optional {
+			removeSandboxCgroup(dir)
+			return "", fmt.Errorf("failed to set %s: %w", limit.file, err)
+		}
+	}
+	return dir, nil
+}
+
+// removeSandboxCgroup deletes a run's cgroup, giving killed processes a moment to leave it
+func removeSandboxCgroup(dir string) {
+	for attempt := 0; ; attempt++ {
+		err := os.Remove(dir)
+		if err == nil || errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		if attempt == 10 {
+			log.Warn().Err(err).Str("cgroup", dir).Msg("Failed to remove sandbox cgroup")
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// ulimitScript applies resource limits in the child shell before exec'ing the interpreter.
+// Memory is capped through RLIMIT_DATA rather than address space, which runtimes like
+// V8 and Go reserve far beyond what they use, unless a cgroup already caps it.
+func (sb *Sandbox) ulimitScript(cgroupLimited bool) string {
+	limits := []string{
+		fmt.Sprintf("ulimit -t %d", sb.config.CPUSeconds),
+		fmt.Sprintf("ulimit -f %d", sb.config.MaxFileSizeMB*1024),
+		fmt.Sprintf("ulimit -n %d", sb.config.MaxOpenFiles),
+		fmt.Sprintf("ulimit -u %d 2>/dev/null", sb.config.MaxProcesses),
+	}
+	if !cgroupLimited {
+		limits = append(limits, fmt.Sprintf("ulimit -d %d", sb.config.MemoryMB*1024))
+	}
+	return strings.Join(limits, "; ") + `; exec "$@"`
+}
+
+// killProcessGroup terminates the child and everything it spawned
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+// limitedBuffer keeps the first max bytes written and records whether more arrived
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func newLimitedBuffer(max int) *limitedBuffer {
+	return &limitedBuffer{max: max}
+}
+
+func (lb *limitedBuffer) Write(p []byte) (int, error) {
+	remaining := lb.max - lb.buf.Len()
+	if remaining <= 0 {
+		lb.truncated = lb.truncated || len(p) > 0
+		return len(p), nil
+	}
+	if len(p) > remaining {
+		lb.buf.Write(p[:remaining])
+		lb.truncated = true
+		return len(p), nil
+	}
+	return lb.buf.Write(p)
+}
+
+func (lb *limitedBuffer) String() string {
+	return lb.buf.String()
+}
@@ -0,0 +1,508 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/tools/sandbox_linux.go
+
+//go:build linux
+
+package tools
+
+import (
+	// stdlib
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	// third-party
+	"golang.org/x/sys/unix"
+)
+
+// sandboxInitName is argv[0] of the service binary re-executed as the namespace helper
+const sandboxInitName = "ocs-sandbox-init"
+
+// sandboxInitSpec tells the namespace helper how to build the child's root
+type sandboxInitSpec struct {
+	Root     string   `json:"root"` // empty host dir the new root is mounted on
+	Work     string   `json:"work"`
+	ReadOnly []string `json:"read_only"`
+	Network  bool     `json:"network"`
+	TmpMB    int      `json:"tmp_mb"`
+}
+
+// The helper runs before main whenever the binary is started under its name
+func init() {
+	if len(os.Args) > 1 && os.Args[0] == sandboxInitName {
+		sandboxInit(os.Args[1], os.Args[2:])
+	}
+}
+
+// prepare builds the child command, wrapping the interpreter in bwrap when installed and
+// in the namespace helper otherwise, and places it in a cgroup when one is configured
+func (sb *Sandbox) prepare(ctx context.Context, workDir string, command []string) (*sandboxRun, error) {
+	if _, err := seccompFilter(); err != nil {
+		return nil, fmt.Errorf("no sandbox available: %w", err)
+	}
+
+	run := &sandboxRun{}
+	if sb.config.CgroupParent != "" {
+		dir, err := newSandboxCgroup(sb.config.CgroupParent, sb.config)
+		if err != nil {
+			return nil, fmt.Errorf("no sandbox available: %w", err)
+		}
+		run.cgroupDir = dir
+		run.closers = append(run.closers, func() { removeSandboxCgroup(dir) })
+	}
+	limited := append([]string{"/bin/sh", "-c", sb.ulimitScript(run.cgroupDir != ""), "sandbox"}, command...)
+
+	var err error
+	if sb.bwrapPath != "" {
+		err = sb.prepareBwrap(ctx, run, workDir, limited)
+	} else {
+		err = sb.prepareNamespaces(ctx, run, workDir, limited)
+	}
+	if err != nil {
+		run.release()
+		return nil, fmt.Errorf("no sandbox available: %w", err)
+	}
+
+	run.cmd.Cancel = func() error { return killProcessGroup(run.cmd) }
+	if run.cgroupDir != "" {
+		cgroup, err := os.Open(run.cgroupDir)
+		if err != nil {
+			run.release()
+			return nil, fmt.Errorf("no sandbox available: %w", err)
+		}
+		run.closers = append(run.closers, func() { cgroup.Close() })
+		run.cmd.SysProcAttr.UseCgroupFD = true
+		run.cmd.SysProcAttr.CgroupFD = int(cgroup.Fd())
+	}
+	return run, nil
+}
+
+// prepareBwrap runs the command under bubblewrap, handing it the seccomp filter on fd 3
+func (sb *Sandbox) prepareBwrap(ctx context.Context, run *sandboxRun, workDir string, command []string) error {
+	filter, err := seccompFilter()
+	if err != nil {
+		return err
+	}
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to pass seccomp filter: %w", err)
+	}
+	// The filter is far smaller than a pipe buffer, so this never blocks
+	_, err = writer.Write(filter)
+	writer.Close()
+	if err != nil {
+		reader.Close()
+		return fmt.Errorf("failed to pass seccomp filter: %w", err)
+	}
+
+	args := []string{
+		"--unshare-all",
+		"--die-with-parent",
+		"--new-session",
+		"--cap-drop", "ALL",
+		"--seccomp", "3",
+		"--proc", "/proc",
+		"--dev", "/dev",
+		"--tmpfs", "/tmp",
+		"--bind", workDir, "/work",
+		"--chdir", "/work",
+		"--clearenv",
+	}
+	if sb.config.AllowNetwork {
+		args = append(args, "--share-net", "--ro-bind-try", "/etc/resolv.conf", "/etc/resolv.conf")
+	}
+	for _, path := range sandboxReadOnlyPaths {
+		args = append(args, "--ro-bind-try", path, path)
+	}
+	for _, kv := range sandboxEnv {
+		parts := strings.SplitN(kv, "=", 2)
+		args = append(args, "--setenv", parts[0], parts[1])
+	}
+	args = append(args, "--")
+	args = append(args, command...)
+
+	run.cmd = exec.CommandContext(ctx, sb.bwrapPath, args...)
+	run.cmd.ExtraFiles = []*os.File{reader}
+	run.cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	run.isolation = "bwrap"
+	return nil
+}
+
+// prepareNamespaces re-executes the service binary as the namespace helper in new user,
+// mount, PID, IPC, UTS and (unless allowed) network namespaces. The helper pivots into
+// a fresh root before exec'ing the command, so the host filesystem is never visible.
+func (sb *Sandbox) prepareNamespaces(ctx context.Context, run *sandboxRun, workDir string, command []string) error {
+	root, err := os.MkdirTemp(filepath.Dir(workDir), ".root-")
+	if err != nil {
+		return fmt.Errorf("failed to create sandbox root: %w", err)
+	}
+	run.closers = append(run.closers, func() { os.Remove(root) })
+
+	readOnly := append([]string{}, sandboxReadOnlyPaths...)
+	if sb.config.AllowNetwork {
+		readOnly = append(readOnly, "/etc/resolv.conf")
+	}
+	spec, err := json.Marshal(&sandboxInitSpec{
+		Root:     root,
+		Work:     workDir,
+		ReadOnly: readOnly,
+		Network:  sb.config.AllowNetwork,
+		TmpMB:    sb.config.MaxFileSizeMB,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode sandbox spec: %w", err)
+	}
+
+	status, statusWriter, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create status pipe: %w", err)
+	}
+	run.status = status
+
+	flags := uintptr(syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS)
+	if !sb.config.AllowNetwork {
+		flags |= syscall.CLONE_NEWNET
+	}
+
+	run.cmd = exec.CommandContext(ctx, "/proc/self/exe")
+	run.cmd.Args = append([]string{sandboxInitName, string(spec)}, command...)
+	run.cmd.Env = append([]string{}, sandboxEnv...)
+	run.cmd.ExtraFiles = []*os.File{statusWriter}
+	run.cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: flags,
+		Setpgid:    true,
+		Pdeathsig:  syscall.SIGKILL,
+		// The helper is root inside the namespace so it can mount, and drops every
+		// capability before the command runs
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		GidMappingsEnableSetgroups: false,
+	}
+	run.isolation = "namespaces"
+	return nil
+}
+
+// sandboxInit is the namespace helper: it builds the root, drops privileges, installs
+// the seccomp filter and execs the command. Failures go to the status pipe on fd 3.
+func sandboxInit(rawSpec string, command []string) {
+	status := os.NewFile(3, "status")
+	syscall.CloseOnExec(3)
+	fail := func(err error) {
+		fmt.Fprintf(status, "sandbox setup: %v", err)
+		os.Exit(1)
+	}
+
+	// Mounts, capabilities and the seccomp filter are per thread until exec
+	runtime.LockOSThread()
+
+	var spec sandboxInitSpec
+	if err := json.Unmarshal([]byte(rawSpec), &spec); err != nil {
+		fail(fmt.Errorf("invalid spec: %w", err))
+	}
+	if len(command) == 0 {
+		fail(errors.New("no command"))
+	}
+	if err := buildSandboxRoot(&spec); err != nil {
+		fail(err)
+	}
+	if err := dropPrivileges(); err != nil {
+		fail(err)
+	}
+	filter, err := seccompFilter()
+	if err != nil {
+		fail(err)
+	}
+	if err := installSeccompFilter(filter); err != nil {
+		fail(err)
+	}
+
+	fail(syscall.Exec(command[0], command, os.Environ()))
+}
+
+// buildSandboxRoot mounts a tmpfs root holding the read-only system paths, the scratch
+// dir at /work, a private /tmp, /proc and a minimal /dev, then pivots into it
+func buildSandboxRoot(spec *sandboxInitSpec) error {
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+	root := spec.Root
+	if err := syscall.Mount("tmpfs", root, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=0755"); err != nil {
+		return fmt.Errorf("mount root: %w", err)
+	}
+
+	for _, path := range spec.ReadOnly {
+		if err := exposeReadOnly(root, path); err != nil {
+			return err
+		}
+	}
+
+	if err := bindMount(spec.Work, filepath.Join(root, "work"), syscall.MS_NOSUID|syscall.MS_NODEV); err != nil {
+		return err
+	}
+	tmp := filepath.Join(root, "tmp")
+	if err := os.Mkdir(tmp, 01777); err != nil {
+		return fmt.Errorf("create /tmp: %w", err)
+	}
+	if err := syscall.Mount("tmpfs", tmp, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, fmt.Sprintf("mode=1777,size=%dm", spec.TmpMB)); err != nil {
+		return fmt.Errorf("mount /tmp: %w", err)
+	}
+	proc := filepath.Join(root, "proc")
+	if err := os.Mkdir(proc, 0555); err != nil {
+		return fmt.Errorf("create /proc: %w", err)
+	}
+	if err := syscall.Mount("proc", proc, "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mount /proc: %w", err)
+	}
+	if err := buildSandboxDev(filepath.Join(root, "dev")); err != nil {
+		return err
+	}
+
+	// pivot_root(".", ".") stacks the old root under the new one, ready to detach
+	if err := os.Chdir(root); err != nil {
+		return fmt.Errorf("enter root: %w", err)
+	}
+	if err := syscall.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("pivot_root: %w", err)
+	}
+	if err := syscall.Unmount(".", syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("detach host root: %w", err)
+	}
+	if err := syscall.Mount("", "/", "", syscall.MS_REMOUNT|syscall.MS_RDONLY|syscall.MS_NOSUID|syscall.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("remount root read-only: %w", err)
+	}
+	if err := syscall.Sethostname([]byte("sandbox")); err != nil {
+		return fmt.Errorf("set hostname: %w", err)
+	}
+	if err := os.Chdir("/work"); err != nil {
+		return fmt.Errorf("enter /work: %w", err)
+	}
+	return nil
+}
+
+// exposeReadOnly recreates a host path under root: symlinks as symlinks, everything
+// else as a read-only bind mount. Missing paths are skipped.
+func exposeReadOnly(root, path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil
+	}
+	target := filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("create %s: %w", filepath.Dir(path), err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(path)
+		if err != nil {
+			return fmt.Errorf("read link %s: %w", path, err)
+		}
+		if err := os.Symlink(link, target); err != nil && !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("link %s: %w", path, err)
+		}
+		return nil
+	}
+	return bindMount(path, target, syscall.MS_RDONLY|syscall.MS_NOSUID|syscall.MS_NODEV)
+}
+
+// bindMount binds source onto target, creating the target, and remounts it with flags
+// plus whichever of the host mount's flags a user namespace may not clear
+func bindMount(source, target string, flags uintptr) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", source, err)
+	}
+	if info.IsDir() {
+		err = os.MkdirAll(target, 0755)
+	} else {
+		var file *os.File
+		if file, err = os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+			file.Close()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("create mount point %s: %w", target, err)
+	}
+
+	if err := syscall.Mount(source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind %s: %w", source, err)
+	}
+	if flags == 0 {
+		return nil
+	}
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(target, &stat); err != nil {
+		return fmt.Errorf("statfs %s: %w", source, err)
+	}
+	locked := uintptr(stat.Flags) & (syscall.MS_RDONLY | syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC | syscall.MS_NOATIME | syscall.MS_NODIRATIME | syscall.MS_RELATIME)
+	if err := syscall.Mount("", target, "", syscall.MS_BIND|syscall.MS_REMOUNT|flags|locked, ""); err != nil {
+		return fmt.Errorf("remount %s: %w", source, err)
+	}
+	return nil
+}
+
+// sandboxDevices are the host device nodes bound into /dev
+var sandboxDevices = []string{"null", "zero", "full", "random", "urandom"}
+
+// buildSandboxDev populates a tmpfs /dev with the harmless devices and the usual links
+func buildSandboxDev(dev string) error {
+	if err := os.Mkdir(dev, 0755); err != nil {
+		return fmt.Errorf("create /dev: %w", err)
+	}
+	if err := syscall.Mount("tmpfs", dev, "tmpfs", syscall.MS_NOSUID, "mode=0755"); err != nil {
+		return fmt.Errorf("mount /dev: %w", err)
+	}
+	for _, name := range sandboxDevices {
+		if err := bindMount(filepath.Join("/dev", name), filepath.Join(dev, name), 0); err != nil {
+			return err
+		}
+	}
+	links := map[string]string{"fd": "/proc/self/fd", "stdin": "/proc/self/fd/0", "stdout": "/proc/self/fd/1", "stderr": "/proc/self/fd/2"}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dev, name)); err != nil {
+			return fmt.Errorf("link /dev/%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// dropPrivileges empties every capability set, including the bounding set that root
+// would otherwise regain on exec, and forbids gaining privileges through setuid files
+func dropPrivileges() error {
+	for capability := 0; capability <= unix.CAP_LAST_CAP; capability++ {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(capability), 0, 0, 0); err != nil && !errors.Is(err, unix.EINVAL) {
+			return fmt.Errorf("drop capability %d: %w", capability, err)
+		}
+	}
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil {
+		return fmt.Errorf("clear ambient capabilities: %w", err)
+	}
+	var data [2]unix.CapUserData
+	if err := unix.Capset(&unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}, &data[0]); err != nil {
+		return fmt.Errorf("clear capabilities: %w", err)
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("set no_new_privs: %w", err)
+	}
+	return nil
+}
+
+// installSeccompFilter loads a filter built by seccompFilter on the calling thread
+func installSeccompFilter(filter []byte) error {
+	program := unix.SockFprog{
+		Len:    uint16(len(filter) / int(unsafe.Sizeof(unix.SockFilter{}))),
+		Filter: (*unix.SockFilter)(unsafe.Pointer(&filter[0])),
+	}
+	if err := unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&program)), 0, 0); err != nil {
+		return fmt.Errorf("install seccomp filter: %w", err)
+	}
+	return nil
+}
+
+// Offsets into struct seccomp_data, and the bit marking x32 syscalls on amd64
+const (
+	seccompDataNr   = 0
+	seccompDataArch = 4
+	seccompDataArg0 = 16
+	x32SyscallBit   = 0x40000000
+)
+
+// seccompDenied lists syscalls that fail with EPERM inside the sandbox: mounting and
+// namespace changes, kernel and system administration, and cross-process access
+var seccompDenied = []uint32{
+	unix.SYS_MOUNT, unix.SYS_UMOUNT2, unix.SYS_PIVOT_ROOT, unix.SYS_CHROOT,
+	unix.SYS_OPEN_TREE, unix.SYS_MOVE_MOUNT, unix.SYS_FSOPEN, unix.SYS_FSCONFIG, unix.SYS_FSMOUNT,
+	unix.SYS_FSPICK, unix.SYS_MOUNT_SETATTR,
+	unix.SYS_UNSHARE, unix.SYS_SETNS,
+	unix.SYS_PTRACE, unix.SYS_PROCESS_VM_READV, unix.SYS_PROCESS_VM_WRITEV, unix.SYS_PIDFD_GETFD,
+	unix.SYS_KEXEC_LOAD, unix.SYS_KEXEC_FILE_LOAD, unix.SYS_INIT_MODULE, unix.SYS_FINIT_MODULE,
+	unix.SYS_DELETE_MODULE, unix.SYS_BPF, unix.SYS_PERF_EVENT_OPEN, unix.SYS_USERFAULTFD,
+	unix.SYS_KEYCTL, unix.SYS_ADD_KEY, unix.SYS_REQUEST_KEY,
+	unix.SYS_OPEN_BY_HANDLE_AT, unix.SYS_NAME_TO_HANDLE_AT,
+	unix.SYS_REBOOT, unix.SYS_SWAPON, unix.SYS_SWAPOFF, unix.SYS_SYSLOG, unix.SYS_ACCT,
+	unix.SYS_QUOTACTL, unix.SYS_SETTIMEOFDAY, unix.SYS_CLOCK_SETTIME, unix.SYS_ADJTIMEX,
+	unix.SYS_CLOCK_ADJTIME, unix.SYS_SETHOSTNAME, unix.SYS_SETDOMAINNAME,
+}
+
+// sandboxNamespaceFlags are the clone flags that would create new namespaces
+const sandboxNamespaceFlags = unix.CLONE_NEWUSER | unix.CLONE_NEWNS | unix.CLONE_NEWPID | unix.CLONE_NEWNET |
+	unix.CLONE_NEWIPC | unix.CLONE_NEWUTS | unix.CLONE_NEWCGROUP
+
+// seccompFilter returns the sandbox's BPF program as raw struct sock_filter entries, the
+// form both seccomp(2) and bwrap --seccomp take. Everything not denied is allowed; clone3
+// reports ENOSYS so C libraries fall back to clone, whose flags the filter can inspect.
+func seccompFilter() ([]byte, error) {
+	var arch uint32
+	switch runtime.GOARCH {
+	case "amd64":
+		arch = unix.AUDIT_ARCH_X86_64
+	case "arm64":
+		arch = unix.AUDIT_ARCH_AARCH64
+	default:
+		return nil, fmt.Errorf("no seccomp filter for %s", runtime.GOARCH)
+	}
+
+	deny := func(errno unix.Errno) unix.SockFilter {
+		return bpfStmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ERRNO|uint32(errno))
+	}
+	program := []unix.SockFilter{
+		// Kill anything issued through another architecture's syscall table
+		bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataArch),
+		bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, arch, 1, 0),
+		bpfStmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_KILL_PROCESS),
+		bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataNr),
+	}
+	if runtime.GOARCH == "amd64" {
+		program = append(program,
+			bpfJump(unix.BPF_JMP|unix.BPF_JGE|unix.BPF_K, x32SyscallBit, 0, 1),
+			deny(unix.EPERM),
+		)
+	}
+	for _, nr := range seccompDenied {
+		program = append(program,
+			bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, nr, 0, 1),
+			deny(unix.EPERM),
+		)
+	}
+	program = append(program,
+		bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, unix.SYS_CLONE3, 0, 1),
+		deny(unix.ENOSYS),
+		bpfJump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, unix.SYS_CLONE, 0, 3),
+		bpfStmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataArg0),
+		bpfJump(unix.BPF_JMP|unix.BPF_JSET|unix.BPF_K, sandboxNamespaceFlags, 0, 1),
+		deny(unix.EPERM),
+		bpfStmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ALLOW),
+	)
+
+	raw := make([]byte, 0, len(program)*int(unsafe.Sizeof(unix.SockFilter{})))
+	for _, instruction := range program {
+		raw = binary.NativeEndian.AppendUint16(raw, instruction.Code)
+		raw = append(raw, instruction.Jt, instruction.Jf)
+		raw = binary.NativeEndian.AppendUint32(raw, instruction.K)
+	}
+	return raw, nil
+}
+
+func bpfStmt(code uint16, k uint32) unix.SockFilter {
+	return unix.SockFilter{Code: code, K: k}
+}
+
+func bpfJump(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+	return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
+
+// maxRSSKB returns the child's peak resident set size
+func maxRSSKB(state *os.ProcessState) int64 {
+	if usage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		return usage.Maxrss
+	}
+	return 0
+}
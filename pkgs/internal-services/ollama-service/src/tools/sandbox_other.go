@@ -0,0 +1,24 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/tools/sandbox_other.go
+
+//go:build !linux
+
+package tools
+
+import (
+	// stdlib
+	"context"
+	"fmt"
+	"os"
+)
+
+// prepare is unavailable outside Linux; execution is refused rather than run unsandboxed
+func (sb *Sandbox) prepare(ctx context.Context, workDir string, command []string) (*sandboxRun, error) {
+	return nil, fmt.Errorf("no sandbox available: isolation requires linux")
+}
+
+// maxRSSKB is not reported outside Linux
+func maxRSSKB(state *os.ProcessState) int64 {
+	return 0
+}
@@ -0,0 +1,280 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/tools/sandbox_test.go
+
+package tools
+
+import (
+	// stdlib
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestSandbox returns a sandbox over a temporary base dir, skipping the test when the
+// host cannot isolate processes (no user namespaces and no bwrap)
+func newTestSandbox(t *testing.T, adjust func(*SandboxConfig)) *Sandbox {
+	t.Helper()
+	config := DefaultSandboxConfig()
+	config.BaseDir = t.TempDir()
+	config.WallTime = 20 * time.Second
+	if adjust != nil {
+		adjust(config)
+	}
+	sb := NewSandbox(config)
+	if _, err := sb.Run(context.Background(), &SandboxRequest{SessionID: "probe", Language: "python", Code: "pass"}); err != nil {
+		t.Skipf("sandbox unavailable: %v", err)
+	}
+	return sb
+}
+
+// runPython runs code in session s1 and fails the test if the sandbox could not start
+func runPython(t *testing.T, sb *Sandbox, code string) *SandboxResult {
+	t.Helper()
+	result, err := sb.Run(context.Background(), &SandboxRequest{SessionID: "s1", Language: "python", Code: code})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return result
+}
+
+func TestSandboxRunsLanguages(t *testing.T) {
+	sb := newTestSandbox(t, nil)
+
+	result, err := sb.Run(context.Background(), &SandboxRequest{
+		SessionID: "s1",
+		Language:  "python",
+		Code:      "import sys\nprint(sys.stdin.read().upper())\nsys.exit(3)",
+		Stdin:     "hello",
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result.ExitCode != 3 || result.Stdout != "HELLO\n" || result.TimedOut || result.MemoryLimit != "rlimit" {
+		t.Errorf("python = %+v", result)
+	}
+	if result.Isolation != "bwrap" && result.Isolation != "namespaces" {
+		t.Errorf("isolation = %q", result.Isolation)
+	}
+
+	// Runtimes that reserve far more address space than they use start under the memory limit;
+	// go run also needs room to compile the standard library into a cold build cache
+	runtimes := []struct{ language, code, want string }{
+		{"javascript", "console.log(6 * 7)", "42\n"},
+		{"go", "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println(6 * 7) }\n", "42\n"},
+	}
+	for _, rt := range runtimes {
+		if rt.language == "go" && testing.Short() {
+			continue
+		}
+		sb.config.WallTime = 3 * time.Minute
+		sb.config.CPUSeconds = 120
+		sb.config.MaxFileSizeMB = 64
+		result, err := sb.Run(context.Background(), &SandboxRequest{SessionID: "s1", Language: rt.language, Code: rt.code})
+		if err != nil {
+			t.Fatalf("Run %s: %v", rt.language, err)
+		}
+		if result.ExitCode != 0 || result.Stdout != rt.want {
+			t.Errorf("%s = exit %d, stdout %q, stderr %q", rt.language, result.ExitCode, result.Stdout, result.Stderr)
+		}
+	}
+}
+
+func TestSandboxLimits(t *testing.T) {
+	sb := newTestSandbox(t, func(config *SandboxConfig) {
+		config.MemoryMB = 128
+		config.CPUSeconds = 1
+		config.MaxFileSizeMB = 1
+	})
+
+	// Memory beyond the limit cannot be allocated
+	result := runPython(t, sb, "buf = bytearray(512 * 1024 * 1024)\nprint('allocated')")
+	if result.ExitCode == 0 || !strings.Contains(result.Stderr, "MemoryError") {
+		t.Errorf("memory = %+v", result)
+	}
+
+	// CPU time is capped independently of wall time
+	result = runPython(t, sb, "while True:\n    pass")
+	if result.ExitCode == 0 || result.TimedOut || result.Usage.WallTime > 10*time.Second {
+		t.Errorf("cpu = %+v, usage = %+v", result, result.Usage)
+	}
+
+	// Files cannot grow past the size limit
+	result = runPython(t, sb, "with open('big.bin', 'wb') as f:\n    f.write(b'x' * 2 * 1024 * 1024)")
+	if info, err := os.Stat(filepath.Join(sb.config.BaseDir, "s1", "big.bin")); result.ExitCode == 0 || err != nil || info.Size() > 1024*1024 {
+		t.Errorf("file size = %+v, %v", result, err)
+	}
+
+	// Output is truncated rather than buffered without bound
+	sb.config.MaxOutputBytes = 100
+	result = runPython(t, sb, "print('x' * 1000)")
+	if result.ExitCode != 0 || len(result.Stdout) != 100 || !result.StdoutTruncated {
+		t.Errorf("output = %d bytes, truncated %v", len(result.Stdout), result.StdoutTruncated)
+	}
+}
+
+func TestSandboxWallTimeout(t *testing.T) {
+	sb := newTestSandbox(t, nil)
+	sb.config.WallTime = time.Second
+
+	// Sleeping uses no CPU, so only the wall clock ends it, children included
+	result := runPython(t, sb, "import subprocess, time\nsubprocess.Popen(['sleep', '60'])\ntime.sleep(60)")
+	if !result.TimedOut || result.ExitCode == 0 || result.Usage.WallTime > 5*time.Second {
+		t.Errorf("timeout = %+v, usage = %+v", result, result.Usage)
+	}
+}
+
+func TestSandboxNetwork(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	connect := fmt.Sprintf("import socket\nsocket.create_connection(('127.0.0.1', %d), timeout=2)\nprint('connected')", listener.Addr().(*net.TCPAddr).Port)
+
+	// The network is off by default: even the host's loopback is out of reach
+	offline := newTestSandbox(t, nil)
+	if result := runPython(t, offline, connect); result.ExitCode == 0 || strings.Contains(result.Stdout, "connected") {
+		t.Errorf("offline = %+v", result)
+	}
+
+	online := newTestSandbox(t, func(config *SandboxConfig) { config.AllowNetwork = true })
+	if result := runPython(t, online, connect); result.ExitCode != 0 || result.Stdout != "connected\n" {
+		t.Errorf("online = %+v", result)
+	}
+}
+
+func TestSandboxFilesystemIsolation(t *testing.T) {
+	sb := newTestSandbox(t, nil)
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(secret, []byte("host secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code := fmt.Sprintf(`import os
+def writable(path):
+    try:
+        with open(path, 'w') as f:
+            f.write('x')
+        return True
+    except OSError:
+        return False
+print(os.path.exists(%q), os.path.exists('/etc/passwd'), os.path.exists('/root'))
+print(writable('/usr/escape'), writable('/escape'), writable('/tmp/scratch'), writable('/work/out.txt'))
+print(os.getcwd(), sorted(os.listdir('/work')))
+`, secret)
+	result := runPython(t, sb, code)
+	want := "False False False\nFalse False True True\n/work ['main.py', 'out.txt']\n"
+	if result.ExitCode != 0 || result.Stdout != want {
+		t.Fatalf("stdout = %q, stderr = %q, want %q", result.Stdout, result.Stderr, want)
+	}
+
+	// /work is the session's scratch dir on the host, and only that session's
+	if data, err := os.ReadFile(filepath.Join(sb.config.BaseDir, "s1", "out.txt")); err != nil || string(data) != "x" {
+		t.Errorf("out.txt = %q, %v", data, err)
+	}
+	other, err := sb.Run(context.Background(), &SandboxRequest{SessionID: "s2", Language: "python", Code: "import os\nprint(os.listdir('/work'))"})
+	if err != nil || other.Stdout != "['main.py']\n" {
+		t.Errorf("other session = %+v, %v", other, err)
+	}
+	if _, err := os.Stat("/usr/escape"); err == nil {
+		t.Error("sandbox wrote to the host's /usr")
+	}
+
+	// Private roots are torn down after each run
+	if roots, _ := filepath.Glob(filepath.Join(sb.config.BaseDir, ".root-*")); len(roots) > 0 {
+		t.Errorf("leftover roots: %v", roots)
+	}
+}
+
+func TestSandboxSeccomp(t *testing.T) {
+	sb := newTestSandbox(t, nil)
+
+	// Namespace and mount syscalls are refused even where the kernel would allow them;
+	// ordinary process creation still works
+	result := runPython(t, sb, `import ctypes, os, subprocess
+libc = ctypes.CDLL(None, use_errno=True)
+print(libc.unshare(0x10000000), ctypes.get_errno())
+print(libc.mount(b"none", b"/tmp", b"tmpfs", 0, None), ctypes.get_errno())
+print(subprocess.run(['echo', 'child'], capture_output=True, text=True).stdout.strip())
+`)
+	if want := "-1 1\n-1 1\nchild\n"; result.ExitCode != 0 || result.Stdout != want {
+		t.Errorf("stdout = %q, stderr = %q, want %q", result.Stdout, result.Stderr, want)
+	}
+
+	// No capability survives into the sandbox
+	result = runPython(t, sb, "print([line for line in open('/proc/self/status') if line.startswith(('CapEff', 'CapBnd', 'NoNewPrivs', 'Seccomp:'))])")
+	for _, want := range []string{"CapEff:\\t0000000000000000", "CapBnd:\\t0000000000000000", "NoNewPrivs:\\t1", "Seccomp:\\t2"} {
+		if !strings.Contains(result.Stdout, want) {
+			t.Errorf("status %s missing from %s", want, result.Stdout)
+		}
+	}
+}
+
+func TestSandboxCgroupLimits(t *testing.T) {
+	config := DefaultSandboxConfig()
+	config.MemoryMB = 64
+	config.MaxProcesses = 16
+	dir, err := newSandboxCgroup(t.TempDir(), config)
+	if err != nil {
+		t.Fatalf("newSandboxCgroup: %v", err)
+	}
+	for file, want := range map[string]string{"memory.max": "67108864", "memory.swap.max": "0", "pids.max": "16"} {
+		if data, err := os.ReadFile(filepath.Join(dir, file)); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v", file, data, err)
+		}
+	}
+
+	run := &sandboxRun{cgroupDir: dir}
+	os.WriteFile(filepath.Join(dir, "memory.events"), []byte("low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n"), 0644)
+	if run.memoryLimit() != "cgroup" || !run.oomKilled() {
+		t.Errorf("memory limit = %s, oom killed = %v", run.memoryLimit(), run.oomKilled())
+	}
+
+	// The cgroup caps memory instead of RLIMIT_DATA, and address space is never capped
+	sb := NewSandbox(config)
+	if script := sb.ulimitScript(true); strings.Contains(script, "ulimit -d") || strings.Contains(script, "ulimit -v") {
+		t.Errorf("cgroup script = %s", script)
+	}
+	if script := sb.ulimitScript(false); !strings.Contains(script, "ulimit -d 65536") || strings.Contains(script, "ulimit -v") {
+		t.Errorf("rlimit script = %s", script)
+	}
+
+	if _, err := newSandboxCgroup(filepath.Join(t.TempDir(), "missing"), config); err == nil {
+		t.Error("created a cgroup under a missing parent")
+	}
+}
+
+func TestSandboxSessionDirNames(t *testing.T) {
+	if got := sessionDirName("s1"); got != "s1" {
+		t.Errorf("s1 = %q", got)
+	}
+
+	// IDs that need rewriting never land in another session's dir
+	ids := []string{"a.b", "a_b", "a/b", "a b", "..", "../a_b", "", "anonymous", "a_b.ffffffffffffffff", strings.Repeat("x", 100), strings.Repeat("x", 101)}
+	seen := make(map[string]string)
+	for _, id := range ids {
+		name := sessionDirName(id)
+		if other, ok := seen[name]; ok {
+			t.Errorf("%q and %q share %q", id, other, name)
+		}
+		seen[name] = id
+		if name == "." || name == ".." || strings.ContainsAny(name, "/\\") || len(name) > 100 {
+			t.Errorf("%q maps to %q", id, name)
+		}
+	}
+}
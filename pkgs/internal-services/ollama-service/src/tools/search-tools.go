@@ -41,25 +41,47 @@ func (st *SearchTool) Execute(ctx context.Context, toolCall *types.ToolCall) (*t
 	case "search_conversation":
 		return st.searchConversation(ctx, toolCall)
 	default:
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   fmt.Sprintf("unknown tool call: %s", toolCall.Name),
 		}, nil
 	}
 }
 
+// ToolSpecs describes the calls SearchTool handles
+func (st *SearchTool) ToolSpecs() []*ToolSpec {
+	return []*ToolSpec{
+		{
+			Name:        "search_memory",
+			Description: "Search the user's long-term memories",
+			Scope:       ScopeTools,
+			Parameters: objectSchema(map[string]interface{}{
+				"query": stringProperty("What to look for"),
+			}, "query"),
+		},
+		{
+			Name:        "search_conversation",
+			Description: "Search earlier messages in this session",
+			Scope:       ScopeTools,
+			Parameters: objectSchema(map[string]interface{}{
+				"query": stringProperty("Text to look for"),
+			}, "query"),
+		},
+	}
+}
+
 // searchMemory searches user memories
 func (st *SearchTool) searchMemory(ctx context.Context, toolCall *types.ToolCall) (*types.ToolResult, error) {
 	query, ok := toolCall.Arguments["query"].(string)
 	if !ok || query == "" {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   "missing or invalid query argument",
 		}, nil
 	}
 	userID, ok := toolCall.Arguments["user_id"].(string)
 	if !ok || userID == "" {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   "missing or invalid user_id argument",
 		}, nil
@@ -67,9 +89,13 @@ func (st *SearchTool) searchMemory(ctx context.Context, toolCall *types.ToolCall
 	sessionID, _ := toolCall.Arguments["session_id"].(string)
 
 	start := time.Now()
-	memories, err := st.memoryManager.SearchMemories(ctx, userID, query)
+	memories, err := st.memoryManager.RetrieveMemories(ctx, &managers.MemoryQuery{
+		UserID:  userID,
+		Content: query,
+		Limit:   10,
+	})
 	if err != nil {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   fmt.Sprintf("memory search failed: %v", err),
 		}, nil
@@ -77,7 +103,7 @@ func (st *SearchTool) searchMemory(ctx context.Context, toolCall *types.ToolCall
 
 	results := make([]string, len(memories))
 	for i, memory := range memories {
-		results[i] = fmt.Sprintf("Memory ID: %s, Content: %s", memory.ID, memory.Content)
+		results[i] = fmt.Sprintf("Memory ID: %s, Content: %s", memory.Memory.ID, memory.Memory.Content)
 	}
 	content := strings.Join(results, "\n")
 
@@ -88,7 +114,7 @@ func (st *SearchTool) searchMemory(ctx context.Context, toolCall *types.ToolCall
 		Dur("duration", time.Since(start)).
 		Msg("Searched memories")
 
-	return &managers.ToolResult{
+	return &types.ToolResult{
 		Success: true,
 		Content: content,
 	}, nil
@@ -98,14 +124,14 @@ func (st *SearchTool) searchMemory(ctx context.Context, toolCall *types.ToolCall
 func (st *SearchTool) searchConversation(ctx context.Context, toolCall *types.ToolCall) (*types.ToolResult, error) {
 	query, ok := toolCall.Arguments["query"].(string)
 	if !ok || query == "" {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   "missing or invalid query argument",
 		}, nil
 	}
 	sessionID, ok := toolCall.Arguments["session_id"].(string)
 	if !ok || sessionID == "" {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   "missing or invalid session_id argument",
 		}, nil
@@ -113,7 +139,7 @@ func (st *SearchTool) searchConversation(ctx context.Context, toolCall *types.To
 
 	session, exists := st.sessionManager.GetSession(sessionID)
 	if !exists {
-		return &managers.ToolResult{
+		return &types.ToolResult{
 			Success: false,
 			Error:   fmt.Sprintf("session not found: %s", sessionID),
 		}, nil
@@ -134,7 +160,7 @@ func (st *SearchTool) searchConversation(ctx context.Context, toolCall *types.To
 		Dur("duration", time.Since(start)).
 		Msg("Searched conversation")
 
-	return &managers.ToolResult{
+	return &types.ToolResult{
 		Success: true,
 		Content: content,
 	}, nil
@@ -5,12 +5,13 @@
 package types
 
 type ToolCall struct {
-	Name string
-	Args map[string]interface{}
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
 }
 
 type ToolResult struct {
-	Success bool
-	Error   string
-	Output  string
-}
\ No newline at end of file
+	Success bool        `json:"success"`
+	Error   string      `json:"error,omitempty"`
+	Content string      `json:"content,omitempty"`
+	Data    interface{} `json:"data,omitempty"` // structured payload, e.g. a sandbox execution result
+}
@@ -0,0 +1,260 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/utils/json-schema.go
+
+package utils
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ValidateJSONSchema checks a decoded JSON value against a JSON Schema subset:
+// type, properties, required, additionalProperties, items, enum, const,
+// min/maxLength, pattern, minimum/maximum, min/maxItems, anyOf and oneOf
+func ValidateJSONSchema(schema map[string]interface{}, value interface{}) error {
+	errs := validateSchema(schema, value, "$")
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
+func validateSchema(schema map[string]interface{}, value interface{}, path string) []string {
+	if schema == nil {
+		return nil
+	}
+	var errs []string
+
+	if t, ok := schema["type"]; ok && !matchesType(t, value) {
+		return []string{fmt.Sprintf("%s: expected %v, got %s", path, t, jsonTypeName(value))}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsValue(enum, value) {
+		errs = append(errs, fmt.Sprintf("%s: must be one of %v", path, enum))
+	}
+	if enum, ok := schema["enum"].([]string); ok && !containsValue(stringsToValues(enum), value) {
+		errs = append(errs, fmt.Sprintf("%s: must be one of %v", path, enum))
+	}
+	if c, ok := schema["const"]; ok && !jsonEqual(c, value) {
+		errs = append(errs, fmt.Sprintf("%s: must equal %v", path, c))
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := len([]rune(v))
+		if min, ok := schemaNumber(schema, "minLength"); ok && float64(length) < min {
+			errs = append(errs, fmt.Sprintf("%s: shorter than %v", path, min))
+		}
+		if max, ok := schemaNumber(schema, "maxLength"); ok && float64(length) > max {
+			errs = append(errs, fmt.Sprintf("%s: longer than %v", path, max))
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				errs = append(errs, fmt.Sprintf("%s: does not match %s", path, pattern))
+			}
+		}
+	case float64, float32, int, int64, int32:
+		n := toFloat(v)
+		if min, ok := schemaNumber(schema, "minimum"); ok && n < min {
+			errs = append(errs, fmt.Sprintf("%s: less than %v", path, min))
+		}
+		if max, ok := schemaNumber(schema, "maximum"); ok && n > max {
+			errs = append(errs, fmt.Sprintf("%s: greater than %v", path, max))
+		}
+	case []interface{}:
+		if min, ok := schemaNumber(schema, "minItems"); ok && float64(len(v)) < min {
+			errs = append(errs, fmt.Sprintf("%s: fewer than %v items", path, min))
+		}
+		if max, ok := schemaNumber(schema, "maxItems"); ok && float64(len(v)) > max {
+			errs = append(errs, fmt.Sprintf("%s: more than %v items", path, max))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				errs = append(errs, validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case map[string]interface{}:
+		errs = append(errs, validateObject(schema, v, path)...)
+	}
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok && countMatches(anyOf, value, path) == 0 {
+		errs = append(errs, fmt.Sprintf("%s: does not match any allowed schema", path))
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok && countMatches(oneOf, value, path) != 1 {
+		errs = append(errs, fmt.Sprintf("%s: must match exactly one schema", path))
+	}
+
+	return errs
+}
+
+func validateObject(schema map[string]interface{}, obj map[string]interface{}, path string) []string {
+	var errs []string
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	for _, name := range requiredNames(schema["required"]) {
+		if _, ok := obj[name]; !ok {
+			errs = append(errs, fmt.Sprintf("%s.%s: required", path, name))
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		propSchema, known := properties[key].(map[string]interface{})
+		if known {
+			errs = append(errs, validateSchema(propSchema, obj[key], path+"."+key)...)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				errs = append(errs, fmt.Sprintf("%s.%s: unexpected property", path, key))
+			}
+		case map[string]interface{}:
+			errs = append(errs, validateSchema(additional, obj[key], path+"."+key)...)
+		}
+	}
+	return errs
+}
+
+func countMatches(schemas []interface{}, value interface{}, path string) int {
+	matches := 0
+	for _, s := range schemas {
+		if sub, ok := s.(map[string]interface{}); ok && len(validateSchema(sub, value, path)) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+func matchesType(t interface{}, value interface{}) bool {
+	switch types := t.(type) {
+	case string:
+		return matchesTypeName(types, value)
+	case []interface{}:
+		for _, name := range types {
+			if s, ok := name.(string); ok && matchesTypeName(s, value) {
+				return true
+			}
+		}
+		return false
+	case []string:
+		for _, name := range types {
+			if matchesTypeName(name, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesTypeName(name string, value interface{}) bool {
+	actual := jsonTypeName(value)
+	switch name {
+	case "integer":
+		if actual != "number" {
+			return false
+		}
+		n := toFloat(value)
+		return n == math.Trunc(n)
+	case "number":
+		return actual == "number"
+	}
+	return actual == name
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, float32, int, int64, int32:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return reflect.TypeOf(value).String()
+}
+
+func requiredNames(required interface{}) []string {
+	switch names := required.(type) {
+	case []string:
+		return names
+	case []interface{}:
+		out := make([]string, 0, len(names))
+		for _, name := range names {
+			if s, ok := name.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	v, ok := schema[key]
+	if !ok {
+		return 0, false
+	}
+	switch v.(type) {
+	case float64, float32, int, int64, int32:
+		return toFloat(v), true
+	}
+	return 0, false
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case int32:
+		return float64(n)
+	}
+	return 0
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if jsonEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func stringsToValues(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+func jsonEqual(a, b interface{}) bool {
+	if jsonTypeName(a) == "number" && jsonTypeName(b) == "number" {
+		return toFloat(a) == toFloat(b)
+	}
+	return reflect.DeepEqual(a, b)
+}
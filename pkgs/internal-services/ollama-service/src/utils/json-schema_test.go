@@ -0,0 +1,141 @@
+// Ollama Control Service - OCS
+// Repo = github.com/freigthdev/main/ocs
+// Path = src/utils/json-schema_test.go
+
+package utils
+
+import (
+	// stdlib
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// decode parses JSON the way schemas and model output reach the validator
+func decode(t *testing.T, data string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		t.Fatalf("bad test JSON %s: %v", data, err)
+	}
+	return value
+}
+
+func TestValidateJSONSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		want   string // substring of the error; empty when the value is valid
+	}{
+		{"empty schema", `{}`, `{"anything": [1, "two"]}`, ""},
+		{"string", `{"type": "string"}`, `"hi"`, ""},
+		{"wrong type", `{"type": "string"}`, `3`, "$: expected string, got number"},
+		{"null", `{"type": "null"}`, `null`, ""},
+		{"boolean", `{"type": "boolean"}`, `"true"`, "expected boolean, got string"},
+		{"integer", `{"type": "integer"}`, `4`, ""},
+		{"integer with fraction", `{"type": "integer"}`, `4.5`, "expected integer"},
+		{"number accepts integer", `{"type": "number"}`, `4`, ""},
+		{"type list", `{"type": ["string", "null"]}`, `null`, ""},
+		{"type list mismatch", `{"type": ["string", "null"]}`, `{}`, "got object"},
+
+		{"enum", `{"enum": ["a", "b"]}`, `"b"`, ""},
+		{"enum miss", `{"enum": ["a", "b"]}`, `"c"`, "must be one of"},
+		{"enum numbers", `{"enum": [1, 2]}`, `2.0`, ""},
+		{"const", `{"const": {"k": [1]}}`, `{"k": [1]}`, ""},
+		{"const miss", `{"const": "x"}`, `"y"`, "must equal x"},
+
+		{"minLength counts runes", `{"type": "string", "minLength": 3}`, `"héé"`, ""},
+		{"minLength", `{"type": "string", "minLength": 3}`, `"ab"`, "shorter than 3"},
+		{"maxLength", `{"type": "string", "maxLength": 2}`, `"abc"`, "longer than 2"},
+		{"pattern", `{"type": "string", "pattern": "^[a-z]+$"}`, `"abc"`, ""},
+		{"pattern miss", `{"type": "string", "pattern": "^[a-z]+$"}`, `"ab1"`, "does not match"},
+		{"invalid pattern ignored", `{"type": "string", "pattern": "(?<=x)"}`, `"ab"`, ""},
+		{"minimum", `{"type": "number", "minimum": 0}`, `-1`, "less than 0"},
+		{"maximum", `{"type": "number", "maximum": 1}`, `1.5`, "greater than 1"},
+		{"bounds inclusive", `{"type": "number", "minimum": 0, "maximum": 1}`, `1`, ""},
+
+		{"minItems", `{"type": "array", "minItems": 2}`, `[1]`, "fewer than 2 items"},
+		{"maxItems", `{"type": "array", "maxItems": 1}`, `[1, 2]`, "more than 1 items"},
+		{"items", `{"type": "array", "items": {"type": "string"}}`, `["a", 2, "c", true]`, "$[1]: expected string"},
+
+		{"required", `{"type": "object", "required": ["a", "b"]}`, `{"a": 1}`, "$.b: required"},
+		{"properties", `{"properties": {"a": {"type": "string"}}}`, `{"a": 1}`, "$.a: expected string"},
+		{"additional allowed by default", `{"properties": {"a": {}}}`, `{"b": 1}`, ""},
+		{"additionalProperties false", `{"properties": {"a": {}}, "additionalProperties": false}`, `{"a": 1, "b": 2}`, "$.b: unexpected property"},
+		{"additionalProperties schema", `{"additionalProperties": {"type": "number"}}`, `{"b": "x"}`, "$.b: expected number"},
+		{"nested path", `{"properties": {"items": {"type": "array", "items": {"required": ["id"]}}}}`, `{"items": [{"id": 1}, {}]}`, "$.items[1].id: required"},
+
+		{"anyOf", `{"anyOf": [{"type": "string"}, {"type": "number"}]}`, `3`, ""},
+		{"anyOf miss", `{"anyOf": [{"type": "string"}, {"type": "number"}]}`, `true`, "does not match any allowed schema"},
+		{"oneOf", `{"oneOf": [{"type": "integer"}, {"type": "string"}]}`, `"x"`, ""},
+		{"oneOf matches two", `{"oneOf": [{"type": "integer"}, {"type": "number"}]}`, `3`, "must match exactly one schema"},
+		{"oneOf matches none", `{"oneOf": [{"type": "integer"}, {"type": "string"}]}`, `3.5`, "must match exactly one schema"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := decode(t, tt.schema).(map[string]interface{})
+			err := ValidateJSONSchema(schema, decode(t, tt.value))
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.want != "" && err == nil:
+				t.Errorf("accepted %s, want error containing %q", tt.value, tt.want)
+			case tt.want != "" && !strings.Contains(err.Error(), tt.want):
+				t.Errorf("error = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateJSONSchemaReportsEveryProblem(t *testing.T) {
+	schema := decode(t, `{
+		"type": "object",
+		"required": ["name", "age"],
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"tags": {"type": "array", "items": {"type": "string"}}
+		},
+		"additionalProperties": false
+	}`).(map[string]interface{})
+
+	err := ValidateJSONSchema(schema, decode(t, `{"name": "", "tags": ["a", 1], "zzz": true}`))
+	if err == nil {
+		t.Fatal("accepted an invalid object")
+	}
+	// Problems are listed in a stable order: required first, then properties by name
+	want := "$.age: required; $.name: shorter than 1; $.tags[1]: expected string, got number; $.zzz: unexpected property"
+	if err.Error() != want {
+		t.Errorf("error =\n  %s\nwant\n  %s", err, want)
+	}
+}
+
+func TestValidateJSONSchemaGoValues(t *testing.T) {
+	// Schemas built in Go use []string and int rather than decoded JSON types
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"mode", "count"},
+		"properties": map[string]interface{}{
+			"mode":  map[string]interface{}{"type": []string{"string"}, "enum": []string{"fast", "slow"}},
+			"count": map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 10},
+		},
+	}
+
+	if err := ValidateJSONSchema(schema, map[string]interface{}{"mode": "fast", "count": 3}); err != nil {
+		t.Errorf("valid Go value rejected: %v", err)
+	}
+	err := ValidateJSONSchema(schema, map[string]interface{}{"mode": "medium", "count": int64(11)})
+	if err == nil || !strings.Contains(err.Error(), "$.count: greater than 10") || !strings.Contains(err.Error(), "$.mode: must be one of") {
+		t.Errorf("error = %v", err)
+	}
+	if err := ValidateJSONSchema(schema, map[string]interface{}{"mode": "slow", "count": int32(0)}); err == nil || !strings.Contains(err.Error(), "$.count: less than 1") {
+		t.Errorf("int32 below the minimum: error = %v", err)
+	}
+	if err := ValidateJSONSchema(schema, map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "$.mode: required") {
+		t.Errorf("missing fields error = %v", err)
+	}
+	if err := ValidateJSONSchema(nil, "anything"); err != nil {
+		t.Errorf("nil schema rejected a value: %v", err)
+	}
+}